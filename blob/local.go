@@ -0,0 +1,125 @@
+package blob
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LocalStore is a blob store backed by a directory of the local filesystem. Each blob is
+// stored in a file named after its digest, under two levels of directories named after the
+// first bytes of the digest so that no directory grows too large.
+type LocalStore struct {
+	path string
+}
+
+// NewLocalStore returns a blob store that stores blobs under path.
+func NewLocalStore(path string) *LocalStore {
+	return &LocalStore{path: path}
+}
+
+// Path returns the path of the directory of the store.
+func (s *LocalStore) Path() string { return s.path }
+
+// Open creates the directory of the store if it doesn't exist.
+func (s *LocalStore) Open() error {
+	return os.MkdirAll(s.path, 0777)
+}
+
+// Close closes the store.
+func (s *LocalStore) Close() error { return nil }
+
+// blobPath returns the path of the file of a blob.
+func (s *LocalStore) blobPath(digest string) string {
+	return filepath.Join(s.path, digest[0:2], digest[2:4], digest)
+}
+
+// Put stores the content read from r. The content is written to a temporary file while it is
+// hashed, then moved to the path of its digest.
+func (s *LocalStore) Put(r io.Reader, limit int64) (*Info, error) {
+	f, err := ioutil.TempFile(s.path, ".upload-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// Read one byte past the limit to detect content that is too large.
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+
+	h1, h2 := sha256.New(), md5.New()
+	n, err := io.Copy(io.MultiWriter(f, h1, h2), r)
+	if err != nil {
+		return nil, err
+	} else if limit > 0 && n > limit {
+		return nil, ErrTooLarge
+	} else if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		SHA256: hex.EncodeToString(h1.Sum(nil)),
+		MD5:    hex.EncodeToString(h2.Sum(nil)),
+		Size:   n,
+	}
+
+	// Keep the existing copy of the content, but refresh its modification time so that it
+	// isn't collected before the new upload is referenced.
+	path := s.blobPath(info.SHA256)
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		return info, os.Chtimes(path, now, now)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return nil, err
+	} else if err := os.Rename(f.Name(), path); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Get returns the content of a blob.
+func (s *LocalStore) Get(digest string) (ReadSeekCloser, error) {
+	if !validDigest(digest) {
+		return nil, ErrInvalidDigest
+	}
+	f, err := os.Open(s.blobPath(digest))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Delete removes a blob.
+func (s *LocalStore) Delete(digest string) error {
+	if !validDigest(digest) {
+		return ErrInvalidDigest
+	}
+	if err := os.Remove(s.blobPath(digest)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Walk calls fn for each of the blobs of the store. Temporary files of uploads in progress
+// are skipped.
+func (s *LocalStore) Walk(fn func(digest string, modTime time.Time) error) error {
+	return filepath.Walk(s.path, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if fi.IsDir() || !validDigest(fi.Name()) {
+			return nil
+		}
+		return fn(fi.Name(), fi.ModTime())
+	})
+}
@@ -0,0 +1,105 @@
+package blob_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/blob"
+)
+
+// Ensure content can be stored, read back and deleted.
+func TestLocalStore_PutGetDelete(t *testing.T) {
+	s := MustOpenLocalStore()
+	defer os.RemoveAll(s.Path())
+
+	info, err := s.Put(strings.NewReader("hello"), 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if info.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("unexpected sha256: %s", info.SHA256)
+	} else if info.MD5 != "5d41402abc4b2a76b9719d911017c592" {
+		t.Fatalf("unexpected md5: %s", info.MD5)
+	} else if info.Size != 5 {
+		t.Fatalf("unexpected size: %d", info.Size)
+	}
+
+	// Storing the same content again keeps a single copy.
+	if other, err := s.Put(strings.NewReader("hello"), 0); err != nil {
+		t.Fatal(err)
+	} else if other.SHA256 != info.SHA256 {
+		t.Fatalf("unexpected sha256: %s", other.SHA256)
+	}
+
+	var digests []string
+	if err := s.Walk(func(digest string, modTime time.Time) error {
+		digests = append(digests, digest)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	} else if len(digests) != 1 || digests[0] != info.SHA256 {
+		t.Fatalf("unexpected digests: %v", digests)
+	}
+
+	f, err := s.Get(info.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadAll(f); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "hello" {
+		t.Fatalf("unexpected content: %q", b)
+	}
+	f.Close()
+
+	if err := s.Delete(info.SHA256); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.Get(info.SHA256); err != blob.ErrBlobNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := s.Delete(info.SHA256); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure content larger than the limit is rejected and not stored.
+func TestLocalStore_Put_TooLarge(t *testing.T) {
+	s := MustOpenLocalStore()
+	defer os.RemoveAll(s.Path())
+
+	if _, err := s.Put(strings.NewReader("hello"), 4); err != blob.ErrTooLarge {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := s.Put(strings.NewReader("hello"), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	s.Walk(func(string, time.Time) error { n++; return nil })
+	if n != 1 {
+		t.Fatalf("unexpected blob count: %d", n)
+	}
+}
+
+// Ensure digests that could escape the directory of the store are rejected.
+func TestLocalStore_Get_InvalidDigest(t *testing.T) {
+	s := MustOpenLocalStore()
+	defer os.RemoveAll(s.Path())
+
+	if _, err := s.Get("../../etc/passwd"); err != blob.ErrInvalidDigest {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// MustOpenLocalStore returns a local store in a temporary directory.
+func MustOpenLocalStore() *blob.LocalStore {
+	path, err := ioutil.TempDir("", "messagedb-blob-")
+	if err != nil {
+		panic(err)
+	}
+	s := blob.NewLocalStore(path)
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+// Package blob provides content-addressed storage for the files attached to messages.
+package blob
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrBlobNotFound is returned when reading a blob that doesn't exist.
+	ErrBlobNotFound = errors.New("blob not found")
+
+	// ErrTooLarge is returned when the content written to the store exceeds the size limit.
+	ErrTooLarge = errors.New("blob too large")
+
+	// ErrInvalidDigest is returned when a digest is not a hex encoded SHA256.
+	ErrInvalidDigest = errors.New("invalid digest")
+)
+
+// Info describes the content of a blob.
+type Info struct {
+	SHA256 string // hex encoded, used as the address of the blob
+	MD5    string // hex encoded
+	Size   int64
+}
+
+// ReadSeekCloser is the interface of an opened blob.
+type ReadSeekCloser interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// Store is the interface of a blob store. Blobs are addressed by the SHA256 of their content,
+// so storing the same content twice only keeps one copy.
+type Store interface {
+	// Put stores the content read from r. Returns ErrTooLarge if r holds more than limit bytes.
+	// A limit of zero or less doesn't limit the size.
+	Put(r io.Reader, limit int64) (*Info, error)
+
+	// Get returns the content of a blob. Returns ErrBlobNotFound if the blob doesn't exist.
+	Get(sha256 string) (ReadSeekCloser, error)
+
+	// Delete removes a blob. Deleting a blob that doesn't exist is not an error.
+	Delete(sha256 string) error
+
+	// Walk calls fn with the digest and the modification time of each of the blobs.
+	Walk(fn func(sha256 string, modTime time.Time) error) error
+}
+
+// validDigest returns true if s is a lowercase hex encoded SHA256.
+func validDigest(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
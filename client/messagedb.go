@@ -136,7 +136,7 @@ func (c *Client) Write(bp BatchPoints) (*Response, error) {
 				p.Tags[k] = v
 			}
 
-			if _, err := b.WriteString(p.MarshalString()); err != nil {
+			if _, err := b.WriteString(p.message().PrecisionString(bp.Precision)); err != nil {
 				return nil, err
 			}
 		}
@@ -375,10 +375,35 @@ func (p *Point) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&point)
 }
 
+// MarshalString encodes the point as a message in the line protocol. The
+// measurement is the conversation key, the id, from and from_name tags set the
+// message ID and sender, the parent tag sets the message replied to, and the
+// text and html fields set the content.
+// Mentions are read from the mentions field as a []db.Mention.
 func (p *Point) MarshalString() string {
-	// return db.NewPoint(p.Measurement, p.Tags, p.Fields, p.Time).String()
-	//TODO: fix this
-	return db.NewMessage(p.Time).String()
+	return p.message().PrecisionString(p.Precision)
+}
+
+func (p *Point) message() db.Message {
+	var content db.Content
+	if v, ok := p.Fields["text"]; ok {
+		content.PlainText = fmt.Sprint(v)
+	}
+	if v, ok := p.Fields["html"]; ok {
+		content.HTML = fmt.Sprint(v)
+	}
+	mentions, _ := p.Fields["mentions"].([]db.Mention)
+
+	m := db.NewMessage(
+		p.Measurement,
+		db.Sender{UserID: p.Tags["from"], Name: p.Tags["from_name"]},
+		content,
+		mentions,
+		p.Time,
+	)
+	m.SetID(p.Tags["id"])
+	m.SetParentID(p.Tags["parent"])
+	return m
 }
 
 // UnmarshalJSON decodes the data into the Point struct
@@ -12,11 +12,18 @@ It has these top-level messages:
 	WriteShardRequest
 	From
 	Content
+	Snippet
 	Mention
 	Message
+	Field
 	WriteShardResponse
 	MapShardRequest
 	MapShardResponse
+	Event
+	PublishRequest
+	PublishResponse
+	Reaction
+	WriteReactionsRequest
 */
 package internal
 
@@ -99,6 +106,38 @@ func (m *Content) GetHTML() string {
 	return ""
 }
 
+type Snippet struct {
+	Language         *string `protobuf:"bytes,1,opt" json:"Language,omitempty"`
+	Filename         *string `protobuf:"bytes,2,opt" json:"Filename,omitempty"`
+	Body             *string `protobuf:"bytes,3,req" json:"Body,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Snippet) Reset()         { *m = Snippet{} }
+func (m *Snippet) String() string { return proto.CompactTextString(m) }
+func (*Snippet) ProtoMessage()    {}
+
+func (m *Snippet) GetLanguage() string {
+	if m != nil && m.Language != nil {
+		return *m.Language
+	}
+	return ""
+}
+
+func (m *Snippet) GetFilename() string {
+	if m != nil && m.Filename != nil {
+		return *m.Filename
+	}
+	return ""
+}
+
+func (m *Snippet) GetBody() string {
+	if m != nil && m.Body != nil {
+		return *m.Body
+	}
+	return ""
+}
+
 type Mention struct {
 	RecipientID       *string `protobuf:"bytes,1,req" json:"RecipientID,omitempty"`
 	RecipientUsername *string `protobuf:"bytes,2,req" json:"RecipientUsername,omitempty"`
@@ -124,12 +163,20 @@ func (m *Mention) GetRecipientUsername() string {
 }
 
 type Message struct {
-	Id               *string  `protobuf:"bytes,1,opt" json:"Id,omitempty"`
-	Time             *int64   `protobuf:"varint,2,req" json:"Time,omitempty"`
-	From             *From    `protobuf:"bytes,3,opt" json:"From,omitempty"`
-	Content          *Content `protobuf:"bytes,4,opt" json:"Content,omitempty"`
-	Mentions         *Mention `protobuf:"bytes,5,opt" json:"Mentions,omitempty"`
-	XXX_unrecognized []byte   `json:"-"`
+	Id               *string    `protobuf:"bytes,1,opt" json:"Id,omitempty"`
+	Time             *int64     `protobuf:"varint,2,req" json:"Time,omitempty"`
+	From             *From      `protobuf:"bytes,3,opt" json:"From,omitempty"`
+	Content          *Content   `protobuf:"bytes,4,opt" json:"Content,omitempty"`
+	Mentions         []*Mention `protobuf:"bytes,5,rep" json:"Mentions,omitempty"`
+	Conversation     *string    `protobuf:"bytes,6,req" json:"Conversation,omitempty"`
+	EditedAt         *int64     `protobuf:"varint,7,opt" json:"EditedAt,omitempty"`
+	Deleted          *bool      `protobuf:"varint,8,opt" json:"Deleted,omitempty"`
+	Fields           []*Field   `protobuf:"bytes,9,rep" json:"Fields,omitempty"`
+	EditedBy         *string    `protobuf:"bytes,10,opt" json:"EditedBy,omitempty"`
+	ParentID         *string    `protobuf:"bytes,11,opt" json:"ParentID,omitempty"`
+	Snippet          *Snippet   `protobuf:"bytes,12,opt" json:"Snippet,omitempty"`
+	Event            *string    `protobuf:"bytes,13,opt" json:"Event,omitempty"`
+	XXX_unrecognized []byte     `json:"-"`
 }
 
 func (m *Message) Reset()         { *m = Message{} }
@@ -164,13 +211,117 @@ func (m *Message) GetContent() *Content {
 	return nil
 }
 
-func (m *Message) GetMentions() *Mention {
+func (m *Message) GetMentions() []*Mention {
 	if m != nil {
 		return m.Mentions
 	}
 	return nil
 }
 
+func (m *Message) GetConversation() string {
+	if m != nil && m.Conversation != nil {
+		return *m.Conversation
+	}
+	return ""
+}
+
+func (m *Message) GetEditedAt() int64 {
+	if m != nil && m.EditedAt != nil {
+		return *m.EditedAt
+	}
+	return 0
+}
+
+func (m *Message) GetDeleted() bool {
+	if m != nil && m.Deleted != nil {
+		return *m.Deleted
+	}
+	return false
+}
+
+func (m *Message) GetFields() []*Field {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+func (m *Message) GetEditedBy() string {
+	if m != nil && m.EditedBy != nil {
+		return *m.EditedBy
+	}
+	return ""
+}
+
+func (m *Message) GetParentID() string {
+	if m != nil && m.ParentID != nil {
+		return *m.ParentID
+	}
+	return ""
+}
+
+func (m *Message) GetSnippet() *Snippet {
+	if m != nil {
+		return m.Snippet
+	}
+	return nil
+}
+
+func (m *Message) GetEvent() string {
+	if m != nil && m.Event != nil {
+		return *m.Event
+	}
+	return ""
+}
+
+type Field struct {
+	Name             *string  `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Int64            *int64   `protobuf:"varint,2,opt" json:"Int64,omitempty"`
+	Float64          *float64 `protobuf:"fixed64,3,opt" json:"Float64,omitempty"`
+	Bool             *bool    `protobuf:"varint,4,opt" json:"Bool,omitempty"`
+	String_          *string  `protobuf:"bytes,5,opt,name=String" json:"String,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *Field) Reset()         { *m = Field{} }
+func (m *Field) String() string { return proto.CompactTextString(m) }
+func (*Field) ProtoMessage()    {}
+
+func (m *Field) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *Field) GetInt64() int64 {
+	if m != nil && m.Int64 != nil {
+		return *m.Int64
+	}
+	return 0
+}
+
+func (m *Field) GetFloat64() float64 {
+	if m != nil && m.Float64 != nil {
+		return *m.Float64
+	}
+	return 0
+}
+
+func (m *Field) GetBool() bool {
+	if m != nil && m.Bool != nil {
+		return *m.Bool
+	}
+	return false
+}
+
+func (m *Field) GetString_() string {
+	if m != nil && m.String_ != nil {
+		return *m.String_
+	}
+	return ""
+}
+
 type WriteShardResponse struct {
 	Code             *int32  `protobuf:"varint,1,req" json:"Code,omitempty"`
 	Message          *string `protobuf:"bytes,2,opt" json:"Message,omitempty"`
@@ -259,5 +410,197 @@ func (m *MapShardResponse) GetData() []byte {
 	return nil
 }
 
+type Event struct {
+	Type             *string  `protobuf:"bytes,1,req" json:"Type,omitempty"`
+	Database         *string  `protobuf:"bytes,2,req" json:"Database,omitempty"`
+	Conversation     *string  `protobuf:"bytes,3,req" json:"Conversation,omitempty"`
+	UserID           *string  `protobuf:"bytes,4,opt" json:"UserID,omitempty"`
+	Time             *int64   `protobuf:"varint,5,req" json:"Time,omitempty"`
+	Message          *Message `protobuf:"bytes,6,opt" json:"Message,omitempty"`
+	MessageID        *string  `protobuf:"bytes,7,opt" json:"MessageID,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+
+func (m *Event) GetType() string {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return ""
+}
+
+func (m *Event) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *Event) GetConversation() string {
+	if m != nil && m.Conversation != nil {
+		return *m.Conversation
+	}
+	return ""
+}
+
+func (m *Event) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *Event) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+func (m *Event) GetMessage() *Message {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (m *Event) GetMessageID() string {
+	if m != nil && m.MessageID != nil {
+		return *m.MessageID
+	}
+	return ""
+}
+
+type PublishRequest struct {
+	Events           []*Event `protobuf:"bytes,1,rep" json:"Events,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *PublishRequest) Reset()         { *m = PublishRequest{} }
+func (m *PublishRequest) String() string { return proto.CompactTextString(m) }
+func (*PublishRequest) ProtoMessage()    {}
+
+func (m *PublishRequest) GetEvents() []*Event {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type PublishResponse struct {
+	Code             *int32  `protobuf:"varint,1,req" json:"Code,omitempty"`
+	Message          *string `protobuf:"bytes,2,opt" json:"Message,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *PublishResponse) Reset()         { *m = PublishResponse{} }
+func (m *PublishResponse) String() string { return proto.CompactTextString(m) }
+func (*PublishResponse) ProtoMessage()    {}
+
+func (m *PublishResponse) GetCode() int32 {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return 0
+}
+
+func (m *PublishResponse) GetMessage() string {
+	if m != nil && m.Message != nil {
+		return *m.Message
+	}
+	return ""
+}
+
+type Reaction struct {
+	Conversation     *string `protobuf:"bytes,1,req" json:"Conversation,omitempty"`
+	Timestamp        *int64  `protobuf:"varint,2,req" json:"Timestamp,omitempty"`
+	MessageID        *string `protobuf:"bytes,3,req" json:"MessageID,omitempty"`
+	Emoji            *string `protobuf:"bytes,4,req" json:"Emoji,omitempty"`
+	UserID           *string `protobuf:"bytes,5,req" json:"UserID,omitempty"`
+	Time             *int64  `protobuf:"varint,6,req" json:"Time,omitempty"`
+	Removed          *bool   `protobuf:"varint,7,opt" json:"Removed,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *Reaction) Reset()         { *m = Reaction{} }
+func (m *Reaction) String() string { return proto.CompactTextString(m) }
+func (*Reaction) ProtoMessage()    {}
+
+func (m *Reaction) GetConversation() string {
+	if m != nil && m.Conversation != nil {
+		return *m.Conversation
+	}
+	return ""
+}
+
+func (m *Reaction) GetTimestamp() int64 {
+	if m != nil && m.Timestamp != nil {
+		return *m.Timestamp
+	}
+	return 0
+}
+
+func (m *Reaction) GetMessageID() string {
+	if m != nil && m.MessageID != nil {
+		return *m.MessageID
+	}
+	return ""
+}
+
+func (m *Reaction) GetEmoji() string {
+	if m != nil && m.Emoji != nil {
+		return *m.Emoji
+	}
+	return ""
+}
+
+func (m *Reaction) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *Reaction) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+func (m *Reaction) GetRemoved() bool {
+	if m != nil && m.Removed != nil {
+		return *m.Removed
+	}
+	return false
+}
+
+type WriteReactionsRequest struct {
+	ShardID          *uint64     `protobuf:"varint,1,req" json:"ShardID,omitempty"`
+	Reactions        []*Reaction `protobuf:"bytes,2,rep" json:"Reactions,omitempty"`
+	XXX_unrecognized []byte      `json:"-"`
+}
+
+func (m *WriteReactionsRequest) Reset()         { *m = WriteReactionsRequest{} }
+func (m *WriteReactionsRequest) String() string { return proto.CompactTextString(m) }
+func (*WriteReactionsRequest) ProtoMessage()    {}
+
+func (m *WriteReactionsRequest) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *WriteReactionsRequest) GetReactions() []*Reaction {
+	if m != nil {
+		return m.Reactions
+	}
+	return nil
+}
+
 func init() {
 }
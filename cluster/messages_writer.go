@@ -81,15 +81,23 @@ type MessagesWriter struct {
 	DataStore interface {
 		CreateShard(database, retentionPolicy string, shardID uint64) error
 		WriteToShard(shardID uint64, messages []db.Message) error
+		WriteReactionsToShard(shardID uint64, reactions []db.Reaction) error
 	}
 
 	ShardWriter interface {
 		WriteShard(shardID, ownerID uint64, points []db.Message) error
+		WriteShardReactions(shardID, ownerID uint64, reactions []db.Reaction) error
 	}
 
 	HintedHandoff interface {
 		WriteShard(shardID, ownerID uint64, points []db.Message) error
 	}
+
+	// Publisher is notified of the messages of accepted writes so they can be
+	// delivered to real-time subscribers.
+	Publisher interface {
+		PublishMessages(database string, messages []db.Message)
+	}
 }
 
 // NewMessagesWriter returns a new instance of MessagesWriter for a node.
@@ -183,13 +191,11 @@ func (w *MessagesWriter) MapShards(wp *WriteMessagesRequest) (*ShardMapping, err
 // WriteMessages writes across multiple local and remote data nodes according the consistency level.
 func (w *MessagesWriter) WriteMessages(m *WriteMessagesRequest) error {
 	if m.RetentionPolicy == "" {
-		db, err := w.MetaStore.Database(m.Database)
+		policy, err := w.defaultRetentionPolicy(m.Database)
 		if err != nil {
 			return err
-		} else if db == nil {
-			return messagedb.ErrDatabaseNotFound(m.Database)
 		}
-		m.RetentionPolicy = db.DefaultRetentionPolicy
+		m.RetentionPolicy = policy
 	}
 
 	shardMappings, err := w.MapShards(m)
@@ -216,13 +222,116 @@ func (w *MessagesWriter) WriteMessages(m *WriteMessagesRequest) error {
 			}
 		}
 	}
+
+	// Publish the accepted writes.
+	if w.Publisher != nil {
+		w.Publisher.PublishMessages(m.Database, m.Messages)
+	}
+	return nil
+}
+
+// WriteReactions writes reactions to the owners of the shards storing the messages they react to, according to
+// the consistency level. Reactions are not queued for hinted handoff, so an owner that cannot be reached does
+// not count towards the consistency level.
+func (w *MessagesWriter) WriteReactions(database string, consistency ConsistencyLevel, reactions []db.Reaction) error {
+	retentionPolicy, err := w.defaultRetentionPolicy(database)
+	if err != nil {
+		return err
+	}
+	rp, err := w.MetaStore.RetentionPolicy(database, retentionPolicy)
+	if err != nil {
+		return err
+	}
+
+	// Reactions belong to the shard of their message, in the shard group of the message timestamp.
+	shards := make(map[uint64]*meta.ShardInfo)
+	mapping := make(map[uint64][]db.Reaction)
+	for _, r := range reactions {
+		sg, err := w.MetaStore.CreateShardGroupIfNotExists(database, retentionPolicy, time.Unix(0, r.Timestamp).Truncate(rp.ShardGroupDuration))
+		if err != nil {
+			return err
+		}
+		sh := sg.ShardFor(r.HashID())
+		shards[sh.ID] = &sh
+		mapping[sh.ID] = append(mapping[sh.ID], r)
+	}
+
+	// Write each shard in it's own goroutine and return as soon
+	// as one fails.
+	ch := make(chan error, len(mapping))
+	for shardID, reactions := range mapping {
+		go func(shard *meta.ShardInfo, reactions []db.Reaction) {
+			ch <- w.writeToOwners(shard, consistency, func(nodeID uint64) error {
+				if w.MetaStore.NodeID() == nodeID {
+					return w.DataStore.WriteReactionsToShard(shard.ID, reactions)
+				}
+				return w.ShardWriter.WriteShardReactions(shard.ID, nodeID, reactions)
+			})
+		}(shards[shardID], reactions)
+	}
+
+	for range mapping {
+		select {
+		case <-w.closing:
+			return ErrWriteFailed
+		case err := <-ch:
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// defaultRetentionPolicy returns the name of the default retention policy of a database.
+func (w *MessagesWriter) defaultRetentionPolicy(database string) (string, error) {
+	di, err := w.MetaStore.Database(database)
+	if err != nil {
+		return "", err
+	} else if di == nil {
+		return "", messagedb.ErrDatabaseNotFound(database)
+	}
+	return di.DefaultRetentionPolicy, nil
+}
+
 // writeToShards writes points to a shard and ensures a write consistency level has been met.  If the write
 // partially succeeds, ErrPartialWrite is returned.
 func (w *MessagesWriter) writeToShard(shard *meta.ShardInfo, database, retentionPolicy string,
 	consistency ConsistencyLevel, messages []db.Message) error {
+	return w.writeToOwners(shard, consistency, func(nodeID uint64) error {
+		if w.MetaStore.NodeID() == nodeID {
+			err := w.DataStore.WriteToShard(shard.ID, messages)
+			// If we've written to shard that should exist on the current node, but the store has
+			// not actually created this shard, tell it to create it and retry the write
+			if err == db.ErrShardNotFound {
+				err = w.DataStore.CreateShard(database, retentionPolicy, shard.ID)
+				if err != nil {
+					return err
+				}
+				err = w.DataStore.WriteToShard(shard.ID, messages)
+			}
+			return err
+		}
+
+		err := w.ShardWriter.WriteShard(shard.ID, nodeID, messages)
+		if err != nil && db.IsRetryable(err) {
+			// The remote write failed so queue it via hinted handoff
+			hherr := w.HintedHandoff.WriteShard(shard.ID, nodeID, messages)
+
+			// If the write consistency level is ANY, then a successful hinted handoff can
+			// be considered a successful write so return nil, otherwise let the original
+			// error propogate
+			if hherr == nil && consistency == ConsistencyLevelAny {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+// writeToOwners writes to each owner of a shard with write and ensures a write consistency level has been
+// met. If the write partially succeeds, ErrPartialWrite is returned.
+func (w *MessagesWriter) writeToOwners(shard *meta.ShardInfo, consistency ConsistencyLevel, write func(nodeID uint64) error) error {
 	// The required number of writes to achieve the requested consistency level
 	required := len(shard.OwnerIDs)
 	switch consistency {
@@ -236,39 +345,9 @@ func (w *MessagesWriter) writeToShard(shard *meta.ShardInfo, database, retention
 	ch := make(chan error, len(shard.OwnerIDs))
 
 	for _, nodeID := range shard.OwnerIDs {
-		go func(shardID, nodeID uint64, messages []db.Message) {
-			if w.MetaStore.NodeID() == nodeID {
-				err := w.DataStore.WriteToShard(shardID, messages)
-				// If we've written to shard that should exist on the current node, but the store has
-				// not actually created this shard, tell it to create it and retry the write
-				if err == db.ErrShardNotFound {
-					err = w.DataStore.CreateShard(database, retentionPolicy, shardID)
-					if err != nil {
-						ch <- err
-						return
-					}
-					err = w.DataStore.WriteToShard(shardID, messages)
-				}
-				ch <- err
-				return
-			}
-
-			err := w.ShardWriter.WriteShard(shardID, nodeID, messages)
-			if err != nil && db.IsRetryable(err) {
-				// The remote write failed so queue it via hinted handoff
-				hherr := w.HintedHandoff.WriteShard(shardID, nodeID, messages)
-
-				// If the write consistency level is ANY, then a successful hinted handoff can
-				// be considered a successful write so send nil to the response channel
-				// otherwise, let the original error propogate to the response channel
-				if hherr == nil && consistency == ConsistencyLevelAny {
-					ch <- nil
-					return
-				}
-			}
-			ch <- err
-
-		}(shard.ID, nodeID, messages)
+		go func(nodeID uint64) {
+			ch <- write(nodeID)
+		}(nodeID)
 	}
 
 	var wrote int
@@ -294,7 +294,13 @@ func TestMessagesWriter_WriteMessages(t *testing.T) {
 		c.DataStore = store
 		c.HintedHandoff = hh
 
+		pub := &fakePublisher{}
+		c.Publisher = pub
+
 		err := c.WriteMessages(pr)
+		if published := len(pub.messages) == len(pr.Messages); published != (err == nil) {
+			t.Errorf("MessagesWriter.WriteMessages(): '%s' published: got %v, exp %v", test.name, published, err == nil)
+		}
 		if err == nil && test.expErr != nil {
 			t.Errorf("MessagesWriter.WriteMessages(): '%s' error: got %v, exp %v", test.name, err, test.expErr)
 		}
@@ -308,25 +314,107 @@ func TestMessagesWriter_WriteMessages(t *testing.T) {
 	}
 }
 
+// Ensure reactions are written to the owners of the shards of their messages.
+func TestMessagesWriter_WriteReactions(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		consistency cluster.ConsistencyLevel
+		err         []error // the responses returned by each shard write call, node ID 1 = pos 0
+		expErr      error
+	}{
+		{name: "write one success", consistency: cluster.ConsistencyLevelOne, err: []error{fmt.Errorf("a failure"), nil, fmt.Errorf("a failure")}},
+		{name: "write quorum, 1/3 failure", consistency: cluster.ConsistencyLevelQuorum, err: []error{fmt.Errorf("a failure"), fmt.Errorf("a failure"), nil}, expErr: cluster.ErrPartialWrite},
+		{name: "no hinted handoff with any", consistency: cluster.ConsistencyLevelAny, err: []error{fmt.Errorf("a failure"), fmt.Errorf("a failure"), fmt.Errorf("a failure")}, expErr: fmt.Errorf("write failed: a failure")},
+	} {
+		theTest := test
+
+		// Two reactions to messages an hour apart map to distinct shards.
+		reactions := []db.Reaction{
+			{Conversation: "general", Timestamp: 0, MessageID: "m1", Emoji: "+1", UserID: "u1", Time: time.Unix(10, 0)},
+			{Conversation: "general", Timestamp: int64(time.Hour), MessageID: "m2", Emoji: "+1", UserID: "u1", Time: time.Unix(10, 0)},
+		}
+
+		var mu sync.Mutex
+		written := make(map[uint64][]uint64)
+		write := func(shardID, nodeID uint64, reactions []db.Reaction) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if theTest.err[int(nodeID)-1] != nil {
+				return theTest.err[int(nodeID)-1]
+			}
+			written[shardID] = append(written[shardID], nodeID)
+			return nil
+		}
+		sw := &fakeShardWriter{ShardWriteReactionsFn: write}
+		store := &fakeStore{
+			WriteReactionsFn: func(shardID uint64, reactions []db.Reaction) error {
+				return write(shardID, 1, reactions)
+			},
+		}
+
+		ms := NewMetaStore()
+		ms.DatabaseFn = func(database string) (*meta.DatabaseInfo, error) {
+			return &meta.DatabaseInfo{Name: database, DefaultRetentionPolicy: "myrp"}, nil
+		}
+		ms.NodeIDFn = func() uint64 { return 1 }
+		c := cluster.NewMessagesWriter()
+		c.MetaStore = ms
+		c.ShardWriter = sw
+		c.DataStore = store
+		c.HintedHandoff = &fakeShardWriter{
+			ShardWriteFn: func(shardID, nodeID uint64, messages []db.Message) error {
+				t.Errorf("%s: unexpected hinted handoff write", test.name)
+				return nil
+			},
+		}
+
+		err := c.WriteReactions("mydb", test.consistency, reactions)
+		if fmt.Sprint(err) != fmt.Sprint(test.expErr) {
+			t.Errorf("MessagesWriter.WriteReactions(): '%s' error: got %v, exp %v", test.name, err, test.expErr)
+		}
+		if err == nil && len(written) != 2 {
+			t.Errorf("MessagesWriter.WriteReactions(): '%s' shards: got %v, exp 2 shards", test.name, written)
+		}
+	}
+}
+
 var shardID uint64
 
 type fakeShardWriter struct {
-	ShardWriteFn func(shardID, nodeID uint64, messages []db.Message) error
+	ShardWriteFn          func(shardID, nodeID uint64, messages []db.Message) error
+	ShardWriteReactionsFn func(shardID, nodeID uint64, reactions []db.Reaction) error
 }
 
 func (f *fakeShardWriter) WriteShard(shardID, nodeID uint64, messages []db.Message) error {
 	return f.ShardWriteFn(shardID, nodeID, messages)
 }
 
+func (f *fakeShardWriter) WriteShardReactions(shardID, nodeID uint64, reactions []db.Reaction) error {
+	return f.ShardWriteReactionsFn(shardID, nodeID, reactions)
+}
+
+type fakePublisher struct {
+	messages []db.Message
+}
+
+func (f *fakePublisher) PublishMessages(database string, messages []db.Message) {
+	f.messages = append(f.messages, messages...)
+}
+
 type fakeStore struct {
-	WriteFn       func(shardID uint64, messages []db.Message) error
-	CreateShardfn func(database, retentionPolicy string, shardID uint64) error
+	WriteFn          func(shardID uint64, messages []db.Message) error
+	WriteReactionsFn func(shardID uint64, reactions []db.Reaction) error
+	CreateShardfn    func(database, retentionPolicy string, shardID uint64) error
 }
 
 func (f *fakeStore) WriteToShard(shardID uint64, messages []db.Message) error {
 	return f.WriteFn(shardID, messages)
 }
 
+func (f *fakeStore) WriteReactionsToShard(shardID uint64, reactions []db.Reaction) error {
+	return f.WriteReactionsFn(shardID, reactions)
+}
+
 func (f *fakeStore) CreateShard(database, retentionPolicy string, shardID uint64) error {
 	return f.CreateShardfn(database, retentionPolicy, shardID)
 }
@@ -0,0 +1,347 @@
+package cluster
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/messagedb/messagedb/db"
+	"github.com/messagedb/messagedb/meta"
+
+	"gopkg.in/fatih/pool.v2"
+)
+
+// DefaultSubscriptionBufferSize is the number of events buffered for a subscriber
+// before it is considered too slow and its subscription is closed.
+const DefaultSubscriptionBufferSize = 256
+
+// EventType identifies the kind of a real-time event.
+type EventType string
+
+const (
+	// EventMessageCreated is published when a message is sent to a conversation.
+	EventMessageCreated EventType = "message.created"
+
+	// EventMessageEdited is published when a message is edited.
+	EventMessageEdited EventType = "message.edited"
+
+	// EventMessageDeleted is published when a message is deleted.
+	EventMessageDeleted EventType = "message.deleted"
+
+	// EventTyping is published when a user is typing in a conversation.
+	EventTyping EventType = "typing"
+
+	// EventPulse is published periodically by the users active in a conversation.
+	EventPulse EventType = "pulse"
+
+	// EventMessageRead is published when a user marks a conversation read up to a message.
+	EventMessageRead EventType = "message.read"
+)
+
+// Event represents a real-time event of a conversation delivered to its subscribers.
+type Event struct {
+	Type         EventType
+	Database     string
+	Conversation string
+	UserID       string
+	Time         time.Time
+	Message      db.Message // set for message events only
+	MessageID    string     // set for read events only
+}
+
+// NewMessageEvent returns the event published for an accepted message write.
+func NewMessageEvent(database string, m db.Message) *Event {
+	e := &Event{
+		Type:         EventMessageCreated,
+		Database:     database,
+		Conversation: string(m.Key()),
+		UserID:       m.From().UserID,
+		Time:         m.Time(),
+		Message:      m,
+	}
+	if m.Deleted() {
+		e.Type = EventMessageDeleted
+	} else if !m.EditedAt().IsZero() {
+		e.Type = EventMessageEdited
+	}
+
+	// Edits and deletions are attributed to the editor at the time of the change.
+	if e.Type != EventMessageCreated {
+		if by := m.EditedBy(); by != "" {
+			e.UserID = by
+		}
+		if t := m.EditedAt(); !t.IsZero() {
+			e.Time = t
+		}
+	}
+	return e
+}
+
+// Hub delivers events to the subscribers of the local node.
+type Hub struct {
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+
+	// Number of events buffered for each subscriber.
+	BufferSize int
+}
+
+// NewHub returns a new instance of Hub.
+func NewHub() *Hub {
+	return &Hub{
+		subscriptions: make(map[*Subscription]struct{}),
+		BufferSize:    DefaultSubscriptionBufferSize,
+	}
+}
+
+// Subscribe returns a new subscription to the events of a database. The subscription
+// receives no events until conversations are added to it.
+func (h *Hub) Subscribe(database string) *Subscription {
+	s := &Subscription{
+		hub:           h,
+		database:      database,
+		conversations: make(map[string]struct{}),
+		c:             make(chan *Event, h.BufferSize),
+	}
+	s.C = s.c
+
+	h.mu.Lock()
+	h.subscriptions[s] = struct{}{}
+	h.mu.Unlock()
+
+	return s
+}
+
+// Publish delivers events to the local subscribers of their conversation. Subscribers
+// that cannot keep up have their subscription closed so they can resynchronize.
+func (h *Hub) Publish(events ...*Event) {
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for s := range h.subscriptions {
+		for _, e := range events {
+			if !s.send(e) {
+				slow = append(slow, s)
+				break
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		s.Close()
+	}
+}
+
+// SubscriberN returns the number of open subscriptions.
+func (h *Hub) SubscriberN() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscriptions)
+}
+
+// Subscription receives the events of the conversations added to it. C is closed
+// when the subscription is closed.
+type Subscription struct {
+	mu            sync.Mutex
+	hub           *Hub
+	database      string
+	conversations map[string]struct{}
+	c             chan *Event
+	closed        bool
+
+	C <-chan *Event
+}
+
+// Add subscribes to the events of a conversation.
+func (s *Subscription) Add(conversation string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conversations[conversation] = struct{}{}
+}
+
+// Remove unsubscribes from the events of a conversation.
+func (s *Subscription) Remove(conversation string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conversations, conversation)
+}
+
+// Has returns true if the subscription includes a conversation.
+func (s *Subscription) Has(conversation string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.conversations[conversation]
+	return ok
+}
+
+// Close removes the subscription from the hub and closes its channel.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	delete(s.hub.subscriptions, s)
+	s.hub.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.c)
+	}
+}
+
+// send queues the event if the subscription matches it. Returns false if the buffer is full.
+func (s *Subscription) send(e *Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || e.Database != s.database {
+		return true
+	}
+	if _, ok := s.conversations[e.Conversation]; !ok {
+		return true
+	}
+
+	select {
+	case s.c <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+// Publisher delivers events to the local subscribers and forwards them to the other
+// nodes of the cluster. Events are ephemeral so forwarding is best effort.
+type Publisher struct {
+	pool    *clientPool
+	timeout time.Duration
+
+	Hub interface {
+		Publish(events ...*Event)
+	}
+
+	MetaStore interface {
+		NodeID() uint64
+		Node(id uint64) (ni *meta.NodeInfo, err error)
+		Nodes() ([]meta.NodeInfo, error)
+	}
+
+	Logger *log.Logger
+}
+
+// NewPublisher returns a new instance of Publisher.
+func NewPublisher(timeout time.Duration) *Publisher {
+	return &Publisher{
+		pool:    newClientPool(),
+		timeout: timeout,
+		Logger:  log.New(os.Stderr, "[publish] ", log.LstdFlags),
+	}
+}
+
+// Publish delivers events to the subscribers of every node in the cluster.
+func (p *Publisher) Publish(events ...*Event) {
+	if len(events) == 0 {
+		return
+	}
+	p.Hub.Publish(events...)
+
+	nodes, err := p.MetaStore.Nodes()
+	if err != nil {
+		p.Logger.Printf("publish failed to list nodes: %s", err)
+		return
+	}
+	for _, n := range nodes {
+		if n.ID == p.MetaStore.NodeID() {
+			continue
+		}
+		go func(nodeID uint64) {
+			if err := p.PublishNode(nodeID, events); err != nil {
+				p.Logger.Printf("publish failed for node %d: %s", nodeID, err)
+			}
+		}(n.ID)
+	}
+}
+
+// PublishMessages publishes the events of accepted message writes.
+func (p *Publisher) PublishMessages(database string, messages []db.Message) {
+	events := make([]*Event, len(messages))
+	for i, m := range messages {
+		events[i] = NewMessageEvent(database, m)
+	}
+	p.Publish(events...)
+}
+
+// PublishNode sends events to the subscribers of a remote node.
+func (p *Publisher) PublishNode(nodeID uint64, events []*Event) error {
+	c, err := p.dial(nodeID)
+	if err != nil {
+		return err
+	}
+
+	conn, ok := c.(*pool.PoolConn)
+	if !ok {
+		panic("wrong connection type")
+	}
+	defer conn.Close() // return to pool
+
+	// Build and marshal the request.
+	var request PublishRequest
+	request.AddEvents(events)
+	buf, err := request.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	// Write request.
+	conn.SetWriteDeadline(time.Now().Add(p.timeout))
+	if err := WriteTLV(conn, publishRequestMessage, buf); err != nil {
+		conn.MarkUnusable()
+		return err
+	}
+
+	// Read the response.
+	conn.SetReadDeadline(time.Now().Add(p.timeout))
+	_, buf, err = ReadTLV(conn)
+	if err != nil {
+		conn.MarkUnusable()
+		return err
+	}
+
+	var response PublishResponse
+	if err := response.UnmarshalBinary(buf); err != nil {
+		return err
+	}
+	if response.Code() != 0 {
+		return fmt.Errorf("error code %d: %s", response.Code(), response.Message())
+	}
+
+	return nil
+}
+
+func (p *Publisher) dial(nodeID uint64) (net.Conn, error) {
+	// If we don't have a connection pool for that addr yet, create one
+	_, ok := p.pool.getPool(nodeID)
+	if !ok {
+		factory := &connFactory{nodeID: nodeID, clientPool: p.pool, timeout: p.timeout}
+		factory.metaStore = p.MetaStore
+
+		cp, err := pool.NewChannelPool(1, 3, factory.dial)
+		if err != nil {
+			return nil, err
+		}
+		p.pool.setPool(nodeID, cp)
+	}
+	return p.pool.conn(nodeID)
+}
+
+// Close closes the connections to the other nodes.
+func (p *Publisher) Close() error {
+	if p.pool == nil {
+		return fmt.Errorf("client already closed")
+	}
+	p.pool.close()
+	p.pool = nil
+	return nil
+}
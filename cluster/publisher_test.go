@@ -0,0 +1,133 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/cluster"
+	"github.com/messagedb/messagedb/db"
+	"github.com/messagedb/messagedb/meta"
+)
+
+// Ensure the hub only delivers the events of subscribed conversations.
+func TestHub_Publish(t *testing.T) {
+	hub := cluster.NewHub()
+	sub := hub.Subscribe("db0")
+	defer sub.Close()
+	sub.Add("general")
+
+	hub.Publish(
+		&cluster.Event{Type: cluster.EventTyping, Database: "db0", Conversation: "random", UserID: "1"},
+		&cluster.Event{Type: cluster.EventTyping, Database: "db1", Conversation: "general", UserID: "2"},
+		&cluster.Event{Type: cluster.EventTyping, Database: "db0", Conversation: "general", UserID: "3"},
+	)
+
+	select {
+	case e := <-sub.C:
+		if e.UserID != "3" {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+	default:
+		t.Fatal("expected event")
+	}
+	select {
+	case e := <-sub.C:
+		t.Fatalf("unexpected event: %#v", e)
+	default:
+	}
+
+	// No more events after unsubscribing from the conversation.
+	sub.Remove("general")
+	hub.Publish(&cluster.Event{Type: cluster.EventPulse, Database: "db0", Conversation: "general", UserID: "3"})
+	select {
+	case e := <-sub.C:
+		t.Fatalf("unexpected event: %#v", e)
+	default:
+	}
+}
+
+// Ensure subscribers that cannot keep up have their subscription closed.
+func TestHub_Publish_SlowSubscriber(t *testing.T) {
+	hub := cluster.NewHub()
+	hub.BufferSize = 1
+	sub := hub.Subscribe("db0")
+	sub.Add("general")
+
+	e := &cluster.Event{Type: cluster.EventTyping, Database: "db0", Conversation: "general", UserID: "1"}
+	hub.Publish(e, e)
+
+	if _, ok := <-sub.C; !ok {
+		t.Fatal("expected buffered event")
+	} else if _, ok := <-sub.C; ok {
+		t.Fatal("expected closed subscription")
+	} else if n := hub.SubscriberN(); n != 0 {
+		t.Fatalf("unexpected subscriber count: %d", n)
+	}
+}
+
+// Ensure message writes are published with the type of the change.
+func TestNewMessageEvent(t *testing.T) {
+	m := db.NewMessage("general", db.Sender{UserID: "1"}, db.Content{PlainText: "hello"}, nil, time.Unix(1, 0))
+	if e := cluster.NewMessageEvent("db0", m); e.Type != cluster.EventMessageCreated || e.UserID != "1" {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+
+	m.SetEditedAt(time.Unix(2, 0))
+	m.SetEditedBy("2")
+	if e := cluster.NewMessageEvent("db0", m); e.Type != cluster.EventMessageEdited || e.UserID != "2" || !e.Time.Equal(time.Unix(2, 0)) {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+
+	m.SetDeleted(true)
+	if e := cluster.NewMessageEvent("db0", m); e.Type != cluster.EventMessageDeleted {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+}
+
+// Ensure the publisher forwards events to the subscribers of a remote node.
+func TestPublisher_PublishNode(t *testing.T) {
+	ts := newTestWriteService(writeShardSuccess)
+	hub := cluster.NewHub()
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.DataStore = ts
+	s.Hub = hub
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	sub := hub.Subscribe("db0")
+	defer sub.Close()
+	sub.Add("general")
+
+	p := cluster.NewPublisher(time.Minute)
+	p.MetaStore = &publisherMetaStore{metaStore: metaStore{host: ts.ln.Addr().String()}}
+	defer p.Close()
+
+	m := db.NewMessage("general", db.Sender{UserID: "1", Name: "jdoe"}, db.Content{PlainText: "hello"}, nil, time.Unix(1, 0))
+	m.SetID("m1")
+	if err := p.PublishNode(2, []*cluster.Event{cluster.NewMessageEvent("db0", m)}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-sub.C:
+		if e.Type != cluster.EventMessageCreated || e.Message == nil || e.Message.ID() != "m1" {
+			t.Fatalf("unexpected event: %#v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+}
+
+type publisherMetaStore struct {
+	metaStore
+}
+
+func (m *publisherMetaStore) NodeID() uint64 { return 1 }
+
+func (m *publisherMetaStore) Nodes() ([]meta.NodeInfo, error) {
+	return []meta.NodeInfo{{ID: 1}, {ID: 2, Host: m.host}}, nil
+}
@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/messagedb/messagedb/cluster/internal"
@@ -78,9 +79,10 @@ type WriteMessagesRequest struct {
 	Messages         []db.Message
 }
 
-// AddMessage adds a message to the WriteMessagesRequest with field name 'value'
+// AddMessage adds a message to the WriteMessagesRequest for the conversation
+// name, using value as the plain text content
 func (w *WriteMessagesRequest) AddMessage(name string, value interface{}, timestamp time.Time, tags map[string]string) {
-	w.Messages = append(w.Messages, db.NewMessage(timestamp))
+	w.Messages = append(w.Messages, newMessage(name, value, timestamp, tags))
 }
 
 // WriteShardRequest represents the a request to write a slice of messages to a shard
@@ -91,18 +93,14 @@ type WriteShardRequest struct {
 func (w *WriteShardRequest) SetShardID(id uint64) { w.pb.ShardID = &id }
 func (w *WriteShardRequest) ShardID() uint64      { return w.pb.GetShardID() }
 
-func (w *WriteShardRequest) Messages() []db.Message { return w.unmarshalMessages() }
+func (w *WriteShardRequest) Messages() []db.Message { return unmarshalMessages(w.pb.GetMessages()) }
 
 func (w *WriteShardRequest) AddMessage(name string, value interface{}, timestamp time.Time, tags map[string]string) {
-	// w.AddMessages([]db.Message{db.NewMessage(
-	// 	name, tags, map[string]interface{}{"value": value}, timestamp,
-	// )})
-	//TODO: fix this
-	w.AddMessages([]db.Message{db.NewMessage(timestamp)})
+	w.AddMessages([]db.Message{newMessage(name, value, timestamp, tags)})
 }
 
 func (w *WriteShardRequest) AddMessages(messages []db.Message) {
-	w.pb.Messages = append(w.pb.Messages, w.marshalMessages(messages)...)
+	w.pb.Messages = append(w.pb.Messages, marshalMessages(messages)...)
 }
 
 // MarshalBinary encodes the object to a binary format.
@@ -118,91 +116,142 @@ func (w *WriteShardRequest) UnmarshalBinary(buf []byte) error {
 	return nil
 }
 
-func (w *WriteShardRequest) marshalMessages(messages []db.Message) []*internal.Message {
+// marshalMessages converts messages to their protocol buffer representation.
+func marshalMessages(messages []db.Message) []*internal.Message {
 	msgs := make([]*internal.Message, len(messages))
-	for i, p := range messages {
-		// fields := []*internal.Field{}
-		// for k, v := range p.Fields() {
-		// 	name := k
-		// 	f := &internal.Field{
-		// 		Name: &name,
-		// 	}
-		// 	switch t := v.(type) {
-		// 	case int:
-		// 		f.Int64 = proto.Int64(int64(t))
-		// 	case int32:
-		// 		f.Int32 = proto.Int32(t)
-		// 	case int64:
-		// 		f.Int64 = proto.Int64(t)
-		// 	case float64:
-		// 		f.Float64 = proto.Float64(t)
-		// 	case bool:
-		// 		f.Bool = proto.Bool(t)
-		// 	case string:
-		// 		f.String_ = proto.String(t)
-		// 	case []byte:
-		// 		f.Bytes = t
-		// 	}
-		// 	fields = append(fields, f)
-		// }
-
-		// tags := []*internal.Tag{}
-		// for k, v := range p.Tags() {
-		// 	key := k
-		// 	value := v
-		// 	tags = append(tags, &internal.Tag{
-		// 		Key:   &key,
-		// 		Value: &value,
-		// 	})
-		// }
-		// name := p.Name()
+	for i, m := range messages {
+		from, content := m.From(), m.Content()
+
+		mentions := make([]*internal.Mention, len(m.Mentions()))
+		for j, mention := range m.Mentions() {
+			mentions[j] = &internal.Mention{
+				RecipientID:       proto.String(mention.RecipientID),
+				RecipientUsername: proto.String(mention.RecipientUsername),
+			}
+		}
+
 		msgs[i] = &internal.Message{
-			// Name: &name,
-			Time: proto.Int64(p.Time().UnixNano()),
-			// Fields: fields,
-			// Tags:   tags,
+			Conversation: proto.String(string(m.Key())),
+			Time:         proto.Int64(m.Time().UnixNano()),
+			From: &internal.From{
+				UserID: proto.String(from.UserID),
+				Name:   proto.String(from.Name),
+			},
+			Content: &internal.Content{
+				PlainText: proto.String(content.PlainText),
+			},
+			Mentions: mentions,
+		}
+		if id := m.ID(); id != "" {
+			msgs[i].Id = proto.String(id)
+		}
+		if parentID := m.ParentID(); parentID != "" {
+			msgs[i].ParentID = proto.String(parentID)
+		}
+		if content.HTML != "" {
+			msgs[i].Content.HTML = proto.String(content.HTML)
+		}
+		if event := m.Event(); event != "" {
+			msgs[i].Event = proto.String(event)
+		}
+		if s := m.Snippet(); s != nil {
+			msgs[i].Snippet = &internal.Snippet{
+				Language: proto.String(s.Language),
+				Filename: proto.String(s.Filename),
+				Body:     proto.String(s.Body),
+			}
+		}
+		if t := m.EditedAt(); !t.IsZero() {
+			msgs[i].EditedAt = proto.Int64(t.UnixNano())
+		}
+		if by := m.EditedBy(); by != "" {
+			msgs[i].EditedBy = proto.String(by)
+		}
+		if m.Deleted() {
+			msgs[i].Deleted = proto.Bool(true)
 		}
 
+		for k, v := range m.Fields() {
+			f := &internal.Field{Name: proto.String(k)}
+			switch v := v.(type) {
+			case int64:
+				f.Int64 = proto.Int64(v)
+			case float64:
+				f.Float64 = proto.Float64(v)
+			case bool:
+				f.Bool = proto.Bool(v)
+			case string:
+				f.String_ = proto.String(v)
+			}
+			msgs[i].Fields = append(msgs[i].Fields, f)
+		}
 	}
 	return msgs
 }
 
-func (w *WriteShardRequest) unmarshalMessages() []db.Message {
-	messages := make([]db.Message, len(w.pb.GetMessages()))
-	for i, m := range w.pb.GetMessages() {
-		// msg := db.NewMessage(
-		// 	m.GetName(), map[string]string{},
-		// 	map[string]interface{}{}, time.Unix(0, m.GetTime()))
-
-		msg := db.NewMessage(time.Unix(0, m.GetTime()))
-
-		// for _, f := range m.GetFields() {
-		// 	n := f.GetName()
-		// 	if f.Int32 != nil {
-		// 		msg.AddField(n, f.GetInt32())
-		// 	} else if f.Int64 != nil {
-		// 		msg.AddField(n, f.GetInt64())
-		// 	} else if f.Float64 != nil {
-		// 		msg.AddField(n, f.GetFloat64())
-		// 	} else if f.Bool != nil {
-		// 		msg.AddField(n, f.GetBool())
-		// 	} else if f.String_ != nil {
-		// 		msg.AddField(n, f.GetString_())
-		// 	} else {
-		// 		msg.AddField(n, f.GetBytes())
-		// 	}
-		// }
-
-		// tags := db.Tags{}
-		// for _, t := range m.GetTags() {
-		// 	tags[t.GetKey()] = t.GetValue()
-		// }
-		// msg.SetTags(tags)
+// unmarshalMessages converts messages from their protocol buffer representation.
+func unmarshalMessages(pb []*internal.Message) []db.Message {
+	messages := make([]db.Message, len(pb))
+	for i, m := range pb {
+		var mentions []db.Mention
+		for _, mention := range m.GetMentions() {
+			mentions = append(mentions, db.Mention{
+				RecipientID:       mention.GetRecipientID(),
+				RecipientUsername: mention.GetRecipientUsername(),
+			})
+		}
+
+		msg := db.NewMessage(
+			m.GetConversation(),
+			db.Sender{UserID: m.GetFrom().GetUserID(), Name: m.GetFrom().GetName()},
+			db.Content{PlainText: m.GetContent().GetPlainText(), HTML: m.GetContent().GetHTML()},
+			mentions,
+			time.Unix(0, m.GetTime()),
+		)
+		msg.SetID(m.GetId())
+		msg.SetParentID(m.GetParentID())
+		msg.SetEvent(m.GetEvent())
+		if s := m.GetSnippet(); s != nil {
+			msg.SetSnippet(&db.Snippet{Language: s.GetLanguage(), Filename: s.GetFilename(), Body: s.GetBody()})
+		}
+		if m.EditedAt != nil {
+			msg.SetEditedAt(time.Unix(0, m.GetEditedAt()).UTC())
+		}
+		msg.SetEditedBy(m.GetEditedBy())
+		msg.SetDeleted(m.GetDeleted())
+
+		for _, f := range m.GetFields() {
+			n := f.GetName()
+			if f.Int64 != nil {
+				msg.AddField(n, f.GetInt64())
+			} else if f.Float64 != nil {
+				msg.AddField(n, f.GetFloat64())
+			} else if f.Bool != nil {
+				msg.AddField(n, f.GetBool())
+			} else {
+				msg.AddField(n, f.GetString_())
+			}
+		}
 		messages[i] = msg
 	}
 	return messages
 }
 
+// newMessage builds a message for the conversation name with value as its
+// plain text content. The id, from and from_name tags, when present, set the
+// message ID and sender.
+func newMessage(name string, value interface{}, timestamp time.Time, tags map[string]string) db.Message {
+	m := db.NewMessage(
+		name,
+		db.Sender{UserID: tags["from"], Name: tags["from_name"]},
+		db.Content{PlainText: fmt.Sprint(value)},
+		nil,
+		timestamp,
+	)
+	m.SetID(tags["id"])
+	return m
+}
+
 func (w *WriteShardResponse) SetCode(code int)          { w.pb.Code = proto.Int32(int32(code)) }
 func (w *WriteShardResponse) SetMessage(message string) { w.pb.Message = &message }
 
@@ -226,3 +275,142 @@ func (w *WriteShardResponse) UnmarshalBinary(buf []byte) error {
 	}
 	return nil
 }
+
+// WriteReactionsRequest represents a request to write reactions to the messages of a shard
+type WriteReactionsRequest struct {
+	pb internal.WriteReactionsRequest
+}
+
+func (w *WriteReactionsRequest) SetShardID(id uint64) { w.pb.ShardID = &id }
+func (w *WriteReactionsRequest) ShardID() uint64      { return w.pb.GetShardID() }
+
+// Reactions returns the reactions of the request.
+func (w *WriteReactionsRequest) Reactions() []db.Reaction {
+	reactions := make([]db.Reaction, len(w.pb.GetReactions()))
+	for i, r := range w.pb.GetReactions() {
+		reactions[i] = db.Reaction{
+			Conversation: r.GetConversation(),
+			Timestamp:    r.GetTimestamp(),
+			MessageID:    r.GetMessageID(),
+			Emoji:        r.GetEmoji(),
+			UserID:       r.GetUserID(),
+			Time:         time.Unix(0, r.GetTime()).UTC(),
+			Removed:      r.GetRemoved(),
+		}
+	}
+	return reactions
+}
+
+// AddReactions adds reactions to the request.
+func (w *WriteReactionsRequest) AddReactions(reactions []db.Reaction) {
+	for _, r := range reactions {
+		pb := &internal.Reaction{
+			Conversation: proto.String(r.Conversation),
+			Timestamp:    proto.Int64(r.Timestamp),
+			MessageID:    proto.String(r.MessageID),
+			Emoji:        proto.String(r.Emoji),
+			UserID:       proto.String(r.UserID),
+			Time:         proto.Int64(r.Time.UnixNano()),
+		}
+		if r.Removed {
+			pb.Removed = proto.Bool(true)
+		}
+		w.pb.Reactions = append(w.pb.Reactions, pb)
+	}
+}
+
+// MarshalBinary encodes the object to a binary format.
+func (w *WriteReactionsRequest) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&w.pb)
+}
+
+// UnmarshalBinary populates WriteReactionsRequest from a binary format.
+func (w *WriteReactionsRequest) UnmarshalBinary(buf []byte) error {
+	if err := proto.Unmarshal(buf, &w.pb); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PublishRequest represents a request to publish real-time events to the subscribers of a node
+type PublishRequest struct {
+	pb internal.PublishRequest
+}
+
+// Events returns the events of the request.
+func (r *PublishRequest) Events() []*Event {
+	events := make([]*Event, len(r.pb.GetEvents()))
+	for i, e := range r.pb.GetEvents() {
+		events[i] = &Event{
+			Type:         EventType(e.GetType()),
+			Database:     e.GetDatabase(),
+			Conversation: e.GetConversation(),
+			UserID:       e.GetUserID(),
+			Time:         time.Unix(0, e.GetTime()).UTC(),
+			MessageID:    e.GetMessageID(),
+		}
+		if e.Message != nil {
+			events[i].Message = unmarshalMessages([]*internal.Message{e.Message})[0]
+		}
+	}
+	return events
+}
+
+// AddEvents adds events to the request.
+func (r *PublishRequest) AddEvents(events []*Event) {
+	for _, e := range events {
+		pb := &internal.Event{
+			Type:         proto.String(string(e.Type)),
+			Database:     proto.String(e.Database),
+			Conversation: proto.String(e.Conversation),
+			Time:         proto.Int64(e.Time.UnixNano()),
+		}
+		if e.UserID != "" {
+			pb.UserID = proto.String(e.UserID)
+		}
+		if e.MessageID != "" {
+			pb.MessageID = proto.String(e.MessageID)
+		}
+		if e.Message != nil {
+			pb.Message = marshalMessages([]db.Message{e.Message})[0]
+		}
+		r.pb.Events = append(r.pb.Events, pb)
+	}
+}
+
+// MarshalBinary encodes the object to a binary format.
+func (r *PublishRequest) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&r.pb)
+}
+
+// UnmarshalBinary populates PublishRequest from a binary format.
+func (r *PublishRequest) UnmarshalBinary(buf []byte) error {
+	if err := proto.Unmarshal(buf, &r.pb); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PublishResponse represents the response returned from a remote PublishRequest call
+type PublishResponse struct {
+	pb internal.PublishResponse
+}
+
+func (r *PublishResponse) Code() int       { return int(r.pb.GetCode()) }
+func (r *PublishResponse) Message() string { return r.pb.GetMessage() }
+
+func (r *PublishResponse) SetCode(code int)          { r.pb.Code = proto.Int32(int32(code)) }
+func (r *PublishResponse) SetMessage(message string) { r.pb.Message = &message }
+
+// MarshalBinary encodes the object to a binary format.
+func (r *PublishResponse) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&r.pb)
+}
+
+// UnmarshalBinary populates PublishResponse from a binary format.
+func (r *PublishResponse) UnmarshalBinary(buf []byte) error {
+	if err := proto.Unmarshal(buf, &r.pb); err != nil {
+		return err
+	}
+	return nil
+}
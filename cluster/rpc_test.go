@@ -1,8 +1,11 @@
 package cluster
 
 import (
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/messagedb/messagedb/db"
 )
 
 func TestWriteShardRequestBinary(t *testing.T) {
@@ -108,3 +111,77 @@ func TestWriteShardResponseBinary(t *testing.T) {
 	}
 
 }
+
+func TestWriteShardRequestBinary_MessageStructure(t *testing.T) {
+	m := db.NewMessage(
+		"general",
+		db.Sender{UserID: "1", Name: "jdoe"},
+		db.Content{PlainText: "hi @jane", HTML: "<p>hi @jane</p>"},
+		[]db.Mention{{RecipientID: "2", RecipientUsername: "jane"}},
+		time.Unix(1, 2),
+	)
+	m.SetID("m1")
+	m.SetParentID("m0")
+	m.SetSnippet(&db.Snippet{Language: "go", Filename: "main.go", Body: "package main\n"})
+	m.SetEditedAt(time.Unix(3, 0))
+	m.SetEditedBy("2")
+	m.SetDeleted(true)
+	m.AddField("priority", 2)
+	m.AddField("score", 0.5)
+	m.AddField("pinned", true)
+	m.AddField("client", "web")
+
+	e := db.NewMessage("general", db.Sender{UserID: "1", Name: "jdoe"}, db.Content{PlainText: "jdoe joined"}, nil, time.Unix(4, 0))
+	e.SetEvent(db.EventParticipantJoined)
+
+	sr := &WriteShardRequest{}
+	sr.SetShardID(1)
+	sr.AddMessages([]db.Message{m, e})
+
+	b, err := sr.MarshalBinary()
+	if err != nil {
+		t.Fatalf("WriteShardRequest.MarshalBinary() failed: %v", err)
+	}
+
+	got := &WriteShardRequest{}
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("WriteShardRequest.UnmarshalBinary() failed: %v", err)
+	}
+
+	g := got.Messages()[0]
+	if g.String() != m.String() {
+		t.Errorf("message mismatch:\n got %v\n exp %v", g.String(), m.String())
+	}
+	if !reflect.DeepEqual(g.Fields(), m.Fields()) {
+		t.Errorf("fields mismatch:\n got %#v\n exp %#v", g.Fields(), m.Fields())
+	}
+	if g := got.Messages()[1]; g.Event() != e.Event() || g.Kind() != db.MessageKindSystem {
+		t.Errorf("event mismatch: got %v, exp %v", g.Event(), e.Event())
+	}
+}
+
+func TestPublishRequestBinary(t *testing.T) {
+	e := &Event{
+		Type:         EventMessageRead,
+		Database:     "db0",
+		Conversation: "general",
+		UserID:       "1",
+		Time:         time.Unix(1, 2).UTC(),
+		MessageID:    "m1",
+	}
+
+	var r PublishRequest
+	r.AddEvents([]*Event{e})
+	b, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatalf("PublishRequest.MarshalBinary() failed: %v", err)
+	}
+
+	var got PublishRequest
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("PublishRequest.UnmarshalBinary() failed: %v", err)
+	}
+	if events := got.Events(); len(events) != 1 || !reflect.DeepEqual(events[0], e) {
+		t.Fatalf("Events mismatch: got %#v, exp %#v", events, e)
+	}
+}
@@ -37,9 +37,15 @@ type Service struct {
 	DataStore interface {
 		CreateShard(database, policy string, shardID uint64) error
 		WriteToShard(shardID uint64, messages []db.Message) error
+		WriteReactionsToShard(shardID uint64, reactions []db.Reaction) error
 		CreateMapper(shardID uint64, query string, chunkSize int) (db.Mapper, error)
 	}
 
+	// Hub delivers events published by other nodes to the local subscribers.
+	Hub interface {
+		Publish(events ...*Event)
+	}
+
 	Logger *log.Logger
 }
 
@@ -146,6 +152,12 @@ func (s *Service) handleConn(conn net.Conn) {
 				s.Logger.Printf("process write shard error: %s", err)
 			}
 			s.writeShardResponse(conn, err)
+		case writeReactionsRequestMessage:
+			err := s.processWriteReactionsRequest(buf)
+			if err != nil {
+				s.Logger.Printf("process write reactions error: %s", err)
+			}
+			s.writeShardResponse(conn, err)
 		case mapShardRequestMessage:
 			err := s.processMapShardRequest(conn, buf)
 			if err != nil {
@@ -154,6 +166,12 @@ func (s *Service) handleConn(conn net.Conn) {
 					s.Logger.Printf("process map shard error writing response: %s", err.Error())
 				}
 			}
+		case publishRequestMessage:
+			err := s.processPublishRequest(buf)
+			if err != nil {
+				s.Logger.Printf("process publish error: %s", err)
+			}
+			s.writePublishResponse(conn, err)
 		default:
 			s.Logger.Printf("cluster service message type not found: %d", typ)
 		}
@@ -200,6 +218,20 @@ func (s *Service) processWriteShardRequest(buf []byte) error {
 	return nil
 }
 
+func (s *Service) processWriteReactionsRequest(buf []byte) error {
+	// Build request
+	var req WriteReactionsRequest
+	if err := req.UnmarshalBinary(buf); err != nil {
+		return err
+	}
+
+	// The shard is not created here, the messages reacted to are always written before.
+	if err := s.DataStore.WriteReactionsToShard(req.ShardID(), req.Reactions()); err != nil {
+		return fmt.Errorf("write reactions to shard %d: %s", req.ShardID(), err)
+	}
+	return nil
+}
+
 func (s *Service) writeShardResponse(w io.Writer, e error) {
 	// Build response.
 	var resp WriteShardResponse
@@ -223,6 +255,42 @@ func (s *Service) writeShardResponse(w io.Writer, e error) {
 	}
 }
 
+func (s *Service) processPublishRequest(buf []byte) error {
+	var req PublishRequest
+	if err := req.UnmarshalBinary(buf); err != nil {
+		return err
+	}
+
+	// Events are only delivered locally, the publishing node forwards them to every node.
+	if s.Hub != nil {
+		s.Hub.Publish(req.Events()...)
+	}
+	return nil
+}
+
+func (s *Service) writePublishResponse(w io.Writer, e error) {
+	// Build response.
+	var resp PublishResponse
+	if e != nil {
+		resp.SetCode(1)
+		resp.SetMessage(e.Error())
+	} else {
+		resp.SetCode(0)
+	}
+
+	// Marshal response to binary.
+	buf, err := resp.MarshalBinary()
+	if err != nil {
+		s.Logger.Printf("error marshalling publish response: %s", err)
+		return
+	}
+
+	// Write to connection.
+	if err := WriteTLV(w, publishResponseMessage, buf); err != nil {
+		s.Logger.Printf("write publish response error: %s", err)
+	}
+}
+
 func (s *Service) processMapShardRequest(w io.Writer, buf []byte) error {
 	// Decode request
 	var req MapShardRequest
@@ -26,9 +26,10 @@ type testService struct {
 	nodeID           uint64
 	ln               net.Listener
 	muxln            net.Listener
-	writeShardFunc   func(shardID uint64, messages []db.Message) error
-	createShardFunc  func(database, policy string, shardID uint64) error
-	createMapperFunc func(shardID uint64, query string, chunkSize int) (db.Mapper, error)
+	writeShardFunc     func(shardID uint64, messages []db.Message) error
+	writeReactionsFunc func(shardID uint64, reactions []db.Reaction) error
+	createShardFunc    func(database, policy string, shardID uint64) error
+	createMapperFunc   func(shardID uint64, query string, chunkSize int) (db.Mapper, error)
 }
 
 func newTestWriteService(f func(shardID uint64, messages []db.Message) error) testService {
@@ -65,6 +66,10 @@ func (t testService) WriteToShard(shardID uint64, messages []db.Message) error {
 	return t.writeShardFunc(shardID, messages)
 }
 
+func (t testService) WriteReactionsToShard(shardID uint64, reactions []db.Reaction) error {
+	return t.writeReactionsFunc(shardID, reactions)
+}
+
 func (t testService) CreateShard(database, policy string, shardID uint64) error {
 	return t.createShardFunc(database, policy, shardID)
 }
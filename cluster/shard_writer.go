@@ -16,6 +16,9 @@ const (
 	writeShardResponseMessage
 	mapShardRequestMessage
 	mapShardResponseMessage
+	publishRequestMessage
+	publishResponseMessage
+	writeReactionsRequestMessage
 )
 
 // ShardWriter writes a set of points to a shard.
@@ -37,23 +40,26 @@ func NewShardWriter(timeout time.Duration) *ShardWriter {
 }
 
 func (w *ShardWriter) WriteShard(shardID, ownerID uint64, messages []db.Message) error {
-	c, err := w.dial(ownerID)
+	// Build write request.
+	var request WriteShardRequest
+	request.SetShardID(shardID)
+	request.AddMessages(messages)
+
+	// Marshal into protocol buffers.
+	buf, err := request.MarshalBinary()
 	if err != nil {
 		return err
 	}
 
-	conn, ok := c.(*pool.PoolConn)
-	if !ok {
-		panic("wrong connection type")
-	}
-	defer func(conn net.Conn) {
-		conn.Close() // return to pool
-	}(conn)
+	return w.write(ownerID, writeShardRequestMessage, buf)
+}
 
+// WriteShardReactions writes reactions to the messages of a shard on its owner.
+func (w *ShardWriter) WriteShardReactions(shardID, ownerID uint64, reactions []db.Reaction) error {
 	// Build write request.
-	var request WriteShardRequest
+	var request WriteReactionsRequest
 	request.SetShardID(shardID)
-	request.AddMessages(messages)
+	request.AddReactions(reactions)
 
 	// Marshal into protocol buffers.
 	buf, err := request.MarshalBinary()
@@ -61,9 +67,27 @@ func (w *ShardWriter) WriteShard(shardID, ownerID uint64, messages []db.Message)
 		return err
 	}
 
+	return w.write(ownerID, writeReactionsRequestMessage, buf)
+}
+
+// write sends an encoded write request of type typ to a node and waits for its response.
+func (w *ShardWriter) write(ownerID uint64, typ byte, buf []byte) error {
+	c, err := w.dial(ownerID)
+	if err != nil {
+		return err
+	}
+
+	conn, ok := c.(*pool.PoolConn)
+	if !ok {
+		panic("wrong connection type")
+	}
+	defer func(conn net.Conn) {
+		conn.Close() // return to pool
+	}(conn)
+
 	// Write request.
 	conn.SetWriteDeadline(time.Now().Add(w.timeout))
-	if err := WriteTLV(conn, writeShardRequestMessage, buf); err != nil {
+	if err := WriteTLV(conn, typ, buf); err != nil {
 		conn.MarkUnusable()
 		return err
 	}
@@ -1,8 +1,11 @@
 package cluster_test
 
 import (
+	"fmt"
 	"net"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -28,7 +31,7 @@ func TestShardWriter_WriteShard_Success(t *testing.T) {
 	// Build a single point.
 	now := time.Now()
 	var messages []db.Message
-	messages = append(messages, db.NewMessage(now))
+	messages = append(messages, db.NewMessage("general", db.Sender{UserID: "1", Name: "jdoe"}, db.Content{PlainText: "hello"}, nil, now))
 
 	// Write to shard and close.
 	if err := w.WriteShard(1, 2, messages); err != nil {
@@ -70,7 +73,7 @@ func TestShardWriter_WriteShard_Multiple(t *testing.T) {
 	// Build a single point.
 	now := time.Now()
 	var messages []db.Message
-	messages = append(messages, db.NewMessage(now))
+	messages = append(messages, db.NewMessage("general", db.Sender{UserID: "1", Name: "jdoe"}, db.Content{PlainText: "hello"}, nil, now))
 
 	// Write to shard twice and close.
 	if err := w.WriteShard(1, 2, messages); err != nil {
@@ -115,13 +118,58 @@ func TestShardWriter_WriteShard_Error(t *testing.T) {
 	shardID := uint64(1)
 	ownerID := uint64(2)
 	var messages []db.Message
-	messages = append(messages, db.NewMessage(now))
+	messages = append(messages, db.NewMessage("general", db.Sender{UserID: "1", Name: "jdoe"}, db.Content{PlainText: "hello"}, nil, now))
 
 	if err := w.WriteShard(shardID, ownerID, messages); err == nil || err.Error() != "error code 1: write shard 1: failed to write" {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
 
+// Ensure the shard writer can write reactions to a shard.
+func TestShardWriter_WriteShardReactions(t *testing.T) {
+	ts := newTestWriteService(nil)
+	written := make(chan []db.Reaction, 1)
+	var fail int32
+	ts.writeReactionsFunc = func(shardID uint64, reactions []db.Reaction) error {
+		if atomic.LoadInt32(&fail) == 1 {
+			return fmt.Errorf("failed to write")
+		} else if shardID != 1 {
+			return fmt.Errorf("unexpected shard id: %d", shardID)
+		}
+		written <- reactions
+		return nil
+	}
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.DataStore = ts
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute)
+	w.MetaStore = &metaStore{host: ts.ln.Addr().String()}
+	defer w.Close()
+
+	now := time.Now().UTC()
+	reactions := []db.Reaction{
+		{Conversation: "general", Timestamp: 1, MessageID: "m1", Emoji: "+1", UserID: "u1", Time: now},
+		{Conversation: "general", Timestamp: 1, MessageID: "m1", Emoji: "tada", UserID: "u1", Time: now, Removed: true},
+	}
+	if err := w.WriteShardReactions(1, 2, reactions); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-written; !reflect.DeepEqual(got, reactions) {
+		t.Fatalf("unexpected reactions:\n got %#v\n exp %#v", got, reactions)
+	}
+
+	atomic.StoreInt32(&fail, 1)
+	if err := w.WriteShardReactions(1, 2, reactions); err == nil || err.Error() != "error code 1: write reactions to shard 1: failed to write" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 // Ensure the shard writer returns an error when dialing times out.
 func TestShardWriter_Write_ErrDialTimeout(t *testing.T) {
 	ts := newTestWriteService(writeShardSuccess)
@@ -141,7 +189,7 @@ func TestShardWriter_Write_ErrDialTimeout(t *testing.T) {
 	shardID := uint64(1)
 	ownerID := uint64(2)
 	var messages []db.Message
-	messages = append(messages, db.NewMessage(now))
+	messages = append(messages, db.NewMessage("general", db.Sender{UserID: "1", Name: "jdoe"}, db.Content{PlainText: "hello"}, nil, now))
 
 	if err, exp := w.WriteShard(shardID, ownerID, messages), "i/o timeout"; err == nil || !strings.Contains(err.Error(), exp) {
 		t.Fatalf("expected error %v, to contain %s", err, exp)
@@ -162,7 +210,7 @@ func TestShardWriter_Write_ErrReadTimeout(t *testing.T) {
 	shardID := uint64(1)
 	ownerID := uint64(2)
 	var messages []db.Message
-	messages = append(messages, db.NewMessage(now))
+	messages = append(messages, db.NewMessage("general", db.Sender{UserID: "1", Name: "jdoe"}, db.Content{PlainText: "hello"}, nil, now))
 
 	if err := w.WriteShard(shardID, ownerID, messages); err == nil || !strings.Contains(err.Error(), "i/o timeout") {
 		t.Fatalf("unexpected error: %s", err)
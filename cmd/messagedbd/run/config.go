@@ -9,7 +9,9 @@ import (
 	"github.com/messagedb/messagedb/cluster"
 	"github.com/messagedb/messagedb/db"
 	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/notify"
 	"github.com/messagedb/messagedb/services/admin"
+	"github.com/messagedb/messagedb/services/attachments"
 	"github.com/messagedb/messagedb/services/hh"
 	"github.com/messagedb/messagedb/services/httpd"
 	"github.com/messagedb/messagedb/services/retention"
@@ -22,6 +24,10 @@ type Config struct {
 	Cluster   cluster.Config   `toml:"cluster"`
 	Retention retention.Config `toml:"retention"`
 
+	Attachments attachments.Config `toml:"attachments"`
+
+	Notifications notify.Config `toml:"notifications"`
+
 	Admin admin.Config `toml:"admin"`
 	HTTPD httpd.Config `toml:"http"`
 
@@ -48,6 +54,8 @@ func NewConfig() *Config {
 	// c.Monitoring = monitor.NewConfig()
 	// c.ContinuousQuery = continuous_querier.NewConfig()
 	c.Retention = retention.NewConfig()
+	c.Attachments = attachments.NewConfig()
+	c.Notifications = notify.NewConfig()
 	c.HintedHandoff = hh.NewConfig()
 
 	return c
@@ -66,6 +74,7 @@ func NewDemoConfig() (*Config, error) {
 	c.Meta.Dir = filepath.Join(u.HomeDir, ".messagedb/meta")
 	c.Data.Dir = filepath.Join(u.HomeDir, ".messagedb/data")
 	c.HintedHandoff.Dir = filepath.Join(u.HomeDir, ".messagedb/hh")
+	c.Attachments.Dir = filepath.Join(u.HomeDir, ".messagedb/attachments")
 
 	c.Admin.Enabled = true
 	// c.Monitoring.Enabled = false
@@ -81,6 +90,14 @@ func (c *Config) Validate() error {
 		return errors.New("Data.Dir must be specified")
 	} else if c.HintedHandoff.Dir == "" {
 		return errors.New("HintedHandoff.Dir must be specified")
+	} else if c.Attachments.Enabled && c.Attachments.Dir == "" {
+		return errors.New("Attachments.Dir must be specified")
+	} else if c.Notifications.Enabled && c.Notifications.Sink == notify.SinkFile && c.Notifications.File == "" {
+		return errors.New("Notifications.File must be specified")
+	}
+
+	if err := c.HTTPD.Validate(); err != nil {
+		return fmt.Errorf("invalid http config: %v", err)
 	}
 
 	// for _, g := range c.Graphites {
@@ -27,6 +27,9 @@ bind-address = ":8088"
 [http]
 bind-address = ":8075"
 
+[attachments]
+dir = "/tmp/attachments"
+
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -40,5 +43,7 @@ bind-address = ":8075"
 		t.Fatalf("unexpected admin bind address: %s", c.Admin.BindAddress)
 	} else if c.HTTPD.BindAddress != ":8075" {
 		t.Fatalf("unexpected api bind address: %s", c.HTTPD.BindAddress)
+	} else if c.Attachments.Dir != "/tmp/attachments" {
+		t.Fatalf("unexpected attachments dir: %s", c.Attachments.Dir)
 	}
 }
@@ -12,7 +12,9 @@ import (
 	"github.com/messagedb/messagedb/cluster"
 	"github.com/messagedb/messagedb/db"
 	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/notify"
 	"github.com/messagedb/messagedb/services/admin"
+	"github.com/messagedb/messagedb/services/attachments"
 	"github.com/messagedb/messagedb/services/hh"
 	"github.com/messagedb/messagedb/services/httpd"
 	"github.com/messagedb/messagedb/services/retention"
@@ -37,14 +39,18 @@ type Server struct {
 	DataStore      *db.Store
 	QueryExecutor  *db.QueryExecutor
 	MessagesWriter *cluster.MessagesWriter
+	Hub            *cluster.Hub
+	Publisher      *cluster.Publisher
 	ShardWriter    *cluster.ShardWriter
 	ShardMapper    *cluster.ShardMapper
 	HintedHandoff  *hh.Service
+	Notifier       notify.Notifier
 
 	Services []Service
 
 	ClusterService     *cluster.Service
 	SnapshotterService *snapshotter.Service
+	AttachmentsService *attachments.Service
 
 	// Server reporting
 	reportingDisabled bool
@@ -93,6 +99,12 @@ func NewServer(c *Config, version string) (*Server, error) {
 	// Create the hinted handoff service
 	s.HintedHandoff = hh.NewService(c.HintedHandoff, s.ShardWriter)
 
+	// Initialize the publisher of real-time events.
+	s.Hub = cluster.NewHub()
+	s.Publisher = cluster.NewPublisher(time.Duration(c.Cluster.ShardWriterTimeout))
+	s.Publisher.Hub = s.Hub
+	s.Publisher.MetaStore = s.MetaStore
+
 	// Initialize points writer.
 	s.MessagesWriter = cluster.NewMessagesWriter()
 	s.MessagesWriter.WriteTimeout = time.Duration(c.Cluster.WriteTimeout)
@@ -100,11 +112,20 @@ func NewServer(c *Config, version string) (*Server, error) {
 	s.MessagesWriter.DataStore = s.DataStore
 	s.MessagesWriter.ShardWriter = s.ShardWriter
 	s.MessagesWriter.HintedHandoff = s.HintedHandoff
+	s.MessagesWriter.Publisher = s.Publisher
+
+	// Initialize the delivery of notifications.
+	n, err := notify.New(c.Notifications)
+	if err != nil {
+		return nil, err
+	}
+	s.Notifier = n
 
 	// Append services.
 	s.appendClusterService(c.Cluster)
 	s.appendSnapshotterService()
 	s.appendAdminService(c.Admin)
+	s.appendAttachmentsService(c.Attachments, c.HTTPD.Database)
 	s.appendHTTPDService(c.HTTPD)
 	s.appendRetentionPolicyService(c.Retention)
 
@@ -115,6 +136,7 @@ func (s *Server) appendClusterService(c cluster.Config) {
 	srv := cluster.NewService(c)
 	srv.MetaStore = s.MetaStore
 	srv.DataStore = s.DataStore
+	srv.Hub = s.Hub
 	s.Services = append(s.Services, srv)
 	s.ClusterService = srv
 }
@@ -137,6 +159,17 @@ func (s *Server) appendRetentionPolicyService(c retention.Config) {
 	s.Services = append(s.Services, srv)
 }
 
+func (s *Server) appendAttachmentsService(c attachments.Config, database string) {
+	if !c.Enabled {
+		return
+	}
+	srv := attachments.NewService(c)
+	srv.MetaStore = s.MetaStore
+	srv.Database = database
+	s.Services = append(s.Services, srv)
+	s.AttachmentsService = srv
+}
+
 func (s *Server) appendAdminService(c admin.Config) {
 	if !c.Enabled {
 		return
@@ -155,6 +188,13 @@ func (s *Server) appendHTTPDService(c httpd.Config) {
 	srv.SetDataStore(s.DataStore)
 	srv.SetQueryExecutor(s.QueryExecutor)
 	srv.SetMessagesWriter(s.MessagesWriter)
+	srv.SetPublisher(s.Hub, s.Publisher)
+	if s.AttachmentsService != nil {
+		srv.SetBlobStore(s.AttachmentsService.BlobStore, s.AttachmentsService.MaxSize())
+	}
+	if s.Notifier != nil {
+		srv.SetNotifier(s.Notifier)
+	}
 	srv.Version = s.version
 
 	s.Services = append(s.Services, srv)
@@ -252,6 +292,9 @@ func (s *Server) Close() error {
 	if s.HintedHandoff != nil {
 		s.HintedHandoff.Close()
 	}
+	if s.Publisher != nil {
+		s.Publisher.Close()
+	}
 	// closes all the services
 	for _, service := range s.Services {
 		service.Close()
@@ -1,7 +1,10 @@
 package db
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
+	"testing"
 	"time"
 
 	"github.com/messagedb/messagedb/meta"
@@ -350,6 +353,183 @@ var nID = uint64(42)
 // 	}
 // }
 
+// Ensure wildcard selects are expanded to the fields written to the conversation.
+func TestWriteMessagesAndExecuteWildcard(t *testing.T) {
+	store, query_executor := testStoreAndQueryExecutor()
+	defer os.RemoveAll(store.path)
+	query_executor.MetaStore = &testQEMetastore{
+		sgFunc: func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+			return []meta.ShardGroupInfo{
+				{
+					ID:        sgID1,
+					StartTime: time.Unix(0, 0),
+					EndTime:   time.Now(),
+					Shards:    []meta.ShardInfo{{ID: uint64(sID0), OwnerIDs: []uint64{nID}}},
+				},
+			}, nil
+		},
+	}
+
+	m := NewMessage("general", Sender{UserID: "u1", Name: "jdoe"}, Content{PlainText: "hello"}, nil, time.Unix(1, 0).UTC())
+	m.SetID("m1")
+	m.AddField("priority", 1)
+	if err := store.WriteToShard(sID0, []Message{m}); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, err := query_executor.rewriteSelectStatement(mustParseSelectStatement(`SELECT * FROM "foo"."bar".general`))
+	if err != nil {
+		t.Fatalf("failed to rewrite query: %s", err)
+	}
+	executor, err := query_executor.plan(stmt, 0)
+	if err != nil {
+		t.Fatalf("failed to plan query: %s", err)
+	}
+
+	exp := `[{"name":"general","columns":["time","from","from_name","id","priority","text"],"values":[["1970-01-01T00:00:01Z","u1","jdoe","m1",1,"hello"]]}]`
+	if got := executeAndGetResults(*executor); got != exp {
+		t.Fatalf("exp: %s\ngot: %s\n", exp, got)
+	}
+}
+
+// Ensure messages can be paged before and after a cursor across shards.
+func TestWriteMessagesAndExecuteCursors(t *testing.T) {
+	store, query_executor := testStoreAndQueryExecutor()
+	defer os.RemoveAll(store.path)
+	query_executor.MetaStore = &testQEMetastore{
+		sgFunc: func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+			return []meta.ShardGroupInfo{
+				{
+					ID:        sgID1,
+					StartTime: time.Unix(0, 0),
+					EndTime:   time.Unix(3, 0),
+					Shards:    []meta.ShardInfo{{ID: uint64(sID0), OwnerIDs: []uint64{nID}}},
+				},
+				{
+					ID:        sgID2,
+					StartTime: time.Unix(3, 0),
+					EndTime:   time.Now(),
+					Shards:    []meta.ShardInfo{{ID: uint64(sID1), OwnerIDs: []uint64{nID}}},
+				},
+			}, nil
+		},
+	}
+
+	for i, text := range []string{"one", "two", "three", "four"} {
+		m := NewMessage("general", Sender{UserID: "u1"}, Content{PlainText: text}, nil, time.Unix(int64(i+1), 0).UTC())
+		shardID := sID0
+		if i >= 2 {
+			shardID = sID1
+		}
+		if err := store.WriteToShard(shardID, []Message{m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM "foo"."bar".general AFTER '%s' LIMIT 2`, cursorAt(1)),
+			expected: `[{"name":"general","columns":["time","text"],"values":[["1970-01-01T00:00:02Z","two"],["1970-01-01T00:00:03Z","three"]]}]`,
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM "foo"."bar".general BEFORE '%s' LIMIT 2`, cursorAt(4)),
+			expected: `[{"name":"general","columns":["time","text"],"values":[["1970-01-01T00:00:03Z","three"],["1970-01-01T00:00:02Z","two"]]}]`,
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM "foo"."bar".general AFTER '%s' BEFORE '%s'`, cursorAt(1), cursorAt(4)),
+			expected: `[{"name":"general","columns":["time","text"],"values":[["1970-01-01T00:00:03Z","three"],["1970-01-01T00:00:02Z","two"]]}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		executor, err := query_executor.plan(mustParseSelectStatement(tt.stmt), 0)
+		if err != nil {
+			t.Fatalf("failed to plan query: %s", err)
+		}
+		if got := executeAndGetResults(*executor); got != tt.expected {
+			t.Errorf("test %s\n\texp: %s\n\tgot: %s", tt.stmt, tt.expected, got)
+		}
+	}
+}
+
+// Ensure messages can be searched across conversations and shards, newest first.
+func TestWriteMessagesAndExecuteSearch(t *testing.T) {
+	store, query_executor := testStoreAndQueryExecutor()
+	defer os.RemoveAll(store.path)
+	query_executor.MetaStore = &testQEMetastore{
+		sgFunc: func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+			return []meta.ShardGroupInfo{
+				{
+					ID:        sgID1,
+					StartTime: time.Unix(0, 0),
+					EndTime:   time.Unix(3, 0),
+					Shards:    []meta.ShardInfo{{ID: uint64(sID0), OwnerIDs: []uint64{nID}}},
+				},
+				{
+					ID:        sgID2,
+					StartTime: time.Unix(3, 0),
+					EndTime:   time.Now(),
+					Shards:    []meta.ShardInfo{{ID: uint64(sID1), OwnerIDs: []uint64{nID}}},
+				},
+			}, nil
+		},
+	}
+
+	for i, text := range []string{"deploy failed", "lunch?", "deploy succeeded", "the deploy failed again"} {
+		conversation := "general"
+		if i%2 == 1 {
+			conversation = "ops"
+		}
+		m := NewMessage(conversation, Sender{UserID: "u1"}, Content{PlainText: text}, nil, time.Unix(int64(i+1), 0).UTC())
+		shardID := sID0
+		if i >= 2 {
+			shardID = sID1
+		}
+		if err := store.WriteToShard(shardID, []Message{m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM "foo"."bar"./.*/ WHERE content MATCHES '"deploy failed"' BEFORE '%s'`, cursorAt(5)),
+			expected: `[{"name":"general","columns":["time","text"],"values":[["1970-01-01T00:00:01Z","deploy failed"]]},{"name":"ops","columns":["time","text"],"values":[["1970-01-01T00:00:04Z","the deploy failed again"]]}]`,
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM "foo"."bar"./.*/ WHERE content MATCHES 'deploy' BEFORE '%s' LIMIT 1`, cursorAt(5)),
+			expected: `[{"name":"general","columns":["time","text"],"values":[["1970-01-01T00:00:03Z","deploy succeeded"]]},{"name":"ops","columns":["time","text"],"values":[["1970-01-01T00:00:04Z","the deploy failed again"]]}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		stmt, err := query_executor.rewriteSelectStatement(mustParseSelectStatement(tt.stmt))
+		if err != nil {
+			t.Fatalf("failed to rewrite query: %s", err)
+		}
+		executor, err := query_executor.plan(stmt, 0)
+		if err != nil {
+			t.Fatalf("failed to plan query: %s", err)
+		}
+		if got := executeAndGetResults(*executor); got != tt.expected {
+			t.Errorf("test %s\n\texp: %s\n\tgot: %s", tt.stmt, tt.expected, got)
+		}
+	}
+}
+
+// cursorAt returns an encoded cursor positioned at the given second.
+func cursorAt(sec int64) string {
+	return sql.NewCursor(time.Unix(sec, 0)).String()
+}
+
 type testQEMetastore struct {
 	sgFunc func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
 }
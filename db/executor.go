@@ -63,15 +63,19 @@ func (e *Executor) nextMapperTagSet() string {
 }
 
 // nextMapperLowestTime returns the lowest minimum time across all Mappers, for the given tagset.
+// When the mappers emit data newest first it returns the highest maximum time instead.
 func (e *Executor) nextMapperLowestTime(tagset string) int64 {
 	minTime := int64(math.MaxInt64)
+	if e.stmt.Descending() {
+		minTime = math.MinInt64
+	}
 	for _, m := range e.mappers {
 		if !m.drained && m.bufferedChunk != nil {
 			if m.bufferedChunk.key() != tagset {
 				continue
 			}
 			t := m.bufferedChunk.Values[len(m.bufferedChunk.Values)-1].Time
-			if t < minTime {
+			if e.pastTime(minTime, t) {
 				minTime = t
 			}
 		}
@@ -79,6 +83,14 @@ func (e *Executor) nextMapperLowestTime(tagset string) int64 {
 	return minTime
 }
 
+// pastTime returns true if time t comes after the boundary in the order the mappers emit data.
+func (e *Executor) pastTime(t, boundary int64) bool {
+	if e.stmt.Descending() {
+		return t < boundary
+	}
+	return t > boundary
+}
+
 // tagSetIsLimited returns whether data for the given tagset has been LIMITed.
 func (e *Executor) tagSetIsLimited(tagset string) bool {
 	_, ok := e.limitedTagSets[tagset]
@@ -170,14 +182,14 @@ func (e *Executor) execute(out chan *sql.Row) {
 
 			// This mapper's next chunk is not for the next tagset, or the very first value of
 			// the chunk is at a higher acceptable timestamp. Skip it.
-			if m.bufferedChunk.key() != tagset || m.bufferedChunk.Values[0].Time > minTime {
+			if m.bufferedChunk.key() != tagset || e.pastTime(m.bufferedChunk.Values[0].Time, minTime) {
 				continue
 			}
 
 			// Find the index of the point up to the min.
 			ind := len(m.bufferedChunk.Values)
 			for i, mo := range m.bufferedChunk.Values {
-				if mo.Time > minTime {
+				if e.pastTime(mo.Time, minTime) {
 					ind = i
 					break
 				}
@@ -202,8 +214,13 @@ func (e *Executor) execute(out chan *sql.Row) {
 			}
 		}
 
-		// Sort the values by time first so we can then handle offset and limit
-		sort.Sort(mapperValues(chunkedOutput.Values))
+		// Sort the values by time first so we can then handle offset and limit. The sort is
+		// stable so revisions of the same message keep the order they were emitted in.
+		if e.stmt.Descending() {
+			sort.Stable(sort.Reverse(mapperValues(chunkedOutput.Values)))
+		} else {
+			sort.Stable(mapperValues(chunkedOutput.Values))
+		}
 
 		// Now that we have full name and tag details, initialize the rowWriter.
 		// The Name and Tags will be the same for all mappers.
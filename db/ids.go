@@ -0,0 +1,115 @@
+package db
+
+import (
+	"github.com/boltdb/bolt"
+)
+
+// indexID adds the message of a conversation at timestamp to the ID index of the conversation.
+// Messages without an ID are not indexed.
+func indexID(tx *bolt.Tx, key []byte, id string, timestamp int64) error {
+	if id == "" {
+		return nil
+	}
+	b, err := tx.Bucket([]byte("ids")).CreateBucketIfNotExists(key)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(id), u64tob(uint64(timestamp)))
+}
+
+// indexIDs builds the ID index of the messages flushed before the index existed. This function
+// must be called within the context of a lock.
+func (s *Shard) indexIDs(tx *bolt.Tx) error {
+	return tx.Bucket([]byte("conversations")).ForEach(func(key, _ []byte) error {
+		b := conversationBucket(tx, string(key))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return indexID(tx, key, s.messageID(string(key), v), int64(btou64(k)))
+		})
+	})
+}
+
+// MessageByID returns the latest revision of the message of a conversation with an ID, or nil
+// if the shard does not store it.
+func (s *Shard) MessageByID(key, id string) (Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Messages in the WAL cache are not indexed yet and replace the flushed revisions.
+	a := s.cache[WALPartition([]byte(key))][key]
+	for i := len(a) - 1; i >= 0; i-- {
+		if timestamp, data := unmarshalCacheEntry(a[i]); s.messageID(key, data) == id {
+			return s.decodeMessage(key, timestamp, data)
+		}
+	}
+
+	var m Message
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		idx := tx.Bucket([]byte("ids")).Bucket([]byte(key))
+		if idx == nil {
+			return nil
+		}
+		v := idx.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+
+		timestamp := int64(btou64(v))
+		b := conversationBucket(tx, key)
+		if b == nil {
+			return nil
+		}
+		data := b.Get(v)
+		if data == nil {
+			return nil
+		}
+
+		// Bolt only guarantees the value for the life of the transaction so decode it here.
+		var err error
+		m, err = s.decodeMessage(key, timestamp, data)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// messageTimestamp returns the timestamp of the message of a conversation with an ID, looked up
+// in the cached entries of the conversation and then in the ID index of tx.
+func (s *Shard) messageTimestamp(tx *bolt.Tx, key, id string, cache [][]byte) (int64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Messages in the WAL cache are not indexed yet.
+	for i := len(cache) - 1; i >= 0; i-- {
+		if timestamp, data := unmarshalCacheEntry(cache[i]); s.messageID(key, data) == id {
+			return timestamp, true
+		}
+	}
+
+	idx := tx.Bucket([]byte("ids")).Bucket([]byte(key))
+	if idx == nil {
+		return 0, false
+	}
+	v := idx.Get([]byte(id))
+	if v == nil {
+		return 0, false
+	}
+	return int64(btou64(v)), true
+}
+
+// decodeMessage returns the message of a conversation at timestamp from its encoded fields.
+// This function must be called within the context of a lock.
+func (s *Shard) decodeMessage(key string, timestamp int64, data []byte) (Message, error) {
+	c := s.conversationFields[key]
+	if c == nil {
+		return nil, nil
+	}
+	values, err := c.codec.DecodeFieldsWithNames(data)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalMessage(key, timestamp, values)
+}
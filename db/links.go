@@ -0,0 +1,314 @@
+package db
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// MaxLinksPerMessage is the maximum number of links indexed for a single message.
+	MaxLinksPerMessage = 32
+
+	// MaxLinkLength is the maximum length in bytes of an indexed link.
+	MaxLinkLength = 2048
+)
+
+var (
+	// linkRegexp matches the http and https URLs written in text.
+	linkRegexp = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'` + "`" + `]+`)
+
+	// hrefRegexp matches the targets of the anchors of an HTML document.
+	hrefRegexp = regexp.MustCompile(`(?i)<a\s[^>]*?\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// Link is a URL found in a message of a conversation, as listed by SHOW LINKS.
+type Link struct {
+	URL        string
+	MessageID  string
+	SenderID   string
+	SenderName string
+	Timestamp  int64
+
+	// Position of the link in the message.
+	pos int
+}
+
+// Links sorts links newest first, in the order they appear within a message.
+type Links []*Link
+
+func (a Links) Len() int { return len(a) }
+func (a Links) Less(i, j int) bool {
+	if a[i].Timestamp != a[j].Timestamp {
+		return a[i].Timestamp > a[j].Timestamp
+	}
+	return a[i].pos < a[j].pos
+}
+func (a Links) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+// truncate returns the first links up to limit, extended with the remaining links of the last
+// message so that a message is never split across pages. Returns all the links if limit is zero.
+func (a Links) truncate(limit int) Links {
+	if limit <= 0 || len(a) <= limit {
+		return a
+	}
+	n := limit
+	for n < len(a) && a[n].Timestamp == a[limit-1].Timestamp {
+		n++
+	}
+	return a[:n]
+}
+
+// ExtractLinks returns the distinct http and https URLs found in the plain text and the HTML
+// content of a message, in the order they appear. The anchors of the HTML come first.
+func ExtractLinks(text, htmlText string) []string {
+	var a []string
+	seen := make(map[string]struct{})
+	add := func(s string) {
+		if len(a) >= MaxLinksPerMessage {
+			return
+		}
+		s = normalizeLink(s)
+		if s == "" {
+			return
+		} else if _, ok := seen[s]; ok {
+			return
+		}
+		seen[s] = struct{}{}
+		a = append(a, s)
+	}
+
+	for _, m := range hrefRegexp.FindAllStringSubmatch(htmlText, -1) {
+		add(html.UnescapeString(m[1] + m[2] + m[3]))
+	}
+	for _, s := range linkRegexp.FindAllString(html.UnescapeString(htmlText), -1) {
+		add(s)
+	}
+	for _, s := range linkRegexp.FindAllString(text, -1) {
+		add(s)
+	}
+	return a
+}
+
+// normalizeLink trims the punctuation ending the sentence a URL is written in, and returns an
+// empty string if the result is not an absolute http or https URL.
+func normalizeLink(s string) string {
+	s = strings.TrimSpace(s)
+	for len(s) > 0 {
+		c := s[len(s)-1]
+		if strings.IndexByte(".,;:!?'\"", c) >= 0 {
+			s = s[:len(s)-1]
+		} else if c == ')' && strings.Count(s, "(") < strings.Count(s, ")") {
+			s = s[:len(s)-1]
+		} else {
+			break
+		}
+	}
+
+	if len(s) > MaxLinkLength {
+		return ""
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || (!strings.EqualFold(u.Scheme, "http") && !strings.EqualFold(u.Scheme, "https")) {
+		return ""
+	}
+	return s
+}
+
+// decodeLinks returns the links found in the content of an encoded message. Deleted messages
+// have no links.
+func decodeLinks(codec *FieldCodec, data []byte) []string {
+	if codec == nil {
+		return nil
+	}
+	if v, _ := codec.DecodeByName(fieldDeleted, data); v == true {
+		return nil
+	}
+	text, _ := codec.DecodeByName(fieldText, data)
+	htmlText, _ := codec.DecodeByName(fieldHTML, data)
+	s1, _ := text.(string)
+	s2, _ := htmlText.(string)
+	return ExtractLinks(s1, s2)
+}
+
+// messageLinks returns the links found in an encoded message of a conversation. This function
+// must be called within the context of a lock.
+func (s *Shard) messageLinks(key string, data []byte) []string {
+	c := s.conversationFields[key]
+	if c == nil {
+		return nil
+	}
+	return decodeLinks(c.codec, data)
+}
+
+// indexLinks adds the links of the message of a conversation at timestamp to the links index of
+// the conversation. Entries are keyed by the message timestamp followed by the position of the
+// link in the message, so they iterate in time order.
+func indexLinks(tx *bolt.Tx, key []byte, timestamp int64, links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	b, err := tx.Bucket([]byte("links")).CreateBucketIfNotExists(key)
+	if err != nil {
+		return err
+	}
+	for i, link := range links {
+		if err := b.Put(linkKey(timestamp, i), []byte(link)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexLinks removes the links of the message of a conversation at timestamp.
+func unindexLinks(tx *bolt.Tx, key []byte, timestamp int64) error {
+	b := tx.Bucket([]byte("links")).Bucket(key)
+	if b == nil {
+		return nil
+	}
+	prefix := u64tob(uint64(timestamp))
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkKey returns the key of the index entry of a link of the message at timestamp.
+func linkKey(timestamp int64, pos int) []byte {
+	k := make([]byte, 10)
+	copy(k[0:8], u64tob(uint64(timestamp)))
+	k[8], k[9] = byte(pos>>8), byte(pos)
+	return k
+}
+
+// Links returns the links found in the messages of a conversation written before a timestamp,
+// newest first. A message is never split, so more links than limit may be returned. Returns all
+// the links if limit is zero.
+func (s *Shard) Links(key string, before int64, limit int) (Links, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Messages in the WAL cache are not indexed yet and replace the flushed revisions.
+	pending := make(map[int64][]byte)
+	for _, entry := range s.cache[WALPartition([]byte(key))][key] {
+		timestamp, data := unmarshalCacheEntry(entry)
+		if timestamp < before {
+			pending[timestamp] = data
+		}
+	}
+
+	var a Links
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("links")).Bucket([]byte(key))
+		cb := conversationBucket(tx, key)
+		if b == nil || cb == nil {
+			return nil
+		}
+
+		// Walk the index backwards from the last entry before the timestamp.
+		c := b.Cursor()
+		k, v := c.Seek(u64tob(uint64(before)))
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+
+		var last int64 = -1
+		var sender *Link
+		for ; k != nil; k, v = c.Prev() {
+			timestamp := int64(btou64(k[0:8]))
+			if _, ok := pending[timestamp]; ok {
+				continue
+			}
+
+			// Stop once the limit is reached, at the boundary of a message.
+			if timestamp != last {
+				if limit > 0 && len(a) >= limit {
+					break
+				}
+				data := cb.Get(k[0:8])
+				if data == nil {
+					sender = nil
+				} else {
+					sender = s.linkSender(key, timestamp, data)
+				}
+				last = timestamp
+			}
+			if sender == nil {
+				continue
+			}
+
+			link := *sender
+			link.URL = string(v)
+			link.pos = int(k[8])<<8 | int(k[9])
+			a = append(a, &link)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for timestamp, data := range pending {
+		sender := s.linkSender(key, timestamp, data)
+		for i, u := range s.messageLinks(key, data) {
+			link := *sender
+			link.URL = u
+			link.pos = i
+			a = append(a, &link)
+		}
+	}
+
+	sort.Sort(a)
+	return a.truncate(limit), nil
+}
+
+// linkSender returns a link holding the message ID and the sender of an encoded message of a
+// conversation. This function must be called within the context of a lock.
+func (s *Shard) linkSender(key string, timestamp int64, data []byte) *Link {
+	link := &Link{Timestamp: timestamp}
+	if c := s.conversationFields[key]; c != nil {
+		id, _ := c.codec.DecodeByName(fieldID, data)
+		fromID, _ := c.codec.DecodeByName(fieldFromID, data)
+		fromName, _ := c.codec.DecodeByName(fieldFromName, data)
+		link.MessageID, _ = id.(string)
+		link.SenderID, _ = fromID.(string)
+		link.SenderName, _ = fromName.(string)
+	}
+	return link
+}
+
+// Links returns the links found in the messages of a conversation written before a timestamp,
+// newest first, across all the local shards of a database. Returns all the links if limit is zero.
+func (s *Store) Links(database, key string, before int64, limit int) (Links, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := s.databaseIndexes[database]
+	if index == nil {
+		return nil, nil
+	}
+
+	var a Links
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		links, err := sh.Links(key, before, limit)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, links...)
+	}
+
+	sort.Sort(a)
+	return a.truncate(limit), nil
+}
@@ -0,0 +1,32 @@
+package db_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/messagedb/messagedb/db"
+)
+
+// Ensure links are extracted from plain text and HTML content.
+func TestExtractLinks(t *testing.T) {
+	for i, tt := range []struct {
+		text string
+		html string
+		exp  []string
+	}{
+		{text: "no links here", exp: nil},
+		{text: "see https://example.com/a, and http://example.com/b.", exp: []string{"https://example.com/a", "http://example.com/b"}},
+		{text: "(https://en.wikipedia.org/wiki/Go_(programming_language))", exp: []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"}},
+		{text: "https://example.com twice https://example.com", exp: []string{"https://example.com"}},
+		{text: "ftp://example.com and https:// alone", exp: nil},
+		{
+			text: "docs at https://example.com/text",
+			html: `<p><a class="x" href='https://example.com/b?x=1&amp;y=2'>docs</a> <a href="/relative">r</a></p>`,
+			exp:  []string{"https://example.com/b?x=1&y=2", "https://example.com/text"},
+		},
+	} {
+		if a := db.ExtractLinks(tt.text, tt.html); !reflect.DeepEqual(a, tt.exp) {
+			t.Errorf("%d. links mismatch:\n got %v\n exp %v", i, a, tt.exp)
+		}
+	}
+}
@@ -45,7 +45,11 @@ type LocalMapper struct {
 	tx              *bolt.Tx              // Read transaction for this shard.
 	queryTMin       int64                 // Minimum time of the query.
 	queryTMax       int64                 // Maximum time of the query.
+	descending      bool                  // Messages are read newest first.
 	whereFields     []string              // field names that occur in the where clause
+	filter          sql.Expr              // where clause without the time conditions
+	messageID       string                // ID of the only message matched by the where clause, if any
+	searchQueries   searchQueries         // full-text queries of the where clause
 	selectFields    []string              // field names that occur in the select clause
 	selectTags      []string              // tag keys that occur in the select clause
 	cursors         []*conversationCursor // Cursors per tag sets.
@@ -98,8 +102,26 @@ func (lm *LocalMapper) Open() error {
 		return lm.openMeta()
 	}
 
-	// Set all time-related parameters on the mapper.
-	lm.queryTMin, lm.queryTMax = sql.TimeRangeAsEpochNano(lm.selectStmt.Condition)
+	// Set all time-related parameters on the mapper. Cursors narrow the time range
+	// and paging before a cursor walks the conversations backwards.
+	lm.queryTMin, lm.queryTMax = lm.selectStmt.CursorTimeRange(sql.TimeRangeAsEpochNano(lm.selectStmt.Condition))
+	lm.descending = lm.selectStmt.Descending()
+
+	// Time conditions are handled by seeking the cursors, the rest of the where
+	// clause is evaluated against the decoded fields of each message.
+	lm.filter = conditionWithoutTime(lm.selectStmt.Condition)
+	if lm.searchQueries, err = parseSearchQueries(lm.filter); err != nil {
+		return err
+	}
+	lm.messageID = conditionMessageID(lm.filter)
+
+	selectFields := newStringSet()
+	for _, n := range lm.selectStmt.NamesInSelect() {
+		if n != "time" {
+			selectFields.add(n)
+		}
+	}
+	lm.selectFields = selectFields.list()
 
 	whereFields := newStringSet()
 
@@ -112,9 +134,9 @@ func (lm *LocalMapper) Open() error {
 
 		c := lm.shard.index.Conversation(mm.Name)
 		if c == nil {
-			// This shard have never received data for the measurement. No Mapper
-			// required.
-			return nil
+			// This shard have never received data for the conversation, the other
+			// sources may still have data.
+			continue
 		}
 
 		wfs := newStringSet()
@@ -134,9 +156,30 @@ func (lm *LocalMapper) Open() error {
 			// No data exists for this key.
 			continue
 		}
-
-		convCursor := newConversationCursor(shardCursor, nil)
-		convCursor.SeekTo(lm.queryTMin)
+		if lm.selectStmt.WithHistory {
+			attachHistory(lm.tx, shardCursor, mm.Name)
+		}
+		shardCursor.descending = lm.descending
+
+		convCursor := newConversationCursor(shardCursor, lm.filter)
+		convCursor.conversation = mm.Name
+		convCursor.tmin, convCursor.tmax = lm.queryTMin, lm.queryTMax
+		if lm.messageID != "" && !lm.selectStmt.WithHistory {
+			// The message is looked up in the ID index so the cursor only reads its timestamp.
+			timestamp, ok := lm.shard.messageTimestamp(lm.tx, mm.Name, lm.messageID, shardCursor.cache)
+			if !ok || timestamp < convCursor.tmin || timestamp > convCursor.tmax {
+				continue
+			}
+			convCursor.tmin, convCursor.tmax = timestamp, timestamp
+		}
+		if len(lm.searchQueries) > 0 {
+			convCursor.matches = lm.searchMatches(mm.Name, shardCursor.cache)
+		}
+		if lm.descending {
+			convCursor.SeekTo(convCursor.tmax)
+		} else {
+			convCursor.SeekTo(convCursor.tmin)
+		}
 		lm.cursors = append(lm.cursors, convCursor)
 
 		sort.Sort(conversationCursors(lm.cursors))
@@ -158,6 +201,10 @@ func (lm *LocalMapper) NextChunk() (interface{}, error) {
 		cursor := lm.cursors[lm.currCursorIndex]
 
 		k, v := cursor.Next()
+		if v != nil && (k > cursor.tmax || k < cursor.tmin) {
+			// Past the end of the query time range, nothing more for this cursor.
+			v = nil
+		}
 		if v == nil {
 			// Tagset cursor is empty, move to next one.
 			lm.currCursorIndex++
@@ -170,12 +217,26 @@ func (lm *LocalMapper) NextChunk() (interface{}, error) {
 			}
 		}
 
+		fields, err := lm.shard.FieldCodec(cursor.conversation).DecodeFieldsWithNames(v)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.matches != nil {
+			fields[ContentField] = &contentMatcher{timestamp: k, matches: cursor.matches}
+		}
+		if cursor.filter != nil && !matchesWhere(cursor.filter, fields) {
+			continue
+		}
+		if !lm.matchesThread(fields) {
+			continue
+		}
+
 		if output == nil {
 			output = &MapperOutput{
 				Name: cursor.conversation,
 			}
 		}
-		value := &mapperValue{Time: k, Value: v}
+		value := &mapperValue{Time: k, Value: lm.selectValue(fields)}
 		output.Values = append(output.Values, value)
 		if len(output.Values) == lm.chunkSize {
 			return output, nil
@@ -183,6 +244,68 @@ func (lm *LocalMapper) NextChunk() (interface{}, error) {
 	}
 }
 
+// selectValue returns the value emitted for a message. A single selected field is
+// emitted as is, otherwise all the selected fields are returned keyed by name.
+func (lm *LocalMapper) selectValue(fields map[string]interface{}) interface{} {
+	if len(lm.selectFields) == 1 {
+		return fields[lm.selectFields[0]]
+	}
+
+	values := make(map[string]interface{}, len(lm.selectFields))
+	for _, n := range lm.selectFields {
+		if v, ok := fields[n]; ok {
+			values[n] = v
+		}
+	}
+	return values
+}
+
+// matchesThread returns true if a message belongs to the thread selected by the statement.
+func (lm *LocalMapper) matchesThread(fields map[string]interface{}) bool {
+	parentID, _ := fields[fieldParentID].(string)
+	if lm.selectStmt.Thread != "" {
+		return parentID == lm.selectStmt.Thread
+	}
+	if lm.selectStmt.TopLevel {
+		return parentID == ""
+	}
+	return true
+}
+
+// searchMatches returns the timestamps of the messages of a conversation matching each full-text query.
+func (lm *LocalMapper) searchMatches(name string, cache [][]byte) map[string]map[int64]struct{} {
+	var idx *searchIndex
+	if b := lm.tx.Bucket([]byte("search")).Bucket([]byte(name)); b != nil {
+		idx = &searchIndex{bucket: b}
+	}
+
+	codec := lm.shard.FieldCodec(name)
+	matches := make(map[string]map[int64]struct{}, len(lm.searchQueries))
+	for s, q := range lm.searchQueries {
+		set := make(map[int64]struct{})
+		if idx != nil {
+			set = idx.search(q)
+		}
+
+		// Messages in the WAL cache are not indexed yet and replace the flushed revisions.
+		for _, entry := range cache {
+			timestamp, data := unmarshalCacheEntry(entry)
+			var text string
+			if codec != nil {
+				v, _ := codec.DecodeByName(fieldText, data)
+				text, _ = v.(string)
+			}
+			if q.matchText(text) {
+				set[timestamp] = struct{}{}
+			} else {
+				delete(set, timestamp)
+			}
+		}
+		matches[s] = set
+	}
+	return matches
+}
+
 // Close closes the mapper.
 func (lm *LocalMapper) Close() {
 	if lm != nil && lm.tx != nil {
@@ -197,6 +320,10 @@ type conversationCursor struct {
 	filter       sql.Expr
 	keyBuffer    int64  // The current timestamp key for the cursor
 	valueBuffer  []byte // The current value for the cursor
+	tmin, tmax   int64  // Time range read by the cursor
+
+	// Timestamps of the messages matching each full-text query of the filter.
+	matches map[string]map[int64]struct{}
 }
 
 // conversationCursors represents a sortable slice of conversationCursors.
@@ -276,7 +403,7 @@ func (cc *conversationCursor) Next() (key int64, value []byte) {
 // consolidates both the Bolt store and any WAL cache.
 func createCursorForConversation(tx *bolt.Tx, shard *Shard, key string) *shardCursor {
 	// Retrieve key bucket.
-	b := tx.Bucket([]byte(key))
+	b := conversationBucket(tx, key)
 
 	// Ignore if there is no bucket or points in the cache.
 	partitionID := WALPartition([]byte(key))
@@ -296,6 +423,15 @@ func createCursorForConversation(tx *bolt.Tx, shard *Shard, key string) *shardCu
 	return cur
 }
 
+// attachHistory sets the cursor to return every revision of the messages in the
+// conversation, including the ones that were replaced by edits or deletions.
+func attachHistory(tx *bolt.Tx, cur *shardCursor, key string) {
+	cur.revisions = true
+	if b := tx.Bucket([]byte("history")).Bucket([]byte(key)); b != nil {
+		cur.history = b.Cursor()
+	}
+}
+
 type tagSetsAndFields struct {
 	tagSets      []*sql.TagSet
 	selectFields []string
@@ -350,6 +486,58 @@ func createTagSetsAndFields(c *Conversation, stmt *sql.SelectStatement) (*tagSet
 	}, nil
 }
 
+// conditionWithoutTime returns the where clause with all time conditions folded
+// out. Returns nil if only time conditions remain.
+func conditionWithoutTime(cond sql.Expr) sql.Expr {
+	if cond == nil {
+		return nil
+	}
+
+	expr := sql.RewriteFunc(sql.CloneExpr(cond), func(n sql.Node) sql.Node {
+		if n, ok := n.(*sql.BinaryExpr); ok {
+			if ref, ok := n.LHS.(*sql.VarRef); ok && strings.ToLower(ref.Val) == "time" {
+				return &sql.BooleanLiteral{Val: true}
+			}
+			if ref, ok := n.RHS.(*sql.VarRef); ok && strings.ToLower(ref.Val) == "time" {
+				return &sql.BooleanLiteral{Val: true}
+			}
+		}
+		return n
+	}).(sql.Expr)
+
+	expr = sql.Reduce(expr, nil)
+	if lit, ok := expr.(*sql.BooleanLiteral); ok && lit.Val {
+		return nil
+	}
+	return expr
+}
+
+// conditionMessageID returns the message ID the where clause requires with an id = '...'
+// condition, or an empty string if it matches messages with any ID.
+func conditionMessageID(cond sql.Expr) string {
+	switch expr := cond.(type) {
+	case *sql.ParenExpr:
+		return conditionMessageID(expr.Expr)
+	case *sql.BinaryExpr:
+		switch expr.Op {
+		case sql.AND:
+			if id := conditionMessageID(expr.LHS); id != "" {
+				return id
+			}
+			return conditionMessageID(expr.RHS)
+		case sql.EQ:
+			ref, ok := expr.LHS.(*sql.VarRef)
+			if !ok || ref.Val != fieldID {
+				return ""
+			}
+			if lit, ok := expr.RHS.(*sql.StringLiteral); ok {
+				return lit.Val
+			}
+		}
+	}
+	return ""
+}
+
 // matchesFilter returns true if the value matches the where clause
 func matchesWhere(f sql.Expr, fields map[string]interface{}) bool {
 	if ok, _ := sql.Eval(f, fields).(bool); !ok {
@@ -1,5 +1,405 @@
 package db
 
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/sql"
+)
+
+// Ensure a raw mapper decodes messages and applies the where clause.
+func TestShardMapper_WriteAndSingleMapperMessageQuery(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+	defer shard.Close()
+
+	m1 := NewMessage("general", Sender{UserID: "u1", Name: "jdoe"}, Content{PlainText: "hello"}, nil, time.Unix(1, 0).UTC())
+	m1.SetID("m1")
+	m2 := NewMessage("general", Sender{UserID: "u2", Name: "jane"}, Content{PlainText: "world"}, nil, time.Unix(2, 0).UTC())
+	m2.SetID("m2")
+	m2.AddField("priority", 3)
+	if err := shard.WriteMessages([]Message{m1, m2}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var tests = []struct {
+		stmt      string
+		chunkSize int
+		expected  []string
+	}{
+		{
+			stmt:     `SELECT text FROM general`,
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":"hello"},{"time":2000000000,"value":"world"}]}`, `null`},
+		},
+		{
+			stmt:      `SELECT text FROM general`,
+			chunkSize: 1,
+			expected:  []string{`{"name":"general","values":[{"time":1000000000,"value":"hello"}]}`, `{"name":"general","values":[{"time":2000000000,"value":"world"}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text FROM empty, general`,
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":"hello"},{"time":2000000000,"value":"world"}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT id, text FROM general WHERE "from" = 'u2'`,
+			expected: []string{`{"name":"general","values":[{"time":2000000000,"value":{"id":"m2","text":"world"}}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text, priority FROM general WHERE priority = 3`,
+			expected: []string{`{"name":"general","values":[{"time":2000000000,"value":{"priority":3,"text":"world"}}]}`, `null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM general WHERE id = 'm1' AND time < '%s'`, time.Unix(2, 0).UTC().Format(sql.DateTimeFormat)),
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":"hello"}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE id = 'm3'`,
+			expected: []string{`null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM general BEFORE '%s'`, cursorAt(3)),
+			expected: []string{`{"name":"general","values":[{"time":2000000000,"value":"world"},{"time":1000000000,"value":"hello"}]}`, `null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM general AFTER '%s'`, cursorAt(1)),
+			expected: []string{`{"name":"general","values":[{"time":2000000000,"value":"world"}]}`, `null`},
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		mapper := openRawMapperOrFail(t, shard, stmt, tt.chunkSize)
+		for i, s := range tt.expected {
+			if got := nextRawChunkAsJson(t, mapper); got != s {
+				t.Errorf("test '%s'\n\tgot      %s\n\texpected %s", tt.stmt, got, s)
+				break
+			} else if i == len(tt.expected)-1 {
+				break
+			}
+		}
+		mapper.Close()
+	}
+}
+
+// Ensure edited and deleted messages only return their latest revision unless the
+// history is requested.
+func TestShardMapper_MessageHistoryQuery(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+	defer shard.Close()
+
+	revision := func(text string, editedBy string, deleted bool) Message {
+		m := NewMessage("general", Sender{UserID: "u1", Name: "jdoe"}, Content{PlainText: text}, nil, time.Unix(1, 0).UTC())
+		m.SetID("m1")
+		if editedBy != "" {
+			m.SetEditedAt(time.Unix(10, 0).UTC())
+			m.SetEditedBy(editedBy)
+		}
+		m.SetDeleted(deleted)
+		return m
+	}
+
+	// The first two revisions are flushed to the store, the tombstone stays in the cache.
+	for _, m := range []Message{revision("hello", "", false), revision("hello world", "u1", false)} {
+		if err := shard.WriteMessages([]Message{m}); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := shard.Flush(0); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	if err := shard.WriteMessages([]Message{revision("", "u2", true)}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected []string
+	}{
+		{
+			stmt:     `SELECT text, deleted FROM general`,
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":{"deleted":true,"text":""}}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text, edited_by FROM general WITH HISTORY`,
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":{"text":"hello"}},{"time":1000000000,"value":{"edited_by":"u1","text":"hello world"}},{"time":1000000000,"value":{"edited_by":"u2","text":""}}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE edited_by = 'u1' WITH HISTORY`,
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":"hello world"}]}`, `null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text, edited_by FROM general WITH HISTORY BEFORE '%s'`, cursorAt(2)),
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":{"edited_by":"u2","text":""}},{"time":1000000000,"value":{"edited_by":"u1","text":"hello world"}},{"time":1000000000,"value":{"text":"hello"}}]}`, `null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text, deleted FROM general BEFORE '%s'`, cursorAt(2)),
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":{"deleted":true,"text":""}}]}`, `null`},
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		mapper := openRawMapperOrFail(t, shard, stmt, 0)
+		for _, s := range tt.expected {
+			if got := nextRawChunkAsJson(t, mapper); got != s {
+				t.Errorf("test '%s'\n\tgot      %s\n\texpected %s", tt.stmt, got, s)
+				break
+			}
+		}
+		mapper.Close()
+	}
+}
+
+// Ensure messages selected by ID are found through the ID index and in the cache.
+func TestShardMapper_MessageIDQuery(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+	defer shard.Close()
+
+	message := func(key, id, text string, sec int64) Message {
+		m := NewMessage(key, Sender{UserID: "u1", Name: "jdoe"}, Content{PlainText: text}, nil, time.Unix(sec, 0).UTC())
+		m.SetID(id)
+		return m
+	}
+
+	// The first messages are flushed to the store and indexed, the last one stays in the cache.
+	if err := shard.WriteMessages([]Message{message("general", "m1", "hello", 1), message("general", "m2", "world", 2), message("random", "m1", "other", 1)}); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := shard.Flush(0); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := shard.WriteMessages([]Message{message("general", "m3", "cached", 3)}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected []string
+	}{
+		{
+			stmt:     `SELECT text FROM general WHERE id = 'm2'`,
+			expected: []string{`{"name":"general","values":[{"time":2000000000,"value":"world"}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE id = 'm3'`,
+			expected: []string{`{"name":"general","values":[{"time":3000000000,"value":"cached"}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text FROM general, random WHERE id = 'm1'`,
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":"hello"}]}`, `{"name":"random","values":[{"time":1000000000,"value":"other"}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE id = 'm1' OR id = 'm3'`,
+			expected: []string{`{"name":"general","values":[{"time":1000000000,"value":"hello"},{"time":3000000000,"value":"cached"}]}`, `null`},
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE id = 'm2' AND "from" = 'u2'`,
+			expected: []string{`null`},
+		},
+		{
+			stmt:     fmt.Sprintf(`SELECT text FROM general WHERE id = 'm2' BEFORE '%s'`, cursorAt(2)),
+			expected: []string{`null`},
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		mapper := openRawMapperOrFail(t, shard, stmt, 0)
+		for _, s := range tt.expected {
+			if got := nextRawChunkAsJson(t, mapper); got != s {
+				t.Errorf("test '%s'\n\tgot      %s\n\texpected %s", tt.stmt, got, s)
+				break
+			}
+		}
+		mapper.Close()
+	}
+}
+
+// Ensure select statements return a single thread or the top-level messages only.
+func TestShardMapper_MessageThreadQuery(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+	defer shard.Close()
+
+	message := func(sec int64, parentID, text string) Message {
+		m := NewMessage("general", Sender{UserID: "u1", Name: "jdoe"}, Content{PlainText: text}, nil, time.Unix(sec, 0).UTC())
+		m.SetID(fmt.Sprintf("m%d", sec))
+		m.SetParentID(parentID)
+		return m
+	}
+	if err := shard.WriteMessages([]Message{
+		message(1, "", "question"),
+		message(2, "m1", "answer"),
+		message(3, "", "unrelated"),
+		message(4, "m1", "thanks"),
+	}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     `SELECT text FROM general THREAD 'm1'`,
+			expected: `{"name":"general","values":[{"time":2000000000,"value":"answer"},{"time":4000000000,"value":"thanks"}]}`,
+		},
+		{
+			stmt:     `SELECT text FROM general WITHOUT REPLIES`,
+			expected: `{"name":"general","values":[{"time":1000000000,"value":"question"},{"time":3000000000,"value":"unrelated"}]}`,
+		},
+		{
+			stmt:     `SELECT text FROM general THREAD 'm3'`,
+			expected: `null`,
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		mapper := openRawMapperOrFail(t, shard, stmt, 0)
+		if got := nextRawChunkAsJson(t, mapper); got != tt.expected {
+			t.Errorf("test '%s'\n\tgot      %s\n\texpected %s", tt.stmt, got, tt.expected)
+		}
+		mapper.Close()
+	}
+}
+
+// Ensure snippets are selected by kind and language.
+func TestShardMapper_MessageSnippetQuery(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+	defer shard.Close()
+
+	message := func(sec int64, text string, snippet *Snippet) Message {
+		m := NewMessage("general", Sender{UserID: "u1", Name: "jdoe"}, Content{PlainText: text}, nil, time.Unix(sec, 0).UTC())
+		m.SetID(fmt.Sprintf("m%d", sec))
+		m.SetSnippet(snippet)
+		return m
+	}
+	if err := shard.WriteMessages([]Message{
+		message(1, "hello", nil),
+		message(2, "server", &Snippet{Language: "go", Filename: "main.go", Body: "package main"}),
+		message(3, "query", &Snippet{Language: "sql", Body: "SELECT 1"}),
+		message(4, "untitled", &Snippet{Body: "echo hi"}),
+	}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     `SELECT text FROM general WHERE kind = 'snippet'`,
+			expected: `{"name":"general","values":[{"time":2000000000,"value":"server"},{"time":3000000000,"value":"query"},{"time":4000000000,"value":"untitled"}]}`,
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE kind = 'snippet' AND snippet_language = 'go'`,
+			expected: `{"name":"general","values":[{"time":2000000000,"value":"server"}]}`,
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE kind = 'snippet' AND snippet_language = 'rust'`,
+			expected: `null`,
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		mapper := openRawMapperOrFail(t, shard, stmt, 0)
+		if got := nextRawChunkAsJson(t, mapper); got != tt.expected {
+			t.Errorf("test '%s'\n\tgot      %s\n\texpected %s", tt.stmt, got, tt.expected)
+		}
+		mapper.Close()
+	}
+}
+
+// Ensure the full-text index matches terms, phrases and prefixes, and follows edits.
+func TestShardMapper_MessageSearchQuery(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	shard := mustCreateShard(tmpDir)
+	defer shard.Close()
+
+	message := func(sec int64, text string) Message {
+		m := NewMessage("general", Sender{UserID: "u1", Name: "jdoe"}, Content{PlainText: text}, nil, time.Unix(sec, 0).UTC())
+		m.SetID(fmt.Sprintf("m%d", sec))
+		return m
+	}
+
+	// The first messages are flushed to the index, the edit of the second one stays in the cache.
+	if err := shard.WriteMessages([]Message{
+		message(1, "The deploy failed again"),
+		message(2, "Deployment is green"),
+		message(3, "failed to deploy, rolling back"),
+	}); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := shard.Flush(0); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := shard.WriteMessages([]Message{message(2, "Deployment is red")}); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var tests = []struct {
+		stmt     string
+		expected string
+	}{
+		{
+			stmt:     `SELECT text FROM general WHERE content MATCHES 'deploy failed'`,
+			expected: `{"name":"general","values":[{"time":1000000000,"value":"The deploy failed again"},{"time":3000000000,"value":"failed to deploy, rolling back"}]}`,
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE content MATCHES '"deploy failed"'`,
+			expected: `{"name":"general","values":[{"time":1000000000,"value":"The deploy failed again"}]}`,
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE content MATCHES 'deploy*'`,
+			expected: `{"name":"general","values":[{"time":1000000000,"value":"The deploy failed again"},{"time":2000000000,"value":"Deployment is red"},{"time":3000000000,"value":"failed to deploy, rolling back"}]}`,
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE content MATCHES 'green'`,
+			expected: `null`,
+		},
+		{
+			stmt:     `SELECT text FROM general WHERE content MATCHES 'red' OR content MATCHES 'again'`,
+			expected: `{"name":"general","values":[{"time":1000000000,"value":"The deploy failed again"},{"time":2000000000,"value":"Deployment is red"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := mustParseSelectStatement(tt.stmt)
+		mapper := openRawMapperOrFail(t, shard, stmt, 0)
+		if got := nextRawChunkAsJson(t, mapper); got != tt.expected {
+			t.Errorf("test '%s'\n\tgot      %s\n\texpected %s", tt.stmt, got, tt.expected)
+		}
+		mapper.Close()
+	}
+
+	// Once flushed, the edit replaces the indexed text.
+	if err := shard.Flush(0); err != nil {
+		t.Fatalf(err.Error())
+	}
+	mapper := openRawMapperOrFail(t, shard, mustParseSelectStatement(`SELECT text FROM general WHERE content MATCHES 'red'`), 0)
+	defer mapper.Close()
+	if got, exp := nextRawChunkAsJson(t, mapper), `{"name":"general","values":[{"time":2000000000,"value":"Deployment is red"}]}`; got != exp {
+		t.Errorf("got %s, expected %s", got, exp)
+	}
+}
+
 // func TestShardMapper_WriteAndSingleMapperRawQuery(t *testing.T) {
 // 	tmpDir, _ := ioutil.TempDir("", "shard_test")
 // 	defer os.RemoveAll(tmpDir)
@@ -383,45 +783,45 @@ package db
 //
 // }
 //
-// func mustCreateShard(dir string) *Shard {
-// 	tmpShard := path.Join(dir, "shard")
-// 	index := NewDatabaseIndex()
-// 	sh := NewShard(index, tmpShard)
-// 	if err := sh.Open(); err != nil {
-// 		panic(fmt.Sprintf("error opening shard: %s", err.Error()))
-// 	}
-// 	return sh
-// }
-//
-// // mustParseSelectStatement parses a select statement. Panic on error.
-// func mustParseSelectStatement(s string) *sql.SelectStatement {
-// 	stmt, err := sql.NewParser(strings.NewReader(s)).ParseStatement()
-// 	if err != nil {
-// 		panic(err)
-// 	}
-// 	return stmt.(*sql.SelectStatement)
-// }
-//
-// func openRawMapperOrFail(t *testing.T, shard *Shard, stmt *sql.SelectStatement, chunkSize int) Mapper {
-// 	mapper := NewLocalMapper(shard, stmt, chunkSize)
-//
-// 	if err := mapper.Open(); err != nil {
-// 		t.Fatalf("failed to open raw mapper: %s", err.Error())
-// 	}
-// 	return mapper
-// }
-//
-// func nextRawChunkAsJson(t *testing.T, mapper Mapper) string {
-// 	r, err := mapper.NextChunk()
-// 	if err != nil {
-// 		t.Fatalf("failed to get next chunk from mapper: %s", err.Error())
-// 	}
-// 	b, err := json.Marshal(r)
-// 	if err != nil {
-// 		t.Fatalf("failed to marshal chunk as JSON: %s", err.Error())
-// 	}
-// 	return string(b)
-// }
+func mustCreateShard(dir string) *Shard {
+	tmpShard := path.Join(dir, "shard")
+	index := NewDatabaseIndex()
+	sh := NewShard(index, tmpShard)
+	if err := sh.Open(); err != nil {
+		panic(fmt.Sprintf("error opening shard: %s", err.Error()))
+	}
+	return sh
+}
+//
+// mustParseSelectStatement parses a select statement. Panic on error.
+func mustParseSelectStatement(s string) *sql.SelectStatement {
+	stmt, err := sql.NewParser(strings.NewReader(s)).ParseStatement()
+	if err != nil {
+		panic(err)
+	}
+	return stmt.(*sql.SelectStatement)
+}
+//
+func openRawMapperOrFail(t *testing.T, shard *Shard, stmt *sql.SelectStatement, chunkSize int) Mapper {
+	mapper := NewLocalMapper(shard, stmt, chunkSize)
+
+	if err := mapper.Open(); err != nil {
+		t.Fatalf("failed to open raw mapper: %s", err.Error())
+	}
+	return mapper
+}
+//
+func nextRawChunkAsJson(t *testing.T, mapper Mapper) string {
+	r, err := mapper.NextChunk()
+	if err != nil {
+		t.Fatalf("failed to get next chunk from mapper: %s", err.Error())
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("failed to marshal chunk as JSON: %s", err.Error())
+	}
+	return string(b)
+}
 //
 // func openLocalMapperOrFail(t *testing.T, shard *Shard, stmt *sql.SelectStatement) *LocalMapper {
 // 	mapper := NewLocalMapper(shard, stmt, 0)
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/boltdb/bolt"
+)
+
+// MentionedMessage is a message mentioning a user, as listed by SHOW MENTIONS.
+type MentionedMessage struct {
+	Conversation string
+	Timestamp    int64
+	Values       map[string]interface{}
+}
+
+// MentionedMessages sorts mentioned messages newest first.
+type MentionedMessages []*MentionedMessage
+
+func (a MentionedMessages) Len() int { return len(a) }
+func (a MentionedMessages) Less(i, j int) bool {
+	if a[i].Timestamp != a[j].Timestamp {
+		return a[i].Timestamp > a[j].Timestamp
+	}
+	return a[i].Conversation < a[j].Conversation
+}
+func (a MentionedMessages) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+// mentionIndex is the index of the messages mentioning a user in a shard. Entries are keyed
+// by the message timestamp followed by the conversation key, so they iterate in time order.
+type mentionIndex struct {
+	bucket *bolt.Bucket
+}
+
+// createMentionIndexIfNotExists returns the mentions index of a user.
+func createMentionIndexIfNotExists(tx *bolt.Tx, userID string) (*mentionIndex, error) {
+	b, err := tx.Bucket([]byte("mentions")).CreateBucketIfNotExists([]byte(userID))
+	if err != nil {
+		return nil, err
+	}
+	return &mentionIndex{bucket: b}, nil
+}
+
+// indexMentions adds the message of a conversation at timestamp to the index of each mentioned user.
+func indexMentions(tx *bolt.Tx, key []byte, timestamp int64, userIDs []string) error {
+	for _, userID := range userIDs {
+		idx, err := createMentionIndexIfNotExists(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := idx.bucket.Put(mentionKey(key, timestamp), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexMentions removes the message of a conversation at timestamp from the index of each mentioned user.
+func unindexMentions(tx *bolt.Tx, key []byte, timestamp int64, userIDs []string) error {
+	for _, userID := range userIDs {
+		b := tx.Bucket([]byte("mentions")).Bucket([]byte(userID))
+		if b == nil {
+			continue
+		}
+		if err := b.Delete(mentionKey(key, timestamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexConversationMentions removes the flushed messages of a conversation from the index of
+// each user they mention. This function must be called within the context of a lock.
+func (s *Shard) unindexConversationMentions(tx *bolt.Tx, key string) error {
+	b := conversationBucket(tx, key)
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(k, v []byte) error {
+		return unindexMentions(tx, []byte(key), int64(btou64(k)), s.messageMentions(key, v))
+	})
+}
+
+// mentionKey returns the key of the index entry of the message of a conversation at timestamp.
+func mentionKey(key []byte, timestamp int64) []byte {
+	k := make([]byte, 8+len(key))
+	copy(k[0:8], u64tob(uint64(timestamp)))
+	copy(k[8:], key)
+	return k
+}
+
+// decodeMentions returns the IDs of the users mentioned in the mentions field of a message.
+// Deleted messages mention no one.
+func decodeMentions(codec *FieldCodec, data []byte) []string {
+	if codec == nil {
+		return nil
+	}
+	if v, _ := codec.DecodeByName(fieldDeleted, data); v == true {
+		return nil
+	}
+
+	v, _ := codec.DecodeByName(fieldMentions, data)
+	s, ok := v.(string)
+	if !ok {
+		return nil
+	}
+
+	var mentions []Mention
+	if err := json.Unmarshal([]byte(s), &mentions); err != nil {
+		return nil
+	}
+
+	ids := make([]string, 0, len(mentions))
+	for _, m := range mentions {
+		if m.RecipientID != "" {
+			ids = append(ids, m.RecipientID)
+		}
+	}
+	return ids
+}
+
+// mentionsUser returns true if the user is one of the mentioned users.
+func mentionsUser(userIDs []string, userID string) bool {
+	for _, id := range userIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// messageMentions returns the IDs of the users mentioned in an encoded message of a conversation.
+// This function must be called within the context of a lock.
+func (s *Shard) messageMentions(key string, data []byte) []string {
+	c := s.conversationFields[key]
+	if c == nil {
+		return nil
+	}
+	return decodeMentions(c.codec, data)
+}
+
+// Mentions returns the messages of the shard mentioning a user written before a timestamp,
+// newest first. Returns all the messages if limit is zero.
+func (s *Shard) Mentions(userID string, before int64, limit int) (MentionedMessages, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Messages in the WAL cache are not indexed yet and replace the flushed revisions.
+	type cacheKey struct {
+		conversation string
+		timestamp    int64
+	}
+	pending := make(map[cacheKey][]byte)
+	for _, partition := range s.cache {
+		for key, entries := range partition {
+			for _, entry := range entries {
+				timestamp, data := unmarshalCacheEntry(entry)
+				if timestamp < before {
+					pending[cacheKey{key, timestamp}] = data
+				}
+			}
+		}
+	}
+
+	var a MentionedMessages
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("mentions")).Bucket([]byte(userID))
+		if b == nil {
+			return nil
+		}
+
+		// Walk the index backwards from the last entry before the timestamp.
+		c := b.Cursor()
+		k, _ := c.Seek(u64tob(uint64(before)))
+		if k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+
+		for ; k != nil && (limit <= 0 || len(a) < limit); k, _ = c.Prev() {
+			timestamp, key := int64(btou64(k[0:8])), string(k[8:])
+			if _, ok := pending[cacheKey{key, timestamp}]; ok {
+				continue
+			}
+
+			cb := conversationBucket(tx, key)
+			if cb == nil {
+				continue
+			}
+			data := cb.Get(k[0:8])
+			if data == nil {
+				continue
+			}
+
+			m, err := s.mentionedMessage(key, timestamp, data)
+			if err != nil {
+				return err
+			}
+			a = append(a, m)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for k, data := range pending {
+		if !mentionsUser(s.messageMentions(k.conversation, data), userID) {
+			continue
+		}
+		m, err := s.mentionedMessage(k.conversation, k.timestamp, data)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, m)
+	}
+
+	sort.Sort(a)
+	if limit > 0 && len(a) > limit {
+		a = a[:limit]
+	}
+	return a, nil
+}
+
+// mentionedMessage decodes an encoded message of a conversation. This function must be called
+// within the context of a lock.
+func (s *Shard) mentionedMessage(key string, timestamp int64, data []byte) (*MentionedMessage, error) {
+	m := &MentionedMessage{Conversation: key, Timestamp: timestamp}
+	if c := s.conversationFields[key]; c != nil {
+		values, err := c.codec.DecodeFieldsWithNames(data)
+		if err != nil {
+			return nil, err
+		}
+		m.Values = values
+	}
+	return m, nil
+}
+
+// Mentions returns the messages of a database mentioning a user written before a timestamp,
+// newest first, across all the local shards. Returns all the messages if limit is zero.
+func (s *Store) Mentions(database, userID string, before int64, limit int) (MentionedMessages, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := s.databaseIndexes[database]
+	if index == nil {
+		return nil, nil
+	}
+
+	var a MentionedMessages
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		messages, err := sh.Mentions(userID, before, limit)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, messages...)
+	}
+
+	sort.Sort(a)
+	if limit > 0 && len(a) > limit {
+		a = a[:limit]
+	}
+	return a, nil
+}
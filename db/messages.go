@@ -1,11 +1,22 @@
 package db
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+
+	"github.com/messagedb/messagedb/sql"
 )
 
+// Message represents a single chat message written to a conversation.
 type Message interface {
 	Time() time.Time
 	SetTime(t time.Time)
@@ -14,68 +25,681 @@ type Message interface {
 	HashID() uint64
 	Key() []byte
 
+	ID() string
+	SetID(id string)
+
+	ParentID() string
+	SetParentID(id string)
+
+	From() Sender
+	Content() Content
+	Mentions() []Mention
+
+	Kind() string
+	Snippet() *Snippet
+	SetSnippet(s *Snippet)
+	Event() string
+	SetEvent(event string)
+
+	EditedAt() time.Time
+	SetEditedAt(t time.Time)
+	EditedBy() string
+	SetEditedBy(userID string)
+	Deleted() bool
+	SetDeleted(deleted bool)
+
+	Fields() Fields
+	AddField(name string, value interface{})
+
 	Data() []byte
 	SetData(buf []byte)
 
 	String() string
+	PrecisionString(precision string) string
+}
+
+// Sender identifies the user that authored a message.
+type Sender struct {
+	UserID string
+	Name   string
+}
+
+// Content holds the body of a message. PlainText is always present, HTML is
+// an optional rich rendering of the same body.
+type Content struct {
+	PlainText string
+	HTML      string
+}
+
+// MaxContentLength is the maximum length in bytes of the plain text and of the
+// HTML of a message, the largest string field value that can be stored.
+const MaxContentLength = maxStringLength
+
+// Validate returns ErrContentTooLarge if the content cannot be stored.
+func (c Content) Validate() error {
+	if len(c.PlainText) > MaxContentLength || len(c.HTML) > MaxContentLength {
+		return ErrContentTooLarge
+	}
+	return nil
+}
+
+// Mention references a user that was mentioned in a message.
+type Mention struct {
+	RecipientID       string
+	RecipientUsername string
+}
+
+// Kinds of messages.
+const (
+	// MessageKindText is the kind of a regular message.
+	MessageKindText = "text"
+
+	// MessageKindSnippet is the kind of a message holding a code snippet.
+	MessageKindSnippet = "snippet"
+
+	// MessageKindSystem is the kind of a message written by the server to record an event of
+	// the conversation.
+	MessageKindSystem = "system"
+)
+
+// Events recorded by system messages.
+const (
+	// EventParticipantJoined records a user joining a conversation or being added to it. The
+	// sender is the user that added the participant, who is mentioned in the message.
+	EventParticipantJoined = "participant_joined"
+
+	// EventParticipantLeft records a user leaving a conversation or being removed from it. The
+	// sender is the user that removed the participant, who is mentioned in the message.
+	EventParticipantLeft = "participant_left"
+)
+
+// Fields represents the custom typed fields attached to a message. Values are
+// float64, int64, bool or string.
+type Fields map[string]interface{}
+
+// Names of the fields used to encode the structure of a message. Custom fields
+// may not use these names.
+const (
+	fieldID       = "id"
+	fieldFromID   = "from"
+	fieldFromName = "from_name"
+	fieldText     = "text"
+	fieldHTML     = "html"
+	fieldMentions = "mentions"
+	fieldEditedAt = "edited_at"
+	fieldEditedBy = "edited_by"
+	fieldDeleted  = "deleted"
+	fieldParentID = "parent_id"
+
+	fieldKind            = "kind"
+	fieldEvent           = "event"
+	fieldSnippetLanguage = "snippet_language"
+	fieldSnippetFilename = "snippet_filename"
+	fieldSnippetBody     = "snippet_body"
+)
+
+// isReservedField returns true if name is used to encode the message structure,
+// or starts with the prefix of a group of such names.
+func isReservedField(name string) bool {
+	switch name {
+	case fieldID, fieldFromID, fieldFromName, fieldText, fieldHTML, fieldMentions, fieldEditedAt, fieldEditedBy, fieldDeleted, fieldParentID,
+		fieldKind, fieldEvent, fieldSnippetLanguage, fieldSnippetFilename, fieldSnippetBody:
+		return true
+	}
+	return strings.HasPrefix(name, "snippet_") || strings.HasPrefix(name, "edited_")
+}
+
+// isValidFieldName returns true if name is a non-empty custom field name made
+// of letters, digits, spaces, '_', '-' and '.'.
+func isValidFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+		case r == ' ', r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 // message is the default implementation of Message.
 type message struct {
 	time time.Time
 
+	// conversation key
 	key []byte
 
-	// text encoding of timestamp
-	ts []byte
+	id       string
+	parentID string
+	from     Sender
+	content  Content
+	mentions []Mention
+
+	// code snippet, nil for a regular message
+	snippet *Snippet
+
+	// event recorded by a system message
+	event string
+
+	// edit and delete markers
+	editedAt time.Time
+	editedBy string
+	deleted  bool
+
+	// custom typed fields
+	fields Fields
 
 	// binary encoded field data
 	data []byte
 }
 
+var (
+	// ErrMissingConversation is returned when a line has no conversation key.
+	ErrMissingConversation = errors.New("missing conversation key")
+
+	// ErrMissingContent is returned when a line has no text field.
+	ErrMissingContent = errors.New("missing text field")
+
+	// ErrInvalidMessageKind is returned when a message has an unknown kind, or fields that do
+	// not belong to its kind.
+	ErrInvalidMessageKind = errors.New("invalid message kind")
+
+	// ErrFieldNameReserved is returned when a custom field uses the name of a
+	// field that encodes the message structure.
+	ErrFieldNameReserved = errors.New("field name reserved")
+
+	// ErrContentTooLarge is returned when the text or the HTML of a message
+	// exceeds MaxContentLength.
+	ErrContentTooLarge = errors.New("content too large")
+
+	// ErrInvalidFieldName is returned when a custom field name is empty or has
+	// characters other than letters, digits, spaces, '_', '-' and '.'.
+	ErrInvalidFieldName = errors.New("invalid field name")
+)
+
+// ParseError describes a single line that could not be parsed.
+type ParseError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("unable to parse line %d '%s': %v", e.Line, e.Text, e.Err)
+}
+
+// ParseErrors is returned by ParseMessages when one or more lines could not be
+// parsed. The messages from all other lines are still returned.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	a := make([]string, len(e))
+	for i, err := range e {
+		a[i] = err.Error()
+	}
+	return strings.Join(a, "\n")
+}
+
 func ParseMessagesString(buf string) ([]Message, error) {
 	return ParseMessages([]byte(buf))
 }
 
+// ParseMessages parses a buffer of messages written in the line protocol,
+// using the current time for lines without a timestamp. Timestamps are in
+// nanoseconds.
+//
+// Each message is a single line made of three space separated sections:
+//
+//	<conversation>[,<tag>=<value>...] text="<plain>"[,<field>=<value>...] [<timestamp>]
+//
+// The supported tags are id, parent, from, from_name and mention. The parent tag
+// holds the id of the message a reply belongs to. The mention tag may
+// be repeated and its value is the recipient id and username separated by a
+// colon. Commas, spaces, equal signs and colons in the conversation key, tag
+// values and field names are escaped with a backslash.
+//
+// The text field holds the plain text content, html an optional rich
+// rendering, edited_at the edit time in nanoseconds, edited_by the id of the
+// user that made the edit and deleted marks a deleted message. A snippet
+// message has kind set to "snippet" and holds its code in snippet_body, with
+// the optional snippet_language and snippet_filename. A system message has
+// kind set to "system" and the event it records in event. Any other field is a
+// custom typed field. String values are
+// double quoted and may contain \" \\ and \n escapes as well as raw
+// newlines, which allows multi-line content. Integers have an i suffix,
+// booleans are written as true or false and all other numbers are floats.
+// Blank lines and lines starting with '#' are skipped.
 func ParseMessages(buf []byte) ([]Message, error) {
+	return ParseMessagesWithPrecision(buf, time.Now().UTC(), "n")
+}
+
+// ParseMessagesWithPrecision is like ParseMessages but uses defaultTime for
+// lines without a timestamp and interprets timestamps in the given precision.
+// Valid precisions are n, u, ms, s, m and h.
+func ParseMessagesWithPrecision(buf []byte, defaultTime time.Time, precision string) ([]Message, error) {
 	messages := []Message{}
 	var (
-		pos   int
-		block []byte
+		pos    int
+		block  []byte
+		line   = 1
+		errs   ParseErrors
+		lineno int
 	)
-	for {
-		pos, block = scanTo(buf, pos, '\n')
+	for pos < len(buf) {
+		lineno = line
+		pos, block = scanLine(buf, pos)
+		line += bytes.Count(block, []byte{'\n'}) + 1
 		pos++
 
-		if len(block) == 0 {
-			break
-		}
-
-		if start := skipWhitespace(block, 0); block[start] == '#' {
+		block = bytes.TrimRight(block, "\r")
+		start := skipWhitespace(block, 0)
+		if start >= len(block) || block[start] == '#' {
 			continue
 		}
 
-		m, err := parseMessage(buf, time.Now().UTC())
+		m, err := parseMessage(block[start:], defaultTime, precision)
 		if err != nil {
-			return nil, fmt.Errorf("unable to parse '%s': %v", string(block), err)
+			errs = append(errs, &ParseError{Line: lineno, Text: string(block), Err: err})
+			continue
 		}
 		messages = append(messages, m)
+	}
+
+	if len(errs) > 0 {
+		return messages, errs
+	}
+	return messages, nil
+}
+
+func parseMessage(buf []byte, defaultTime time.Time, precision string) (Message, error) {
+	m := &message{}
 
-		if pos >= len(buf) {
+	// conversation key
+	i, key := scanKey(buf, 0)
+	if len(key) == 0 {
+		return nil, ErrMissingConversation
+	}
+	m.key = unescape(key)
+
+	// optional tags
+	for i < len(buf) && buf[i] == ',' {
+		var tag []byte
+		i, tag = scanKey(buf, i+1)
+		if err := m.parseTag(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	// fields
+	i = skipWhitespace(buf, i)
+	if i >= len(buf) {
+		return nil, ErrMissingContent
+	}
+	i, err := m.parseFields(buf, i)
+	if err != nil {
+		return nil, err
+	}
+
+	// optional timestamp
+	i = skipWhitespace(buf, i)
+	if i >= len(buf) {
+		m.time = defaultTime
+		return m, nil
+	}
+	end, ts := scanTo(buf, i, ' ')
+	if skipWhitespace(buf, end) < len(buf) {
+		return nil, fmt.Errorf("unexpected data after timestamp: '%s'", buf[end:])
+	}
+	n, err := strconv.ParseInt(string(ts), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp '%s'", ts)
+	}
+	if m.time, err = timeWithPrecision(n, precision); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseTag applies a single unescaped name=value tag to the message.
+func (m *message) parseTag(tag []byte) error {
+	i, name := scanTo(tag, 0, '=')
+	if i >= len(tag) || len(name) == 0 {
+		return fmt.Errorf("invalid tag '%s'", tag)
+	}
+	value := tag[i+1:]
+
+	switch string(name) {
+	case "id":
+		m.id = string(unescape(value))
+	case "parent":
+		m.parentID = string(unescape(value))
+	case "from":
+		m.from.UserID = string(unescape(value))
+	case "from_name":
+		m.from.Name = string(unescape(value))
+	case "mention":
+		j, id := scanTo(value, 0, ':')
+		if j >= len(value) || len(id) == 0 {
+			return fmt.Errorf("invalid mention '%s': expected <id>:<username>", value)
+		}
+		m.mentions = append(m.mentions, Mention{
+			RecipientID:       string(unescape(id)),
+			RecipientUsername: string(unescape(value[j+1:])),
+		})
+	default:
+		return fmt.Errorf("unknown tag '%s'", name)
+	}
+	return nil
+}
+
+// parseFields parses the comma separated fields starting at i and returns the
+// position after the last field.
+func (m *message) parseFields(buf []byte, i int) (int, error) {
+	var hasText bool
+	var kind string
+	var snippet Snippet
+	var hasSnippet bool
+	for {
+		end, name := scanTo(buf, i, '=')
+		if end >= len(buf) || len(name) == 0 {
+			return 0, fmt.Errorf("invalid field '%s'", buf[i:end])
+		}
+		name = unescape(name)
+
+		var value interface{}
+		var err error
+		if i, value, err = scanFieldValue(buf, end+1); err != nil {
+			return 0, fmt.Errorf("field '%s': %v", name, err)
+		}
+
+		switch string(name) {
+		case fieldText, fieldHTML:
+			v, ok := value.(string)
+			if !ok {
+				return 0, fmt.Errorf("field '%s' must be a string", name)
+			}
+			if string(name) == fieldText {
+				m.content.PlainText, hasText = v, true
+			} else {
+				m.content.HTML = v
+			}
+		case fieldEditedAt:
+			v, ok := value.(int64)
+			if !ok {
+				return 0, fmt.Errorf("field '%s' must be an integer", name)
+			}
+			m.editedAt = time.Unix(0, v).UTC()
+		case fieldEditedBy:
+			v, ok := value.(string)
+			if !ok {
+				return 0, fmt.Errorf("field '%s' must be a string", name)
+			}
+			m.editedBy = v
+		case fieldDeleted:
+			v, ok := value.(bool)
+			if !ok {
+				return 0, fmt.Errorf("field '%s' must be a boolean", name)
+			}
+			m.deleted = v
+		case fieldKind:
+			v, ok := value.(string)
+			if !ok {
+				return 0, fmt.Errorf("field '%s' must be a string", name)
+			} else if v != MessageKindText && v != MessageKindSnippet && v != MessageKindSystem {
+				return 0, fmt.Errorf("field '%s': %v", name, ErrInvalidMessageKind)
+			}
+			kind = v
+		case fieldEvent:
+			v, ok := value.(string)
+			if !ok {
+				return 0, fmt.Errorf("field '%s' must be a string", name)
+			}
+			m.event = v
+		case fieldSnippetLanguage, fieldSnippetFilename, fieldSnippetBody:
+			v, ok := value.(string)
+			if !ok {
+				return 0, fmt.Errorf("field '%s' must be a string", name)
+			}
+			switch string(name) {
+			case fieldSnippetLanguage:
+				snippet.Language = v
+			case fieldSnippetFilename:
+				snippet.Filename = v
+			default:
+				snippet.Body = v
+			}
+			hasSnippet = true
+		default:
+			if isReservedField(string(name)) {
+				return 0, fmt.Errorf("field '%s': %v", name, ErrFieldNameReserved)
+			}
+			m.AddField(string(name), value)
+		}
+
+		if i >= len(buf) || buf[i] != ',' {
 			break
 		}
+		i++
 	}
 
-	return messages, nil
+	if !hasText {
+		return 0, ErrMissingContent
+	}
+
+	// Snippet fields imply a snippet message and an event a system message.
+	if kind == "" && m.event != "" {
+		kind = MessageKindSystem
+	} else if kind == "" && hasSnippet {
+		kind = MessageKindSnippet
+	}
+	if (kind != MessageKindSnippet && hasSnippet) || (kind != MessageKindSystem && m.event != "") {
+		return 0, fmt.Errorf("field '%s': %v", fieldKind, ErrInvalidMessageKind)
+	} else if kind == MessageKindSystem && m.event == "" {
+		return 0, fmt.Errorf("field '%s' required", fieldEvent)
+	} else if kind == MessageKindSnippet {
+		if err := snippet.Validate(); err != nil {
+			return 0, err
+		}
+		m.snippet = &snippet
+	}
+	return i, nil
+}
+
+// scanFieldValue reads a field value starting at i and returns the position
+// after the value. Quoted values are strings, t/true and f/false are booleans,
+// numbers with an i suffix are integers and all other numbers are floats.
+func scanFieldValue(buf []byte, i int) (int, interface{}, error) {
+	if i < len(buf) && buf[i] == '"' {
+		return scanQuoted(buf, i)
+	}
+
+	i, v := scanKey(buf, i)
+	switch string(v) {
+	case "":
+		return 0, nil, errors.New("missing value")
+	case "t", "T", "true", "True", "TRUE":
+		return i, true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return i, false, nil
+	}
+
+	if v[len(v)-1] == 'i' {
+		n, err := strconv.ParseInt(string(v[:len(v)-1]), 10, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid integer '%s'", v)
+		}
+		return i, n, nil
+	}
+
+	f, err := strconv.ParseFloat(string(v), 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid number '%s'", v)
+	}
+	return i, f, nil
+}
+
+// timeWithPrecision converts an epoch timestamp in the given precision to a
+// time.Time. Timestamps that do not fit in nanoseconds are out of range.
+func timeWithPrecision(n int64, precision string) (time.Time, error) {
+	unit, ok := precisionUnit(precision)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid precision '%s'", precision)
+	}
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return time.Time{}, fmt.Errorf("timestamp %d out of range for precision '%s'", n, precision)
+	}
+	return time.Unix(0, n*int64(unit)).UTC(), nil
+}
+
+// precisionUnit returns the duration of one unit of the given precision.
+func precisionUnit(precision string) (time.Duration, bool) {
+	switch precision {
+	case "", "n":
+		return time.Nanosecond, true
+	case "u":
+		return time.Microsecond, true
+	case "ms":
+		return time.Millisecond, true
+	case "s":
+		return time.Second, true
+	case "m":
+		return time.Minute, true
+	case "h":
+		return time.Hour, true
+	}
+	return 0, false
+}
+
+// marshalFields returns the field values used to encode m with a FieldCodec.
+// The message structure is stored under the reserved field names next to the
+// custom fields.
+func marshalFields(m Message) (map[string]interface{}, error) {
+	content, from := m.Content(), m.From()
+
+	values := map[string]interface{}{fieldText: content.PlainText}
+	if id := m.ID(); id != "" {
+		values[fieldID] = id
+	}
+	if parentID := m.ParentID(); parentID != "" {
+		values[fieldParentID] = parentID
+	}
+	if from.UserID != "" {
+		values[fieldFromID] = from.UserID
+	}
+	if from.Name != "" {
+		values[fieldFromName] = from.Name
+	}
+	if content.HTML != "" {
+		values[fieldHTML] = content.HTML
+	}
+	if mentions := m.Mentions(); len(mentions) > 0 {
+		values[fieldMentions] = string(mustMarshalJSON(mentions))
+	}
+	if t := m.EditedAt(); !t.IsZero() {
+		values[fieldEditedAt] = t.UnixNano()
+	}
+	if by := m.EditedBy(); by != "" {
+		values[fieldEditedBy] = by
+	}
+	if m.Deleted() {
+		values[fieldDeleted] = true
+	}
+	if event := m.Event(); event != "" {
+		values[fieldKind] = MessageKindSystem
+		values[fieldEvent] = event
+	}
+	if s := m.Snippet(); s != nil {
+		values[fieldKind] = MessageKindSnippet
+		values[fieldSnippetBody] = s.Body
+		if s.Language != "" {
+			values[fieldSnippetLanguage] = s.Language
+		}
+		if s.Filename != "" {
+			values[fieldSnippetFilename] = s.Filename
+		}
+	}
+
+	fields := m.Fields()
+	if err := fields.Validate(); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		values[k] = v
+	}
+	return values, nil
 }
 
-func parseMessage(buf []byte, defaultTime time.Time) (Message, error) {
-	return nil, nil
+// UnmarshalMessage returns the message of the conversation key written at
+// timestamp from the field values decoded by a FieldCodec.
+func UnmarshalMessage(key string, timestamp int64, values map[string]interface{}) (Message, error) {
+	m := &message{key: []byte(key), time: time.Unix(0, timestamp).UTC()}
+	for k, v := range values {
+		if v == nil {
+			continue
+		}
+
+		switch k {
+		case fieldID:
+			m.id, _ = v.(string)
+		case fieldParentID:
+			m.parentID, _ = v.(string)
+		case fieldFromID:
+			m.from.UserID, _ = v.(string)
+		case fieldFromName:
+			m.from.Name, _ = v.(string)
+		case fieldText:
+			m.content.PlainText, _ = v.(string)
+		case fieldHTML:
+			m.content.HTML, _ = v.(string)
+		case fieldMentions:
+			s, _ := v.(string)
+			if err := json.Unmarshal([]byte(s), &m.mentions); err != nil {
+				return nil, fmt.Errorf("mentions: %s", err)
+			}
+		case fieldEditedAt:
+			if n, ok := v.(int64); ok {
+				m.editedAt = time.Unix(0, n).UTC()
+			}
+		case fieldEditedBy:
+			m.editedBy, _ = v.(string)
+		case fieldDeleted:
+			m.deleted, _ = v.(bool)
+		case fieldKind:
+			// The kind is implied by the snippet and event fields.
+		case fieldEvent:
+			m.event, _ = v.(string)
+		case fieldSnippetLanguage, fieldSnippetFilename, fieldSnippetBody:
+			if m.snippet == nil {
+				m.snippet = &Snippet{}
+			}
+			switch k {
+			case fieldSnippetLanguage:
+				m.snippet.Language, _ = v.(string)
+			case fieldSnippetFilename:
+				m.snippet.Filename, _ = v.(string)
+			default:
+				m.snippet.Body, _ = v.(string)
+			}
+		default:
+			m.AddField(k, v)
+		}
+	}
+	return m, nil
 }
 
-func NewMessage(time time.Time) Message {
-	//TODO: fix this here
-	return &message{time: time}
+// NewMessage returns a new message for the conversation identified by key.
+func NewMessage(key string, from Sender, content Content, mentions []Mention, t time.Time) Message {
+	return &message{
+		key:      []byte(key),
+		from:     from,
+		content:  content,
+		mentions: mentions,
+		time:     t,
+	}
 }
 
 func (m *message) Data() []byte {
@@ -88,6 +712,107 @@ func (m *message) Key() []byte {
 	return m.key
 }
 
+func (m *message) ID() string {
+	return m.id
+}
+
+func (m *message) SetID(id string) {
+	m.id = id
+}
+
+func (m *message) ParentID() string {
+	return m.parentID
+}
+
+func (m *message) SetParentID(id string) {
+	m.parentID = id
+}
+
+func (m *message) From() Sender {
+	return m.from
+}
+
+func (m *message) Content() Content {
+	return m.content
+}
+
+func (m *message) Mentions() []Mention {
+	return m.mentions
+}
+
+// Kind returns MessageKindSystem for a message recording an event,
+// MessageKindSnippet for a message holding a snippet and MessageKindText
+// otherwise.
+func (m *message) Kind() string {
+	if m.event != "" {
+		return MessageKindSystem
+	} else if m.snippet != nil {
+		return MessageKindSnippet
+	}
+	return MessageKindText
+}
+
+func (m *message) Snippet() *Snippet {
+	return m.snippet
+}
+
+func (m *message) SetSnippet(s *Snippet) {
+	m.snippet = s
+}
+
+func (m *message) Event() string {
+	return m.event
+}
+
+func (m *message) SetEvent(event string) {
+	m.event = event
+}
+
+func (m *message) EditedAt() time.Time {
+	return m.editedAt
+}
+
+func (m *message) SetEditedAt(t time.Time) {
+	m.editedAt = t
+}
+
+func (m *message) EditedBy() string {
+	return m.editedBy
+}
+
+func (m *message) SetEditedBy(userID string) {
+	m.editedBy = userID
+}
+
+func (m *message) Deleted() bool {
+	return m.deleted
+}
+
+func (m *message) SetDeleted(deleted bool) {
+	m.deleted = deleted
+}
+
+func (m *message) Fields() Fields {
+	return m.fields
+}
+
+// AddField sets a custom field on the message. Integer and float values are
+// widened to int64 and float64.
+func (m *message) AddField(name string, value interface{}) {
+	if m.fields == nil {
+		m.fields = make(Fields)
+	}
+	switch v := value.(type) {
+	case int:
+		value = int64(v)
+	case int32:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	}
+	m.fields[name] = value
+}
+
 func (m *message) Time() time.Time {
 	return m.time
 }
@@ -107,27 +832,292 @@ func (m *message) HashID() uint64 {
 	return sum
 }
 
+// String returns the message encoded in the line protocol with a nanosecond
+// timestamp.
 func (m *message) String() string {
-	return fmt.Sprintf("%s %d", m.Key(), m.UnixNano())
+	return m.PrecisionString("n")
 }
 
-// skipWhitespace returns the end position within buf, starting at i after
-// scanning over spaces in tags
-func skipWhitespace(buf []byte, i int) int {
-	for {
-		if i >= len(buf) {
-			return i
+// PrecisionString returns the message encoded in the line protocol with the
+// timestamp truncated to the given precision.
+func (m *message) PrecisionString(precision string) string {
+	var b bytes.Buffer
+	b.Write(escape(m.key, ", "))
+	if m.id != "" {
+		b.WriteString(",id=")
+		b.Write(escape([]byte(m.id), ", ="))
+	}
+	if m.parentID != "" {
+		b.WriteString(",parent=")
+		b.Write(escape([]byte(m.parentID), ", ="))
+	}
+	if m.from.UserID != "" {
+		b.WriteString(",from=")
+		b.Write(escape([]byte(m.from.UserID), ", ="))
+	}
+	if m.from.Name != "" {
+		b.WriteString(",from_name=")
+		b.Write(escape([]byte(m.from.Name), ", ="))
+	}
+	for _, mention := range m.mentions {
+		b.WriteString(",mention=")
+		b.Write(escape([]byte(mention.RecipientID), ", =:"))
+		b.WriteByte(':')
+		b.Write(escape([]byte(mention.RecipientUsername), ", =:"))
+	}
+
+	b.WriteString(" text=")
+	b.WriteString(quote(m.content.PlainText))
+	if m.content.HTML != "" {
+		b.WriteString(",html=")
+		b.WriteString(quote(m.content.HTML))
+	}
+	if m.event != "" {
+		b.WriteString(",kind=")
+		b.WriteString(quote(MessageKindSystem))
+		b.WriteString(",event=")
+		b.WriteString(quote(m.event))
+	}
+	if m.snippet != nil {
+		b.WriteString(",kind=")
+		b.WriteString(quote(MessageKindSnippet))
+		if m.snippet.Language != "" {
+			b.WriteString(",snippet_language=")
+			b.WriteString(quote(m.snippet.Language))
+		}
+		if m.snippet.Filename != "" {
+			b.WriteString(",snippet_filename=")
+			b.WriteString(quote(m.snippet.Filename))
+		}
+		b.WriteString(",snippet_body=")
+		b.WriteString(quote(m.snippet.Body))
+	}
+	if !m.editedAt.IsZero() {
+		b.WriteString(",edited_at=")
+		b.WriteString(strconv.FormatInt(m.editedAt.UnixNano(), 10))
+		b.WriteByte('i')
+	}
+	if m.editedBy != "" {
+		b.WriteString(",edited_by=")
+		b.WriteString(quote(m.editedBy))
+	}
+	if m.deleted {
+		b.WriteString(",deleted=true")
+	}
+	for _, k := range m.fields.names() {
+		b.WriteByte(',')
+		b.Write(escape([]byte(k), ", ="))
+		b.WriteByte('=')
+		b.WriteString(formatFieldValue(m.fields[k]))
+	}
+
+	unit, ok := precisionUnit(precision)
+	if !ok {
+		unit = time.Nanosecond
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(m.UnixNano()/int64(unit), 10))
+	return b.String()
+}
+
+// Validate returns an error if a field has an invalid or reserved name, or a
+// type that cannot be encoded.
+func (f Fields) Validate() error {
+	for _, k := range f.names() {
+		if !isValidFieldName(k) {
+			return fmt.Errorf("field %q: %v", k, ErrInvalidFieldName)
+		} else if isReservedField(k) {
+			return fmt.Errorf("field '%s': %v", k, ErrFieldNameReserved)
+		}
+		switch sql.InspectDataType(f[k]) {
+		case sql.String:
+			if len(f[k].(string)) > maxStringLength {
+				return fmt.Errorf("field %q: %v", k, ErrFieldValueTooLarge)
+			}
+		case sql.Float, sql.Integer, sql.Boolean:
+		default:
+			return fmt.Errorf("field '%s' has unsupported type %T", k, f[k])
+		}
+	}
+	return nil
+}
+
+// names returns the sorted field names.
+func (f Fields) names() []string {
+	a := make([]string, 0, len(f))
+	for k := range f {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// formatFieldValue returns the line protocol encoding of a field value.
+func formatFieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return quote(v)
+	case int64:
+		return strconv.FormatInt(v, 10) + "i"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return quote(fmt.Sprint(v))
+}
+
+// escape prefixes a backslash to backslashes, double quotes and any of the
+// chars in buf. Newlines are written as \n so that a key never spans lines.
+func escape(buf []byte, chars string) []byte {
+	if !bytes.ContainsAny(buf, chars+"\\\"\n") {
+		return buf
+	}
+	b := make([]byte, 0, len(buf)+4)
+	for _, c := range buf {
+		switch {
+		case c == '\n':
+			b = append(b, '\\', 'n')
+			continue
+		case c == '\\' || c == '"' || strings.IndexByte(chars, c) >= 0:
+			b = append(b, '\\')
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// unescape removes the backslash from escaped chars in buf, \n being a newline.
+func unescape(buf []byte) []byte {
+	if bytes.IndexByte(buf, '\\') < 0 {
+		return buf
+	}
+	b := make([]byte, 0, len(buf))
+	for i := 0; i < len(buf); i++ {
+		if buf[i] == '\\' && i+1 < len(buf) {
+			i++
+			if buf[i] == 'n' {
+				b = append(b, '\n')
+				continue
+			}
+		}
+		b = append(b, buf[i])
+	}
+	return b
+}
+
+// quote returns s as a double quoted field value.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// scanQuoted reads a double quoted field value starting at i and returns the
+// position after the closing quote along with the unescaped value.
+func scanQuoted(buf []byte, i int) (int, interface{}, error) {
+	if i >= len(buf) || buf[i] != '"' {
+		return 0, nil, errors.New("value must be double quoted")
+	}
+	i++
+
+	var b bytes.Buffer
+	for ; i < len(buf); i++ {
+		switch buf[i] {
+		case '"':
+			return i + 1, b.String(), nil
+		case '\\':
+			if i+1 >= len(buf) {
+				break
+			}
+			i++
+			switch buf[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case '"', '\\':
+				b.WriteByte(buf[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(buf[i])
+			}
+			continue
 		}
+		b.WriteByte(buf[i])
+	}
+	return 0, nil, errors.New("unterminated quoted value")
+}
 
+// scanKey returns the end position in buf and the next block of bytes that
+// ends with an unescaped comma or space.
+func scanKey(buf []byte, i int) (int, []byte) {
+	start := i
+	for i < len(buf) {
 		if buf[i] == '\\' {
 			i += 2
 			continue
 		}
-		if buf[i] == ' ' || buf[i] == '\t' {
-			i++
+		if buf[i] == ',' || buf[i] == ' ' {
+			break
+		}
+		i++
+	}
+	if i > len(buf) {
+		i = len(buf)
+	}
+	return i, buf[start:i]
+}
+
+// scanLine returns the end position in buf and the next line starting at i.
+// Newlines inside double quoted field values do not end the line. Quotes only
+// delimit values in the field section, after the '=' of a field, so that a
+// quote in a conversation key, a tag or a comment never leaves the line open.
+func scanLine(buf []byte, i int) (int, []byte) {
+	const (
+		sectionStart = iota
+		sectionKey
+		sectionFields
+		sectionEnd
+	)
+
+	start := i
+	section, quoted := sectionStart, false
+	for i < len(buf) {
+		c := buf[i]
+		switch {
+		case c == '\\':
+			i += 2
 			continue
+		case quoted:
+			quoted = c != '"'
+		case c == '\n':
+			return i, buf[start:i]
+		case section == sectionStart:
+			if c == '#' {
+				section = sectionEnd
+			} else if c != ' ' && c != '\t' {
+				section = sectionKey
+			}
+		case section == sectionKey:
+			if c == ' ' {
+				section = sectionFields
+			}
+		case section == sectionFields:
+			if c == '"' && buf[i-1] == '=' {
+				quoted = true
+			} else if c == ' ' && buf[i-1] != ' ' && buf[i-1] != '\t' {
+				// spaces before the fields are skipped, the next one ends them
+				section = sectionEnd
+			}
 		}
-		break
+		i++
+	}
+	return len(buf), buf[start:]
+}
+
+// skipWhitespace returns the end position within buf, starting at i after
+// scanning over spaces and tabs
+func skipWhitespace(buf []byte, i int) int {
+	for i < len(buf) && (buf[i] == ' ' || buf[i] == '\t') {
+		i++
 	}
 	return i
 }
@@ -155,5 +1145,8 @@ func scanTo(buf []byte, i int, stop byte) (int, []byte) {
 		i++
 	}
 
+	if i > len(buf) {
+		i = len(buf)
+	}
 	return i, buf[start:i]
 }
@@ -0,0 +1,341 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMessages(t *testing.T) {
+	buf := `# comment
+general,id=m1,from=u1,from_name=John\ Doe,mention=u2:jane,mention=u3:bob text="hi @jane and @bob",html="<p>hi</p>" 1000000000
+
+general\,random,from=u1 text="say \"hello\"\nto everyone" 2000000000
+dev text="first line
+second line" 3000000000
+`
+	messages, err := ParseMessagesString(buf)
+	if err != nil {
+		t.Fatalf("ParseMessages() failed: %v", err)
+	}
+	if exp := 3; len(messages) != exp {
+		t.Fatalf("ParseMessages() count mismatch: got %v, exp %v", len(messages), exp)
+	}
+
+	m := messages[0]
+	if exp := "general"; string(m.Key()) != exp {
+		t.Errorf("Key() mismatch: got %v, exp %v", string(m.Key()), exp)
+	}
+	if exp := "m1"; m.ID() != exp {
+		t.Errorf("ID() mismatch: got %v, exp %v", m.ID(), exp)
+	}
+	if exp := (Sender{UserID: "u1", Name: "John Doe"}); m.From() != exp {
+		t.Errorf("From() mismatch: got %v, exp %v", m.From(), exp)
+	}
+	if exp := (Content{PlainText: "hi @jane and @bob", HTML: "<p>hi</p>"}); m.Content() != exp {
+		t.Errorf("Content() mismatch: got %v, exp %v", m.Content(), exp)
+	}
+	if exp := []Mention{{"u2", "jane"}, {"u3", "bob"}}; !reflect.DeepEqual(m.Mentions(), exp) {
+		t.Errorf("Mentions() mismatch: got %v, exp %v", m.Mentions(), exp)
+	}
+	if exp := time.Unix(1, 0); !m.Time().Equal(exp) {
+		t.Errorf("Time() mismatch: got %v, exp %v", m.Time(), exp)
+	}
+
+	if exp := "general,random"; string(messages[1].Key()) != exp {
+		t.Errorf("Key() mismatch: got %v, exp %v", string(messages[1].Key()), exp)
+	}
+	if exp := "say \"hello\"\nto everyone"; messages[1].Content().PlainText != exp {
+		t.Errorf("PlainText mismatch: got %q, exp %q", messages[1].Content().PlainText, exp)
+	}
+	if exp := "first line\nsecond line"; messages[2].Content().PlainText != exp {
+		t.Errorf("PlainText mismatch: got %q, exp %q", messages[2].Content().PlainText, exp)
+	}
+}
+
+func TestParseMessagesWithPrecision(t *testing.T) {
+	now := time.Unix(100, 0).UTC()
+	messages, err := ParseMessagesWithPrecision([]byte("general text=\"a\" 5\ngeneral text=\"b\""), now, "s")
+	if err != nil {
+		t.Fatalf("ParseMessagesWithPrecision() failed: %v", err)
+	}
+	if exp := time.Unix(5, 0); !messages[0].Time().Equal(exp) {
+		t.Errorf("Time() mismatch: got %v, exp %v", messages[0].Time(), exp)
+	}
+	if !messages[1].Time().Equal(now) {
+		t.Errorf("default Time() mismatch: got %v, exp %v", messages[1].Time(), now)
+	}
+
+	if _, err := ParseMessagesWithPrecision([]byte(`general text="a" 5`), now, "d"); err == nil {
+		t.Fatal("expected invalid precision error")
+	}
+}
+
+func TestParseMessagesErrors(t *testing.T) {
+	buf := `general text="ok" 1
+general
+general,color=red text="x"
+general text="ok" 2
+general text="unterminated 3`
+
+	messages, err := ParseMessagesString(buf)
+	if exp := 2; len(messages) != exp {
+		t.Fatalf("ParseMessages() count mismatch: got %v, exp %v", len(messages), exp)
+	}
+
+	errs, ok := err.(ParseErrors)
+	if !ok {
+		t.Fatalf("expected ParseErrors, got %T: %v", err, err)
+	}
+	if exp := 3; len(errs) != exp {
+		t.Fatalf("error count mismatch: got %v, exp %v", len(errs), exp)
+	}
+	for i, exp := range []int{2, 3, 5} {
+		if errs[i].Line != exp {
+			t.Errorf("error %d line mismatch: got %v, exp %v", i, errs[i].Line, exp)
+		}
+	}
+	if errs[0].Err != ErrMissingContent {
+		t.Errorf("error mismatch: got %v, exp %v", errs[0].Err, ErrMissingContent)
+	}
+}
+
+func TestMessageStringRoundTrip(t *testing.T) {
+	m := NewMessage(
+		"team chat",
+		Sender{UserID: "u,1", Name: "a=b"},
+		Content{PlainText: "line 1\nline \\2 \"quoted\"", HTML: "<b>x</b>"},
+		[]Mention{{RecipientID: "u:2", RecipientUsername: "jane doe"}},
+		time.Unix(1, 2),
+	)
+	m.SetID("m 1")
+
+	messages, err := ParseMessagesString(m.String())
+	if err != nil {
+		t.Fatalf("ParseMessages() failed: %v", err)
+	}
+	if exp := 1; len(messages) != exp {
+		t.Fatalf("ParseMessages() count mismatch: got %v, exp %v", len(messages), exp)
+	}
+
+	got := messages[0]
+	if got.String() != m.String() {
+		t.Errorf("String() mismatch:\n got %v\n exp %v", got.String(), m.String())
+	}
+	if !reflect.DeepEqual(got.Mentions(), m.Mentions()) || got.Content() != m.Content() || got.From() != m.From() || got.ID() != m.ID() {
+		t.Errorf("message mismatch:\n got %#v\n exp %#v", got, m)
+	}
+	if !got.Time().Equal(m.Time()) {
+		t.Errorf("Time() mismatch: got %v, exp %v", got.Time(), m.Time())
+	}
+
+	if exp := `team\ chat,id=m\ 1,from=u\,1,from_name=a\=b,mention=u\:2:jane\ doe text="line 1\nline \\2 \"quoted\"",html="<b>x</b>" 1`; m.PrecisionString("s") != exp {
+		t.Errorf("PrecisionString() mismatch:\n got %v\n exp %v", m.PrecisionString("s"), exp)
+	}
+}
+
+// Ensure quotes, backslashes and newlines in keys, tags and field names survive
+// a round trip without leaving the line open for the next one.
+func TestMessageStringRoundTrip_Escaping(t *testing.T) {
+	for i, tt := range []struct {
+		key    string
+		from   Sender
+		fields Fields
+	}{
+		{key: "general", from: Sender{UserID: "u1", Name: `O"Brien`}},
+		{key: "general", from: Sender{UserID: "u1", Name: "John\nDoe"}},
+		{key: "general", from: Sender{UserID: "u1", Name: "back\\slash\\"}},
+		{key: "general", from: Sender{UserID: "u1"}, fields: Fields{`a"b`: "x", "c\nd": int64(1)}},
+		{key: `say "hi"`, from: Sender{UserID: `u"1`}},
+	} {
+		m := NewMessage(tt.key, tt.from, Content{PlainText: "hi"}, nil, time.Unix(1, 0))
+		for k, v := range tt.fields {
+			m.AddField(k, v)
+		}
+		next := NewMessage("general", Sender{UserID: "u2"}, Content{PlainText: "next"}, nil, time.Unix(2, 0))
+
+		buf := m.String() + "\n" + next.String()
+		if strings.Count(buf, "\n") != 1 {
+			t.Errorf("%d. unexpected newline in encoded message: %q", i, buf)
+		}
+
+		messages, err := ParseMessagesString(buf)
+		if err != nil {
+			t.Errorf("%d. ParseMessages() failed: %v", i, err)
+			continue
+		} else if len(messages) != 2 {
+			t.Errorf("%d. ParseMessages() count mismatch: got %v, exp 2", i, len(messages))
+			continue
+		}
+
+		got := messages[0]
+		if string(got.Key()) != tt.key || got.From() != tt.from {
+			t.Errorf("%d. message mismatch: got %q %#v", i, got.Key(), got.From())
+		} else if len(tt.fields) > 0 && !reflect.DeepEqual(got.Fields(), tt.fields) {
+			t.Errorf("%d. Fields() mismatch: got %#v, exp %#v", i, got.Fields(), tt.fields)
+		} else if messages[1].Content().PlainText != "next" {
+			t.Errorf("%d. next message mismatch: got %#v", i, messages[1])
+		}
+	}
+}
+
+// Ensure quotes outside of field values do not join lines.
+func TestParseMessages_QuotesOutsideFields(t *testing.T) {
+	buf := "# a \"quoted comment\ngeneral text=\"a\" 1\ngeneral,from_name=O\"Brien text=\"b\" 2\ngeneral  text=\"c\nd\" 3"
+	messages, err := ParseMessagesString(buf)
+	if err != nil {
+		t.Fatalf("ParseMessages() failed: %v", err)
+	} else if len(messages) != 3 {
+		t.Fatalf("ParseMessages() count mismatch: got %v, exp 3", len(messages))
+	} else if exp := "O\"Brien"; messages[1].From().Name != exp {
+		t.Errorf("From().Name mismatch: got %q, exp %q", messages[1].From().Name, exp)
+	} else if exp := "c\nd"; messages[2].Content().PlainText != exp {
+		t.Errorf("PlainText mismatch: got %q, exp %q", messages[2].Content().PlainText, exp)
+	}
+}
+
+// Ensure timestamps that overflow nanoseconds are rejected.
+func TestParseMessagesWithPrecision_OutOfRange(t *testing.T) {
+	for _, tt := range []struct {
+		ts        string
+		precision string
+	}{
+		{"9223372037", "s"},
+		{"-9223372037", "s"},
+		{"9223372036855", "ms"},
+		{"9223372036854776", "u"},
+		{"153722868", "m"},
+	} {
+		if _, err := ParseMessagesWithPrecision([]byte(`general text="a" `+tt.ts), time.Now(), tt.precision); err == nil {
+			t.Errorf("expected out of range error for %s%s", tt.ts, tt.precision)
+		}
+	}
+
+	if m, err := ParseMessagesWithPrecision([]byte(`general text="a" 9223372036`), time.Now(), "s"); err != nil {
+		t.Fatal(err)
+	} else if exp := time.Unix(9223372036, 0); !m[0].Time().Equal(exp) {
+		t.Errorf("Time() mismatch: got %v, exp %v", m[0].Time(), exp)
+	}
+}
+
+func TestParseMessagesFields(t *testing.T) {
+	buf := `general,from=u1 text="hi",edited_at=5i,deleted=true,priority=3i,score=1.5,pinned=t,client\ name="web" 1`
+	messages, err := ParseMessagesString(buf)
+	if err != nil {
+		t.Fatalf("ParseMessages() failed: %v", err)
+	}
+
+	m := messages[0]
+	if exp := time.Unix(0, 5); !m.EditedAt().Equal(exp) {
+		t.Errorf("EditedAt() mismatch: got %v, exp %v", m.EditedAt(), exp)
+	}
+	if !m.Deleted() {
+		t.Errorf("Deleted() mismatch: got %v, exp %v", m.Deleted(), true)
+	}
+	exp := Fields{"priority": int64(3), "score": 1.5, "pinned": true, "client name": "web"}
+	if !reflect.DeepEqual(m.Fields(), exp) {
+		t.Errorf("Fields() mismatch:\n got %#v\n exp %#v", m.Fields(), exp)
+	}
+
+	if got := m.String(); got != `general,from=u1 text="hi",edited_at=5i,deleted=true,client\ name="web",pinned=true,priority=3i,score=1.5 1` {
+		t.Errorf("String() mismatch: got %v", got)
+	}
+
+	if _, err := ParseMessagesString(`general text="hi",mentions="x"`); err == nil {
+		t.Error("expected reserved field name error")
+	}
+	if _, err := ParseMessagesString(`general text=1i`); err == nil {
+		t.Error("expected text type error")
+	}
+}
+
+func TestFields_Validate(t *testing.T) {
+	if err := (Fields{"priority": int64(3), "client name": "web", "app.version-2_b": 1.5, "café": true}).Validate(); err != nil {
+		t.Errorf("Validate() failed: %v", err)
+	}
+
+	for _, name := range []string{"", `a"b`, "a\nb", "a\tb", "a=b", "a,b", `a\b`} {
+		if err := (Fields{name: "x"}).Validate(); err == nil || !strings.Contains(err.Error(), ErrInvalidFieldName.Error()) {
+			t.Errorf("Validate(%q) mismatch: got %v, exp %v", name, err, ErrInvalidFieldName)
+		}
+	}
+	for _, name := range []string{"text", "html", "kind", "event", "deleted", "snippet_body", "snippet_theme", "edited_at", "edited_reason"} {
+		if err := (Fields{name: "x"}).Validate(); err == nil || !strings.Contains(err.Error(), ErrFieldNameReserved.Error()) {
+			t.Errorf("Validate(%q) mismatch: got %v, exp %v", name, err, ErrFieldNameReserved)
+		}
+	}
+}
+
+func TestParseMessagesSnippet(t *testing.T) {
+	buf := `general,from=u1 text="",kind="snippet",snippet_language="Go",snippet_filename="main.go",snippet_body="package main\n" 1`
+	messages, err := ParseMessagesString(buf)
+	if err != nil {
+		t.Fatalf("ParseMessages() failed: %v", err)
+	}
+
+	m := messages[0]
+	if m.Kind() != MessageKindSnippet {
+		t.Errorf("Kind() mismatch: got %v, exp %v", m.Kind(), MessageKindSnippet)
+	}
+	exp := &Snippet{Language: "go", Filename: "main.go", Body: "package main\n"}
+	if !reflect.DeepEqual(m.Snippet(), exp) {
+		t.Errorf("Snippet() mismatch:\n got %#v\n exp %#v", m.Snippet(), exp)
+	}
+	if len(m.Fields()) != 0 {
+		t.Errorf("unexpected fields: %#v", m.Fields())
+	}
+
+	if got := m.String(); got != `general,from=u1 text="",kind="snippet",snippet_language="go",snippet_filename="main.go",snippet_body="package main\n" 1` {
+		t.Errorf("String() mismatch: got %v", got)
+	}
+
+	// Snippet fields imply the kind.
+	if messages, err := ParseMessagesString(`general text="",snippet_body="x" 1`); err != nil {
+		t.Fatalf("ParseMessages() failed: %v", err)
+	} else if messages[0].Kind() != MessageKindSnippet {
+		t.Errorf("Kind() mismatch: got %v, exp %v", messages[0].Kind(), MessageKindSnippet)
+	}
+
+	for _, tt := range []struct {
+		line string
+		err  error
+	}{
+		{line: `general text="",kind="snippet"`, err: ErrSnippetBodyRequired},
+		{line: `general text="",kind="text",snippet_body="x"`, err: ErrInvalidMessageKind},
+		{line: `general text="",kind="image"`, err: ErrInvalidMessageKind},
+		{line: `general text="",snippet_body="x",snippet_language="c c"`, err: ErrInvalidSnippetLanguage},
+		{line: `general text="",snippet_body="x",snippet_filename="../x.go"`, err: ErrInvalidSnippetFilename},
+	} {
+		_, err := ParseMessagesString(tt.line)
+		if errs, ok := err.(ParseErrors); !ok || len(errs) != 1 || !strings.Contains(errs[0].Err.Error(), tt.err.Error()) {
+			t.Errorf("%s: error mismatch: got %v, exp %v", tt.line, err, tt.err)
+		}
+	}
+}
+
+func TestParseMessagesSystem(t *testing.T) {
+	buf := `general,from=u1,mention=u2:jane text="jdoe added jane",kind="system",event="participant_joined" 1`
+	messages, err := ParseMessagesString(buf)
+	if err != nil {
+		t.Fatalf("ParseMessages() failed: %v", err)
+	}
+
+	m := messages[0]
+	if m.Kind() != MessageKindSystem || m.Event() != EventParticipantJoined {
+		t.Errorf("event mismatch: got %v %v", m.Kind(), m.Event())
+	}
+	if got := m.String(); got != buf {
+		t.Errorf("String() mismatch: got %v", got)
+	}
+
+	for _, line := range []string{
+		`general text="",kind="system"`,
+		`general text="",kind="text",event="participant_left"`,
+		`general text="",event="participant_left",snippet_body="x"`,
+	} {
+		if _, err := ParseMessagesString(line); err == nil {
+			t.Errorf("%s: expected error", line)
+		}
+	}
+}
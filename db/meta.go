@@ -1,6 +1,7 @@
 package db
 
 import (
+	"math"
 	"sort"
 	"sync"
 
@@ -12,7 +13,9 @@ import (
 //go:generate protoc --gogo_out=. internal/meta.proto
 
 const (
-	maxStringLength = 64 * 1024
+	// maxStringLength is the maximum length of a string field value, encoded
+	// after its length on 2 bytes.
+	maxStringLength = math.MaxUint16
 )
 
 // Conversation represent unique series messages in a database
@@ -32,9 +35,20 @@ type Conversation struct {
 func (c *Conversation) HasField(name string) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	// 	_, hasField := c.fieldNames[name]
-	// 	return hasField
-	return true //TODO: fix this
+	_, hasField := c.fieldNames[name]
+	return hasField
+}
+
+// FieldNames returns a sorted list of the field names written to the conversation
+func (c *Conversation) FieldNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	a := make([]string, 0, len(c.fieldNames))
+	for n := range c.fieldNames {
+		a = append(a, n)
+	}
+	sort.Strings(a)
+	return a
 }
 
 // HasTagKey returns true if at least one series in this measurement has written a value for the passed in tag key
@@ -163,6 +163,14 @@ func (q *QueryExecutor) ExecuteQuery(query *sql.Query, database string, chunkSiz
 				res = q.executeDropConversationStatement(stmt, database)
 			case *sql.ShowConversationsStatement:
 				res = q.executeShowConversationsStatement(stmt, database)
+			case *sql.ShowMentionsStatement:
+				res = q.executeShowMentionsStatement(stmt, database)
+			case *sql.ShowLinksStatement:
+				res = q.executeShowLinksStatement(stmt, database)
+			case *sql.ShowRepliesStatement:
+				res = q.executeShowRepliesStatement(stmt, database)
+			case *sql.ShowReactionsStatement:
+				res = q.executeShowReactionsStatement(stmt, database)
 			case *sql.ShowDiagnosticsStatement:
 				res = q.executeShowDiagnosticsStatement(stmt)
 			case *sql.DeleteStatement:
@@ -212,6 +220,10 @@ func (q *QueryExecutor) plan(stmt *sql.SelectStatement, chunkSize int) (*Executo
 		tmin = time.Unix(0, 0)
 	}
 
+	// Only the shards between the cursors of the statement are needed.
+	min, max := stmt.CursorTimeRange(tmin.UnixNano(), tmax.UnixNano())
+	tmin, tmax = time.Unix(0, min), time.Unix(0, max)
+
 	for _, src := range stmt.Sources {
 		mm, ok := src.(*sql.Conversation)
 		if !ok {
@@ -257,6 +269,12 @@ func (q *QueryExecutor) executeSelectStatement(statementID int, stmt *sql.Select
 		return err
 	}
 
+	// Nothing was ever written to the conversations, so there is nothing to select.
+	if len(stmt.Fields) == 0 {
+		results <- &sql.Result{StatementID: statementID, Rows: make([]*sql.Row, 0)}
+		return nil
+	}
+
 	// Plan statement execution.
 	e, err := q.plan(stmt, chunkSize)
 	if err != nil {
@@ -294,19 +312,54 @@ func (q *QueryExecutor) rewriteSelectStatement(stmt *sql.SelectStatement) (*sql.
 	}
 	stmt.Sources = sources
 
-	// Expand wildcards in the fields or GROUP BY.
-	// if stmt.HasWildcard() {
-	// 	stmt, err = q.expandWildcards(stmt)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// }
+	// Expand wildcards in the fields.
+	if stmt.HasWildcard() {
+		stmt, err = q.expandWildcards(stmt)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// stmt.RewriteDistinct()
 
 	return stmt, nil
 }
 
+// expandWildcards returns a copy of the statement with each wildcard field replaced by
+// the fields written to the source conversations, sorted by name.
+func (q *QueryExecutor) expandWildcards(stmt *sql.SelectStatement) (*sql.SelectStatement, error) {
+	names := newStringSet()
+	for _, src := range stmt.Sources {
+		c, ok := src.(*sql.Conversation)
+		if !ok {
+			return nil, fmt.Errorf("invalid source type: %#v", src)
+		}
+
+		db := q.store.DatabaseIndex(c.Database)
+		if db == nil {
+			continue
+		}
+		if conv := db.Conversation(c.Name); conv != nil {
+			names.add(conv.FieldNames()...)
+		}
+	}
+
+	fields := make(sql.Fields, 0, len(stmt.Fields))
+	for _, f := range stmt.Fields {
+		if _, ok := f.Expr.(*sql.Wildcard); !ok {
+			fields = append(fields, f)
+			continue
+		}
+		for _, n := range names.list() {
+			fields = append(fields, &sql.Field{Expr: &sql.VarRef{Val: n}})
+		}
+	}
+
+	other := stmt.Clone()
+	other.Fields = fields
+	return other, nil
+}
+
 // expandSources expands regex sources and removes duplicates.
 // NOTE: sources must be normalized (db and rp set) before calling this function.
 func (q *QueryExecutor) expandSources(sources sql.Sources) (sql.Sources, error) {
@@ -473,6 +526,113 @@ func (q *QueryExecutor) executeShowConversationsStatement(stmt *sql.ShowConversa
 	return result
 }
 
+func (q *QueryExecutor) executeShowMentionsStatement(stmt *sql.ShowMentionsStatement, database string) *sql.Result {
+	// List the mentions up to now unless a cursor is given.
+	before := time.Now().UnixNano()
+	if stmt.Before != nil {
+		before = stmt.Before.Time
+	}
+
+	messages, err := q.store.Mentions(database, stmt.Name, before, stmt.Limit)
+	if err != nil {
+		return &sql.Result{Err: err}
+	}
+
+	// The columns are the union of the fields of the messages.
+	names := make(map[string]struct{})
+	for _, m := range messages {
+		for name := range m.Values {
+			names[name] = struct{}{}
+		}
+	}
+	fields := make([]string, 0, len(names))
+	for name := range names {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+
+	// Make a result row to hold all the mentioned messages.
+	row := &sql.Row{
+		Name:    "mentions",
+		Columns: append([]string{"time", "conversation"}, fields...),
+	}
+
+	// Add one value to the row for each message.
+	for _, m := range messages {
+		values := make([]interface{}, 0, len(row.Columns))
+		values = append(values, time.Unix(0, m.Timestamp).UTC(), m.Conversation)
+		for _, name := range fields {
+			values = append(values, m.Values[name])
+		}
+		row.Values = append(row.Values, values)
+	}
+
+	return &sql.Result{Rows: []*sql.Row{row}}
+}
+
+func (q *QueryExecutor) executeShowLinksStatement(stmt *sql.ShowLinksStatement, database string) *sql.Result {
+	// List the links up to now unless a cursor is given.
+	before := time.Now().UnixNano()
+	if stmt.Before != nil {
+		before = stmt.Before.Time
+	}
+
+	links, err := q.store.Links(database, stmt.Name, before, stmt.Limit)
+	if err != nil {
+		return &sql.Result{Err: err}
+	}
+
+	// Make a result row to hold all the links.
+	row := &sql.Row{
+		Name:    "links",
+		Columns: []string{"time", "url", "message_id", "from", "from_name"},
+	}
+	for _, l := range links {
+		row.Values = append(row.Values, []interface{}{time.Unix(0, l.Timestamp).UTC(), l.URL, l.MessageID, l.SenderID, l.SenderName})
+	}
+
+	return &sql.Result{Rows: []*sql.Row{row}}
+}
+
+func (q *QueryExecutor) executeShowRepliesStatement(stmt *sql.ShowRepliesStatement, database string) *sql.Result {
+	counts, err := q.store.Replies(database, stmt.Name, stmt.ParentIDs)
+	if err != nil {
+		return &sql.Result{Err: err}
+	}
+
+	// Make a result row with the reply count of each parent message, in the requested order.
+	row := &sql.Row{
+		Name:    "replies",
+		Columns: []string{"parent_id", "count"},
+	}
+	for _, id := range stmt.ParentIDs {
+		row.Values = append(row.Values, []interface{}{id, int64(counts[id])})
+	}
+
+	return &sql.Result{Rows: []*sql.Row{row}}
+}
+
+func (q *QueryExecutor) executeShowReactionsStatement(stmt *sql.ShowReactionsStatement, database string) *sql.Result {
+	reactions, err := q.store.Reactions(database, stmt.Name, stmt.MessageIDs)
+	if err != nil {
+		return &sql.Result{Err: err}
+	}
+
+	// Make a result row with the reaction summaries of each message, in the requested order. The
+	// summaries of a message are ordered by the time of their first reaction.
+	row := &sql.Row{
+		Name:    "reactions",
+		Columns: []string{"time", "message_id", "emoji", "count", "user_ids"},
+	}
+	for _, id := range stmt.MessageIDs {
+		for _, r := range reactions[id] {
+			row.Values = append(row.Values, []interface{}{time.Unix(0, r.firstAt).UTC(), id, r.Emoji, int64(r.Count), r.UserIDs})
+		}
+	}
+
+	return &sql.Result{Rows: []*sql.Row{row}}
+}
+
 // conversationsFromSourcesOrDB returns a list of conversations from the
 // sources passed in or, if sources is empty, a list of all
 // conversations names from the database passed in.
@@ -0,0 +1,212 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/boltdb/bolt"
+)
+
+// MaxEmojiLength is the maximum length in bytes of the emoji of a reaction.
+const MaxEmojiLength = 64
+
+// ErrInvalidEmoji is returned when the emoji of a reaction is empty, too long or contains whitespace.
+var ErrInvalidEmoji = errors.New("invalid emoji")
+
+// Reaction is the reaction of a user with an emoji to a message, or its removal. Reactions are
+// written to the shard that stores the message they react to.
+type Reaction struct {
+	Conversation string
+	Timestamp    int64 // timestamp of the message
+	MessageID    string
+	Emoji        string
+	UserID       string
+	Time         time.Time // time of the reaction
+	Removed      bool
+}
+
+// HashID returns the hash of the conversation of the reaction. It selects the shard of the
+// message in its shard group.
+func (r *Reaction) HashID() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(r.Conversation))
+	return h.Sum64()
+}
+
+// ReactionSummary is the aggregate of the reactions to a message with the same emoji.
+type ReactionSummary struct {
+	Emoji string
+	Count int
+
+	// IDs of the users that reacted, sorted.
+	UserIDs []string
+
+	// Time of the first reaction with the emoji.
+	firstAt int64
+}
+
+// Reacted returns true if the user is one of the users that reacted.
+func (r *ReactionSummary) Reacted(userID string) bool {
+	i := sort.SearchStrings(r.UserIDs, userID)
+	return i < len(r.UserIDs) && r.UserIDs[i] == userID
+}
+
+// ReactionSummaries sorts the reaction summaries of a message by the time of their first reaction.
+type ReactionSummaries []*ReactionSummary
+
+func (a ReactionSummaries) Len() int { return len(a) }
+func (a ReactionSummaries) Less(i, j int) bool {
+	if a[i].firstAt != a[j].firstAt {
+		return a[i].firstAt < a[j].firstAt
+	}
+	return a[i].Emoji < a[j].Emoji
+}
+func (a ReactionSummaries) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+// ValidateEmoji returns ErrInvalidEmoji if emoji cannot be used in a reaction.
+func ValidateEmoji(emoji string) error {
+	if emoji == "" || len(emoji) > MaxEmojiLength || strings.IndexFunc(emoji, unicode.IsSpace) >= 0 || strings.IndexByte(emoji, 0) >= 0 {
+		return ErrInvalidEmoji
+	}
+	return nil
+}
+
+// reactionPrefix returns the prefix of the reactions to a message. IDs are terminated by a zero
+// byte so that no ID is the prefix of another.
+func reactionPrefix(messageID string) []byte {
+	k := make([]byte, len(messageID)+1)
+	copy(k, messageID)
+	return k
+}
+
+// reactionKey returns the key of the reaction of a user to a message with an emoji.
+func reactionKey(messageID, emoji, userID string) []byte {
+	k := append(reactionPrefix(messageID), emoji...)
+	k = append(k, 0)
+	return append(k, userID...)
+}
+
+// WriteReactions adds and removes reactions to the messages of the shard.
+func (s *Shard) WriteReactions(reactions []Reaction) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, r := range reactions {
+			k := reactionKey(r.MessageID, r.Emoji, r.UserID)
+			if r.Removed {
+				if b := tx.Bucket([]byte("reactions")).Bucket([]byte(r.Conversation)); b != nil {
+					if err := b.Delete(k); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			b, err := tx.Bucket([]byte("reactions")).CreateBucketIfNotExists([]byte(r.Conversation))
+			if err != nil {
+				return err
+			}
+
+			// Reacting twice with the same emoji keeps the time of the first reaction.
+			if b.Get(k) != nil {
+				continue
+			}
+			if err := b.Put(k, u64tob(uint64(r.Time.UnixNano()))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reactions returns the reaction summaries of each of the messages of a conversation.
+// Messages without reactions are omitted.
+func (s *Shard) Reactions(key string, messageIDs []string) (map[string]ReactionSummaries, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m := make(map[string]ReactionSummaries)
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("reactions")).Bucket([]byte(key))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for _, messageID := range messageIDs {
+			var a ReactionSummaries
+			prefix := reactionPrefix(messageID)
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				i := bytes.IndexByte(k[len(prefix):], 0)
+				if i < 0 {
+					continue
+				}
+				emoji, userID := string(k[len(prefix):len(prefix)+i]), string(k[len(prefix)+i+1:])
+
+				// Entries of the same emoji are contiguous and sorted by user ID.
+				if len(a) == 0 || a[len(a)-1].Emoji != emoji {
+					a = append(a, &ReactionSummary{Emoji: emoji, firstAt: int64(btou64(v))})
+				}
+				r := a[len(a)-1]
+				r.Count++
+				r.UserIDs = append(r.UserIDs, userID)
+				if t := int64(btou64(v)); t < r.firstAt {
+					r.firstAt = t
+				}
+			}
+			if len(a) > 0 {
+				sort.Sort(a)
+				m[messageID] = a
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WriteReactionsToShard adds and removes reactions to the messages of a shard.
+func (s *Store) WriteReactionsToShard(shardID uint64, reactions []Reaction) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sh, ok := s.shards[shardID]
+	if !ok {
+		return ErrShardNotFound
+	}
+
+	return sh.WriteReactions(reactions)
+}
+
+// Reactions returns the reaction summaries of each of the messages of a conversation across all
+// the local shards of a database. Messages without reactions are omitted.
+func (s *Store) Reactions(database, key string, messageIDs []string) (map[string]ReactionSummaries, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := s.databaseIndexes[database]
+	if index == nil {
+		return nil, nil
+	}
+
+	m := make(map[string]ReactionSummaries)
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		a, err := sh.Reactions(key, messageIDs)
+		if err != nil {
+			return nil, err
+		}
+		for messageID, summaries := range a {
+			m[messageID] = append(m[messageID], summaries...)
+		}
+	}
+	return m, nil
+}
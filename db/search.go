@@ -0,0 +1,312 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/boltdb/bolt"
+	"github.com/messagedb/messagedb/sql"
+)
+
+// ContentField is the name of the virtual field searched with the MATCHES operator. It refers to the
+// full-text index of the message text.
+const ContentField = "content"
+
+// ErrInvalidSearchQuery is returned when a full-text search query has no terms to search for.
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+// searchTerm is a term of a search query. A term is a phrase of one or more consecutive words, where
+// the last word matches any word it is a prefix of when prefix is set.
+type searchTerm struct {
+	words  []string
+	prefix bool
+}
+
+// searchQuery is a full-text search query. Messages match when they contain all of its terms.
+type searchQuery []*searchTerm
+
+// parseSearchQuery parses a full-text search query. Words are separated by spaces, quoted words
+// are searched as a phrase and words ending with * are searched as a prefix.
+func parseSearchQuery(s string) (searchQuery, error) {
+	var q searchQuery
+	for len(s) > 0 {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			break
+		}
+
+		// Read a quoted phrase or a single word.
+		var text string
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end == -1 {
+				text, s = s[1:], ""
+			} else {
+				text, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end == -1 {
+				end = len(s)
+			}
+			text, s = s[:end], s[end:]
+		}
+
+		t := &searchTerm{words: tokenize(text), prefix: strings.HasSuffix(text, "*")}
+		if len(t.words) > 0 {
+			q = append(q, t)
+		}
+	}
+
+	if len(q) == 0 {
+		return nil, ErrInvalidSearchQuery
+	}
+	return q, nil
+}
+
+// ValidateSearchQuery returns an error if a full-text search query has no terms to search for.
+func ValidateSearchQuery(s string) error {
+	_, err := parseSearchQuery(s)
+	return err
+}
+
+// matchText returns true if the text contains all the terms of the query.
+func (q searchQuery) matchText(text string) bool {
+	positions := termPositions(tokenize(text))
+	for _, t := range q {
+		t := t
+		if !t.match(func(i int) map[int]struct{} {
+			if !t.isPrefix(i) {
+				return positions[t.words[i]]
+			}
+			set := make(map[int]struct{})
+			for term, a := range positions {
+				if strings.HasPrefix(term, t.words[i]) {
+					for p := range a {
+						set[p] = struct{}{}
+					}
+				}
+			}
+			return set
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPrefix returns true if the i-th word of the term is matched as a prefix.
+func (t *searchTerm) isPrefix(i int) bool { return t.prefix && i == len(t.words)-1 }
+
+// match returns true if the words of the term occur at consecutive positions, given the positions
+// of the i-th word of the term in a message.
+func (t *searchTerm) match(positions func(i int) map[int]struct{}) bool {
+	sets := make([]map[int]struct{}, len(t.words))
+	for i := range t.words {
+		sets[i] = positions(i)
+		if len(sets[i]) == 0 {
+			return false
+		}
+	}
+
+	for p := range sets[0] {
+		matched := true
+		for i := 1; i < len(sets) && matched; i++ {
+			_, matched = sets[i][p+i]
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// maxTermLength is the length of the longest word indexed. Longer words are not searchable so
+// that postings keys stay within the key size limit of the index.
+const maxTermLength = 256
+
+// tokenize splits text into lowercase words. Words are made of letters and digits.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	a := words[:0]
+	for _, w := range words {
+		if len(w) <= maxTermLength {
+			a = append(a, w)
+		}
+	}
+	return a
+}
+
+// termPositions returns the positions of each distinct term in a list of words.
+func termPositions(words []string) map[string]map[int]struct{} {
+	m := make(map[string]map[int]struct{})
+	for i, w := range words {
+		if m[w] == nil {
+			m[w] = make(map[int]struct{})
+		}
+		m[w][i] = struct{}{}
+	}
+	return m
+}
+
+// searchIndex is the inverted index of the message text of a conversation in a shard. Postings are
+// keyed by term, a zero byte and the message timestamp, and hold the positions of the term in the message.
+type searchIndex struct {
+	bucket *bolt.Bucket
+}
+
+// indexText adds the terms of the message text at timestamp to the index.
+func (idx *searchIndex) indexText(timestamp int64, text string) error {
+	for term, positions := range termPositions(tokenize(text)) {
+		var buf []byte
+		for p := range positions {
+			buf = appendUvarint(buf, uint64(p))
+		}
+		if err := idx.bucket.Put(postingKey(term, timestamp), buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexText removes the terms of the message text at timestamp from the index.
+func (idx *searchIndex) unindexText(timestamp int64, text string) error {
+	for term := range termPositions(tokenize(text)) {
+		if err := idx.bucket.Delete(postingKey(term, timestamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// search returns the timestamps of the messages matching the query.
+func (idx *searchIndex) search(q searchQuery) map[int64]struct{} {
+	var matches map[int64]struct{}
+	for _, t := range q {
+		// Candidates must contain every word of the term, the phrase is then checked with the positions.
+		postings := make([]map[int64]map[int]struct{}, len(t.words))
+		for i, w := range t.words {
+			postings[i] = idx.postings(w, t.isPrefix(i))
+		}
+
+		set := make(map[int64]struct{})
+		for timestamp := range postings[0] {
+			if matches != nil {
+				if _, ok := matches[timestamp]; !ok {
+					continue
+				}
+			}
+
+			if t.match(func(i int) map[int]struct{} { return postings[i][timestamp] }) {
+				set[timestamp] = struct{}{}
+			}
+		}
+
+		matches = set
+		if len(matches) == 0 {
+			break
+		}
+	}
+	return matches
+}
+
+// postings returns the positions of a word in each message, by timestamp. When prefix is set, the
+// positions of all the terms starting with the word are merged.
+func (idx *searchIndex) postings(word string, prefix bool) map[int64]map[int]struct{} {
+	m := make(map[int64]map[int]struct{})
+
+	seek := []byte(word)
+	if !prefix {
+		seek = append(seek, 0)
+	}
+
+	c := idx.bucket.Cursor()
+	for k, v := c.Seek(seek); k != nil && bytes.HasPrefix(k, seek); k, v = c.Next() {
+		timestamp := int64(btou64(k[len(k)-8:]))
+		if m[timestamp] == nil {
+			m[timestamp] = make(map[int]struct{})
+		}
+		for len(v) > 0 {
+			p, n := binary.Uvarint(v)
+			if n <= 0 {
+				break
+			}
+			m[timestamp][int(p)] = struct{}{}
+			v = v[n:]
+		}
+	}
+	return m
+}
+
+// postingKey returns the key of the posting of a term for the message at timestamp.
+func postingKey(term string, timestamp int64) []byte {
+	k := make([]byte, len(term)+9)
+	copy(k, term)
+	copy(k[len(term)+1:], u64tob(uint64(timestamp)))
+	return k
+}
+
+// appendUvarint appends the varint encoding of v to buf.
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	return append(buf, b[:n]...)
+}
+
+// searchQueries are the full-text search queries of a where clause, by query string.
+type searchQueries map[string]searchQuery
+
+// parseSearchQueries returns the full-text search queries of the MATCHES conditions on the
+// content field in the where clause.
+func parseSearchQueries(cond sql.Expr) (searchQueries, error) {
+	queries := make(searchQueries)
+
+	var err error
+	sql.WalkFunc(cond, func(n sql.Node) {
+		expr, ok := n.(*sql.BinaryExpr)
+		if !ok || expr.Op != sql.MATCHES || err != nil {
+			return
+		}
+
+		ref, ok := expr.LHS.(*sql.VarRef)
+		if !ok || ref.Val != ContentField {
+			err = errors.New("MATCHES is only supported on the content field")
+			return
+		}
+		lit, ok := expr.RHS.(*sql.StringLiteral)
+		if !ok {
+			err = ErrInvalidSearchQuery
+			return
+		}
+
+		q, e := parseSearchQuery(lit.Val)
+		if e != nil {
+			err = e
+			return
+		}
+		queries[lit.Val] = q
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return queries, nil
+}
+
+// contentMatcher is the value of the content field of a message. It resolves MATCHES conditions
+// with the matches of each query computed for the conversation.
+type contentMatcher struct {
+	timestamp int64
+	matches   map[string]map[int64]struct{}
+}
+
+// MatchText returns true if the message matches the search query.
+func (m *contentMatcher) MatchText(query string) bool {
+	_, ok := m.matches[query][m.timestamp]
+	return ok
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Ensure search queries are split into words, phrases and prefixes.
+func TestParseSearchQuery(t *testing.T) {
+	var tests = []struct {
+		s   string
+		exp searchQuery
+		err error
+	}{
+		{s: `deploy failed`, exp: searchQuery{{words: []string{"deploy"}}, {words: []string{"failed"}}}},
+		{s: ` "Deploy  failed" `, exp: searchQuery{{words: []string{"deploy", "failed"}}}},
+		{s: `depl*`, exp: searchQuery{{words: []string{"depl"}, prefix: true}}},
+		{s: `"rolling back*`, exp: searchQuery{{words: []string{"rolling", "back"}, prefix: true}}},
+		{s: `re-deploy`, exp: searchQuery{{words: []string{"re", "deploy"}}}},
+		{s: ` "" * `, err: ErrInvalidSearchQuery},
+	}
+
+	for i, tt := range tests {
+		q, err := parseSearchQuery(tt.s)
+		if err != tt.err {
+			t.Errorf("%d. %q: error mismatch: got %v, exp %v", i, tt.s, err, tt.err)
+		} else if !reflect.DeepEqual(q, tt.exp) {
+			t.Errorf("%d. %q: got %#v, exp %#v", i, tt.s, q, tt.exp)
+		}
+	}
+}
+
+// Ensure search queries are matched against text without the index.
+func TestSearchQuery_MatchText(t *testing.T) {
+	var tests = []struct {
+		q    string
+		text string
+		exp  bool
+	}{
+		{q: `deploy failed`, text: "Failed to deploy", exp: true},
+		{q: `"deploy failed"`, text: "Failed to deploy", exp: false},
+		{q: `"deploy failed"`, text: "The deploy failed.", exp: true},
+		{q: `"the deploy*"`, text: "The deployment is green", exp: true},
+		{q: `deploy`, text: "The deployment is green", exp: false},
+		{q: `café`, text: "Lunch at the CAFÉ", exp: true},
+	}
+
+	for i, tt := range tests {
+		q, err := parseSearchQuery(tt.q)
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %s", i, err)
+		} else if got := q.matchText(tt.text); got != tt.exp {
+			t.Errorf("%d. %q matches %q: got %v, exp %v", i, tt.q, tt.text, got, tt.exp)
+		}
+	}
+}
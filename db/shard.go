@@ -32,6 +32,10 @@ var (
 	// ErrFieldNotFound is returned when a field cannot be found.
 	ErrFieldNotFound = errors.New("field not found")
 
+	// ErrFieldValueTooLarge is returned when a string field value is longer
+	// than the field encoding allows.
+	ErrFieldValueTooLarge = errors.New("field value too large")
+
 	// ErrFieldUnmappedID is returned when the system is presented, during decode, with a field ID
 	// there is no mapping for.
 	ErrFieldUnmappedID = errors.New("field ID not mapped")
@@ -41,8 +45,10 @@ var (
 	ErrWALPartitionNotFound = errors.New("wal partition not found")
 )
 
-// topLevelBucketN is the number of non-series buckets in the bolt db.
-const topLevelBucketN = 3
+// shardBuckets are the top-level buckets in the bolt db. The messages of each conversation
+// are stored in a bucket nested under the messages bucket, so that no conversation key can
+// collide with the other buckets.
+var shardBuckets = []string{"messages", "conversations", "fields", "wal", "history", "search", "mentions", "threads", "reactions", "links", "ids"}
 
 // Shard represents a self-contained time series database. An inverted index of
 // the measurement and tag data is kept along with the raw time series data.
@@ -122,13 +128,15 @@ func (s *Shard) Open() error {
 		}
 		s.db = store
 
-		// Initialize store.
+		// Initialize store. The ID index is built when opening a shard written without it.
+		var indexIDs bool
 		if err := s.db.Update(func(tx *bolt.Tx) error {
-			_, _ = tx.CreateBucketIfNotExists([]byte("messages"))
-			_, _ = tx.CreateBucketIfNotExists([]byte("fields"))
-			_, _ = tx.CreateBucketIfNotExists([]byte("wal"))
+			indexIDs = tx.Bucket([]byte("ids")) == nil
+			for _, name := range shardBuckets {
+				_, _ = tx.CreateBucketIfNotExists([]byte(name))
+			}
 
-			return nil
+			return moveConversationBuckets(tx)
 		}); err != nil {
 			return fmt.Errorf("init: %s", err)
 		}
@@ -137,6 +145,12 @@ func (s *Shard) Open() error {
 			return fmt.Errorf("load metadata index: %s", err)
 		}
 
+		if indexIDs {
+			if err := s.db.Update(s.indexIDs); err != nil {
+				return fmt.Errorf("index ids: %s", err)
+			}
+		}
+
 		// Initialize logger.
 		s.logger = log.New(s.LogOutput, "[shard] ", log.LstdFlags)
 
@@ -191,12 +205,251 @@ func (s *Shard) DB() *bolt.DB {
 	return s.db
 }
 
+// FieldCodec returns the codec of the fields written to a conversation of the shard, used by the mappers to decode
+// the stored messages. It returns nil when no message has been written to the conversation in this shard. The shard
+// is read locked while the codec is looked up. Writes of new fields replace the codec rather than modify it, so the
+// returned codec stays usable without the lock but does not know the fields added after the call.
+func (s *Shard) FieldCodec(conversationKey string) *FieldCodec {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := s.conversationFields[conversationKey]
+	if c == nil {
+		return nil
+	}
+	return c.codec
+}
+
 // WriteMessages will write the raw data messages and any new metadata to the index in the shard
 func (s *Shard) WriteMessages(messages []Message) error {
-	// TODO: implement this
+	values := make([]map[string]interface{}, len(messages))
+	for i, m := range messages {
+		v, err := marshalFields(m)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+
+	conversationsToCreate, fieldsToCreate, err := s.validateConversationsAndFields(messages, values)
+	if err != nil {
+		return err
+	}
+
+	// add any new conversations to the in-memory index
+	if len(conversationsToCreate) > 0 {
+		s.index.mu.Lock()
+		for _, c := range conversationsToCreate {
+			s.index.createConversationIndexIfNotExists(c.Key, c)
+		}
+		s.index.mu.Unlock()
+	}
+
+	// get the field codecs for all the conversations and create any new fields
+	conversationFieldsToSave, err := s.createFieldsAndConversations(fieldsToCreate)
+	if err != nil {
+		return err
+	}
+
+	// encode the message data using the conversation's field codec
+	s.mu.RLock()
+	for i, m := range messages {
+		c := s.conversationFields[string(m.Key())]
+		data, err := c.codec.EncodeFields(values[i])
+		if err != nil {
+			s.mu.RUnlock()
+			return err
+		}
+		m.SetData(data)
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// save to the underlying bolt instance
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		// only a message with the same ID replaces the message at its timestamp
+		s.resolveTimestamps(tx, messages)
+
+		// save any new conversations
+		if len(conversationsToCreate) > 0 {
+			b := tx.Bucket([]byte("conversations"))
+			for _, c := range conversationsToCreate {
+				data, err := c.MarshalBinary()
+				if err != nil {
+					return err
+				}
+				if err := b.Put([]byte(c.Key), data); err != nil {
+					return err
+				}
+			}
+		}
+
+		// save new field metadata
+		if len(conversationFieldsToSave) > 0 {
+			b := tx.Bucket([]byte("fields"))
+			for key, c := range conversationFieldsToSave {
+				data, err := c.MarshalBinary()
+				if err != nil {
+					return err
+				}
+				if err := b.Put([]byte(key), data); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Write messages to WAL bucket.
+		wal := tx.Bucket([]byte("wal"))
+		for _, m := range messages {
+			// Retrieve partition bucket.
+			key := m.Key()
+			b, err := wal.CreateBucketIfNotExists([]byte{WALPartition(key)})
+			if err != nil {
+				return fmt.Errorf("create WAL partition bucket: %s", err)
+			}
+
+			// Generate an autoincrementing index for the WAL partition.
+			id, _ := b.NextSequence()
+
+			// Append message to the end of the WAL partition.
+			if err := b.Put(u64tob(id), marshalWALEntry(key, m.UnixNano(), m.Data())); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// If successful then save messages to in-memory cache.
+	for _, m := range messages {
+		// Generate in-memory cache entry of <timestamp,data>.
+		key, data := m.Key(), m.Data()
+		v := marshalCacheEntry(m.UnixNano(), data)
+
+		// Determine cache partition.
+		partitionID := WALPartition(key)
+
+		// Append to cache list.
+		a := s.cache[partitionID][string(key)]
+		a = append(a, v)
+
+		// Sort by timestamp if the newly appended value is out of order. The
+		// sort is stable so the latest write wins for duplicate timestamps.
+		if len(a) > 1 && bytes.Compare(a[len(a)-2][0:8], v[0:8]) == 1 {
+			sort.Stable(cacheEntries(a))
+		}
+		s.cache[partitionID][string(key)] = a
+
+		// Calculate estimated WAL size.
+		s.walSize += len(key) + len(v)
+	}
+
+	// Check for flush threshold.
+	s.triggerAutoFlush()
+
 	return nil
 }
 
+// validateConversationsAndFields checks which conversations and fields are
+// new and whose metadata should be saved and indexed
+func (s *Shard) validateConversationsAndFields(messages []Message, values []map[string]interface{}) ([]*Conversation, []*fieldCreate, error) {
+	var conversationsToCreate []*Conversation
+	var fieldsToCreate []*fieldCreate
+	seen := make(map[string]struct{})
+
+	// get the mutex for the in memory index, which is shared across shards
+	s.index.mu.RLock()
+	defer s.index.mu.RUnlock()
+
+	// get the shard mutex for locally defined fields
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i, m := range messages {
+		key := string(m.Key())
+
+		// see if the conversation should be added to the index
+		if _, ok := seen[key]; !ok && s.index.conversations[key] == nil {
+			conversationsToCreate = append(conversationsToCreate, &Conversation{
+				Name:       key,
+				Key:        key,
+				Tags:       make(map[string]string),
+				fieldNames: make(map[string]struct{}),
+				index:      s.index,
+			})
+		}
+		seen[key] = struct{}{}
+
+		// see if any fields should be created
+		c := s.conversationFields[key]
+		for name, value := range values[i] {
+			typ := sql.InspectDataType(value)
+			if c != nil {
+				if f := c.Fields[name]; f != nil {
+					// Field present in shard metadata, make sure there is no type conflict.
+					if f.Type != typ {
+						return nil, nil, fmt.Errorf("field \"%s\" is type %s, mapped as type %s: %s", name, typ, f.Type, ErrFieldTypeConflict)
+					}
+					continue
+				}
+			}
+
+			// Field isn't in the shard metadata. Add it to the list of fields to create.
+			fieldsToCreate = append(fieldsToCreate, &fieldCreate{conversation: key, name: name, typ: typ})
+		}
+	}
+
+	return conversationsToCreate, fieldsToCreate, nil
+}
+
+// createFieldsAndConversations creates the field metadata for new fields and
+// returns the conversation fields that need to be persisted.
+func (s *Shard) createFieldsAndConversations(fieldsToCreate []*fieldCreate) (map[string]*conversationFields, error) {
+	if len(fieldsToCreate) == 0 {
+		return nil, nil
+	}
+
+	s.index.mu.Lock()
+	s.mu.Lock()
+	defer s.index.mu.Unlock()
+	defer s.mu.Unlock()
+
+	// add fields
+	conversationsToSave := make(map[string]*conversationFields)
+	for _, f := range fieldsToCreate {
+		c := s.conversationFields[f.conversation]
+		if c == nil {
+			c = &conversationFields{Fields: make(map[string]*field)}
+			s.conversationFields[f.conversation] = c
+		}
+		conversationsToSave[f.conversation] = c
+
+		// add the field to the in memory index
+		if err := c.createFieldIfNotExists(f.name, f.typ); err != nil {
+			return nil, err
+		}
+
+		// ensure the conversation is in the index and the field is there
+		if conv := s.index.conversations[f.conversation]; conv != nil {
+			conv.mu.Lock()
+			conv.fieldNames[f.name] = struct{}{}
+			conv.mu.Unlock()
+		}
+	}
+
+	return conversationsToSave, nil
+}
+
+// fieldCreate holds information for a field to create on a conversation
+type fieldCreate struct {
+	conversation string
+	name         string
+	typ          sql.DataType
+}
+
 // Flush writes all points from the write ahead log to the index.
 func (s *Shard) Flush(partitionFlushDelay time.Duration) error {
 	// Retrieve a list of WAL buckets.
@@ -253,16 +506,81 @@ func (s *Shard) FlushPartition(partitionID uint8) error {
 			key, timestamp, data := unmarshalWALEntry(v)
 
 			// Create bucket for entry.
-			b, err := tx.CreateBucketIfNotExists(key)
+			b, err := tx.Bucket([]byte("messages")).CreateBucketIfNotExists(key)
 			if err != nil {
 				return fmt.Errorf("create bucket: %s", err)
 			}
 
+			// Retrieve the search index of the conversation.
+			idx, err := createSearchIndexIfNotExists(tx, key)
+			if err != nil {
+				return fmt.Errorf("create search index: %s", err)
+			}
+
+			// Retrieve the thread index of the conversation.
+			threads, err := createThreadIndexIfNotExists(tx, key)
+			if err != nil {
+				return fmt.Errorf("create thread index: %s", err)
+			}
+
+			// Move a message written at the timestamp of another message to the
+			// next free timestamp, it is only a revision if it has the same ID.
+			prev := b.Get(u64tob(uint64(timestamp)))
+			for prev != nil && !sameMessage(s.messageID(string(key), prev), s.messageID(string(key), data)) {
+				timestamp++
+				prev = b.Get(u64tob(uint64(timestamp)))
+			}
+
+			// Keep the revision being replaced in the history of the conversation,
+			// its text is no longer searchable.
+			if prev != nil {
+				if err := putRevision(tx, key, timestamp, prev); err != nil {
+					return fmt.Errorf("put revision: %s", err)
+				}
+				if err := idx.unindexText(timestamp, s.messageText(string(key), prev)); err != nil {
+					return fmt.Errorf("unindex: %s", err)
+				}
+				if err := unindexMentions(tx, key, timestamp, s.messageMentions(string(key), prev)); err != nil {
+					return fmt.Errorf("unindex mentions: %s", err)
+				}
+				if err := threads.unindexReply(s.messageParentID(string(key), prev), timestamp); err != nil {
+					return fmt.Errorf("unindex reply: %s", err)
+				}
+				if err := unindexLinks(tx, key, timestamp); err != nil {
+					return fmt.Errorf("unindex links: %s", err)
+				}
+			}
+
 			// Write point to bucket.
 			if err := b.Put(u64tob(uint64(timestamp)), data); err != nil {
 				return fmt.Errorf("put: %s", err)
 			}
 
+			// Index the message by its ID.
+			if err := indexID(tx, key, s.messageID(string(key), data), timestamp); err != nil {
+				return fmt.Errorf("index id: %s", err)
+			}
+
+			// Index the text of the message.
+			if err := idx.indexText(timestamp, s.messageText(string(key), data)); err != nil {
+				return fmt.Errorf("index: %s", err)
+			}
+
+			// Index the users mentioned in the message.
+			if err := indexMentions(tx, key, timestamp, s.messageMentions(string(key), data)); err != nil {
+				return fmt.Errorf("index mentions: %s", err)
+			}
+
+			// Index the message as a reply to its parent.
+			if err := threads.indexReply(s.messageParentID(string(key), data), timestamp); err != nil {
+				return fmt.Errorf("index reply: %s", err)
+			}
+
+			// Index the links found in the message.
+			if err := indexLinks(tx, key, timestamp, s.messageLinks(string(key), data)); err != nil {
+				return fmt.Errorf("index links: %s", err)
+			}
+
 			// Remove entry in the WAL.
 			if err := c.Delete(); err != nil {
 				return fmt.Errorf("delete: %s", err)
@@ -286,6 +604,118 @@ func (s *Shard) FlushPartition(partitionID uint8) error {
 	return nil
 }
 
+// putRevision stores a replaced message in the history bucket of the conversation. Revisions
+// are keyed by timestamp followed by a sequence so they iterate in the order they were replaced.
+func putRevision(tx *bolt.Tx, key []byte, timestamp int64, data []byte) error {
+	b, err := tx.Bucket([]byte("history")).CreateBucketIfNotExists(key)
+	if err != nil {
+		return err
+	}
+
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	k := make([]byte, 16)
+	copy(k[0:8], u64tob(uint64(timestamp)))
+	copy(k[8:16], u64tob(seq))
+
+	// Bolt only guarantees the value for the life of the transaction so store a copy.
+	v := make([]byte, len(data))
+	copy(v, data)
+
+	return b.Put(k, v)
+}
+
+// createSearchIndexIfNotExists returns the full-text search index of a conversation.
+func createSearchIndexIfNotExists(tx *bolt.Tx, key []byte) (*searchIndex, error) {
+	b, err := tx.Bucket([]byte("search")).CreateBucketIfNotExists(key)
+	if err != nil {
+		return nil, err
+	}
+	return &searchIndex{bucket: b}, nil
+}
+
+// resolveTimestamps moves the messages written at the timestamp of a message of their
+// conversation with another ID to the next free timestamp, so that a write only replaces
+// a revision of the same message. This function must be called within the context of a lock.
+func (s *Shard) resolveTimestamps(tx *bolt.Tx, messages []Message) {
+	written := make(map[string]map[int64]string)
+	for _, m := range messages {
+		key := string(m.Key())
+		timestamp := m.UnixNano()
+		for {
+			id, ok := written[key][timestamp]
+			if !ok {
+				id, ok = s.storedMessageID(tx, key, timestamp)
+			}
+			if !ok || sameMessage(id, m.ID()) {
+				break
+			}
+			timestamp++
+		}
+		if timestamp != m.UnixNano() {
+			m.SetTime(time.Unix(0, timestamp).UTC())
+		}
+
+		if written[key] == nil {
+			written[key] = make(map[int64]string)
+		}
+		written[key][timestamp] = m.ID()
+	}
+}
+
+// sameMessage returns true if two messages with the given IDs are revisions of the same
+// message. Messages without an ID are never revisions of another message.
+func sameMessage(id, other string) bool {
+	return id != "" && id == other
+}
+
+// storedMessageID returns the ID of the latest revision of the message of a conversation
+// at timestamp, from the cache or the store. Returns false if there is no such message.
+// This function must be called within the context of a lock.
+func (s *Shard) storedMessageID(tx *bolt.Tx, key string, timestamp int64) (string, bool) {
+	a := s.cache[WALPartition([]byte(key))][key]
+	i := sort.Search(len(a), func(i int) bool { return int64(btou64(a[i][0:8])) > timestamp })
+	if i > 0 {
+		if ts, data := unmarshalCacheEntry(a[i-1]); ts == timestamp {
+			return s.messageID(key, data), true
+		}
+	}
+
+	if b := conversationBucket(tx, key); b != nil {
+		if data := b.Get(u64tob(uint64(timestamp))); data != nil {
+			return s.messageID(key, data), true
+		}
+	}
+	return "", false
+}
+
+// messageID returns the ID of an encoded message of a conversation. This function
+// must be called within the context of a lock.
+func (s *Shard) messageID(key string, data []byte) string {
+	c := s.conversationFields[key]
+	if c == nil {
+		return ""
+	}
+	id, _ := c.codec.DecodeByName(fieldID, data)
+	str, _ := id.(string)
+	return str
+}
+
+// messageText returns the text of an encoded message of a conversation. This function
+// must be called within the context of a lock.
+func (s *Shard) messageText(key string, data []byte) string {
+	c := s.conversationFields[key]
+	if c == nil {
+		return ""
+	}
+	text, _ := c.codec.DecodeByName(fieldText, data)
+	str, _ := text.(string)
+	return str
+}
+
 // autoflusher waits for notification of a flush and kicks it off in the background.
 // This method runs in a separate goroutine.
 func (s *Shard) autoflusher(closing chan struct{}) {
@@ -333,6 +763,30 @@ func (s *Shard) deleteConversation(name string) error {
 		if err := b.Delete([]byte(name)); err != nil {
 			return err
 		}
+		if err := s.unindexConversationMentions(tx, name); err != nil {
+			return err
+		}
+		if err := tx.Bucket([]byte("messages")).DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		if err := tx.Bucket([]byte("history")).DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		if err := tx.Bucket([]byte("search")).DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		if err := tx.Bucket([]byte("threads")).DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		if err := tx.Bucket([]byte("reactions")).DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		if err := tx.Bucket([]byte("links")).DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		if err := tx.Bucket([]byte("ids")).DeleteBucket([]byte(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
 		delete(s.cache[WALPartition([]byte(name))], name)
 
 		return nil
@@ -349,23 +803,6 @@ func (s *Shard) loadMetadataIndex() error {
 		s.index.mu.Lock()
 		defer s.index.mu.Unlock()
 
-		// load measurement metadata
-		// meta := tx.Bucket([]byte("fields"))
-		// c := meta.Cursor()
-		// for k, v := c.First(); k != nil; k, v = c.Next() {
-		// 	m := s.index.createMeasurementIndexIfNotExists(string(k))
-		//
-		// 	mf := &measurementFields{}
-		// 	if err := mf.UnmarshalBinary(v); err != nil {
-		// 		return err
-		// 	}
-		// 	for name, _ := range mf.Fields {
-		// 		m.fieldNames[name] = struct{}{}
-		// 	}
-		// 	mf.codec = newFieldCodec(mf.Fields)
-		// 	s.measurementFields[string(k)] = mf
-		// }
-
 		// load conversations metadata
 		meta := tx.Bucket([]byte("conversations"))
 		c := meta.Cursor()
@@ -374,7 +811,27 @@ func (s *Shard) loadMetadataIndex() error {
 			if err := conversation.UnmarshalBinary(v); err != nil {
 				return err
 			}
-			s.index.createConversationIndexIfNotExists(string(k), conversation)
+			conversation.Name = conversation.Key
+			conversation.fieldNames = make(map[string]struct{})
+			conversation = s.index.createConversationIndexIfNotExists(string(k), conversation)
+			conversation.index = s.index
+		}
+
+		// load field metadata
+		meta = tx.Bucket([]byte("fields"))
+		c = meta.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			cf := &conversationFields{}
+			if err := cf.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			if conversation := s.index.conversations[string(k)]; conversation != nil {
+				for name := range cf.Fields {
+					conversation.fieldNames[name] = struct{}{}
+				}
+			}
+			cf.codec = newFieldCodec(cf.Fields)
+			s.conversationFields[string(k)] = cf
 		}
 		return nil
 	})
@@ -384,16 +841,61 @@ func (s *Shard) loadMetadataIndex() error {
 // This does not include a count from the WAL.
 func (s *Shard) ConversationsCount() (n int, err error) {
 	err = s.db.View(func(tx *bolt.Tx) error {
-		return tx.ForEach(func(_ []byte, _ *bolt.Bucket) error {
-			n++
+		return tx.Bucket([]byte("messages")).ForEach(func(_, v []byte) error {
+			// Nested buckets have no value.
+			if v == nil {
+				n++
+			}
 			return nil
 		})
 	})
+	return
+}
 
-	// Remove top-level buckets.
-	n -= topLevelBucketN
+// conversationBucket returns the bucket of the messages of a conversation, or nil if the
+// conversation has no flushed messages.
+func conversationBucket(tx *bolt.Tx, key string) *bolt.Bucket {
+	return tx.Bucket([]byte("messages")).Bucket([]byte(key))
+}
 
-	return
+// moveConversationBuckets moves the messages of the conversations of a shard written when
+// they were stored in top-level buckets under the messages bucket.
+func moveConversationBuckets(tx *bolt.Tx) error {
+	var keys [][]byte
+	if err := tx.Bucket([]byte("conversations")).ForEach(func(k, _ []byte) error {
+		if tx.Bucket(k) != nil && !isShardBucket(string(k)) {
+			keys = append(keys, append([]byte{}, k...))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		dst, err := tx.Bucket([]byte("messages")).CreateBucketIfNotExists(key)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(key).ForEach(func(k, v []byte) error {
+			return dst.Put(append([]byte{}, k...), append([]byte{}, v...))
+		}); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isShardBucket returns true if name is the name of a top-level bucket of a shard.
+func isShardBucket(name string) bool {
+	for _, n := range shardBuckets {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
 
 type conversationFields struct {
@@ -535,7 +1037,7 @@ func (f *FieldCodec) EncodeFields(values map[string]interface{}) ([]byte, error)
 		case sql.String:
 			value := v.(string)
 			if len(value) > maxStringLength {
-				value = value[:maxStringLength]
+				return nil, fmt.Errorf("field \"%s\": %s", field.Name, ErrFieldValueTooLarge)
 			}
 			// Make a buffer for field ID (1 bytes), the string length (2 bytes), and the string.
 			buf = make([]byte, len(value)+3)
@@ -610,7 +1112,7 @@ func (f *FieldCodec) DecodeFields(b []byte) (map[uint8]interface{}, error) {
 			// Move bytes forward.
 			b = b[2:]
 		case sql.String:
-			size := binary.BigEndian.Uint16(b[1:3])
+			size := int(binary.BigEndian.Uint16(b[1:3]))
 			value = string(b[3 : size+3])
 			// Move bytes forward.
 			b = b[size+3:]
@@ -683,7 +1185,7 @@ func (f *FieldCodec) DecodeByID(targetID uint8, b []byte) (interface{}, error) {
 			// Move bytes forward.
 			b = b[2:]
 		case sql.String:
-			size := binary.BigEndian.Uint16(b[1:3])
+			size := int(binary.BigEndian.Uint16(b[1:3]))
 			value = string(b[3 : 3+size])
 			// Move bytes forward.
 			b = b[size+3:]
@@ -734,6 +1236,13 @@ func mustUnmarshalJSON(b []byte, v interface{}) {
 	}
 }
 
+// cacheEntries represents a slice of cache entries sortable by timestamp.
+type cacheEntries [][]byte
+
+func (a cacheEntries) Len() int           { return len(a) }
+func (a cacheEntries) Less(i, j int) bool { return bytes.Compare(a[i][0:8], a[j][0:8]) == -1 }
+func (a cacheEntries) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
 // u64tob converts a uint64 into an 8-byte slice.
 func u64tob(v uint64) []byte {
 	b := make([]byte, 8)
@@ -790,6 +1299,8 @@ func unmarshalCacheEntry(buf []byte) (timestamp int64, data []byte) {
 }
 
 // shardCursor provides ordered iteration across a Bolt bucket and shard cache.
+// By default only the latest value of a key is returned. In revisions mode every
+// revision of a key is returned, in the order they were written.
 type shardCursor struct {
 	// Bolt cursor and readahead buffer.
 	cursor *bolt.Cursor
@@ -797,22 +1308,52 @@ type shardCursor struct {
 		key, value []byte
 	}
 
+	// Bolt cursor over replaced revisions and its readahead buffer.
+	history *bolt.Cursor
+	hbuf    struct {
+		key, value []byte
+	}
+
 	// Cache and current cache index.
 	cache [][]byte
 	index int
+
+	// Return all revisions instead of only the latest value of a key.
+	revisions bool
+
+	// Iterate from the highest key to the lowest.
+	descending bool
 }
 
 // Seek moves the cursor to a position and returns the closest key/value pair.
+// When descending the closest pair is the one at or before the position.
 func (sc *shardCursor) Seek(seek []byte) (key, value []byte) {
 	// Seek bolt cursor.
 	if sc.cursor != nil {
-		sc.buf.key, sc.buf.value = sc.cursor.Seek(seek)
+		sc.buf.key, sc.buf.value = seekCursor(sc.cursor, seek, sc.descending)
+	}
+
+	// Seek history cursor. Revisions are keyed by timestamp and sequence so seek
+	// past all the revisions of the position when descending.
+	if sc.history != nil {
+		if sc.descending {
+			seek := append(append([]byte{}, seek...), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+			sc.hbuf.key, sc.hbuf.value = seekCursor(sc.history, seek, true)
+		} else {
+			sc.hbuf.key, sc.hbuf.value = seekCursor(sc.history, seek, false)
+		}
 	}
 
 	// Seek cache index.
-	sc.index = sort.Search(len(sc.cache), func(i int) bool {
-		return bytes.Compare(sc.cache[i][0:8], seek) != -1
-	})
+	if sc.descending {
+		sc.index = sort.Search(len(sc.cache), func(i int) bool {
+			return bytes.Compare(sc.cache[i][0:8], seek) == 1
+		}) - 1
+	} else {
+		sc.index = sort.Search(len(sc.cache), func(i int) bool {
+			return bytes.Compare(sc.cache[i][0:8], seek) != -1
+		})
+	}
 
 	return sc.read()
 }
@@ -821,7 +1362,12 @@ func (sc *shardCursor) Seek(seek []byte) (key, value []byte) {
 func (sc *shardCursor) Next() (key, value []byte) {
 	// Read next bolt key/value if not bufferred.
 	if sc.buf.key == nil && sc.cursor != nil {
-		sc.buf.key, sc.buf.value = sc.cursor.Next()
+		sc.buf.key, sc.buf.value = nextCursor(sc.cursor, sc.descending)
+	}
+
+	// Read next history key/value if not bufferred.
+	if sc.hbuf.key == nil && sc.history != nil {
+		sc.hbuf.key, sc.hbuf.value = nextCursor(sc.history, sc.descending)
 	}
 
 	return sc.read()
@@ -829,19 +1375,37 @@ func (sc *shardCursor) Next() (key, value []byte) {
 
 // read returns the next key/value in the cursor buffer or cache.
 func (sc *shardCursor) read() (key, value []byte) {
+	if sc.revisions {
+		return sc.readRevision()
+	}
+
 	// If neither a buffer or cache exists then return nil.
-	if sc.buf.key == nil && sc.index >= len(sc.cache) {
+	ck := sc.cacheKey()
+	if sc.buf.key == nil && ck == nil {
 		return nil, nil
 	}
 
-	// Use the buffer if it exists and there's no cache or if it is lower than the cache.
-	if sc.buf.key != nil && (sc.index >= len(sc.cache) || bytes.Compare(sc.buf.key, sc.cache[sc.index][0:8]) == -1) {
+	// Use the buffer if it exists and there's no cache or if it comes before the cache.
+	if sc.buf.key != nil && (ck == nil || sc.before(sc.buf.key, ck)) {
 		key, value = sc.buf.key, sc.buf.value
 		sc.buf.key, sc.buf.value = nil, nil
 		return
 	}
 
-	// Otherwise read from the cache.
+	// A cached value for the same key replaces the one stored in bolt.
+	if sc.buf.key != nil && bytes.Equal(sc.buf.key, ck) {
+		sc.buf.key, sc.buf.value = nil, nil
+	}
+
+	// Otherwise read from the cache. When descending the first of duplicate keys
+	// is the latest write, skip the ones that follow it.
+	if sc.descending {
+		key, value = sc.cache[sc.index][0:8], sc.cache[sc.index][8:]
+		for sc.index--; sc.index >= 0 && bytes.Equal(key, sc.cache[sc.index][0:8]); sc.index-- {
+		}
+		return
+	}
+
 	// Continue skipping ahead through duplicate keys in the cache list.
 	for {
 		// Read the current cache key/value pair.
@@ -857,6 +1421,99 @@ func (sc *shardCursor) read() (key, value []byte) {
 	return
 }
 
+// readRevision returns the next revision in the history buffer, cursor buffer or cache.
+// Revisions of the same key are returned in the order they were written: replaced
+// revisions first, then the value stored in bolt and finally the cached values. The
+// order is reversed when descending.
+func (sc *shardCursor) readRevision() (key, value []byte) {
+	var hk []byte
+	if sc.hbuf.key != nil {
+		hk = sc.hbuf.key[0:8]
+	}
+	ck := sc.cacheKey()
+
+	// Pick the key that comes first, ties go to the revision written first.
+	first, second, third := hk, sc.buf.key, ck
+	if sc.descending {
+		first, third = third, first
+	}
+	switch {
+	case first != nil && (second == nil || !sc.before(second, first)) && (third == nil || !sc.before(third, first)):
+		if sc.descending {
+			return sc.readCache()
+		}
+		return sc.readHistory()
+	case second != nil && (third == nil || !sc.before(third, second)):
+		key, value = sc.buf.key, sc.buf.value
+		sc.buf.key, sc.buf.value = nil, nil
+		return
+	case third != nil:
+		if sc.descending {
+			return sc.readHistory()
+		}
+		return sc.readCache()
+	}
+	return nil, nil
+}
+
+// readHistory returns the buffered history revision.
+func (sc *shardCursor) readHistory() (key, value []byte) {
+	key, value = sc.hbuf.key[0:8], sc.hbuf.value
+	sc.hbuf.key, sc.hbuf.value = nil, nil
+	return
+}
+
+// readCache returns the current cache entry, including duplicate keys.
+func (sc *shardCursor) readCache() (key, value []byte) {
+	key, value = sc.cache[sc.index][0:8], sc.cache[sc.index][8:]
+	if sc.descending {
+		sc.index--
+	} else {
+		sc.index++
+	}
+	return
+}
+
+// cacheKey returns the key of the current cache entry, or nil if the cache is exhausted.
+func (sc *shardCursor) cacheKey() []byte {
+	if sc.index < 0 || sc.index >= len(sc.cache) {
+		return nil
+	}
+	return sc.cache[sc.index][0:8]
+}
+
+// before returns true if key a comes before key b in the iteration order.
+func (sc *shardCursor) before(a, b []byte) bool {
+	if sc.descending {
+		return bytes.Compare(a, b) == 1
+	}
+	return bytes.Compare(a, b) == -1
+}
+
+// seekCursor moves a bolt cursor to the first key at or after seek, or to the
+// last key at or before seek when descending.
+func seekCursor(c *bolt.Cursor, seek []byte, descending bool) (key, value []byte) {
+	key, value = c.Seek(seek)
+	if !descending {
+		return
+	}
+	if key == nil {
+		return c.Last()
+	}
+	if bytes.Compare(key, seek) == 1 {
+		return c.Prev()
+	}
+	return
+}
+
+// nextCursor moves a bolt cursor to the next key in the iteration order.
+func nextCursor(c *bolt.Cursor, descending bool) (key, value []byte) {
+	if descending {
+		return c.Prev()
+	}
+	return c.Next()
+}
+
 // WALPartitionN is the number of partitions in the write ahead log.
 const WALPartitionN = 8
 
@@ -1,34 +1,708 @@
 package db
 
-// // Ensure the shard will automatically flush the WAL after a threshold has been reached.
-// func TestShard_Autoflush(t *testing.T) {
-// 	path, _ := ioutil.TempDir("", "shard_test")
-// 	defer os.RemoveAll(path)
-//
-// 	// Open shard with a really low size threshold, high flush interval.
-// 	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
-// 	sh.MaxWALSize = 1024 // 1KB
-// 	sh.WALFlushInterval = 1 * time.Hour
-// 	sh.WALPartitionFlushDelay = 1 * time.Millisecond
-// 	if err := sh.Open(); err != nil {
-// 		t.Fatal(err)
-// 	}
-// 	defer sh.Close()
-//
-// 	// Write a bunch of points.
-// 	for i := 0; i < 100; i++ {
-// 		if err := sh.WriteMessages([]Message{NewMessage(time.Unix(1, 2))}); err != nil {
-// 			t.Fatal(err)
-// 		}
-// 	}
-//
-// 	// Wait for autoflush.
-// 	time.Sleep(100 * time.Millisecond)
-//
-// 	// Make sure we have series buckets created outside the WAL.
-// 	if n, err := sh.ConversationsCount(); err != nil {
-// 		t.Fatal(err)
-// 	} else if n < 10 {
-// 		t.Fatalf("not enough series, expected at least 10, got %d", n)
-// 	}
-// }
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Ensure the shard will automatically flush the WAL after a threshold has been reached.
+func TestShard_Autoflush(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	// Open shard with a really low size threshold, high flush interval.
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	sh.MaxWALSize = 1024 // 1KB
+	sh.WALFlushInterval = 1 * time.Hour
+	sh.WALPartitionFlushDelay = 1 * time.Millisecond
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	// Write a bunch of messages.
+	for i := 0; i < 100; i++ {
+		m := NewMessage(fmt.Sprintf("conversation%d", i), Sender{UserID: "1"}, Content{PlainText: "hello"}, nil, time.Unix(1, 2))
+		if err := sh.WriteMessages([]Message{m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Wait for autoflush.
+	time.Sleep(100 * time.Millisecond)
+
+	// Make sure we have conversation buckets created outside the WAL.
+	if n, err := sh.ConversationsCount(); err != nil {
+		t.Fatal(err)
+	} else if n < 10 {
+		t.Fatalf("not enough conversations, expected at least 10, got %d", n)
+	}
+}
+
+// Ensure messages written to a shard keep their structure after a reopen.
+func TestShard_WriteMessages(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	tmpShard := filepath.Join(path, "shard")
+	sh := NewShard(NewDatabaseIndex(), tmpShard)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMessage(
+		"general",
+		Sender{UserID: "1", Name: "jdoe"},
+		Content{PlainText: "hi @jane", HTML: "<p>hi @jane</p>"},
+		[]Mention{{RecipientID: "2", RecipientUsername: "jane"}},
+		time.Unix(1, 2).UTC(),
+	)
+	m.SetID("m1")
+	m.SetSnippet(&Snippet{Language: "go", Filename: "main.go", Body: "package main\n"})
+	m.SetEditedAt(time.Unix(3, 0).UTC())
+	m.AddField("priority", 2)
+	m.AddField("score", 0.5)
+	m.AddField("pinned", true)
+	m.AddField("client", "web")
+
+	if err := sh.WriteMessages([]Message{m}); err != nil {
+		t.Fatalf("error writing message: %s", err)
+	}
+	if err := sh.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the shard so the data is read back from disk.
+	index := NewDatabaseIndex()
+	sh = NewShard(index, tmpShard)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	c := index.Conversation("general")
+	if c == nil {
+		t.Fatal("conversation not in index")
+	} else if !c.HasField("text") || !c.HasField("priority") {
+		t.Fatal("conversation fields not in index")
+	}
+
+	tx, err := sh.DB().Begin(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	k, v := createCursorForConversation(tx, sh, "general").Seek(u64tob(0))
+	if k == nil {
+		t.Fatal("message not found")
+	}
+	values, err := sh.FieldCodec("general").DecodeFieldsWithNames(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalMessage("general", int64(btou64(k)), values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.String() != m.String() {
+		t.Fatalf("message mismatch:\n got %v\n exp %v", got.String(), m.String())
+	}
+	if !reflect.DeepEqual(got.Fields(), m.Fields()) {
+		t.Fatalf("fields mismatch:\n got %#v\n exp %#v", got.Fields(), m.Fields())
+	}
+	if !reflect.DeepEqual(got.Snippet(), m.Snippet()) {
+		t.Fatalf("snippet mismatch:\n got %#v\n exp %#v", got.Snippet(), m.Snippet())
+	}
+}
+
+// Ensure the shard rejects fields whose type changed or that use a reserved name.
+func TestShard_WriteMessages_FieldErrors(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	m := NewMessage("general", Sender{}, Content{PlainText: "a"}, nil, time.Unix(1, 0))
+	m.AddField("priority", 1)
+	if err := sh.WriteMessages([]Message{m}); err != nil {
+		t.Fatal(err)
+	}
+
+	m = NewMessage("general", Sender{}, Content{PlainText: "b"}, nil, time.Unix(2, 0))
+	m.AddField("priority", "high")
+	if err := sh.WriteMessages([]Message{m}); err == nil {
+		t.Fatal("expected field type conflict")
+	}
+
+	m = NewMessage("general", Sender{}, Content{PlainText: "c"}, nil, time.Unix(3, 0))
+	m.AddField("text", "other")
+	if err := sh.WriteMessages([]Message{m}); err == nil {
+		t.Fatal("expected reserved field name error")
+	}
+}
+
+// Ensure string values at the size limit are read back intact and larger ones are rejected.
+func TestShard_WriteMessages_SizeLimits(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	snippet := &Snippet{Language: "go", Body: strings.Repeat("x", MaxSnippetSize)}
+	if err := snippet.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	m := NewMessage("general", Sender{UserID: "1"}, Content{PlainText: strings.Repeat("y", MaxContentLength)}, nil, time.Unix(1, 0))
+	m.SetID("m1")
+	m.SetSnippet(snippet)
+	if err := sh.WriteMessages([]Message{m}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := sh.MessageByID("general", "m1")
+	if err != nil {
+		t.Fatal(err)
+	} else if got == nil || got.Snippet().Body != snippet.Body || got.Content().PlainText != m.Content().PlainText {
+		t.Fatal("message mismatch at the size limit")
+	}
+
+	if err := (&Snippet{Body: strings.Repeat("x", MaxSnippetSize+1)}).Validate(); err != ErrSnippetTooLarge {
+		t.Fatalf("Snippet.Validate() mismatch: got %v, exp %v", err, ErrSnippetTooLarge)
+	}
+	if err := (Content{PlainText: strings.Repeat("y", MaxContentLength+1)}).Validate(); err != ErrContentTooLarge {
+		t.Fatalf("Content.Validate() mismatch: got %v, exp %v", err, ErrContentTooLarge)
+	}
+
+	// Values too large for the field encoding are rejected instead of being truncated.
+	m = NewMessage("general", Sender{UserID: "1"}, Content{PlainText: strings.Repeat("y", MaxContentLength+1)}, nil, time.Unix(2, 0))
+	if err := sh.WriteMessages([]Message{m}); err == nil || !strings.Contains(err.Error(), ErrFieldValueTooLarge.Error()) {
+		t.Fatalf("expected field value too large error, got %v", err)
+	}
+	m = NewMessage("general", Sender{UserID: "1"}, Content{PlainText: "z"}, nil, time.Unix(3, 0))
+	m.AddField("note", strings.Repeat("z", maxStringLength+1))
+	if err := sh.WriteMessages([]Message{m}); err == nil || !strings.Contains(err.Error(), ErrFieldValueTooLarge.Error()) {
+		t.Fatalf("expected field value too large error, got %v", err)
+	}
+}
+
+// Ensure conversations named after the buckets of the shard keep their messages apart, and
+// that messages stored in top-level buckets are moved when the shard is opened.
+func TestShard_WriteMessages_BucketNames(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	tmpShard := filepath.Join(path, "shard")
+	sh := NewShard(NewDatabaseIndex(), tmpShard)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"general", "history", "ids", "wal"}
+	for _, key := range keys {
+		m := NewMessage(key, Sender{UserID: "1"}, Content{PlainText: key}, nil, time.Unix(1, 0))
+		m.SetID(key)
+		if err := sh.WriteMessages([]Message{m}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Store the messages of a conversation the way older shards did.
+	if err := sh.DB().Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("general"))
+		if err != nil {
+			return err
+		}
+		if err := conversationBucket(tx, "general").ForEach(func(k, v []byte) error { return b.Put(k, v) }); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("messages")).DeleteBucket([]byte("general"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sh = NewShard(NewDatabaseIndex(), tmpShard)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	if n, err := sh.ConversationsCount(); err != nil {
+		t.Fatal(err)
+	} else if n != len(keys) {
+		t.Fatalf("ConversationsCount() mismatch: got %d, exp %d", n, len(keys))
+	}
+	for _, key := range keys {
+		if m, err := sh.MessageByID(key, key); err != nil {
+			t.Fatal(err)
+		} else if m == nil || m.Content().PlainText != key {
+			t.Fatalf("message of %s mismatch: got %v", key, m)
+		}
+	}
+}
+
+// Ensure a message written at the timestamp of another message does not replace it.
+func TestShard_WriteMessages_SameTimestamp(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	newMessage := func(id, text string) Message {
+		m := NewMessage("general", Sender{UserID: "1"}, Content{PlainText: text}, nil, time.Unix(1, 0))
+		m.SetID(id)
+		return m
+	}
+
+	// Messages of the same batch, in the cache and in the store are all kept.
+	m1, m2 := newMessage("m1", "a"), newMessage("m2", "b")
+	if err := sh.WriteMessages([]Message{m1, m2}); err != nil {
+		t.Fatal(err)
+	}
+	m3 := newMessage("m3", "c")
+	if err := sh.WriteMessages([]Message{m3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	m4 := newMessage("m4", "d")
+	if err := sh.WriteMessages([]Message{m4}); err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range []Message{m1, m2, m3, m4} {
+		if exp := time.Unix(1, int64(i)).UnixNano(); m.UnixNano() != exp {
+			t.Errorf("%s timestamp mismatch: got %v, exp %v", m.ID(), m.UnixNano(), exp)
+		}
+	}
+
+	// A message with the same ID is a revision.
+	edit := newMessage("m2", "b2")
+	edit.SetEditedAt(time.Unix(2, 0))
+	if err := sh.WriteMessages([]Message{edit}); err != nil {
+		t.Fatal(err)
+	} else if edit.UnixNano() != m2.UnixNano() {
+		t.Errorf("edit timestamp mismatch: got %v, exp %v", edit.UnixNano(), m2.UnixNano())
+	}
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := sh.DB().Begin(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var got []string
+	cur := createCursorForConversation(tx, sh, "general")
+	attachHistory(tx, cur, "general")
+	for k, v := cur.Seek(u64tob(0)); k != nil; k, v = cur.Next() {
+		got = append(got, fmt.Sprintf("%d:%s", btou64(k)-uint64(time.Second), sh.messageText("general", v)))
+	}
+	if exp := []string{"0:a", "1:b", "1:b2", "2:c", "3:d"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("messages mismatch:\n got %v\n exp %v", got, exp)
+	}
+}
+
+// Ensure messages without an ID written at the same timestamp are all kept.
+func TestShard_WriteMessages_SameTimestampWithoutID(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	for _, buf := range []string{"general text=\"a\" 1000\ngeneral text=\"b\" 1000", "general text=\"c\" 1000"} {
+		messages, err := ParseMessagesString(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sh.WriteMessages(messages); err != nil {
+			t.Fatal(err)
+		}
+		if err := sh.Flush(0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tx, err := sh.DB().Begin(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var got []string
+	cur := createCursorForConversation(tx, sh, "general")
+	attachHistory(tx, cur, "general")
+	for k, v := cur.Seek(u64tob(0)); k != nil; k, v = cur.Next() {
+		got = append(got, fmt.Sprintf("%d:%s", btou64(k), sh.messageText("general", v)))
+	}
+	if exp := []string{"1000:a", "1001:b", "1002:c"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("messages mismatch:\n got %v\n exp %v", got, exp)
+	}
+}
+
+// Ensure the shard lists the messages mentioning a user, newest first, before and after a flush.
+func TestShard_Mentions(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	jane := []Mention{{RecipientID: "2", RecipientUsername: "jane"}}
+	question := NewMessage("random", Sender{UserID: "1"}, Content{PlainText: "@jane?"}, jane, time.Unix(3, 0))
+	question.SetID("m3")
+	messages := []Message{
+		NewMessage("general", Sender{UserID: "1"}, Content{PlainText: "hi @jane"}, jane, time.Unix(1, 0)),
+		NewMessage("random", Sender{UserID: "1"}, Content{PlainText: "hello"}, nil, time.Unix(2, 0)),
+		question,
+	}
+	if err := sh.WriteMessages(messages); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(before int64, limit int, exp ...string) {
+		a, err := sh.Mentions("2", before, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range a {
+			got = append(got, fmt.Sprintf("%s@%d:%v", m.Conversation, m.Timestamp/int64(time.Second), m.Values["text"]))
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Fatalf("mentions mismatch:\n got %v\n exp %v", got, exp)
+		}
+	}
+
+	// Unflushed messages are listed from the WAL cache.
+	check(int64(time.Hour), 0, "random@3:@jane?", "general@1:hi @jane")
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	check(int64(time.Hour), 0, "random@3:@jane?", "general@1:hi @jane")
+	check(int64(time.Hour), 1, "random@3:@jane?")
+	check(int64(3*time.Second), 0, "general@1:hi @jane")
+
+	// Editing out the mention removes the message from the index.
+	m := NewMessage("random", Sender{UserID: "1"}, Content{PlainText: "nevermind"}, nil, time.Unix(3, 0))
+	m.SetID("m3")
+	m.SetEditedAt(time.Unix(4, 0))
+	if err := sh.WriteMessages([]Message{m}); err != nil {
+		t.Fatal(err)
+	}
+	check(int64(time.Hour), 0, "general@1:hi @jane")
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	check(int64(time.Hour), 0, "general@1:hi @jane")
+
+	// Deleting the conversation removes its messages from the index.
+	if err := sh.deleteConversation("general"); err != nil {
+		t.Fatal(err)
+	}
+	check(int64(time.Hour), 0)
+	if err := sh.DB().View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket([]byte("mentions")).Bucket([]byte("2")).Cursor().First(); k != nil {
+			t.Fatalf("unexpected mention index entry: %q", k)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure the replies to a message are counted from the WAL cache and the thread index.
+func TestShard_Replies(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	reply := func(parentID, text string, sec int64) Message {
+		m := NewMessage("general", Sender{UserID: "1"}, Content{PlainText: text}, nil, time.Unix(sec, 0))
+		m.SetID(fmt.Sprintf("r%d", sec))
+		m.SetParentID(parentID)
+		return m
+	}
+	if err := sh.WriteMessages([]Message{
+		NewMessage("general", Sender{UserID: "1"}, Content{PlainText: "question"}, nil, time.Unix(1, 0)),
+		reply("m1", "answer", 2),
+		reply("m1", "thanks", 3),
+		reply("m10", "other", 4),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(exp map[string]int) {
+		counts, err := sh.Replies("general", []string{"m1", "m10", "m2"})
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(counts, exp) {
+			t.Fatalf("replies mismatch:\n got %v\n exp %v", counts, exp)
+		}
+	}
+
+	// Unflushed replies are counted from the WAL cache.
+	check(map[string]int{"m1": 2, "m10": 1})
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]int{"m1": 2, "m10": 1})
+
+	// Deleted replies are no longer counted.
+	m := reply("m1", "", 3)
+	m.SetDeleted(true)
+	if err := sh.WriteMessages([]Message{m}); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]int{"m1": 1, "m10": 1})
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]int{"m1": 1, "m10": 1})
+}
+
+// Ensure messages are looked up by ID from the WAL cache, the ID index and shards written without it.
+func TestShard_MessageByID(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	tmpShard := filepath.Join(path, "shard")
+	sh := NewShard(NewDatabaseIndex(), tmpShard)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	message := func(id, text string, sec int64) Message {
+		m := NewMessage("general", Sender{UserID: "1"}, Content{PlainText: text}, nil, time.Unix(sec, 0))
+		m.SetID(id)
+		return m
+	}
+	if err := sh.WriteMessages([]Message{message("m1", "a", 1), message("m2", "b", 2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(id, exp string) {
+		m, err := sh.MessageByID("general", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if m != nil {
+			got = fmt.Sprintf("%s@%d:%s", m.ID(), m.Time().Unix(), m.Content().PlainText)
+		}
+		if got != exp {
+			t.Fatalf("MessageByID(%q) mismatch: got %q, exp %q", id, got, exp)
+		}
+	}
+
+	// Unflushed messages are found in the WAL cache.
+	check("m1", "m1@1:a")
+	check("m3", "")
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	check("m1", "m1@1:a")
+	check("m2", "m2@2:b")
+
+	// The latest revision is returned.
+	if err := sh.WriteMessages([]Message{message("m1", "a2", 1)}); err != nil {
+		t.Fatal(err)
+	}
+	check("m1", "m1@1:a2")
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	check("m1", "m1@1:a2")
+
+	// The index is built when reopening a shard written without it.
+	if err := sh.DB().Update(func(tx *bolt.Tx) error { return tx.DeleteBucket([]byte("ids")) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sh = NewShard(NewDatabaseIndex(), tmpShard)
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+	check("m2", "m2@2:b")
+}
+
+// Ensure the links of the messages are indexed and paged through newest first.
+func TestShard_Links(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	message := func(id string, content Content, sec int64) Message {
+		m := NewMessage("general", Sender{UserID: "1", Name: "susy"}, content, nil, time.Unix(sec, 0))
+		m.SetID(id)
+		return m
+	}
+	if err := sh.WriteMessages([]Message{
+		message("m1", Content{PlainText: "see https://example.com/a."}, 1),
+		message("m2", Content{PlainText: "no links"}, 2),
+		message("m3", Content{PlainText: "docs", HTML: `<a href="https://example.com/b?x=1&amp;y=2">docs</a> and http://example.com/c`}, 3),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(before int64, limit int, exp []string) Links {
+		links, err := sh.Links("general", before, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var urls []string
+		for _, l := range links {
+			urls = append(urls, l.URL)
+		}
+		if !reflect.DeepEqual(urls, exp) {
+			t.Fatalf("links mismatch:\n got %v\n exp %v", urls, exp)
+		}
+		return links
+	}
+
+	// Unflushed links are listed from the WAL cache.
+	all := []string{"https://example.com/b?x=1&y=2", "http://example.com/c", "https://example.com/a"}
+	check(time.Unix(10, 0).UnixNano(), 0, all)
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	links := check(time.Unix(10, 0).UnixNano(), 0, all)
+	if l := links[2]; l.MessageID != "m1" || l.SenderID != "1" || l.SenderName != "susy" || l.Timestamp != time.Unix(1, 0).UnixNano() {
+		t.Fatalf("unexpected link: %#v", l)
+	}
+
+	// Pages end at the boundary of a message.
+	check(time.Unix(10, 0).UnixNano(), 1, all[:2])
+	check(time.Unix(3, 0).UnixNano(), 1, all[2:])
+
+	// Deleted messages no longer have links.
+	m := message("m3", Content{}, 3)
+	m.SetDeleted(true)
+	if err := sh.WriteMessages([]Message{m}); err != nil {
+		t.Fatal(err)
+	}
+	check(time.Unix(10, 0).UnixNano(), 0, all[2:])
+	if err := sh.Flush(0); err != nil {
+		t.Fatal(err)
+	}
+	check(time.Unix(10, 0).UnixNano(), 0, all[2:])
+}
+
+// Ensure reactions are stored next to the messages and aggregated by emoji.
+func TestShard_Reactions(t *testing.T) {
+	path, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(path)
+
+	sh := NewShard(NewDatabaseIndex(), filepath.Join(path, "shard"))
+	if err := sh.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer sh.Close()
+
+	m := NewMessage("general", Sender{UserID: "1"}, Content{PlainText: "ship it"}, nil, time.Unix(1, 0))
+	m.SetID("m1")
+	if err := sh.WriteMessages([]Message{m}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages can be reacted to before they are flushed.
+	reaction := func(emoji, userID string, sec int64) Reaction {
+		return Reaction{Conversation: "general", Timestamp: m.UnixNano(), MessageID: "m1", Emoji: emoji, UserID: userID, Time: time.Unix(sec, 0)}
+	}
+	if err := sh.WriteReactions([]Reaction{reaction("+1", "2", 3), reaction("tada", "1", 2), reaction("+1", "1", 4)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.WriteReactions([]Reaction{reaction("+1", "2", 5)}); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(exp string) {
+		a, err := sh.Reactions("general", []string{"m1", "m2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range a["m1"] {
+			got = append(got, fmt.Sprintf("%s:%d:%v", r.Emoji, r.Count, r.UserIDs))
+		}
+		if s := strings.Join(got, " "); s != exp {
+			t.Fatalf("reactions mismatch:\n got %s\n exp %s", s, exp)
+		} else if _, ok := a["m2"]; ok {
+			t.Fatal("unexpected reactions to m2")
+		}
+	}
+	check("tada:1:[1] +1:2:[1 2]")
+
+	removal := reaction("tada", "1", 6)
+	removal.Removed = true
+	if err := sh.WriteReactions([]Reaction{removal}); err != nil {
+		t.Fatal(err)
+	}
+	check("+1:2:[1 2]")
+}
+
+// Ensure emojis are validated.
+func TestValidateEmoji(t *testing.T) {
+	for _, s := range []string{"+1", "👍", "thumbs_up"} {
+		if err := ValidateEmoji(s); err != nil {
+			t.Errorf("%q: unexpected error: %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "a b", strings.Repeat("x", MaxEmojiLength+1)} {
+		if err := ValidateEmoji(s); err != ErrInvalidEmoji {
+			t.Errorf("%q: unexpected error: %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// MaxSnippetSize is the maximum size in bytes of the body of a snippet, the
+	// largest string field value that can be stored.
+	MaxSnippetSize = maxStringLength
+
+	// MaxSnippetLanguageLength is the maximum length of the language of a snippet.
+	MaxSnippetLanguageLength = 32
+
+	// MaxSnippetFilenameLength is the maximum length of the filename of a snippet.
+	MaxSnippetFilenameLength = 255
+)
+
+var (
+	// ErrSnippetBodyRequired is returned when a snippet has no body.
+	ErrSnippetBodyRequired = errors.New("snippet body required")
+
+	// ErrSnippetTooLarge is returned when the body of a snippet exceeds MaxSnippetSize.
+	ErrSnippetTooLarge = errors.New("snippet too large")
+
+	// ErrInvalidSnippetLanguage is returned when the language of a snippet is not a valid name.
+	ErrInvalidSnippetLanguage = errors.New("invalid snippet language")
+
+	// ErrInvalidSnippetFilename is returned when the filename of a snippet contains a path.
+	ErrInvalidSnippetFilename = errors.New("invalid snippet filename")
+)
+
+// Snippet is a piece of code shared in a message. The language and the filename are optional.
+type Snippet struct {
+	Language string
+	Filename string
+	Body     string
+}
+
+// NormalizeSnippetLanguage returns the canonical name of a snippet language, as stored and
+// searched for. Language names are case insensitive.
+func NormalizeSnippetLanguage(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Validate normalizes the language of the snippet and returns an error if the snippet cannot be
+// stored.
+func (s *Snippet) Validate() error {
+	if s.Body == "" {
+		return ErrSnippetBodyRequired
+	} else if len(s.Body) > MaxSnippetSize {
+		return ErrSnippetTooLarge
+	}
+
+	s.Language = NormalizeSnippetLanguage(s.Language)
+	if len(s.Language) > MaxSnippetLanguageLength {
+		return ErrInvalidSnippetLanguage
+	}
+	for _, c := range s.Language {
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && !strings.ContainsRune("+#._-", c) {
+			return ErrInvalidSnippetLanguage
+		}
+	}
+
+	s.Filename = strings.TrimSpace(s.Filename)
+	if len(s.Filename) > MaxSnippetFilenameLength || strings.ContainsAny(s.Filename, "/\\\x00") ||
+		s.Filename == "." || s.Filename == ".." {
+		return ErrInvalidSnippetFilename
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"bytes"
+
+	"github.com/boltdb/bolt"
+)
+
+// threadIndex is the index of the replies of a conversation in a shard. Entries are keyed by
+// the ID of the parent message followed by the reply timestamp, so the replies of a thread
+// are contiguous and iterate in time order.
+type threadIndex struct {
+	bucket *bolt.Bucket
+}
+
+// createThreadIndexIfNotExists returns the thread index of a conversation.
+func createThreadIndexIfNotExists(tx *bolt.Tx, key []byte) (*threadIndex, error) {
+	b, err := tx.Bucket([]byte("threads")).CreateBucketIfNotExists(key)
+	if err != nil {
+		return nil, err
+	}
+	return &threadIndex{bucket: b}, nil
+}
+
+// indexReply adds the reply at timestamp to the thread of the parent message.
+func (idx *threadIndex) indexReply(parentID string, timestamp int64) error {
+	if parentID == "" {
+		return nil
+	}
+	return idx.bucket.Put(threadKey(parentID, timestamp), nil)
+}
+
+// unindexReply removes the reply at timestamp from the thread of the parent message.
+func (idx *threadIndex) unindexReply(parentID string, timestamp int64) error {
+	if parentID == "" {
+		return nil
+	}
+	return idx.bucket.Delete(threadKey(parentID, timestamp))
+}
+
+// replies returns the timestamps of the indexed replies to the parent message.
+func (idx *threadIndex) replies(parentID string) map[int64]struct{} {
+	set := make(map[int64]struct{})
+	prefix := threadPrefix(parentID)
+	c := idx.bucket.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		set[int64(btou64(k[len(prefix):]))] = struct{}{}
+	}
+	return set
+}
+
+// threadPrefix returns the prefix of the index entries of the replies to a parent message.
+// IDs are terminated by a zero byte so that no ID is the prefix of another.
+func threadPrefix(parentID string) []byte {
+	k := make([]byte, len(parentID)+1)
+	copy(k, parentID)
+	return k
+}
+
+// threadKey returns the key of the index entry of the reply at timestamp to a parent message.
+func threadKey(parentID string, timestamp int64) []byte {
+	return append(threadPrefix(parentID), u64tob(uint64(timestamp))...)
+}
+
+// decodeParentID returns the ID of the message an encoded message replies to.
+// Deleted messages no longer count as replies.
+func decodeParentID(codec *FieldCodec, data []byte) string {
+	if codec == nil {
+		return ""
+	}
+	if v, _ := codec.DecodeByName(fieldDeleted, data); v == true {
+		return ""
+	}
+	v, _ := codec.DecodeByName(fieldParentID, data)
+	s, _ := v.(string)
+	return s
+}
+
+// messageParentID returns the ID of the message an encoded message of a conversation replies to.
+// This function must be called within the context of a lock.
+func (s *Shard) messageParentID(key string, data []byte) string {
+	c := s.conversationFields[key]
+	if c == nil {
+		return ""
+	}
+	return decodeParentID(c.codec, data)
+}
+
+// Replies returns the number of replies to each of the parent messages of a conversation.
+// Messages without replies are omitted.
+func (s *Shard) Replies(key string, parentIDs []string) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Messages in the WAL cache are not indexed yet and replace the flushed revisions.
+	pending := make(map[int64][]byte)
+	for _, entry := range s.cache[WALPartition([]byte(key))][key] {
+		timestamp, data := unmarshalCacheEntry(entry)
+		pending[timestamp] = data
+	}
+
+	counts := make(map[string]int)
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		var idx *threadIndex
+		if b := tx.Bucket([]byte("threads")).Bucket([]byte(key)); b != nil {
+			idx = &threadIndex{bucket: b}
+		}
+
+		for _, parentID := range parentIDs {
+			replies := make(map[int64]struct{})
+			if idx != nil {
+				replies = idx.replies(parentID)
+			}
+			for timestamp, data := range pending {
+				if s.messageParentID(key, data) == parentID {
+					replies[timestamp] = struct{}{}
+				} else {
+					delete(replies, timestamp)
+				}
+			}
+			if len(replies) > 0 {
+				counts[parentID] = len(replies)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// Replies returns the number of replies to each of the parent messages of a conversation
+// across all the local shards of a database. Messages without replies are omitted.
+func (s *Store) Replies(database, key string, parentIDs []string) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index := s.databaseIndexes[database]
+	if index == nil {
+		return nil, nil
+	}
+
+	counts := make(map[string]int)
+	for _, sh := range s.shards {
+		if sh.index != index {
+			continue
+		}
+		a, err := sh.Replies(key, parentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for parentID, n := range a {
+			counts[parentID] += n
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,178 @@
+package meta
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// AccountInfo represents the account of a user of the REST API. The username is also the path
+// of the namespace of the account.
+type AccountInfo struct {
+	ID           string
+	Username     string
+	NamespaceID  string
+	GivenName    string
+	FamilyName   string
+	Hash         string // bcrypt hash of the password
+	PrimaryEmail string
+	Emails       []EmailInfo
+	Identities   []IdentityInfo // identities of external providers the user logs in with
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// HasEmail returns true if the email address is registered to the account. Email addresses are
+// case insensitive.
+func (ai *AccountInfo) HasEmail(email string) bool {
+	for i := range ai.Emails {
+		if strings.EqualFold(ai.Emails[i].Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConfirmedEmail returns true if the email address is registered to the account and its owner
+// confirmed receiving mail at it.
+func (ai *AccountInfo) HasConfirmedEmail(email string) bool {
+	for i := range ai.Emails {
+		if strings.EqualFold(ai.Emails[i].Email, email) {
+			return ai.Emails[i].Confirmed
+		}
+	}
+	return false
+}
+
+// HasIdentity returns true if the identity of the issuer is linked to the account.
+func (ai *AccountInfo) HasIdentity(issuer, subject string) bool {
+	for i := range ai.Identities {
+		if ai.Identities[i].Issuer == issuer && ai.Identities[i].Subject == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// clone returns a deep copy of ai.
+func (ai AccountInfo) clone() AccountInfo {
+	other := ai
+
+	if ai.Emails != nil {
+		other.Emails = make([]EmailInfo, len(ai.Emails))
+		copy(other.Emails, ai.Emails)
+	}
+
+	if ai.Identities != nil {
+		other.Identities = make([]IdentityInfo, len(ai.Identities))
+		copy(other.Identities, ai.Identities)
+	}
+
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (ai AccountInfo) marshal() *internal.AccountInfo {
+	pb := &internal.AccountInfo{
+		ID:           proto.String(ai.ID),
+		Username:     proto.String(ai.Username),
+		NamespaceID:  proto.String(ai.NamespaceID),
+		GivenName:    proto.String(ai.GivenName),
+		FamilyName:   proto.String(ai.FamilyName),
+		Hash:         proto.String(ai.Hash),
+		PrimaryEmail: proto.String(ai.PrimaryEmail),
+		CreatedAt:    proto.Int64(MarshalTime(ai.CreatedAt)),
+		UpdatedAt:    proto.Int64(MarshalTime(ai.UpdatedAt)),
+	}
+
+	for _, ei := range ai.Emails {
+		pb.Emails = append(pb.Emails, ei.marshal())
+	}
+
+	for _, ii := range ai.Identities {
+		pb.Identities = append(pb.Identities, ii.marshal())
+	}
+
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ai *AccountInfo) unmarshal(pb *internal.AccountInfo) {
+	ai.ID = pb.GetID()
+	ai.Username = pb.GetUsername()
+	ai.NamespaceID = pb.GetNamespaceID()
+	ai.GivenName = pb.GetGivenName()
+	ai.FamilyName = pb.GetFamilyName()
+	ai.Hash = pb.GetHash()
+	ai.PrimaryEmail = pb.GetPrimaryEmail()
+	ai.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+	ai.UpdatedAt = UnmarshalTime(pb.GetUpdatedAt())
+
+	ai.Emails = nil
+	if len(pb.GetEmails()) > 0 {
+		ai.Emails = make([]EmailInfo, len(pb.GetEmails()))
+		for i, x := range pb.GetEmails() {
+			ai.Emails[i].unmarshal(x)
+		}
+	}
+
+	ai.Identities = nil
+	if len(pb.GetIdentities()) > 0 {
+		ai.Identities = make([]IdentityInfo, len(pb.GetIdentities()))
+		for i, x := range pb.GetIdentities() {
+			ai.Identities[i].unmarshal(x)
+		}
+	}
+}
+
+// EmailInfo represents an email address registered to an account.
+type EmailInfo struct {
+	Email       string
+	Confirmed   bool
+	ConfirmedAt time.Time
+}
+
+// marshal serializes to a protobuf representation.
+func (ei EmailInfo) marshal() *internal.EmailInfo {
+	return &internal.EmailInfo{
+		Email:       proto.String(ei.Email),
+		Confirmed:   proto.Bool(ei.Confirmed),
+		ConfirmedAt: proto.Int64(MarshalTime(ei.ConfirmedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ei *EmailInfo) unmarshal(pb *internal.EmailInfo) {
+	ei.Email = pb.GetEmail()
+	ei.Confirmed = pb.GetConfirmed()
+	ei.ConfirmedAt = UnmarshalTime(pb.GetConfirmedAt())
+}
+
+// IdentityInfo represents the identity of a user at an external identity provider, such as an
+// OpenID Connect issuer. The subject uniquely identifies the user at the issuer.
+type IdentityInfo struct {
+	Issuer    string
+	Subject   string
+	Email     string // email address the provider asserted when the identity was linked
+	CreatedAt time.Time
+}
+
+// marshal serializes to a protobuf representation.
+func (ii IdentityInfo) marshal() *internal.IdentityInfo {
+	return &internal.IdentityInfo{
+		Issuer:    proto.String(ii.Issuer),
+		Subject:   proto.String(ii.Subject),
+		Email:     proto.String(ii.Email),
+		CreatedAt: proto.Int64(MarshalTime(ii.CreatedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ii *IdentityInfo) unmarshal(pb *internal.IdentityInfo) {
+	ii.Issuer = pb.GetIssuer()
+	ii.Subject = pb.GetSubject()
+	ii.Email = pb.GetEmail()
+	ii.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+}
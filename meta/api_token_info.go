@@ -0,0 +1,81 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// API token scopes.
+const (
+	ApiTokenScopeReadMessages  = "messages:read"
+	ApiTokenScopeWriteMessages = "messages:write"
+	ApiTokenScopeAdmin         = "admin"
+)
+
+// ApiTokenInfo represents a long-lived token a user or an integration of an organization consumes
+// the API with, instead of a password. Integration tokens belong to an organization and are
+// restricted to its conversations.
+type ApiTokenInfo struct {
+	ID             string
+	Name           string
+	TokenHash      string // hash of the token, the token itself is never stored
+	UserID         string // user the requests made with the token are made as
+	OrganizationID string // organization of an integration token, empty for personal tokens
+	Scopes         []string
+	RateLimit      int64 // maximum number of requests per minute, zero for the default limit
+	ExpiresAt      time.Time
+	LastUsedAt     time.Time
+	CreatedAt      time.Time
+}
+
+// HasScope returns true if the token was granted the scope. The admin scope grants all scopes.
+func (ti *ApiTokenInfo) HasScope(scope string) bool {
+	return contains(ti.Scopes, scope) || contains(ti.Scopes, ApiTokenScopeAdmin)
+}
+
+// Expired returns true if the token can no longer be used at t.
+func (ti *ApiTokenInfo) Expired(t time.Time) bool {
+	return !ti.ExpiresAt.IsZero() && !t.Before(ti.ExpiresAt)
+}
+
+// clone returns a deep copy of ti.
+func (ti ApiTokenInfo) clone() ApiTokenInfo {
+	other := ti
+	if ti.Scopes != nil {
+		other.Scopes = make([]string, len(ti.Scopes))
+		copy(other.Scopes, ti.Scopes)
+	}
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (ti ApiTokenInfo) marshal() *internal.ApiTokenInfo {
+	return &internal.ApiTokenInfo{
+		ID:             proto.String(ti.ID),
+		Name:           proto.String(ti.Name),
+		TokenHash:      proto.String(ti.TokenHash),
+		UserID:         proto.String(ti.UserID),
+		OrganizationID: proto.String(ti.OrganizationID),
+		Scopes:         ti.Scopes,
+		RateLimit:      proto.Int64(ti.RateLimit),
+		ExpiresAt:      proto.Int64(MarshalTime(ti.ExpiresAt)),
+		LastUsedAt:     proto.Int64(MarshalTime(ti.LastUsedAt)),
+		CreatedAt:      proto.Int64(MarshalTime(ti.CreatedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ti *ApiTokenInfo) unmarshal(pb *internal.ApiTokenInfo) {
+	ti.ID = pb.GetID()
+	ti.Name = pb.GetName()
+	ti.TokenHash = pb.GetTokenHash()
+	ti.UserID = pb.GetUserID()
+	ti.OrganizationID = pb.GetOrganizationID()
+	ti.Scopes = pb.GetScopes()
+	ti.RateLimit = pb.GetRateLimit()
+	ti.ExpiresAt = UnmarshalTime(pb.GetExpiresAt())
+	ti.LastUsedAt = UnmarshalTime(pb.GetLastUsedAt())
+	ti.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+}
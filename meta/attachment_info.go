@@ -0,0 +1,59 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// AttachmentInfo represents a file uploaded to a conversation. The content is stored in the
+// blob store under its SHA256 digest, and is shared by attachments with the same content.
+type AttachmentInfo struct {
+	ID             string
+	ConversationID string
+	MessageID      string // message the attachment is linked to, empty until sent
+	UploaderID     string
+	Filename       string
+	ContentType    string
+	Size           int64
+	MD5            string
+	SHA256         string
+	CreatedAt      time.Time
+}
+
+// clone returns a deep copy of ai.
+func (ai AttachmentInfo) clone() AttachmentInfo { return ai }
+
+// marshal serializes to a protobuf representation.
+func (ai AttachmentInfo) marshal() *internal.AttachmentInfo {
+	pb := &internal.AttachmentInfo{
+		ID:             proto.String(ai.ID),
+		ConversationID: proto.String(ai.ConversationID),
+		UploaderID:     proto.String(ai.UploaderID),
+		Filename:       proto.String(ai.Filename),
+		ContentType:    proto.String(ai.ContentType),
+		Size:           proto.Int64(ai.Size),
+		MD5:            proto.String(ai.MD5),
+		SHA256:         proto.String(ai.SHA256),
+		CreatedAt:      proto.Int64(MarshalTime(ai.CreatedAt)),
+	}
+	if ai.MessageID != "" {
+		pb.MessageID = proto.String(ai.MessageID)
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ai *AttachmentInfo) unmarshal(pb *internal.AttachmentInfo) {
+	ai.ID = pb.GetID()
+	ai.ConversationID = pb.GetConversationID()
+	ai.MessageID = pb.GetMessageID()
+	ai.UploaderID = pb.GetUploaderID()
+	ai.Filename = pb.GetFilename()
+	ai.ContentType = pb.GetContentType()
+	ai.Size = pb.GetSize()
+	ai.MD5 = pb.GetMD5()
+	ai.SHA256 = pb.GetSHA256()
+	ai.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+}
@@ -4,4 +4,12 @@ package bindings
 type CreateConversation struct {
 	Title   string `json:"title" binding:"required"`
 	Purpose string `json:"purpose" binding:"required"`
+	Type    string `json:"type"`
+	Privacy string `json:"privacy"`
+}
+
+// MarkRead is the API payload representation when marking a Conversation read up to a message. The latest message is
+// used when no message is given.
+type MarkRead struct {
+	MessageID string `json:"message_id"`
 }
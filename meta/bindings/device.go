@@ -0,0 +1,8 @@
+package bindings
+
+// AddUpdateDevice is the API payload representation when registering or modifying a client device
+type AddUpdateDevice struct {
+	Name     string `json:"name" binding:"required"`
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+}
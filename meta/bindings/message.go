@@ -0,0 +1,32 @@
+package bindings
+
+// CreateMessage is the API payload representation when sending a new Message to a Conversation
+type CreateMessage struct {
+	Text        string                 `json:"text"`
+	HTML        string                 `json:"html"`
+	Mentions    []Mention              `json:"mentions"`
+	Fields      map[string]interface{} `json:"fields"`
+	ParentID    string                 `json:"parent_id"`
+	Attachments []string               `json:"attachments"`
+	Snippet     *Snippet               `json:"snippet"`
+}
+
+// UpdateMessage is the API payload representation when editing the content of a Message
+type UpdateMessage struct {
+	Text     string    `json:"text" binding:"required"`
+	HTML     string    `json:"html"`
+	Mentions []Mention `json:"mentions"`
+}
+
+// Snippet is the API payload representation of the code snippet of a Message
+type Snippet struct {
+	Language string `json:"language"`
+	Filename string `json:"filename"`
+	Body     string `json:"body" binding:"required"`
+}
+
+// Mention is the API payload representation of a user mentioned in a Message
+type Mention struct {
+	UserID   string `json:"user_id" binding:"required"`
+	Username string `json:"username"`
+}
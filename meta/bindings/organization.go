@@ -8,6 +8,7 @@ type CreateOrganization struct {
 
 // UpdateOrganization is the API payload representation when updating an Organizations
 type UpdateOrganization struct {
+	Path         string `json:"path"`
 	Name         string `json:"name" binding:"required"`
 	BillingEmail string `json:"billing_email" binding:"required"`
 	Email        string `json:"email"`
@@ -25,3 +26,9 @@ type AddUpdateMembership struct {
 type EditMyMembership struct {
 	State string `json:"state" binding:"required"`
 }
+
+// InviteMember is the API payload representation when inviting an email address to join an organization
+type InviteMember struct {
+	Email string `json:"email" binding:"required"`
+	Role  string `json:"role"`
+}
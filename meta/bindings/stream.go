@@ -0,0 +1,8 @@
+package bindings
+
+// StreamCommand is the payload representation of a command sent by a client over the real-time stream. The type
+// is one of subscribe, unsubscribe, typing or pulse.
+type StreamCommand struct {
+	Type           string `json:"type"`
+	ConversationID string `json:"conversation_id"`
+}
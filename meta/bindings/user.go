@@ -15,6 +15,10 @@ type RefreshToken struct {
 	Token string `json:"refresh_token" binding:"required"`
 }
 
+type Logout struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type UpdateUser struct {
 	GivenName  string `json:"given_name,omitempty" binding:"required"`
 	FamilyName string `json:"family_name,omitempty" binding:"required"`
@@ -32,3 +36,28 @@ type ChangePassword struct {
 type UpdateEmail struct {
 	Email string `json:"email" binding:"required"`
 }
+
+// ConfirmEmail is the API payload representation when confirming an email address with the token sent to it
+type ConfirmEmail struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RequestPasswordReset is the API payload representation when requesting a link to reset a forgotten password
+type RequestPasswordReset struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPassword is the API payload representation when resetting a password with the token sent by email
+type ResetPassword struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// CreateApiToken is the API payload representation when creating a personal or an integration API token. ExpiresIn
+// is the number of seconds the token is valid for, the token does not expire when omitted
+type CreateApiToken struct {
+	Name      string   `json:"name" binding:"required"`
+	Scopes    []string `json:"scopes" binding:"required"`
+	ExpiresIn int64    `json:"expires_in"`
+	RateLimit int64    `json:"rate_limit"`
+}
@@ -0,0 +1,77 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// ConversationInfo represents a conversation within a namespace. The type, privacy and retention
+// mode hold the names of the schema enums.
+type ConversationInfo struct {
+	ID             string
+	NamespaceID    string
+	CreatorID      string
+	Title          string
+	Purpose        string
+	Topic          string
+	Type           string
+	Privacy        string
+	RetentionMode  string
+	RetentionValue int64
+	Archived       bool
+	ArchivedAt     time.Time
+	LastActiveAt   time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	// ParticipantsCount is maintained by the commands adding and removing participants.
+	ParticipantsCount int
+}
+
+// clone returns a deep copy of ci.
+func (ci ConversationInfo) clone() ConversationInfo { return ci }
+
+// marshal serializes to a protobuf representation.
+func (ci ConversationInfo) marshal() *internal.ConversationInfo {
+	return &internal.ConversationInfo{
+		ID:             proto.String(ci.ID),
+		NamespaceID:    proto.String(ci.NamespaceID),
+		CreatorID:      proto.String(ci.CreatorID),
+		Title:          proto.String(ci.Title),
+		Purpose:        proto.String(ci.Purpose),
+		Topic:          proto.String(ci.Topic),
+		Type:           proto.String(ci.Type),
+		Privacy:        proto.String(ci.Privacy),
+		RetentionMode:  proto.String(ci.RetentionMode),
+		RetentionValue: proto.Int64(ci.RetentionValue),
+		Archived:       proto.Bool(ci.Archived),
+		ArchivedAt:     proto.Int64(MarshalTime(ci.ArchivedAt)),
+		LastActiveAt:   proto.Int64(MarshalTime(ci.LastActiveAt)),
+		CreatedAt:      proto.Int64(MarshalTime(ci.CreatedAt)),
+		UpdatedAt:      proto.Int64(MarshalTime(ci.UpdatedAt)),
+
+		ParticipantsCount: proto.Int64(int64(ci.ParticipantsCount)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ci *ConversationInfo) unmarshal(pb *internal.ConversationInfo) {
+	ci.ID = pb.GetID()
+	ci.NamespaceID = pb.GetNamespaceID()
+	ci.CreatorID = pb.GetCreatorID()
+	ci.Title = pb.GetTitle()
+	ci.Purpose = pb.GetPurpose()
+	ci.Topic = pb.GetTopic()
+	ci.Type = pb.GetType()
+	ci.Privacy = pb.GetPrivacy()
+	ci.RetentionMode = pb.GetRetentionMode()
+	ci.RetentionValue = pb.GetRetentionValue()
+	ci.Archived = pb.GetArchived()
+	ci.ArchivedAt = UnmarshalTime(pb.GetArchivedAt())
+	ci.LastActiveAt = UnmarshalTime(pb.GetLastActiveAt())
+	ci.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+	ci.UpdatedAt = UnmarshalTime(pb.GetUpdatedAt())
+	ci.ParticipantsCount = int(pb.GetParticipantsCount())
+}
@@ -2,6 +2,7 @@ package meta
 
 import (
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -20,6 +21,9 @@ const (
 
 	// MinRetentionPolicyDuration represents the minimum duration for a policy.
 	MinRetentionPolicyDuration = time.Hour
+
+	// MaxNamespacePathLength is the maximum length of the path of a namespace.
+	MaxNamespacePathLength = 64
 )
 
 // Data represents the top level collection of all metadata.
@@ -34,6 +38,24 @@ type Data struct {
 	MaxNodeID       uint64
 	MaxShardGroupID uint64
 	MaxShardID      uint64
+
+	ReadMarkers  []ReadMarkerInfo
+	Attachments  []AttachmentInfo
+	Participants []ParticipantInfo
+
+	Namespaces    []NamespaceInfo
+	Accounts      []AccountInfo
+	Organizations []OrganizationInfo
+	Members       []MemberInfo
+	Conversations []ConversationInfo
+	Devices       []DeviceInfo
+	Invitations   []InvitationInfo
+	Teams         []TeamInfo
+
+	RevokedTokens []RevokedTokenInfo
+	ApiTokens     []ApiTokenInfo
+
+	index dataIndex
 }
 
 // Node returns a node by id.
@@ -479,91 +501,1603 @@ func (data *Data) UserPrivilege(name, database string) (*sql.Privilege, error) {
 	return sql.NewPrivilege(sql.NoPrivileges), nil
 }
 
-// Clone returns a copy of data with a new version.
-func (data *Data) Clone() *Data {
-	other := *data
+// ReadMarker returns the read marker of a user in a conversation.
+func (data *Data) ReadMarker(conversationID, userID string) *ReadMarkerInfo {
+	for i := range data.ReadMarkers {
+		if data.ReadMarkers[i].ConversationID == conversationID && data.ReadMarkers[i].UserID == userID {
+			return &data.ReadMarkers[i]
+		}
+	}
+	return nil
+}
 
-	// Copy nodes.
-	if data.Nodes != nil {
-		other.Nodes = make([]NodeInfo, len(data.Nodes))
-		for i := range data.Nodes {
-			other.Nodes[i] = data.Nodes[i].clone()
+// SetReadMarker moves the read marker of a user in a conversation to a message. Markers only
+// move forward so a message older than the current marker is ignored.
+func (data *Data) SetReadMarker(conversationID, userID, messageID string, t time.Time) error {
+	if conversationID == "" {
+		return ErrConversationIDRequired
+	} else if userID == "" {
+		return ErrUserIDRequired
+	}
+
+	if rmi := data.ReadMarker(conversationID, userID); rmi != nil {
+		if t.After(rmi.Time) {
+			rmi.MessageID, rmi.Time = messageID, t
 		}
+		return nil
 	}
 
-	// Deep copy databases.
-	if data.Databases != nil {
-		other.Databases = make([]DatabaseInfo, len(data.Databases))
-		for i := range data.Databases {
-			other.Databases[i] = data.Databases[i].clone()
+	data.ReadMarkers = append(data.ReadMarkers, ReadMarkerInfo{
+		ConversationID: conversationID,
+		UserID:         userID,
+		MessageID:      messageID,
+		Time:           t,
+	})
+
+	return nil
+}
+
+// Attachment returns an attachment by ID.
+func (data *Data) Attachment(id string) *AttachmentInfo {
+	for i := range data.Attachments {
+		if data.Attachments[i].ID == id {
+			return &data.Attachments[i]
 		}
 	}
+	return nil
+}
 
-	// Copy users.
-	if data.Users != nil {
-		other.Users = make([]UserInfo, len(data.Users))
-		for i := range data.Users {
-			other.Users[i] = data.Users[i].clone()
+// CreateAttachment adds an attachment to the metadata. The attachment is not linked to a message.
+func (data *Data) CreateAttachment(ai AttachmentInfo) error {
+	if ai.ID == "" {
+		return ErrAttachmentIDRequired
+	} else if ai.ConversationID == "" {
+		return ErrConversationIDRequired
+	} else if ai.UploaderID == "" {
+		return ErrUserIDRequired
+	} else if data.Attachment(ai.ID) != nil {
+		return ErrAttachmentExists
+	}
+
+	ai.MessageID = ""
+	data.Attachments = append(data.Attachments, ai)
+	return nil
+}
+
+// SetAttachmentMessage links an attachment to the message it was sent with. An attachment
+// can only be linked to a single message.
+func (data *Data) SetAttachmentMessage(id, messageID string) error {
+	ai := data.Attachment(id)
+	if ai == nil {
+		return ErrAttachmentNotFound
+	} else if ai.MessageID != "" && ai.MessageID != messageID {
+		return ErrAttachmentLinked
+	}
+	ai.MessageID = messageID
+	return nil
+}
+
+// DeleteAttachment removes an attachment from the metadata.
+func (data *Data) DeleteAttachment(id string) error {
+	for i := range data.Attachments {
+		if data.Attachments[i].ID == id {
+			data.Attachments = append(data.Attachments[:i], data.Attachments[i+1:]...)
+			return nil
 		}
 	}
+	return ErrAttachmentNotFound
+}
 
-	return &other
+// Participant returns the participant of a conversation with the given user ID.
+func (data *Data) Participant(conversationID, userID string) *ParticipantInfo {
+	for i := range data.Participants {
+		if data.Participants[i].ConversationID == conversationID && data.Participants[i].UserID == userID {
+			return &data.Participants[i]
+		}
+	}
+	return nil
 }
 
-// marshal serializes to a protobuf representation.
-func (data *Data) marshal() *internal.Data {
-	pb := &internal.Data{
-		Term:      proto.Uint64(data.Term),
-		Index:     proto.Uint64(data.Index),
-		ClusterID: proto.Uint64(data.ClusterID),
+// AddParticipant adds a user to the participants of a conversation, joining at t.
+func (data *Data) AddParticipant(conversationID, userID string, t time.Time) error {
+	if conversationID == "" {
+		return ErrConversationIDRequired
+	} else if userID == "" {
+		return ErrUserIDRequired
+	} else if data.Participant(conversationID, userID) != nil {
+		return ErrParticipantExists
+	}
 
-		MaxNodeID:       proto.Uint64(data.MaxNodeID),
-		MaxShardGroupID: proto.Uint64(data.MaxShardGroupID),
-		MaxShardID:      proto.Uint64(data.MaxShardID),
+	data.Participants = append(data.Participants, ParticipantInfo{
+		ConversationID: conversationID,
+		UserID:         userID,
+		JoinedAt:       t,
+		LastActivityAt: t,
+	})
+	if ci := data.Conversation(conversationID); ci != nil {
+		ci.ParticipantsCount++
+	}
+	return nil
+}
+
+// RemoveParticipant removes a user from the participants of a conversation.
+func (data *Data) RemoveParticipant(conversationID, userID string) error {
+	for i := range data.Participants {
+		if data.Participants[i].ConversationID == conversationID && data.Participants[i].UserID == userID {
+			data.Participants = append(data.Participants[:i], data.Participants[i+1:]...)
+			if ci := data.Conversation(conversationID); ci != nil && ci.ParticipantsCount > 0 {
+				ci.ParticipantsCount--
+			}
+			return nil
+		}
 	}
+	return ErrParticipantNotFound
+}
 
-	pb.Nodes = make([]*internal.NodeInfo, len(data.Nodes))
-	for i := range data.Nodes {
-		pb.Nodes[i] = data.Nodes[i].marshal()
+// countParticipants returns the number of participants of a conversation.
+func (data *Data) countParticipants(conversationID string) int {
+	var n int
+	for i := range data.Participants {
+		if data.Participants[i].ConversationID == conversationID {
+			n++
+		}
 	}
+	return n
+}
 
-	pb.Databases = make([]*internal.DatabaseInfo, len(data.Databases))
-	for i := range data.Databases {
-		pb.Databases[i] = data.Databases[i].marshal()
+// SetParticipantActivity records the last activity of a participant of a conversation. The
+// activity only moves forward so an older time is ignored.
+func (data *Data) SetParticipantActivity(conversationID, userID string, t time.Time) error {
+	pi := data.Participant(conversationID, userID)
+	if pi == nil {
+		return ErrParticipantNotFound
+	}
+	if t.After(pi.LastActivityAt) {
+		pi.LastActivityAt = t
 	}
+	return nil
+}
 
-	pb.Users = make([]*internal.UserInfo, len(data.Users))
-	for i := range data.Users {
-		pb.Users[i] = data.Users[i].marshal()
+// Namespace returns a namespace by path. Paths are case insensitive.
+func (data *Data) Namespace(path string) *NamespaceInfo {
+	if i, ok := data.index.namespacePaths[strings.ToLower(path)]; ok {
+		return &data.Namespaces[i]
 	}
+	return nil
+}
 
-	return pb
+// ResolveNamespace returns a namespace by path, following the redirects of renamed namespaces.
+// Returns true if the path redirects to the namespace.
+func (data *Data) ResolveNamespace(path string) (*NamespaceInfo, bool) {
+	if ni := data.Namespace(path); ni != nil {
+		return ni, false
+	}
+	if i, ok := data.index.namespaceRedirects[strings.ToLower(path)]; ok {
+		return &data.Namespaces[i], true
+	}
+	return nil, false
 }
 
-// unmarshal deserializes from a protobuf representation.
-func (data *Data) unmarshal(pb *internal.Data) {
-	data.Term = pb.GetTerm()
-	data.Index = pb.GetIndex()
-	data.ClusterID = pb.GetClusterID()
+// NamespaceByID returns a namespace by ID.
+func (data *Data) NamespaceByID(id string) *NamespaceInfo {
+	if i, ok := data.index.namespaces[id]; ok {
+		return &data.Namespaces[i]
+	}
+	return nil
+}
 
-	data.MaxNodeID = pb.GetMaxNodeID()
-	data.MaxShardGroupID = pb.GetMaxShardGroupID()
-	data.MaxShardID = pb.GetMaxShardID()
+// createNamespace adds a namespace owned by a user account or an organization. The path is
+// stored in lowercase.
+func (data *Data) createNamespace(ni NamespaceInfo) error {
+	ni.Path = strings.ToLower(ni.Path)
+	if ni.ID == "" {
+		return ErrNamespaceIDRequired
+	} else if !validNamespacePath(ni.Path) {
+		return ErrInvalidNamespacePath
+	} else if data.NamespaceByID(ni.ID) != nil || data.Namespace(ni.Path) != nil {
+		return ErrNamespaceExists
+	}
 
-	data.Nodes = make([]NodeInfo, len(pb.GetNodes()))
-	for i, x := range pb.GetNodes() {
-		data.Nodes[i].unmarshal(x)
+	data.releaseRedirect(ni.Path)
+	data.Namespaces = append(data.Namespaces, ni)
+	data.reindex()
+	return nil
+}
+
+// RenameNamespace changes the path of a namespace. The previous path redirects to the namespace
+// until another namespace takes it. The username of a user account follows the path of its
+// namespace so both are renamed at once.
+func (data *Data) RenameNamespace(id, path string, t time.Time) error {
+	path = strings.ToLower(path)
+
+	ni := data.NamespaceByID(id)
+	if ni == nil {
+		return ErrNamespaceNotFound
+	} else if !validNamespacePath(path) {
+		return ErrInvalidNamespacePath
+	} else if ni.Path == path {
+		return nil
+	} else if data.Namespace(path) != nil {
+		return ErrNamespaceExists
 	}
 
-	data.Databases = make([]DatabaseInfo, len(pb.GetDatabases()))
-	for i, x := range pb.GetDatabases() {
-		data.Databases[i].unmarshal(x)
+	var ai *AccountInfo
+	if ni.OwnerType == NamespaceOwnerUser {
+		if ai = data.Account(ni.OwnerID); ai == nil {
+			return ErrAccountNotFound
+		}
+		ai.Username = path
+		ai.UpdatedAt = t
 	}
 
-	data.Users = make([]UserInfo, len(pb.GetUsers()))
-	for i, x := range pb.GetUsers() {
-		data.Users[i].unmarshal(x)
+	data.releaseRedirect(path)
+	ni.Redirects = append(ni.Redirects, ni.Path)
+	ni.Path = path
+	ni.UpdatedAt = t
+	data.reindex()
+	return nil
+}
+
+// releaseRedirect removes path from the redirects of renamed namespaces so it can be taken.
+func (data *Data) releaseRedirect(path string) {
+	i, ok := data.index.namespaceRedirects[path]
+	if !ok {
+		return
+	}
+
+	ni := &data.Namespaces[i]
+	redirects := make([]string, 0, len(ni.Redirects))
+	for _, r := range ni.Redirects {
+		if r != path {
+			redirects = append(redirects, r)
+		}
+	}
+	ni.Redirects = redirects
+}
+
+// dropNamespace removes a namespace by ID.
+func (data *Data) dropNamespace(id string) {
+	for i := range data.Namespaces {
+		if data.Namespaces[i].ID == id {
+			data.Namespaces = append(data.Namespaces[:i], data.Namespaces[i+1:]...)
+			data.reindex()
+			return
+		}
+	}
+}
+
+// validNamespacePath returns true if path can be used in the URLs of the API. Paths are made of
+// lowercase letters, digits, dots, dashes and underscores and start with a letter or a digit.
+func validNamespacePath(path string) bool {
+	if path == "" || len(path) > MaxNamespacePathLength {
+		return false
+	}
+	for i, c := range path {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			continue
+		} else if i > 0 && (c == '.' || c == '-' || c == '_') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// Account returns a user account by ID.
+func (data *Data) Account(id string) *AccountInfo {
+	if i, ok := data.index.accounts[id]; ok {
+		return &data.Accounts[i]
+	}
+	return nil
+}
+
+// AccountByUsername returns a user account by username. Usernames are case insensitive.
+func (data *Data) AccountByUsername(username string) *AccountInfo {
+	if i, ok := data.index.usernames[strings.ToLower(username)]; ok {
+		return &data.Accounts[i]
+	}
+	return nil
+}
+
+// AccountByEmail returns the user account an email address is registered to. Email addresses
+// are case insensitive.
+func (data *Data) AccountByEmail(email string) *AccountInfo {
+	if i, ok := data.index.emails[strings.ToLower(email)]; ok {
+		return &data.Accounts[i]
+	}
+	return nil
+}
+
+// CreateAccount adds a user account along with the namespace of its username. The username and
+// the email addresses are stored in lowercase, and the primary email address is registered to
+// the account.
+func (data *Data) CreateAccount(ai AccountInfo) error {
+	if ai.ID == "" {
+		return ErrUserIDRequired
+	} else if ai.Username == "" {
+		return ErrUsernameRequired
+	} else if data.Account(ai.ID) != nil {
+		return ErrAccountExists
+	}
+
+	ai.Username = strings.ToLower(ai.Username)
+	normalizeAccountEmails(&ai)
+	if err := data.checkAccountEmails(&ai); err != nil {
+		return err
+	}
+	for _, ii := range ai.Identities {
+		if err := data.checkIdentity(ai.ID, ii); err != nil {
+			return err
+		}
+	}
+
+	if err := data.createNamespace(NamespaceInfo{
+		ID:        ai.NamespaceID,
+		Path:      ai.Username,
+		OwnerID:   ai.ID,
+		OwnerType: NamespaceOwnerUser,
+		CreatedAt: ai.CreatedAt,
+		UpdatedAt: ai.CreatedAt,
+	}); err != nil {
+		return err
+	}
+
+	data.Accounts = append(data.Accounts, ai)
+	data.reindex()
+	return nil
+}
+
+// UpdateAccount replaces the names, the password hash and the email addresses of a user
+// account. The username, the namespace and the identities of the account are left unchanged.
+func (data *Data) UpdateAccount(ai AccountInfo) error {
+	other := data.Account(ai.ID)
+	if other == nil {
+		return ErrAccountNotFound
+	}
+
+	ai.Username = other.Username
+	ai.NamespaceID = other.NamespaceID
+	ai.CreatedAt = other.CreatedAt
+	ai.Identities = other.Identities
+	normalizeAccountEmails(&ai)
+	if err := data.checkAccountEmails(&ai); err != nil {
+		return err
+	}
+
+	*other = ai
+	data.reindex()
+	return nil
+}
+
+// normalizeAccountEmails lowercases the email addresses of an account, removes the duplicates
+// and registers the primary email address.
+func normalizeAccountEmails(ai *AccountInfo) {
+	ai.PrimaryEmail = strings.ToLower(ai.PrimaryEmail)
+
+	emails := make([]EmailInfo, 0, len(ai.Emails)+1)
+	seen := make(map[string]struct{})
+	for _, ei := range ai.Emails {
+		ei.Email = strings.ToLower(ei.Email)
+		if _, ok := seen[ei.Email]; ok || ei.Email == "" {
+			continue
+		}
+		seen[ei.Email] = struct{}{}
+		emails = append(emails, ei)
+	}
+	if _, ok := seen[ai.PrimaryEmail]; !ok && ai.PrimaryEmail != "" {
+		emails = append(emails, EmailInfo{Email: ai.PrimaryEmail})
+	}
+	ai.Emails = emails
+}
+
+// checkAccountEmails returns an error if an email address of an account is registered to
+// another account.
+func (data *Data) checkAccountEmails(ai *AccountInfo) error {
+	for _, ei := range ai.Emails {
+		if other := data.AccountByEmail(ei.Email); other != nil && other.ID != ai.ID {
+			return ErrEmailExists
+		}
+	}
+	return nil
+}
+
+// AccountByIdentity returns the account an identity of an external provider is linked to.
+func (data *Data) AccountByIdentity(issuer, subject string) *AccountInfo {
+	for i := range data.Accounts {
+		if data.Accounts[i].HasIdentity(issuer, subject) {
+			return &data.Accounts[i]
+		}
+	}
+	return nil
+}
+
+// LinkIdentity links an identity of an external provider to an account, so that the user logs
+// in with the provider. Linking an identity twice to the same account has no effect.
+func (data *Data) LinkIdentity(userID string, ii IdentityInfo) error {
+	ai := data.Account(userID)
+	if ai == nil {
+		return ErrAccountNotFound
+	} else if err := data.checkIdentity(userID, ii); err != nil {
+		return err
+	} else if ai.HasIdentity(ii.Issuer, ii.Subject) {
+		return nil
+	}
+	ai.Identities = append(ai.Identities, ii)
+	return nil
+}
+
+// checkIdentity returns an error if an identity is incomplete or linked to another account.
+func (data *Data) checkIdentity(userID string, ii IdentityInfo) error {
+	if ii.Issuer == "" || ii.Subject == "" {
+		return ErrIdentityRequired
+	} else if other := data.AccountByIdentity(ii.Issuer, ii.Subject); other != nil && other.ID != userID {
+		return ErrIdentityExists
+	}
+	return nil
+}
+
+// Organization returns an organization by ID.
+func (data *Data) Organization(id string) *OrganizationInfo {
+	if i, ok := data.index.organizations[id]; ok {
+		return &data.Organizations[i]
+	}
+	return nil
+}
+
+// CreateOrganization adds an organization along with its namespace at path. The user account
+// creating the organization becomes its first owner.
+func (data *Data) CreateOrganization(oi OrganizationInfo, path, ownerID string) error {
+	if oi.ID == "" {
+		return ErrOrganizationIDRequired
+	} else if data.Organization(oi.ID) != nil {
+		return ErrOrganizationExists
+	} else if data.Account(ownerID) == nil {
+		return ErrAccountNotFound
+	}
+
+	if err := data.createNamespace(NamespaceInfo{
+		ID:        oi.NamespaceID,
+		Path:      path,
+		OwnerID:   oi.ID,
+		OwnerType: NamespaceOwnerOrganization,
+		CreatedAt: oi.CreatedAt,
+		UpdatedAt: oi.CreatedAt,
+	}); err != nil {
+		return err
+	}
+
+	data.Organizations = append(data.Organizations, oi)
+	data.Members = append(data.Members, MemberInfo{
+		OrganizationID: oi.ID,
+		UserID:         ownerID,
+		Role:           MemberRoleOwner,
+		State:          MemberStateActive,
+		CreatedAt:      oi.CreatedAt,
+		UpdatedAt:      oi.CreatedAt,
+	})
+	data.reindex()
+	return nil
+}
+
+// UpdateOrganization replaces the profile of an organization. The namespace of the
+// organization is left unchanged.
+func (data *Data) UpdateOrganization(oi OrganizationInfo) error {
+	other := data.Organization(oi.ID)
+	if other == nil {
+		return ErrOrganizationNotFound
+	}
+
+	oi.NamespaceID = other.NamespaceID
+	oi.CreatedAt = other.CreatedAt
+	*other = oi
+	return nil
+}
+
+// DropOrganization removes an organization along with its namespace, its memberships, its
+// invitations and its teams. An organization cannot be dropped while conversations remain in its namespace.
+func (data *Data) DropOrganization(id string) error {
+	oi := data.Organization(id)
+	if oi == nil {
+		return ErrOrganizationNotFound
+	}
+	for i := range data.Conversations {
+		if data.Conversations[i].NamespaceID == oi.NamespaceID {
+			return ErrOrganizationNotEmpty
+		}
+	}
+
+	var members []MemberInfo
+	for _, mi := range data.Members {
+		if mi.OrganizationID != id {
+			members = append(members, mi)
+		}
+	}
+	data.Members = members
+
+	var invitations []InvitationInfo
+	for _, ii := range data.Invitations {
+		if ii.OrganizationID != id {
+			invitations = append(invitations, ii)
+		}
+	}
+	data.Invitations = invitations
+
+	var teams []TeamInfo
+	for _, ti := range data.Teams {
+		if ti.OrganizationID != id {
+			teams = append(teams, ti)
+		}
+	}
+	data.Teams = teams
+
+	var tokens []ApiTokenInfo
+	for _, ti := range data.ApiTokens {
+		if ti.OrganizationID != id {
+			tokens = append(tokens, ti)
+		}
+	}
+	data.ApiTokens = tokens
+
+	data.dropNamespace(oi.NamespaceID)
+	for i := range data.Organizations {
+		if data.Organizations[i].ID == id {
+			data.Organizations = append(data.Organizations[:i], data.Organizations[i+1:]...)
+			break
+		}
+	}
+	data.reindex()
+	return nil
+}
+
+// Member returns the membership of a user in an organization.
+func (data *Data) Member(orgID, userID string) *MemberInfo {
+	for i := range data.Members {
+		if data.Members[i].OrganizationID == orgID && data.Members[i].UserID == userID {
+			return &data.Members[i]
+		}
+	}
+	return nil
+}
+
+// AddOrUpdateMembership sets the role of a user in an organization. A new membership is pending
+// until the user accepts it.
+func (data *Data) AddOrUpdateMembership(orgID, userID, role string, t time.Time) error {
+	if role != MemberRoleOwner && role != MemberRoleMember && role != MemberRoleGuest {
+		return ErrInvalidMemberRole
+	} else if data.Organization(orgID) == nil {
+		return ErrOrganizationNotFound
+	} else if data.Account(userID) == nil {
+		return ErrAccountNotFound
+	}
+
+	if mi := data.Member(orgID, userID); mi != nil {
+		if role != MemberRoleOwner && data.isLastOwner(mi) {
+			return ErrLastOrganizationOwner
+		}
+		mi.Role = role
+		mi.UpdatedAt = t
+		return nil
 	}
+
+	data.Members = append(data.Members, MemberInfo{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+		State:          MemberStatePending,
+		CreatedAt:      t,
+		UpdatedAt:      t,
+	})
+	return nil
+}
+
+// RemoveMembership removes a user from an organization and its teams, along with the API tokens
+// the user created for the organization. The last owner of an organization cannot be removed.
+func (data *Data) RemoveMembership(orgID, userID string) error {
+	for i := range data.Members {
+		mi := &data.Members[i]
+		if mi.OrganizationID == orgID && mi.UserID == userID {
+			if data.isLastOwner(mi) {
+				return ErrLastOrganizationOwner
+			}
+			data.Members = append(data.Members[:i], data.Members[i+1:]...)
+
+			// The user leaves the teams of the organization.
+			for j := range data.Teams {
+				if data.Teams[j].OrganizationID == orgID && data.Teams[j].HasMember(userID) {
+					data.RemoveTeamMember(data.Teams[j].ID, userID)
+				}
+			}
+
+			var tokens []ApiTokenInfo
+			for _, ti := range data.ApiTokens {
+				if ti.OrganizationID != orgID || ti.UserID != userID {
+					tokens = append(tokens, ti)
+				}
+			}
+			data.ApiTokens = tokens
+			return nil
+		}
+	}
+	return ErrMemberNotFound
+}
+
+// EditMyMembership sets the state of the membership of a user in an organization.
+func (data *Data) EditMyMembership(orgID, userID, state string, t time.Time) error {
+	if state != MemberStatePending && state != MemberStateActive {
+		return ErrInvalidMemberState
+	}
+
+	mi := data.Member(orgID, userID)
+	if mi == nil {
+		return ErrMemberNotFound
+	} else if state != MemberStateActive && data.isLastOwner(mi) {
+		return ErrLastOrganizationOwner
+	}
+	mi.State = state
+	mi.UpdatedAt = t
+	return nil
+}
+
+// SetMembershipPublished sets whether the membership of a user in an organization is visible to
+// everyone.
+func (data *Data) SetMembershipPublished(orgID, userID string, published bool, t time.Time) error {
+	mi := data.Member(orgID, userID)
+	if mi == nil {
+		return ErrMemberNotFound
+	}
+	if mi.Published != published {
+		mi.Published = published
+		mi.UpdatedAt = t
+	}
+	return nil
+}
+
+// isLastOwner returns true if mi is the only active owner of its organization.
+func (data *Data) isLastOwner(mi *MemberInfo) bool {
+	if mi.Role != MemberRoleOwner || mi.State != MemberStateActive {
+		return false
+	}
+	for i := range data.Members {
+		other := &data.Members[i]
+		if other != mi && other.OrganizationID == mi.OrganizationID && other.Role == MemberRoleOwner && other.State == MemberStateActive {
+			return false
+		}
+	}
+	return true
+}
+
+// Invitation returns an invitation by ID.
+func (data *Data) Invitation(id string) *InvitationInfo {
+	for i := range data.Invitations {
+		if data.Invitations[i].ID == id {
+			return &data.Invitations[i]
+		}
+	}
+	return nil
+}
+
+// InvitationByToken returns the invitation sent with the token of the given hash.
+func (data *Data) InvitationByToken(tokenHash string) *InvitationInfo {
+	for i := range data.Invitations {
+		if data.Invitations[i].TokenHash == tokenHash {
+			return &data.Invitations[i]
+		}
+	}
+	return nil
+}
+
+// CreateInvitation invites an email address to join an organization. The email address is stored
+// in lowercase and cannot be invited twice to the same organization, nor be a confirmed email address
+// of one of its members.
+func (data *Data) CreateInvitation(ii InvitationInfo) error {
+	if ii.ID == "" {
+		return ErrInvitationIDRequired
+	} else if ii.Email == "" {
+		return ErrInvitationEmailRequired
+	} else if ii.Role != MemberRoleOwner && ii.Role != MemberRoleMember && ii.Role != MemberRoleGuest {
+		return ErrInvalidMemberRole
+	} else if data.Organization(ii.OrganizationID) == nil {
+		return ErrOrganizationNotFound
+	} else if data.Invitation(ii.ID) != nil {
+		return ErrInvitationExists
+	}
+
+	ii.Email = strings.ToLower(ii.Email)
+	for i := range data.Invitations {
+		if data.Invitations[i].OrganizationID == ii.OrganizationID && data.Invitations[i].Email == ii.Email {
+			return ErrInvitationExists
+		}
+	}
+	if ai := data.AccountByEmail(ii.Email); ai != nil && ai.HasConfirmedEmail(ii.Email) && data.Member(ii.OrganizationID, ai.ID) != nil {
+		return ErrMemberExists
+	}
+
+	data.Invitations = append(data.Invitations, ii)
+	return nil
+}
+
+// ResendInvitation replaces the token of an invitation and extends its expiry.
+func (data *Data) ResendInvitation(id, tokenHash string, sentAt, expiresAt time.Time) error {
+	ii := data.Invitation(id)
+	if ii == nil {
+		return ErrInvitationNotFound
+	}
+	ii.TokenHash = tokenHash
+	ii.SentAt = sentAt
+	ii.ExpiresAt = expiresAt
+	return nil
+}
+
+// AcceptInvitation makes a user an active member of the organization of an invitation and removes
+// the invitation. The role of the invitation only applies to users that are not yet active members.
+func (data *Data) AcceptInvitation(id, userID string, t time.Time) error {
+	ii := data.Invitation(id)
+	if ii == nil {
+		return ErrInvitationNotFound
+	} else if ii.Expired(t) {
+		return ErrInvitationExpired
+	} else if data.Account(userID) == nil {
+		return ErrAccountNotFound
+	}
+
+	mi := data.Member(ii.OrganizationID, userID)
+	if mi == nil {
+		data.Members = append(data.Members, MemberInfo{
+			OrganizationID: ii.OrganizationID,
+			UserID:         userID,
+			CreatedAt:      t,
+		})
+		mi = &data.Members[len(data.Members)-1]
+	}
+	if mi.State != MemberStateActive {
+		mi.Role = ii.Role
+		mi.State = MemberStateActive
+	}
+	mi.InvitedBy = ii.InvitedBy
+	mi.InviteEmail = ii.Email
+	mi.InviteAcceptedAt = t
+	mi.UpdatedAt = t
+
+	return data.DeleteInvitation(id)
+}
+
+// DeleteInvitation removes an invitation, whether it is declined or canceled.
+func (data *Data) DeleteInvitation(id string) error {
+	for i := range data.Invitations {
+		if data.Invitations[i].ID == id {
+			data.Invitations = append(data.Invitations[:i], data.Invitations[i+1:]...)
+			return nil
+		}
+	}
+	return ErrInvitationNotFound
+}
+
+// Team returns a team by ID.
+func (data *Data) Team(id string) *TeamInfo {
+	for i := range data.Teams {
+		if data.Teams[i].ID == id {
+			return &data.Teams[i]
+		}
+	}
+	return nil
+}
+
+// TeamByName returns the team of an organization with the given name. Names are case insensitive.
+func (data *Data) TeamByName(orgID, name string) *TeamInfo {
+	for i := range data.Teams {
+		if data.Teams[i].OrganizationID == orgID && strings.EqualFold(data.Teams[i].Name, name) {
+			return &data.Teams[i]
+		}
+	}
+	return nil
+}
+
+// CreateTeam adds a team to an organization. An organization has a single owners team. The
+// members of the team must be members of the organization.
+func (data *Data) CreateTeam(ti TeamInfo) error {
+	if ti.ID == "" {
+		return ErrTeamIDRequired
+	} else if ti.Name == "" {
+		return ErrTeamNameRequired
+	} else if ti.Type != TeamTypeOwners && ti.Type != TeamTypeAdmins && ti.Type != TeamTypeTeams {
+		return ErrInvalidTeamType
+	} else if data.Organization(ti.OrganizationID) == nil {
+		return ErrOrganizationNotFound
+	} else if data.Team(ti.ID) != nil || data.TeamByName(ti.OrganizationID, ti.Name) != nil {
+		return ErrTeamExists
+	}
+	for i := range data.Teams {
+		if data.Teams[i].OrganizationID == ti.OrganizationID && data.Teams[i].Type == TeamTypeOwners && ti.Type == TeamTypeOwners {
+			return ErrTeamExists
+		}
+	}
+	for _, userID := range ti.Members {
+		if data.Member(ti.OrganizationID, userID) == nil {
+			return ErrMemberNotFound
+		}
+	}
+
+	// Conversations are granted once the team exists.
+	ti.Conversations = nil
+	data.Teams = append(data.Teams, ti)
+	return nil
+}
+
+// UpdateTeam replaces the name and the description of a team.
+func (data *Data) UpdateTeam(ti TeamInfo) error {
+	other := data.Team(ti.ID)
+	if other == nil {
+		return ErrTeamNotFound
+	} else if ti.Name == "" {
+		return ErrTeamNameRequired
+	} else if t := data.TeamByName(other.OrganizationID, ti.Name); t != nil && t.ID != ti.ID {
+		return ErrTeamExists
+	}
+
+	other.Name = ti.Name
+	other.Description = ti.Description
+	other.UpdatedAt = ti.UpdatedAt
+	return nil
+}
+
+// DropTeam removes a team. The owners and the admins teams cannot be dropped, nor a team that still has members.
+func (data *Data) DropTeam(id string) error {
+	for i := range data.Teams {
+		ti := &data.Teams[i]
+		if ti.ID != id {
+			continue
+		}
+		if ti.Type == TeamTypeOwners || ti.Type == TeamTypeAdmins {
+			return ErrTeamNotDeletable
+		} else if len(ti.Members) > 0 {
+			return ErrTeamHasMembers
+		}
+		data.Teams = append(data.Teams[:i], data.Teams[i+1:]...)
+		return nil
+	}
+	return ErrTeamNotFound
+}
+
+// AddTeamMember adds a member of an organization to one of its teams. The user participates in all the
+// conversations granted to the team from t. Adding a user twice has no effect.
+func (data *Data) AddTeamMember(teamID, userID string, t time.Time) error {
+	ti := data.Team(teamID)
+	if ti == nil {
+		return ErrTeamNotFound
+	} else if data.Member(ti.OrganizationID, userID) == nil {
+		return ErrMemberNotFound
+	} else if ti.HasMember(userID) {
+		return nil
+	}
+
+	ti.Members = append(ti.Members, userID)
+	for _, conversationID := range ti.Conversations {
+		data.grantParticipant(conversationID, userID, teamID, t)
+	}
+	return nil
+}
+
+// RemoveTeamMember removes a user from a team, along with the participation granted by the team.
+func (data *Data) RemoveTeamMember(teamID, userID string) error {
+	ti := data.Team(teamID)
+	if ti == nil {
+		return ErrTeamNotFound
+	} else if !ti.HasMember(userID) {
+		return ErrTeamMemberNotFound
+	}
+
+	ti.Members = without(ti.Members, userID)
+	for _, conversationID := range ti.Conversations {
+		data.revokeParticipant(conversationID, userID, teamID)
+	}
+	return nil
+}
+
+// GrantTeamConversation grants a conversation of the namespace of an organization to one of its teams.
+// The members of the team participate in the conversation from t. Granting a conversation twice has no effect.
+func (data *Data) GrantTeamConversation(teamID, conversationID string, t time.Time) error {
+	ti := data.Team(teamID)
+	if ti == nil {
+		return ErrTeamNotFound
+	}
+	ci, oi := data.Conversation(conversationID), data.Organization(ti.OrganizationID)
+	if ci == nil || oi == nil || ci.NamespaceID != oi.NamespaceID {
+		return ErrConversationNotFound
+	} else if ti.HasConversation(conversationID) {
+		return nil
+	}
+
+	ti.Conversations = append(ti.Conversations, conversationID)
+	for _, userID := range ti.Members {
+		data.grantParticipant(conversationID, userID, teamID, t)
+	}
+	return nil
+}
+
+// RevokeTeamConversation revokes a conversation from a team, along with the participation of the
+// members of the team that was granted by the team.
+func (data *Data) RevokeTeamConversation(teamID, conversationID string) error {
+	ti := data.Team(teamID)
+	if ti == nil {
+		return ErrTeamNotFound
+	} else if !ti.HasConversation(conversationID) {
+		return ErrTeamConversationNotFound
+	}
+
+	ti.Conversations = without(ti.Conversations, conversationID)
+	for _, userID := range ti.Members {
+		data.revokeParticipant(conversationID, userID, teamID)
+	}
+	return nil
+}
+
+// grantParticipant makes a user participate in a conversation through a team. A user that already
+// participates on its own is left unchanged, so revoking the team doesn't remove it.
+func (data *Data) grantParticipant(conversationID, userID, teamID string, t time.Time) {
+	pi := data.Participant(conversationID, userID)
+	if pi == nil {
+		data.Participants = append(data.Participants, ParticipantInfo{
+			ConversationID: conversationID,
+			UserID:         userID,
+			JoinedAt:       t,
+			LastActivityAt: t,
+			Teams:          []string{teamID},
+		})
+	} else if len(pi.Teams) > 0 && !contains(pi.Teams, teamID) {
+		pi.Teams = append(pi.Teams, teamID)
+	}
+}
+
+// revokeParticipant removes the participation of a user in a conversation granted by a team. The
+// user keeps participating while another team grants the conversation.
+func (data *Data) revokeParticipant(conversationID, userID, teamID string) {
+	for i := range data.Participants {
+		pi := &data.Participants[i]
+		if pi.ConversationID != conversationID || pi.UserID != userID || !contains(pi.Teams, teamID) {
+			continue
+		}
+		if pi.Teams = without(pi.Teams, teamID); len(pi.Teams) == 0 {
+			data.Participants = append(data.Participants[:i], data.Participants[i+1:]...)
+		}
+		return
+	}
+}
+
+// Conversation returns a conversation by ID.
+func (data *Data) Conversation(id string) *ConversationInfo {
+	if i, ok := data.index.conversations[id]; ok {
+		return &data.Conversations[i]
+	}
+	return nil
+}
+
+// CreateConversation adds a conversation to a namespace. The creator of the conversation
+// becomes its first participant.
+func (data *Data) CreateConversation(ci ConversationInfo) error {
+	if ci.ID == "" {
+		return ErrConversationIDRequired
+	} else if data.Conversation(ci.ID) != nil {
+		return ErrConversationExists
+	} else if data.NamespaceByID(ci.NamespaceID) == nil {
+		return ErrNamespaceNotFound
+	} else if ci.CreatorID != "" && data.Account(ci.CreatorID) == nil {
+		return ErrAccountNotFound
+	}
+
+	ci.ParticipantsCount = data.countParticipants(ci.ID)
+	if ci.CreatorID != "" && data.Participant(ci.ID, ci.CreatorID) == nil {
+		data.Participants = append(data.Participants, ParticipantInfo{
+			ConversationID: ci.ID,
+			UserID:         ci.CreatorID,
+			JoinedAt:       ci.CreatedAt,
+			LastActivityAt: ci.CreatedAt,
+		})
+		ci.ParticipantsCount++
+	}
+	data.Conversations = append(data.Conversations, ci)
+	data.reindex()
+	return nil
+}
+
+// UpdateConversation replaces the settings of a conversation. The namespace and the creator of
+// the conversation are left unchanged.
+func (data *Data) UpdateConversation(ci ConversationInfo) error {
+	other := data.Conversation(ci.ID)
+	if other == nil {
+		return ErrConversationNotFound
+	}
+
+	ci.NamespaceID = other.NamespaceID
+	ci.CreatorID = other.CreatorID
+	ci.CreatedAt = other.CreatedAt
+	ci.ParticipantsCount = other.ParticipantsCount
+	*other = ci
+	return nil
+}
+
+// DropConversation removes a conversation along with its participants, read markers and
+// attachments.
+func (data *Data) DropConversation(id string) error {
+	if data.Conversation(id) == nil {
+		return ErrConversationNotFound
+	}
+
+	var participants []ParticipantInfo
+	for _, pi := range data.Participants {
+		if pi.ConversationID != id {
+			participants = append(participants, pi)
+		}
+	}
+	data.Participants = participants
+
+	var markers []ReadMarkerInfo
+	for _, rmi := range data.ReadMarkers {
+		if rmi.ConversationID != id {
+			markers = append(markers, rmi)
+		}
+	}
+	data.ReadMarkers = markers
+
+	var attachments []AttachmentInfo
+	for _, ai := range data.Attachments {
+		if ai.ConversationID != id {
+			attachments = append(attachments, ai)
+		}
+	}
+	data.Attachments = attachments
+
+	for i := range data.Teams {
+		if data.Teams[i].HasConversation(id) {
+			data.Teams[i].Conversations = without(data.Teams[i].Conversations, id)
+		}
+	}
+
+	for i := range data.Conversations {
+		if data.Conversations[i].ID == id {
+			data.Conversations = append(data.Conversations[:i], data.Conversations[i+1:]...)
+			break
+		}
+	}
+	data.reindex()
+	return nil
+}
+
+// Device returns a device by ID.
+func (data *Data) Device(id string) *DeviceInfo {
+	if i, ok := data.index.devices[id]; ok {
+		return &data.Devices[i]
+	}
+	return nil
+}
+
+// AddDevice registers a device to a user account.
+func (data *Data) AddDevice(di DeviceInfo) error {
+	if di.ID == "" {
+		return ErrDeviceIDRequired
+	} else if data.Device(di.ID) != nil {
+		return ErrDeviceExists
+	} else if data.Account(di.UserID) == nil {
+		return ErrAccountNotFound
+	}
+
+	data.Devices = append(data.Devices, di)
+	data.reindex()
+	return nil
+}
+
+// UpdateDevice replaces the name, the platform and the token of a device.
+func (data *Data) UpdateDevice(di DeviceInfo) error {
+	other := data.Device(di.ID)
+	if other == nil {
+		return ErrDeviceNotFound
+	}
+
+	di.UserID = other.UserID
+	di.CreatedAt = other.CreatedAt
+	*other = di
+	return nil
+}
+
+// DeleteDevice removes a device.
+func (data *Data) DeleteDevice(id string) error {
+	for i := range data.Devices {
+		if data.Devices[i].ID == id {
+			data.Devices = append(data.Devices[:i], data.Devices[i+1:]...)
+			data.reindex()
+			return nil
+		}
+	}
+	return ErrDeviceNotFound
+}
+
+// dataIndex maps the IDs, usernames, email addresses and namespace paths of the metadata to
+// their position in the slices of the data.
+type dataIndex struct {
+	namespaces         map[string]int
+	namespacePaths     map[string]int
+	namespaceRedirects map[string]int
+	accounts           map[string]int
+	usernames          map[string]int
+	emails             map[string]int
+	organizations      map[string]int
+	conversations      map[string]int
+	devices            map[string]int
+}
+
+// reindex rebuilds the lookup indexes. It must be called whenever namespaces, accounts,
+// organizations, conversations or devices are added or removed, and namespaces renamed. The maps are never modified in
+// place since they are shared with the clones of the data.
+func (data *Data) reindex() {
+	idx := dataIndex{
+		namespaces:         make(map[string]int, len(data.Namespaces)),
+		namespacePaths:     make(map[string]int, len(data.Namespaces)),
+		namespaceRedirects: make(map[string]int),
+		accounts:           make(map[string]int, len(data.Accounts)),
+		usernames:          make(map[string]int, len(data.Accounts)),
+		emails:             make(map[string]int, len(data.Accounts)),
+		organizations:      make(map[string]int, len(data.Organizations)),
+		conversations:      make(map[string]int, len(data.Conversations)),
+		devices:            make(map[string]int, len(data.Devices)),
+	}
+
+	for i, ni := range data.Namespaces {
+		idx.namespaces[ni.ID] = i
+		idx.namespacePaths[strings.ToLower(ni.Path)] = i
+		for _, path := range ni.Redirects {
+			idx.namespaceRedirects[path] = i
+		}
+	}
+	for i, ai := range data.Accounts {
+		idx.accounts[ai.ID] = i
+		idx.usernames[strings.ToLower(ai.Username)] = i
+		for _, ei := range ai.Emails {
+			idx.emails[strings.ToLower(ei.Email)] = i
+		}
+	}
+	for i, oi := range data.Organizations {
+		idx.organizations[oi.ID] = i
+	}
+	for i, ci := range data.Conversations {
+		idx.conversations[ci.ID] = i
+	}
+	for i, di := range data.Devices {
+		idx.devices[di.ID] = i
+	}
+
+	data.index = idx
+}
+
+// RevokedToken returns a revoked token by ID.
+func (data *Data) RevokedToken(id string) *RevokedTokenInfo {
+	for i := range data.RevokedTokens {
+		if data.RevokedTokens[i].ID == id {
+			return &data.RevokedTokens[i]
+		}
+	}
+	return nil
+}
+
+// RevokeToken denies a token until it expires. The tokens that expired by t are removed, since they
+// are no longer accepted anyway.
+func (data *Data) RevokeToken(ri RevokedTokenInfo, t time.Time) error {
+	if ri.ID == "" {
+		return ErrTokenIDRequired
+	}
+
+	var tokens []RevokedTokenInfo
+	for _, x := range data.RevokedTokens {
+		if x.ExpiresAt.After(t) {
+			tokens = append(tokens, x)
+		}
+	}
+	data.RevokedTokens = tokens
+
+	if data.RevokedToken(ri.ID) != nil {
+		return ErrTokenRevoked
+	}
+	data.RevokedTokens = append(data.RevokedTokens, ri)
+	return nil
+}
+
+// ApiToken returns an API token by ID.
+func (data *Data) ApiToken(id string) *ApiTokenInfo {
+	for i := range data.ApiTokens {
+		if data.ApiTokens[i].ID == id {
+			return &data.ApiTokens[i]
+		}
+	}
+	return nil
+}
+
+// ApiTokenByHash returns the API token of the given hash.
+func (data *Data) ApiTokenByHash(tokenHash string) *ApiTokenInfo {
+	for i := range data.ApiTokens {
+		if data.ApiTokens[i].TokenHash == tokenHash {
+			return &data.ApiTokens[i]
+		}
+	}
+	return nil
+}
+
+// CreateApiToken adds an API token. The token of an organization must be created by one of its
+// members.
+func (data *Data) CreateApiToken(ti ApiTokenInfo) error {
+	if ti.ID == "" {
+		return ErrApiTokenIDRequired
+	} else if data.Account(ti.UserID) == nil {
+		return ErrAccountNotFound
+	} else if data.ApiToken(ti.ID) != nil || data.ApiTokenByHash(ti.TokenHash) != nil {
+		return ErrApiTokenExists
+	}
+
+	if ti.OrganizationID != "" {
+		if data.Organization(ti.OrganizationID) == nil {
+			return ErrOrganizationNotFound
+		} else if data.Member(ti.OrganizationID, ti.UserID) == nil {
+			return ErrMemberNotFound
+		}
+	}
+
+	for _, scope := range ti.Scopes {
+		if scope != ApiTokenScopeReadMessages && scope != ApiTokenScopeWriteMessages && scope != ApiTokenScopeAdmin {
+			return ErrInvalidApiTokenScope
+		}
+	}
+
+	data.ApiTokens = append(data.ApiTokens, ti)
+	return nil
+}
+
+// TouchApiToken records the time an API token was last used.
+func (data *Data) TouchApiToken(id string, t time.Time) error {
+	ti := data.ApiToken(id)
+	if ti == nil {
+		return ErrApiTokenNotFound
+	}
+	if t.After(ti.LastUsedAt) {
+		ti.LastUsedAt = t
+	}
+	return nil
+}
+
+// DeleteApiToken removes an API token.
+func (data *Data) DeleteApiToken(id string) error {
+	for i := range data.ApiTokens {
+		if data.ApiTokens[i].ID == id {
+			data.ApiTokens = append(data.ApiTokens[:i], data.ApiTokens[i+1:]...)
+			return nil
+		}
+	}
+	return ErrApiTokenNotFound
+}
+
+// Clone returns a copy of data with a new version.
+func (data *Data) Clone() *Data {
+	other := *data
+
+	// Copy nodes.
+	if data.Nodes != nil {
+		other.Nodes = make([]NodeInfo, len(data.Nodes))
+		for i := range data.Nodes {
+			other.Nodes[i] = data.Nodes[i].clone()
+		}
+	}
+
+	// Deep copy databases.
+	if data.Databases != nil {
+		other.Databases = make([]DatabaseInfo, len(data.Databases))
+		for i := range data.Databases {
+			other.Databases[i] = data.Databases[i].clone()
+		}
+	}
+
+	// Copy users.
+	if data.Users != nil {
+		other.Users = make([]UserInfo, len(data.Users))
+		for i := range data.Users {
+			other.Users[i] = data.Users[i].clone()
+		}
+	}
+
+	// Copy read markers.
+	if data.ReadMarkers != nil {
+		other.ReadMarkers = make([]ReadMarkerInfo, len(data.ReadMarkers))
+		for i := range data.ReadMarkers {
+			other.ReadMarkers[i] = data.ReadMarkers[i].clone()
+		}
+	}
+
+	// Copy attachments.
+	if data.Attachments != nil {
+		other.Attachments = make([]AttachmentInfo, len(data.Attachments))
+		for i := range data.Attachments {
+			other.Attachments[i] = data.Attachments[i].clone()
+		}
+	}
+
+	// Copy participants.
+	if data.Participants != nil {
+		other.Participants = make([]ParticipantInfo, len(data.Participants))
+		for i := range data.Participants {
+			other.Participants[i] = data.Participants[i].clone()
+		}
+	}
+
+	// Copy namespaces.
+	if data.Namespaces != nil {
+		other.Namespaces = make([]NamespaceInfo, len(data.Namespaces))
+		for i := range data.Namespaces {
+			other.Namespaces[i] = data.Namespaces[i].clone()
+		}
+	}
+
+	// Copy accounts.
+	if data.Accounts != nil {
+		other.Accounts = make([]AccountInfo, len(data.Accounts))
+		for i := range data.Accounts {
+			other.Accounts[i] = data.Accounts[i].clone()
+		}
+	}
+
+	// Copy organizations.
+	if data.Organizations != nil {
+		other.Organizations = make([]OrganizationInfo, len(data.Organizations))
+		for i := range data.Organizations {
+			other.Organizations[i] = data.Organizations[i].clone()
+		}
+	}
+
+	// Copy members.
+	if data.Members != nil {
+		other.Members = make([]MemberInfo, len(data.Members))
+		for i := range data.Members {
+			other.Members[i] = data.Members[i].clone()
+		}
+	}
+
+	// Copy conversations.
+	if data.Conversations != nil {
+		other.Conversations = make([]ConversationInfo, len(data.Conversations))
+		for i := range data.Conversations {
+			other.Conversations[i] = data.Conversations[i].clone()
+		}
+	}
+
+	// Copy devices.
+	if data.Devices != nil {
+		other.Devices = make([]DeviceInfo, len(data.Devices))
+		for i := range data.Devices {
+			other.Devices[i] = data.Devices[i].clone()
+		}
+	}
+
+	// Copy invitations.
+	if data.Invitations != nil {
+		other.Invitations = make([]InvitationInfo, len(data.Invitations))
+		for i := range data.Invitations {
+			other.Invitations[i] = data.Invitations[i].clone()
+		}
+	}
+
+	// Copy teams.
+	if data.Teams != nil {
+		other.Teams = make([]TeamInfo, len(data.Teams))
+		for i := range data.Teams {
+			other.Teams[i] = data.Teams[i].clone()
+		}
+	}
+
+	// Copy revoked tokens.
+	if data.RevokedTokens != nil {
+		other.RevokedTokens = make([]RevokedTokenInfo, len(data.RevokedTokens))
+		for i := range data.RevokedTokens {
+			other.RevokedTokens[i] = data.RevokedTokens[i].clone()
+		}
+	}
+
+	if data.ApiTokens != nil {
+		other.ApiTokens = make([]ApiTokenInfo, len(data.ApiTokens))
+		for i := range data.ApiTokens {
+			other.ApiTokens[i] = data.ApiTokens[i].clone()
+		}
+	}
+
+	other.reindex()
+
+	return &other
+}
+
+// marshal serializes to a protobuf representation.
+func (data *Data) marshal() *internal.Data {
+	pb := &internal.Data{
+		Term:      proto.Uint64(data.Term),
+		Index:     proto.Uint64(data.Index),
+		ClusterID: proto.Uint64(data.ClusterID),
+
+		MaxNodeID:       proto.Uint64(data.MaxNodeID),
+		MaxShardGroupID: proto.Uint64(data.MaxShardGroupID),
+		MaxShardID:      proto.Uint64(data.MaxShardID),
+	}
+
+	pb.Nodes = make([]*internal.NodeInfo, len(data.Nodes))
+	for i := range data.Nodes {
+		pb.Nodes[i] = data.Nodes[i].marshal()
+	}
+
+	pb.Databases = make([]*internal.DatabaseInfo, len(data.Databases))
+	for i := range data.Databases {
+		pb.Databases[i] = data.Databases[i].marshal()
+	}
+
+	pb.Users = make([]*internal.UserInfo, len(data.Users))
+	for i := range data.Users {
+		pb.Users[i] = data.Users[i].marshal()
+	}
+
+	pb.ReadMarkers = make([]*internal.ReadMarkerInfo, len(data.ReadMarkers))
+	for i := range data.ReadMarkers {
+		pb.ReadMarkers[i] = data.ReadMarkers[i].marshal()
+	}
+
+	pb.Attachments = make([]*internal.AttachmentInfo, len(data.Attachments))
+	for i := range data.Attachments {
+		pb.Attachments[i] = data.Attachments[i].marshal()
+	}
+
+	pb.Participants = make([]*internal.ParticipantInfo, len(data.Participants))
+	for i := range data.Participants {
+		pb.Participants[i] = data.Participants[i].marshal()
+	}
+
+	pb.Namespaces = make([]*internal.NamespaceInfo, len(data.Namespaces))
+	for i := range data.Namespaces {
+		pb.Namespaces[i] = data.Namespaces[i].marshal()
+	}
+
+	pb.Accounts = make([]*internal.AccountInfo, len(data.Accounts))
+	for i := range data.Accounts {
+		pb.Accounts[i] = data.Accounts[i].marshal()
+	}
+
+	pb.Organizations = make([]*internal.OrganizationInfo, len(data.Organizations))
+	for i := range data.Organizations {
+		pb.Organizations[i] = data.Organizations[i].marshal()
+	}
+
+	pb.Members = make([]*internal.MemberInfo, len(data.Members))
+	for i := range data.Members {
+		pb.Members[i] = data.Members[i].marshal()
+	}
+
+	pb.Conversations = make([]*internal.ConversationInfo, len(data.Conversations))
+	for i := range data.Conversations {
+		pb.Conversations[i] = data.Conversations[i].marshal()
+	}
+
+	pb.Devices = make([]*internal.DeviceInfo, len(data.Devices))
+	for i := range data.Devices {
+		pb.Devices[i] = data.Devices[i].marshal()
+	}
+
+	pb.Invitations = make([]*internal.InvitationInfo, len(data.Invitations))
+	for i := range data.Invitations {
+		pb.Invitations[i] = data.Invitations[i].marshal()
+	}
+
+	pb.Teams = make([]*internal.TeamInfo, len(data.Teams))
+	for i := range data.Teams {
+		pb.Teams[i] = data.Teams[i].marshal()
+	}
+
+	pb.RevokedTokens = make([]*internal.RevokedTokenInfo, len(data.RevokedTokens))
+	for i := range data.RevokedTokens {
+		pb.RevokedTokens[i] = data.RevokedTokens[i].marshal()
+	}
+
+	pb.ApiTokens = make([]*internal.ApiTokenInfo, len(data.ApiTokens))
+	for i := range data.ApiTokens {
+		pb.ApiTokens[i] = data.ApiTokens[i].marshal()
+	}
+
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (data *Data) unmarshal(pb *internal.Data) {
+	data.Term = pb.GetTerm()
+	data.Index = pb.GetIndex()
+	data.ClusterID = pb.GetClusterID()
+
+	data.MaxNodeID = pb.GetMaxNodeID()
+	data.MaxShardGroupID = pb.GetMaxShardGroupID()
+	data.MaxShardID = pb.GetMaxShardID()
+
+	data.Nodes = make([]NodeInfo, len(pb.GetNodes()))
+	for i, x := range pb.GetNodes() {
+		data.Nodes[i].unmarshal(x)
+	}
+
+	data.Databases = make([]DatabaseInfo, len(pb.GetDatabases()))
+	for i, x := range pb.GetDatabases() {
+		data.Databases[i].unmarshal(x)
+	}
+
+	data.Users = make([]UserInfo, len(pb.GetUsers()))
+	for i, x := range pb.GetUsers() {
+		data.Users[i].unmarshal(x)
+	}
+
+	data.ReadMarkers = make([]ReadMarkerInfo, len(pb.GetReadMarkers()))
+	for i, x := range pb.GetReadMarkers() {
+		data.ReadMarkers[i].unmarshal(x)
+	}
+
+	data.Attachments = make([]AttachmentInfo, len(pb.GetAttachments()))
+	for i, x := range pb.GetAttachments() {
+		data.Attachments[i].unmarshal(x)
+	}
+
+	data.Participants = make([]ParticipantInfo, len(pb.GetParticipants()))
+	for i, x := range pb.GetParticipants() {
+		data.Participants[i].unmarshal(x)
+	}
+
+	data.Namespaces = make([]NamespaceInfo, len(pb.GetNamespaces()))
+	for i, x := range pb.GetNamespaces() {
+		data.Namespaces[i].unmarshal(x)
+	}
+
+	data.Accounts = make([]AccountInfo, len(pb.GetAccounts()))
+	for i, x := range pb.GetAccounts() {
+		data.Accounts[i].unmarshal(x)
+	}
+
+	data.Organizations = make([]OrganizationInfo, len(pb.GetOrganizations()))
+	for i, x := range pb.GetOrganizations() {
+		data.Organizations[i].unmarshal(x)
+	}
+
+	data.Members = make([]MemberInfo, len(pb.GetMembers()))
+	for i, x := range pb.GetMembers() {
+		data.Members[i].unmarshal(x)
+	}
+
+	data.Conversations = make([]ConversationInfo, len(pb.GetConversations()))
+	for i, x := range pb.GetConversations() {
+		data.Conversations[i].unmarshal(x)
+
+		// conversations saved before the count was maintained count their participants once
+		if x.ParticipantsCount == nil {
+			data.Conversations[i].ParticipantsCount = data.countParticipants(data.Conversations[i].ID)
+		}
+	}
+
+	data.Devices = make([]DeviceInfo, len(pb.GetDevices()))
+	for i, x := range pb.GetDevices() {
+		data.Devices[i].unmarshal(x)
+	}
+
+	data.Invitations = make([]InvitationInfo, len(pb.GetInvitations()))
+	for i, x := range pb.GetInvitations() {
+		data.Invitations[i].unmarshal(x)
+	}
+
+	data.Teams = make([]TeamInfo, len(pb.GetTeams()))
+	for i, x := range pb.GetTeams() {
+		data.Teams[i].unmarshal(x)
+	}
+
+	data.RevokedTokens = make([]RevokedTokenInfo, len(pb.GetRevokedTokens()))
+	for i, x := range pb.GetRevokedTokens() {
+		data.RevokedTokens[i].unmarshal(x)
+	}
+
+	data.ApiTokens = make([]ApiTokenInfo, len(pb.GetApiTokens()))
+	for i, x := range pb.GetApiTokens() {
+		data.ApiTokens[i].unmarshal(x)
+	}
+
+	data.reindex()
 }
 
 // MarshalBinary encodes the metadata to a binary format.
@@ -545,6 +545,465 @@ func TestData_UpdateUser_ErrUserNotFound(t *testing.T) {
 	}
 }
 
+// Ensure a read marker only moves forward.
+func TestData_SetReadMarker(t *testing.T) {
+	var data meta.Data
+	t0, t1 := time.Unix(1, 0).UTC(), time.Unix(2, 0).UTC()
+	if err := data.SetReadMarker("c0", "u0", "m1", t1); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetReadMarker("c0", "u0", "m0", t0); err != nil {
+		t.Fatal(err)
+	}
+
+	if rmi := data.ReadMarker("c0", "u0"); !reflect.DeepEqual(rmi, &meta.ReadMarkerInfo{ConversationID: "c0", UserID: "u0", MessageID: "m1", Time: t1}) {
+		t.Fatalf("unexpected read marker: %#v", rmi)
+	} else if rmi := data.ReadMarker("c0", "u1"); rmi != nil {
+		t.Fatalf("unexpected read marker: %#v", rmi)
+	}
+
+	if err := data.SetReadMarker("", "u0", "m0", t0); err != meta.ErrConversationIDRequired {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.SetReadMarker("c0", "", "m0", t0); err != meta.ErrUserIDRequired {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure an attachment can be created, linked to a message and deleted.
+func TestData_Attachments(t *testing.T) {
+	var data meta.Data
+	ai := meta.AttachmentInfo{ID: "a0", ConversationID: "c0", UploaderID: "u0", Filename: "a.txt", SHA256: "sha"}
+	if err := data.CreateAttachment(ai); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateAttachment(ai); err != meta.ErrAttachmentExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateAttachment(meta.AttachmentInfo{ConversationID: "c0", UploaderID: "u0"}); err != meta.ErrAttachmentIDRequired {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := data.SetAttachmentMessage("a0", "m0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetAttachmentMessage("a0", "m1"); err != meta.ErrAttachmentLinked {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.SetAttachmentMessage("a1", "m0"); err != meta.ErrAttachmentNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ai.MessageID = "m0"
+	if other := data.Attachment("a0"); !reflect.DeepEqual(other, &ai) {
+		t.Fatalf("unexpected attachment: %#v", other)
+	}
+
+	if err := data.DeleteAttachment("a0"); err != nil {
+		t.Fatal(err)
+	} else if data.Attachment("a0") != nil {
+		t.Fatal("expected attachment to be deleted")
+	} else if err := data.DeleteAttachment("a0"); err != meta.ErrAttachmentNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure participants can be added, track their activity and be removed.
+func TestData_Participants(t *testing.T) {
+	var data meta.Data
+	t0 := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.AddParticipant("c0", "u0", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.AddParticipant("c0", "u0", t0); err != meta.ErrParticipantExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.AddParticipant("", "u0", t0); err != meta.ErrConversationIDRequired {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.AddParticipant("c0", "", t0); err != meta.ErrUserIDRequired {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t1 := t0.Add(time.Hour)
+	if err := data.SetParticipantActivity("c0", "u0", t1); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetParticipantActivity("c0", "u0", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetParticipantActivity("c0", "u1", t1); err != meta.ErrParticipantNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	exp := &meta.ParticipantInfo{ConversationID: "c0", UserID: "u0", JoinedAt: t0, LastActivityAt: t1}
+	if other := data.Participant("c0", "u0"); !reflect.DeepEqual(other, exp) {
+		t.Fatalf("unexpected participant: %#v", other)
+	}
+
+	if err := data.RemoveParticipant("c0", "u0"); err != nil {
+		t.Fatal(err)
+	} else if data.Participant("c0", "u0") != nil {
+		t.Fatal("expected participant to be removed")
+	} else if err := data.RemoveParticipant("c0", "u0"); err != meta.ErrParticipantNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure accounts can be created and looked up by ID, username and email address.
+func TestData_CreateAccount(t *testing.T) {
+	var data meta.Data
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "Susy", PrimaryEmail: "susy@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ai := data.AccountByUsername("SUSY"); ai == nil || ai.ID != "u0" || ai.Username != "susy" {
+		t.Fatalf("unexpected account: %#v", ai)
+	} else if ai := data.AccountByEmail("Susy@Example.com"); ai == nil || ai.ID != "u0" {
+		t.Fatalf("unexpected account: %#v", ai)
+	} else if ni := data.Namespace("susy"); ni == nil || ni.ID != "n0" || ni.OwnerID != "u0" || ni.OwnerType != meta.NamespaceOwnerUser {
+		t.Fatalf("unexpected namespace: %#v", ni)
+	}
+
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "susy"}); err != meta.ErrNamespaceExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "bob", PrimaryEmail: "SUSY@example.com"}); err != meta.ErrEmailExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "-bob"}); err != meta.ErrInvalidNamespacePath {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n1", Username: "bob"}); err != meta.ErrAccountExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if data.Namespace("bob") != nil {
+		t.Fatal("expected failed account not to take a namespace")
+	}
+
+	// Ensure the email index follows updates.
+	if err := data.UpdateAccount(meta.AccountInfo{ID: "u0", Username: "other", PrimaryEmail: "s@example.com"}); err != nil {
+		t.Fatal(err)
+	} else if data.AccountByEmail("susy@example.com") != nil {
+		t.Fatal("expected email address to be unregistered")
+	} else if ai := data.AccountByEmail("s@example.com"); ai == nil || ai.Username != "susy" {
+		t.Fatalf("unexpected account: %#v", ai)
+	}
+}
+
+// Ensure identities of external providers are linked to a single account.
+func TestData_LinkIdentity(t *testing.T) {
+	var data meta.Data
+	idp := "https://idp.example.com"
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "susy", Identities: []meta.IdentityInfo{{Issuer: idp, Subject: "s0"}}}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "bob", Identities: []meta.IdentityInfo{{Issuer: idp, Subject: "s0"}}}); err != meta.ErrIdentityExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.LinkIdentity("u1", meta.IdentityInfo{Issuer: idp}); err != meta.ErrIdentityRequired {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.LinkIdentity("u1", meta.IdentityInfo{Issuer: idp, Subject: "s0"}); err != meta.ErrIdentityExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.LinkIdentity("u1", meta.IdentityInfo{Issuer: idp, Subject: "s1"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.LinkIdentity("u1", meta.IdentityInfo{Issuer: idp, Subject: "s1"}); err != nil {
+		t.Fatal(err)
+	} else if ai := data.AccountByIdentity(idp, "s1"); ai == nil || ai.ID != "u1" || len(ai.Identities) != 1 {
+		t.Fatalf("unexpected account: %#v", ai)
+	}
+
+	// Identities are kept when the account is updated.
+	if err := data.UpdateAccount(meta.AccountInfo{ID: "u0", GivenName: "Susy"}); err != nil {
+		t.Fatal(err)
+	} else if ai := data.AccountByIdentity(idp, "s0"); ai == nil || ai.ID != "u0" {
+		t.Fatalf("unexpected account: %#v", ai)
+	}
+}
+
+// Ensure namespaces can be renamed, leaving a redirect from their previous path.
+func TestData_RenameNamespace(t *testing.T) {
+	var data meta.Data
+	t0 := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "susy"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.RenameNamespace("n0", "Susan", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.RenameNamespace("n0", "bob", t0); err != meta.ErrNamespaceExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RenameNamespace("n0", "-susy", t0); err != meta.ErrInvalidNamespacePath {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RenameNamespace("n2", "alice", t0); err != meta.ErrNamespaceNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The username follows the namespace and the previous path redirects to it.
+	if ai := data.AccountByUsername("susan"); ai == nil || ai.ID != "u0" || !ai.UpdatedAt.Equal(t0) {
+		t.Fatalf("unexpected account: %#v", ai)
+	} else if data.AccountByUsername("susy") != nil {
+		t.Fatal("expected previous username to be released")
+	} else if data.Namespace("susy") != nil {
+		t.Fatal("expected previous path not to be a namespace")
+	} else if ni, redirected := data.ResolveNamespace("SUSY"); ni == nil || ni.ID != "n0" || !redirected {
+		t.Fatalf("unexpected resolution: %#v, %v", ni, redirected)
+	} else if ni, redirected := data.ResolveNamespace("susan"); ni == nil || ni.ID != "n0" || redirected {
+		t.Fatalf("unexpected resolution: %#v, %v", ni, redirected)
+	}
+
+	// Taking a previous path removes its redirect.
+	if err := data.RenameNamespace("n1", "susy", t0); err != nil {
+		t.Fatal(err)
+	} else if ni, redirected := data.ResolveNamespace("susy"); ni == nil || ni.ID != "n1" || redirected {
+		t.Fatalf("unexpected resolution: %#v, %v", ni, redirected)
+	} else if ni := data.NamespaceByID("n0"); len(ni.Redirects) != 0 {
+		t.Fatalf("unexpected redirects: %v", ni.Redirects)
+	}
+}
+
+// Ensure organizations can be created with an owner, and the last owner is protected.
+func TestData_Organizations(t *testing.T) {
+	var data meta.Data
+	t0 := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "susy"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "bob"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateOrganization(meta.OrganizationInfo{ID: "o0", NamespaceID: "n2", Name: "acme"}, "acme", "u0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateOrganization(meta.OrganizationInfo{ID: "o1", NamespaceID: "n3"}, "susy", "u0"); err != meta.ErrNamespaceExists {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if mi := data.Member("o0", "u0"); mi == nil || mi.Role != meta.MemberRoleOwner || mi.State != meta.MemberStateActive {
+		t.Fatalf("unexpected owner: %#v", mi)
+	}
+
+	// New members are pending until they accept.
+	if err := data.AddOrUpdateMembership("o0", "u1", meta.MemberRoleMember, t0); err != nil {
+		t.Fatal(err)
+	} else if mi := data.Member("o0", "u1"); mi == nil || mi.State != meta.MemberStatePending {
+		t.Fatalf("unexpected member: %#v", mi)
+	} else if err := data.EditMyMembership("o0", "u1", meta.MemberStateActive, t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.AddOrUpdateMembership("o0", "u1", "admin", t0); err != meta.ErrInvalidMemberRole {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := data.RemoveMembership("o0", "u0"); err != meta.ErrLastOrganizationOwner {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.AddOrUpdateMembership("o0", "u0", meta.MemberRoleMember, t0); err != meta.ErrLastOrganizationOwner {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RemoveMembership("o0", "u1"); err != nil {
+		t.Fatal(err)
+	} else if err := data.RemoveMembership("o0", "u1"); err != meta.ErrMemberNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := data.DropOrganization("o0"); err != nil {
+		t.Fatal(err)
+	} else if data.Organization("o0") != nil || data.Namespace("acme") != nil || data.Member("o0", "u0") != nil {
+		t.Fatal("expected organization to be dropped")
+	}
+}
+
+// Ensure email addresses can be invited to an organization and the invitations accepted.
+func TestData_Invitations(t *testing.T) {
+	var data meta.Data
+	t0 := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "susy", PrimaryEmail: "susy@example.com", Emails: []meta.EmailInfo{{Email: "susy@example.com", Confirmed: true}, {Email: "susan@example.com"}}}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "bob"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateOrganization(meta.OrganizationInfo{ID: "o0", NamespaceID: "n2"}, "acme", "u0"); err != nil {
+		t.Fatal(err)
+	}
+
+	invite := meta.InvitationInfo{ID: "i0", OrganizationID: "o0", Email: "Bob@Example.com", Role: meta.MemberRoleMember, InvitedBy: "u0", TokenHash: "h0", ExpiresAt: t0.Add(time.Hour)}
+	if err := data.CreateInvitation(invite); err != nil {
+		t.Fatal(err)
+	} else if ii := data.InvitationByToken("h0"); ii == nil || ii.ID != "i0" || ii.Email != "bob@example.com" {
+		t.Fatalf("unexpected invitation: %#v", ii)
+	}
+
+	// An email address is invited once, and never when a member confirmed it.
+	if err := data.CreateInvitation(meta.InvitationInfo{ID: "i1", OrganizationID: "o0", Email: "bob@example.com", Role: meta.MemberRoleMember}); err != meta.ErrInvitationExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateInvitation(meta.InvitationInfo{ID: "i1", OrganizationID: "o0", Email: "susy@example.com", Role: meta.MemberRoleMember}); err != meta.ErrMemberExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateInvitation(meta.InvitationInfo{ID: "i1", OrganizationID: "o0", Email: "joe@example.com", Role: "admin"}); err != meta.ErrInvalidMemberRole {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateInvitation(meta.InvitationInfo{ID: "i2", OrganizationID: "o0", Email: "susan@example.com", Role: meta.MemberRoleMember}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Expired invitations cannot be accepted until they are sent again.
+	if err := data.AcceptInvitation("i0", "u1", t0.Add(time.Hour)); err != meta.ErrInvitationExpired {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.ResendInvitation("i0", "h1", t0, t0.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if data.InvitationByToken("h0") != nil {
+		t.Fatal("expected previous token to be replaced")
+	} else if err := data.AcceptInvitation("i0", "u1", t0.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if mi := data.Member("o0", "u1"); mi == nil || mi.State != meta.MemberStateActive || mi.Role != meta.MemberRoleMember || mi.InvitedBy != "u0" || !mi.InviteAcceptedAt.Equal(t0.Add(time.Hour)) {
+		t.Fatalf("unexpected member: %#v", mi)
+	} else if data.Invitation("i0") != nil {
+		t.Fatal("expected invitation to be removed")
+	} else if err := data.DeleteInvitation("i0"); err != meta.ErrInvitationNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure teams grant their conversations to their members.
+func TestData_Teams(t *testing.T) {
+	var data meta.Data
+	t0 := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "susy"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "bob"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateOrganization(meta.OrganizationInfo{ID: "o0", NamespaceID: "n2"}, "acme", "u0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.AddOrUpdateMembership("o0", "u1", meta.MemberRoleMember, t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateConversation(meta.ConversationInfo{ID: "c0", NamespaceID: "n2", CreatorID: "u0"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateConversation(meta.ConversationInfo{ID: "c1", NamespaceID: "n0", CreatorID: "u0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.CreateTeam(meta.TeamInfo{ID: "t0", OrganizationID: "o0", Name: "Owners", Type: meta.TeamTypeOwners, Members: []string{"u0"}}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateTeam(meta.TeamInfo{ID: "t1", OrganizationID: "o0", Name: "Core", Type: meta.TeamTypeOwners}); err != meta.ErrTeamExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateTeam(meta.TeamInfo{ID: "t1", OrganizationID: "o0", Name: "owners", Type: meta.TeamTypeTeams}); err != meta.ErrTeamExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateTeam(meta.TeamInfo{ID: "t1", OrganizationID: "o0", Name: "Core", Type: meta.TeamTypeTeams}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Members of the team participate in the conversations of the organization granted to the team.
+	if err := data.GrantTeamConversation("t1", "c1", t0); err != meta.ErrConversationNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.GrantTeamConversation("t1", "c0", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.AddTeamMember("t1", "u1", t0); err != nil {
+		t.Fatal(err)
+	} else if pi := data.Participant("c0", "u1"); pi == nil || !reflect.DeepEqual(pi.Teams, []string{"t1"}) {
+		t.Fatalf("unexpected participant: %#v", pi)
+	}
+
+	// Participants that joined on their own are kept when the team is revoked.
+	if err := data.AddTeamMember("t1", "u0", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.RevokeTeamConversation("t1", "c0"); err != nil {
+		t.Fatal(err)
+	} else if data.Participant("c0", "u1") != nil {
+		t.Fatal("expected participant to be revoked")
+	} else if data.Participant("c0", "u0") == nil {
+		t.Fatal("expected creator to participate")
+	}
+
+	// Leaving the organization leaves its teams.
+	if err := data.GrantTeamConversation("t1", "c0", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.RemoveMembership("o0", "u1"); err != nil {
+		t.Fatal(err)
+	} else if data.Team("t1").HasMember("u1") || data.Participant("c0", "u1") != nil {
+		t.Fatal("expected member to leave the team")
+	} else if err := data.AddTeamMember("t1", "u1", t0); err != meta.ErrMemberNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := data.DropTeam("t0"); err != meta.ErrTeamNotDeletable {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.DropTeam("t1"); err != meta.ErrTeamHasMembers {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RemoveTeamMember("t1", "u0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.DropTeam("t1"); err != nil {
+		t.Fatal(err)
+	} else if data.Team("t1") != nil {
+		t.Fatal("expected team to be dropped")
+	}
+}
+
+// Ensure conversations can be created in a namespace and dropped.
+func TestData_Conversations(t *testing.T) {
+	var data meta.Data
+	t0 := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "susy"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateConversation(meta.ConversationInfo{ID: "c0", NamespaceID: "n0", CreatorID: "u0", CreatedAt: t0}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateConversation(meta.ConversationInfo{ID: "c0", NamespaceID: "n0", CreatorID: "u0"}); err != meta.ErrConversationExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateConversation(meta.ConversationInfo{ID: "c1", NamespaceID: "n1", CreatorID: "u0"}); err != meta.ErrNamespaceNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The creator participates in the conversation.
+	if pi := data.Participant("c0", "u0"); pi == nil || !pi.JoinedAt.Equal(t0) {
+		t.Fatalf("unexpected participant: %#v", pi)
+	}
+
+	if err := data.UpdateConversation(meta.ConversationInfo{ID: "c0", Title: "general"}); err != nil {
+		t.Fatal(err)
+	} else if ci := data.Conversation("c0"); ci == nil || ci.Title != "general" || ci.NamespaceID != "n0" || ci.CreatorID != "u0" {
+		t.Fatalf("unexpected conversation: %#v", ci)
+	}
+
+	// The participants are counted as they join and leave, and the count is kept by the snapshots.
+	if err := data.AddParticipant("c0", "u1", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.AddParticipant("c0", "u2", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.RemoveParticipant("c0", "u1"); err != nil {
+		t.Fatal(err)
+	} else if n := data.Conversation("c0").ParticipantsCount; n != 2 {
+		t.Fatalf("unexpected participants count: %d", n)
+	}
+	if buf, err := data.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	} else {
+		var other meta.Data
+		if err := other.UnmarshalBinary(buf); err != nil {
+			t.Fatal(err)
+		} else if n := other.Conversation("c0").ParticipantsCount; n != 2 {
+			t.Fatalf("unexpected unmarshaled participants count: %d", n)
+		}
+	}
+
+	if err := data.DropConversation("c0"); err != nil {
+		t.Fatal(err)
+	} else if data.Conversation("c0") != nil || data.Participant("c0", "u0") != nil {
+		t.Fatal("expected conversation to be dropped")
+	} else if err := data.DropConversation("c0"); err != meta.ErrConversationNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure devices can be registered to an account, updated and deleted.
+func TestData_Devices(t *testing.T) {
+	var data meta.Data
+	if err := data.AddDevice(meta.DeviceInfo{ID: "d0", UserID: "u0"}); err != meta.ErrAccountNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "susy"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.AddDevice(meta.DeviceInfo{ID: "d0", UserID: "u0", Name: "phone"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.AddDevice(meta.DeviceInfo{ID: "d0", UserID: "u0"}); err != meta.ErrDeviceExists {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := data.UpdateDevice(meta.DeviceInfo{ID: "d0", UserID: "u1", Name: "tablet"}); err != nil {
+		t.Fatal(err)
+	} else if di := data.Device("d0"); di == nil || di.Name != "tablet" || di.UserID != "u0" {
+		t.Fatalf("unexpected device: %#v", di)
+	}
+
+	if err := data.DeleteDevice("d0"); err != nil {
+		t.Fatal(err)
+	} else if data.Device("d0") != nil {
+		t.Fatal("expected device to be deleted")
+	} else if err := data.DeleteDevice("d0"); err != meta.ErrDeviceNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 // Ensure the data can be deeply copied.
 func TestData_Clone(t *testing.T) {
 	data := meta.Data{
@@ -612,6 +1071,89 @@ func TestData_Clone(t *testing.T) {
 	}
 }
 
+// Ensure revoked tokens are denied until they expire.
+func TestData_RevokeToken(t *testing.T) {
+	var data meta.Data
+	t0 := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := data.RevokeToken(meta.RevokedTokenInfo{UserID: "u0", ExpiresAt: t0}, t0); err != meta.ErrTokenIDRequired {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.RevokeToken(meta.RevokedTokenInfo{ID: "j0", UserID: "u0", ExpiresAt: t0.Add(time.Hour)}, t0); err != nil {
+		t.Fatal(err)
+	} else if data.RevokedToken("j0") == nil {
+		t.Fatal("expected token to be revoked")
+	}
+
+	// Revoking a token twice fails, so that a refresh token is only rotated once.
+	if err := data.RevokeToken(meta.RevokedTokenInfo{ID: "j0", UserID: "u0", ExpiresAt: t0.Add(time.Hour)}, t0); err != meta.ErrTokenRevoked {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Expired tokens are removed by later revocations.
+	if err := data.RevokeToken(meta.RevokedTokenInfo{ID: "j1", UserID: "u0", ExpiresAt: t0.Add(3 * time.Hour)}, t0.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if data.RevokedToken("j0") != nil {
+		t.Fatal("expected expired token to be removed")
+	} else if data.RevokedToken("j1") == nil {
+		t.Fatal("expected token to be revoked")
+	}
+}
+
+// Ensure API tokens are looked up by hash and removed along with the memberships they were created for.
+func TestData_ApiTokens(t *testing.T) {
+	var data meta.Data
+	t0 := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.CreateAccount(meta.AccountInfo{ID: "u0", NamespaceID: "n0", Username: "susy"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateAccount(meta.AccountInfo{ID: "u1", NamespaceID: "n1", Username: "bob"}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateOrganization(meta.OrganizationInfo{ID: "o0", NamespaceID: "n2"}, "acme", "u0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.AddOrUpdateMembership("o0", "u1", meta.MemberRoleMember, t0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.CreateApiToken(meta.ApiTokenInfo{UserID: "u0", TokenHash: "h0"}); err != meta.ErrApiTokenIDRequired {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateApiToken(meta.ApiTokenInfo{ID: "k0", UserID: "u0", TokenHash: "h0", Scopes: []string{"messages:delete"}}); err != meta.ErrInvalidApiTokenScope {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateApiToken(meta.ApiTokenInfo{ID: "k0", UserID: "u0", TokenHash: "h0", Scopes: []string{meta.ApiTokenScopeReadMessages}}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateApiToken(meta.ApiTokenInfo{ID: "k1", UserID: "u0", TokenHash: "h0"}); err != meta.ErrApiTokenExists {
+		t.Fatalf("unexpected error: %s", err)
+	} else if ti := data.ApiTokenByHash("h0"); ti == nil || ti.ID != "k0" || !ti.HasScope(meta.ApiTokenScopeReadMessages) || ti.HasScope(meta.ApiTokenScopeWriteMessages) {
+		t.Fatalf("unexpected token: %#v", ti)
+	}
+
+	// Integration tokens are created by members of the organization.
+	if err := data.CreateApiToken(meta.ApiTokenInfo{ID: "k1", UserID: "u1", OrganizationID: "o1", TokenHash: "h1"}); err != meta.ErrOrganizationNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := data.CreateApiToken(meta.ApiTokenInfo{ID: "k1", UserID: "u1", OrganizationID: "o0", TokenHash: "h1", Scopes: []string{meta.ApiTokenScopeAdmin}}); err != nil {
+		t.Fatal(err)
+	} else if ti := data.ApiToken("k1"); !ti.HasScope(meta.ApiTokenScopeWriteMessages) {
+		t.Fatalf("expected admin scope to grant all scopes: %#v", ti)
+	}
+
+	if err := data.TouchApiToken("k1", t0); err != nil {
+		t.Fatal(err)
+	} else if err := data.TouchApiToken("k1", t0.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	} else if ti := data.ApiToken("k1"); !ti.LastUsedAt.Equal(t0) {
+		t.Fatalf("unexpected last used time: %v", ti.LastUsedAt)
+	}
+
+	// The integration tokens of a user are removed with the membership.
+	if err := data.RemoveMembership("o0", "u1"); err != nil {
+		t.Fatal(err)
+	} else if data.ApiToken("k1") != nil {
+		t.Fatal("expected integration token to be removed")
+	} else if err := data.DeleteApiToken("k0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.DeleteApiToken("k0"); err != meta.ErrApiTokenNotFound {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 // Ensure the data can be marshaled and unmarshaled.
 func TestData_MarshalBinary(t *testing.T) {
 	data := meta.Data{
@@ -659,6 +1201,47 @@ func TestData_MarshalBinary(t *testing.T) {
 				Privileges: map[string]sql.Privilege{"db0": sql.AllPrivileges},
 			},
 		},
+		ReadMarkers: []meta.ReadMarkerInfo{
+			{ConversationID: "c0", UserID: "u0", MessageID: "m0", Time: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		Attachments: []meta.AttachmentInfo{
+			{ID: "a0", ConversationID: "c0", MessageID: "m0", UploaderID: "u0", Filename: "a.txt", ContentType: "text/plain", Size: 3, MD5: "md5", SHA256: "sha", CreatedAt: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)},
+			{ID: "a1", ConversationID: "c0", UploaderID: "u0", Filename: "b.txt", CreatedAt: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		Participants: []meta.ParticipantInfo{
+			{ConversationID: "c0", UserID: "u0", JoinedAt: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), LastActivityAt: time.Date(2000, time.January, 2, 0, 0, 0, 0, time.UTC), Teams: []string{"t0"}},
+		},
+		Namespaces: []meta.NamespaceInfo{
+			{ID: "n0", Path: "susy", OwnerID: "u0", OwnerType: meta.NamespaceOwnerUser, Redirects: []string{"susan"}, CreatedAt: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)},
+			{ID: "n1", Path: "acme", OwnerID: "o0", OwnerType: meta.NamespaceOwnerOrganization, CreatedAt: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		Accounts: []meta.AccountInfo{
+			{ID: "u0", Username: "susy", NamespaceID: "n0", Hash: "ABC123", PrimaryEmail: "susy@example.com", Emails: []meta.EmailInfo{{Email: "susy@example.com", Confirmed: true, ConfirmedAt: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)}}, Identities: []meta.IdentityInfo{{Issuer: "https://idp.example.com", Subject: "s0", Email: "susy@example.com", CreatedAt: time.Unix(0, 100).UTC()}}},
+		},
+		Organizations: []meta.OrganizationInfo{
+			{ID: "o0", NamespaceID: "n1", Name: "Acme", BillingEmail: "billing@example.com"},
+		},
+		Members: []meta.MemberInfo{
+			{OrganizationID: "o0", UserID: "u0", Role: meta.MemberRoleOwner, State: meta.MemberStateActive, Published: true, InvitedBy: "u1", InviteEmail: "susy@example.com", InviteAcceptedAt: time.Unix(0, 100).UTC()},
+		},
+		Conversations: []meta.ConversationInfo{
+			{ID: "c0", NamespaceID: "n1", CreatorID: "u0", Title: "general", Type: "channel", Privacy: "public", RetentionMode: "days", RetentionValue: 30},
+		},
+		Devices: []meta.DeviceInfo{
+			{ID: "d0", UserID: "u0", Name: "phone", Platform: "ios", Token: "t0"},
+		},
+		Teams: []meta.TeamInfo{
+			{ID: "t0", OrganizationID: "o0", Name: "Owners", Type: meta.TeamTypeOwners, Members: []string{"u0"}, Conversations: []string{"c0"}},
+		},
+		Invitations: []meta.InvitationInfo{
+			{ID: "i0", OrganizationID: "o0", Email: "bob@example.com", Role: meta.MemberRoleMember, InvitedBy: "u0", TokenHash: "h0", SentAt: time.Unix(0, 100).UTC(), ExpiresAt: time.Unix(0, 200).UTC(), CreatedAt: time.Unix(0, 100).UTC()},
+		},
+		RevokedTokens: []meta.RevokedTokenInfo{
+			{ID: "j0", UserID: "u0", ExpiresAt: time.Unix(0, 300).UTC()},
+		},
+		ApiTokens: []meta.ApiTokenInfo{
+			{ID: "k0", Name: "bot", TokenHash: "h0", UserID: "u0", OrganizationID: "o0", Scopes: []string{meta.ApiTokenScopeWriteMessages}, RateLimit: 60, ExpiresAt: time.Unix(0, 400).UTC(), LastUsedAt: time.Unix(0, 100).UTC(), CreatedAt: time.Unix(0, 100).UTC()},
+		},
 	}
 
 	// Marshal the data struture.
@@ -681,5 +1264,38 @@ func TestData_MarshalBinary(t *testing.T) {
 		t.Fatalf("unexpected databases: %#v", other.Databases)
 	} else if !reflect.DeepEqual(data.Users, other.Users) {
 		t.Fatalf("unexpected users: %#v", other.Users)
+	} else if !reflect.DeepEqual(data.ReadMarkers, other.ReadMarkers) {
+		t.Fatalf("unexpected read markers: %#v", other.ReadMarkers)
+	} else if !reflect.DeepEqual(data.Attachments, other.Attachments) {
+		t.Fatalf("unexpected attachments: %#v", other.Attachments)
+	} else if !reflect.DeepEqual(data.Participants, other.Participants) {
+		t.Fatalf("unexpected participants: %#v", other.Participants)
+	} else if !reflect.DeepEqual(data.Namespaces, other.Namespaces) {
+		t.Fatalf("unexpected namespaces: %#v", other.Namespaces)
+	} else if !reflect.DeepEqual(data.Accounts, other.Accounts) {
+		t.Fatalf("unexpected accounts: %#v", other.Accounts)
+	} else if !reflect.DeepEqual(data.Organizations, other.Organizations) {
+		t.Fatalf("unexpected organizations: %#v", other.Organizations)
+	} else if !reflect.DeepEqual(data.Members, other.Members) {
+		t.Fatalf("unexpected members: %#v", other.Members)
+	} else if !reflect.DeepEqual(data.Conversations, other.Conversations) {
+		t.Fatalf("unexpected conversations: %#v", other.Conversations)
+	} else if !reflect.DeepEqual(data.Devices, other.Devices) {
+		t.Fatalf("unexpected devices: %#v", other.Devices)
+	} else if !reflect.DeepEqual(data.Invitations, other.Invitations) {
+		t.Fatalf("unexpected invitations: %#v", other.Invitations)
+	} else if !reflect.DeepEqual(data.Teams, other.Teams) {
+		t.Fatalf("unexpected teams: %#v", other.Teams)
+	} else if !reflect.DeepEqual(data.RevokedTokens, other.RevokedTokens) {
+		t.Fatalf("unexpected revoked tokens: %#v", other.RevokedTokens)
+	} else if !reflect.DeepEqual(data.ApiTokens, other.ApiTokens) {
+		t.Fatalf("unexpected api tokens: %#v", other.ApiTokens)
+	}
+
+	// Ensure the lookup indexes are rebuilt.
+	if ai := other.AccountByEmail("susy@example.com"); ai == nil || ai.ID != "u0" {
+		t.Fatalf("unexpected account: %#v", ai)
+	} else if ni, _ := other.ResolveNamespace("susan"); ni == nil || ni.ID != "n0" {
+		t.Fatalf("unexpected namespace: %#v", ni)
 	}
 }
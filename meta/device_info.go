@@ -0,0 +1,46 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// DeviceInfo represents a client device registered to a user account.
+type DeviceInfo struct {
+	ID        string
+	UserID    string
+	Name      string
+	Platform  string
+	Token     string // push notification token
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// clone returns a deep copy of di.
+func (di DeviceInfo) clone() DeviceInfo { return di }
+
+// marshal serializes to a protobuf representation.
+func (di DeviceInfo) marshal() *internal.DeviceInfo {
+	return &internal.DeviceInfo{
+		ID:        proto.String(di.ID),
+		UserID:    proto.String(di.UserID),
+		Name:      proto.String(di.Name),
+		Platform:  proto.String(di.Platform),
+		Token:     proto.String(di.Token),
+		CreatedAt: proto.Int64(MarshalTime(di.CreatedAt)),
+		UpdatedAt: proto.Int64(MarshalTime(di.UpdatedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (di *DeviceInfo) unmarshal(pb *internal.DeviceInfo) {
+	di.ID = pb.GetID()
+	di.UserID = pb.GetUserID()
+	di.Name = pb.GetName()
+	di.Platform = pb.GetPlatform()
+	di.Token = pb.GetToken()
+	di.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+	di.UpdatedAt = UnmarshalTime(pb.GetUpdatedAt())
+}
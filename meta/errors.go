@@ -90,10 +90,193 @@ var (
 	ErrUsernameRequired = errors.New("username required")
 )
 
+var (
+	// ErrConversationIDRequired is returned when setting a read marker without a conversation.
+	ErrConversationIDRequired = errors.New("conversation id required")
+
+	// ErrUserIDRequired is returned when setting a read marker without a user.
+	ErrUserIDRequired = errors.New("user id required")
+
+	// ErrAttachmentIDRequired is returned when creating an attachment without an ID.
+	ErrAttachmentIDRequired = errors.New("attachment id required")
+
+	// ErrAttachmentExists is returned when creating an attachment that already exists.
+	ErrAttachmentExists = errors.New("attachment already exists")
+
+	// ErrAttachmentNotFound is returned when mutating an attachment that doesn't exist.
+	ErrAttachmentNotFound = errors.New("attachment not found")
+
+	// ErrAttachmentLinked is returned when linking an attachment that was sent with another message.
+	ErrAttachmentLinked = errors.New("attachment linked to another message")
+
+	// ErrParticipantExists is returned when adding a user that already participates in a conversation.
+	ErrParticipantExists = errors.New("participant already exists")
+
+	// ErrParticipantNotFound is returned when mutating a participant that doesn't exist.
+	ErrParticipantNotFound = errors.New("participant not found")
+)
+
+var (
+	// ErrNamespaceIDRequired is returned when creating a namespace without an ID.
+	ErrNamespaceIDRequired = errors.New("namespace id required")
+
+	// ErrInvalidNamespacePath is returned when creating a namespace with a path that cannot be used in a URL.
+	ErrInvalidNamespacePath = errors.New("invalid namespace path")
+
+	// ErrNamespaceExists is returned when creating a namespace with a path that is already taken.
+	ErrNamespaceExists = errors.New("namespace already exists")
+
+	// ErrNamespaceNotFound is returned when referencing a namespace that doesn't exist.
+	ErrNamespaceNotFound = errors.New("namespace not found")
+
+	// ErrAccountExists is returned when creating an already existing account.
+	ErrAccountExists = errors.New("account already exists")
+
+	// ErrAccountNotFound is returned when referencing an account that doesn't exist.
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrEmailExists is returned when registering an email address that belongs to another account.
+	ErrEmailExists = errors.New("email already exists")
+
+	// ErrIdentityRequired is returned when linking an identity without an issuer or a subject.
+	ErrIdentityRequired = errors.New("identity issuer and subject required")
+
+	// ErrIdentityExists is returned when linking an identity that is linked to another account.
+	ErrIdentityExists = errors.New("identity already exists")
+)
+
+var (
+	// ErrOrganizationIDRequired is returned when creating an organization without an ID.
+	ErrOrganizationIDRequired = errors.New("organization id required")
+
+	// ErrOrganizationExists is returned when creating an already existing organization.
+	ErrOrganizationExists = errors.New("organization already exists")
+
+	// ErrOrganizationNotFound is returned when referencing an organization that doesn't exist.
+	ErrOrganizationNotFound = errors.New("organization not found")
+
+	// ErrOrganizationNotEmpty is returned when dropping an organization that still has conversations.
+	ErrOrganizationNotEmpty = errors.New("organization has conversations")
+
+	// ErrMemberNotFound is returned when mutating a membership that doesn't exist.
+	ErrMemberNotFound = errors.New("member not found")
+
+	// ErrInvalidMemberRole is returned when setting an unknown membership role.
+	ErrInvalidMemberRole = errors.New("invalid member role")
+
+	// ErrInvalidMemberState is returned when setting an unknown membership state.
+	ErrInvalidMemberState = errors.New("invalid member state")
+
+	// ErrLastOrganizationOwner is returned when removing or demoting the last owner of an organization.
+	ErrLastOrganizationOwner = errors.New("organization must have an owner")
+
+	// ErrMemberExists is returned when inviting a user that is already a member of an organization.
+	ErrMemberExists = errors.New("member already exists")
+
+	// ErrInvitationIDRequired is returned when creating an invitation without an ID.
+	ErrInvitationIDRequired = errors.New("invitation id required")
+
+	// ErrInvitationEmailRequired is returned when creating an invitation without an email address.
+	ErrInvitationEmailRequired = errors.New("invitation email required")
+
+	// ErrInvitationExists is returned when inviting an email address that already has a pending invitation.
+	ErrInvitationExists = errors.New("invitation already exists")
+
+	// ErrInvitationNotFound is returned when referencing an invitation that doesn't exist.
+	ErrInvitationNotFound = errors.New("invitation not found")
+
+	// ErrInvitationExpired is returned when accepting an invitation after its expiry.
+	ErrInvitationExpired = errors.New("invitation expired")
+)
+
+var (
+	// ErrTeamIDRequired is returned when creating a team without an ID.
+	ErrTeamIDRequired = errors.New("team id required")
+
+	// ErrTeamNameRequired is returned when creating a team without a name.
+	ErrTeamNameRequired = errors.New("team name required")
+
+	// ErrTeamExists is returned when creating a team with the name of another team of the organization,
+	// or a second owners team.
+	ErrTeamExists = errors.New("team already exists")
+
+	// ErrTeamNotFound is returned when mutating a team that doesn't exist.
+	ErrTeamNotFound = errors.New("team not found")
+
+	// ErrInvalidTeamType is returned when creating a team of an unknown type.
+	ErrInvalidTeamType = errors.New("invalid team type")
+
+	// ErrTeamNotDeletable is returned when dropping the owners or the admins team of an organization.
+	ErrTeamNotDeletable = errors.New("team cannot be deleted")
+
+	// ErrTeamHasMembers is returned when dropping a team that still has members.
+	ErrTeamHasMembers = errors.New("team has members")
+
+	// ErrTeamMemberNotFound is returned when removing a user that is not a member of a team.
+	ErrTeamMemberNotFound = errors.New("team member not found")
+
+	// ErrTeamConversationNotFound is returned when revoking a conversation that is not granted to a team.
+	ErrTeamConversationNotFound = errors.New("team conversation not found")
+)
+
+var (
+	// ErrConversationExists is returned when creating an already existing conversation.
+	ErrConversationExists = errors.New("conversation already exists")
+
+	// ErrConversationNotFound is returned when mutating a conversation that doesn't exist.
+	ErrConversationNotFound = errors.New("conversation not found")
+
+	// ErrDeviceIDRequired is returned when adding a device without an ID.
+	ErrDeviceIDRequired = errors.New("device id required")
+
+	// ErrDeviceExists is returned when adding an already existing device.
+	ErrDeviceExists = errors.New("device already exists")
+
+	// ErrDeviceNotFound is returned when mutating a device that doesn't exist.
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+var (
+	// ErrTokenIDRequired is returned when revoking a token without an ID.
+	ErrTokenIDRequired = errors.New("token id required")
+
+	// ErrTokenRevoked is returned when revoking a token that is already revoked.
+	ErrTokenRevoked = errors.New("token already revoked")
+)
+
+var (
+	// ErrApiTokenIDRequired is returned when creating an API token without an ID.
+	ErrApiTokenIDRequired = errors.New("api token id required")
+
+	// ErrApiTokenExists is returned when creating an already existing API token.
+	ErrApiTokenExists = errors.New("api token already exists")
+
+	// ErrApiTokenNotFound is returned when mutating an API token that doesn't exist.
+	ErrApiTokenNotFound = errors.New("api token not found")
+
+	// ErrInvalidApiTokenScope is returned when creating an API token with an unknown scope.
+	ErrInvalidApiTokenScope = errors.New("invalid api token scope")
+)
+
 var errs = [...]error{
 	ErrStoreOpen, ErrStoreClosed,
 	ErrNodeExists, ErrNodeNotFound,
 	ErrDatabaseExists, ErrDatabaseNotFound, ErrDatabaseNameRequired,
+	ErrAttachmentIDRequired, ErrAttachmentExists, ErrAttachmentNotFound, ErrAttachmentLinked,
+	ErrParticipantExists, ErrParticipantNotFound,
+	ErrNamespaceIDRequired, ErrInvalidNamespacePath, ErrNamespaceExists, ErrNamespaceNotFound,
+	ErrAccountExists, ErrAccountNotFound, ErrEmailExists, ErrUsernameRequired, ErrUserIDRequired,
+	ErrIdentityRequired, ErrIdentityExists,
+	ErrOrganizationIDRequired, ErrOrganizationExists, ErrOrganizationNotFound, ErrOrganizationNotEmpty,
+	ErrMemberNotFound, ErrInvalidMemberRole, ErrInvalidMemberState, ErrLastOrganizationOwner,
+	ErrMemberExists, ErrInvitationIDRequired, ErrInvitationEmailRequired, ErrInvitationExists,
+	ErrInvitationNotFound, ErrInvitationExpired,
+	ErrTeamIDRequired, ErrTeamNameRequired, ErrTeamExists, ErrTeamNotFound, ErrInvalidTeamType,
+	ErrTeamNotDeletable, ErrTeamHasMembers, ErrTeamMemberNotFound, ErrTeamConversationNotFound,
+	ErrConversationIDRequired, ErrConversationExists, ErrConversationNotFound,
+	ErrDeviceIDRequired, ErrDeviceExists, ErrDeviceNotFound,
+	ErrTokenIDRequired, ErrTokenRevoked,
+	ErrApiTokenIDRequired, ErrApiTokenExists, ErrApiTokenNotFound, ErrInvalidApiTokenScope,
 }
 
 // errLookup stores a mapping of error strings to well defined error types.
@@ -6,9 +6,11 @@
 Package internal is a generated protocol buffer package.
 
 It is generated from these files:
+
 	internal/meta.proto
 
 It has these top-level messages:
+
 	Data
 	NodeInfo
 	DatabaseInfo
@@ -17,6 +19,21 @@ It has these top-level messages:
 	ShardInfo
 	UserInfo
 	UserPrivilege
+	ReadMarkerInfo
+	AttachmentInfo
+	ParticipantInfo
+	NamespaceInfo
+	EmailInfo
+	AccountInfo
+	OrganizationInfo
+	MemberInfo
+	ConversationInfo
+	DeviceInfo
+	InvitationInfo
+	TeamInfo
+	RevokedTokenInfo
+	ApiTokenInfo
+	IdentityInfo
 	Command
 	CreateNodeCommand
 	DeleteNodeCommand
@@ -34,6 +51,46 @@ It has these top-level messages:
 	SetPrivilegeCommand
 	SetDataCommand
 	SetAdminPrivilegeCommand
+	SetReadMarkerCommand
+	CreateAttachmentCommand
+	SetAttachmentMessageCommand
+	DeleteAttachmentCommand
+	AddParticipantCommand
+	RemoveParticipantCommand
+	SetParticipantActivityCommand
+	CreateOrganizationCommand
+	DropOrganizationCommand
+	UpdateOrganizationCommand
+	AddOrUpdateMembershipCommand
+	RemoveMembershipCommand
+	EditMyMembershipCommand
+	CreateConversationCommand
+	DropConversationCommand
+	UpdateConversationCommand
+	PublicizeMembershipCommand
+	ConcealMembershipCommand
+	AddDeviceCommand
+	UpdateDeviceCommand
+	DeleteDeviceCommand
+	CreateAccountCommand
+	UpdateAccountCommand
+	RenameNamespaceCommand
+	CreateInvitationCommand
+	ResendInvitationCommand
+	AcceptInvitationCommand
+	DeleteInvitationCommand
+	CreateTeamCommand
+	UpdateTeamCommand
+	DropTeamCommand
+	AddTeamMemberCommand
+	RemoveTeamMemberCommand
+	GrantTeamConversationCommand
+	RevokeTeamConversationCommand
+	RevokeTokenCommand
+	CreateApiTokenCommand
+	DeleteApiTokenCommand
+	TouchApiTokenCommand
+	LinkIdentityCommand
 	Response
 */
 package internal
@@ -78,6 +135,32 @@ const (
 	Command_UpdateDeviceCommand              Command_Type = 28
 	Command_DeleteDeviceCommand              Command_Type = 29
 	Command_SetAdminPrivilegeCommand         Command_Type = 30
+	Command_SetReadMarkerCommand             Command_Type = 31
+	Command_CreateAttachmentCommand          Command_Type = 32
+	Command_SetAttachmentMessageCommand      Command_Type = 33
+	Command_DeleteAttachmentCommand          Command_Type = 34
+	Command_AddParticipantCommand            Command_Type = 35
+	Command_RemoveParticipantCommand         Command_Type = 36
+	Command_SetParticipantActivityCommand    Command_Type = 37
+	Command_CreateAccountCommand             Command_Type = 38
+	Command_UpdateAccountCommand             Command_Type = 39
+	Command_RenameNamespaceCommand           Command_Type = 40
+	Command_CreateInvitationCommand          Command_Type = 41
+	Command_ResendInvitationCommand          Command_Type = 42
+	Command_AcceptInvitationCommand          Command_Type = 43
+	Command_DeleteInvitationCommand          Command_Type = 44
+	Command_CreateTeamCommand                Command_Type = 45
+	Command_UpdateTeamCommand                Command_Type = 46
+	Command_DropTeamCommand                  Command_Type = 47
+	Command_AddTeamMemberCommand             Command_Type = 48
+	Command_RemoveTeamMemberCommand          Command_Type = 49
+	Command_GrantTeamConversationCommand     Command_Type = 50
+	Command_RevokeTeamConversationCommand    Command_Type = 51
+	Command_RevokeTokenCommand               Command_Type = 52
+	Command_CreateApiTokenCommand            Command_Type = 53
+	Command_DeleteApiTokenCommand            Command_Type = 54
+	Command_TouchApiTokenCommand             Command_Type = 55
+	Command_LinkIdentityCommand              Command_Type = 56
 )
 
 var Command_Type_name = map[int32]string{
@@ -111,6 +194,32 @@ var Command_Type_name = map[int32]string{
 	28: "UpdateDeviceCommand",
 	29: "DeleteDeviceCommand",
 	30: "SetAdminPrivilegeCommand",
+	31: "SetReadMarkerCommand",
+	32: "CreateAttachmentCommand",
+	33: "SetAttachmentMessageCommand",
+	34: "DeleteAttachmentCommand",
+	35: "AddParticipantCommand",
+	36: "RemoveParticipantCommand",
+	37: "SetParticipantActivityCommand",
+	38: "CreateAccountCommand",
+	39: "UpdateAccountCommand",
+	40: "RenameNamespaceCommand",
+	41: "CreateInvitationCommand",
+	42: "ResendInvitationCommand",
+	43: "AcceptInvitationCommand",
+	44: "DeleteInvitationCommand",
+	45: "CreateTeamCommand",
+	46: "UpdateTeamCommand",
+	47: "DropTeamCommand",
+	48: "AddTeamMemberCommand",
+	49: "RemoveTeamMemberCommand",
+	50: "GrantTeamConversationCommand",
+	51: "RevokeTeamConversationCommand",
+	52: "RevokeTokenCommand",
+	53: "CreateApiTokenCommand",
+	54: "DeleteApiTokenCommand",
+	55: "TouchApiTokenCommand",
+	56: "LinkIdentityCommand",
 }
 var Command_Type_value = map[string]int32{
 	"CreateNodeCommand":                1,
@@ -143,6 +252,32 @@ var Command_Type_value = map[string]int32{
 	"UpdateDeviceCommand":              28,
 	"DeleteDeviceCommand":              29,
 	"SetAdminPrivilegeCommand":         30,
+	"SetReadMarkerCommand":             31,
+	"CreateAttachmentCommand":          32,
+	"SetAttachmentMessageCommand":      33,
+	"DeleteAttachmentCommand":          34,
+	"AddParticipantCommand":            35,
+	"RemoveParticipantCommand":         36,
+	"SetParticipantActivityCommand":    37,
+	"CreateAccountCommand":             38,
+	"UpdateAccountCommand":             39,
+	"RenameNamespaceCommand":           40,
+	"CreateInvitationCommand":          41,
+	"ResendInvitationCommand":          42,
+	"AcceptInvitationCommand":          43,
+	"DeleteInvitationCommand":          44,
+	"CreateTeamCommand":                45,
+	"UpdateTeamCommand":                46,
+	"DropTeamCommand":                  47,
+	"AddTeamMemberCommand":             48,
+	"RemoveTeamMemberCommand":          49,
+	"GrantTeamConversationCommand":     50,
+	"RevokeTeamConversationCommand":    51,
+	"RevokeTokenCommand":               52,
+	"CreateApiTokenCommand":            53,
+	"DeleteApiTokenCommand":            54,
+	"TouchApiTokenCommand":             55,
+	"LinkIdentityCommand":              56,
 }
 
 func (x Command_Type) Enum() *Command_Type {
@@ -163,16 +298,29 @@ func (x *Command_Type) UnmarshalJSON(data []byte) error {
 }
 
 type Data struct {
-	Term             *uint64         `protobuf:"varint,1,req" json:"Term,omitempty"`
-	Index            *uint64         `protobuf:"varint,2,req" json:"Index,omitempty"`
-	ClusterID        *uint64         `protobuf:"varint,3,req" json:"ClusterID,omitempty"`
-	Nodes            []*NodeInfo     `protobuf:"bytes,4,rep" json:"Nodes,omitempty"`
-	Databases        []*DatabaseInfo `protobuf:"bytes,5,rep" json:"Databases,omitempty"`
-	Users            []*UserInfo     `protobuf:"bytes,6,rep" json:"Users,omitempty"`
-	MaxNodeID        *uint64         `protobuf:"varint,7,req" json:"MaxNodeID,omitempty"`
-	MaxShardGroupID  *uint64         `protobuf:"varint,8,req" json:"MaxShardGroupID,omitempty"`
-	MaxShardID       *uint64         `protobuf:"varint,9,req" json:"MaxShardID,omitempty"`
-	XXX_unrecognized []byte          `json:"-"`
+	Term             *uint64             `protobuf:"varint,1,req" json:"Term,omitempty"`
+	Index            *uint64             `protobuf:"varint,2,req" json:"Index,omitempty"`
+	ClusterID        *uint64             `protobuf:"varint,3,req" json:"ClusterID,omitempty"`
+	Nodes            []*NodeInfo         `protobuf:"bytes,4,rep" json:"Nodes,omitempty"`
+	Databases        []*DatabaseInfo     `protobuf:"bytes,5,rep" json:"Databases,omitempty"`
+	Users            []*UserInfo         `protobuf:"bytes,6,rep" json:"Users,omitempty"`
+	MaxNodeID        *uint64             `protobuf:"varint,7,req" json:"MaxNodeID,omitempty"`
+	MaxShardGroupID  *uint64             `protobuf:"varint,8,req" json:"MaxShardGroupID,omitempty"`
+	MaxShardID       *uint64             `protobuf:"varint,9,req" json:"MaxShardID,omitempty"`
+	ReadMarkers      []*ReadMarkerInfo   `protobuf:"bytes,10,rep" json:"ReadMarkers,omitempty"`
+	Attachments      []*AttachmentInfo   `protobuf:"bytes,11,rep" json:"Attachments,omitempty"`
+	Participants     []*ParticipantInfo  `protobuf:"bytes,12,rep" json:"Participants,omitempty"`
+	Namespaces       []*NamespaceInfo    `protobuf:"bytes,13,rep" json:"Namespaces,omitempty"`
+	Accounts         []*AccountInfo      `protobuf:"bytes,14,rep" json:"Accounts,omitempty"`
+	Organizations    []*OrganizationInfo `protobuf:"bytes,15,rep" json:"Organizations,omitempty"`
+	Members          []*MemberInfo       `protobuf:"bytes,16,rep" json:"Members,omitempty"`
+	Conversations    []*ConversationInfo `protobuf:"bytes,17,rep" json:"Conversations,omitempty"`
+	Devices          []*DeviceInfo       `protobuf:"bytes,18,rep" json:"Devices,omitempty"`
+	Invitations      []*InvitationInfo   `protobuf:"bytes,19,rep" json:"Invitations,omitempty"`
+	Teams            []*TeamInfo         `protobuf:"bytes,20,rep" json:"Teams,omitempty"`
+	RevokedTokens    []*RevokedTokenInfo `protobuf:"bytes,21,rep" json:"RevokedTokens,omitempty"`
+	ApiTokens        []*ApiTokenInfo     `protobuf:"bytes,22,rep" json:"ApiTokens,omitempty"`
+	XXX_unrecognized []byte              `json:"-"`
 }
 
 func (m *Data) Reset()         { *m = Data{} }
@@ -242,6 +390,97 @@ func (m *Data) GetMaxShardID() uint64 {
 	return 0
 }
 
+func (m *Data) GetReadMarkers() []*ReadMarkerInfo {
+	if m != nil {
+		return m.ReadMarkers
+	}
+	return nil
+}
+
+func (m *Data) GetAttachments() []*AttachmentInfo {
+	if m != nil {
+		return m.Attachments
+	}
+	return nil
+}
+
+func (m *Data) GetParticipants() []*ParticipantInfo {
+	if m != nil {
+		return m.Participants
+	}
+	return nil
+}
+
+func (m *Data) GetNamespaces() []*NamespaceInfo {
+	if m != nil {
+		return m.Namespaces
+	}
+	return nil
+}
+
+func (m *Data) GetAccounts() []*AccountInfo {
+	if m != nil {
+		return m.Accounts
+	}
+	return nil
+}
+
+func (m *Data) GetOrganizations() []*OrganizationInfo {
+	if m != nil {
+		return m.Organizations
+	}
+	return nil
+}
+
+func (m *Data) GetMembers() []*MemberInfo {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *Data) GetConversations() []*ConversationInfo {
+	if m != nil {
+		return m.Conversations
+	}
+	return nil
+}
+
+func (m *Data) GetDevices() []*DeviceInfo {
+	if m != nil {
+		return m.Devices
+	}
+	return nil
+}
+
+func (m *Data) GetInvitations() []*InvitationInfo {
+	if m != nil {
+		return m.Invitations
+	}
+	return nil
+}
+
+func (m *Data) GetTeams() []*TeamInfo {
+	if m != nil {
+		return m.Teams
+	}
+	return nil
+}
+
+func (m *Data) GetRevokedTokens() []*RevokedTokenInfo {
+	if m != nil {
+		return m.RevokedTokens
+	}
+	return nil
+}
+
+func (m *Data) GetApiTokens() []*ApiTokenInfo {
+	if m != nil {
+		return m.ApiTokens
+	}
+	return nil
+}
+
 type NodeInfo struct {
 	ID               *uint64 `protobuf:"varint,1,req" json:"ID,omitempty"`
 	Host             *string `protobuf:"bytes,2,req" json:"Host,omitempty"`
@@ -482,563 +721,2891 @@ func (m *UserPrivilege) GetPrivilege() int32 {
 	return 0
 }
 
-type Command struct {
-	Type             *Command_Type             `protobuf:"varint,1,req,name=type,enum=internal.Command_Type" json:"type,omitempty"`
-	XXX_extensions   map[int32]proto.Extension `json:"-"`
-	XXX_unrecognized []byte                    `json:"-"`
+type ReadMarkerInfo struct {
+	ConversationID   *string `protobuf:"bytes,1,req" json:"ConversationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	MessageID        *string `protobuf:"bytes,3,req" json:"MessageID,omitempty"`
+	Time             *int64  `protobuf:"varint,4,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *Command) Reset()         { *m = Command{} }
-func (m *Command) String() string { return proto.CompactTextString(m) }
-func (*Command) ProtoMessage()    {}
+func (m *ReadMarkerInfo) Reset()         { *m = ReadMarkerInfo{} }
+func (m *ReadMarkerInfo) String() string { return proto.CompactTextString(m) }
+func (*ReadMarkerInfo) ProtoMessage()    {}
 
-var extRange_Command = []proto.ExtensionRange{
-	{100, 536870911},
+func (m *ReadMarkerInfo) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
+	}
+	return ""
 }
 
-func (*Command) ExtensionRangeArray() []proto.ExtensionRange {
-	return extRange_Command
+func (m *ReadMarkerInfo) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
 }
-func (m *Command) ExtensionMap() map[int32]proto.Extension {
-	if m.XXX_extensions == nil {
-		m.XXX_extensions = make(map[int32]proto.Extension)
+
+func (m *ReadMarkerInfo) GetMessageID() string {
+	if m != nil && m.MessageID != nil {
+		return *m.MessageID
 	}
-	return m.XXX_extensions
+	return ""
 }
 
-func (m *Command) GetType() Command_Type {
-	if m != nil && m.Type != nil {
-		return *m.Type
+func (m *ReadMarkerInfo) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
 	}
-	return Command_CreateNodeCommand
+	return 0
 }
 
-type CreateNodeCommand struct {
-	Host             *string `protobuf:"bytes,1,req" json:"Host,omitempty"`
-	Rand             *uint64 `protobuf:"varint,2,req" json:"Rand,omitempty"`
+type AttachmentInfo struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	ConversationID   *string `protobuf:"bytes,2,req" json:"ConversationID,omitempty"`
+	MessageID        *string `protobuf:"bytes,3,opt" json:"MessageID,omitempty"`
+	UploaderID       *string `protobuf:"bytes,4,req" json:"UploaderID,omitempty"`
+	Filename         *string `protobuf:"bytes,5,req" json:"Filename,omitempty"`
+	ContentType      *string `protobuf:"bytes,6,req" json:"ContentType,omitempty"`
+	Size             *int64  `protobuf:"varint,7,req" json:"Size,omitempty"`
+	MD5              *string `protobuf:"bytes,8,req" json:"MD5,omitempty"`
+	SHA256           *string `protobuf:"bytes,9,req" json:"SHA256,omitempty"`
+	CreatedAt        *int64  `protobuf:"varint,10,req" json:"CreatedAt,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *CreateNodeCommand) Reset()         { *m = CreateNodeCommand{} }
-func (m *CreateNodeCommand) String() string { return proto.CompactTextString(m) }
-func (*CreateNodeCommand) ProtoMessage()    {}
+func (m *AttachmentInfo) Reset()         { *m = AttachmentInfo{} }
+func (m *AttachmentInfo) String() string { return proto.CompactTextString(m) }
+func (*AttachmentInfo) ProtoMessage()    {}
 
-func (m *CreateNodeCommand) GetHost() string {
-	if m != nil && m.Host != nil {
-		return *m.Host
+func (m *AttachmentInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
 	}
 	return ""
 }
 
-func (m *CreateNodeCommand) GetRand() uint64 {
-	if m != nil && m.Rand != nil {
-		return *m.Rand
+func (m *AttachmentInfo) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
 	}
-	return 0
+	return ""
 }
 
-var E_CreateNodeCommand_Command = &proto.ExtensionDesc{
-	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*CreateNodeCommand)(nil),
-	Field:         101,
-	Name:          "internal.CreateNodeCommand.command",
-	Tag:           "bytes,101,opt,name=command",
+func (m *AttachmentInfo) GetMessageID() string {
+	if m != nil && m.MessageID != nil {
+		return *m.MessageID
+	}
+	return ""
 }
 
-type DeleteNodeCommand struct {
-	ID               *uint64 `protobuf:"varint,1,req" json:"ID,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+func (m *AttachmentInfo) GetUploaderID() string {
+	if m != nil && m.UploaderID != nil {
+		return *m.UploaderID
+	}
+	return ""
 }
 
-func (m *DeleteNodeCommand) Reset()         { *m = DeleteNodeCommand{} }
-func (m *DeleteNodeCommand) String() string { return proto.CompactTextString(m) }
-func (*DeleteNodeCommand) ProtoMessage()    {}
-
-func (m *DeleteNodeCommand) GetID() uint64 {
-	if m != nil && m.ID != nil {
-		return *m.ID
+func (m *AttachmentInfo) GetFilename() string {
+	if m != nil && m.Filename != nil {
+		return *m.Filename
 	}
-	return 0
+	return ""
 }
 
-var E_DeleteNodeCommand_Command = &proto.ExtensionDesc{
-	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*DeleteNodeCommand)(nil),
-	Field:         102,
-	Name:          "internal.DeleteNodeCommand.command",
-	Tag:           "bytes,102,opt,name=command",
+func (m *AttachmentInfo) GetContentType() string {
+	if m != nil && m.ContentType != nil {
+		return *m.ContentType
+	}
+	return ""
 }
 
-type CreateDatabaseCommand struct {
-	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+func (m *AttachmentInfo) GetSize() int64 {
+	if m != nil && m.Size != nil {
+		return *m.Size
+	}
+	return 0
 }
 
-func (m *CreateDatabaseCommand) Reset()         { *m = CreateDatabaseCommand{} }
-func (m *CreateDatabaseCommand) String() string { return proto.CompactTextString(m) }
-func (*CreateDatabaseCommand) ProtoMessage()    {}
+func (m *AttachmentInfo) GetMD5() string {
+	if m != nil && m.MD5 != nil {
+		return *m.MD5
+	}
+	return ""
+}
 
-func (m *CreateDatabaseCommand) GetName() string {
-	if m != nil && m.Name != nil {
-		return *m.Name
+func (m *AttachmentInfo) GetSHA256() string {
+	if m != nil && m.SHA256 != nil {
+		return *m.SHA256
 	}
 	return ""
 }
 
-var E_CreateDatabaseCommand_Command = &proto.ExtensionDesc{
-	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*CreateDatabaseCommand)(nil),
-	Field:         103,
-	Name:          "internal.CreateDatabaseCommand.command",
-	Tag:           "bytes,103,opt,name=command",
+func (m *AttachmentInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
 }
 
-type DropDatabaseCommand struct {
-	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+type ParticipantInfo struct {
+	ConversationID   *string  `protobuf:"bytes,1,req" json:"ConversationID,omitempty"`
+	UserID           *string  `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	JoinedAt         *int64   `protobuf:"varint,3,req" json:"JoinedAt,omitempty"`
+	LastActivityAt   *int64   `protobuf:"varint,4,req" json:"LastActivityAt,omitempty"`
+	Teams            []string `protobuf:"bytes,5,rep" json:"Teams,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
-func (m *DropDatabaseCommand) Reset()         { *m = DropDatabaseCommand{} }
-func (m *DropDatabaseCommand) String() string { return proto.CompactTextString(m) }
-func (*DropDatabaseCommand) ProtoMessage()    {}
+func (m *ParticipantInfo) Reset()         { *m = ParticipantInfo{} }
+func (m *ParticipantInfo) String() string { return proto.CompactTextString(m) }
+func (*ParticipantInfo) ProtoMessage()    {}
 
-func (m *DropDatabaseCommand) GetName() string {
-	if m != nil && m.Name != nil {
-		return *m.Name
+func (m *ParticipantInfo) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
 	}
 	return ""
 }
 
-var E_DropDatabaseCommand_Command = &proto.ExtensionDesc{
-	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*DropDatabaseCommand)(nil),
-	Field:         104,
-	Name:          "internal.DropDatabaseCommand.command",
-	Tag:           "bytes,104,opt,name=command",
+func (m *ParticipantInfo) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
 }
 
-type CreateRetentionPolicyCommand struct {
-	Database         *string              `protobuf:"bytes,1,req" json:"Database,omitempty"`
-	RetentionPolicy  *RetentionPolicyInfo `protobuf:"bytes,2,req" json:"RetentionPolicy,omitempty"`
-	XXX_unrecognized []byte               `json:"-"`
+func (m *ParticipantInfo) GetJoinedAt() int64 {
+	if m != nil && m.JoinedAt != nil {
+		return *m.JoinedAt
+	}
+	return 0
 }
 
-func (m *CreateRetentionPolicyCommand) Reset()         { *m = CreateRetentionPolicyCommand{} }
-func (m *CreateRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
-func (*CreateRetentionPolicyCommand) ProtoMessage()    {}
-
-func (m *CreateRetentionPolicyCommand) GetDatabase() string {
-	if m != nil && m.Database != nil {
-		return *m.Database
+func (m *ParticipantInfo) GetLastActivityAt() int64 {
+	if m != nil && m.LastActivityAt != nil {
+		return *m.LastActivityAt
 	}
-	return ""
+	return 0
 }
 
-func (m *CreateRetentionPolicyCommand) GetRetentionPolicy() *RetentionPolicyInfo {
+func (m *ParticipantInfo) GetTeams() []string {
 	if m != nil {
-		return m.RetentionPolicy
+		return m.Teams
 	}
 	return nil
 }
 
-var E_CreateRetentionPolicyCommand_Command = &proto.ExtensionDesc{
-	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*CreateRetentionPolicyCommand)(nil),
-	Field:         105,
-	Name:          "internal.CreateRetentionPolicyCommand.command",
-	Tag:           "bytes,105,opt,name=command",
+type NamespaceInfo struct {
+	ID               *string  `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	Path             *string  `protobuf:"bytes,2,req" json:"Path,omitempty"`
+	OwnerID          *string  `protobuf:"bytes,3,req" json:"OwnerID,omitempty"`
+	OwnerType        *string  `protobuf:"bytes,4,req" json:"OwnerType,omitempty"`
+	CreatedAt        *int64   `protobuf:"varint,5,req" json:"CreatedAt,omitempty"`
+	UpdatedAt        *int64   `protobuf:"varint,6,req" json:"UpdatedAt,omitempty"`
+	Redirects        []string `protobuf:"bytes,7,rep" json:"Redirects,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
-type DropRetentionPolicyCommand struct {
-	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
-	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+func (m *NamespaceInfo) Reset()         { *m = NamespaceInfo{} }
+func (m *NamespaceInfo) String() string { return proto.CompactTextString(m) }
+func (*NamespaceInfo) ProtoMessage()    {}
+
+func (m *NamespaceInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
 }
 
-func (m *DropRetentionPolicyCommand) Reset()         { *m = DropRetentionPolicyCommand{} }
-func (m *DropRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
-func (*DropRetentionPolicyCommand) ProtoMessage()    {}
+func (m *NamespaceInfo) GetPath() string {
+	if m != nil && m.Path != nil {
+		return *m.Path
+	}
+	return ""
+}
 
-func (m *DropRetentionPolicyCommand) GetDatabase() string {
-	if m != nil && m.Database != nil {
-		return *m.Database
+func (m *NamespaceInfo) GetOwnerID() string {
+	if m != nil && m.OwnerID != nil {
+		return *m.OwnerID
 	}
 	return ""
 }
 
-func (m *DropRetentionPolicyCommand) GetName() string {
-	if m != nil && m.Name != nil {
-		return *m.Name
+func (m *NamespaceInfo) GetOwnerType() string {
+	if m != nil && m.OwnerType != nil {
+		return *m.OwnerType
 	}
 	return ""
 }
 
-var E_DropRetentionPolicyCommand_Command = &proto.ExtensionDesc{
-	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*DropRetentionPolicyCommand)(nil),
-	Field:         106,
-	Name:          "internal.DropRetentionPolicyCommand.command",
-	Tag:           "bytes,106,opt,name=command",
+func (m *NamespaceInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
 }
 
-type SetDefaultRetentionPolicyCommand struct {
-	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
-	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+func (m *NamespaceInfo) GetUpdatedAt() int64 {
+	if m != nil && m.UpdatedAt != nil {
+		return *m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *NamespaceInfo) GetRedirects() []string {
+	if m != nil {
+		return m.Redirects
+	}
+	return nil
+}
+
+type EmailInfo struct {
+	Email            *string `protobuf:"bytes,1,req" json:"Email,omitempty"`
+	Confirmed        *bool   `protobuf:"varint,2,req" json:"Confirmed,omitempty"`
+	ConfirmedAt      *int64  `protobuf:"varint,3,opt" json:"ConfirmedAt,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *SetDefaultRetentionPolicyCommand) Reset()         { *m = SetDefaultRetentionPolicyCommand{} }
-func (m *SetDefaultRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
-func (*SetDefaultRetentionPolicyCommand) ProtoMessage()    {}
+func (m *EmailInfo) Reset()         { *m = EmailInfo{} }
+func (m *EmailInfo) String() string { return proto.CompactTextString(m) }
+func (*EmailInfo) ProtoMessage()    {}
 
-func (m *SetDefaultRetentionPolicyCommand) GetDatabase() string {
-	if m != nil && m.Database != nil {
-		return *m.Database
+func (m *EmailInfo) GetEmail() string {
+	if m != nil && m.Email != nil {
+		return *m.Email
 	}
 	return ""
 }
 
-func (m *SetDefaultRetentionPolicyCommand) GetName() string {
-	if m != nil && m.Name != nil {
-		return *m.Name
+func (m *EmailInfo) GetConfirmed() bool {
+	if m != nil && m.Confirmed != nil {
+		return *m.Confirmed
 	}
-	return ""
+	return false
+}
+
+func (m *EmailInfo) GetConfirmedAt() int64 {
+	if m != nil && m.ConfirmedAt != nil {
+		return *m.ConfirmedAt
+	}
+	return 0
+}
+
+type AccountInfo struct {
+	ID               *string         `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	Username         *string         `protobuf:"bytes,2,req" json:"Username,omitempty"`
+	NamespaceID      *string         `protobuf:"bytes,3,req" json:"NamespaceID,omitempty"`
+	GivenName        *string         `protobuf:"bytes,4,opt" json:"GivenName,omitempty"`
+	FamilyName       *string         `protobuf:"bytes,5,opt" json:"FamilyName,omitempty"`
+	Hash             *string         `protobuf:"bytes,6,req" json:"Hash,omitempty"`
+	PrimaryEmail     *string         `protobuf:"bytes,7,req" json:"PrimaryEmail,omitempty"`
+	Emails           []*EmailInfo    `protobuf:"bytes,8,rep" json:"Emails,omitempty"`
+	CreatedAt        *int64          `protobuf:"varint,9,req" json:"CreatedAt,omitempty"`
+	UpdatedAt        *int64          `protobuf:"varint,10,req" json:"UpdatedAt,omitempty"`
+	Identities       []*IdentityInfo `protobuf:"bytes,11,rep" json:"Identities,omitempty"`
+	XXX_unrecognized []byte          `json:"-"`
+}
+
+func (m *AccountInfo) Reset()         { *m = AccountInfo{} }
+func (m *AccountInfo) String() string { return proto.CompactTextString(m) }
+func (*AccountInfo) ProtoMessage()    {}
+
+func (m *AccountInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetUsername() string {
+	if m != nil && m.Username != nil {
+		return *m.Username
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetNamespaceID() string {
+	if m != nil && m.NamespaceID != nil {
+		return *m.NamespaceID
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetGivenName() string {
+	if m != nil && m.GivenName != nil {
+		return *m.GivenName
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetFamilyName() string {
+	if m != nil && m.FamilyName != nil {
+		return *m.FamilyName
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetHash() string {
+	if m != nil && m.Hash != nil {
+		return *m.Hash
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetPrimaryEmail() string {
+	if m != nil && m.PrimaryEmail != nil {
+		return *m.PrimaryEmail
+	}
+	return ""
+}
+
+func (m *AccountInfo) GetEmails() []*EmailInfo {
+	if m != nil {
+		return m.Emails
+	}
+	return nil
+}
+
+func (m *AccountInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetUpdatedAt() int64 {
+	if m != nil && m.UpdatedAt != nil {
+		return *m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *AccountInfo) GetIdentities() []*IdentityInfo {
+	if m != nil {
+		return m.Identities
+	}
+	return nil
+}
+
+type OrganizationInfo struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	NamespaceID      *string `protobuf:"bytes,2,req" json:"NamespaceID,omitempty"`
+	Name             *string `protobuf:"bytes,3,req" json:"Name,omitempty"`
+	Description      *string `protobuf:"bytes,4,opt" json:"Description,omitempty"`
+	URL              *string `protobuf:"bytes,5,opt" json:"URL,omitempty"`
+	Location         *string `protobuf:"bytes,6,opt" json:"Location,omitempty"`
+	Email            *string `protobuf:"bytes,7,opt" json:"Email,omitempty"`
+	BillingEmail     *string `protobuf:"bytes,8,req" json:"BillingEmail,omitempty"`
+	CreatedAt        *int64  `protobuf:"varint,9,req" json:"CreatedAt,omitempty"`
+	UpdatedAt        *int64  `protobuf:"varint,10,req" json:"UpdatedAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *OrganizationInfo) Reset()         { *m = OrganizationInfo{} }
+func (m *OrganizationInfo) String() string { return proto.CompactTextString(m) }
+func (*OrganizationInfo) ProtoMessage()    {}
+
+func (m *OrganizationInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *OrganizationInfo) GetNamespaceID() string {
+	if m != nil && m.NamespaceID != nil {
+		return *m.NamespaceID
+	}
+	return ""
+}
+
+func (m *OrganizationInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *OrganizationInfo) GetDescription() string {
+	if m != nil && m.Description != nil {
+		return *m.Description
+	}
+	return ""
+}
+
+func (m *OrganizationInfo) GetURL() string {
+	if m != nil && m.URL != nil {
+		return *m.URL
+	}
+	return ""
+}
+
+func (m *OrganizationInfo) GetLocation() string {
+	if m != nil && m.Location != nil {
+		return *m.Location
+	}
+	return ""
+}
+
+func (m *OrganizationInfo) GetEmail() string {
+	if m != nil && m.Email != nil {
+		return *m.Email
+	}
+	return ""
+}
+
+func (m *OrganizationInfo) GetBillingEmail() string {
+	if m != nil && m.BillingEmail != nil {
+		return *m.BillingEmail
+	}
+	return ""
+}
+
+func (m *OrganizationInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+func (m *OrganizationInfo) GetUpdatedAt() int64 {
+	if m != nil && m.UpdatedAt != nil {
+		return *m.UpdatedAt
+	}
+	return 0
+}
+
+type MemberInfo struct {
+	OrganizationID   *string `protobuf:"bytes,1,req" json:"OrganizationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Role             *string `protobuf:"bytes,3,req" json:"Role,omitempty"`
+	State            *string `protobuf:"bytes,4,req" json:"State,omitempty"`
+	Published        *bool   `protobuf:"varint,5,req" json:"Published,omitempty"`
+	CreatedAt        *int64  `protobuf:"varint,6,req" json:"CreatedAt,omitempty"`
+	UpdatedAt        *int64  `protobuf:"varint,7,req" json:"UpdatedAt,omitempty"`
+	InvitedBy        *string `protobuf:"bytes,8,opt" json:"InvitedBy,omitempty"`
+	InviteEmail      *string `protobuf:"bytes,9,opt" json:"InviteEmail,omitempty"`
+	InviteAcceptedAt *int64  `protobuf:"varint,10,opt" json:"InviteAcceptedAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *MemberInfo) Reset()         { *m = MemberInfo{} }
+func (m *MemberInfo) String() string { return proto.CompactTextString(m) }
+func (*MemberInfo) ProtoMessage()    {}
+
+func (m *MemberInfo) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *MemberInfo) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *MemberInfo) GetRole() string {
+	if m != nil && m.Role != nil {
+		return *m.Role
+	}
+	return ""
+}
+
+func (m *MemberInfo) GetState() string {
+	if m != nil && m.State != nil {
+		return *m.State
+	}
+	return ""
+}
+
+func (m *MemberInfo) GetPublished() bool {
+	if m != nil && m.Published != nil {
+		return *m.Published
+	}
+	return false
+}
+
+func (m *MemberInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+func (m *MemberInfo) GetUpdatedAt() int64 {
+	if m != nil && m.UpdatedAt != nil {
+		return *m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *MemberInfo) GetInvitedBy() string {
+	if m != nil && m.InvitedBy != nil {
+		return *m.InvitedBy
+	}
+	return ""
+}
+
+func (m *MemberInfo) GetInviteEmail() string {
+	if m != nil && m.InviteEmail != nil {
+		return *m.InviteEmail
+	}
+	return ""
+}
+
+func (m *MemberInfo) GetInviteAcceptedAt() int64 {
+	if m != nil && m.InviteAcceptedAt != nil {
+		return *m.InviteAcceptedAt
+	}
+	return 0
+}
+
+type ConversationInfo struct {
+	ID                *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	NamespaceID       *string `protobuf:"bytes,2,req" json:"NamespaceID,omitempty"`
+	CreatorID         *string `protobuf:"bytes,3,req" json:"CreatorID,omitempty"`
+	Title             *string `protobuf:"bytes,4,req" json:"Title,omitempty"`
+	Purpose           *string `protobuf:"bytes,5,opt" json:"Purpose,omitempty"`
+	Topic             *string `protobuf:"bytes,6,opt" json:"Topic,omitempty"`
+	Type              *string `protobuf:"bytes,7,req" json:"Type,omitempty"`
+	Privacy           *string `protobuf:"bytes,8,req" json:"Privacy,omitempty"`
+	RetentionMode     *string `protobuf:"bytes,9,opt" json:"RetentionMode,omitempty"`
+	RetentionValue    *int64  `protobuf:"varint,10,opt" json:"RetentionValue,omitempty"`
+	Archived          *bool   `protobuf:"varint,11,req" json:"Archived,omitempty"`
+	ArchivedAt        *int64  `protobuf:"varint,12,opt" json:"ArchivedAt,omitempty"`
+	LastActiveAt      *int64  `protobuf:"varint,13,req" json:"LastActiveAt,omitempty"`
+	CreatedAt         *int64  `protobuf:"varint,14,req" json:"CreatedAt,omitempty"`
+	UpdatedAt         *int64  `protobuf:"varint,15,req" json:"UpdatedAt,omitempty"`
+	ParticipantsCount *int64  `protobuf:"varint,16,opt" json:"ParticipantsCount,omitempty"`
+	XXX_unrecognized  []byte  `json:"-"`
+}
+
+func (m *ConversationInfo) Reset()         { *m = ConversationInfo{} }
+func (m *ConversationInfo) String() string { return proto.CompactTextString(m) }
+func (*ConversationInfo) ProtoMessage()    {}
+
+func (m *ConversationInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetNamespaceID() string {
+	if m != nil && m.NamespaceID != nil {
+		return *m.NamespaceID
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetCreatorID() string {
+	if m != nil && m.CreatorID != nil {
+		return *m.CreatorID
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetTitle() string {
+	if m != nil && m.Title != nil {
+		return *m.Title
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetPurpose() string {
+	if m != nil && m.Purpose != nil {
+		return *m.Purpose
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetTopic() string {
+	if m != nil && m.Topic != nil {
+		return *m.Topic
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetType() string {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetPrivacy() string {
+	if m != nil && m.Privacy != nil {
+		return *m.Privacy
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetRetentionMode() string {
+	if m != nil && m.RetentionMode != nil {
+		return *m.RetentionMode
+	}
+	return ""
+}
+
+func (m *ConversationInfo) GetRetentionValue() int64 {
+	if m != nil && m.RetentionValue != nil {
+		return *m.RetentionValue
+	}
+	return 0
+}
+
+func (m *ConversationInfo) GetArchived() bool {
+	if m != nil && m.Archived != nil {
+		return *m.Archived
+	}
+	return false
+}
+
+func (m *ConversationInfo) GetArchivedAt() int64 {
+	if m != nil && m.ArchivedAt != nil {
+		return *m.ArchivedAt
+	}
+	return 0
+}
+
+func (m *ConversationInfo) GetLastActiveAt() int64 {
+	if m != nil && m.LastActiveAt != nil {
+		return *m.LastActiveAt
+	}
+	return 0
+}
+
+func (m *ConversationInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+func (m *ConversationInfo) GetUpdatedAt() int64 {
+	if m != nil && m.UpdatedAt != nil {
+		return *m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *ConversationInfo) GetParticipantsCount() int64 {
+	if m != nil && m.ParticipantsCount != nil {
+		return *m.ParticipantsCount
+	}
+	return 0
+}
+
+type DeviceInfo struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Name             *string `protobuf:"bytes,3,opt" json:"Name,omitempty"`
+	Platform         *string `protobuf:"bytes,4,opt" json:"Platform,omitempty"`
+	Token            *string `protobuf:"bytes,5,opt" json:"Token,omitempty"`
+	CreatedAt        *int64  `protobuf:"varint,6,req" json:"CreatedAt,omitempty"`
+	UpdatedAt        *int64  `protobuf:"varint,7,req" json:"UpdatedAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DeviceInfo) Reset()         { *m = DeviceInfo{} }
+func (m *DeviceInfo) String() string { return proto.CompactTextString(m) }
+func (*DeviceInfo) ProtoMessage()    {}
+
+func (m *DeviceInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *DeviceInfo) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *DeviceInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *DeviceInfo) GetPlatform() string {
+	if m != nil && m.Platform != nil {
+		return *m.Platform
+	}
+	return ""
+}
+
+func (m *DeviceInfo) GetToken() string {
+	if m != nil && m.Token != nil {
+		return *m.Token
+	}
+	return ""
+}
+
+func (m *DeviceInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+func (m *DeviceInfo) GetUpdatedAt() int64 {
+	if m != nil && m.UpdatedAt != nil {
+		return *m.UpdatedAt
+	}
+	return 0
+}
+
+type InvitationInfo struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	OrganizationID   *string `protobuf:"bytes,2,req" json:"OrganizationID,omitempty"`
+	Email            *string `protobuf:"bytes,3,req" json:"Email,omitempty"`
+	Role             *string `protobuf:"bytes,4,req" json:"Role,omitempty"`
+	InvitedBy        *string `protobuf:"bytes,5,req" json:"InvitedBy,omitempty"`
+	TokenHash        *string `protobuf:"bytes,6,req" json:"TokenHash,omitempty"`
+	SentAt           *int64  `protobuf:"varint,7,req" json:"SentAt,omitempty"`
+	ExpiresAt        *int64  `protobuf:"varint,8,req" json:"ExpiresAt,omitempty"`
+	CreatedAt        *int64  `protobuf:"varint,9,req" json:"CreatedAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *InvitationInfo) Reset()         { *m = InvitationInfo{} }
+func (m *InvitationInfo) String() string { return proto.CompactTextString(m) }
+func (*InvitationInfo) ProtoMessage()    {}
+
+func (m *InvitationInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *InvitationInfo) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *InvitationInfo) GetEmail() string {
+	if m != nil && m.Email != nil {
+		return *m.Email
+	}
+	return ""
+}
+
+func (m *InvitationInfo) GetRole() string {
+	if m != nil && m.Role != nil {
+		return *m.Role
+	}
+	return ""
+}
+
+func (m *InvitationInfo) GetInvitedBy() string {
+	if m != nil && m.InvitedBy != nil {
+		return *m.InvitedBy
+	}
+	return ""
+}
+
+func (m *InvitationInfo) GetTokenHash() string {
+	if m != nil && m.TokenHash != nil {
+		return *m.TokenHash
+	}
+	return ""
+}
+
+func (m *InvitationInfo) GetSentAt() int64 {
+	if m != nil && m.SentAt != nil {
+		return *m.SentAt
+	}
+	return 0
+}
+
+func (m *InvitationInfo) GetExpiresAt() int64 {
+	if m != nil && m.ExpiresAt != nil {
+		return *m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *InvitationInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+type TeamInfo struct {
+	ID               *string  `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	OrganizationID   *string  `protobuf:"bytes,2,req" json:"OrganizationID,omitempty"`
+	Name             *string  `protobuf:"bytes,3,req" json:"Name,omitempty"`
+	Description      *string  `protobuf:"bytes,4,req" json:"Description,omitempty"`
+	Type             *string  `protobuf:"bytes,5,req" json:"Type,omitempty"`
+	Members          []string `protobuf:"bytes,6,rep" json:"Members,omitempty"`
+	Conversations    []string `protobuf:"bytes,7,rep" json:"Conversations,omitempty"`
+	CreatedAt        *int64   `protobuf:"varint,8,req" json:"CreatedAt,omitempty"`
+	UpdatedAt        *int64   `protobuf:"varint,9,req" json:"UpdatedAt,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *TeamInfo) Reset()         { *m = TeamInfo{} }
+func (m *TeamInfo) String() string { return proto.CompactTextString(m) }
+func (*TeamInfo) ProtoMessage()    {}
+
+func (m *TeamInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *TeamInfo) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *TeamInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *TeamInfo) GetDescription() string {
+	if m != nil && m.Description != nil {
+		return *m.Description
+	}
+	return ""
+}
+
+func (m *TeamInfo) GetType() string {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return ""
+}
+
+func (m *TeamInfo) GetMembers() []string {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *TeamInfo) GetConversations() []string {
+	if m != nil {
+		return m.Conversations
+	}
+	return nil
+}
+
+func (m *TeamInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+func (m *TeamInfo) GetUpdatedAt() int64 {
+	if m != nil && m.UpdatedAt != nil {
+		return *m.UpdatedAt
+	}
+	return 0
+}
+
+type RevokedTokenInfo struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	ExpiresAt        *int64  `protobuf:"varint,3,req" json:"ExpiresAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RevokedTokenInfo) Reset()         { *m = RevokedTokenInfo{} }
+func (m *RevokedTokenInfo) String() string { return proto.CompactTextString(m) }
+func (*RevokedTokenInfo) ProtoMessage()    {}
+
+func (m *RevokedTokenInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *RevokedTokenInfo) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *RevokedTokenInfo) GetExpiresAt() int64 {
+	if m != nil && m.ExpiresAt != nil {
+		return *m.ExpiresAt
+	}
+	return 0
+}
+
+type ApiTokenInfo struct {
+	ID               *string  `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	Name             *string  `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	TokenHash        *string  `protobuf:"bytes,3,req" json:"TokenHash,omitempty"`
+	UserID           *string  `protobuf:"bytes,4,req" json:"UserID,omitempty"`
+	OrganizationID   *string  `protobuf:"bytes,5,opt" json:"OrganizationID,omitempty"`
+	Scopes           []string `protobuf:"bytes,6,rep" json:"Scopes,omitempty"`
+	RateLimit        *int64   `protobuf:"varint,7,opt" json:"RateLimit,omitempty"`
+	ExpiresAt        *int64   `protobuf:"varint,8,opt" json:"ExpiresAt,omitempty"`
+	LastUsedAt       *int64   `protobuf:"varint,9,opt" json:"LastUsedAt,omitempty"`
+	CreatedAt        *int64   `protobuf:"varint,10,req" json:"CreatedAt,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *ApiTokenInfo) Reset()         { *m = ApiTokenInfo{} }
+func (m *ApiTokenInfo) String() string { return proto.CompactTextString(m) }
+func (*ApiTokenInfo) ProtoMessage()    {}
+
+func (m *ApiTokenInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *ApiTokenInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *ApiTokenInfo) GetTokenHash() string {
+	if m != nil && m.TokenHash != nil {
+		return *m.TokenHash
+	}
+	return ""
+}
+
+func (m *ApiTokenInfo) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *ApiTokenInfo) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *ApiTokenInfo) GetScopes() []string {
+	if m != nil {
+		return m.Scopes
+	}
+	return nil
+}
+
+func (m *ApiTokenInfo) GetRateLimit() int64 {
+	if m != nil && m.RateLimit != nil {
+		return *m.RateLimit
+	}
+	return 0
+}
+
+func (m *ApiTokenInfo) GetExpiresAt() int64 {
+	if m != nil && m.ExpiresAt != nil {
+		return *m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *ApiTokenInfo) GetLastUsedAt() int64 {
+	if m != nil && m.LastUsedAt != nil {
+		return *m.LastUsedAt
+	}
+	return 0
+}
+
+func (m *ApiTokenInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+type IdentityInfo struct {
+	Issuer           *string `protobuf:"bytes,1,req" json:"Issuer,omitempty"`
+	Subject          *string `protobuf:"bytes,2,req" json:"Subject,omitempty"`
+	Email            *string `protobuf:"bytes,3,opt" json:"Email,omitempty"`
+	CreatedAt        *int64  `protobuf:"varint,4,req" json:"CreatedAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *IdentityInfo) Reset()         { *m = IdentityInfo{} }
+func (m *IdentityInfo) String() string { return proto.CompactTextString(m) }
+func (*IdentityInfo) ProtoMessage()    {}
+
+func (m *IdentityInfo) GetIssuer() string {
+	if m != nil && m.Issuer != nil {
+		return *m.Issuer
+	}
+	return ""
+}
+
+func (m *IdentityInfo) GetSubject() string {
+	if m != nil && m.Subject != nil {
+		return *m.Subject
+	}
+	return ""
+}
+
+func (m *IdentityInfo) GetEmail() string {
+	if m != nil && m.Email != nil {
+		return *m.Email
+	}
+	return ""
+}
+
+func (m *IdentityInfo) GetCreatedAt() int64 {
+	if m != nil && m.CreatedAt != nil {
+		return *m.CreatedAt
+	}
+	return 0
+}
+
+type Command struct {
+	Type             *Command_Type             `protobuf:"varint,1,req,name=type,enum=internal.Command_Type" json:"type,omitempty"`
+	XXX_extensions   map[int32]proto.Extension `json:"-"`
+	XXX_unrecognized []byte                    `json:"-"`
+}
+
+func (m *Command) Reset()         { *m = Command{} }
+func (m *Command) String() string { return proto.CompactTextString(m) }
+func (*Command) ProtoMessage()    {}
+
+var extRange_Command = []proto.ExtensionRange{
+	{100, 536870911},
+}
+
+func (*Command) ExtensionRangeArray() []proto.ExtensionRange {
+	return extRange_Command
+}
+func (m *Command) ExtensionMap() map[int32]proto.Extension {
+	if m.XXX_extensions == nil {
+		m.XXX_extensions = make(map[int32]proto.Extension)
+	}
+	return m.XXX_extensions
+}
+
+func (m *Command) GetType() Command_Type {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return Command_CreateNodeCommand
+}
+
+type CreateNodeCommand struct {
+	Host             *string `protobuf:"bytes,1,req" json:"Host,omitempty"`
+	Rand             *uint64 `protobuf:"varint,2,req" json:"Rand,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *CreateNodeCommand) Reset()         { *m = CreateNodeCommand{} }
+func (m *CreateNodeCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateNodeCommand) ProtoMessage()    {}
+
+func (m *CreateNodeCommand) GetHost() string {
+	if m != nil && m.Host != nil {
+		return *m.Host
+	}
+	return ""
+}
+
+func (m *CreateNodeCommand) GetRand() uint64 {
+	if m != nil && m.Rand != nil {
+		return *m.Rand
+	}
+	return 0
+}
+
+var E_CreateNodeCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateNodeCommand)(nil),
+	Field:         101,
+	Name:          "internal.CreateNodeCommand.command",
+	Tag:           "bytes,101,opt,name=command",
+}
+
+type DeleteNodeCommand struct {
+	ID               *uint64 `protobuf:"varint,1,req" json:"ID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DeleteNodeCommand) Reset()         { *m = DeleteNodeCommand{} }
+func (m *DeleteNodeCommand) String() string { return proto.CompactTextString(m) }
+func (*DeleteNodeCommand) ProtoMessage()    {}
+
+func (m *DeleteNodeCommand) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+var E_DeleteNodeCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DeleteNodeCommand)(nil),
+	Field:         102,
+	Name:          "internal.DeleteNodeCommand.command",
+	Tag:           "bytes,102,opt,name=command",
+}
+
+type CreateDatabaseCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *CreateDatabaseCommand) Reset()         { *m = CreateDatabaseCommand{} }
+func (m *CreateDatabaseCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateDatabaseCommand) ProtoMessage()    {}
+
+func (m *CreateDatabaseCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_CreateDatabaseCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateDatabaseCommand)(nil),
+	Field:         103,
+	Name:          "internal.CreateDatabaseCommand.command",
+	Tag:           "bytes,103,opt,name=command",
+}
+
+type DropDatabaseCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropDatabaseCommand) Reset()         { *m = DropDatabaseCommand{} }
+func (m *DropDatabaseCommand) String() string { return proto.CompactTextString(m) }
+func (*DropDatabaseCommand) ProtoMessage()    {}
+
+func (m *DropDatabaseCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_DropDatabaseCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropDatabaseCommand)(nil),
+	Field:         104,
+	Name:          "internal.DropDatabaseCommand.command",
+	Tag:           "bytes,104,opt,name=command",
+}
+
+type CreateRetentionPolicyCommand struct {
+	Database         *string              `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	RetentionPolicy  *RetentionPolicyInfo `protobuf:"bytes,2,req" json:"RetentionPolicy,omitempty"`
+	XXX_unrecognized []byte               `json:"-"`
+}
+
+func (m *CreateRetentionPolicyCommand) Reset()         { *m = CreateRetentionPolicyCommand{} }
+func (m *CreateRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateRetentionPolicyCommand) ProtoMessage()    {}
+
+func (m *CreateRetentionPolicyCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *CreateRetentionPolicyCommand) GetRetentionPolicy() *RetentionPolicyInfo {
+	if m != nil {
+		return m.RetentionPolicy
+	}
+	return nil
+}
+
+var E_CreateRetentionPolicyCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateRetentionPolicyCommand)(nil),
+	Field:         105,
+	Name:          "internal.CreateRetentionPolicyCommand.command",
+	Tag:           "bytes,105,opt,name=command",
+}
+
+type DropRetentionPolicyCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropRetentionPolicyCommand) Reset()         { *m = DropRetentionPolicyCommand{} }
+func (m *DropRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
+func (*DropRetentionPolicyCommand) ProtoMessage()    {}
+
+func (m *DropRetentionPolicyCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DropRetentionPolicyCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_DropRetentionPolicyCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropRetentionPolicyCommand)(nil),
+	Field:         106,
+	Name:          "internal.DropRetentionPolicyCommand.command",
+	Tag:           "bytes,106,opt,name=command",
+}
+
+type SetDefaultRetentionPolicyCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetDefaultRetentionPolicyCommand) Reset()         { *m = SetDefaultRetentionPolicyCommand{} }
+func (m *SetDefaultRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
+func (*SetDefaultRetentionPolicyCommand) ProtoMessage()    {}
+
+func (m *SetDefaultRetentionPolicyCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *SetDefaultRetentionPolicyCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_SetDefaultRetentionPolicyCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetDefaultRetentionPolicyCommand)(nil),
+	Field:         107,
+	Name:          "internal.SetDefaultRetentionPolicyCommand.command",
+	Tag:           "bytes,107,opt,name=command",
+}
+
+type UpdateRetentionPolicyCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
+	NewName          *string `protobuf:"bytes,3,opt" json:"NewName,omitempty"`
+	Duration         *int64  `protobuf:"varint,4,opt" json:"Duration,omitempty"`
+	ReplicaN         *uint32 `protobuf:"varint,5,opt" json:"ReplicaN,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *UpdateRetentionPolicyCommand) Reset()         { *m = UpdateRetentionPolicyCommand{} }
+func (m *UpdateRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateRetentionPolicyCommand) ProtoMessage()    {}
+
+func (m *UpdateRetentionPolicyCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *UpdateRetentionPolicyCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *UpdateRetentionPolicyCommand) GetNewName() string {
+	if m != nil && m.NewName != nil {
+		return *m.NewName
+	}
+	return ""
+}
+
+func (m *UpdateRetentionPolicyCommand) GetDuration() int64 {
+	if m != nil && m.Duration != nil {
+		return *m.Duration
+	}
+	return 0
+}
+
+func (m *UpdateRetentionPolicyCommand) GetReplicaN() uint32 {
+	if m != nil && m.ReplicaN != nil {
+		return *m.ReplicaN
+	}
+	return 0
+}
+
+var E_UpdateRetentionPolicyCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateRetentionPolicyCommand)(nil),
+	Field:         108,
+	Name:          "internal.UpdateRetentionPolicyCommand.command",
+	Tag:           "bytes,108,opt,name=command",
+}
+
+type CreateShardGroupCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Policy           *string `protobuf:"bytes,2,req" json:"Policy,omitempty"`
+	Timestamp        *int64  `protobuf:"varint,3,req" json:"Timestamp,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *CreateShardGroupCommand) Reset()         { *m = CreateShardGroupCommand{} }
+func (m *CreateShardGroupCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateShardGroupCommand) ProtoMessage()    {}
+
+func (m *CreateShardGroupCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *CreateShardGroupCommand) GetPolicy() string {
+	if m != nil && m.Policy != nil {
+		return *m.Policy
+	}
+	return ""
+}
+
+func (m *CreateShardGroupCommand) GetTimestamp() int64 {
+	if m != nil && m.Timestamp != nil {
+		return *m.Timestamp
+	}
+	return 0
+}
+
+var E_CreateShardGroupCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateShardGroupCommand)(nil),
+	Field:         109,
+	Name:          "internal.CreateShardGroupCommand.command",
+	Tag:           "bytes,109,opt,name=command",
+}
+
+type DeleteShardGroupCommand struct {
+	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
+	Policy           *string `protobuf:"bytes,2,req" json:"Policy,omitempty"`
+	ShardGroupID     *uint64 `protobuf:"varint,3,req" json:"ShardGroupID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DeleteShardGroupCommand) Reset()         { *m = DeleteShardGroupCommand{} }
+func (m *DeleteShardGroupCommand) String() string { return proto.CompactTextString(m) }
+func (*DeleteShardGroupCommand) ProtoMessage()    {}
+
+func (m *DeleteShardGroupCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *DeleteShardGroupCommand) GetPolicy() string {
+	if m != nil && m.Policy != nil {
+		return *m.Policy
+	}
+	return ""
+}
+
+func (m *DeleteShardGroupCommand) GetShardGroupID() uint64 {
+	if m != nil && m.ShardGroupID != nil {
+		return *m.ShardGroupID
+	}
+	return 0
+}
+
+var E_DeleteShardGroupCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DeleteShardGroupCommand)(nil),
+	Field:         110,
+	Name:          "internal.DeleteShardGroupCommand.command",
+	Tag:           "bytes,110,opt,name=command",
+}
+
+type CreateUserCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Hash             *string `protobuf:"bytes,2,req" json:"Hash,omitempty"`
+	Admin            *bool   `protobuf:"varint,3,req" json:"Admin,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *CreateUserCommand) Reset()         { *m = CreateUserCommand{} }
+func (m *CreateUserCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateUserCommand) ProtoMessage()    {}
+
+func (m *CreateUserCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *CreateUserCommand) GetHash() string {
+	if m != nil && m.Hash != nil {
+		return *m.Hash
+	}
+	return ""
+}
+
+func (m *CreateUserCommand) GetAdmin() bool {
+	if m != nil && m.Admin != nil {
+		return *m.Admin
+	}
+	return false
+}
+
+var E_CreateUserCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateUserCommand)(nil),
+	Field:         111,
+	Name:          "internal.CreateUserCommand.command",
+	Tag:           "bytes,111,opt,name=command",
+}
+
+type DropUserCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropUserCommand) Reset()         { *m = DropUserCommand{} }
+func (m *DropUserCommand) String() string { return proto.CompactTextString(m) }
+func (*DropUserCommand) ProtoMessage()    {}
+
+func (m *DropUserCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+var E_DropUserCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropUserCommand)(nil),
+	Field:         112,
+	Name:          "internal.DropUserCommand.command",
+	Tag:           "bytes,112,opt,name=command",
+}
+
+type UpdateUserCommand struct {
+	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+	Hash             *string `protobuf:"bytes,2,req" json:"Hash,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *UpdateUserCommand) Reset()         { *m = UpdateUserCommand{} }
+func (m *UpdateUserCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateUserCommand) ProtoMessage()    {}
+
+func (m *UpdateUserCommand) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *UpdateUserCommand) GetHash() string {
+	if m != nil && m.Hash != nil {
+		return *m.Hash
+	}
+	return ""
+}
+
+var E_UpdateUserCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateUserCommand)(nil),
+	Field:         113,
+	Name:          "internal.UpdateUserCommand.command",
+	Tag:           "bytes,113,opt,name=command",
+}
+
+type SetPrivilegeCommand struct {
+	Username         *string `protobuf:"bytes,1,req" json:"Username,omitempty"`
+	Database         *string `protobuf:"bytes,2,req" json:"Database,omitempty"`
+	Privilege        *int32  `protobuf:"varint,3,req" json:"Privilege,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetPrivilegeCommand) Reset()         { *m = SetPrivilegeCommand{} }
+func (m *SetPrivilegeCommand) String() string { return proto.CompactTextString(m) }
+func (*SetPrivilegeCommand) ProtoMessage()    {}
+
+func (m *SetPrivilegeCommand) GetUsername() string {
+	if m != nil && m.Username != nil {
+		return *m.Username
+	}
+	return ""
+}
+
+func (m *SetPrivilegeCommand) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *SetPrivilegeCommand) GetPrivilege() int32 {
+	if m != nil && m.Privilege != nil {
+		return *m.Privilege
+	}
+	return 0
+}
+
+var E_SetPrivilegeCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetPrivilegeCommand)(nil),
+	Field:         114,
+	Name:          "internal.SetPrivilegeCommand.command",
+	Tag:           "bytes,114,opt,name=command",
+}
+
+type SetDataCommand struct {
+	Data             *Data  `protobuf:"bytes,1,req" json:"Data,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *SetDataCommand) Reset()         { *m = SetDataCommand{} }
+func (m *SetDataCommand) String() string { return proto.CompactTextString(m) }
+func (*SetDataCommand) ProtoMessage()    {}
+
+func (m *SetDataCommand) GetData() *Data {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+var E_SetDataCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetDataCommand)(nil),
+	Field:         115,
+	Name:          "internal.SetDataCommand.command",
+	Tag:           "bytes,115,opt,name=command",
+}
+
+type SetAdminPrivilegeCommand struct {
+	Username         *string `protobuf:"bytes,1,req" json:"Username,omitempty"`
+	Admin            *bool   `protobuf:"varint,2,req" json:"Admin,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetAdminPrivilegeCommand) Reset()         { *m = SetAdminPrivilegeCommand{} }
+func (m *SetAdminPrivilegeCommand) String() string { return proto.CompactTextString(m) }
+func (*SetAdminPrivilegeCommand) ProtoMessage()    {}
+
+func (m *SetAdminPrivilegeCommand) GetUsername() string {
+	if m != nil && m.Username != nil {
+		return *m.Username
+	}
+	return ""
+}
+
+func (m *SetAdminPrivilegeCommand) GetAdmin() bool {
+	if m != nil && m.Admin != nil {
+		return *m.Admin
+	}
+	return false
+}
+
+var E_SetAdminPrivilegeCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetAdminPrivilegeCommand)(nil),
+	Field:         116,
+	Name:          "internal.SetAdminPrivilegeCommand.command",
+	Tag:           "bytes,116,opt,name=command",
+}
+
+type SetReadMarkerCommand struct {
+	ConversationID   *string `protobuf:"bytes,1,req" json:"ConversationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	MessageID        *string `protobuf:"bytes,3,req" json:"MessageID,omitempty"`
+	Time             *int64  `protobuf:"varint,4,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetReadMarkerCommand) Reset()         { *m = SetReadMarkerCommand{} }
+func (m *SetReadMarkerCommand) String() string { return proto.CompactTextString(m) }
+func (*SetReadMarkerCommand) ProtoMessage()    {}
+
+func (m *SetReadMarkerCommand) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
+	}
+	return ""
+}
+
+func (m *SetReadMarkerCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *SetReadMarkerCommand) GetMessageID() string {
+	if m != nil && m.MessageID != nil {
+		return *m.MessageID
+	}
+	return ""
+}
+
+func (m *SetReadMarkerCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_SetReadMarkerCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetReadMarkerCommand)(nil),
+	Field:         117,
+	Name:          "internal.SetReadMarkerCommand.command",
+	Tag:           "bytes,117,opt,name=command",
+}
+
+type CreateAttachmentCommand struct {
+	Attachment       *AttachmentInfo `protobuf:"bytes,1,req" json:"Attachment,omitempty"`
+	XXX_unrecognized []byte          `json:"-"`
+}
+
+func (m *CreateAttachmentCommand) Reset()         { *m = CreateAttachmentCommand{} }
+func (m *CreateAttachmentCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateAttachmentCommand) ProtoMessage()    {}
+
+func (m *CreateAttachmentCommand) GetAttachment() *AttachmentInfo {
+	if m != nil {
+		return m.Attachment
+	}
+	return nil
+}
+
+var E_CreateAttachmentCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateAttachmentCommand)(nil),
+	Field:         118,
+	Name:          "internal.CreateAttachmentCommand.command",
+	Tag:           "bytes,118,opt,name=command",
+}
+
+type SetAttachmentMessageCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	MessageID        *string `protobuf:"bytes,2,req" json:"MessageID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetAttachmentMessageCommand) Reset()         { *m = SetAttachmentMessageCommand{} }
+func (m *SetAttachmentMessageCommand) String() string { return proto.CompactTextString(m) }
+func (*SetAttachmentMessageCommand) ProtoMessage()    {}
+
+func (m *SetAttachmentMessageCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *SetAttachmentMessageCommand) GetMessageID() string {
+	if m != nil && m.MessageID != nil {
+		return *m.MessageID
+	}
+	return ""
+}
+
+var E_SetAttachmentMessageCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetAttachmentMessageCommand)(nil),
+	Field:         119,
+	Name:          "internal.SetAttachmentMessageCommand.command",
+	Tag:           "bytes,119,opt,name=command",
+}
+
+type DeleteAttachmentCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DeleteAttachmentCommand) Reset()         { *m = DeleteAttachmentCommand{} }
+func (m *DeleteAttachmentCommand) String() string { return proto.CompactTextString(m) }
+func (*DeleteAttachmentCommand) ProtoMessage()    {}
+
+func (m *DeleteAttachmentCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+var E_DeleteAttachmentCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DeleteAttachmentCommand)(nil),
+	Field:         120,
+	Name:          "internal.DeleteAttachmentCommand.command",
+	Tag:           "bytes,120,opt,name=command",
+}
+
+type AddParticipantCommand struct {
+	ConversationID   *string `protobuf:"bytes,1,req" json:"ConversationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AddParticipantCommand) Reset()         { *m = AddParticipantCommand{} }
+func (m *AddParticipantCommand) String() string { return proto.CompactTextString(m) }
+func (*AddParticipantCommand) ProtoMessage()    {}
+
+func (m *AddParticipantCommand) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
+	}
+	return ""
+}
+
+func (m *AddParticipantCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *AddParticipantCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_AddParticipantCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*AddParticipantCommand)(nil),
+	Field:         121,
+	Name:          "internal.AddParticipantCommand.command",
+	Tag:           "bytes,121,opt,name=command",
+}
+
+type RemoveParticipantCommand struct {
+	ConversationID   *string `protobuf:"bytes,1,req" json:"ConversationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RemoveParticipantCommand) Reset()         { *m = RemoveParticipantCommand{} }
+func (m *RemoveParticipantCommand) String() string { return proto.CompactTextString(m) }
+func (*RemoveParticipantCommand) ProtoMessage()    {}
+
+func (m *RemoveParticipantCommand) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
+	}
+	return ""
+}
+
+func (m *RemoveParticipantCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+var E_RemoveParticipantCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*RemoveParticipantCommand)(nil),
+	Field:         122,
+	Name:          "internal.RemoveParticipantCommand.command",
+	Tag:           "bytes,122,opt,name=command",
+}
+
+type SetParticipantActivityCommand struct {
+	ConversationID   *string `protobuf:"bytes,1,req" json:"ConversationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetParticipantActivityCommand) Reset()         { *m = SetParticipantActivityCommand{} }
+func (m *SetParticipantActivityCommand) String() string { return proto.CompactTextString(m) }
+func (*SetParticipantActivityCommand) ProtoMessage()    {}
+
+func (m *SetParticipantActivityCommand) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
+	}
+	return ""
+}
+
+func (m *SetParticipantActivityCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *SetParticipantActivityCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_SetParticipantActivityCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetParticipantActivityCommand)(nil),
+	Field:         123,
+	Name:          "internal.SetParticipantActivityCommand.command",
+	Tag:           "bytes,123,opt,name=command",
+}
+
+type CreateOrganizationCommand struct {
+	Organization     *OrganizationInfo `protobuf:"bytes,1,req" json:"Organization,omitempty"`
+	Path             *string           `protobuf:"bytes,2,req" json:"Path,omitempty"`
+	OwnerID          *string           `protobuf:"bytes,3,req" json:"OwnerID,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
+}
+
+func (m *CreateOrganizationCommand) Reset()         { *m = CreateOrganizationCommand{} }
+func (m *CreateOrganizationCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateOrganizationCommand) ProtoMessage()    {}
+
+func (m *CreateOrganizationCommand) GetOrganization() *OrganizationInfo {
+	if m != nil {
+		return m.Organization
+	}
+	return nil
+}
+
+func (m *CreateOrganizationCommand) GetPath() string {
+	if m != nil && m.Path != nil {
+		return *m.Path
+	}
+	return ""
+}
+
+func (m *CreateOrganizationCommand) GetOwnerID() string {
+	if m != nil && m.OwnerID != nil {
+		return *m.OwnerID
+	}
+	return ""
+}
+
+var E_CreateOrganizationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateOrganizationCommand)(nil),
+	Field:         124,
+	Name:          "internal.CreateOrganizationCommand.command",
+	Tag:           "bytes,124,opt,name=command",
 }
 
-var E_SetDefaultRetentionPolicyCommand_Command = &proto.ExtensionDesc{
+type DropOrganizationCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropOrganizationCommand) Reset()         { *m = DropOrganizationCommand{} }
+func (m *DropOrganizationCommand) String() string { return proto.CompactTextString(m) }
+func (*DropOrganizationCommand) ProtoMessage()    {}
+
+func (m *DropOrganizationCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+var E_DropOrganizationCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetDefaultRetentionPolicyCommand)(nil),
-	Field:         107,
-	Name:          "internal.SetDefaultRetentionPolicyCommand.command",
-	Tag:           "bytes,107,opt,name=command",
+	ExtensionType: (*DropOrganizationCommand)(nil),
+	Field:         125,
+	Name:          "internal.DropOrganizationCommand.command",
+	Tag:           "bytes,125,opt,name=command",
 }
 
-type UpdateRetentionPolicyCommand struct {
-	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
-	Name             *string `protobuf:"bytes,2,req" json:"Name,omitempty"`
-	NewName          *string `protobuf:"bytes,3,opt" json:"NewName,omitempty"`
-	Duration         *int64  `protobuf:"varint,4,opt" json:"Duration,omitempty"`
-	ReplicaN         *uint32 `protobuf:"varint,5,opt" json:"ReplicaN,omitempty"`
+type UpdateOrganizationCommand struct {
+	Organization     *OrganizationInfo `protobuf:"bytes,1,req" json:"Organization,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
+}
+
+func (m *UpdateOrganizationCommand) Reset()         { *m = UpdateOrganizationCommand{} }
+func (m *UpdateOrganizationCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateOrganizationCommand) ProtoMessage()    {}
+
+func (m *UpdateOrganizationCommand) GetOrganization() *OrganizationInfo {
+	if m != nil {
+		return m.Organization
+	}
+	return nil
+}
+
+var E_UpdateOrganizationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateOrganizationCommand)(nil),
+	Field:         126,
+	Name:          "internal.UpdateOrganizationCommand.command",
+	Tag:           "bytes,126,opt,name=command",
+}
+
+type AddOrUpdateMembershipCommand struct {
+	OrganizationID   *string `protobuf:"bytes,1,req" json:"OrganizationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Role             *string `protobuf:"bytes,3,req" json:"Role,omitempty"`
+	Time             *int64  `protobuf:"varint,4,req" json:"Time,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *UpdateRetentionPolicyCommand) Reset()         { *m = UpdateRetentionPolicyCommand{} }
-func (m *UpdateRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
-func (*UpdateRetentionPolicyCommand) ProtoMessage()    {}
+func (m *AddOrUpdateMembershipCommand) Reset()         { *m = AddOrUpdateMembershipCommand{} }
+func (m *AddOrUpdateMembershipCommand) String() string { return proto.CompactTextString(m) }
+func (*AddOrUpdateMembershipCommand) ProtoMessage()    {}
 
-func (m *UpdateRetentionPolicyCommand) GetDatabase() string {
-	if m != nil && m.Database != nil {
-		return *m.Database
+func (m *AddOrUpdateMembershipCommand) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *AddOrUpdateMembershipCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *AddOrUpdateMembershipCommand) GetRole() string {
+	if m != nil && m.Role != nil {
+		return *m.Role
+	}
+	return ""
+}
+
+func (m *AddOrUpdateMembershipCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_AddOrUpdateMembershipCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*AddOrUpdateMembershipCommand)(nil),
+	Field:         127,
+	Name:          "internal.AddOrUpdateMembershipCommand.command",
+	Tag:           "bytes,127,opt,name=command",
+}
+
+type RemoveMembershipCommand struct {
+	OrganizationID   *string `protobuf:"bytes,1,req" json:"OrganizationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RemoveMembershipCommand) Reset()         { *m = RemoveMembershipCommand{} }
+func (m *RemoveMembershipCommand) String() string { return proto.CompactTextString(m) }
+func (*RemoveMembershipCommand) ProtoMessage()    {}
+
+func (m *RemoveMembershipCommand) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *RemoveMembershipCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+var E_RemoveMembershipCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*RemoveMembershipCommand)(nil),
+	Field:         128,
+	Name:          "internal.RemoveMembershipCommand.command",
+	Tag:           "bytes,128,opt,name=command",
+}
+
+type EditMyMembershipCommand struct {
+	OrganizationID   *string `protobuf:"bytes,1,req" json:"OrganizationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	State            *string `protobuf:"bytes,3,req" json:"State,omitempty"`
+	Time             *int64  `protobuf:"varint,4,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *EditMyMembershipCommand) Reset()         { *m = EditMyMembershipCommand{} }
+func (m *EditMyMembershipCommand) String() string { return proto.CompactTextString(m) }
+func (*EditMyMembershipCommand) ProtoMessage()    {}
+
+func (m *EditMyMembershipCommand) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *EditMyMembershipCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *EditMyMembershipCommand) GetState() string {
+	if m != nil && m.State != nil {
+		return *m.State
+	}
+	return ""
+}
+
+func (m *EditMyMembershipCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_EditMyMembershipCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*EditMyMembershipCommand)(nil),
+	Field:         129,
+	Name:          "internal.EditMyMembershipCommand.command",
+	Tag:           "bytes,129,opt,name=command",
+}
+
+type CreateConversationCommand struct {
+	Conversation     *ConversationInfo `protobuf:"bytes,1,req" json:"Conversation,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
+}
+
+func (m *CreateConversationCommand) Reset()         { *m = CreateConversationCommand{} }
+func (m *CreateConversationCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateConversationCommand) ProtoMessage()    {}
+
+func (m *CreateConversationCommand) GetConversation() *ConversationInfo {
+	if m != nil {
+		return m.Conversation
+	}
+	return nil
+}
+
+var E_CreateConversationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateConversationCommand)(nil),
+	Field:         130,
+	Name:          "internal.CreateConversationCommand.command",
+	Tag:           "bytes,130,opt,name=command",
+}
+
+type DropConversationCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropConversationCommand) Reset()         { *m = DropConversationCommand{} }
+func (m *DropConversationCommand) String() string { return proto.CompactTextString(m) }
+func (*DropConversationCommand) ProtoMessage()    {}
+
+func (m *DropConversationCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+var E_DropConversationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropConversationCommand)(nil),
+	Field:         131,
+	Name:          "internal.DropConversationCommand.command",
+	Tag:           "bytes,131,opt,name=command",
+}
+
+type UpdateConversationCommand struct {
+	Conversation     *ConversationInfo `protobuf:"bytes,1,req" json:"Conversation,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
+}
+
+func (m *UpdateConversationCommand) Reset()         { *m = UpdateConversationCommand{} }
+func (m *UpdateConversationCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateConversationCommand) ProtoMessage()    {}
+
+func (m *UpdateConversationCommand) GetConversation() *ConversationInfo {
+	if m != nil {
+		return m.Conversation
+	}
+	return nil
+}
+
+var E_UpdateConversationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateConversationCommand)(nil),
+	Field:         132,
+	Name:          "internal.UpdateConversationCommand.command",
+	Tag:           "bytes,132,opt,name=command",
+}
+
+type PublicizeMembershipCommand struct {
+	OrganizationID   *string `protobuf:"bytes,1,req" json:"OrganizationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *PublicizeMembershipCommand) Reset()         { *m = PublicizeMembershipCommand{} }
+func (m *PublicizeMembershipCommand) String() string { return proto.CompactTextString(m) }
+func (*PublicizeMembershipCommand) ProtoMessage()    {}
+
+func (m *PublicizeMembershipCommand) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *PublicizeMembershipCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *PublicizeMembershipCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_PublicizeMembershipCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*PublicizeMembershipCommand)(nil),
+	Field:         133,
+	Name:          "internal.PublicizeMembershipCommand.command",
+	Tag:           "bytes,133,opt,name=command",
+}
+
+type ConcealMembershipCommand struct {
+	OrganizationID   *string `protobuf:"bytes,1,req" json:"OrganizationID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ConcealMembershipCommand) Reset()         { *m = ConcealMembershipCommand{} }
+func (m *ConcealMembershipCommand) String() string { return proto.CompactTextString(m) }
+func (*ConcealMembershipCommand) ProtoMessage()    {}
+
+func (m *ConcealMembershipCommand) GetOrganizationID() string {
+	if m != nil && m.OrganizationID != nil {
+		return *m.OrganizationID
+	}
+	return ""
+}
+
+func (m *ConcealMembershipCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *ConcealMembershipCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_ConcealMembershipCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*ConcealMembershipCommand)(nil),
+	Field:         134,
+	Name:          "internal.ConcealMembershipCommand.command",
+	Tag:           "bytes,134,opt,name=command",
+}
+
+type AddDeviceCommand struct {
+	Device           *DeviceInfo `protobuf:"bytes,1,req" json:"Device,omitempty"`
+	XXX_unrecognized []byte      `json:"-"`
+}
+
+func (m *AddDeviceCommand) Reset()         { *m = AddDeviceCommand{} }
+func (m *AddDeviceCommand) String() string { return proto.CompactTextString(m) }
+func (*AddDeviceCommand) ProtoMessage()    {}
+
+func (m *AddDeviceCommand) GetDevice() *DeviceInfo {
+	if m != nil {
+		return m.Device
+	}
+	return nil
+}
+
+var E_AddDeviceCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*AddDeviceCommand)(nil),
+	Field:         135,
+	Name:          "internal.AddDeviceCommand.command",
+	Tag:           "bytes,135,opt,name=command",
+}
+
+type UpdateDeviceCommand struct {
+	Device           *DeviceInfo `protobuf:"bytes,1,req" json:"Device,omitempty"`
+	XXX_unrecognized []byte      `json:"-"`
+}
+
+func (m *UpdateDeviceCommand) Reset()         { *m = UpdateDeviceCommand{} }
+func (m *UpdateDeviceCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateDeviceCommand) ProtoMessage()    {}
+
+func (m *UpdateDeviceCommand) GetDevice() *DeviceInfo {
+	if m != nil {
+		return m.Device
+	}
+	return nil
+}
+
+var E_UpdateDeviceCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateDeviceCommand)(nil),
+	Field:         136,
+	Name:          "internal.UpdateDeviceCommand.command",
+	Tag:           "bytes,136,opt,name=command",
+}
+
+type DeleteDeviceCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DeleteDeviceCommand) Reset()         { *m = DeleteDeviceCommand{} }
+func (m *DeleteDeviceCommand) String() string { return proto.CompactTextString(m) }
+func (*DeleteDeviceCommand) ProtoMessage()    {}
+
+func (m *DeleteDeviceCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+var E_DeleteDeviceCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DeleteDeviceCommand)(nil),
+	Field:         137,
+	Name:          "internal.DeleteDeviceCommand.command",
+	Tag:           "bytes,137,opt,name=command",
+}
+
+type CreateAccountCommand struct {
+	Account          *AccountInfo `protobuf:"bytes,1,req" json:"Account,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
+}
+
+func (m *CreateAccountCommand) Reset()         { *m = CreateAccountCommand{} }
+func (m *CreateAccountCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateAccountCommand) ProtoMessage()    {}
+
+func (m *CreateAccountCommand) GetAccount() *AccountInfo {
+	if m != nil {
+		return m.Account
+	}
+	return nil
+}
+
+var E_CreateAccountCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateAccountCommand)(nil),
+	Field:         138,
+	Name:          "internal.CreateAccountCommand.command",
+	Tag:           "bytes,138,opt,name=command",
+}
+
+type UpdateAccountCommand struct {
+	Account          *AccountInfo `protobuf:"bytes,1,req" json:"Account,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
+}
+
+func (m *UpdateAccountCommand) Reset()         { *m = UpdateAccountCommand{} }
+func (m *UpdateAccountCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateAccountCommand) ProtoMessage()    {}
+
+func (m *UpdateAccountCommand) GetAccount() *AccountInfo {
+	if m != nil {
+		return m.Account
+	}
+	return nil
+}
+
+var E_UpdateAccountCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*UpdateAccountCommand)(nil),
+	Field:         139,
+	Name:          "internal.UpdateAccountCommand.command",
+	Tag:           "bytes,139,opt,name=command",
+}
+
+type RenameNamespaceCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	Path             *string `protobuf:"bytes,2,req" json:"Path,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RenameNamespaceCommand) Reset()         { *m = RenameNamespaceCommand{} }
+func (m *RenameNamespaceCommand) String() string { return proto.CompactTextString(m) }
+func (*RenameNamespaceCommand) ProtoMessage()    {}
+
+func (m *RenameNamespaceCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *RenameNamespaceCommand) GetPath() string {
+	if m != nil && m.Path != nil {
+		return *m.Path
 	}
 	return ""
 }
 
-func (m *UpdateRetentionPolicyCommand) GetName() string {
-	if m != nil && m.Name != nil {
-		return *m.Name
+func (m *RenameNamespaceCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_RenameNamespaceCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*RenameNamespaceCommand)(nil),
+	Field:         140,
+	Name:          "internal.RenameNamespaceCommand.command",
+	Tag:           "bytes,140,opt,name=command",
+}
+
+type CreateInvitationCommand struct {
+	Invitation       *InvitationInfo `protobuf:"bytes,1,req" json:"Invitation,omitempty"`
+	XXX_unrecognized []byte          `json:"-"`
+}
+
+func (m *CreateInvitationCommand) Reset()         { *m = CreateInvitationCommand{} }
+func (m *CreateInvitationCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateInvitationCommand) ProtoMessage()    {}
+
+func (m *CreateInvitationCommand) GetInvitation() *InvitationInfo {
+	if m != nil {
+		return m.Invitation
+	}
+	return nil
+}
+
+var E_CreateInvitationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateInvitationCommand)(nil),
+	Field:         141,
+	Name:          "internal.CreateInvitationCommand.command",
+	Tag:           "bytes,141,opt,name=command",
+}
+
+type ResendInvitationCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	TokenHash        *string `protobuf:"bytes,2,req" json:"TokenHash,omitempty"`
+	SentAt           *int64  `protobuf:"varint,3,req" json:"SentAt,omitempty"`
+	ExpiresAt        *int64  `protobuf:"varint,4,req" json:"ExpiresAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ResendInvitationCommand) Reset()         { *m = ResendInvitationCommand{} }
+func (m *ResendInvitationCommand) String() string { return proto.CompactTextString(m) }
+func (*ResendInvitationCommand) ProtoMessage()    {}
+
+func (m *ResendInvitationCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
 	}
 	return ""
 }
 
-func (m *UpdateRetentionPolicyCommand) GetNewName() string {
-	if m != nil && m.NewName != nil {
-		return *m.NewName
+func (m *ResendInvitationCommand) GetTokenHash() string {
+	if m != nil && m.TokenHash != nil {
+		return *m.TokenHash
 	}
 	return ""
 }
 
-func (m *UpdateRetentionPolicyCommand) GetDuration() int64 {
-	if m != nil && m.Duration != nil {
-		return *m.Duration
+func (m *ResendInvitationCommand) GetSentAt() int64 {
+	if m != nil && m.SentAt != nil {
+		return *m.SentAt
 	}
 	return 0
 }
 
-func (m *UpdateRetentionPolicyCommand) GetReplicaN() uint32 {
-	if m != nil && m.ReplicaN != nil {
-		return *m.ReplicaN
+func (m *ResendInvitationCommand) GetExpiresAt() int64 {
+	if m != nil && m.ExpiresAt != nil {
+		return *m.ExpiresAt
 	}
 	return 0
 }
 
-var E_UpdateRetentionPolicyCommand_Command = &proto.ExtensionDesc{
+var E_ResendInvitationCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*UpdateRetentionPolicyCommand)(nil),
-	Field:         108,
-	Name:          "internal.UpdateRetentionPolicyCommand.command",
-	Tag:           "bytes,108,opt,name=command",
+	ExtensionType: (*ResendInvitationCommand)(nil),
+	Field:         142,
+	Name:          "internal.ResendInvitationCommand.command",
+	Tag:           "bytes,142,opt,name=command",
 }
 
-type CreateShardGroupCommand struct {
-	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
-	Policy           *string `protobuf:"bytes,2,req" json:"Policy,omitempty"`
-	Timestamp        *int64  `protobuf:"varint,3,req" json:"Timestamp,omitempty"`
+type AcceptInvitationCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req" json:"Time,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *CreateShardGroupCommand) Reset()         { *m = CreateShardGroupCommand{} }
-func (m *CreateShardGroupCommand) String() string { return proto.CompactTextString(m) }
-func (*CreateShardGroupCommand) ProtoMessage()    {}
+func (m *AcceptInvitationCommand) Reset()         { *m = AcceptInvitationCommand{} }
+func (m *AcceptInvitationCommand) String() string { return proto.CompactTextString(m) }
+func (*AcceptInvitationCommand) ProtoMessage()    {}
 
-func (m *CreateShardGroupCommand) GetDatabase() string {
-	if m != nil && m.Database != nil {
-		return *m.Database
+func (m *AcceptInvitationCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
 	}
 	return ""
 }
 
-func (m *CreateShardGroupCommand) GetPolicy() string {
-	if m != nil && m.Policy != nil {
-		return *m.Policy
+func (m *AcceptInvitationCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
 	}
 	return ""
 }
 
-func (m *CreateShardGroupCommand) GetTimestamp() int64 {
-	if m != nil && m.Timestamp != nil {
-		return *m.Timestamp
+func (m *AcceptInvitationCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
 	}
 	return 0
 }
 
-var E_CreateShardGroupCommand_Command = &proto.ExtensionDesc{
+var E_AcceptInvitationCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*CreateShardGroupCommand)(nil),
-	Field:         109,
-	Name:          "internal.CreateShardGroupCommand.command",
-	Tag:           "bytes,109,opt,name=command",
+	ExtensionType: (*AcceptInvitationCommand)(nil),
+	Field:         143,
+	Name:          "internal.AcceptInvitationCommand.command",
+	Tag:           "bytes,143,opt,name=command",
 }
 
-type DeleteShardGroupCommand struct {
-	Database         *string `protobuf:"bytes,1,req" json:"Database,omitempty"`
-	Policy           *string `protobuf:"bytes,2,req" json:"Policy,omitempty"`
-	ShardGroupID     *uint64 `protobuf:"varint,3,req" json:"ShardGroupID,omitempty"`
+type DeleteInvitationCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *DeleteShardGroupCommand) Reset()         { *m = DeleteShardGroupCommand{} }
-func (m *DeleteShardGroupCommand) String() string { return proto.CompactTextString(m) }
-func (*DeleteShardGroupCommand) ProtoMessage()    {}
+func (m *DeleteInvitationCommand) Reset()         { *m = DeleteInvitationCommand{} }
+func (m *DeleteInvitationCommand) String() string { return proto.CompactTextString(m) }
+func (*DeleteInvitationCommand) ProtoMessage()    {}
 
-func (m *DeleteShardGroupCommand) GetDatabase() string {
-	if m != nil && m.Database != nil {
-		return *m.Database
+func (m *DeleteInvitationCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
 	}
 	return ""
 }
 
-func (m *DeleteShardGroupCommand) GetPolicy() string {
-	if m != nil && m.Policy != nil {
-		return *m.Policy
+var E_DeleteInvitationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DeleteInvitationCommand)(nil),
+	Field:         144,
+	Name:          "internal.DeleteInvitationCommand.command",
+	Tag:           "bytes,144,opt,name=command",
+}
+
+type CreateTeamCommand struct {
+	Team             *TeamInfo `protobuf:"bytes,1,req" json:"Team,omitempty"`
+	XXX_unrecognized []byte    `json:"-"`
+}
+
+func (m *CreateTeamCommand) Reset()         { *m = CreateTeamCommand{} }
+func (m *CreateTeamCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateTeamCommand) ProtoMessage()    {}
+
+func (m *CreateTeamCommand) GetTeam() *TeamInfo {
+	if m != nil {
+		return m.Team
 	}
-	return ""
+	return nil
 }
 
-func (m *DeleteShardGroupCommand) GetShardGroupID() uint64 {
-	if m != nil && m.ShardGroupID != nil {
-		return *m.ShardGroupID
+var E_CreateTeamCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*CreateTeamCommand)(nil),
+	Field:         145,
+	Name:          "internal.CreateTeamCommand.command",
+	Tag:           "bytes,145,opt,name=command",
+}
+
+type UpdateTeamCommand struct {
+	Team             *TeamInfo `protobuf:"bytes,1,req" json:"Team,omitempty"`
+	XXX_unrecognized []byte    `json:"-"`
+}
+
+func (m *UpdateTeamCommand) Reset()         { *m = UpdateTeamCommand{} }
+func (m *UpdateTeamCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateTeamCommand) ProtoMessage()    {}
+
+func (m *UpdateTeamCommand) GetTeam() *TeamInfo {
+	if m != nil {
+		return m.Team
 	}
-	return 0
+	return nil
 }
 
-var E_DeleteShardGroupCommand_Command = &proto.ExtensionDesc{
+var E_UpdateTeamCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*DeleteShardGroupCommand)(nil),
-	Field:         110,
-	Name:          "internal.DeleteShardGroupCommand.command",
-	Tag:           "bytes,110,opt,name=command",
+	ExtensionType: (*UpdateTeamCommand)(nil),
+	Field:         146,
+	Name:          "internal.UpdateTeamCommand.command",
+	Tag:           "bytes,146,opt,name=command",
 }
 
-type CreateUserCommand struct {
-	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
-	Hash             *string `protobuf:"bytes,2,req" json:"Hash,omitempty"`
-	Admin            *bool   `protobuf:"varint,3,req" json:"Admin,omitempty"`
+type DropTeamCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *CreateUserCommand) Reset()         { *m = CreateUserCommand{} }
-func (m *CreateUserCommand) String() string { return proto.CompactTextString(m) }
-func (*CreateUserCommand) ProtoMessage()    {}
+func (m *DropTeamCommand) Reset()         { *m = DropTeamCommand{} }
+func (m *DropTeamCommand) String() string { return proto.CompactTextString(m) }
+func (*DropTeamCommand) ProtoMessage()    {}
 
-func (m *CreateUserCommand) GetName() string {
-	if m != nil && m.Name != nil {
-		return *m.Name
+func (m *DropTeamCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
 	}
 	return ""
 }
 
-func (m *CreateUserCommand) GetHash() string {
-	if m != nil && m.Hash != nil {
-		return *m.Hash
+var E_DropTeamCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DropTeamCommand)(nil),
+	Field:         147,
+	Name:          "internal.DropTeamCommand.command",
+	Tag:           "bytes,147,opt,name=command",
+}
+
+type AddTeamMemberCommand struct {
+	TeamID           *string `protobuf:"bytes,1,req" json:"TeamID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AddTeamMemberCommand) Reset()         { *m = AddTeamMemberCommand{} }
+func (m *AddTeamMemberCommand) String() string { return proto.CompactTextString(m) }
+func (*AddTeamMemberCommand) ProtoMessage()    {}
+
+func (m *AddTeamMemberCommand) GetTeamID() string {
+	if m != nil && m.TeamID != nil {
+		return *m.TeamID
 	}
 	return ""
 }
 
-func (m *CreateUserCommand) GetAdmin() bool {
-	if m != nil && m.Admin != nil {
-		return *m.Admin
+func (m *AddTeamMemberCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
 	}
-	return false
+	return ""
 }
 
-var E_CreateUserCommand_Command = &proto.ExtensionDesc{
+func (m *AddTeamMemberCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_AddTeamMemberCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*CreateUserCommand)(nil),
-	Field:         111,
-	Name:          "internal.CreateUserCommand.command",
-	Tag:           "bytes,111,opt,name=command",
+	ExtensionType: (*AddTeamMemberCommand)(nil),
+	Field:         148,
+	Name:          "internal.AddTeamMemberCommand.command",
+	Tag:           "bytes,148,opt,name=command",
 }
 
-type DropUserCommand struct {
-	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
+type RemoveTeamMemberCommand struct {
+	TeamID           *string `protobuf:"bytes,1,req" json:"TeamID,omitempty"`
+	UserID           *string `protobuf:"bytes,2,req" json:"UserID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *DropUserCommand) Reset()         { *m = DropUserCommand{} }
-func (m *DropUserCommand) String() string { return proto.CompactTextString(m) }
-func (*DropUserCommand) ProtoMessage()    {}
+func (m *RemoveTeamMemberCommand) Reset()         { *m = RemoveTeamMemberCommand{} }
+func (m *RemoveTeamMemberCommand) String() string { return proto.CompactTextString(m) }
+func (*RemoveTeamMemberCommand) ProtoMessage()    {}
 
-func (m *DropUserCommand) GetName() string {
-	if m != nil && m.Name != nil {
-		return *m.Name
+func (m *RemoveTeamMemberCommand) GetTeamID() string {
+	if m != nil && m.TeamID != nil {
+		return *m.TeamID
 	}
 	return ""
 }
 
-var E_DropUserCommand_Command = &proto.ExtensionDesc{
+func (m *RemoveTeamMemberCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+var E_RemoveTeamMemberCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*DropUserCommand)(nil),
-	Field:         112,
-	Name:          "internal.DropUserCommand.command",
-	Tag:           "bytes,112,opt,name=command",
+	ExtensionType: (*RemoveTeamMemberCommand)(nil),
+	Field:         149,
+	Name:          "internal.RemoveTeamMemberCommand.command",
+	Tag:           "bytes,149,opt,name=command",
 }
 
-type UpdateUserCommand struct {
-	Name             *string `protobuf:"bytes,1,req" json:"Name,omitempty"`
-	Hash             *string `protobuf:"bytes,2,req" json:"Hash,omitempty"`
+type GrantTeamConversationCommand struct {
+	TeamID           *string `protobuf:"bytes,1,req" json:"TeamID,omitempty"`
+	ConversationID   *string `protobuf:"bytes,2,req" json:"ConversationID,omitempty"`
+	Time             *int64  `protobuf:"varint,3,req" json:"Time,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *UpdateUserCommand) Reset()         { *m = UpdateUserCommand{} }
-func (m *UpdateUserCommand) String() string { return proto.CompactTextString(m) }
-func (*UpdateUserCommand) ProtoMessage()    {}
+func (m *GrantTeamConversationCommand) Reset()         { *m = GrantTeamConversationCommand{} }
+func (m *GrantTeamConversationCommand) String() string { return proto.CompactTextString(m) }
+func (*GrantTeamConversationCommand) ProtoMessage()    {}
 
-func (m *UpdateUserCommand) GetName() string {
-	if m != nil && m.Name != nil {
-		return *m.Name
+func (m *GrantTeamConversationCommand) GetTeamID() string {
+	if m != nil && m.TeamID != nil {
+		return *m.TeamID
 	}
 	return ""
 }
 
-func (m *UpdateUserCommand) GetHash() string {
-	if m != nil && m.Hash != nil {
-		return *m.Hash
+func (m *GrantTeamConversationCommand) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
 	}
 	return ""
 }
 
-var E_UpdateUserCommand_Command = &proto.ExtensionDesc{
+func (m *GrantTeamConversationCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_GrantTeamConversationCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*UpdateUserCommand)(nil),
-	Field:         113,
-	Name:          "internal.UpdateUserCommand.command",
-	Tag:           "bytes,113,opt,name=command",
+	ExtensionType: (*GrantTeamConversationCommand)(nil),
+	Field:         150,
+	Name:          "internal.GrantTeamConversationCommand.command",
+	Tag:           "bytes,150,opt,name=command",
 }
 
-type SetPrivilegeCommand struct {
-	Username         *string `protobuf:"bytes,1,req" json:"Username,omitempty"`
-	Database         *string `protobuf:"bytes,2,req" json:"Database,omitempty"`
-	Privilege        *int32  `protobuf:"varint,3,req" json:"Privilege,omitempty"`
+type RevokeTeamConversationCommand struct {
+	TeamID           *string `protobuf:"bytes,1,req" json:"TeamID,omitempty"`
+	ConversationID   *string `protobuf:"bytes,2,req" json:"ConversationID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *SetPrivilegeCommand) Reset()         { *m = SetPrivilegeCommand{} }
-func (m *SetPrivilegeCommand) String() string { return proto.CompactTextString(m) }
-func (*SetPrivilegeCommand) ProtoMessage()    {}
+func (m *RevokeTeamConversationCommand) Reset()         { *m = RevokeTeamConversationCommand{} }
+func (m *RevokeTeamConversationCommand) String() string { return proto.CompactTextString(m) }
+func (*RevokeTeamConversationCommand) ProtoMessage()    {}
 
-func (m *SetPrivilegeCommand) GetUsername() string {
-	if m != nil && m.Username != nil {
-		return *m.Username
+func (m *RevokeTeamConversationCommand) GetTeamID() string {
+	if m != nil && m.TeamID != nil {
+		return *m.TeamID
 	}
 	return ""
 }
 
-func (m *SetPrivilegeCommand) GetDatabase() string {
-	if m != nil && m.Database != nil {
-		return *m.Database
+func (m *RevokeTeamConversationCommand) GetConversationID() string {
+	if m != nil && m.ConversationID != nil {
+		return *m.ConversationID
 	}
 	return ""
 }
 
-func (m *SetPrivilegeCommand) GetPrivilege() int32 {
-	if m != nil && m.Privilege != nil {
-		return *m.Privilege
+var E_RevokeTeamConversationCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*RevokeTeamConversationCommand)(nil),
+	Field:         151,
+	Name:          "internal.RevokeTeamConversationCommand.command",
+	Tag:           "bytes,151,opt,name=command",
+}
+
+type RevokeTokenCommand struct {
+	Token            *RevokedTokenInfo `protobuf:"bytes,1,req" json:"Token,omitempty"`
+	Time             *int64            `protobuf:"varint,2,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
+}
+
+func (m *RevokeTokenCommand) Reset()         { *m = RevokeTokenCommand{} }
+func (m *RevokeTokenCommand) String() string { return proto.CompactTextString(m) }
+func (*RevokeTokenCommand) ProtoMessage()    {}
+
+func (m *RevokeTokenCommand) GetToken() *RevokedTokenInfo {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+func (m *RevokeTokenCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
 	}
 	return 0
 }
 
-var E_SetPrivilegeCommand_Command = &proto.ExtensionDesc{
+var E_RevokeTokenCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetPrivilegeCommand)(nil),
-	Field:         114,
-	Name:          "internal.SetPrivilegeCommand.command",
-	Tag:           "bytes,114,opt,name=command",
+	ExtensionType: (*RevokeTokenCommand)(nil),
+	Field:         152,
+	Name:          "internal.RevokeTokenCommand.command",
+	Tag:           "bytes,152,opt,name=command",
 }
 
-type SetDataCommand struct {
-	Data             *Data  `protobuf:"bytes,1,req" json:"Data,omitempty"`
-	XXX_unrecognized []byte `json:"-"`
+type CreateApiTokenCommand struct {
+	Token            *ApiTokenInfo `protobuf:"bytes,1,req" json:"Token,omitempty"`
+	XXX_unrecognized []byte        `json:"-"`
 }
 
-func (m *SetDataCommand) Reset()         { *m = SetDataCommand{} }
-func (m *SetDataCommand) String() string { return proto.CompactTextString(m) }
-func (*SetDataCommand) ProtoMessage()    {}
+func (m *CreateApiTokenCommand) Reset()         { *m = CreateApiTokenCommand{} }
+func (m *CreateApiTokenCommand) String() string { return proto.CompactTextString(m) }
+func (*CreateApiTokenCommand) ProtoMessage()    {}
 
-func (m *SetDataCommand) GetData() *Data {
+func (m *CreateApiTokenCommand) GetToken() *ApiTokenInfo {
 	if m != nil {
-		return m.Data
+		return m.Token
 	}
 	return nil
 }
 
-var E_SetDataCommand_Command = &proto.ExtensionDesc{
+var E_CreateApiTokenCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetDataCommand)(nil),
-	Field:         115,
-	Name:          "internal.SetDataCommand.command",
-	Tag:           "bytes,115,opt,name=command",
+	ExtensionType: (*CreateApiTokenCommand)(nil),
+	Field:         153,
+	Name:          "internal.CreateApiTokenCommand.command",
+	Tag:           "bytes,153,opt,name=command",
 }
 
-type SetAdminPrivilegeCommand struct {
-	Username         *string `protobuf:"bytes,1,req" json:"Username,omitempty"`
-	Admin            *bool   `protobuf:"varint,2,req" json:"Admin,omitempty"`
+type DeleteApiTokenCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *SetAdminPrivilegeCommand) Reset()         { *m = SetAdminPrivilegeCommand{} }
-func (m *SetAdminPrivilegeCommand) String() string { return proto.CompactTextString(m) }
-func (*SetAdminPrivilegeCommand) ProtoMessage()    {}
+func (m *DeleteApiTokenCommand) Reset()         { *m = DeleteApiTokenCommand{} }
+func (m *DeleteApiTokenCommand) String() string { return proto.CompactTextString(m) }
+func (*DeleteApiTokenCommand) ProtoMessage()    {}
 
-func (m *SetAdminPrivilegeCommand) GetUsername() string {
-	if m != nil && m.Username != nil {
-		return *m.Username
+func (m *DeleteApiTokenCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
 	}
 	return ""
 }
 
-func (m *SetAdminPrivilegeCommand) GetAdmin() bool {
-	if m != nil && m.Admin != nil {
-		return *m.Admin
+var E_DeleteApiTokenCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*DeleteApiTokenCommand)(nil),
+	Field:         154,
+	Name:          "internal.DeleteApiTokenCommand.command",
+	Tag:           "bytes,154,opt,name=command",
+}
+
+type TouchApiTokenCommand struct {
+	ID               *string `protobuf:"bytes,1,req" json:"ID,omitempty"`
+	Time             *int64  `protobuf:"varint,2,req" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TouchApiTokenCommand) Reset()         { *m = TouchApiTokenCommand{} }
+func (m *TouchApiTokenCommand) String() string { return proto.CompactTextString(m) }
+func (*TouchApiTokenCommand) ProtoMessage()    {}
+
+func (m *TouchApiTokenCommand) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
 	}
-	return false
+	return ""
 }
 
-var E_SetAdminPrivilegeCommand_Command = &proto.ExtensionDesc{
+func (m *TouchApiTokenCommand) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+var E_TouchApiTokenCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetAdminPrivilegeCommand)(nil),
-	Field:         116,
-	Name:          "internal.SetAdminPrivilegeCommand.command",
-	Tag:           "bytes,116,opt,name=command",
+	ExtensionType: (*TouchApiTokenCommand)(nil),
+	Field:         155,
+	Name:          "internal.TouchApiTokenCommand.command",
+	Tag:           "bytes,155,opt,name=command",
+}
+
+type LinkIdentityCommand struct {
+	UserID           *string       `protobuf:"bytes,1,req" json:"UserID,omitempty"`
+	Identity         *IdentityInfo `protobuf:"bytes,2,req" json:"Identity,omitempty"`
+	XXX_unrecognized []byte        `json:"-"`
+}
+
+func (m *LinkIdentityCommand) Reset()         { *m = LinkIdentityCommand{} }
+func (m *LinkIdentityCommand) String() string { return proto.CompactTextString(m) }
+func (*LinkIdentityCommand) ProtoMessage()    {}
+
+func (m *LinkIdentityCommand) GetUserID() string {
+	if m != nil && m.UserID != nil {
+		return *m.UserID
+	}
+	return ""
+}
+
+func (m *LinkIdentityCommand) GetIdentity() *IdentityInfo {
+	if m != nil {
+		return m.Identity
+	}
+	return nil
+}
+
+var E_LinkIdentityCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*LinkIdentityCommand)(nil),
+	Field:         156,
+	Name:          "internal.LinkIdentityCommand.command",
+	Tag:           "bytes,156,opt,name=command",
 }
 
 type Response struct {
@@ -1091,4 +3658,44 @@ func init() {
 	proto.RegisterExtension(E_SetPrivilegeCommand_Command)
 	proto.RegisterExtension(E_SetDataCommand_Command)
 	proto.RegisterExtension(E_SetAdminPrivilegeCommand_Command)
+	proto.RegisterExtension(E_SetReadMarkerCommand_Command)
+	proto.RegisterExtension(E_CreateAttachmentCommand_Command)
+	proto.RegisterExtension(E_SetAttachmentMessageCommand_Command)
+	proto.RegisterExtension(E_DeleteAttachmentCommand_Command)
+	proto.RegisterExtension(E_AddParticipantCommand_Command)
+	proto.RegisterExtension(E_RemoveParticipantCommand_Command)
+	proto.RegisterExtension(E_SetParticipantActivityCommand_Command)
+	proto.RegisterExtension(E_CreateOrganizationCommand_Command)
+	proto.RegisterExtension(E_DropOrganizationCommand_Command)
+	proto.RegisterExtension(E_UpdateOrganizationCommand_Command)
+	proto.RegisterExtension(E_AddOrUpdateMembershipCommand_Command)
+	proto.RegisterExtension(E_RemoveMembershipCommand_Command)
+	proto.RegisterExtension(E_EditMyMembershipCommand_Command)
+	proto.RegisterExtension(E_CreateConversationCommand_Command)
+	proto.RegisterExtension(E_DropConversationCommand_Command)
+	proto.RegisterExtension(E_UpdateConversationCommand_Command)
+	proto.RegisterExtension(E_PublicizeMembershipCommand_Command)
+	proto.RegisterExtension(E_ConcealMembershipCommand_Command)
+	proto.RegisterExtension(E_AddDeviceCommand_Command)
+	proto.RegisterExtension(E_UpdateDeviceCommand_Command)
+	proto.RegisterExtension(E_DeleteDeviceCommand_Command)
+	proto.RegisterExtension(E_CreateAccountCommand_Command)
+	proto.RegisterExtension(E_UpdateAccountCommand_Command)
+	proto.RegisterExtension(E_RenameNamespaceCommand_Command)
+	proto.RegisterExtension(E_CreateInvitationCommand_Command)
+	proto.RegisterExtension(E_ResendInvitationCommand_Command)
+	proto.RegisterExtension(E_AcceptInvitationCommand_Command)
+	proto.RegisterExtension(E_DeleteInvitationCommand_Command)
+	proto.RegisterExtension(E_CreateTeamCommand_Command)
+	proto.RegisterExtension(E_UpdateTeamCommand_Command)
+	proto.RegisterExtension(E_DropTeamCommand_Command)
+	proto.RegisterExtension(E_AddTeamMemberCommand_Command)
+	proto.RegisterExtension(E_RemoveTeamMemberCommand_Command)
+	proto.RegisterExtension(E_GrantTeamConversationCommand_Command)
+	proto.RegisterExtension(E_RevokeTeamConversationCommand_Command)
+	proto.RegisterExtension(E_RevokeTokenCommand_Command)
+	proto.RegisterExtension(E_CreateApiTokenCommand_Command)
+	proto.RegisterExtension(E_DeleteApiTokenCommand_Command)
+	proto.RegisterExtension(E_TouchApiTokenCommand_Command)
+	proto.RegisterExtension(E_LinkIdentityCommand_Command)
 }
@@ -0,0 +1,57 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// InvitationInfo represents a pending invitation of an email address to join an organization.
+type InvitationInfo struct {
+	ID             string
+	OrganizationID string
+	Email          string
+	Role           string // role of the membership created when the invitation is accepted
+	InvitedBy      string
+	TokenHash      string // hash of the token sent with the invitation, the token itself is never stored
+	SentAt         time.Time
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+}
+
+// Expired returns true if the invitation can no longer be accepted at t.
+func (ii *InvitationInfo) Expired(t time.Time) bool {
+	return !ii.ExpiresAt.IsZero() && !t.Before(ii.ExpiresAt)
+}
+
+// clone returns a deep copy of ii.
+func (ii InvitationInfo) clone() InvitationInfo { return ii }
+
+// marshal serializes to a protobuf representation.
+func (ii InvitationInfo) marshal() *internal.InvitationInfo {
+	return &internal.InvitationInfo{
+		ID:             proto.String(ii.ID),
+		OrganizationID: proto.String(ii.OrganizationID),
+		Email:          proto.String(ii.Email),
+		Role:           proto.String(ii.Role),
+		InvitedBy:      proto.String(ii.InvitedBy),
+		TokenHash:      proto.String(ii.TokenHash),
+		SentAt:         proto.Int64(MarshalTime(ii.SentAt)),
+		ExpiresAt:      proto.Int64(MarshalTime(ii.ExpiresAt)),
+		CreatedAt:      proto.Int64(MarshalTime(ii.CreatedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ii *InvitationInfo) unmarshal(pb *internal.InvitationInfo) {
+	ii.ID = pb.GetID()
+	ii.OrganizationID = pb.GetOrganizationID()
+	ii.Email = pb.GetEmail()
+	ii.Role = pb.GetRole()
+	ii.InvitedBy = pb.GetInvitedBy()
+	ii.TokenHash = pb.GetTokenHash()
+	ii.SentAt = UnmarshalTime(pb.GetSentAt())
+	ii.ExpiresAt = UnmarshalTime(pb.GetExpiresAt())
+	ii.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+}
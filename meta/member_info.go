@@ -0,0 +1,70 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// Membership roles.
+const (
+	MemberRoleOwner  = "owner"
+	MemberRoleMember = "member"
+	MemberRoleGuest  = "guest"
+)
+
+// Membership states.
+const (
+	MemberStatePending = "pending"
+	MemberStateActive  = "active"
+)
+
+// MemberInfo represents the membership of a user in an organization.
+type MemberInfo struct {
+	OrganizationID string
+	UserID         string
+	Role           string
+	State          string
+	Published      bool // membership is visible to everyone
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	InvitedBy        string // user who sent the accepted invitation
+	InviteEmail      string // email address the invitation was sent to
+	InviteAcceptedAt time.Time
+}
+
+// clone returns a deep copy of mi.
+func (mi MemberInfo) clone() MemberInfo { return mi }
+
+// marshal serializes to a protobuf representation.
+func (mi MemberInfo) marshal() *internal.MemberInfo {
+	return &internal.MemberInfo{
+		OrganizationID: proto.String(mi.OrganizationID),
+		UserID:         proto.String(mi.UserID),
+		Role:           proto.String(mi.Role),
+		State:          proto.String(mi.State),
+		Published:      proto.Bool(mi.Published),
+		CreatedAt:      proto.Int64(MarshalTime(mi.CreatedAt)),
+		UpdatedAt:      proto.Int64(MarshalTime(mi.UpdatedAt)),
+
+		InvitedBy:        proto.String(mi.InvitedBy),
+		InviteEmail:      proto.String(mi.InviteEmail),
+		InviteAcceptedAt: proto.Int64(MarshalTime(mi.InviteAcceptedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (mi *MemberInfo) unmarshal(pb *internal.MemberInfo) {
+	mi.OrganizationID = pb.GetOrganizationID()
+	mi.UserID = pb.GetUserID()
+	mi.Role = pb.GetRole()
+	mi.State = pb.GetState()
+	mi.Published = pb.GetPublished()
+	mi.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+	mi.UpdatedAt = UnmarshalTime(pb.GetUpdatedAt())
+	mi.InvitedBy = pb.GetInvitedBy()
+	mi.InviteEmail = pb.GetInviteEmail()
+	mi.InviteAcceptedAt = UnmarshalTime(pb.GetInviteAcceptedAt())
+}
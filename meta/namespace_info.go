@@ -0,0 +1,63 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// Namespace owner types.
+const (
+	// NamespaceOwnerUser is the owner type of the namespace of a user account.
+	NamespaceOwnerUser = "user"
+
+	// NamespaceOwnerOrganization is the owner type of the namespace of an organization.
+	NamespaceOwnerOrganization = "org"
+)
+
+// NamespaceInfo represents the unique path of a user account or an organization. Paths are
+// case insensitive and stored in lowercase.
+type NamespaceInfo struct {
+	ID        string
+	Path      string
+	OwnerID   string
+	OwnerType string
+	Redirects []string // previous paths, redirected to the current one
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// clone returns a deep copy of ni.
+func (ni NamespaceInfo) clone() NamespaceInfo {
+	other := ni
+	if ni.Redirects != nil {
+		other.Redirects = make([]string, len(ni.Redirects))
+		copy(other.Redirects, ni.Redirects)
+	}
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (ni NamespaceInfo) marshal() *internal.NamespaceInfo {
+	return &internal.NamespaceInfo{
+		ID:        proto.String(ni.ID),
+		Path:      proto.String(ni.Path),
+		OwnerID:   proto.String(ni.OwnerID),
+		OwnerType: proto.String(ni.OwnerType),
+		Redirects: ni.Redirects,
+		CreatedAt: proto.Int64(MarshalTime(ni.CreatedAt)),
+		UpdatedAt: proto.Int64(MarshalTime(ni.UpdatedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ni *NamespaceInfo) unmarshal(pb *internal.NamespaceInfo) {
+	ni.ID = pb.GetID()
+	ni.Path = pb.GetPath()
+	ni.OwnerID = pb.GetOwnerID()
+	ni.OwnerType = pb.GetOwnerType()
+	ni.Redirects = pb.GetRedirects()
+	ni.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+	ni.UpdatedAt = UnmarshalTime(pb.GetUpdatedAt())
+}
@@ -0,0 +1,55 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// OrganizationInfo represents an organization, team, group or company owning a namespace.
+type OrganizationInfo struct {
+	ID           string
+	NamespaceID  string
+	Name         string
+	Description  string
+	URL          string
+	Location     string
+	Email        string
+	BillingEmail string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// clone returns a deep copy of oi.
+func (oi OrganizationInfo) clone() OrganizationInfo { return oi }
+
+// marshal serializes to a protobuf representation.
+func (oi OrganizationInfo) marshal() *internal.OrganizationInfo {
+	return &internal.OrganizationInfo{
+		ID:           proto.String(oi.ID),
+		NamespaceID:  proto.String(oi.NamespaceID),
+		Name:         proto.String(oi.Name),
+		Description:  proto.String(oi.Description),
+		URL:          proto.String(oi.URL),
+		Location:     proto.String(oi.Location),
+		Email:        proto.String(oi.Email),
+		BillingEmail: proto.String(oi.BillingEmail),
+		CreatedAt:    proto.Int64(MarshalTime(oi.CreatedAt)),
+		UpdatedAt:    proto.Int64(MarshalTime(oi.UpdatedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (oi *OrganizationInfo) unmarshal(pb *internal.OrganizationInfo) {
+	oi.ID = pb.GetID()
+	oi.NamespaceID = pb.GetNamespaceID()
+	oi.Name = pb.GetName()
+	oi.Description = pb.GetDescription()
+	oi.URL = pb.GetURL()
+	oi.Location = pb.GetLocation()
+	oi.Email = pb.GetEmail()
+	oi.BillingEmail = pb.GetBillingEmail()
+	oi.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+	oi.UpdatedAt = UnmarshalTime(pb.GetUpdatedAt())
+}
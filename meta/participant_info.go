@@ -0,0 +1,47 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// ParticipantInfo represents the membership of a user in a conversation.
+type ParticipantInfo struct {
+	ConversationID string
+	UserID         string
+	JoinedAt       time.Time
+	LastActivityAt time.Time
+	Teams          []string // teams granting the conversation, empty when the user joined on its own
+}
+
+// clone returns a deep copy of pi.
+func (pi ParticipantInfo) clone() ParticipantInfo {
+	other := pi
+	if pi.Teams != nil {
+		other.Teams = make([]string, len(pi.Teams))
+		copy(other.Teams, pi.Teams)
+	}
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (pi ParticipantInfo) marshal() *internal.ParticipantInfo {
+	return &internal.ParticipantInfo{
+		ConversationID: proto.String(pi.ConversationID),
+		UserID:         proto.String(pi.UserID),
+		JoinedAt:       proto.Int64(MarshalTime(pi.JoinedAt)),
+		LastActivityAt: proto.Int64(MarshalTime(pi.LastActivityAt)),
+		Teams:          pi.Teams,
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (pi *ParticipantInfo) unmarshal(pb *internal.ParticipantInfo) {
+	pi.ConversationID = pb.GetConversationID()
+	pi.UserID = pb.GetUserID()
+	pi.JoinedAt = UnmarshalTime(pb.GetJoinedAt())
+	pi.LastActivityAt = UnmarshalTime(pb.GetLastActivityAt())
+	pi.Teams = pb.GetTeams()
+}
@@ -0,0 +1,227 @@
+// Package policy decides which actions the members of an organization can perform, based on their role in the
+// organization owning the resource the action is on.
+package policy
+
+import (
+	"fmt"
+
+	"github.com/messagedb/messagedb/meta/schema"
+)
+
+// Role is the role of a user in the organization owning a resource.
+type Role int
+
+// Roles, from the least to the most privileged
+const (
+	// RoleNone is the role of users that are not active members of the organization, or of everyone when the
+	// resource is not owned by an organization
+	RoleNone Role = iota
+	RoleGuest
+	RoleMember
+	RoleOwner
+)
+
+var roles = [...]string{"none", "guest", "member", "owner"}
+
+func (r Role) String() string {
+	return roles[r]
+}
+
+// RoleOf returns the role granted by an organization membership. Pending memberships grant no role.
+func RoleOf(m *schema.Member) Role {
+	if m == nil || !m.IsActive() {
+		return RoleNone
+	}
+	switch m.Role {
+	case schema.MemberRoleOwner:
+		return RoleOwner
+	case schema.MemberRoleGuest:
+		return RoleGuest
+	}
+	return RoleMember
+}
+
+// Action is an operation the policy decides on.
+type Action string
+
+// Actions
+const (
+	ReadConversation   Action = "conversations:read"
+	JoinConversation   Action = "conversations:join"
+	ListConversations  Action = "conversations:list"
+	CreateConversation Action = "conversations:create"
+	PostMessage        Action = "messages:create"
+	DeleteMessage      Action = "messages:delete"
+	InviteMember       Action = "members:invite"
+	ManageIntegrations Action = "integrations:manage"
+	ManageApiTokens    Action = "tokens:manage"
+)
+
+// Subject is the user requesting an action.
+type Subject struct {
+	UserID string
+	Role   Role
+
+	// Participant is set when the user participates in the conversation the action is on
+	Participant bool
+}
+
+// Resource is what an action is on. Conversation is nil for actions on an organization.
+type Resource struct {
+	Conversation *schema.Conversation
+
+	// OwnerID is the user the resource belongs to, such as the sender of a message
+	OwnerID string
+}
+
+// Denial is the error returned when the policy denies an action.
+type Denial struct {
+	Action Action
+	Role   Role
+	Reason string
+}
+
+func (d *Denial) Error() string {
+	return d.Reason
+}
+
+// Rule returns the reason an action is denied, or an empty string when it is allowed.
+type Rule func(s Subject, r Resource) string
+
+// Policy is a set of rules, one for each action.
+type Policy struct {
+	rules map[Action]Rule
+}
+
+// New returns the default policy.
+//
+// Owners can do anything members can, invite new members, manage the API tokens of the organization and moderate the
+// messages of others. Members create conversations, read the public ones and manage the integrations of the
+// conversations they created. Guests only read and post to the conversations explicitly shared with them.
+func New() *Policy {
+	return &Policy{rules: map[Action]Rule{
+		ReadConversation:   canReadConversation,
+		JoinConversation:   canJoinConversation,
+		ListConversations:  canListConversations,
+		CreateConversation: canCreateConversation,
+		PostMessage:        canPostMessage,
+		DeleteMessage:      canDeleteMessage,
+		InviteMember:       canInviteMember,
+		ManageIntegrations: canManageIntegrations,
+		ManageApiTokens:    canManageApiTokens,
+	}}
+}
+
+// Authorize returns a *Denial if the subject cannot perform the action on the resource. Actions without a rule are
+// always denied.
+func (p *Policy) Authorize(s Subject, a Action, r Resource) error {
+	rule, ok := p.rules[a]
+	if !ok {
+		return &Denial{Action: a, Role: s.Role, Reason: fmt.Sprintf("No policy for action %s", a)}
+	}
+	if reason := rule(s, r); reason != "" {
+		return &Denial{Action: a, Role: s.Role, Reason: reason}
+	}
+	return nil
+}
+
+// Default is the policy consulted by the API.
+var Default = New()
+
+// Authorize returns a *Denial if the default policy does not allow the subject to perform the action on the resource.
+func Authorize(s Subject, a Action, r Resource) error {
+	return Default.Authorize(s, a, r)
+}
+
+func canReadConversation(s Subject, r Resource) string {
+	if s.Participant {
+		return ""
+	} else if s.Role == RoleGuest {
+		return "Guests can only read the conversations shared with them"
+	} else if r.Conversation.Privacy != schema.PrivacyPublic {
+		return "Only participants can read this conversation"
+	}
+	return ""
+}
+
+func canJoinConversation(s Subject, r Resource) string {
+	if s.Role == RoleGuest {
+		return "Guests cannot join conversations, they are added by the participants"
+	}
+	return ""
+}
+
+func canListConversations(s Subject, r Resource) string {
+	switch s.Role {
+	case RoleNone:
+		return "Only organization members can list its conversations"
+	case RoleGuest:
+		return "Guests can only list the conversations shared with them"
+	}
+	return ""
+}
+
+func canCreateConversation(s Subject, r Resource) string {
+	switch s.Role {
+	case RoleNone:
+		return "Only organization members can create conversations"
+	case RoleGuest:
+		return "Guests cannot create conversations"
+	}
+	return ""
+}
+
+func canPostMessage(s Subject, r Resource) string {
+	if !s.Participant {
+		return "Only participants can post to this conversation"
+	} else if r.Conversation.IsArchived() {
+		return "Conversation is archived"
+	}
+	return ""
+}
+
+func canDeleteMessage(s Subject, r Resource) string {
+	if r.Conversation.IsArchived() {
+		return "Conversation is archived"
+	}
+
+	// senders delete their own messages as long as they participate
+	if r.OwnerID == s.UserID {
+		if !s.Participant {
+			return "Only participants can delete their messages"
+		}
+		return ""
+	}
+
+	if s.Role == RoleOwner {
+		return ""
+	} else if s.Role != RoleGuest && r.Conversation.CreatorID.Hex() == s.UserID {
+		return ""
+	}
+	return "Only the sender, the creator of the conversation or an organization owner can delete a message"
+}
+
+func canInviteMember(s Subject, r Resource) string {
+	if s.Role != RoleOwner {
+		return "Only organization owners can invite members"
+	}
+	return ""
+}
+
+func canManageApiTokens(s Subject, r Resource) string {
+	if s.Role != RoleOwner {
+		return "Only organization owners can manage the API tokens of the organization"
+	}
+	return ""
+}
+
+func canManageIntegrations(s Subject, r Resource) string {
+	if r.Conversation.IsArchived() {
+		return "Conversation is archived"
+	} else if s.Role == RoleOwner {
+		return ""
+	} else if s.Role != RoleGuest && r.Conversation.CreatorID.Hex() == s.UserID {
+		return ""
+	}
+	return "Only the creator of the conversation or an organization owner can manage its integrations"
+}
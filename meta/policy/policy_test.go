@@ -0,0 +1,122 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/messagedb/messagedb/meta/policy"
+	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Ensure the policy decides who reads and posts to conversations.
+func TestPolicy_Conversations(t *testing.T) {
+	public := &schema.Conversation{Privacy: schema.PrivacyPublic}
+	private := &schema.Conversation{Privacy: schema.PrivacyPrivate}
+	archived := &schema.Conversation{Privacy: schema.PrivacyPublic, Archived: true}
+
+	var tests = []struct {
+		conversation *schema.Conversation
+		role         policy.Role
+		participant  bool
+		action       policy.Action
+		exp          bool
+	}{
+		{public, policy.RoleNone, false, policy.ReadConversation, true},
+		{public, policy.RoleNone, false, policy.PostMessage, false},
+		{public, policy.RoleNone, true, policy.PostMessage, true},
+		{private, policy.RoleNone, false, policy.ReadConversation, false},
+		{private, policy.RoleNone, true, policy.ReadConversation, true},
+		{private, policy.RoleNone, true, policy.PostMessage, true},
+		{archived, policy.RoleNone, true, policy.ReadConversation, true},
+		{archived, policy.RoleNone, true, policy.PostMessage, false},
+
+		// members read public conversations, guests only the ones shared with them
+		{public, policy.RoleMember, false, policy.ReadConversation, true},
+		{public, policy.RoleGuest, false, policy.ReadConversation, false},
+		{private, policy.RoleGuest, true, policy.ReadConversation, true},
+		{private, policy.RoleGuest, true, policy.PostMessage, true},
+		{private, policy.RoleOwner, false, policy.ReadConversation, false},
+
+		{public, policy.RoleMember, false, policy.JoinConversation, true},
+		{public, policy.RoleGuest, false, policy.JoinConversation, false},
+	}
+
+	for i, tt := range tests {
+		err := policy.Authorize(policy.Subject{UserID: "u", Role: tt.role, Participant: tt.participant}, tt.action, policy.Resource{Conversation: tt.conversation})
+		if got := err == nil; got != tt.exp {
+			t.Errorf("%d. %s privacy=%s role=%s participant=%v: got %v, exp %v (%v)", i, tt.action, tt.conversation.Privacy, tt.role, tt.participant, got, tt.exp, err)
+		}
+	}
+}
+
+// Ensure the policy decides on organization actions by role.
+func TestPolicy_Organizations(t *testing.T) {
+	var tests = []struct {
+		action policy.Action
+		role   policy.Role
+		exp    bool
+	}{
+		{policy.CreateConversation, policy.RoleOwner, true},
+		{policy.CreateConversation, policy.RoleMember, true},
+		{policy.CreateConversation, policy.RoleGuest, false},
+		{policy.CreateConversation, policy.RoleNone, false},
+		{policy.ListConversations, policy.RoleMember, true},
+		{policy.ListConversations, policy.RoleGuest, false},
+		{policy.InviteMember, policy.RoleOwner, true},
+		{policy.InviteMember, policy.RoleMember, false},
+		{policy.InviteMember, policy.RoleGuest, false},
+		{policy.ManageApiTokens, policy.RoleOwner, true},
+		{policy.ManageApiTokens, policy.RoleMember, false},
+		{policy.Action("unknown"), policy.RoleOwner, false},
+	}
+
+	for i, tt := range tests {
+		err := policy.Authorize(policy.Subject{UserID: "u", Role: tt.role}, tt.action, policy.Resource{})
+		if got := err == nil; got != tt.exp {
+			t.Errorf("%d. %s role=%s: got %v, exp %v", i, tt.action, tt.role, got, tt.exp)
+		}
+	}
+}
+
+// Ensure messages are deleted by their sender, the creator of the conversation or an owner.
+func TestPolicy_DeleteMessage(t *testing.T) {
+	sender, creator, other := bson.NewObjectId(), bson.NewObjectId(), bson.NewObjectId()
+	conversation := &schema.Conversation{CreatorID: creator}
+	archived := &schema.Conversation{CreatorID: creator, Archived: true}
+
+	var tests = []struct {
+		conversation *schema.Conversation
+		user         bson.ObjectId
+		role         policy.Role
+		participant  bool
+		exp          bool
+	}{
+		{conversation, sender, policy.RoleGuest, true, true},
+		{conversation, sender, policy.RoleMember, false, false},
+		{archived, sender, policy.RoleMember, true, false},
+		{conversation, creator, policy.RoleMember, true, true},
+		{conversation, creator, policy.RoleGuest, true, false},
+		{conversation, other, policy.RoleOwner, false, true},
+		{conversation, other, policy.RoleMember, true, false},
+		{archived, other, policy.RoleOwner, true, false},
+	}
+
+	for i, tt := range tests {
+		s := policy.Subject{UserID: tt.user.Hex(), Role: tt.role, Participant: tt.participant}
+		err := policy.Authorize(s, policy.DeleteMessage, policy.Resource{Conversation: tt.conversation, OwnerID: sender.Hex()})
+		if got := err == nil; got != tt.exp {
+			t.Errorf("%d. role=%s participant=%v: got %v, exp %v", i, tt.role, tt.participant, got, tt.exp)
+		}
+	}
+}
+
+// Ensure denials report the action and the role they were decided for.
+func TestPolicy_Denial(t *testing.T) {
+	err := policy.Authorize(policy.Subject{Role: policy.RoleGuest}, policy.CreateConversation, policy.Resource{})
+	if d, ok := err.(*policy.Denial); !ok {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if d.Action != policy.CreateConversation || d.Role != policy.RoleGuest || d.Reason == "" {
+		t.Fatalf("unexpected denial: %#v", d)
+	}
+}
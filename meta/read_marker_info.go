@@ -0,0 +1,37 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// ReadMarkerInfo represents the position up to which a participant has read a conversation.
+type ReadMarkerInfo struct {
+	ConversationID string
+	UserID         string
+	MessageID      string    // last read message
+	Time           time.Time // time of the last read message
+}
+
+// clone returns a deep copy of rmi.
+func (rmi ReadMarkerInfo) clone() ReadMarkerInfo { return rmi }
+
+// marshal serializes to a protobuf representation.
+func (rmi ReadMarkerInfo) marshal() *internal.ReadMarkerInfo {
+	return &internal.ReadMarkerInfo{
+		ConversationID: proto.String(rmi.ConversationID),
+		UserID:         proto.String(rmi.UserID),
+		MessageID:      proto.String(rmi.MessageID),
+		Time:           proto.Int64(MarshalTime(rmi.Time)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (rmi *ReadMarkerInfo) unmarshal(pb *internal.ReadMarkerInfo) {
+	rmi.ConversationID = pb.GetConversationID()
+	rmi.UserID = pb.GetUserID()
+	rmi.MessageID = pb.GetMessageID()
+	rmi.Time = UnmarshalTime(pb.GetTime())
+}
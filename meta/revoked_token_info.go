@@ -0,0 +1,35 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// RevokedTokenInfo represents an authentication token that was revoked before it expired, such as
+// the tokens of a session the user logged out of. It is kept until the token expires.
+type RevokedTokenInfo struct {
+	ID        string // unique identifier of the token, its jti claim
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// clone returns a deep copy of ri.
+func (ri RevokedTokenInfo) clone() RevokedTokenInfo { return ri }
+
+// marshal serializes to a protobuf representation.
+func (ri RevokedTokenInfo) marshal() *internal.RevokedTokenInfo {
+	return &internal.RevokedTokenInfo{
+		ID:        proto.String(ri.ID),
+		UserID:    proto.String(ri.UserID),
+		ExpiresAt: proto.Int64(MarshalTime(ri.ExpiresAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ri *RevokedTokenInfo) unmarshal(pb *internal.RevokedTokenInfo) {
+	ri.ID = pb.GetID()
+	ri.UserID = pb.GetUserID()
+	ri.ExpiresAt = UnmarshalTime(pb.GetExpiresAt())
+}
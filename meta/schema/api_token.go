@@ -0,0 +1,44 @@
+package schema
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// API token scopes
+const (
+	ApiTokenScopeReadMessages  = "messages:read"
+	ApiTokenScopeWriteMessages = "messages:write"
+	ApiTokenScopeAdmin         = "admin"
+)
+
+// ApiToken represents a token a user or an integration of an organization consumes the API with, instead of a
+// password. Requests made with the token are made as its user
+type ApiToken struct {
+	ID             bson.ObjectId `bson:"_id,omitempty"`
+	Name           string        `bson:"name"`
+	UserID         bson.ObjectId `bson:"user_id"`
+	OrganizationID bson.ObjectId `bson:"org_id,omitempty"`
+	Scopes         []string      `bson:"scopes"`
+	RateLimit      int64         `bson:"rate_limit"`
+	ExpiresAt      time.Time     `bson:"expires_at"`
+	LastUsedAt     time.Time     `bson:"last_used_at"`
+	CreatedAt      time.Time     `bson:"created_at"`
+	Errors         Errors        `bson:"-"`
+}
+
+// HasScope returns if the token was granted the scope. The admin scope grants all scopes
+func (t *ApiToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ApiTokenScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIntegration returns if the token belongs to an organization, which restricts it to the organization resources
+func (t *ApiToken) IsIntegration() bool {
+	return t.OrganizationID != ""
+}
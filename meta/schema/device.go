@@ -9,6 +9,9 @@ import (
 type Device struct {
 	Id        bson.ObjectId `json:"id" bson:"_id,omitempty"`
 	UserId    bson.ObjectId `json:"user_id" bson:"user_id,omitempty"`
+	Name      string        `json:"name" bson:"name"`
+	Platform  string        `json:"platform" bson:"platform"`
+	Token     string        `json:"-" bson:"token"`
 	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at" bson:"updated_at"`
 	Errors    Errors        `json:"-" bson:"-"`
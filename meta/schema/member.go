@@ -34,6 +34,7 @@ type Member struct {
 	InviteEmail      string        `bson:"invite_email,omitempty"`
 	InviteToken      string        `bson:"invite_token,omitempty"`
 	InviteSentAt     time.Time     `bson:"invite_sent_at,omitempty"`
+	InviteExpiresAt  time.Time     `bson:"invite_expires_at,omitempty"`
 	InviteAcceptedAt time.Time     `bson:"invite_accepted_at,omitempty"`
 	CreatedAt        time.Time     `bson:"created_at"`
 	UpdatedAt        time.Time     `bson:"updated_at"`
@@ -1,52 +1,63 @@
 package schema
 
-// import (
-// 	"time"
-// 	"gopkg.in/mgo.v2/bson"
-// )
-
-// type TeamType int
-
-// const (
-// 	TeamTypeOwners TeamType = iota
-// 	TeamTypeAdmins
-// 	TeamTypeTeams
-// )
-
-// type Team struct {
-// 	storage.Document `bson:"-"`
-// 	Id               bson.ObjectId `json:"id" bson:"_id,omitempty"`
-// 	OrganizationId   bson.ObjectId `json:"org_id" bson:"org_id"`
-// 	Name             string        `json:"name" bson:"name"`
-// 	Description      string        `json:"description" bson:"description"`
-// 	TeamType         TeamType      `json:"team_type" bson:"team_type"`
-// 	CreatedAt        time.Time     `json:"created_at" bson:"created_at"`
-// 	UpdatedAt        time.Time     `json:"updated_at" bson:"updated_at"`
-// 	Errors           Errors        `json:"-" bson:"-"`
-// }
-
-// func (t *Team) CanBeDeleted() bool {
-// 	return !(t.TeamType == TeamTypeOwners || t.TeamType == TeamTypeAdmins)
-// }
-
-// type Teams []Team
-
-// func (ts Teams) Add(team Team) {
-// 	for _, item := range ts {
-// 		if item.Id == team.Id {
-// 			return //found already, so bail
-// 		}
-// 	}
-// 	ts = append(ts, team)
-// }
-
-// func (ts Teams) Remove(team Team) {
-// 	for i, item := range ts {
-// 		if item.Id == team.Id {
-// 			// we found, so remove it
-// 			ts = append(ts[:i], ts[i+1:]...)
-// 			break
-// 		}
-// 	}
-
-// }
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// TeamType describes the kind of a team
+type TeamType int
+
+// Team types
+const (
+	TeamTypeOwners TeamType = iota
+	TeamTypeAdmins
+	TeamTypeTeams
+)
+
+var teamTypes = [...]string{"owners", "admins", "teams"}
+
+func (t TeamType) String() string {
+	return teamTypes[t]
+}
+
+// Team represents a group of members within an organization. Members of a team participate in the conversations
+// granted to the team
+type Team struct {
+	ID             bson.ObjectId   `bson:"_id,omitempty"`
+	OrganizationID bson.ObjectId   `bson:"org_id"`
+	Name           string          `bson:"name"`
+	Description    string          `bson:"description"`
+	TeamType       TeamType        `bson:"team_type"`
+	MemberIDs      []bson.ObjectId `bson:"member_ids"`
+	Conversations  []bson.ObjectId `bson:"conversation_ids"`
+	CreatedAt      time.Time       `bson:"created_at"`
+	UpdatedAt      time.Time       `bson:"updated_at"`
+	Errors         Errors          `bson:"-"`
+}
+
+// CanBeDeleted returns if the team can be deleted. The Owners and Admins teams of an organization cannot be deleted
+func (t *Team) CanBeDeleted() bool {
+	return !(t.TeamType == TeamTypeOwners || t.TeamType == TeamTypeAdmins)
+}
+
+// HasMember returns if the user is a member of the team
+func (t *Team) HasMember(userID bson.ObjectId) bool {
+	for _, id := range t.MemberIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConversation returns if the conversation is granted to the team
+func (t *Team) HasConversation(conversationID bson.ObjectId) bool {
+	for _, id := range t.Conversations {
+		if id == conversationID {
+			return true
+		}
+	}
+	return false
+}
@@ -123,6 +123,28 @@ func (u *User) HasEmailAddress(email string) bool {
 	return false
 }
 
+// HasConfirmedEmail returns true if the user has the provided email address registered and confirmed
+func (u *User) HasConfirmedEmail(email string) bool {
+	email = strings.ToLower(email)
+	for _, item := range u.Emails {
+		if strings.ToLower(item.Email) == email {
+			return item.IsConfirmed
+		}
+	}
+	return false
+}
+
+// ConfirmEmailAddress marks the email address as confirmed at the provided time
+func (u *User) ConfirmEmailAddress(email string, t time.Time) {
+	email = strings.ToLower(email)
+	for i, item := range u.Emails {
+		if strings.ToLower(item.Email) == email && !item.IsConfirmed {
+			u.Emails[i].IsConfirmed = true
+			u.Emails[i].ConfirmedAt = t
+		}
+	}
+}
+
 // AddEmailAddress adds a new email address to the user's account
 func (u *User) AddEmailAddress(email string) {
 	email = strings.ToLower(email)
@@ -2,9 +2,14 @@ package services
 
 import (
 	"errors"
+	"strings"
+	"time"
 
+	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
 	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
 )
 
 // Errors
@@ -12,6 +17,9 @@ var (
 	ErrAuthenticationFailedUserNotFound     = errors.New("Authentication failed. User does not exists")
 	ErrAuthenticationFailedValidationError  = errors.New("Authentication failed. Validation Error")
 	ErrAuthenticationFailedPasswordMismatch = errors.New("Authentication failed. Password does not match")
+	ErrAuthenticationFailedEmailUnconfirmed = errors.New("Authentication failed. Email address is not confirmed")
+	ErrCannotRemovePrimaryEmail             = errors.New("Cannot remove the primary email address")
+	ErrInvalidMembershipState               = errors.New("Invalid membership state")
 )
 
 // AccountService is a service that allows actions on the authenticated user
@@ -21,117 +29,148 @@ type AccountService struct {
 
 // NewAccountService creates a new service for authenticated user
 func NewAccountService(userParam interface{}) (*AccountService, error) {
-	// var user *schema.User
-	// var ok bool
-	// var err error
-	//
-	// if user, ok = userParam.(*schema.User); !ok {
-	// 	userid := utils.ObjectId(userParam)
-	// 	user, err = models.User.FindById(userid)
-	// 	if err != nil {
-	// 		log.Errorf("Unable to find user when creating AccountService: %v", userid)
-	// 		return nil, err
-	// 	}
-	// }
-	//
-	// service := &AccountService{User: user}
-	//
-	// return service, nil
-	return nil, nil
+	user, ok := userParam.(*schema.User)
+	if !ok {
+		id, _ := userParam.(string)
+		u, err := FindUser(id)
+		if err != nil {
+			return nil, err
+		} else if u == nil {
+			return nil, ErrUserNotFound
+		}
+		user = u
+	}
+
+	return &AccountService{User: user}, nil
 }
 
 // ChangePassword updates the authenticated user's password
 func (s *AccountService) ChangePassword(form bindings.ChangePassword) (bool, error) {
-	//TODO: fix me
-	return false, nil
+	// the password is left unchanged if the old password does not match
+	if ok, _ := s.User.ValidatePassword(form.OldPassword); !ok {
+		return false, nil
+	}
+
+	user := *s.User
+	if err := user.SetPassword(form.NewPassword); err != nil {
+		return false, err
+	}
+	if err := s.save(&user); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // ChangeUsername updates the authenticated users' username
 func (s *AccountService) ChangeUsername(form bindings.ChangeUsername) (bool, error) {
-	//TODO: fix me
-	return false, nil
+	// the username is the path of the user's namespace, so both are renamed at once
+	if err := renameNamespace(s.User.Namespace.ID.Hex(), form.Username); err != nil {
+		return false, err
+	}
+
+	user, err := FindUser(s.User.ID.Hex())
+	if err != nil {
+		return false, err
+	} else if user == nil {
+		return false, ErrUserNotFound
+	}
+	*s.User = *user
+	return true, nil
 }
 
 // AddEmailAddress adds a new email address to the user's account
 func (s *AccountService) AddEmailAddress(form bindings.UpdateEmail) error {
-	// TODO: fix this
-	// s.User.AddEmailAddress(form.Email)
-	// err := s.User.Save()
-	// if err != nil {
-	// 	return err
-	// }
-	return nil
+	if s.User.HasEmailAddress(strings.ToLower(form.Email)) {
+		return nil
+	}
+
+	user := *s.User
+	user.Emails = append([]schema.EmailAddress{}, s.User.Emails...)
+	user.AddEmailAddress(form.Email)
+	return s.save(&user)
 }
 
 // RemoveEmailAddress removes an email existing email address from the user's account
 func (s *AccountService) RemoveEmailAddress(form bindings.UpdateEmail) error {
-	// TODO: fix this
-	// s.User.RemoveEmailAddress(form.Email)
-	// err := s.User.Save()
-	// if err != nil {
-	// 	return err
-	// }
+	if strings.EqualFold(form.Email, s.User.GetPrimaryEmail()) {
+		return ErrCannotRemovePrimaryEmail
+	}
+
+	user := *s.User
+	user.Emails = append([]schema.EmailAddress{}, s.User.Emails...)
+	user.RemoveEmailAddress(form.Email)
+	return s.save(&user)
+}
+
+// save replicates the account changes made to user and updates the wrapped user on success.
+func (s *AccountService) save(user *schema.User) error {
+	if err := store.UpdateAccount(accountFromUser(user)); err != nil {
+		if err == meta.ErrEmailExists {
+			return ErrEmailAlreadyExists
+		}
+		return err
+	}
+	*s.User = *user
 	return nil
 }
 
 // ListMyMemberships returns all active memberships for the authenticated user
 func (s *AccountService) ListMyMemberships() ([]*schema.Member, error) {
+	memberships, err := store.Memberships(s.User.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
 	members := []*schema.Member{}
-	// err := models.Member.Find(bson.M{"user_id": s.User.ID, "status": "active"}).All(&members)
-	// if err != nil {
-	// 	return nil, err
-	// }
+	for i := range memberships {
+		if memberships[i].State == meta.MemberStateActive {
+			members = append(members, memberFromInfo(&memberships[i]))
+		}
+	}
 	return members, nil
 }
 
 // ListMyOrganizations returns all active organizations for the authenticated user
 func (s *AccountService) ListMyOrganizations() ([]*schema.Organization, error) {
-	// TODO: fix this
-	// members, err := s.ListMyMemberships()
-	// if err != nil {
-	// 	return nil, err
-	// }
-	//
-	// orgIds := []bson.ObjectId{}
-	// for _, member := range members {
-	// 	orgIds = append(orgIds, member.OrganizationID)
-	// }
-	//
-	// orgs, err := models.Organization.FindAllIds(orgIds)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	//
-	// return orgs, nil
-	return nil, nil
+	members, err := s.ListMyMemberships()
+	if err != nil {
+		return nil, err
+	}
+	return organizationsOf(members)
 }
 
 // GetMyMembership returns the membership for an organization that the authenticated user is a member of
 func (s *AccountService) GetMyMembership(orgID interface{}) (*schema.Member, error) {
-	member := &schema.Member{}
-	//TODO: fix this
-	// err := models.Member.Find(bson.M{"org_id": utils.ObjectId(orgID), "user_id": s.User.ID}).One(&member)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	return member, nil
+	mi, err := store.Member(idOf(orgID), s.User.ID.Hex())
+	if err != nil {
+		return nil, err
+	} else if mi == nil {
+		return nil, ErrMembershipNotFound
+	}
+	return memberFromInfo(mi), nil
 }
 
 // EditMyMembership edits the membership for an organization that the authenticated user is a member of
 func (s *AccountService) EditMyMembership(orgID interface{}, json bindings.EditMyMembership) (*schema.Member, error) {
-	member := &schema.Member{}
-	// TODO: Fix this
-	// err := models.Member.Find(bson.M{"org_id": utils.ObjectId(orgID), "user_id": s.User.ID}).One(&member)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	//
-	// member.State = json.State
-	// err = member.Save()
-	// if err != nil {
-	// 	log.Errorf("Failed to save membership: %v", err)
-	// 	return nil, err
-	// }
-
-	return member, nil
+	if err := store.EditMyMembership(idOf(orgID), s.User.ID.Hex(), json.State, time.Now().UTC()); err != nil {
+		switch err {
+		case meta.ErrMemberNotFound:
+			return nil, ErrMembershipNotFound
+		case meta.ErrInvalidMemberState:
+			return nil, ErrInvalidMembershipState
+		}
+		return nil, err
+	}
+	return s.GetMyMembership(orgID)
+}
+
+// idOf returns the hex representation of an ID passed either as a string or an object id.
+func idOf(id interface{}) string {
+	switch id := id.(type) {
+	case bson.ObjectId:
+		return id.Hex()
+	case string:
+		return id
+	}
+	return ""
 }
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// apiTokenTouchInterval is how often the last use of an API token is recorded. Recording every use would replicate a
+// command through the cluster for each request made with the token.
+const apiTokenTouchInterval = time.Minute
+
+var (
+	// ErrInvalidApiToken is raised when an API token is unknown or expired
+	ErrInvalidApiToken = errors.New("Invalid API Token")
+
+	// ErrApiTokenNotFound is raised when revoking an API token that does not exist
+	ErrApiTokenNotFound = errors.New("API token not found")
+
+	// ErrInvalidApiTokenScope is raised when creating an API token with an unknown scope
+	ErrInvalidApiTokenScope = errors.New("Invalid API token scope")
+)
+
+// FindApiToken returns the API token with the given ID. Returns nil if the token does not exist.
+func FindApiToken(id string) (*schema.ApiToken, error) {
+	ti, err := store.ApiToken(id)
+	if err != nil || ti == nil {
+		return nil, err
+	}
+	return apiTokenFromInfo(ti), nil
+}
+
+// ValidateApiToken returns an API token and the user requests made with it are made as. The token must not be expired.
+func ValidateApiToken(token string) (*schema.ApiToken, *schema.User, error) {
+	ti, err := store.ApiTokenByHash(hashSecretToken(token))
+	if err != nil {
+		return nil, nil, err
+	} else if ti == nil {
+		return nil, nil, ErrInvalidApiToken
+	}
+
+	now := time.Now().UTC()
+	if ti.Expired(now) {
+		return nil, nil, ErrInvalidApiToken
+	}
+
+	user, err := FindUser(ti.UserID)
+	if err != nil {
+		return nil, nil, err
+	} else if user == nil {
+		return nil, nil, ErrInvalidApiToken
+	}
+
+	if now.Sub(ti.LastUsedAt) >= apiTokenTouchInterval {
+		if err := store.TouchApiToken(ti.ID, now); err != nil && err != meta.ErrApiTokenNotFound {
+			return nil, nil, err
+		}
+		ti.LastUsedAt = now
+	}
+
+	return apiTokenFromInfo(ti), user, nil
+}
+
+// ListApiTokens returns the personal API tokens of the user
+func ListApiTokens(user *schema.User) ([]*schema.ApiToken, error) {
+	infos, err := store.ApiTokens(user.ID.Hex(), "")
+	if err != nil {
+		return nil, err
+	}
+	return apiTokensFromInfos(infos), nil
+}
+
+// CreateApiToken creates a personal API token for the user. The token is returned along with the API token, it cannot
+// be read back afterwards.
+func CreateApiToken(user *schema.User, json bindings.CreateApiToken) (*schema.ApiToken, string, error) {
+	return createApiToken(user, "", json)
+}
+
+// RevokeApiToken deletes a personal API token of the user.
+func RevokeApiToken(user *schema.User, token *schema.ApiToken) error {
+	if token.UserID != user.ID || token.IsIntegration() {
+		return ErrApiTokenNotFound
+	}
+	return deleteApiToken(token.ID.Hex())
+}
+
+// ListApiTokens returns the integration API tokens of the organization. The authenticated user must be an
+// organization owner.
+func (s *OrganizationService) ListApiTokens() ([]*schema.ApiToken, error) {
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	infos, err := store.OrganizationApiTokens(s.Org.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return apiTokensFromInfos(infos), nil
+}
+
+// CreateApiToken creates an integration API token, restricted to the organization. Requests made with the token are
+// made as the authenticated user, and the token is revoked when the user leaves the organization. The authenticated
+// user must be an organization owner.
+func (s *OrganizationService) CreateApiToken(json bindings.CreateApiToken) (*schema.ApiToken, string, error) {
+	if err := s.checkOwnership(); err != nil {
+		return nil, "", err
+	}
+	return createApiToken(s.CurrentUser, s.Org.ID.Hex(), json)
+}
+
+// RevokeApiToken deletes an integration API token of the organization. The authenticated user must be an
+// organization owner.
+func (s *OrganizationService) RevokeApiToken(token *schema.ApiToken) error {
+	if err := s.checkOwnership(); err != nil {
+		return err
+	}
+
+	if token.OrganizationID != s.Org.ID {
+		return ErrApiTokenNotFound
+	}
+	return deleteApiToken(token.ID.Hex())
+}
+
+// createApiToken creates an API token for the user, restricted to an organization unless orgID is empty.
+func createApiToken(user *schema.User, orgID string, json bindings.CreateApiToken) (*schema.ApiToken, string, error) {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now().UTC()
+	ti := meta.ApiTokenInfo{
+		ID:             bson.NewObjectId().Hex(),
+		Name:           json.Name,
+		TokenHash:      hash,
+		UserID:         user.ID.Hex(),
+		OrganizationID: orgID,
+		Scopes:         json.Scopes,
+		RateLimit:      json.RateLimit,
+		CreatedAt:      now,
+	}
+	if json.ExpiresIn > 0 {
+		ti.ExpiresAt = now.Add(time.Duration(json.ExpiresIn) * time.Second)
+	}
+
+	if err := store.CreateApiToken(ti); err != nil {
+		switch err {
+		case meta.ErrInvalidApiTokenScope:
+			return nil, "", ErrInvalidApiTokenScope
+		case meta.ErrMemberNotFound:
+			return nil, "", ErrNotAnOrganizationMember
+		}
+		return nil, "", err
+	}
+	return apiTokenFromInfo(&ti), token, nil
+}
+
+// deleteApiToken removes an API token from the meta store.
+func deleteApiToken(id string) error {
+	if err := store.DeleteApiToken(id); err != nil {
+		if err == meta.ErrApiTokenNotFound {
+			return ErrApiTokenNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// apiTokensFromInfos converts the API tokens held by the meta store.
+func apiTokensFromInfos(infos []meta.ApiTokenInfo) []*schema.ApiToken {
+	tokens := []*schema.ApiToken{}
+	for i := range infos {
+		tokens = append(tokens, apiTokenFromInfo(&infos[i]))
+	}
+	return tokens
+}
@@ -1,34 +1,95 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
 	"github.com/messagedb/messagedb/meta/schema"
 
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-// Secrets
-var (
-	signingKey = []byte("secret")
-	refreshKey = []byte("refreshsecret")
+const (
+	// DefaultAccessTokenExpiry is the default time an access token is valid after it is issued.
+	DefaultAccessTokenExpiry = 2 * time.Hour
+
+	// DefaultRefreshTokenExpiry is the default time a refresh token is valid after it is issued.
+	DefaultRefreshTokenExpiry = 14 * 24 * time.Hour
+
+	// ephemeralKeyID is the ID of the random key tokens are signed with when no key is configured.
+	ephemeralKeyID = "ephemeral"
+)
+
+// Token types, set in the typ claim so that refresh tokens cannot be used as access tokens and vice versa
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+	tokenTypeState   = "oidc_state"
+
+	tokenTypeEmailVerification = "email_verification"
+	tokenTypePasswordReset     = "password_reset"
 )
 
 // Errors
 var (
 	ErrInvalidAccessToken  = errors.New("Invalid Access Token")
 	ErrInvalidRefreshToken = errors.New("Invalid Refresh Token")
+
+	// ErrSigningKeyNotFound is raised when the key new tokens are signed with is not one of the configured keys
+	ErrSigningKeyNotFound = errors.New("Signing key not found")
 )
 
 // Auth is the singleton instance for the Auth service
-var Auth = &authService{signingKey, refreshKey}
+var Auth = newAuthService()
 
 type authService struct {
-	SigningKey []byte
-	RefreshKey []byte
+	mu    sync.RWMutex
+	keys  map[string][]byte // keys tokens are validated with, by key ID
+	keyID string            // ID of the key new tokens are signed with
+
+	AccessTokenExpiry  time.Duration
+	RefreshTokenExpiry time.Duration
+}
+
+// newAuthService returns an auth service signing tokens with a random key until keys are configured. Such tokens are
+// not valid on the other nodes of a cluster, nor after a restart.
+func newAuthService() *authService {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+
+	return &authService{
+		keys:               map[string][]byte{ephemeralKeyID: key},
+		keyID:              ephemeralKeyID,
+		AccessTokenExpiry:  DefaultAccessTokenExpiry,
+		RefreshTokenExpiry: DefaultRefreshTokenExpiry,
+	}
+}
+
+// SetKeys replaces the keys the tokens are signed with. New tokens are signed with the key keyID and carry it as their
+// kid header, while the tokens signed with any of the keys remain valid. Keys are rotated by adding a new key, signing
+// with it, and removing the previous key once the tokens it signed have expired.
+func (a *authService) SetKeys(keyID string, keys map[string][]byte) error {
+	if _, ok := keys[keyID]; !ok {
+		return ErrSigningKeyNotFound
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys = make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		a.keys[id] = key
+	}
+	a.keyID = keyID
+	return nil
 }
 
 // TokenFields represents the security tokens that gets generated and sent as API response
@@ -38,27 +99,17 @@ type TokenFields struct {
 	ExpiresAt    time.Time `json:"expires_at"`
 }
 
+// GenerateToken issues a new access token and a new refresh token to the user
 func (a *authService) GenerateToken(user *schema.User) (*TokenFields, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(a.AccessTokenExpiry)
 
-	expiresAt := time.Now().Add(time.Hour * 2)
-
-	token := jwt.New(jwt.GetSigningMethod("HS256"))
-	token.Claims["uid"] = user.ID
-	token.Claims["uname"] = user.Username
-	token.Claims["iat"] = expiresAt.Unix()
-
-	accessToken, err := token.SignedString(a.SigningKey)
+	accessToken, err := a.signToken(user, tokenTypeAccess, now, expiresAt)
 	if err != nil {
 		return nil, err
 	}
 
-	// generate JWT access token
-	token = jwt.New(jwt.GetSigningMethod("HS256"))
-	token.Claims["uid"] = user.ID
-	token.Claims["uname"] = user.Username
-	token.Claims["iat"] = time.Now().Add(time.Hour * 24 * 14).Unix()
-
-	refreshToken, err := token.SignedString(a.RefreshKey)
+	refreshToken, err := a.signToken(user, tokenTypeRefresh, now, now.Add(a.RefreshTokenExpiry))
 	if err != nil {
 		return nil, err
 	}
@@ -66,91 +117,215 @@ func (a *authService) GenerateToken(user *schema.User) (*TokenFields, error) {
 	return &TokenFields{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresAt: expiresAt}, nil
 }
 
+// signToken returns a token of the given type issued to the user at iat and valid until exp.
+func (a *authService) signToken(user *schema.User, typ string, iat, exp time.Time) (string, error) {
+	return a.sign(typ, iat, exp, map[string]interface{}{
+		"uid":   user.ID.Hex(),
+		"uname": user.Username,
+	})
+}
+
+// sign returns a token of the given type carrying claims, issued at iat and valid until exp.
+func (a *authService) sign(typ string, iat, exp time.Time, claims map[string]interface{}) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.RLock()
+	keyID, key := a.keyID, a.keys[a.keyID]
+	a.mu.RUnlock()
+
+	token := jwt.New(jwt.GetSigningMethod("HS256"))
+	token.Header["kid"] = keyID
+	for k, v := range claims {
+		token.Claims[k] = v
+	}
+	token.Claims["jti"] = jti
+	token.Claims["typ"] = typ
+	token.Claims["iat"] = iat.Unix()
+	token.Claims["exp"] = exp.Unix()
+
+	return token.SignedString(key)
+}
+
 func (a *authService) AuthorizeUser(credentials bindings.AuthorizeUser) (*schema.User, error) {
-	// var user *schema.User
-	// var err error
-	//
-	// // first check if the login credentials used is an email, otherwise use username to locate user
-	// if valid.IsEmail(credentials.Login) {
-	// 	user, err = models.User.FindByEmail(credentials.Login)
-	// } else {
-	// 	user, err = models.User.FindByUsername(credentials.Login)
-	// }
-	// if err != nil {
-	// 	return nil, err
-	// }
-	//
-	// // if we cannot find the user, return an error indicating that authentication has failed
-	// if user == nil {
-	// 	return nil, ErrAuthenticationFailedUserNotFound
-	// }
-	//
-	// // Lets try to validate the credentials against the database
-	// ok, err := user.ValidatePassword(credentials.Password)
-	// if err != nil {
-	// 	return user, ErrAuthenticationFailedValidationError
-	// }
-	//
-	// // if not OK then the password did not match
-	// if !ok {
-	// 	return user, ErrAuthenticationFailedPasswordMismatch
-	// }
-	//
-	// return user, nil
-	return nil, nil
+	var user *schema.User
+	var err error
+
+	// first check if the login credentials used is an email, otherwise use username to locate user
+	if strings.Contains(credentials.Login, "@") {
+		user, err = FindUserByEmail(credentials.Login)
+	} else {
+		user, err = FindUserByUsername(credentials.Login)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// if we cannot find the user, return an error indicating that authentication has failed
+	if user == nil {
+		return nil, ErrAuthenticationFailedUserNotFound
+	}
+
+	// an email address only identifies the user once the user confirmed receiving mail at it
+	if strings.Contains(credentials.Login, "@") && !user.HasConfirmedEmail(credentials.Login) {
+		return nil, ErrAuthenticationFailedEmailUnconfirmed
+	}
+
+	// if the password does not match the stored hash then authentication has failed
+	if ok, _ := user.ValidatePassword(credentials.Password); !ok {
+		return user, ErrAuthenticationFailedPasswordMismatch
+	}
+
+	return user, nil
 }
 
+// ValidateAccessToken returns the user an access token was issued to. The token must not be expired nor revoked.
 func (a *authService) ValidateAccessToken(accessToken string) (*schema.User, error) {
+	token, err := a.parseToken(accessToken, tokenTypeAccess)
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	return a.tokenUser(token)
+}
 
-	// 	token, err := jwt.Parse(accessToken, a.validateAccessTokenFunc)
-	// 	if err != nil || !token.Valid {
-	// 		return nil, ErrInvalidRefreshToken
-	// 	}
-	//
-	// 	userID := token.Claims["uid"]
-	// 	user, err := models.User.FindById(userID.(string))
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("Unexpected loading user: %s", userID)
-	// 	}
-	//
-	// 	if user == nil {
-	// 		return nil, fmt.Errorf("Unable to find user: %s", userID)
-	// 	}
-	//
-	// 	return user, nil
-	// }
-	//
-	// func (a *authService) ValidateRefreshToken(refreshToken string) (*schema.User, error) {
-	//
-	// 	token, err := jwt.Parse(refreshToken, a.validateRefreshTokenFunc)
-	// 	if err != nil || !token.Valid {
-	// 		return nil, ErrInvalidRefreshToken
-	// 	}
-	//
-	// 	userID := token.Claims["uid"]
-	// 	user, err := models.User.FindById(userID.(string))
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("Unexpected loading user: %s", userID)
-	// 	}
-	//
-	// 	if user == nil {
-	// 		return nil, fmt.Errorf("Unable to find user: %s", userID)
-	// 	}
-	//
-	// 	return user, nil
-	return nil, nil
-}
-
-func (a *authService) validateAccessTokenFunc(token *jwt.Token) (interface{}, error) {
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+// ValidateRefreshToken returns the user a refresh token was issued to. The token must not be expired nor revoked.
+func (a *authService) ValidateRefreshToken(refreshToken string) (*schema.User, error) {
+	token, err := a.parseToken(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
 	}
-	return a.SigningKey, nil
+	return a.tokenUser(token)
 }
 
-func (a *authService) validateRefreshTokenFunc(token *jwt.Token) (interface{}, error) {
+// RefreshToken issues new tokens in exchange of a refresh token. The refresh token is revoked, so that each refresh
+// token is only exchanged once, even when it is presented to several nodes at the same time.
+func (a *authService) RefreshToken(refreshToken string) (*schema.User, *TokenFields, error) {
+	token, err := a.parseToken(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	user, err := a.tokenUser(token)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if err := revokeToken(token); err == meta.ErrTokenRevoked {
+		return nil, nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	tokenFields, err := a.GenerateToken(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokenFields, nil
+}
+
+// RevokeAccessToken revokes an access token across the cluster until it expires.
+func (a *authService) RevokeAccessToken(accessToken string) error {
+	token, err := a.parseToken(accessToken, tokenTypeAccess)
+	if err != nil {
+		return ErrInvalidAccessToken
+	}
+	if err := revokeToken(token); err != nil && err != meta.ErrTokenRevoked {
+		return err
+	}
+	return nil
+}
+
+// RevokeRefreshToken revokes a refresh token of the user across the cluster until it expires.
+func (a *authService) RevokeRefreshToken(refreshToken string, user *schema.User) error {
+	token, err := a.parseToken(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return ErrInvalidRefreshToken
+	} else if uid, _ := token.Claims["uid"].(string); uid != user.ID.Hex() {
+		return ErrInvalidRefreshToken
+	}
+	if err := revokeToken(token); err != nil && err != meta.ErrTokenRevoked {
+		return err
+	}
+	return nil
+}
+
+// parseToken parses and validates a token of the given type. Expired and revoked tokens are not valid.
+func (a *authService) parseToken(raw, typ string) (*jwt.Token, error) {
+	token, err := jwt.Parse(raw, a.keyFunc)
+	if err != nil {
+		return nil, err
+	} else if !token.Valid {
+		return nil, errors.New("Invalid token")
+	}
+
+	if t, _ := token.Claims["typ"].(string); t != typ {
+		return nil, fmt.Errorf("Unexpected token type: %v", token.Claims["typ"])
+	}
+	if _, ok := token.Claims["exp"].(float64); !ok {
+		return nil, errors.New("Token has no expiry")
+	}
+
+	jti, _ := token.Claims["jti"].(string)
+	if jti == "" {
+		return nil, errors.New("Token has no ID")
+	}
+	revoked, err := store.IsTokenRevoked(jti)
+	if err != nil {
+		return nil, err
+	} else if revoked {
+		return nil, errors.New("Token has been revoked")
+	}
+
+	return token, nil
+}
+
+// keyFunc returns the key a token was signed with, identified by its kid header.
+func (a *authService) keyFunc(token *jwt.Token) (interface{}, error) {
 	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 	}
-	return a.RefreshKey, nil
+
+	keyID, _ := token.Header["kid"].(string)
+
+	a.mu.RLock()
+	key, ok := a.keys[keyID]
+	a.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown signing key: %v", token.Header["kid"])
+	}
+	return key, nil
+}
+
+// tokenUser returns the user a validated token was issued to.
+func (a *authService) tokenUser(token *jwt.Token) (*schema.User, error) {
+	userID, _ := token.Claims["uid"].(string)
+	user, err := FindUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Unexpected loading user: %s", userID)
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("Unable to find user: %s", userID)
+	}
+
+	return user, nil
+}
+
+// revokeToken adds a validated token to the denylist of the meta store until it expires.
+func revokeToken(token *jwt.Token) error {
+	jti, _ := token.Claims["jti"].(string)
+	uid, _ := token.Claims["uid"].(string)
+	exp, _ := token.Claims["exp"].(float64)
+	return store.RevokeToken(jti, uid, time.Unix(int64(exp), 0).UTC(), time.Now().UTC())
+}
+
+// newTokenID returns a random identifier for the jti claim of a token.
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
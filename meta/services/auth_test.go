@@ -0,0 +1,158 @@
+package services
+
+import (
+	"testing"
+)
+
+// Ensure a refresh token is exchanged for new tokens only once.
+func TestAuth_RefreshToken_Rotation(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+
+	a := newAuthService()
+	user := mustRegisterUser(t, "susy", "susy@example.com")
+	tokens, err := a.GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, refreshed, err := a.RefreshToken(tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	} else if other.ID != user.ID {
+		t.Fatalf("unexpected user: %s", other.ID.Hex())
+	} else if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Fatal("expected a new refresh token")
+	}
+
+	if _, _, err := a.RefreshToken(tokens.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := a.ValidateRefreshToken(tokens.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the new refresh token is exchanged in turn
+	if _, _, err := a.RefreshToken(refreshed.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure access and refresh tokens are not accepted in place of each other.
+func TestAuth_TokenType(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+
+	a := newAuthService()
+	user := mustRegisterUser(t, "susy", "susy@example.com")
+	tokens, err := a.GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.ValidateAccessToken(tokens.AccessToken); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ValidateRefreshToken(tokens.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.ValidateAccessToken(tokens.RefreshToken); err != ErrInvalidAccessToken {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := a.ValidateRefreshToken(tokens.AccessToken); err != ErrInvalidRefreshToken {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, _, err := a.RefreshToken(tokens.AccessToken); err != ErrInvalidRefreshToken {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := a.RevokeAccessToken(tokens.RefreshToken); err != ErrInvalidAccessToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure tokens signed with a key that is no longer configured are rejected, while rotated keys remain valid.
+func TestAuth_SetKeys(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+
+	a := newAuthService()
+	user := mustRegisterUser(t, "susy", "susy@example.com")
+	ephemeral, err := a.GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.SetKeys("k0", map[string][]byte{"k1": []byte("secret1")}); err != ErrSigningKeyNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := a.SetKeys("k0", map[string][]byte{"k0": []byte("secret0")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ValidateAccessToken(ephemeral.AccessToken); err != ErrInvalidAccessToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	k0, err := a.GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens signed with the previous key are valid until it is removed
+	if err := a.SetKeys("k1", map[string][]byte{"k0": []byte("secret0"), "k1": []byte("secret1")}); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ValidateAccessToken(k0.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.SetKeys("k1", map[string][]byte{"k1": []byte("secret1")}); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ValidateAccessToken(k0.AccessToken); err != ErrInvalidAccessToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a key with the same ID but another secret does not validate the tokens either
+	if err := a.SetKeys("k0", map[string][]byte{"k0": []byte("other")}); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ValidateAccessToken(k0.AccessToken); err != ErrInvalidAccessToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure revoked tokens are recorded in the meta store and rejected.
+func TestAuth_RevokeToken(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+
+	a := newAuthService()
+	user := mustRegisterUser(t, "susy", "susy@example.com")
+	tokens, err := a.GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access, err := a.parseToken(tokens.AccessToken, tokenTypeAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jti := access.Claims["jti"].(string)
+	if revoked, err := s.IsTokenRevoked(jti); err != nil {
+		t.Fatal(err)
+	} else if revoked {
+		t.Fatal("unexpected revoked token")
+	}
+
+	if err := a.RevokeAccessToken(tokens.AccessToken); err != nil {
+		t.Fatal(err)
+	} else if revoked, err := s.IsTokenRevoked(jti); err != nil {
+		t.Fatal(err)
+	} else if !revoked {
+		t.Fatal("expected token to be revoked")
+	} else if _, err := a.ValidateAccessToken(tokens.AccessToken); err != ErrInvalidAccessToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// refresh tokens are only revoked by the user they were issued to
+	other := mustRegisterUser(t, "bob", "bob@example.com")
+	if err := a.RevokeRefreshToken(tokens.RefreshToken, other); err != ErrInvalidRefreshToken {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := a.RevokeRefreshToken(tokens.RefreshToken, user); err != nil {
+		t.Fatal(err)
+	} else if _, _, err := a.RefreshToken(tokens.RefreshToken); err != ErrInvalidRefreshToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
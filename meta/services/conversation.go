@@ -1,8 +1,22 @@
 package services
 
 import (
+	"errors"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
 	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	// ErrConversationNotFound is raised when the conversation a service is created for does not exist
+	ErrConversationNotFound = errors.New("Conversation not found")
+
+	// ErrNamespaceNotFound is raised when a conversation is created in a namespace that does not exist
+	ErrNamespaceNotFound = errors.New("Namespace not found")
 )
 
 // ConversationService is responsible for all related actions and properties for
@@ -14,26 +28,32 @@ type ConversationService struct {
 
 // NewConversationService creates a service that wraps an organization and the current user making API requests
 func NewConversationService(conversationParam interface{}, currentUser *schema.User) (*ConversationService, error) {
-	// var conversation *schema.Conversation
-	// var ok bool
-	// var err error
-	//
-	// if conversation, ok = conversationParam.(*schema.Conversation); !ok {
-	// 	conversationID := utils.ObjectId(conversationParam)
-	// 	conversation, err = models.Conversation.FindByID(conversationID)
-	// 	if err != nil {
-	// 		log.Errorf("Unable to find conversation when creating ConversationService: %v", conversationID)
-	// 		return nil, err
-	// 	}
-	// }
-	//
-	// service := &ConversationService{
-	// 	Conversation: conversation,
-	// 	CurrentUser:  currentUser,
-	// }
-	//
-	// return service, nil
-	return nil, nil
+	conversation, ok := conversationParam.(*schema.Conversation)
+	if !ok {
+		c, err := FindConversation(idOf(conversationParam))
+		if err != nil {
+			return nil, err
+		} else if c == nil {
+			return nil, ErrConversationNotFound
+		}
+		conversation = c
+	}
+
+	service := &ConversationService{
+		Conversation: conversation,
+		CurrentUser:  currentUser,
+	}
+
+	return service, nil
+}
+
+// FindConversation returns the conversation with the given ID. Returns nil if the conversation does not exist.
+func FindConversation(id string) (*schema.Conversation, error) {
+	ci, err := store.Conversation(id)
+	if err != nil || ci == nil {
+		return nil, err
+	}
+	return conversationFromInfo(ci)
 }
 
 // GetConversation retrieves the conversation
@@ -43,46 +63,85 @@ func (s *ConversationService) GetConversation() (*schema.Conversation, error) {
 
 // ListPublicConversations returns all the conversations
 func ListPublicConversations(options interface{}) ([]*schema.Conversation, error) {
-	// var err error
-
 	//TODO: handle pagination via options (eg. page, per_page, sort)
 
-	// var conversations []*schema.Conversation
-	//
-	// err = models.Conversation.Find(nil).All(conversations)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	//
-	// return conversations, err
-	return nil, nil
+	infos, err := store.AllConversations()
+	if err != nil {
+		return nil, err
+	}
+	return publicConversations(infos)
+}
+
+// ListNamespaceConversations returns all the conversations in a namespace
+func ListNamespaceConversations(namespaceID string) ([]*schema.Conversation, error) {
+	infos, err := store.NamespaceConversations(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := []*schema.Conversation{}
+	for i := range infos {
+		c, err := conversationFromInfo(&infos[i])
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, nil
+}
+
+// ListNamespacePublicConversations returns the public conversations in a namespace
+func ListNamespacePublicConversations(namespaceID string) ([]*schema.Conversation, error) {
+	infos, err := store.NamespaceConversations(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	return publicConversations(infos)
+}
+
+// publicConversations converts the public conversations that are not archived.
+func publicConversations(infos []meta.ConversationInfo) ([]*schema.Conversation, error) {
+	conversations := []*schema.Conversation{}
+	for i := range infos {
+		if infos[i].Archived || infos[i].Privacy != schema.PrivacyPublic.String() {
+			continue
+		}
+		c, err := conversationFromInfo(&infos[i])
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, nil
 }
 
 // CreateConversation creates a new conversation in the namespace
-func CreateConversation(namespaceID int, json bindings.CreateConversation) (*schema.Conversation, error) {
-
-	// conversation := models.Conversation.New()
-	// conversation.Title = json.Title
-	// conversation.Purpose = json.Purpose
-	//
-	// namespace, err := models.Namespace.FindByID(namespaceID)
-	// if err != nil {
-	// 	log.Errorf("Unable to find namespace: %v", namespaceID)
-	// 	return nil, err
-	// }
-	//
-	// conversation.Namespace.ID = namespace.ID
-	// conversation.Namespace.Path = namespace.Path
-	// conversation.Namespace.OwnerType = namespace.OwnerType
-	// conversation.Namespace.OwnerID = namespace.OwnerID
-	//
-	// err = conversation.Save()
-	// if err != nil {
-	// 	log.Errorf("Unable to create conversation: %v", err)
-	// 	return nil, err
-	// }
-	//
-	// return conversation, nil
-
-	return nil, nil
+func CreateConversation(namespaceID string, json bindings.CreateConversation, creator *schema.User) (*schema.Conversation, error) {
+	conversation := schema.NewConversation()
+	conversation.ID = bson.NewObjectId()
+	conversation.CreatorID = creator.ID
+	conversation.Namespace.ID = objectID(namespaceID)
+	conversation.Title = json.Title
+	conversation.Purpose = json.Purpose
+	conversation.ConversationType = schema.ConversationTypeChannel
+	conversation.Privacy = schema.PrivacyPublic
+	if json.Type != "" {
+		conversation.ConversationType = conversationType(json.Type)
+	}
+	if json.Privacy != "" {
+		conversation.Privacy = privacy(json.Privacy)
+	}
+	conversation.CreatedAt = time.Now().UTC()
+	conversation.UpdatedAt = conversation.CreatedAt
+	conversation.LastActiveAt = conversation.CreatedAt
+
+	// the creator joins the conversation as its first participant
+	if err := store.CreateConversation(conversationInfo(conversation)); err != nil {
+		if err == meta.ErrNamespaceNotFound {
+			return nil, ErrNamespaceNotFound
+		}
+		return nil, err
+	}
+
+	return FindConversation(conversation.ID.Hex())
 }
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// objectID converts an ID held by the meta store. IDs that are not object ids convert to an empty id.
+func objectID(id string) bson.ObjectId {
+	if !bson.IsObjectIdHex(id) {
+		return bson.ObjectId("")
+	}
+	return bson.ObjectIdHex(id)
+}
+
+// ownerType converts the owner type of a namespace.
+func ownerType(t string) schema.OwnerType {
+	if t == meta.NamespaceOwnerOrganization {
+		return schema.OwnerTypeOrganization
+	}
+	return schema.OwnerTypeUser
+}
+
+// namespacePath returns the path of a namespace, or an empty string if it doesn't exist.
+func namespacePath(id string) (string, error) {
+	ni, err := store.NamespaceByID(id)
+	if err != nil || ni == nil {
+		return "", err
+	}
+	return ni.Path, nil
+}
+
+// namespaceFromInfo converts a namespace held by the meta store.
+func namespaceFromInfo(ni *meta.NamespaceInfo) *schema.Namespace {
+	return &schema.Namespace{
+		ID:        objectID(ni.ID),
+		Path:      ni.Path,
+		OwnerID:   objectID(ni.OwnerID),
+		OwnerType: ownerType(ni.OwnerType),
+		CreatedAt: ni.CreatedAt,
+		UpdatedAt: ni.UpdatedAt,
+	}
+}
+
+// userFromAccount converts a user account held by the meta store.
+func userFromAccount(ai *meta.AccountInfo) (*schema.User, error) {
+	path, err := namespacePath(ai.NamespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &schema.User{
+		ID:             objectID(ai.ID),
+		Username:       ai.Username,
+		GivenName:      ai.GivenName,
+		FamilyName:     ai.FamilyName,
+		HashedPassword: ai.Hash,
+		CreatedAt:      ai.CreatedAt,
+		UpdatedAt:      ai.UpdatedAt,
+	}
+	user.Namespace.ID = objectID(ai.NamespaceID)
+	user.Namespace.Path = path
+	user.Namespace.OwnerType = schema.OwnerTypeUser
+
+	for _, e := range ai.Emails {
+		user.Emails = append(user.Emails, schema.EmailAddress{Email: e.Email, IsConfirmed: e.Confirmed, ConfirmedAt: e.ConfirmedAt})
+	}
+	if ai.PrimaryEmail != "" {
+		user.SetPrimaryEmail(ai.PrimaryEmail)
+	}
+	return user, nil
+}
+
+// accountFromUser converts a user back into the account held by the meta store.
+func accountFromUser(user *schema.User) meta.AccountInfo {
+	ai := meta.AccountInfo{
+		ID:           user.ID.Hex(),
+		Username:     user.Username,
+		NamespaceID:  user.Namespace.ID.Hex(),
+		GivenName:    user.GivenName,
+		FamilyName:   user.FamilyName,
+		Hash:         user.HashedPassword,
+		PrimaryEmail: user.GetPrimaryEmail(),
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+	}
+	for _, e := range user.Emails {
+		ai.Emails = append(ai.Emails, meta.EmailInfo{Email: e.Email, Confirmed: e.IsConfirmed, ConfirmedAt: e.ConfirmedAt})
+	}
+	return ai
+}
+
+// organizationFromInfo converts an organization held by the meta store.
+func organizationFromInfo(oi *meta.OrganizationInfo) (*schema.Organization, error) {
+	path, err := namespacePath(oi.NamespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	org := &schema.Organization{
+		ID:           objectID(oi.ID),
+		Name:         oi.Name,
+		Description:  oi.Description,
+		URL:          oi.URL,
+		Location:     oi.Location,
+		Email:        oi.Email,
+		BillingEmail: oi.BillingEmail,
+		CreatedAt:    oi.CreatedAt,
+		UpdatedAt:    oi.UpdatedAt,
+	}
+	org.Namespace.ID = objectID(oi.NamespaceID)
+	org.Namespace.Path = path
+	org.Namespace.OwnerType = schema.OwnerTypeOrganization
+	return org, nil
+}
+
+// memberFromInfo converts an organization membership held by the meta store.
+func memberFromInfo(mi *meta.MemberInfo) *schema.Member {
+	return &schema.Member{
+		UserID:           objectID(mi.UserID),
+		OrganizationID:   objectID(mi.OrganizationID),
+		State:            mi.State,
+		Published:        mi.Published,
+		Role:             memberRole(mi.Role),
+		InvitedBy:        objectID(mi.InvitedBy),
+		InviteEmail:      mi.InviteEmail,
+		InviteAcceptedAt: mi.InviteAcceptedAt,
+		CreatedAt:        mi.CreatedAt,
+		UpdatedAt:        mi.UpdatedAt,
+	}
+}
+
+// invitationFromInfo converts an invitation held by the meta store into a pending membership.
+func invitationFromInfo(ii *meta.InvitationInfo) *schema.Member {
+	return &schema.Member{
+		ID:              objectID(ii.ID),
+		OrganizationID:  objectID(ii.OrganizationID),
+		State:           meta.MemberStatePending,
+		Role:            memberRole(ii.Role),
+		InvitedBy:       objectID(ii.InvitedBy),
+		InviteEmail:     ii.Email,
+		InviteSentAt:    ii.SentAt,
+		InviteExpiresAt: ii.ExpiresAt,
+		CreatedAt:       ii.CreatedAt,
+		UpdatedAt:       ii.SentAt,
+	}
+}
+
+// memberRole returns the membership role with the given name. Defaults to member.
+func memberRole(name string) schema.MemberRole {
+	switch name {
+	case meta.MemberRoleOwner:
+		return schema.MemberRoleOwner
+	case meta.MemberRoleGuest:
+		return schema.MemberRoleGuest
+	}
+	return schema.MemberRoleMember
+}
+
+// conversationFromInfo converts a conversation held by the meta store.
+func conversationFromInfo(ci *meta.ConversationInfo) (*schema.Conversation, error) {
+	ni, err := store.NamespaceByID(ci.NamespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &schema.Conversation{
+		ID:           objectID(ci.ID),
+		CreatorID:    objectID(ci.CreatorID),
+		Title:        ci.Title,
+		Purpose:      ci.Purpose,
+		Topic:        ci.Topic,
+		LastActiveAt: ci.LastActiveAt,
+		Archived:     ci.Archived,
+		ArchivedAt:   ci.ArchivedAt,
+		CreatedAt:    ci.CreatedAt,
+		UpdatedAt:    ci.UpdatedAt,
+
+		ParticipantsCount: ci.ParticipantsCount,
+	}
+	c.Namespace.ID = objectID(ci.NamespaceID)
+	if ni != nil {
+		c.Namespace.Path = ni.Path
+		c.Namespace.OwnerID = objectID(ni.OwnerID)
+		c.Namespace.OwnerType = ownerType(ni.OwnerType)
+	}
+
+	c.ConversationType = conversationType(ci.Type)
+	c.Privacy = privacy(ci.Privacy)
+	c.Retention.Mode = retentionMode(ci.RetentionMode)
+	c.Retention.Value = int(ci.RetentionValue)
+	return c, nil
+}
+
+// conversationInfo converts a conversation back into the conversation held by the meta store.
+func conversationInfo(c *schema.Conversation) meta.ConversationInfo {
+	return meta.ConversationInfo{
+		ID:             c.ID.Hex(),
+		NamespaceID:    c.Namespace.ID.Hex(),
+		CreatorID:      c.CreatorID.Hex(),
+		Title:          c.Title,
+		Purpose:        c.Purpose,
+		Topic:          c.Topic,
+		Type:           c.ConversationType.String(),
+		Privacy:        c.Privacy.String(),
+		RetentionMode:  c.Retention.Mode.String(),
+		RetentionValue: int64(c.Retention.Value),
+		Archived:       c.Archived,
+		ArchivedAt:     c.ArchivedAt,
+		LastActiveAt:   c.LastActiveAt,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+	}
+}
+
+// conversationType returns the conversation type with the given name. Defaults to a channel.
+func conversationType(name string) schema.ConversationType {
+	for _, t := range []schema.ConversationType{schema.ConversationTypePrivate, schema.ConversationTypeGroup, schema.ConversationTypeChannel} {
+		if t.String() == name {
+			return t
+		}
+	}
+	return schema.ConversationTypeChannel
+}
+
+// privacy returns the privacy with the given name. Defaults to public.
+func privacy(name string) schema.Privacy {
+	for _, p := range []schema.Privacy{schema.PrivacyPersonal, schema.PrivacyPublic, schema.PrivacyPrivate, schema.PrivacyProtected, schema.PrivacySecret} {
+		if p.String() == name {
+			return p
+		}
+	}
+	return schema.PrivacyPublic
+}
+
+// retentionMode returns the retention mode with the given name. Defaults to keeping all messages.
+func retentionMode(name string) schema.RetentionMode {
+	for _, m := range []schema.RetentionMode{schema.RetentionModeAll, schema.RetentionModeNone, schema.RetentionModeAge, schema.RetentionModeDays} {
+		if m.String() == name {
+			return m
+		}
+	}
+	return schema.RetentionModeAll
+}
+
+// deviceFromInfo converts a device held by the meta store.
+func deviceFromInfo(di *meta.DeviceInfo) *schema.Device {
+	return &schema.Device{
+		Id:        objectID(di.ID),
+		UserId:    objectID(di.UserID),
+		Name:      di.Name,
+		Platform:  di.Platform,
+		Token:     di.Token,
+		CreatedAt: di.CreatedAt,
+		UpdatedAt: di.UpdatedAt,
+	}
+}
+
+// teamFromInfo converts a team held by the meta store.
+func teamFromInfo(ti *meta.TeamInfo) *schema.Team {
+	t := &schema.Team{
+		ID:             objectID(ti.ID),
+		OrganizationID: objectID(ti.OrganizationID),
+		Name:           ti.Name,
+		Description:    ti.Description,
+		CreatedAt:      ti.CreatedAt,
+		UpdatedAt:      ti.UpdatedAt,
+	}
+	switch ti.Type {
+	case meta.TeamTypeOwners:
+		t.TeamType = schema.TeamTypeOwners
+	case meta.TeamTypeAdmins:
+		t.TeamType = schema.TeamTypeAdmins
+	default:
+		t.TeamType = schema.TeamTypeTeams
+	}
+	for _, id := range ti.Members {
+		t.MemberIDs = append(t.MemberIDs, objectID(id))
+	}
+	for _, id := range ti.Conversations {
+		t.Conversations = append(t.Conversations, objectID(id))
+	}
+	return t
+}
+
+// apiTokenFromInfo converts an API token held by the meta store.
+func apiTokenFromInfo(ti *meta.ApiTokenInfo) *schema.ApiToken {
+	return &schema.ApiToken{
+		ID:             objectID(ti.ID),
+		Name:           ti.Name,
+		UserID:         objectID(ti.UserID),
+		OrganizationID: objectID(ti.OrganizationID),
+		Scopes:         ti.Scopes,
+		RateLimit:      ti.RateLimit,
+		ExpiresAt:      ti.ExpiresAt,
+		LastUsedAt:     ti.LastUsedAt,
+		CreatedAt:      ti.CreatedAt,
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// FindDevice returns the device with the given ID. Returns nil if the device does not exist.
+func FindDevice(id string) (*schema.Device, error) {
+	di, err := store.Device(id)
+	if err != nil || di == nil {
+		return nil, err
+	}
+	return deviceFromInfo(di), nil
+}
+
+// ListDevices returns the client devices registered to the user
+func ListDevices(user *schema.User) ([]*schema.Device, error) {
+	infos, err := store.Devices(user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []*schema.Device{}
+	for i := range infos {
+		devices = append(devices, deviceFromInfo(&infos[i]))
+	}
+	return devices, nil
+}
+
+// AddDevice registers a new client device to the user
+func AddDevice(user *schema.User, json bindings.AddUpdateDevice) (*schema.Device, error) {
+	now := time.Now().UTC()
+	di := meta.DeviceInfo{
+		ID:        bson.NewObjectId().Hex(),
+		UserID:    user.ID.Hex(),
+		Name:      json.Name,
+		Platform:  json.Platform,
+		Token:     json.Token,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := store.AddDevice(di); err != nil {
+		return nil, err
+	}
+	return deviceFromInfo(&di), nil
+}
+
+// UpdateDevice modifies a client device
+func UpdateDevice(device *schema.Device, json bindings.AddUpdateDevice) (*schema.Device, error) {
+	di := meta.DeviceInfo{
+		ID:        device.Id.Hex(),
+		UserID:    device.UserId.Hex(),
+		Name:      json.Name,
+		Platform:  json.Platform,
+		Token:     json.Token,
+		CreatedAt: device.CreatedAt,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if err := store.UpdateDevice(di); err != nil {
+		return nil, err
+	}
+	return deviceFromInfo(&di), nil
+}
+
+// DeleteDevice removes a client device
+func DeleteDevice(device *schema.Device) error {
+	return store.DeleteDevice(device.Id.Hex())
+}
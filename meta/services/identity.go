@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/oidc"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// AuthorizationStateExpiry is the time a user has to log in with an identity provider.
+const AuthorizationStateExpiry = 10 * time.Minute
+
+// maxUsernameAttempts is the number of usernames tried when provisioning the user of an identity.
+const maxUsernameAttempts = 20
+
+var (
+	// ErrInvalidAuthorizationState is raised when a login with an identity provider was not started by this server
+	ErrInvalidAuthorizationState = errors.New("Invalid authorization state")
+
+	// ErrIdentityEmailUnconfirmed is raised when the email address of an identity belongs to a user who did not
+	// confirm it, the identity cannot be linked to that user
+	ErrIdentityEmailUnconfirmed = errors.New("Email address of the identity is not confirmed")
+
+	// ErrIdentityAlreadyLinked is raised when an identity was linked to another user in the meantime
+	ErrIdentityAlreadyLinked = errors.New("Identity already linked to another user")
+)
+
+// NewAuthorizationState returns the state and the nonce of a login with an identity provider. The state is a token
+// signed by the server, so that the callback of the provider can be validated by any node of the cluster.
+func (a *authService) NewAuthorizationState(provider string) (state, nonce string, err error) {
+	if nonce, err = newTokenID(); err != nil {
+		return "", "", err
+	}
+
+	now := time.Now().UTC()
+	state, err = a.sign(tokenTypeState, now, now.Add(AuthorizationStateExpiry), map[string]interface{}{
+		"provider": provider,
+		"nonce":    nonce,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return state, nonce, nil
+}
+
+// ValidateAuthorizationState validates the state the identity provider redirected back with, and returns the nonce the
+// ID token must carry. The state is revoked so that each login completes once.
+func (a *authService) ValidateAuthorizationState(state, provider string) (string, error) {
+	token, err := a.parseToken(state, tokenTypeState)
+	if err != nil {
+		return "", ErrInvalidAuthorizationState
+	} else if p, _ := token.Claims["provider"].(string); p != provider {
+		return "", ErrInvalidAuthorizationState
+	}
+
+	if err := revokeToken(token); err == meta.ErrTokenRevoked {
+		return "", ErrInvalidAuthorizationState
+	} else if err != nil {
+		return "", err
+	}
+
+	nonce, _ := token.Claims["nonce"].(string)
+	return nonce, nil
+}
+
+// AuthorizeIdentity returns the user an identity authenticated by an identity provider belongs to. An identity seen for
+// the first time is linked to the user its email address is confirmed by, when the provider verified it as well.
+// Otherwise a user is provisioned for the identity.
+func (a *authService) AuthorizeIdentity(identity *oidc.Identity) (*schema.User, error) {
+	ai, err := store.AccountByIdentity(identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	} else if ai != nil {
+		return userFromAccount(ai)
+	}
+
+	ii := meta.IdentityInfo{
+		Issuer:    identity.Issuer,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if identity.Email != "" && identity.EmailVerified {
+		user, err := FindUserByEmail(identity.Email)
+		if err != nil {
+			return nil, err
+		} else if user != nil {
+			return linkIdentity(user, identity.Email, ii)
+		}
+	}
+
+	return provisionUser(identity, ii)
+}
+
+// linkIdentity links an identity to the user its email address belongs to. The user must have confirmed the address.
+func linkIdentity(user *schema.User, email string, ii meta.IdentityInfo) (*schema.User, error) {
+	if !user.HasConfirmedEmail(email) {
+		return nil, ErrIdentityEmailUnconfirmed
+	}
+
+	if err := store.LinkIdentity(user.ID.Hex(), ii); err != nil {
+		if err == meta.ErrIdentityExists {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return nil, err
+	}
+	return FindUser(user.ID.Hex())
+}
+
+// provisionUser creates the user of an identity, named after the username or the email address the identity provider
+// asserted. The user has no usable password, it logs in with the identity provider.
+func provisionUser(identity *oidc.Identity, ii meta.IdentityInfo) (*schema.User, error) {
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(password)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ai := meta.AccountInfo{
+		ID:         bson.NewObjectId().Hex(),
+		GivenName:  identity.GivenName,
+		FamilyName: identity.FamilyName,
+		Hash:       string(hash),
+		Identities: []meta.IdentityInfo{ii},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if identity.Email != "" {
+		ai.PrimaryEmail = identity.Email
+		ai.Emails = []meta.EmailInfo{{Email: identity.Email}}
+		if identity.EmailVerified {
+			ai.Emails[0].Confirmed = true
+			ai.Emails[0].ConfirmedAt = now
+		}
+	}
+
+	// the username is made unique by a number suffix, retried when taken in the meantime
+	base := usernameOf(identity)
+	for i := 0; i < maxUsernameAttempts; i++ {
+		ai.Username = base
+		if i > 0 {
+			ai.Username = fmt.Sprintf("%s%d", base, i+1)
+		}
+		ai.NamespaceID = bson.NewObjectId().Hex()
+
+		if ns, err := store.Namespace(ai.Username); err != nil {
+			return nil, err
+		} else if ns != nil {
+			continue
+		}
+
+		err := store.CreateAccount(ai)
+		switch err {
+		case nil:
+			return FindUser(ai.ID)
+		case meta.ErrNamespaceExists:
+			continue
+		case meta.ErrEmailExists:
+			return nil, ErrEmailAlreadyExists
+		case meta.ErrIdentityExists:
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return nil, err
+	}
+	return nil, ErrNamespaceAlreadyExists
+}
+
+// usernameOf returns a username for the user of an identity, made of the characters allowed in namespace paths.
+func usernameOf(identity *oidc.Identity) string {
+	for _, s := range []string{identity.PreferredUsername, strings.SplitN(identity.Email, "@", 2)[0]} {
+		var b []byte
+		for _, c := range []byte(strings.ToLower(s)) {
+			switch {
+			case (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'):
+				b = append(b, c)
+			case len(b) > 0 && (c == '.' || c == '-' || c == '_'):
+				b = append(b, c)
+			}
+		}
+
+		// leave room for the suffix making the username unique
+		if max := meta.MaxNamespacePathLength - 2; len(b) > max {
+			b = b[:max]
+		}
+		if len(b) > 0 {
+			return string(b)
+		}
+	}
+	return "user"
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/messagedb/messagedb/oidc"
+	"github.com/messagedb/messagedb/oidc/oidctest"
+)
+
+// mustExchange logs in with the identity provider as identity and returns the identity asserted by its ID token.
+func mustExchange(t *testing.T, s *oidctest.Server, identity oidc.Identity) *oidc.Identity {
+	s.SetIdentity(identity)
+	p := oidc.NewProvider(s.Config("corp", "http://localhost:8075/authorize/corp/callback"), nil)
+
+	authURL, err := p.AuthCodeURL("state0", "nonce0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := s.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := p.Exchange(code, "nonce0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return other
+}
+
+// Ensure the user of a new identity is provisioned, and found again on the next logins.
+func TestAuth_AuthorizeIdentity_Provision(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+	idp := oidctest.NewServer("client0", "secret0")
+	defer idp.Close()
+
+	a := newAuthService()
+	mustRegisterUser(t, "jdoe", "john@example.com")
+
+	identity := mustExchange(t, idp, oidc.Identity{Subject: "0001", Email: "jdoe@example.com", EmailVerified: true, PreferredUsername: "JDoe"})
+	user, err := a.AuthorizeIdentity(identity)
+	if err != nil {
+		t.Fatal(err)
+	} else if user.Username != "jdoe2" {
+		t.Fatalf("unexpected username: %s", user.Username)
+	} else if !user.HasConfirmedEmail("jdoe@example.com") {
+		t.Fatal("expected the verified address to be confirmed")
+	}
+
+	if other, err := a.AuthorizeIdentity(identity); err != nil {
+		t.Fatal(err)
+	} else if other.ID != user.ID {
+		t.Fatalf("unexpected user: %s", other.Username)
+	}
+
+	// an unverified address is registered unconfirmed
+	identity = mustExchange(t, idp, oidc.Identity{Subject: "0002", Email: "jane@example.com"})
+	if user, err := a.AuthorizeIdentity(identity); err != nil {
+		t.Fatal(err)
+	} else if user.Username != "jane" || !user.HasEmailAddress("jane@example.com") || user.HasConfirmedEmail("jane@example.com") {
+		t.Fatalf("unexpected user: %#v", user)
+	}
+}
+
+// Ensure an identity is linked to the user who confirmed its verified email address, and to no other user.
+func TestAuth_AuthorizeIdentity_Link(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+	mail, closeMailbox := mustOpenMailbox()
+	defer closeMailbox()
+	idp := oidctest.NewServer("client0", "secret0")
+	defer idp.Close()
+
+	a := newAuthService()
+	susy := mustRegisterUser(t, "susy", "susy@example.com")
+	if err := a.SendEmailVerification(susy, "susy@example.com"); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ConfirmEmail(mail.token(t, "susy@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	mustRegisterUser(t, "bob", "bob@example.com")
+
+	// an address the provider did not verify does not prove the identity belongs to the user
+	unverified := mustExchange(t, idp, oidc.Identity{Subject: "0001", Email: "susy@example.com", PreferredUsername: "susy"})
+	if _, err := a.AuthorizeIdentity(unverified); err != ErrEmailAlreadyExists {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// nor does an address the user did not confirm
+	unconfirmed := mustExchange(t, idp, oidc.Identity{Subject: "0002", Email: "bob@example.com", EmailVerified: true})
+	if _, err := a.AuthorizeIdentity(unconfirmed); err != ErrIdentityEmailUnconfirmed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	verified := mustExchange(t, idp, oidc.Identity{Subject: "0003", Email: "SUSY@example.com", EmailVerified: true})
+	if user, err := a.AuthorizeIdentity(verified); err != nil {
+		t.Fatal(err)
+	} else if user.ID != susy.ID {
+		t.Fatalf("unexpected user: %s", user.Username)
+	}
+	if ai, err := s.AccountByIdentity(verified.Issuer, verified.Subject); err != nil {
+		t.Fatal(err)
+	} else if ai == nil || ai.ID != susy.ID.Hex() {
+		t.Fatalf("unexpected account: %#v", ai)
+	}
+}
+
+// Ensure an authorization state completes a single login with the provider it was issued for.
+func TestAuth_ValidateAuthorizationState(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+
+	a := newAuthService()
+	state, nonce, err := a.NewAuthorizationState("corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.ValidateAuthorizationState(state, "other"); err != ErrInvalidAuthorizationState {
+		t.Fatalf("unexpected error: %v", err)
+	} else if other, err := a.ValidateAuthorizationState(state, "corp"); err != nil {
+		t.Fatal(err)
+	} else if other != nonce {
+		t.Fatalf("unexpected nonce: %s", other)
+	} else if _, err := a.ValidateAuthorizationState(state, "corp"); err != ErrInvalidAuthorizationState {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/notify"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DefaultInvitationExpiry is the default time an invitation can be accepted after it is sent.
+const DefaultInvitationExpiry = 7 * 24 * time.Hour
+
+var (
+	// ErrInvitationNotFound is raised when an invitation does not exist or its token is not valid
+	ErrInvitationNotFound = errors.New("Invitation not found")
+
+	// ErrInvitationExpired is raised when accepting an invitation that has expired
+	ErrInvitationExpired = errors.New("Invitation has expired")
+
+	// ErrInvitationNotDelivered is raised when an invitation is saved but the notifier failed to deliver it
+	ErrInvitationNotDelivered = errors.New("Invitation could not be delivered")
+
+	// ErrAlreadyInvited is raised when inviting an email address that has a pending invitation to the organization
+	ErrAlreadyInvited = errors.New("Email address has already been invited to the organization")
+
+	// ErrAlreadyAMember is raised when inviting an email address registered to a member of the organization
+	ErrAlreadyAMember = errors.New("User is already a member of the organization")
+)
+
+var (
+	// InvitationExpiry is the time an invitation can be accepted after it is sent.
+	InvitationExpiry = DefaultInvitationExpiry
+
+	// InvitationURL is the base of the link sent with an invitation, the token of the invitation is
+	// appended to it. Only the token is sent when it is empty.
+	InvitationURL string
+
+	// notifier delivers the invitations, the email verifications and the password resets. Invitations are saved but
+	// not delivered when it is nil.
+	notifier notify.Notifier
+)
+
+// SetNotifier sets the notifier that delivers the invitations, the email verifications and the password resets.
+func SetNotifier(n notify.Notifier) { notifier = n }
+
+// InviteMember invites an email address to join the organization. The authenticated user must be an organization owner.
+// The invitation is delivered with a token that accepts or declines it until it expires.
+func (s *OrganizationMembershipService) InviteMember(form bindings.InviteMember) (*schema.Member, error) {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	if form.Role == "" {
+		form.Role = meta.MemberRoleMember
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ii := meta.InvitationInfo{
+		ID:             bson.NewObjectId().Hex(),
+		OrganizationID: s.Org.ID.Hex(),
+		Email:          strings.ToLower(form.Email),
+		Role:           form.Role,
+		InvitedBy:      s.CurrentUser.ID.Hex(),
+		TokenHash:      hash,
+		SentAt:         now,
+		ExpiresAt:      now.Add(InvitationExpiry),
+		CreatedAt:      now,
+	}
+	if err := store.CreateInvitation(ii); err != nil {
+		switch err {
+		case meta.ErrInvalidMemberRole:
+			return nil, ErrInvalidMembershipRole
+		case meta.ErrInvitationExists:
+			return nil, ErrAlreadyInvited
+		case meta.ErrMemberExists:
+			return nil, ErrAlreadyAMember
+		}
+		return nil, err
+	}
+
+	if err := s.deliverInvitation(&ii, token); err != nil {
+		return nil, err
+	}
+	return invitationFromInfo(&ii), nil
+}
+
+// ListInvitations returns the pending invitations of the organization. The authenticated user must be an organization owner.
+func (s *OrganizationMembershipService) ListInvitations() ([]*schema.Member, error) {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	infos, err := store.Invitations(s.Org.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []*schema.Member{}
+	for i := range infos {
+		invitations = append(invitations, invitationFromInfo(&infos[i]))
+	}
+	return invitations, nil
+}
+
+// ResendInvitation delivers an invitation again with a new token and a new expiry. The token previously sent no longer
+// accepts the invitation. The authenticated user must be an organization owner.
+func (s *OrganizationMembershipService) ResendInvitation(id string) (*schema.Member, error) {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	ii, err := s.findInvitation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := store.ResendInvitation(ii.ID, hash, now, now.Add(InvitationExpiry)); err != nil {
+		if err == meta.ErrInvitationNotFound {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	ii.TokenHash, ii.SentAt, ii.ExpiresAt = hash, now, now.Add(InvitationExpiry)
+
+	if err := s.deliverInvitation(ii, token); err != nil {
+		return nil, err
+	}
+	return invitationFromInfo(ii), nil
+}
+
+// CancelInvitation removes a pending invitation of the organization. The authenticated user must be an organization owner.
+func (s *OrganizationMembershipService) CancelInvitation(id string) error {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return err
+	}
+
+	ii, err := s.findInvitation(id)
+	if err != nil {
+		return err
+	}
+	return deleteInvitation(ii.ID)
+}
+
+// findInvitation returns a pending invitation of the organization.
+func (s *OrganizationMembershipService) findInvitation(id string) (*meta.InvitationInfo, error) {
+	ii, err := store.Invitation(id)
+	if err != nil {
+		return nil, err
+	} else if ii == nil || ii.OrganizationID != s.Org.ID.Hex() {
+		return nil, ErrInvitationNotFound
+	}
+	return ii, nil
+}
+
+// deliverInvitation sends an invitation along with its token through the notifier.
+func (s *OrganizationMembershipService) deliverInvitation(ii *meta.InvitationInfo, token string) error {
+	if notifier == nil {
+		return nil
+	}
+
+	name := s.Org.Name
+	if name == "" {
+		name = s.Org.Namespace.Path
+	}
+
+	link := linkTo(InvitationURL, token)
+
+	m := &notify.Message{
+		To:      ii.Email,
+		Subject: fmt.Sprintf("You have been invited to join %s", name),
+		Body: fmt.Sprintf("%s has invited you to join the %s organization as %s.\n\nAccept or decline the invitation: %s\n\nThe invitation expires on %s.\n",
+			s.CurrentUser.Username, name, ii.Role, link, ii.ExpiresAt.Format(time.RFC1123)),
+	}
+	if err := notifier.Notify(m); err != nil {
+		return ErrInvitationNotDelivered
+	}
+	return nil
+}
+
+// AcceptInvitation makes the authenticated user an active member of the organization the token invites to
+func (s *AccountService) AcceptInvitation(token string) (*schema.Member, error) {
+	ii, err := findInvitationByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if ii.Expired(now) {
+		return nil, ErrInvitationExpired
+	}
+
+	if err := store.AcceptInvitation(ii.ID, s.User.ID.Hex(), now); err != nil {
+		switch err {
+		case meta.ErrInvitationNotFound:
+			return nil, ErrInvitationNotFound
+		case meta.ErrInvitationExpired:
+			return nil, ErrInvitationExpired
+		}
+		return nil, err
+	}
+
+	mi, err := store.Member(ii.OrganizationID, s.User.ID.Hex())
+	if err != nil {
+		return nil, err
+	} else if mi == nil {
+		return nil, ErrMembershipNotFound
+	}
+	return memberFromInfo(mi), nil
+}
+
+// DeclineInvitation removes the invitation sent with the token. Expired invitations can be declined.
+func DeclineInvitation(token string) error {
+	ii, err := findInvitationByToken(token)
+	if err != nil {
+		return err
+	}
+	return deleteInvitation(ii.ID)
+}
+
+// findInvitationByToken returns the invitation sent with a token.
+func findInvitationByToken(token string) (*meta.InvitationInfo, error) {
+	ii, err := store.InvitationByToken(hashSecretToken(token))
+	if err != nil {
+		return nil, err
+	} else if ii == nil {
+		return nil, ErrInvitationNotFound
+	}
+	return ii, nil
+}
+
+// deleteInvitation removes an invitation from the meta store.
+func deleteInvitation(id string) error {
+	if err := store.DeleteInvitation(id); err != nil {
+		if err == meta.ErrInvitationNotFound {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// newSecretToken returns a random token for an invitation or an API token along with its hash. Only
+// the hash is stored, so the token cannot be read back from the meta store.
+func newSecretToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, hashSecretToken(token), nil
+}
+
+// hashSecretToken returns the hex encoded SHA256 of a token.
+func hashSecretToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
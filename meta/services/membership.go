@@ -2,7 +2,9 @@ package services
 
 import (
 	"errors"
+	"time"
 
+	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
 	"github.com/messagedb/messagedb/meta/schema"
 )
@@ -11,8 +13,20 @@ var (
 	// ErrNotAnOrganizationOwner is raised when a user that is not an organization member tries to perform an action
 	ErrNotAnOrganizationOwner = errors.New("Authenticated user is not an organization owner")
 
+	// ErrNotAnOrganizationMember is raised when a user that is not an active organization member tries to perform an action
+	ErrNotAnOrganizationMember = errors.New("Authenticated user is not an organization member")
+
 	// ErrCannotChangeMembershipVisibility is raised when a user tries to change membership visibility of another user
 	ErrCannotChangeMembershipVisibility = errors.New("Cannot change other member's membership visibility")
+
+	// ErrMembershipNotFound is raised when the user is not a member of the organization
+	ErrMembershipNotFound = errors.New("Membership not found")
+
+	// ErrInvalidMembershipRole is raised when a membership is given a role other than owner, member or guest
+	ErrInvalidMembershipRole = errors.New("Invalid membership role")
+
+	// ErrLastOrganizationOwner is raised when the last owner of an organization would be removed or demoted
+	ErrLastOrganizationOwner = errors.New("Cannot remove the last owner of the organization")
 )
 
 // OrganizationMembershipService is a service that allows actions on an organization's membership
@@ -23,91 +37,130 @@ type OrganizationMembershipService struct {
 
 // GetMembership returns the Member record for the provided user
 func (s *OrganizationMembershipService) GetMembership(user *schema.User) (*schema.Member, error) {
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
 
-	currentMember, err := s.GetCurrentMembership()
+	return s.findMembership(user)
+}
+
+// GetCurrentMembership returns the organization membership for the authenticated user
+func (s *OrganizationMembershipService) GetCurrentMembership() (*schema.Member, error) {
+	return s.findMembership(s.CurrentUser)
+}
+
+// findMembership returns the organization membership of a user.
+func (s *OrganizationMembershipService) findMembership(user *schema.User) (*schema.Member, error) {
+	mi, err := store.Member(s.Org.ID.Hex(), user.ID.Hex())
 	if err != nil {
 		return nil, err
+	} else if mi == nil {
+		return nil, ErrMembershipNotFound
 	}
+	return memberFromInfo(mi), nil
+}
 
-	// if authenticated user is not an organization owner raise an error
-	if !currentMember.IsOwner() {
-		return nil, ErrNotAnOrganizationOwner
+// FindMember returns the membership of a user in an organization. Returns nil if the user is not a member.
+func FindMember(orgID, userID string) (*schema.Member, error) {
+	mi, err := store.Member(orgID, userID)
+	if err != nil || mi == nil {
+		return nil, err
 	}
+	return memberFromInfo(mi), nil
+}
 
-	// return models.Member.FindByUserID(s.Org.ID, user.ID)
-	return nil, nil //TODO: fix this
+// checkOwnership returns an error if the authenticated user is not an active owner of the organization.
+func (s *OrganizationMembershipService) checkOwnership() error {
+	currentMember, err := s.GetCurrentMembership()
+	if err == ErrMembershipNotFound {
+		return ErrNotAnOrganizationOwner
+	} else if err != nil {
+		return err
+	}
+
+	if !currentMember.IsOwner() || !currentMember.IsActive() {
+		return ErrNotAnOrganizationOwner
+	}
+	return nil
 }
 
-// GetCurrentMembership returns the organization membership for the authenticated user
-func (s *OrganizationMembershipService) GetCurrentMembership() (*schema.Member, error) {
-	// return models.Member.FindByUserID(s.Org.ID, s.CurrentUser)
-	return nil, nil //TODO: fix this
+// checkMembership returns an error if the authenticated user is not an active member of the organization.
+func (s *OrganizationMembershipService) checkMembership() error {
+	ok, err := s.CheckMembership(s.CurrentUser)
+	if err != nil {
+		return err
+	} else if !ok {
+		return ErrNotAnOrganizationMember
+	}
+	return nil
 }
 
 // GetMembers returns the list of all members of the organization
 func (s *OrganizationMembershipService) GetMembers() ([]*schema.Member, error) {
-	members := []*schema.Member{}
-	//TODO: fix this
-	// err := models.Member.Find(bson.M{"org_id": s.Org.ID}).All(&members)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	return members, nil
+	return s.listMembers(false)
 }
 
 // GetPublicMembers returns the organization public member
 func (s *OrganizationMembershipService) GetPublicMembers() ([]*schema.Member, error) {
+	return s.listMembers(true)
+}
+
+// listMembers returns the members of the organization, optionally only the published ones.
+func (s *OrganizationMembershipService) listMembers(published bool) ([]*schema.Member, error) {
+	infos, err := store.Members(s.Org.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
 	members := []*schema.Member{}
-	//TODO: fix this
-	// err := models.Member.Find(bson.M{"org_id": s.Org.ID, "published": true}).All(&members)
-	// if err != nil {
-	// 	return nil, err
-	// }
+	for i := range infos {
+		if published && !infos[i].Published {
+			continue
+		}
+		members = append(members, memberFromInfo(&infos[i]))
+	}
 	return members, nil
 }
 
 // CheckMembershipByUsername verifies if the provided username has a membership in the organization
 func (s *OrganizationMembershipService) CheckMembershipByUsername(username string) (bool, error) {
-	// user, _ := models.User.FindByUsername(username)
-	// return s.CheckMembership(user)
-	return false, nil
+	user, err := FindUserByUsername(username)
+	if err != nil || user == nil {
+		return false, err
+	}
+	return s.CheckMembership(user)
 }
 
 // CheckMembership verifies if the provided user has a membership in the organization
 func (s *OrganizationMembershipService) CheckMembership(user *schema.User) (bool, error) {
+	member, err := s.findMembership(user)
+	if err == ErrMembershipNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
 
-	//TODO: fix this
-	// member, err := models.Member.FindByUserID(s.Org.ID, user.ID)
-	// if err != nil {
-	// 	if err == storage.ErrNotFound {
-	// 		return false, nil
-	// 	}
-	// 	return false, err
-	// }
-	//
-	// if member.IsPending() {
-	// 	return false, nil
-	// }
+	if member.IsPending() {
+		return false, nil
+	}
 
 	return true, nil
 }
 
 // CheckPublicMembership verifies if the provided user has a public membership in the organization
 func (s *OrganizationMembershipService) CheckPublicMembership(user *schema.User) (bool, error) {
+	member, err := s.findMembership(user)
+	if err == ErrMembershipNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
 
-	//TODO: fix this
-	// member, err := models.Member.FindByUserID(s.Org.ID, user.ID)
-	// if err != nil {
-	// 	if err == storage.ErrNotFound {
-	// 		return false, nil
-	// 	}
-	// 	return false, err
-	// }
-	//
-	// // also check if membership has been published
-	// if member.IsPending() || member.IsPrivate() {
-	// 	return false, nil
-	// }
+	// also check if membership has been published
+	if member.IsPending() || member.IsPrivate() {
+		return false, nil
+	}
 
 	return true, nil
 }
@@ -120,17 +173,12 @@ func (s *OrganizationMembershipService) PublicizeMembership(user *schema.User) e
 		return ErrCannotChangeMembershipVisibility
 	}
 
-	//TODO: fix this
-	// member, err := models.Member.FindByUserID(s.Org.ID, user.ID)
-	// if err != nil {
-	// 	return err
-	// }
-	//
-	// if !member.Published {
-	// 	member.Published = true
-	// 	return member.Save()
-	// }
-
+	if err := store.PublicizeMembership(s.Org.ID.Hex(), user.ID.Hex(), time.Now().UTC()); err != nil {
+		if err == meta.ErrMemberNotFound {
+			return ErrMembershipNotFound
+		}
+		return err
+	}
 	return nil
 }
 
@@ -142,90 +190,53 @@ func (s *OrganizationMembershipService) ConcealMembership(user *schema.User) err
 		return ErrCannotChangeMembershipVisibility
 	}
 
-	// TODO: fix this
-	// member, err := models.Member.FindByUserID(s.Org.ID, user.ID)
-	// if err != nil {
-	// 	return err
-	// }
-	//
-	// if member.Published {
-	// 	member.Published = false
-	// 	return member.Save()
-	// }
-
+	if err := store.ConcealMembership(s.Org.ID.Hex(), user.ID.Hex(), time.Now().UTC()); err != nil {
+		if err == meta.ErrMemberNotFound {
+			return ErrMembershipNotFound
+		}
+		return err
+	}
 	return nil
 }
 
 // AddOrUpdateMembership adds or updates the membership for the user in the organization
 func (s *OrganizationMembershipService) AddOrUpdateMembership(user *schema.User, form bindings.AddUpdateMembership) (*schema.Member, error) {
 
-	currentMember, err := s.GetCurrentMembership()
-	if err != nil {
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
 		return nil, err
 	}
 
-	// if authenticated user is not an organization owner raise an error
-	if !currentMember.IsOwner() {
-		return nil, ErrNotAnOrganizationOwner
-	}
-
-	// TODO: fix this
-	// found := false
-	// member, err := models.Member.FindByUserID(s.Org.ID, user.ID)
-	// if err != nil {
-	// 	if err == storage.ErrNotFound {
-	// 		found = true
-	// 	} else {
-	// 		return nil, err
-	// 	}
-	// }
-	//
-	// if !found {
-	// 	member := models.Member.New()
-	// 	member.UserID = user.ID
-	// 	member.OrganizationID = s.Org.ID
-	// }
-	//
-	// if form.Role == "owner" {
-	// 	member.Role = schema.MemberRoleOwner
-	// } else {
-	// 	member.Role = schema.MemberRoleMember
-	// }
-	//
-	// err = member.Save()
-	// if err != nil {
-	// 	return nil, err
-	// }
-	//
-	// return member, nil
-	return nil, nil
+	if err := store.AddOrUpdateMembership(s.Org.ID.Hex(), user.ID.Hex(), form.Role, time.Now().UTC()); err != nil {
+		switch err {
+		case meta.ErrInvalidMemberRole:
+			return nil, ErrInvalidMembershipRole
+		case meta.ErrLastOrganizationOwner:
+			return nil, ErrLastOrganizationOwner
+		}
+		return nil, err
+	}
+
+	return s.findMembership(user)
 }
 
 // RemoveMembership removes the user from the organization
 func (s *OrganizationMembershipService) RemoveMembership(user *schema.User) error {
 
-	//TODO: don't allow to remove current user's own ownership
-
-	currentMember, err := s.GetCurrentMembership()
-	if err != nil {
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
 		return err
 	}
 
-	// if authenticated user is not an organization owner raise an error
-	if !currentMember.IsOwner() {
-		return ErrNotAnOrganizationOwner
+	// the last owner of the organization cannot be removed, which includes the current user
+	if err := store.RemoveMembership(s.Org.ID.Hex(), user.ID.Hex()); err != nil {
+		switch err {
+		case meta.ErrMemberNotFound:
+			return ErrMembershipNotFound
+		case meta.ErrLastOrganizationOwner:
+			return ErrLastOrganizationOwner
+		}
+		return err
 	}
-
-	// TODO: fix this
-	// member, err := models.Member.FindByUserID(s.Org.ID, user.ID)
-	// if err != nil {
-	// 	return err
-	// }
-	//
-	// err = models.Member.RemoveID(member.ID)
-	// if err != nil {
-	// 	return err
-	// }
-	//
 	return nil
 }
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/schema"
+)
+
+// ResolveNamespace returns the namespace with the given path. The previous paths of renamed namespaces
+// resolve to the namespace, in which case redirected is true. Returns nil if the path is not taken.
+func ResolveNamespace(path string) (namespace *schema.Namespace, redirected bool, err error) {
+	ni, redirected, err := store.ResolveNamespace(path)
+	if err != nil || ni == nil {
+		return nil, false, err
+	}
+	return namespaceFromInfo(ni), redirected, nil
+}
+
+// FindNamespaceOwner returns the user or the organization owning the namespace with the given path, following
+// the redirects of renamed namespaces. Both are nil if the path is not taken.
+func FindNamespaceOwner(path string) (*schema.User, *schema.Organization, error) {
+	namespace, _, err := ResolveNamespace(path)
+	if err != nil || namespace == nil {
+		return nil, nil, err
+	}
+
+	if namespace.BelongsToOrganization() {
+		org, err := FindOrganization(namespace.OwnerID.Hex())
+		return nil, org, err
+	}
+	user, err := FindUser(namespace.OwnerID.Hex())
+	return user, nil, err
+}
+
+// renameNamespace changes the path of a namespace. The previous path keeps redirecting to it.
+func renameNamespace(namespaceID, path string) error {
+	if err := store.RenameNamespace(namespaceID, path, time.Now().UTC()); err != nil {
+		switch err {
+		case meta.ErrNamespaceExists:
+			return ErrNamespaceAlreadyExists
+		case meta.ErrInvalidNamespacePath:
+			return ErrInvalidNamespacePath
+		case meta.ErrNamespaceNotFound:
+			return ErrNamespaceNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -2,14 +2,22 @@ package services
 
 import (
 	"errors"
+	"strings"
+	"time"
 
+	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
 	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
 )
 
 var (
 	// ErrOrganizationDuplicateKey is raised when there is an error saving an organization to the database because of unique index conflict
 	ErrOrganizationDuplicateKey = errors.New("Duplicate Key for Organization")
+
+	// ErrOrganizationNotFound is raised when the organization a service is created for does not exist
+	ErrOrganizationNotFound = errors.New("Organization not found")
 )
 
 // OrganizationService is a service that allows operations on an organization. It wraps an organization and the current user making API requests.
@@ -23,112 +31,143 @@ type OrganizationService struct {
 
 // NewOrganizationService creates a service that wraps an organization and the current user making API requests
 func NewOrganizationService(orgParam interface{}, currentUser *schema.User) (*OrganizationService, error) {
-	// var org *schema.Organization
-	// var ok bool
-	// var err error
-	//
-	// if org, ok = orgParam.(*schema.Organization); !ok {
-	// 	orgid := utils.ObjectId(orgParam)
-	// 	org, err = models.Organization.FindByID(orgid)
-	// 	if err != nil {
-	// 		log.Errorf("Unable to find organization when creating OrganizationService: %v", orgid)
-	// 		return nil, err
-	// 	}
-	// }
-	//
-	// service := &OrganizationService{
-	// 	Org:                           org,
-	// 	CurrentUser:                   currentUser,
-	// 	OrganizationMembershipService: &OrganizationMembershipService{Org: org, CurrentUser: currentUser},
-	// }
-	//
-	// return service, nil
-	return nil, nil
+	org, ok := orgParam.(*schema.Organization)
+	if !ok {
+		o, err := FindOrganization(idOf(orgParam))
+		if err != nil {
+			return nil, err
+		} else if o == nil {
+			return nil, ErrOrganizationNotFound
+		}
+		org = o
+	}
+
+	service := &OrganizationService{
+		Org:                           org,
+		CurrentUser:                   currentUser,
+		OrganizationMembershipService: &OrganizationMembershipService{Org: org, CurrentUser: currentUser},
+	}
+
+	return service, nil
+}
+
+// FindOrganization returns the organization with the given ID or namespace path. Returns nil if the
+// organization does not exist.
+func FindOrganization(idOrPath string) (*schema.Organization, error) {
+	oi, err := store.Organization(idOrPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// the previous paths of renamed organizations still resolve to them
+	if oi == nil {
+		ni, _, err := store.ResolveNamespace(idOrPath)
+		if err != nil || ni == nil || ni.OwnerType != meta.NamespaceOwnerOrganization {
+			return nil, err
+		}
+		if oi, err = store.Organization(ni.OwnerID); err != nil || oi == nil {
+			return nil, err
+		}
+	}
+
+	return organizationFromInfo(oi)
+}
+
+// ListAllOrganizations returns a list of all organizations
+func ListAllOrganizations() ([]*schema.Organization, error) {
+	infos, err := store.Organizations()
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := []*schema.Organization{}
+	for i := range infos {
+		org, err := organizationFromInfo(&infos[i])
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
 }
 
 // UpdateOrganization modifies the organization wrapped by the service
 func (s *OrganizationService) UpdateOrganization(newOrg bindings.UpdateOrganization) (*schema.Organization, error) {
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	// the previous path keeps redirecting to the organization
+	if newOrg.Path != "" && !strings.EqualFold(newOrg.Path, s.Org.Namespace.Path) {
+		if err := renameNamespace(s.Org.Namespace.ID.Hex(), newOrg.Path); err != nil {
+			return nil, err
+		}
+	}
 
-	// TODO: fix this
-	// // copy fields from bindings payload into the target object
-	// s.Org.Name = newOrg.Name
-	// s.Org.BillingEmail = newOrg.BillingEmail
-	// s.Org.Email = newOrg.Email
-	// s.Org.Description = newOrg.Description
-	// s.Org.URL = newOrg.URL
-	// s.Org.Location = newOrg.Location
-	//
-	// err := s.Org.Save()
-	// if err != nil {
-	// 	log.Errorf("Error saving Organization %v", s.Org)
-	// 	return nil, err
-	// }
-	//
-	// return s.Org, nil
-	return nil, nil
+	oi := meta.OrganizationInfo{
+		ID:           s.Org.ID.Hex(),
+		NamespaceID:  s.Org.Namespace.ID.Hex(),
+		Name:         newOrg.Name,
+		Description:  newOrg.Description,
+		URL:          newOrg.URL,
+		Location:     newOrg.Location,
+		Email:        newOrg.Email,
+		BillingEmail: newOrg.BillingEmail,
+		CreatedAt:    s.Org.CreatedAt,
+		UpdatedAt:    time.Now().UTC(),
+	}
+	if err := store.UpdateOrganization(oi); err != nil {
+		return nil, err
+	}
+
+	org, err := organizationFromInfo(&oi)
+	if err != nil {
+		return nil, err
+	}
+	s.Org = org
+	s.OrganizationMembershipService.Org = org
+	return org, nil
 }
 
 // CreateOrganization creates a new organization and makes the current user the owner of the new organization
 func CreateOrganization(newOrg bindings.CreateOrganization, currentUser *schema.User) (*schema.Organization, error) {
-	//TODO: fix this
-	// var err error
-	//
-	// namespace, err := models.Namespace.FindByPath(newOrg.Path)
-	// if err != nil && err != storage.ErrNotFound {
-	// 	return nil, err
-	// }
-	//
-	// // if namespace was found... then return error
-	// if namespace != nil {
-	// 	log.Errorf("Error Namespace already exists: %v", namespace)
-	// 	return nil, ErrNamespaceAlreadyExists
-	// }
-	//
-	// // creates new Namespace
-	// namespace = models.Namespace.New()
-	// namespace.Path = strings.ToLower(newOrg.Path)
-	// namespace.OwnerType = schema.OwnerTypeOrganization
-	//
-	// err = namespace.Save()
-	// if err != nil {
-	// 	if mgo.IsDup(err) {
-	// 		log.Errorf("Duplicate key when saving namespace %v", namespace)
-	// 		return nil, ErrNamespaceDuplicateKey
-	// 	}
-	// 	return nil, err
-	// }
-	//
-	// // lets create a new Organization instance
-	// org := models.Organization.New()
-	// org.Namespace.ID = namespace.ID
-	// org.Namespace.Path = namespace.Path
-	// org.Name = newOrg.Path
-	// org.BillingEmail = newOrg.BillingEmail
-	//
-	// err = org.Save()
-	// if err != nil {
-	// 	if mgo.IsDup(err) {
-	// 		log.Errorf("Duplicate key when saving organization %v", org)
-	// 		return nil, ErrOrganizationDuplicateKey
-	// 	}
-	// 	log.Errorf("Error saving Organization %v", org)
-	// 	return nil, err
-	// }
-	//
-	// // associate the new org with the namespace owner_id
-	// namespace.OwnerID = org.ID
-	//
-	// // performs the save to the database
-	// err = namespace.Save()
-	// if err != nil {
-	// 	if mgo.IsDup(err) {
-	// 		log.Errorf("Duplicate key when saving namespace %v", namespace)
-	// 		return nil, ErrNamespaceDuplicateKey
-	// 	}
-	// 	log.Errorf("Error saving Namespace %v: %v", namespace, err)
-	// 	return nil, err
-	// }
-	//
-	// return org, nil
-	return nil, nil
+	namespace, err := store.Namespace(newOrg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// if namespace was found... then return error
+	if namespace != nil {
+		return nil, ErrNamespaceAlreadyExists
+	}
+
+	now := time.Now().UTC()
+	oi := meta.OrganizationInfo{
+		ID:           bson.NewObjectId().Hex(),
+		NamespaceID:  bson.NewObjectId().Hex(),
+		Name:         newOrg.Path,
+		BillingEmail: newOrg.BillingEmail,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	// the organization, its namespace and the owner membership are created at once
+	if err := store.CreateOrganization(oi, newOrg.Path, currentUser.ID.Hex()); err != nil {
+		switch err {
+		case meta.ErrNamespaceExists:
+			return nil, ErrNamespaceAlreadyExists
+		case meta.ErrInvalidNamespacePath:
+			return nil, ErrInvalidNamespacePath
+		case meta.ErrOrganizationExists:
+			return nil, ErrOrganizationDuplicateKey
+		}
+		return nil, err
+	}
+
+	// the owner is the first member of the Owners team of the organization
+	if err := createOwnersTeam(oi.ID, currentUser, now); err != nil {
+		return nil, err
+	}
+
+	return FindOrganization(oi.ID)
 }
@@ -2,9 +2,14 @@ package services
 
 import (
 	"errors"
+	"time"
 
+	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
 	"github.com/messagedb/messagedb/meta/schema"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/mgo.v2/bson"
 )
 
 // Errors
@@ -12,67 +17,53 @@ var (
 	ErrNamespaceAlreadyExists = errors.New("Namespace already exists")
 	ErrNamespaceDuplicateKey  = errors.New("Namespace duplicate key error")
 	ErrUserDuplicateKey       = errors.New("Duplicate Key for Organization")
+	ErrInvalidNamespacePath   = errors.New("Invalid namespace path")
+	ErrEmailAlreadyExists     = errors.New("Email address already exists")
 )
 
 // RegisterNewUser creates a new user account
 func RegisterNewUser(newUser bindings.RegisterNewUser) (*schema.User, error) {
+	namespace, err := store.Namespace(newUser.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	// if namespace was found... then return error
+	if namespace != nil {
+		return nil, ErrNamespaceAlreadyExists
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ai := meta.AccountInfo{
+		ID:           bson.NewObjectId().Hex(),
+		Username:     newUser.Username,
+		NamespaceID:  bson.NewObjectId().Hex(),
+		Hash:         string(hash),
+		PrimaryEmail: newUser.EmailAddress,
+		Emails:       []meta.EmailInfo{{Email: newUser.EmailAddress}},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	// the account and the namespace of its username are created at once
+	if err := store.CreateAccount(ai); err != nil {
+		switch err {
+		case meta.ErrNamespaceExists:
+			return nil, ErrNamespaceAlreadyExists
+		case meta.ErrInvalidNamespacePath:
+			return nil, ErrInvalidNamespacePath
+		case meta.ErrEmailExists:
+			return nil, ErrEmailAlreadyExists
+		case meta.ErrAccountExists:
+			return nil, ErrUserDuplicateKey
+		}
+		return nil, err
+	}
 
-	// var err error
-	//
-	// namespace, err := models.Namespace.FindByPath(newUser.Username)
-	// if err != nil && err != storage.ErrNotFound {
-	// 	return nil, err
-	// }
-	//
-	// // if namespace was found... then return error
-	// if namespace != nil {
-	// 	log.Errorf("Error Namespace already exists: %v", namespace)
-	// 	return nil, ErrNamespaceAlreadyExists
-	// }
-	//
-	// // creates new Namespace
-	// namespace = models.Namespace.New()
-	// namespace.Path = strings.ToLower(newUser.Username)
-	// namespace.OwnerType = schema.OwnerTypeUser
-	//
-	// err = namespace.Save()
-	// if err != nil {
-	// 	if mgo.IsDup(err) {
-	// 		log.Errorf("Duplicate key when saving namespace %v", namespace)
-	// 		return nil, ErrNamespaceDuplicateKey
-	// 	}
-	// 	return nil, err
-	// }
-	//
-	// // Creates a new user
-	// user := models.User.New()
-	// user.Namespace.ID = namespace.ID
-	// user.Namespace.Path = namespace.Path
-	// user.Username = namespace.Path
-	// user.SetPrimaryEmail(newUser.EmailAddress)
-	// user.SetPassword(newUser.Password)
-	// err = user.Save()
-	// if err != nil {
-	// 	if mgo.IsDup(err) {
-	// 		log.Errorf("Duplicate key when saving user %v", user)
-	// 		return nil, ErrUserDuplicateKey
-	// 	}
-	// 	log.Errorf("Error saving User %v", user)
-	// 	return nil, err
-	// }
-	//
-	// // associate the new user with the namespace owner_id
-	// namespace.OwnerID = user.ID
-	// err = namespace.Save()
-	// if err != nil {
-	// 	if mgo.IsDup(err) {
-	// 		log.Errorf("Duplicate key when saving namespace %v", namespace)
-	// 		return nil, ErrNamespaceDuplicateKey
-	// 	}
-	// 	log.Errorf("Error saving Namespace %v", namespace)
-	// 	return nil, err
-	// }
-	//
-	// return user, nil
-	return nil, nil
+	return FindUser(ai.ID)
 }
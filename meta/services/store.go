@@ -0,0 +1,84 @@
+package services
+
+import (
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+)
+
+// MetaStore is the subset of the meta store the services read and replicate their entities through.
+type MetaStore interface {
+	Namespace(path string) (*meta.NamespaceInfo, error)
+	NamespaceByID(id string) (*meta.NamespaceInfo, error)
+	ResolveNamespace(path string) (*meta.NamespaceInfo, bool, error)
+	RenameNamespace(id, path string, t time.Time) error
+
+	Account(id string) (*meta.AccountInfo, error)
+	AccountByUsername(username string) (*meta.AccountInfo, error)
+	AccountByEmail(email string) (*meta.AccountInfo, error)
+	AccountByIdentity(issuer, subject string) (*meta.AccountInfo, error)
+	Accounts() ([]meta.AccountInfo, error)
+	CreateAccount(ai meta.AccountInfo) error
+	UpdateAccount(ai meta.AccountInfo) error
+	LinkIdentity(userID string, ii meta.IdentityInfo) error
+
+	Organization(id string) (*meta.OrganizationInfo, error)
+	Organizations() ([]meta.OrganizationInfo, error)
+	CreateOrganization(oi meta.OrganizationInfo, path, ownerID string) error
+	UpdateOrganization(oi meta.OrganizationInfo) error
+
+	Member(orgID, userID string) (*meta.MemberInfo, error)
+	Members(orgID string) ([]meta.MemberInfo, error)
+	Memberships(userID string) ([]meta.MemberInfo, error)
+	AddOrUpdateMembership(orgID, userID, role string, t time.Time) error
+	RemoveMembership(orgID, userID string) error
+	EditMyMembership(orgID, userID, state string, t time.Time) error
+	PublicizeMembership(orgID, userID string, t time.Time) error
+	ConcealMembership(orgID, userID string, t time.Time) error
+
+	Invitation(id string) (*meta.InvitationInfo, error)
+	InvitationByToken(tokenHash string) (*meta.InvitationInfo, error)
+	Invitations(orgID string) ([]meta.InvitationInfo, error)
+	CreateInvitation(ii meta.InvitationInfo) error
+	ResendInvitation(id, tokenHash string, sentAt, expiresAt time.Time) error
+	AcceptInvitation(id, userID string, t time.Time) error
+	DeleteInvitation(id string) error
+
+	Team(id string) (*meta.TeamInfo, error)
+	Teams(orgID string) ([]meta.TeamInfo, error)
+	CreateTeam(ti meta.TeamInfo) error
+	UpdateTeam(ti meta.TeamInfo) error
+	DropTeam(id string) error
+	AddTeamMember(teamID, userID string, t time.Time) error
+	RemoveTeamMember(teamID, userID string) error
+	GrantTeamConversation(teamID, conversationID string, t time.Time) error
+	RevokeTeamConversation(teamID, conversationID string) error
+
+	Conversation(id string) (*meta.ConversationInfo, error)
+	AllConversations() ([]meta.ConversationInfo, error)
+	NamespaceConversations(namespaceID string) ([]meta.ConversationInfo, error)
+	CreateConversation(ci meta.ConversationInfo) error
+
+	IsTokenRevoked(id string) (bool, error)
+	RevokeToken(id, userID string, expiresAt, t time.Time) error
+
+	ApiToken(id string) (*meta.ApiTokenInfo, error)
+	ApiTokenByHash(tokenHash string) (*meta.ApiTokenInfo, error)
+	ApiTokens(userID, orgID string) ([]meta.ApiTokenInfo, error)
+	OrganizationApiTokens(orgID string) ([]meta.ApiTokenInfo, error)
+	CreateApiToken(ti meta.ApiTokenInfo) error
+	DeleteApiToken(id string) error
+	TouchApiToken(id string, t time.Time) error
+
+	Device(id string) (*meta.DeviceInfo, error)
+	Devices(userID string) ([]meta.DeviceInfo, error)
+	AddDevice(di meta.DeviceInfo) error
+	UpdateDevice(di meta.DeviceInfo) error
+	DeleteDevice(id string) error
+}
+
+// store is the meta store backing the services. It is set once the server has opened it.
+var store MetaStore
+
+// SetMetaStore sets the meta store the services read from and write to.
+func SetMetaStore(s MetaStore) { store = s }
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/tcp"
+	"github.com/messagedb/messagedb/toml"
+)
+
+// testStore is a single node meta store in a temporary directory, set as the store of the services.
+type testStore struct {
+	*meta.Store
+	ln net.Listener
+}
+
+// mustOpenStore opens a meta store and sets it as the store of the services until it is closed.
+func mustOpenStore(t *testing.T) *testStore {
+	dir, err := ioutil.TempDir("", "messagedb-services-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := meta.NewConfig()
+	c.Dir = dir
+	c.HeartbeatTimeout = toml.Duration(50 * time.Millisecond)
+	c.ElectionTimeout = toml.Duration(50 * time.Millisecond)
+	c.LeaderLeaseTimeout = toml.Duration(50 * time.Millisecond)
+	c.CommitTimeout = toml.Duration(5 * time.Millisecond)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testStore{Store: meta.NewStore(c), ln: ln}
+	s.Logger = log.New(ioutil.Discard, "", 0)
+	s.Addr = ln.Addr()
+
+	// the raft and exec connections share the listener like on a node
+	mux := tcp.NewMux()
+	s.RaftListener = mux.Listen(meta.MuxRaftHeader)
+	s.ExecListener = mux.Listen(meta.MuxExecHeader)
+	go mux.Serve(ln)
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-s.Err():
+		t.Fatal(err)
+	case <-s.Ready():
+	}
+	if err := s.WaitForLeader(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	SetMetaStore(s.Store)
+	return s
+}
+
+// Close closes the store and removes its directory.
+func (s *testStore) Close() error {
+	defer os.RemoveAll(s.Path())
+	defer s.ln.Close()
+	SetMetaStore(nil)
+	return s.Store.Close()
+}
+
+// mustRegisterUser registers a user with the password "password".
+func mustRegisterUser(t *testing.T, username, email string) *schema.User {
+	user, err := RegisterNewUser(bindings.RegisterNewUser{Username: username, EmailAddress: email, Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
@@ -1,176 +1,364 @@
 package services
 
-// import (
-// 	"errors"
-
-// 	"github.com/messagedb/messagedb/meta/bindings"
-// 	"github.com/messagedb/messagedb/meta/models"
-// 	"github.com/messagedb/messagedb/meta/schema"
-
-// 	log "github.com/Sirupsen/logrus"
-// 	"gopkg.in/mgo.v2"
-// )
-
-// var (
-// 	ErrTeamDuplicateKey               = errors.New("Team duplicate key error")
-// 	ErrTeamNameAlreadyExists          = errors.New("Team name already exists")
-// 	ErrTeamHasMembers                 = errors.New("Team has members")
-// 	ErrForbiddenToDeleteOwnersTeam    = errors.New("Forbiden to delete the organization Owners team")
-// 	ErrForbiddenNotOrganizationOwner  = errors.New("User is not part of the Organization Owners")
-// 	ErrForbiddenNotOrganizationMember = errors.New("User is not part of the Organization Members")
-// )
-
-// var Team *teamService = &teamService{}
-
-// type teamService struct{}
-
-// func (s *teamService) CreateOwnersTeam(org *schema.Organization) (*schema.Team, error) {
-// 	newTeam := bindings.CreateUpdateTeam{Name: "Owners", Description: ""}
-// 	return s.CreateTeam(org, newTeam, schema.TeamTypeOwners)
-// }
-
-// func (s *teamService) CreateAdminsTeam(org *schema.Organization) (*schema.Team, error) {
-// 	newTeam := bindings.CreateUpdateTeam{Name: "Admins", Description: ""}
-// 	return s.CreateTeam(org, newTeam, schema.TeamTypeAdmins)
-// }
-
-// func (s *teamService) CreateTeam(org *schema.Organization, newTeam bindings.CreateUpdateTeam, teamType schema.TeamType) (*schema.Team, error) {
-// 	var err error
-
-// 	var existingTeam *schema.Team
-// 	if existingTeam, err = s.getExistingTeam(org, newTeam.Name); err != nil {
-// 		return nil, err
-// 	}
-
-// 	// make sure team name doesn't already exists
-// 	if existingTeam != nil {
-// 		log.Warnf("Trying creating team with same name: %s", newTeam.Name)
-// 		return nil, ErrTeamNameAlreadyExists
-// 	}
-
-// 	team := models.Team.New()
-// 	team.Name = newTeam.Name
-// 	team.Description = newTeam.Description
-// 	team.TeamType = teamType
-// 	team.OrganizationId = org.Id
-
-// 	err = team.Save()
-// 	if err != nil {
-// 		if mgo.IsDup(err) {
-// 			log.Errorf("Duplicate key when saving team %v", team)
-// 			return nil, ErrTeamDuplicateKey
-// 		}
-// 		log.Errorf("Error saving team %v", team)
-// 		return nil, err
-// 	}
-
-// 	return team, nil
-// }
-
-// func (s *teamService) UpdateTeam(org *schema.Organization, team *schema.Team, newTeam bindings.CreateUpdateTeam) (*schema.Team, error) {
-// 	var err error
-// 	var existingTeam *schema.Team
-
-// 	if existingTeam, err = s.getExistingTeam(org, newTeam.Name); err != nil {
-// 		return nil, err
-// 	}
-
-// 	// make sure team name doesn't already exists and doesn't match the team we are trying to edit
-// 	if existingTeam != nil && existingTeam.Id != team.Id {
-// 		log.Warnf("Trying creating team with same name: %s", newTeam.Name)
-// 		return nil, ErrTeamNameAlreadyExists
-// 	}
-
-// 	// copy fields from bindings payload into the target object
-// 	team.Name = newTeam.Name
-// 	team.Description = newTeam.Description
-
-// 	err = team.Save()
-// 	if err != nil {
-// 		log.Errorf("Error saving Team %v", team)
-// 		return nil, err
-// 	}
-
-// 	return team, nil
-// }
-
-// func (s *teamService) DeleteTeam(org *schema.Organization, team *schema.Team) error {
-
-// 	// we should not be able to delete Owners Team
-// 	if org.OwnersTeamId == team.Id {
-// 		return ErrForbiddenToDeleteOwnersTeam
-// 	}
-
-// 	// we shouldn't delete a team if it has members
-// 	// so we try to retrieve all members that belongs to the team first
-// 	members, err := models.Member.FindByTeam(team)
-// 	if err != nil {
-// 		log.Errorf("Error retrieving members for Team %v", team)
-// 		return err
-// 	}
-
-// 	// if we found any members, then we bail with an error
-// 	if len(members) > 0 {
-// 		return ErrTeamHasMembers
-// 	}
-
-// 	err = models.Team.RemoveID(team.Id)
-// 	if err != nil {
-// 		log.Errorf("Error deleting Team %v", team)
-// 		return err
-// 	}
-// 	return nil
-// }
-
-// func (s *teamService) getOrganizationFromTeam(team *schema.Team) (*schema.Organization, error) {
-// 	org := &schema.Organization{}
-// 	err := models.Organization.FindId(team.OrganizationId).One(org)
-// 	if err != nil {
-// 		log.Errorf("Failed to retrieve organization (%s) from database: %v ", team.OrganizationId.Hex(), err)
-// 		return nil, err
-// 	}
-// 	return org, nil
-// }
-
-// func (s *teamService) getExistingTeam(org *schema.Organization, name string) (*schema.Team, error) {
-// 	existingTeam, err := models.Team.FindByOrganizationIdAndName(org.Id, name)
-// 	if err != nil && err != storage.ErrNotFound {
-// 		log.Errorf("Failed to find team by organization_id and name: %v", err)
-// 		return nil, err
-// 	}
-// 	return existingTeam, nil
-// }
-
-// func (s *teamService) checkOrganizationOwnership(user *schema.User, org *schema.Organization) error {
-
-// 	// check if the current user is part of the organization owners
-// 	ok, err := models.Member.IsOrganizationOwner(org, user)
-// 	if err != nil {
-// 		log.Errorf("Error checking if user (%s) is an organization owner (%s): %v ", user.Id.Hex(), org.Id.Hex(), err)
-// 		return err
-// 	}
-
-// 	if !ok {
-// 		log.Warnf("Unauthorized user (%s) for organization (%s) ", user.Id.Hex(), org.Id.Hex())
-// 		return ErrForbiddenNotOrganizationOwner
-// 	}
-
-// 	return nil
-// }
-
-// func (s *teamService) checkOrganizationMembership(user *schema.User, org *schema.Organization) error {
-
-// 	// check if the current user is part of the organization owners
-// 	ok, err := models.Member.IsOrganizationMember(org, user)
-// 	if err != nil {
-// 		log.Errorf("Error checking if user (%s) is an organization member (%s): %v ", user.Id.Hex(), org.Id.Hex(), err)
-// 		return err
-// 	}
-
-// 	if !ok {
-// 		log.Warnf("Unauthorized user (%s) for organization (%s) ", user.Id.Hex(), org.Id.Hex())
-// 		return ErrForbiddenNotOrganizationMember
-// 	}
-
-// 	return nil
-// }
+import (
+	"errors"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	// ErrTeamNotFound is raised when the team a service is created for does not exist
+	ErrTeamNotFound = errors.New("Team not found")
+
+	// ErrTeamNameAlreadyExists is raised when a team is given the name of another team of the organization
+	ErrTeamNameAlreadyExists = errors.New("Team name already exists")
+
+	// ErrTeamHasMembers is raised when deleting a team that still has members
+	ErrTeamHasMembers = errors.New("Team has members")
+
+	// ErrForbiddenToDeleteOwnersTeam is raised when deleting the Owners or the Admins team of an organization
+	ErrForbiddenToDeleteOwnersTeam = errors.New("Forbidden to delete the organization Owners team")
+
+	// ErrTeamMembershipNotFound is raised when the user is not a member of the team
+	ErrTeamMembershipNotFound = errors.New("Team membership not found")
+
+	// ErrTeamConversationNotFound is raised when the conversation is not granted to the team
+	ErrTeamConversationNotFound = errors.New("Team conversation not found")
+
+	// ErrNotATeamMember is raised when a user that is not a team member tries to manage the conversations of the team
+	ErrNotATeamMember = errors.New("Authenticated user is not a team member")
+)
+
+// TeamService is a service that allows operations on a team. It wraps a team, its organization and the current user
+// making API requests, and enforces access-controls based on the current user
+type TeamService struct {
+	Team *schema.Team
+
+	*OrganizationMembershipService
+}
+
+// NewTeamService creates a service that wraps a team and the current user making API requests
+func NewTeamService(teamParam interface{}, currentUser *schema.User) (*TeamService, error) {
+	team, ok := teamParam.(*schema.Team)
+	if !ok {
+		t, err := FindTeam(idOf(teamParam))
+		if err != nil {
+			return nil, err
+		} else if t == nil {
+			return nil, ErrTeamNotFound
+		}
+		team = t
+	}
+
+	org, err := FindOrganization(team.OrganizationID.Hex())
+	if err != nil {
+		return nil, err
+	} else if org == nil {
+		return nil, ErrOrganizationNotFound
+	}
+
+	service := &TeamService{
+		Team:                          team,
+		OrganizationMembershipService: &OrganizationMembershipService{Org: org, CurrentUser: currentUser},
+	}
+	return service, nil
+}
+
+// FindTeam returns the team with the given ID. Returns nil if the team does not exist.
+func FindTeam(id string) (*schema.Team, error) {
+	ti, err := store.Team(id)
+	if err != nil || ti == nil {
+		return nil, err
+	}
+	return teamFromInfo(ti), nil
+}
+
+// createOwnersTeam creates the Owners team of a new organization with its owner as first member.
+func createOwnersTeam(orgID string, owner *schema.User, t time.Time) error {
+	return store.CreateTeam(meta.TeamInfo{
+		ID:             bson.NewObjectId().Hex(),
+		OrganizationID: orgID,
+		Name:           "Owners",
+		Type:           meta.TeamTypeOwners,
+		Members:        []string{owner.ID.Hex()},
+		CreatedAt:      t,
+		UpdatedAt:      t,
+	})
+}
+
+// ListTeams returns the teams of the organization. The authenticated user must be an organization member.
+func (s *OrganizationService) ListTeams() ([]*schema.Team, error) {
+	if err := s.checkMembership(); err != nil {
+		return nil, err
+	}
+
+	infos, err := store.Teams(s.Org.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	teams := []*schema.Team{}
+	for i := range infos {
+		teams = append(teams, teamFromInfo(&infos[i]))
+	}
+	return teams, nil
+}
+
+// CreateTeam creates a new team in the organization. The authenticated user must be an organization owner.
+func (s *OrganizationService) CreateTeam(newTeam bindings.CreateUpdateTeam) (*schema.Team, error) {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ti := meta.TeamInfo{
+		ID:             bson.NewObjectId().Hex(),
+		OrganizationID: s.Org.ID.Hex(),
+		Name:           newTeam.Name,
+		Description:    newTeam.Description,
+		Type:           meta.TeamTypeTeams,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := store.CreateTeam(ti); err != nil {
+		if err == meta.ErrTeamExists {
+			return nil, ErrTeamNameAlreadyExists
+		}
+		return nil, err
+	}
+
+	return FindTeam(ti.ID)
+}
+
+// GetTeam returns the team. The authenticated user must be an organization member.
+func (s *TeamService) GetTeam() (*schema.Team, error) {
+	if err := s.checkMembership(); err != nil {
+		return nil, err
+	}
+	return s.Team, nil
+}
+
+// UpdateTeam changes the name and the description of the team. The authenticated user must be an organization owner.
+func (s *TeamService) UpdateTeam(newTeam bindings.CreateUpdateTeam) (*schema.Team, error) {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	ti := meta.TeamInfo{
+		ID:          s.Team.ID.Hex(),
+		Name:        newTeam.Name,
+		Description: newTeam.Description,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if err := store.UpdateTeam(ti); err != nil {
+		return nil, teamError(err)
+	}
+
+	return s.reload()
+}
+
+// DeleteTeam deletes the team. The Owners team cannot be deleted, nor a team that still has members. The authenticated
+// user must be an organization owner.
+func (s *TeamService) DeleteTeam() error {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return err
+	}
+
+	// we should not be able to delete the Owners team
+	if !s.Team.CanBeDeleted() {
+		return ErrForbiddenToDeleteOwnersTeam
+	}
+
+	if err := store.DropTeam(s.Team.ID.Hex()); err != nil {
+		return teamError(err)
+	}
+	return nil
+}
+
+// ListMembers returns the organization memberships of the members of the team. The authenticated user must be an
+// organization member.
+func (s *TeamService) ListMembers() ([]*schema.Member, error) {
+	if err := s.checkMembership(); err != nil {
+		return nil, err
+	}
+
+	members := []*schema.Member{}
+	for _, id := range s.Team.MemberIDs {
+		mi, err := store.Member(s.Org.ID.Hex(), id.Hex())
+		if err != nil {
+			return nil, err
+		} else if mi == nil {
+			continue
+		}
+		members = append(members, memberFromInfo(mi))
+	}
+	return members, nil
+}
+
+// GetTeamMembership returns the organization membership of a member of the team. The authenticated user must be an
+// organization owner.
+func (s *TeamService) GetTeamMembership(user *schema.User) (*schema.Member, error) {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	if !s.Team.HasMember(user.ID) {
+		return nil, ErrTeamMembershipNotFound
+	}
+	return s.findMembership(user)
+}
+
+// AddTeamMembership adds a member of the organization to the team. The user participates in all the conversations
+// granted to the team. The authenticated user must be an organization owner.
+func (s *TeamService) AddTeamMembership(user *schema.User) (*schema.Member, error) {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return nil, err
+	}
+
+	if err := store.AddTeamMember(s.Team.ID.Hex(), user.ID.Hex(), time.Now().UTC()); err != nil {
+		return nil, teamError(err)
+	}
+	if _, err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	return s.findMembership(user)
+}
+
+// RemoveTeamMembership removes a user from the team, along with the conversations the team granted to the user. The
+// authenticated user must be an organization owner.
+func (s *TeamService) RemoveTeamMembership(user *schema.User) error {
+
+	// if authenticated user is not an organization owner raise an error
+	if err := s.checkOwnership(); err != nil {
+		return err
+	}
+
+	if err := store.RemoveTeamMember(s.Team.ID.Hex(), user.ID.Hex()); err != nil {
+		return teamError(err)
+	}
+	_, err := s.reload()
+	return err
+}
+
+// ListConversations returns the conversations granted to the team. The authenticated user must be an organization member.
+func (s *TeamService) ListConversations() ([]*schema.Conversation, error) {
+	if err := s.checkMembership(); err != nil {
+		return nil, err
+	}
+
+	conversations := []*schema.Conversation{}
+	for _, id := range s.Team.Conversations {
+		conversation, err := FindConversation(id.Hex())
+		if err != nil {
+			return nil, err
+		} else if conversation == nil {
+			continue
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// CheckConversation verifies if the conversation is granted to the team. The authenticated user must be an
+// organization member.
+func (s *TeamService) CheckConversation(conversation *schema.Conversation) (bool, error) {
+	if err := s.checkMembership(); err != nil {
+		return false, err
+	}
+	return s.Team.HasConversation(conversation.ID), nil
+}
+
+// AddConversation grants a conversation of the organization to the team, so that all members of the team participate
+// in it. The authenticated user must be a member of the team or an organization owner.
+func (s *TeamService) AddConversation(conversation *schema.Conversation) error {
+	if err := s.checkTeamMembership(); err != nil {
+		return err
+	}
+
+	if err := store.GrantTeamConversation(s.Team.ID.Hex(), conversation.ID.Hex(), time.Now().UTC()); err != nil {
+		return teamError(err)
+	}
+	_, err := s.reload()
+	return err
+}
+
+// RemoveConversation revokes a conversation from the team. The members of the team stop participating in it, unless
+// they joined it on their own or another team grants it. The authenticated user must be a member of the team or an
+// organization owner.
+func (s *TeamService) RemoveConversation(conversation *schema.Conversation) error {
+	if err := s.checkTeamMembership(); err != nil {
+		return err
+	}
+
+	if err := store.RevokeTeamConversation(s.Team.ID.Hex(), conversation.ID.Hex()); err != nil {
+		return teamError(err)
+	}
+	_, err := s.reload()
+	return err
+}
+
+// checkTeamMembership returns an error if the authenticated user is neither an organization owner nor an active
+// organization member belonging to the team.
+func (s *TeamService) checkTeamMembership() error {
+	if err := s.checkOwnership(); err != ErrNotAnOrganizationOwner {
+		return err
+	}
+	if err := s.checkMembership(); err != nil {
+		return err
+	}
+	if !s.Team.HasMember(s.CurrentUser.ID) {
+		return ErrNotATeamMember
+	}
+	return nil
+}
+
+// reload refreshes the team from the meta store.
+func (s *TeamService) reload() (*schema.Team, error) {
+	team, err := FindTeam(s.Team.ID.Hex())
+	if err != nil {
+		return nil, err
+	} else if team == nil {
+		return nil, ErrTeamNotFound
+	}
+	s.Team = team
+	return team, nil
+}
+
+// teamError maps the errors of the meta store raised while managing teams.
+func teamError(err error) error {
+	switch err {
+	case meta.ErrTeamNotFound:
+		return ErrTeamNotFound
+	case meta.ErrTeamExists:
+		return ErrTeamNameAlreadyExists
+	case meta.ErrTeamHasMembers:
+		return ErrTeamHasMembers
+	case meta.ErrTeamNotDeletable:
+		return ErrForbiddenToDeleteOwnersTeam
+	case meta.ErrTeamMemberNotFound:
+		return ErrTeamMembershipNotFound
+	case meta.ErrTeamConversationNotFound:
+		return ErrTeamConversationNotFound
+	case meta.ErrMemberNotFound:
+		return ErrMembershipNotFound
+	case meta.ErrConversationNotFound:
+		return ErrConversationNotFound
+	}
+	return err
+}
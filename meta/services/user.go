@@ -1,6 +1,16 @@
 package services
 
-import "github.com/messagedb/messagedb/meta/schema"
+import (
+	"errors"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/schema"
+)
+
+var (
+	// ErrUserNotFound is raised when the user a service is created for does not exist
+	ErrUserNotFound = errors.New("User not found")
+)
 
 // UserService is a service that allows actions on specific user
 type UserService struct {
@@ -9,65 +19,102 @@ type UserService struct {
 
 // NewUserService creates a new service for authenticated user
 func NewUserService(userParam interface{}) (*UserService, error) {
+	user, ok := userParam.(*schema.User)
+	if !ok {
+		id, _ := userParam.(string)
+		u, err := FindUser(id)
+		if err != nil {
+			return nil, err
+		} else if u == nil {
+			return nil, ErrUserNotFound
+		}
+		user = u
+	}
+
+	return &UserService{User: user}, nil
+}
 
-	// var user *schema.User
-	// var ok bool
-	// var err error
-	//
-	// if user, ok = userParam.(*schema.User); !ok {
-	// 	userid := utils.ObjectId(userParam)
-	// 	user, err = models.User.FindById(userid)
-	// 	if err != nil {
-	// 		log.Errorf("Unable to find user when creating UserService: %v", userid)
-	// 		return nil, err
-	// 	}
-	// }
-	//
-	// service := &UserService{User: user}
-	//
-	// return service, nil
-	return nil, nil
+// FindUser returns the user with the given ID. Returns nil if the user does not exist.
+func FindUser(id string) (*schema.User, error) {
+	ai, err := store.Account(id)
+	if err != nil || ai == nil {
+		return nil, err
+	}
+	return userFromAccount(ai)
+}
+
+// FindUserByUsername returns the user with the given username. Returns nil if the user does not exist.
+func FindUserByUsername(username string) (*schema.User, error) {
+	ai, err := store.AccountByUsername(username)
+	if err != nil || ai == nil {
+		return nil, err
+	}
+	return userFromAccount(ai)
+}
+
+// FindUserByEmail returns the user the email address is registered to. Returns nil if the user does not exist.
+func FindUserByEmail(email string) (*schema.User, error) {
+	ai, err := store.AccountByEmail(email)
+	if err != nil || ai == nil {
+		return nil, err
+	}
+	return userFromAccount(ai)
 }
 
 // ListMemberships returns all public memberships for the user
 func (s *UserService) ListMemberships() ([]*schema.Member, error) {
+	memberships, err := store.Memberships(s.User.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
 	members := []*schema.Member{}
-	//TODO: fix this
-	// err := models.Member.Find(bson.M{"user_id": s.User.ID, "status": "active", "published": true}).All(&members)
-	// if err != nil {
-	// 	return nil, err
-	// }
+	for i := range memberships {
+		if memberships[i].State == meta.MemberStateActive && memberships[i].Published {
+			members = append(members, memberFromInfo(&memberships[i]))
+		}
+	}
 	return members, nil
 }
 
 // ListOrganizations returns all the active publicized organizations for the user
 func (s *UserService) ListOrganizations() ([]*schema.Organization, error) {
-	// members, err := s.ListMemberships()
-	// if err != nil {
-	// 	return nil, err
-	// }
-	//
-	// orgIds := []bson.ObjectId{}
-	// for _, member := range members {
-	// 	orgIds = append(orgIds, member.OrganizationID)
-	// }
-	//
-	// orgs, err := models.Organization.FindAllIds(orgIds)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	//
-	// return orgs, nil
-	return nil, nil
-
+	members, err := s.ListMemberships()
+	if err != nil {
+		return nil, err
+	}
+	return organizationsOf(members)
 }
 
 // ListAllUsers returns a list of all users
 func ListAllUsers() ([]*schema.User, error) {
+	accounts, err := store.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
 	users := []*schema.User{}
-	// err := models.User.Find(nil).All(&users)
-	// if err != nil {
-	// 	return nil, err
-	// }
+	for i := range accounts {
+		user, err := userFromAccount(&accounts[i])
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
 	return users, nil
 }
+
+// organizationsOf returns the organizations of a list of memberships.
+func organizationsOf(members []*schema.Member) ([]*schema.Organization, error) {
+	orgs := []*schema.Organization{}
+	for _, m := range members {
+		org, err := FindOrganization(m.OrganizationID.Hex())
+		if err != nil {
+			return nil, err
+		} else if org == nil {
+			continue
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
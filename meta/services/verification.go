@@ -0,0 +1,203 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/notify"
+)
+
+const (
+	// DefaultEmailVerificationExpiry is the default time an email verification link is valid after it is sent.
+	DefaultEmailVerificationExpiry = 24 * time.Hour
+
+	// DefaultPasswordResetExpiry is the default time a password reset link is valid after it is sent.
+	DefaultPasswordResetExpiry = time.Hour
+)
+
+// Errors
+var (
+	ErrInvalidVerificationToken  = errors.New("Invalid email verification token")
+	ErrInvalidPasswordResetToken = errors.New("Invalid password reset token")
+
+	// ErrEmailNotFound is raised when verifying an email address the user did not register
+	ErrEmailNotFound = errors.New("Email address not found")
+
+	// ErrEmailAlreadyConfirmed is raised when verifying an email address the user already confirmed
+	ErrEmailAlreadyConfirmed = errors.New("Email address already confirmed")
+
+	// ErrNotificationNotDelivered is raised when the notifier failed to deliver a verification or a reset link
+	ErrNotificationNotDelivered = errors.New("Notification could not be delivered")
+)
+
+var (
+	// EmailVerificationExpiry is the time an email verification link is valid after it is sent.
+	EmailVerificationExpiry = DefaultEmailVerificationExpiry
+
+	// PasswordResetExpiry is the time a password reset link is valid after it is sent.
+	PasswordResetExpiry = DefaultPasswordResetExpiry
+
+	// EmailVerificationURL is the base of the link sent to verify an email address, the token is appended to it.
+	// Only the token is sent when it is empty.
+	EmailVerificationURL string
+
+	// PasswordResetURL is the base of the link sent to reset a password, the token is appended to it. Only the token
+	// is sent when it is empty.
+	PasswordResetURL string
+)
+
+// SendEmailVerification sends a link confirming an email address of the user to that address. The link carries a
+// signed token valid for EmailVerificationExpiry.
+func (a *authService) SendEmailVerification(user *schema.User, email string) error {
+	email = strings.ToLower(email)
+	if !user.HasEmailAddress(email) {
+		return ErrEmailNotFound
+	} else if user.HasConfirmedEmail(email) {
+		return ErrEmailAlreadyConfirmed
+	}
+
+	now := time.Now().UTC()
+	token, err := a.sign(tokenTypeEmailVerification, now, now.Add(EmailVerificationExpiry), map[string]interface{}{
+		"uid":   user.ID.Hex(),
+		"email": email,
+	})
+	if err != nil {
+		return err
+	}
+
+	return deliver(&notify.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nConfirm that %s is your email address: %s\n\nThe link expires on %s.\n",
+			user.Username, email, linkTo(EmailVerificationURL, token), now.Add(EmailVerificationExpiry).Format(time.RFC1123)),
+	})
+}
+
+// ConfirmEmail confirms the email address an email verification token was sent to, and returns the user it belongs
+// to. The token is revoked so that it is used once.
+func (a *authService) ConfirmEmail(verificationToken string) (*schema.User, error) {
+	token, err := a.parseToken(verificationToken, tokenTypeEmailVerification)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	user, err := a.tokenUser(token)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	// the address may have been removed since the token was sent
+	email, _ := token.Claims["email"].(string)
+	if !user.HasEmailAddress(email) {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if err := revokeToken(token); err == meta.ErrTokenRevoked {
+		return nil, ErrInvalidVerificationToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	if user.HasConfirmedEmail(email) {
+		return user, nil
+	}
+
+	u := *user
+	u.Emails = append([]schema.EmailAddress{}, user.Emails...)
+	u.ConfirmEmailAddress(email, time.Now().UTC())
+	if err := store.UpdateAccount(accountFromUser(&u)); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// SendPasswordReset sends a link resetting the password of the user a confirmed email address belongs to. Nothing is
+// sent when the address is unknown or unconfirmed, without telling the caller so that it cannot find out the
+// addresses of users. The link carries a signed token valid for PasswordResetExpiry.
+func (a *authService) SendPasswordReset(email string) error {
+	user, err := FindUserByEmail(email)
+	if err != nil {
+		return err
+	} else if user == nil || !user.HasConfirmedEmail(email) {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	token, err := a.sign(tokenTypePasswordReset, now, now.Add(PasswordResetExpiry), map[string]interface{}{
+		"uid": user.ID.Hex(),
+		"pwh": passwordFingerprint(user),
+	})
+	if err != nil {
+		return err
+	}
+
+	return deliver(&notify.Message{
+		To:      strings.ToLower(email),
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nA password reset was requested for your account. Choose a new password: %s\n\nThe link expires on %s. Ignore this email if you did not request it.\n",
+			user.Username, linkTo(PasswordResetURL, token), now.Add(PasswordResetExpiry).Format(time.RFC1123)),
+	})
+}
+
+// ResetPassword sets the password of the user a password reset token was sent to. The token is revoked so that it is
+// used once, and it is no longer valid once the password changed.
+func (a *authService) ResetPassword(resetToken, password string) (*schema.User, error) {
+	token, err := a.parseToken(resetToken, tokenTypePasswordReset)
+	if err != nil {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	user, err := a.tokenUser(token)
+	if err != nil {
+		return nil, ErrInvalidPasswordResetToken
+	} else if pwh, _ := token.Claims["pwh"].(string); pwh != passwordFingerprint(user) {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	if err := revokeToken(token); err == meta.ErrTokenRevoked {
+		return nil, ErrInvalidPasswordResetToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	u := *user
+	if err := u.SetPassword(password); err != nil {
+		return nil, err
+	}
+	if err := store.UpdateAccount(accountFromUser(&u)); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// passwordFingerprint returns a digest of the password hash of the user, carried by the password reset tokens so that
+// they are no longer valid once the password changed.
+func passwordFingerprint(user *schema.User) string {
+	h := sha256.Sum256([]byte(user.HashedPassword))
+	return hex.EncodeToString(h[:8])
+}
+
+// linkTo returns the link carrying a token, made of the token appended to base when it is not empty.
+func linkTo(base, token string) string {
+	if base == "" {
+		return token
+	}
+	return strings.TrimRight(base, "/") + "/" + token
+}
+
+// deliver sends a message through the notifier. Messages are dropped when no notifier is set.
+func deliver(m *notify.Message) error {
+	if notifier == nil {
+		return nil
+	}
+	if err := notifier.Notify(m); err != nil {
+		return ErrNotificationNotDelivered
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/notify"
+)
+
+// mailbox records the messages delivered by the services.
+type mailbox struct {
+	mu       sync.Mutex
+	messages []*notify.Message
+}
+
+func (m *mailbox) Notify(msg *notify.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// token returns the token of the link of the last message sent to an address.
+func (m *mailbox) token(t *testing.T, to string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To != to {
+			continue
+		}
+		body := m.messages[i].Body
+		j := strings.Index(body, testLinkURL)
+		if j == -1 {
+			break
+		}
+		return strings.Fields(body[j+len(testLinkURL):])[0]
+	}
+	t.Fatalf("no link sent to %s", to)
+	return ""
+}
+
+// testLinkURL is the base of the links sent by the tests.
+const testLinkURL = "https://example.com/links/"
+
+// mustOpenMailbox sets a mailbox as the notifier and the base of the links, until the returned function is called.
+func mustOpenMailbox() (*mailbox, func()) {
+	m := &mailbox{}
+	verificationURL, resetURL := EmailVerificationURL, PasswordResetURL
+	EmailVerificationURL, PasswordResetURL = testLinkURL, testLinkURL
+	SetNotifier(m)
+	return m, func() {
+		EmailVerificationURL, PasswordResetURL = verificationURL, resetURL
+		SetNotifier(nil)
+	}
+}
+
+// Ensure an email verification token confirms the address once, and not once the address is removed.
+func TestAuth_ConfirmEmail(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+	mail, closeMailbox := mustOpenMailbox()
+	defer closeMailbox()
+
+	a := newAuthService()
+	user := mustRegisterUser(t, "susy", "susy@example.com")
+	if err := a.SendEmailVerification(user, "susy@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	token := mail.token(t, "susy@example.com")
+
+	if u, err := a.ConfirmEmail(token); err != nil {
+		t.Fatal(err)
+	} else if !u.HasConfirmedEmail("susy@example.com") {
+		t.Fatal("expected address to be confirmed")
+	}
+	if u, err := FindUser(user.ID.Hex()); err != nil {
+		t.Fatal(err)
+	} else if !u.HasConfirmedEmail("susy@example.com") {
+		t.Fatal("expected confirmation to be saved")
+	}
+
+	if _, err := a.ConfirmEmail(token); err != ErrInvalidVerificationToken {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := a.SendEmailVerification(user, "other@example.com"); err != ErrEmailNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the token of an address is not valid once the address is removed
+	account := &AccountService{User: user}
+	if err := account.AddEmailAddress(bindings.UpdateEmail{Email: "susy@work.example.com"}); err != nil {
+		t.Fatal(err)
+	} else if err := a.SendEmailVerification(account.User, "susy@work.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	token = mail.token(t, "susy@work.example.com")
+	if err := account.RemoveEmailAddress(bindings.UpdateEmail{Email: "susy@work.example.com"}); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ConfirmEmail(token); err != ErrInvalidVerificationToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// other types of tokens do not confirm addresses
+	tokens, err := a.GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ConfirmEmail(tokens.AccessToken); err != ErrInvalidVerificationToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a password reset token sets the password once, and not once the password changed.
+func TestAuth_ResetPassword(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+	mail, closeMailbox := mustOpenMailbox()
+	defer closeMailbox()
+
+	a := newAuthService()
+	user := mustRegisterUser(t, "susy", "susy@example.com")
+
+	// nothing is sent to unconfirmed addresses
+	if err := a.SendPasswordReset("susy@example.com"); err != nil {
+		t.Fatal(err)
+	} else if len(mail.messages) != 0 {
+		t.Fatalf("unexpected message: %#v", mail.messages[0])
+	}
+
+	if err := a.SendEmailVerification(user, "susy@example.com"); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ConfirmEmail(mail.token(t, "susy@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.SendPasswordReset("susy@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	first := mail.token(t, "susy@example.com")
+	if err := a.SendPasswordReset("susy@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	second := mail.token(t, "susy@example.com")
+
+	if _, err := a.ResetPassword(first, "secret1"); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.AuthorizeUser(bindings.AuthorizeUser{Login: "susy", Password: "secret1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ResetPassword(first, "secret2"); err != ErrInvalidPasswordResetToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the second token was issued for the previous password
+	if _, err := a.ResetPassword(second, "secret2"); err != ErrInvalidPasswordResetToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// so are the tokens issued before the user changed the password
+	if err := a.SendPasswordReset("susy@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	third := mail.token(t, "susy@example.com")
+	current, err := FindUser(user.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := &AccountService{User: current}
+	if ok, err := account.ChangePassword(bindings.ChangePassword{OldPassword: "secret1", NewPassword: "secret3"}); err != nil || !ok {
+		t.Fatalf("unexpected password change: %v %v", ok, err)
+	} else if _, err := a.ResetPassword(third, "secret4"); err != ErrInvalidPasswordResetToken {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure users only log in with the email addresses they confirmed.
+func TestAuth_AuthorizeUser_UnconfirmedEmail(t *testing.T) {
+	s := mustOpenStore(t)
+	defer s.Close()
+	mail, closeMailbox := mustOpenMailbox()
+	defer closeMailbox()
+
+	a := newAuthService()
+	user := mustRegisterUser(t, "susy", "susy@example.com")
+
+	if _, err := a.AuthorizeUser(bindings.AuthorizeUser{Login: "susy@example.com", Password: "password"}); err != ErrAuthenticationFailedEmailUnconfirmed {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := a.AuthorizeUser(bindings.AuthorizeUser{Login: "susy", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.SendEmailVerification(user, "susy@example.com"); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.ConfirmEmail(mail.token(t, "susy@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AuthorizeUser(bindings.AuthorizeUser{Login: "SUSY@example.com", Password: "password"}); err != nil {
+		t.Fatal(err)
+	} else if _, err := a.AuthorizeUser(bindings.AuthorizeUser{Login: "susy@example.com", Password: "wrong"}); err != ErrAuthenticationFailedPasswordMismatch {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
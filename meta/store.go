@@ -1178,524 +1178,2012 @@ func (s *Store) UserCount() (count int, err error) {
 	return
 }
 
-// SetData force overwrites the root data.
-// This should only be used when restoring a snapshot.
-func (s *Store) SetData(data *Data) error {
-	return s.exec(internal.Command_SetDataCommand, internal.E_SetDataCommand_Command,
-		&internal.SetDataCommand{
-			Data: data.marshal(),
+// ReadMarker returns the read marker of a user in a conversation. Returns nil if
+// the user has not read the conversation yet.
+func (s *Store) ReadMarker(conversationID, userID string) (rmi *ReadMarkerInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if m := data.ReadMarker(conversationID, userID); m != nil {
+			other := m.clone()
+			rmi = &other
+		}
+		return nil
+	})
+	return
+}
+
+// ReadMarkers returns the read markers of a user in all the conversations.
+func (s *Store) ReadMarkers(userID string) (a []ReadMarkerInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.ReadMarkers {
+			if data.ReadMarkers[i].UserID == userID {
+				a = append(a, data.ReadMarkers[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// SetReadMarker moves the read marker of a user in a conversation to a message.
+func (s *Store) SetReadMarker(conversationID, userID, messageID string, t time.Time) error {
+	return s.exec(internal.Command_SetReadMarkerCommand, internal.E_SetReadMarkerCommand_Command,
+		&internal.SetReadMarkerCommand{
+			ConversationID: proto.String(conversationID),
+			UserID:         proto.String(userID),
+			MessageID:      proto.String(messageID),
+			Time:           proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// Attachment returns an attachment by ID. Returns nil if the attachment doesn't exist.
+func (s *Store) Attachment(id string) (ai *AttachmentInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if a := data.Attachment(id); a != nil {
+			other := a.clone()
+			ai = &other
+		}
+		return nil
+	})
+	return
+}
+
+// Attachments returns the attachments of a conversation. All the attachments are returned when
+// conversationID is empty.
+func (s *Store) Attachments(conversationID string) (a []AttachmentInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Attachments {
+			if conversationID == "" || data.Attachments[i].ConversationID == conversationID {
+				a = append(a, data.Attachments[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// CreateAttachment adds the metadata of an uploaded attachment.
+func (s *Store) CreateAttachment(ai AttachmentInfo) error {
+	return s.exec(internal.Command_CreateAttachmentCommand, internal.E_CreateAttachmentCommand_Command,
+		&internal.CreateAttachmentCommand{
+			Attachment: ai.marshal(),
+		},
+	)
+}
+
+// SetAttachmentMessage links an attachment to the message it was sent with.
+func (s *Store) SetAttachmentMessage(id, messageID string) error {
+	return s.exec(internal.Command_SetAttachmentMessageCommand, internal.E_SetAttachmentMessageCommand_Command,
+		&internal.SetAttachmentMessageCommand{
+			ID:        proto.String(id),
+			MessageID: proto.String(messageID),
+		},
+	)
+}
+
+// DeleteAttachment removes the metadata of an attachment.
+func (s *Store) DeleteAttachment(id string) error {
+	return s.exec(internal.Command_DeleteAttachmentCommand, internal.E_DeleteAttachmentCommand_Command,
+		&internal.DeleteAttachmentCommand{
+			ID: proto.String(id),
+		},
+	)
+}
+
+// Participant returns the participant of a conversation with the given user ID. Returns nil if
+// the user doesn't participate in the conversation.
+func (s *Store) Participant(conversationID, userID string) (pi *ParticipantInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if p := data.Participant(conversationID, userID); p != nil {
+			other := p.clone()
+			pi = &other
+		}
+		return nil
+	})
+	return
+}
+
+// Participants returns the participants of a conversation, in the order they joined.
+func (s *Store) Participants(conversationID string) (a []ParticipantInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Participants {
+			if data.Participants[i].ConversationID == conversationID {
+				a = append(a, data.Participants[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// IsParticipant returns true if a user participates in a conversation.
+func (s *Store) IsParticipant(conversationID, userID string) (ok bool, err error) {
+	err = s.read(func(data *Data) error {
+		ok = data.Participant(conversationID, userID) != nil
+		return nil
+	})
+	return
+}
+
+// Conversations returns the IDs of the conversations a user participates in.
+func (s *Store) Conversations(userID string) (a []string, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Participants {
+			if data.Participants[i].UserID == userID {
+				a = append(a, data.Participants[i].ConversationID)
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// AddParticipant adds a user to the participants of a conversation, joining at t.
+func (s *Store) AddParticipant(conversationID, userID string, t time.Time) error {
+	return s.exec(internal.Command_AddParticipantCommand, internal.E_AddParticipantCommand_Command,
+		&internal.AddParticipantCommand{
+			ConversationID: proto.String(conversationID),
+			UserID:         proto.String(userID),
+			Time:           proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// RemoveParticipant removes a user from the participants of a conversation.
+func (s *Store) RemoveParticipant(conversationID, userID string) error {
+	return s.exec(internal.Command_RemoveParticipantCommand, internal.E_RemoveParticipantCommand_Command,
+		&internal.RemoveParticipantCommand{
+			ConversationID: proto.String(conversationID),
+			UserID:         proto.String(userID),
+		},
+	)
+}
+
+// SetParticipantActivity records the last activity of a participant of a conversation.
+func (s *Store) SetParticipantActivity(conversationID, userID string, t time.Time) error {
+	return s.exec(internal.Command_SetParticipantActivityCommand, internal.E_SetParticipantActivityCommand_Command,
+		&internal.SetParticipantActivityCommand{
+			ConversationID: proto.String(conversationID),
+			UserID:         proto.String(userID),
+			Time:           proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// Namespace returns a namespace by path. Returns nil if the path is not taken.
+func (s *Store) Namespace(path string) (ni *NamespaceInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if n := data.Namespace(path); n != nil {
+			other := n.clone()
+			ni = &other
+		}
+		return nil
+	})
+	return
+}
+
+// NamespaceByID returns a namespace by ID. Returns nil if the namespace doesn't exist.
+func (s *Store) NamespaceByID(id string) (ni *NamespaceInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if n := data.NamespaceByID(id); n != nil {
+			other := n.clone()
+			ni = &other
+		}
+		return nil
+	})
+	return
+}
+
+// ResolveNamespace returns a namespace by path, following the redirects left by renames. Returns
+// true if path is a previous path of the namespace. Returns nil if the path is not taken.
+func (s *Store) ResolveNamespace(path string) (ni *NamespaceInfo, redirected bool, err error) {
+	err = s.read(func(data *Data) error {
+		if n, ok := data.ResolveNamespace(path); n != nil {
+			other := n.clone()
+			ni, redirected = &other, ok
+		}
+		return nil
+	})
+	return
+}
+
+// RenameNamespace changes the path of a namespace, along with the username of the user account
+// owning it. The previous path redirects to the namespace.
+func (s *Store) RenameNamespace(id, path string, t time.Time) error {
+	return s.exec(internal.Command_RenameNamespaceCommand, internal.E_RenameNamespaceCommand_Command,
+		&internal.RenameNamespaceCommand{
+			ID:   proto.String(id),
+			Path: proto.String(path),
+			Time: proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// Account returns a user account by ID. Returns nil if the account doesn't exist.
+func (s *Store) Account(id string) (ai *AccountInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if a := data.Account(id); a != nil {
+			other := a.clone()
+			ai = &other
+		}
+		return nil
+	})
+	return
+}
+
+// AccountByUsername returns a user account by username. Returns nil if the account doesn't exist.
+func (s *Store) AccountByUsername(username string) (ai *AccountInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if a := data.AccountByUsername(username); a != nil {
+			other := a.clone()
+			ai = &other
+		}
+		return nil
+	})
+	return
+}
+
+// AccountByEmail returns the user account an email address is registered to. Returns nil if the
+// email address is not registered.
+func (s *Store) AccountByEmail(email string) (ai *AccountInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if a := data.AccountByEmail(email); a != nil {
+			other := a.clone()
+			ai = &other
+		}
+		return nil
+	})
+	return
+}
+
+// AccountByIdentity returns the account an identity of an external provider is linked to.
+// Returns nil if the identity is not linked to any account.
+func (s *Store) AccountByIdentity(issuer, subject string) (ai *AccountInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if a := data.AccountByIdentity(issuer, subject); a != nil {
+			other := a.clone()
+			ai = &other
+		}
+		return nil
+	})
+	return
+}
+
+// Accounts returns all the user accounts.
+func (s *Store) Accounts() (a []AccountInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Accounts {
+			a = append(a, data.Accounts[i].clone())
+		}
+		return nil
+	})
+	return
+}
+
+// CreateAccount adds a user account along with the namespace of its username.
+func (s *Store) CreateAccount(ai AccountInfo) error {
+	return s.exec(internal.Command_CreateAccountCommand, internal.E_CreateAccountCommand_Command,
+		&internal.CreateAccountCommand{
+			Account: ai.marshal(),
+		},
+	)
+}
+
+// UpdateAccount replaces the names, the password hash and the email addresses of a user account.
+func (s *Store) UpdateAccount(ai AccountInfo) error {
+	return s.exec(internal.Command_UpdateAccountCommand, internal.E_UpdateAccountCommand_Command,
+		&internal.UpdateAccountCommand{
+			Account: ai.marshal(),
+		},
+	)
+}
+
+// LinkIdentity links an identity of an external provider to a user account.
+func (s *Store) LinkIdentity(userID string, ii IdentityInfo) error {
+	return s.exec(internal.Command_LinkIdentityCommand, internal.E_LinkIdentityCommand_Command,
+		&internal.LinkIdentityCommand{
+			UserID:   proto.String(userID),
+			Identity: ii.marshal(),
+		},
+	)
+}
+
+// Organization returns an organization by ID. Returns nil if the organization doesn't exist.
+func (s *Store) Organization(id string) (oi *OrganizationInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if o := data.Organization(id); o != nil {
+			other := o.clone()
+			oi = &other
+		}
+		return nil
+	})
+	return
+}
+
+// Organizations returns all the organizations.
+func (s *Store) Organizations() (a []OrganizationInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Organizations {
+			a = append(a, data.Organizations[i].clone())
+		}
+		return nil
+	})
+	return
+}
+
+// CreateOrganization adds an organization along with its namespace at path, owned by a user.
+func (s *Store) CreateOrganization(oi OrganizationInfo, path, ownerID string) error {
+	return s.exec(internal.Command_CreateOrganizationCommand, internal.E_CreateOrganizationCommand_Command,
+		&internal.CreateOrganizationCommand{
+			Organization: oi.marshal(),
+			Path:         proto.String(path),
+			OwnerID:      proto.String(ownerID),
+		},
+	)
+}
+
+// UpdateOrganization replaces the profile of an organization.
+func (s *Store) UpdateOrganization(oi OrganizationInfo) error {
+	return s.exec(internal.Command_UpdateOrganizationCommand, internal.E_UpdateOrganizationCommand_Command,
+		&internal.UpdateOrganizationCommand{
+			Organization: oi.marshal(),
+		},
+	)
+}
+
+// DropOrganization removes an organization along with its namespace and its memberships.
+func (s *Store) DropOrganization(id string) error {
+	return s.exec(internal.Command_DropOrganizationCommand, internal.E_DropOrganizationCommand_Command,
+		&internal.DropOrganizationCommand{
+			ID: proto.String(id),
+		},
+	)
+}
+
+// Member returns the membership of a user in an organization. Returns nil if the user is not a
+// member of the organization.
+func (s *Store) Member(orgID, userID string) (mi *MemberInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if m := data.Member(orgID, userID); m != nil {
+			other := m.clone()
+			mi = &other
+		}
+		return nil
+	})
+	return
+}
+
+// Members returns the memberships of an organization.
+func (s *Store) Members(orgID string) (a []MemberInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Members {
+			if data.Members[i].OrganizationID == orgID {
+				a = append(a, data.Members[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// Memberships returns the memberships of a user in all organizations.
+func (s *Store) Memberships(userID string) (a []MemberInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Members {
+			if data.Members[i].UserID == userID {
+				a = append(a, data.Members[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// AddOrUpdateMembership sets the role of a user in an organization.
+func (s *Store) AddOrUpdateMembership(orgID, userID, role string, t time.Time) error {
+	return s.exec(internal.Command_AddOrUpdateMembershipCommand, internal.E_AddOrUpdateMembershipCommand_Command,
+		&internal.AddOrUpdateMembershipCommand{
+			OrganizationID: proto.String(orgID),
+			UserID:         proto.String(userID),
+			Role:           proto.String(role),
+			Time:           proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// RemoveMembership removes a user from an organization.
+func (s *Store) RemoveMembership(orgID, userID string) error {
+	return s.exec(internal.Command_RemoveMembershipCommand, internal.E_RemoveMembershipCommand_Command,
+		&internal.RemoveMembershipCommand{
+			OrganizationID: proto.String(orgID),
+			UserID:         proto.String(userID),
+		},
+	)
+}
+
+// EditMyMembership sets the state of the membership of a user in an organization.
+func (s *Store) EditMyMembership(orgID, userID, state string, t time.Time) error {
+	return s.exec(internal.Command_EditMyMembershipCommand, internal.E_EditMyMembershipCommand_Command,
+		&internal.EditMyMembershipCommand{
+			OrganizationID: proto.String(orgID),
+			UserID:         proto.String(userID),
+			State:          proto.String(state),
+			Time:           proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// PublicizeMembership makes the membership of a user in an organization visible to everyone.
+func (s *Store) PublicizeMembership(orgID, userID string, t time.Time) error {
+	return s.exec(internal.Command_PublicizeMembershipCommand, internal.E_PublicizeMembershipCommand_Command,
+		&internal.PublicizeMembershipCommand{
+			OrganizationID: proto.String(orgID),
+			UserID:         proto.String(userID),
+			Time:           proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// ConcealMembership hides the membership of a user in an organization.
+func (s *Store) ConcealMembership(orgID, userID string, t time.Time) error {
+	return s.exec(internal.Command_ConcealMembershipCommand, internal.E_ConcealMembershipCommand_Command,
+		&internal.ConcealMembershipCommand{
+			OrganizationID: proto.String(orgID),
+			UserID:         proto.String(userID),
+			Time:           proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// Invitation returns an invitation by ID. Returns nil if the invitation doesn't exist.
+func (s *Store) Invitation(id string) (ii *InvitationInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if i := data.Invitation(id); i != nil {
+			other := i.clone()
+			ii = &other
+		}
+		return nil
+	})
+	return
+}
+
+// InvitationByToken returns the invitation sent with the token of the given hash.
+// Returns nil if no invitation was sent with the token.
+func (s *Store) InvitationByToken(tokenHash string) (ii *InvitationInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if i := data.InvitationByToken(tokenHash); i != nil {
+			other := i.clone()
+			ii = &other
+		}
+		return nil
+	})
+	return
+}
+
+// Invitations returns the pending invitations of an organization.
+func (s *Store) Invitations(orgID string) (a []InvitationInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Invitations {
+			if data.Invitations[i].OrganizationID == orgID {
+				a = append(a, data.Invitations[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// CreateInvitation invites an email address to join an organization.
+func (s *Store) CreateInvitation(ii InvitationInfo) error {
+	return s.exec(internal.Command_CreateInvitationCommand, internal.E_CreateInvitationCommand_Command,
+		&internal.CreateInvitationCommand{
+			Invitation: ii.marshal(),
 		},
 	)
 }
 
-// read executes a function with the current metadata.
-// If an error is returned then the cache is invalidated and retried.
-//
-// The error returned by the retry is passed through to the original caller
-// unless the error is errInvalidate. A nil error is passed through when
-// errInvalidate is returned.
-func (s *Store) read(fn func(*Data) error) error {
-	// First use the cached metadata.
-	s.mu.RLock()
-	data := s.data
-	s.mu.RUnlock()
+// ResendInvitation replaces the token of an invitation and extends its expiry.
+func (s *Store) ResendInvitation(id, tokenHash string, sentAt, expiresAt time.Time) error {
+	return s.exec(internal.Command_ResendInvitationCommand, internal.E_ResendInvitationCommand_Command,
+		&internal.ResendInvitationCommand{
+			ID:        proto.String(id),
+			TokenHash: proto.String(tokenHash),
+			SentAt:    proto.Int64(MarshalTime(sentAt)),
+			ExpiresAt: proto.Int64(MarshalTime(expiresAt)),
+		},
+	)
+}
+
+// AcceptInvitation makes a user an active member of the organization of an invitation.
+func (s *Store) AcceptInvitation(id, userID string, t time.Time) error {
+	return s.exec(internal.Command_AcceptInvitationCommand, internal.E_AcceptInvitationCommand_Command,
+		&internal.AcceptInvitationCommand{
+			ID:     proto.String(id),
+			UserID: proto.String(userID),
+			Time:   proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// DeleteInvitation removes an invitation.
+func (s *Store) DeleteInvitation(id string) error {
+	return s.exec(internal.Command_DeleteInvitationCommand, internal.E_DeleteInvitationCommand_Command,
+		&internal.DeleteInvitationCommand{
+			ID: proto.String(id),
+		},
+	)
+}
+
+// Team returns a team by ID. Returns nil if the team doesn't exist.
+func (s *Store) Team(id string) (ti *TeamInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if t := data.Team(id); t != nil {
+			other := t.clone()
+			ti = &other
+		}
+		return nil
+	})
+	return
+}
+
+// Teams returns the teams of an organization.
+func (s *Store) Teams(orgID string) (a []TeamInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Teams {
+			if data.Teams[i].OrganizationID == orgID {
+				a = append(a, data.Teams[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// CreateTeam adds a team to an organization.
+func (s *Store) CreateTeam(ti TeamInfo) error {
+	return s.exec(internal.Command_CreateTeamCommand, internal.E_CreateTeamCommand_Command,
+		&internal.CreateTeamCommand{
+			Team: ti.marshal(),
+		},
+	)
+}
+
+// UpdateTeam replaces the name and the description of a team.
+func (s *Store) UpdateTeam(ti TeamInfo) error {
+	return s.exec(internal.Command_UpdateTeamCommand, internal.E_UpdateTeamCommand_Command,
+		&internal.UpdateTeamCommand{
+			Team: ti.marshal(),
+		},
+	)
+}
+
+// DropTeam removes a team.
+func (s *Store) DropTeam(id string) error {
+	return s.exec(internal.Command_DropTeamCommand, internal.E_DropTeamCommand_Command,
+		&internal.DropTeamCommand{
+			ID: proto.String(id),
+		},
+	)
+}
+
+// AddTeamMember adds a member of an organization to one of its teams.
+func (s *Store) AddTeamMember(teamID, userID string, t time.Time) error {
+	return s.exec(internal.Command_AddTeamMemberCommand, internal.E_AddTeamMemberCommand_Command,
+		&internal.AddTeamMemberCommand{
+			TeamID: proto.String(teamID),
+			UserID: proto.String(userID),
+			Time:   proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// RemoveTeamMember removes a user from a team.
+func (s *Store) RemoveTeamMember(teamID, userID string) error {
+	return s.exec(internal.Command_RemoveTeamMemberCommand, internal.E_RemoveTeamMemberCommand_Command,
+		&internal.RemoveTeamMemberCommand{
+			TeamID: proto.String(teamID),
+			UserID: proto.String(userID),
+		},
+	)
+}
+
+// GrantTeamConversation grants a conversation to a team.
+func (s *Store) GrantTeamConversation(teamID, conversationID string, t time.Time) error {
+	return s.exec(internal.Command_GrantTeamConversationCommand, internal.E_GrantTeamConversationCommand_Command,
+		&internal.GrantTeamConversationCommand{
+			TeamID:         proto.String(teamID),
+			ConversationID: proto.String(conversationID),
+			Time:           proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// RevokeTeamConversation revokes a conversation from a team.
+func (s *Store) RevokeTeamConversation(teamID, conversationID string) error {
+	return s.exec(internal.Command_RevokeTeamConversationCommand, internal.E_RevokeTeamConversationCommand_Command,
+		&internal.RevokeTeamConversationCommand{
+			TeamID:         proto.String(teamID),
+			ConversationID: proto.String(conversationID),
+		},
+	)
+}
+
+// IsTokenRevoked returns true if the token with the given ID has been revoked.
+func (s *Store) IsTokenRevoked(id string) (revoked bool, err error) {
+	err = s.read(func(data *Data) error {
+		revoked = data.RevokedToken(id) != nil
+		return nil
+	})
+	return
+}
+
+// RevokeToken denies a token across the cluster until it expires.
+func (s *Store) RevokeToken(id, userID string, expiresAt, t time.Time) error {
+	return s.exec(internal.Command_RevokeTokenCommand, internal.E_RevokeTokenCommand_Command,
+		&internal.RevokeTokenCommand{
+			Token: RevokedTokenInfo{ID: id, UserID: userID, ExpiresAt: expiresAt}.marshal(),
+			Time:  proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// ApiToken returns an API token by ID. Returns nil if the token doesn't exist.
+func (s *Store) ApiToken(id string) (ti *ApiTokenInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if t := data.ApiToken(id); t != nil {
+			other := t.clone()
+			ti = &other
+		}
+		return nil
+	})
+	return
+}
+
+// ApiTokenByHash returns the API token of the given hash. Returns nil if the token doesn't exist.
+func (s *Store) ApiTokenByHash(tokenHash string) (ti *ApiTokenInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if t := data.ApiTokenByHash(tokenHash); t != nil {
+			other := t.clone()
+			ti = &other
+		}
+		return nil
+	})
+	return
+}
+
+// ApiTokens returns the API tokens a user created for an organization, or the personal tokens of
+// the user when orgID is empty.
+func (s *Store) ApiTokens(userID, orgID string) (a []ApiTokenInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.ApiTokens {
+			if data.ApiTokens[i].UserID == userID && data.ApiTokens[i].OrganizationID == orgID {
+				a = append(a, data.ApiTokens[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// OrganizationApiTokens returns the API tokens of an organization.
+func (s *Store) OrganizationApiTokens(orgID string) (a []ApiTokenInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.ApiTokens {
+			if data.ApiTokens[i].OrganizationID == orgID {
+				a = append(a, data.ApiTokens[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// CreateApiToken creates a new API token.
+func (s *Store) CreateApiToken(ti ApiTokenInfo) error {
+	return s.exec(internal.Command_CreateApiTokenCommand, internal.E_CreateApiTokenCommand_Command,
+		&internal.CreateApiTokenCommand{
+			Token: ti.marshal(),
+		},
+	)
+}
+
+// DeleteApiToken removes an API token.
+func (s *Store) DeleteApiToken(id string) error {
+	return s.exec(internal.Command_DeleteApiTokenCommand, internal.E_DeleteApiTokenCommand_Command,
+		&internal.DeleteApiTokenCommand{
+			ID: proto.String(id),
+		},
+	)
+}
+
+// TouchApiToken records the time an API token was last used.
+func (s *Store) TouchApiToken(id string, t time.Time) error {
+	return s.exec(internal.Command_TouchApiTokenCommand, internal.E_TouchApiTokenCommand_Command,
+		&internal.TouchApiTokenCommand{
+			ID:   proto.String(id),
+			Time: proto.Int64(MarshalTime(t)),
+		},
+	)
+}
+
+// Conversation returns a conversation by ID. Returns nil if the conversation doesn't exist.
+func (s *Store) Conversation(id string) (ci *ConversationInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if c := data.Conversation(id); c != nil {
+			other := c.clone()
+			ci = &other
+		}
+		return nil
+	})
+	return
+}
+
+// AllConversations returns all the conversations.
+func (s *Store) AllConversations() (a []ConversationInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Conversations {
+			a = append(a, data.Conversations[i].clone())
+		}
+		return nil
+	})
+	return
+}
+
+// NamespaceConversations returns the conversations of a namespace.
+func (s *Store) NamespaceConversations(namespaceID string) (a []ConversationInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Conversations {
+			if data.Conversations[i].NamespaceID == namespaceID {
+				a = append(a, data.Conversations[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// CreateConversation adds a conversation to a namespace, with its creator as first participant.
+func (s *Store) CreateConversation(ci ConversationInfo) error {
+	return s.exec(internal.Command_CreateConversationCommand, internal.E_CreateConversationCommand_Command,
+		&internal.CreateConversationCommand{
+			Conversation: ci.marshal(),
+		},
+	)
+}
+
+// UpdateConversation replaces the settings of a conversation.
+func (s *Store) UpdateConversation(ci ConversationInfo) error {
+	return s.exec(internal.Command_UpdateConversationCommand, internal.E_UpdateConversationCommand_Command,
+		&internal.UpdateConversationCommand{
+			Conversation: ci.marshal(),
+		},
+	)
+}
+
+// DropConversation removes a conversation along with its participants, read markers and attachments.
+func (s *Store) DropConversation(id string) error {
+	return s.exec(internal.Command_DropConversationCommand, internal.E_DropConversationCommand_Command,
+		&internal.DropConversationCommand{
+			ID: proto.String(id),
+		},
+	)
+}
+
+// Device returns a device by ID. Returns nil if the device doesn't exist.
+func (s *Store) Device(id string) (di *DeviceInfo, err error) {
+	err = s.read(func(data *Data) error {
+		if d := data.Device(id); d != nil {
+			other := d.clone()
+			di = &other
+		}
+		return nil
+	})
+	return
+}
+
+// Devices returns the devices registered to a user account.
+func (s *Store) Devices(userID string) (a []DeviceInfo, err error) {
+	err = s.read(func(data *Data) error {
+		for i := range data.Devices {
+			if data.Devices[i].UserID == userID {
+				a = append(a, data.Devices[i].clone())
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// AddDevice registers a device to a user account.
+func (s *Store) AddDevice(di DeviceInfo) error {
+	return s.exec(internal.Command_AddDeviceCommand, internal.E_AddDeviceCommand_Command,
+		&internal.AddDeviceCommand{
+			Device: di.marshal(),
+		},
+	)
+}
+
+// UpdateDevice replaces the name, the platform and the token of a device.
+func (s *Store) UpdateDevice(di DeviceInfo) error {
+	return s.exec(internal.Command_UpdateDeviceCommand, internal.E_UpdateDeviceCommand_Command,
+		&internal.UpdateDeviceCommand{
+			Device: di.marshal(),
+		},
+	)
+}
+
+// DeleteDevice removes a device.
+func (s *Store) DeleteDevice(id string) error {
+	return s.exec(internal.Command_DeleteDeviceCommand, internal.E_DeleteDeviceCommand_Command,
+		&internal.DeleteDeviceCommand{
+			ID: proto.String(id),
+		},
+	)
+}
+
+// SetData force overwrites the root data.
+// This should only be used when restoring a snapshot.
+func (s *Store) SetData(data *Data) error {
+	return s.exec(internal.Command_SetDataCommand, internal.E_SetDataCommand_Command,
+		&internal.SetDataCommand{
+			Data: data.marshal(),
+		},
+	)
+}
+
+// read executes a function with the current metadata.
+// If an error is returned then the cache is invalidated and retried.
+//
+// The error returned by the retry is passed through to the original caller
+// unless the error is errInvalidate. A nil error is passed through when
+// errInvalidate is returned.
+func (s *Store) read(fn func(*Data) error) error {
+	// First use the cached metadata.
+	s.mu.RLock()
+	data := s.data
+	s.mu.RUnlock()
+
+	// Execute fn against cached data.
+	// Return immediately if there was no error.
+	if err := fn(data); err == nil {
+		return nil
+	}
+
+	// If an error occurred then invalidate cache and retry.
+	if err := s.invalidate(); err != nil {
+		return err
+	}
+
+	// Re-read the metadata.
+	s.mu.RLock()
+	data = s.data
+	s.mu.RUnlock()
+
+	// Passthrough error unless it is a cache invalidation.
+	if err := fn(data); err != nil && err != errInvalidate {
+		return err
+	}
+
+	return nil
+}
+
+// errInvalidate is returned to read() when the cache should be invalidated
+// but an error should not be passed through to the caller.
+var errInvalidate = errors.New("invalidate cache")
+
+func (s *Store) invalidate() error {
+	time.Sleep(1 * time.Second)
+	return nil // FIXME(benbjohnson): Reload cache from the leader.
+}
+
+func (s *Store) exec(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) error {
+	// Create command.
+	cmd := &internal.Command{Type: &typ}
+	err := proto.SetExtension(cmd, desc, value)
+	assert(err == nil, "proto.SetExtension: %s", err)
+
+	// Marshal to a byte slice.
+	b, err := proto.Marshal(cmd)
+	assert(err == nil, "proto.Marshal: %s", err)
+
+	// Apply the command if this is the leader.
+	// Otherwise remotely execute the command against the current leader.
+	if s.raft.State() == raft.Leader {
+		return s.apply(b)
+	}
+	return s.remoteExec(b)
+}
+
+// apply applies a serialized command to the raft log.
+func (s *Store) apply(b []byte) error {
+	// Apply to raft log.
+	f := s.raft.Apply(b, 0)
+	if err := f.Error(); err != nil {
+		return err
+	}
+
+	// Return response if it's an error.
+	// No other non-nil objects should be returned.
+	resp := f.Response()
+	if err, ok := resp.(error); ok {
+		return lookupError(err)
+	}
+	assert(resp == nil, "unexpected response: %#v", resp)
+
+	return nil
+}
+
+// remoteExec sends an encoded command to the remote leader.
+func (s *Store) remoteExec(b []byte) error {
+	// Retrieve the current known leader.
+	leader := s.raft.Leader()
+	if leader == "" {
+		return errors.New("no leader")
+	}
+
+	// Create a connection to the leader.
+	conn, err := net.DialTimeout("tcp", leader, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Write a marker byte for exec messages.
+	_, err = conn.Write([]byte{MuxExecHeader})
+	if err != nil {
+		return err
+	}
+
+	// Write a marker message.
+	_, err = conn.Write([]byte(ExecMagic))
+	if err != nil {
+		return err
+	}
+
+	// Write command size & bytes.
+	if err := binary.Write(conn, binary.BigEndian, uint64(len(b))); err != nil {
+		return fmt.Errorf("write command size: %s", err)
+	} else if _, err := conn.Write(b); err != nil {
+		return fmt.Errorf("write command: %s", err)
+	}
+
+	// Read response bytes.
+	var sz uint64
+	if err := binary.Read(conn, binary.BigEndian, &sz); err != nil {
+		return fmt.Errorf("read response size: %s", err)
+	}
+	buf := make([]byte, sz)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return fmt.Errorf("read response: %s", err)
+	}
+
+	// Unmarshal response.
+	var resp internal.Response
+	if err := proto.Unmarshal(buf, &resp); err != nil {
+		return fmt.Errorf("unmarshal response: %s", err)
+	} else if !resp.GetOK() {
+		return fmt.Errorf("exec failed: %s", resp.GetError())
+	}
+
+	// Wait for local FSM to sync to index.
+	if err := s.sync(resp.GetIndex(), 5*time.Second); err != nil {
+		return fmt.Errorf("sync: %s", err)
+	}
+
+	return nil
+}
+
+// sync polls the state machine until it reaches a given index.
+func (s *Store) sync(index uint64, timeout time.Duration) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		// Wait for next tick or timeout.
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			return errors.New("timeout")
+		}
+
+		// Compare index against current metadata.
+		s.mu.Lock()
+		ok := (s.data.Index >= index)
+		s.mu.Unlock()
+
+		// Exit if we are at least at the given index.
+		if ok {
+			return nil
+		}
+	}
+}
+
+// BcryptCost is the cost associated with generating password with Bcrypt.
+// This setting is lowered during testing to improve test suite performance.
+var BcryptCost = 10
+
+// HashPasswordFn represnets a password hashing function.
+type HashPasswordFn func(password string) ([]byte, error)
+
+// GetHashPasswordFn returns the current password hashing function.
+func (s *Store) GetHashPasswordFn() HashPasswordFn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hashPassword
+}
+
+// SetHashPasswordFn sets the password hashing function.
+func (s *Store) SetHashPasswordFn(fn HashPasswordFn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashPassword = fn
+}
+
+// storeFSM represents the finite state machine used by Store to interact with Raft.
+type storeFSM Store
+
+func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
+	var cmd internal.Command
+	if err := proto.Unmarshal(l.Data, &cmd); err != nil {
+		panic(fmt.Errorf("cannot marshal command: %x", l.Data))
+	}
+
+	// Lock the store.
+	s := (*Store)(fsm)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := func() interface{} {
+		switch cmd.GetType() {
+		case internal.Command_CreateNodeCommand:
+			return fsm.applyCreateNodeCommand(&cmd)
+		case internal.Command_DeleteNodeCommand:
+			return fsm.applyDeleteNodeCommand(&cmd)
+		case internal.Command_CreateDatabaseCommand:
+			return fsm.applyCreateDatabaseCommand(&cmd)
+		case internal.Command_DropDatabaseCommand:
+			return fsm.applyDropDatabaseCommand(&cmd)
+		case internal.Command_CreateRetentionPolicyCommand:
+			return fsm.applyCreateRetentionPolicyCommand(&cmd)
+		case internal.Command_DropRetentionPolicyCommand:
+			return fsm.applyDropRetentionPolicyCommand(&cmd)
+		case internal.Command_SetDefaultRetentionPolicyCommand:
+			return fsm.applySetDefaultRetentionPolicyCommand(&cmd)
+		case internal.Command_UpdateRetentionPolicyCommand:
+			return fsm.applyUpdateRetentionPolicyCommand(&cmd)
+		case internal.Command_CreateShardGroupCommand:
+			return fsm.applyCreateShardGroupCommand(&cmd)
+		case internal.Command_DeleteShardGroupCommand:
+			return fsm.applyDeleteShardGroupCommand(&cmd)
+		// case internal.Command_CreateContinuousQueryCommand:
+		// 	return fsm.applyCreateContinuousQueryCommand(&cmd)
+		// case internal.Command_DropContinuousQueryCommand:
+		// 	return fsm.applyDropContinuousQueryCommand(&cmd)
+		case internal.Command_CreateUserCommand:
+			return fsm.applyCreateUserCommand(&cmd)
+		case internal.Command_DropUserCommand:
+			return fsm.applyDropUserCommand(&cmd)
+		case internal.Command_UpdateUserCommand:
+			return fsm.applyUpdateUserCommand(&cmd)
+		case internal.Command_SetPrivilegeCommand:
+			return fsm.applySetPrivilegeCommand(&cmd)
+		case internal.Command_SetAdminPrivilegeCommand:
+			return fsm.applySetAdminPrivilegeCommand(&cmd)
+		case internal.Command_SetReadMarkerCommand:
+			return fsm.applySetReadMarkerCommand(&cmd)
+		case internal.Command_CreateAttachmentCommand:
+			return fsm.applyCreateAttachmentCommand(&cmd)
+		case internal.Command_SetAttachmentMessageCommand:
+			return fsm.applySetAttachmentMessageCommand(&cmd)
+		case internal.Command_DeleteAttachmentCommand:
+			return fsm.applyDeleteAttachmentCommand(&cmd)
+		case internal.Command_AddParticipantCommand:
+			return fsm.applyAddParticipantCommand(&cmd)
+		case internal.Command_RemoveParticipantCommand:
+			return fsm.applyRemoveParticipantCommand(&cmd)
+		case internal.Command_SetParticipantActivityCommand:
+			return fsm.applySetParticipantActivityCommand(&cmd)
+		case internal.Command_CreateAccountCommand:
+			return fsm.applyCreateAccountCommand(&cmd)
+		case internal.Command_UpdateAccountCommand:
+			return fsm.applyUpdateAccountCommand(&cmd)
+		case internal.Command_LinkIdentityCommand:
+			return fsm.applyLinkIdentityCommand(&cmd)
+		case internal.Command_RenameNamespaceCommand:
+			return fsm.applyRenameNamespaceCommand(&cmd)
+		case internal.Command_CreateOrganizationCommand:
+			return fsm.applyCreateOrganizationCommand(&cmd)
+		case internal.Command_UpdateOrganizationCommand:
+			return fsm.applyUpdateOrganizationCommand(&cmd)
+		case internal.Command_DropOrganizationCommand:
+			return fsm.applyDropOrganizationCommand(&cmd)
+		case internal.Command_AddOrUpdateMembershipCommand:
+			return fsm.applyAddOrUpdateMembershipCommand(&cmd)
+		case internal.Command_RemoveMembershipCommand:
+			return fsm.applyRemoveMembershipCommand(&cmd)
+		case internal.Command_EditMyMembershipCommand:
+			return fsm.applyEditMyMembershipCommand(&cmd)
+		case internal.Command_PublicizeMembershipCommand:
+			return fsm.applyPublicizeMembershipCommand(&cmd)
+		case internal.Command_ConcealMembershipCommand:
+			return fsm.applyConcealMembershipCommand(&cmd)
+		case internal.Command_CreateInvitationCommand:
+			return fsm.applyCreateInvitationCommand(&cmd)
+		case internal.Command_ResendInvitationCommand:
+			return fsm.applyResendInvitationCommand(&cmd)
+		case internal.Command_AcceptInvitationCommand:
+			return fsm.applyAcceptInvitationCommand(&cmd)
+		case internal.Command_DeleteInvitationCommand:
+			return fsm.applyDeleteInvitationCommand(&cmd)
+		case internal.Command_CreateTeamCommand:
+			return fsm.applyCreateTeamCommand(&cmd)
+		case internal.Command_UpdateTeamCommand:
+			return fsm.applyUpdateTeamCommand(&cmd)
+		case internal.Command_DropTeamCommand:
+			return fsm.applyDropTeamCommand(&cmd)
+		case internal.Command_AddTeamMemberCommand:
+			return fsm.applyAddTeamMemberCommand(&cmd)
+		case internal.Command_RemoveTeamMemberCommand:
+			return fsm.applyRemoveTeamMemberCommand(&cmd)
+		case internal.Command_GrantTeamConversationCommand:
+			return fsm.applyGrantTeamConversationCommand(&cmd)
+		case internal.Command_RevokeTeamConversationCommand:
+			return fsm.applyRevokeTeamConversationCommand(&cmd)
+		case internal.Command_RevokeTokenCommand:
+			return fsm.applyRevokeTokenCommand(&cmd)
+		case internal.Command_CreateApiTokenCommand:
+			return fsm.applyCreateApiTokenCommand(&cmd)
+		case internal.Command_DeleteApiTokenCommand:
+			return fsm.applyDeleteApiTokenCommand(&cmd)
+		case internal.Command_TouchApiTokenCommand:
+			return fsm.applyTouchApiTokenCommand(&cmd)
+		case internal.Command_CreateConversationCommand:
+			return fsm.applyCreateConversationCommand(&cmd)
+		case internal.Command_UpdateConversationCommand:
+			return fsm.applyUpdateConversationCommand(&cmd)
+		case internal.Command_DropConversationCommand:
+			return fsm.applyDropConversationCommand(&cmd)
+		case internal.Command_AddDeviceCommand:
+			return fsm.applyAddDeviceCommand(&cmd)
+		case internal.Command_UpdateDeviceCommand:
+			return fsm.applyUpdateDeviceCommand(&cmd)
+		case internal.Command_DeleteDeviceCommand:
+			return fsm.applyDeleteDeviceCommand(&cmd)
+		case internal.Command_SetDataCommand:
+			return fsm.applySetDataCommand(&cmd)
+		default:
+			panic(fmt.Errorf("cannot apply command: %x", l.Data))
+		}
+	}()
+
+	// Copy term and index to new metadata.
+	fsm.data.Term = l.Term
+	fsm.data.Index = l.Index
+
+	return err
+}
+
+func (fsm *storeFSM) applyCreateNodeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateNodeCommand_Command)
+	v := ext.(*internal.CreateNodeCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateNode(v.GetHost()); err != nil {
+		return err
+	}
+
+	// If the cluster ID hasn't been set then use the command's random number.
+	if other.ClusterID == 0 {
+		other.ClusterID = uint64(v.GetRand())
+	}
+
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyDeleteNodeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DeleteNodeCommand_Command)
+	v := ext.(*internal.DeleteNodeCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DeleteNode(v.GetID()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateDatabaseCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateDatabaseCommand_Command)
+	v := ext.(*internal.CreateDatabaseCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateDatabase(v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyDropDatabaseCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropDatabaseCommand_Command)
+	v := ext.(*internal.DropDatabaseCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropDatabase(v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateRetentionPolicyCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateRetentionPolicyCommand_Command)
+	v := ext.(*internal.CreateRetentionPolicyCommand)
+	pb := v.GetRetentionPolicy()
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateRetentionPolicy(v.GetDatabase(),
+		&RetentionPolicyInfo{
+			Name:               pb.GetName(),
+			ReplicaN:           int(pb.GetReplicaN()),
+			Duration:           time.Duration(pb.GetDuration()),
+			ShardGroupDuration: time.Duration(pb.GetShardGroupDuration()),
+		}); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyDropRetentionPolicyCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropRetentionPolicyCommand_Command)
+	v := ext.(*internal.DropRetentionPolicyCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropRetentionPolicy(v.GetDatabase(), v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applySetDefaultRetentionPolicyCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetDefaultRetentionPolicyCommand_Command)
+	v := ext.(*internal.SetDefaultRetentionPolicyCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetDefaultRetentionPolicy(v.GetDatabase(), v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyUpdateRetentionPolicyCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateRetentionPolicyCommand_Command)
+	v := ext.(*internal.UpdateRetentionPolicyCommand)
+
+	// Create update object.
+	rpu := RetentionPolicyUpdate{Name: v.NewName}
+	if v.Duration != nil {
+		value := time.Duration(v.GetDuration())
+		rpu.Duration = &value
+	}
+	if v.ReplicaN != nil {
+		value := int(v.GetReplicaN())
+		rpu.ReplicaN = &value
+	}
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.UpdateRetentionPolicy(v.GetDatabase(), v.GetName(), &rpu); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyCreateShardGroupCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateShardGroupCommand_Command)
+	v := ext.(*internal.CreateShardGroupCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateShardGroup(v.GetDatabase(), v.GetPolicy(), time.Unix(0, v.GetTimestamp())); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyDeleteShardGroupCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DeleteShardGroupCommand_Command)
+	v := ext.(*internal.DeleteShardGroupCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DeleteShardGroup(v.GetDatabase(), v.GetPolicy(), v.GetShardGroupID()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+// func (fsm *storeFSM) applyCreateContinuousQueryCommand(cmd *internal.Command) interface{} {
+// 	ext, _ := proto.GetExtension(cmd, internal.E_CreateContinuousQueryCommand_Command)
+// 	v := ext.(*internal.CreateContinuousQueryCommand)
+//
+// 	// Copy data and update.
+// 	other := fsm.data.Clone()
+// 	if err := other.CreateContinuousQuery(v.GetDatabase(), v.GetName(), v.GetQuery()); err != nil {
+// 		return err
+// 	}
+// 	fsm.data = other
+//
+// 	return nil
+// }
+//
+// func (fsm *storeFSM) applyDropContinuousQueryCommand(cmd *internal.Command) interface{} {
+// 	ext, _ := proto.GetExtension(cmd, internal.E_DropContinuousQueryCommand_Command)
+// 	v := ext.(*internal.DropContinuousQueryCommand)
+//
+// 	// Copy data and update.
+// 	other := fsm.data.Clone()
+// 	if err := other.DropContinuousQuery(v.GetDatabase(), v.GetName()); err != nil {
+// 		return err
+// 	}
+// 	fsm.data = other
+//
+// 	return nil
+// }
+
+func (fsm *storeFSM) applyCreateUserCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateUserCommand_Command)
+	v := ext.(*internal.CreateUserCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateUser(v.GetName(), v.GetHash(), v.GetAdmin()); err != nil {
+		return err
+	}
+	fsm.data = other
+
+	return nil
+}
+
+func (fsm *storeFSM) applyDropUserCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropUserCommand_Command)
+	v := ext.(*internal.DropUserCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropUser(v.GetName()); err != nil {
+		return err
+	}
+	fsm.data = other
+	delete(fsm.authCache, v.GetName())
+	return nil
+}
+
+func (fsm *storeFSM) applyUpdateUserCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateUserCommand_Command)
+	v := ext.(*internal.UpdateUserCommand)
 
-	// Execute fn against cached data.
-	// Return immediately if there was no error.
-	if err := fn(data); err == nil {
-		return nil
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.UpdateUser(v.GetName(), v.GetHash()); err != nil {
+		return err
 	}
+	fsm.data = other
+	delete(fsm.authCache, v.GetName())
+	return nil
+}
 
-	// If an error occurred then invalidate cache and retry.
-	if err := s.invalidate(); err != nil {
+func (fsm *storeFSM) applySetPrivilegeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetPrivilegeCommand_Command)
+	v := ext.(*internal.SetPrivilegeCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetPrivilege(v.GetUsername(), v.GetDatabase(), sql.Privilege(v.GetPrivilege())); err != nil {
 		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Re-read the metadata.
-	s.mu.RLock()
-	data = s.data
-	s.mu.RUnlock()
+func (fsm *storeFSM) applySetAdminPrivilegeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetAdminPrivilegeCommand_Command)
+	v := ext.(*internal.SetAdminPrivilegeCommand)
 
-	// Passthrough error unless it is a cache invalidation.
-	if err := fn(data); err != nil && err != errInvalidate {
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetAdminPrivilege(v.GetUsername(), v.GetAdmin()); err != nil {
 		return err
 	}
-
+	fsm.data = other
 	return nil
 }
 
-// errInvalidate is returned to read() when the cache should be invalidated
-// but an error should not be passed through to the caller.
-var errInvalidate = errors.New("invalidate cache")
+func (fsm *storeFSM) applySetReadMarkerCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetReadMarkerCommand_Command)
+	v := ext.(*internal.SetReadMarkerCommand)
 
-func (s *Store) invalidate() error {
-	time.Sleep(1 * time.Second)
-	return nil // FIXME(benbjohnson): Reload cache from the leader.
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetReadMarker(v.GetConversationID(), v.GetUserID(), v.GetMessageID(), UnmarshalTime(v.GetTime())); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
 }
 
-func (s *Store) exec(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) error {
-	// Create command.
-	cmd := &internal.Command{Type: &typ}
-	err := proto.SetExtension(cmd, desc, value)
-	assert(err == nil, "proto.SetExtension: %s", err)
+func (fsm *storeFSM) applyCreateAttachmentCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateAttachmentCommand_Command)
+	v := ext.(*internal.CreateAttachmentCommand)
 
-	// Marshal to a byte slice.
-	b, err := proto.Marshal(cmd)
-	assert(err == nil, "proto.Marshal: %s", err)
+	var ai AttachmentInfo
+	ai.unmarshal(v.GetAttachment())
 
-	// Apply the command if this is the leader.
-	// Otherwise remotely execute the command against the current leader.
-	if s.raft.State() == raft.Leader {
-		return s.apply(b)
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateAttachment(ai); err != nil {
+		return err
 	}
-	return s.remoteExec(b)
+	fsm.data = other
+	return nil
 }
 
-// apply applies a serialized command to the raft log.
-func (s *Store) apply(b []byte) error {
-	// Apply to raft log.
-	f := s.raft.Apply(b, 0)
-	if err := f.Error(); err != nil {
+func (fsm *storeFSM) applySetAttachmentMessageCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetAttachmentMessageCommand_Command)
+	v := ext.(*internal.SetAttachmentMessageCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetAttachmentMessage(v.GetID(), v.GetMessageID()); err != nil {
 		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Return response if it's an error.
-	// No other non-nil objects should be returned.
-	resp := f.Response()
-	if err, ok := resp.(error); ok {
-		return lookupError(err)
-	}
-	assert(resp == nil, "unexpected response: %#v", resp)
+func (fsm *storeFSM) applyDeleteAttachmentCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DeleteAttachmentCommand_Command)
+	v := ext.(*internal.DeleteAttachmentCommand)
 
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DeleteAttachment(v.GetID()); err != nil {
+		return err
+	}
+	fsm.data = other
 	return nil
 }
 
-// remoteExec sends an encoded command to the remote leader.
-func (s *Store) remoteExec(b []byte) error {
-	// Retrieve the current known leader.
-	leader := s.raft.Leader()
-	if leader == "" {
-		return errors.New("no leader")
+func (fsm *storeFSM) applyAddParticipantCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_AddParticipantCommand_Command)
+	v := ext.(*internal.AddParticipantCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.AddParticipant(v.GetConversationID(), v.GetUserID(), UnmarshalTime(v.GetTime())); err != nil {
+		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Create a connection to the leader.
-	conn, err := net.DialTimeout("tcp", leader, 10*time.Second)
-	if err != nil {
+func (fsm *storeFSM) applyRemoveParticipantCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RemoveParticipantCommand_Command)
+	v := ext.(*internal.RemoveParticipantCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.RemoveParticipant(v.GetConversationID(), v.GetUserID()); err != nil {
 		return err
 	}
-	defer conn.Close()
+	fsm.data = other
+	return nil
+}
 
-	// Write a marker byte for exec messages.
-	_, err = conn.Write([]byte{MuxExecHeader})
-	if err != nil {
+func (fsm *storeFSM) applySetParticipantActivityCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetParticipantActivityCommand_Command)
+	v := ext.(*internal.SetParticipantActivityCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetParticipantActivity(v.GetConversationID(), v.GetUserID(), UnmarshalTime(v.GetTime())); err != nil {
 		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Write a marker message.
-	_, err = conn.Write([]byte(ExecMagic))
-	if err != nil {
+func (fsm *storeFSM) applyCreateAccountCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateAccountCommand_Command)
+	v := ext.(*internal.CreateAccountCommand)
+
+	var ai AccountInfo
+	ai.unmarshal(v.GetAccount())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateAccount(ai); err != nil {
 		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Write command size & bytes.
-	if err := binary.Write(conn, binary.BigEndian, uint64(len(b))); err != nil {
-		return fmt.Errorf("write command size: %s", err)
-	} else if _, err := conn.Write(b); err != nil {
-		return fmt.Errorf("write command: %s", err)
+func (fsm *storeFSM) applyUpdateAccountCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateAccountCommand_Command)
+	v := ext.(*internal.UpdateAccountCommand)
+
+	var ai AccountInfo
+	ai.unmarshal(v.GetAccount())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.UpdateAccount(ai); err != nil {
+		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Read response bytes.
-	var sz uint64
-	if err := binary.Read(conn, binary.BigEndian, &sz); err != nil {
-		return fmt.Errorf("read response size: %s", err)
+func (fsm *storeFSM) applyLinkIdentityCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_LinkIdentityCommand_Command)
+	v := ext.(*internal.LinkIdentityCommand)
+
+	var ii IdentityInfo
+	ii.unmarshal(v.GetIdentity())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.LinkIdentity(v.GetUserID(), ii); err != nil {
+		return err
 	}
-	buf := make([]byte, sz)
-	if _, err := io.ReadFull(conn, buf); err != nil {
-		return fmt.Errorf("read response: %s", err)
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyRenameNamespaceCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RenameNamespaceCommand_Command)
+	v := ext.(*internal.RenameNamespaceCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.RenameNamespace(v.GetID(), v.GetPath(), UnmarshalTime(v.GetTime())); err != nil {
+		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Unmarshal response.
-	var resp internal.Response
-	if err := proto.Unmarshal(buf, &resp); err != nil {
-		return fmt.Errorf("unmarshal response: %s", err)
-	} else if !resp.GetOK() {
-		return fmt.Errorf("exec failed: %s", resp.GetError())
+func (fsm *storeFSM) applyCreateOrganizationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateOrganizationCommand_Command)
+	v := ext.(*internal.CreateOrganizationCommand)
+
+	var oi OrganizationInfo
+	oi.unmarshal(v.GetOrganization())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateOrganization(oi, v.GetPath(), v.GetOwnerID()); err != nil {
+		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Wait for local FSM to sync to index.
-	if err := s.sync(resp.GetIndex(), 5*time.Second); err != nil {
-		return fmt.Errorf("sync: %s", err)
+func (fsm *storeFSM) applyUpdateOrganizationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateOrganizationCommand_Command)
+	v := ext.(*internal.UpdateOrganizationCommand)
+
+	var oi OrganizationInfo
+	oi.unmarshal(v.GetOrganization())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.UpdateOrganization(oi); err != nil {
+		return err
 	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyDropOrganizationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropOrganizationCommand_Command)
+	v := ext.(*internal.DropOrganizationCommand)
 
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.DropOrganization(v.GetID()); err != nil {
+		return err
+	}
+	fsm.data = other
 	return nil
 }
 
-// sync polls the state machine until it reaches a given index.
-func (s *Store) sync(index uint64, timeout time.Duration) error {
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+func (fsm *storeFSM) applyAddOrUpdateMembershipCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_AddOrUpdateMembershipCommand_Command)
+	v := ext.(*internal.AddOrUpdateMembershipCommand)
 
-	timer := time.NewTimer(timeout)
-	defer timer.Stop()
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.AddOrUpdateMembership(v.GetOrganizationID(), v.GetUserID(), v.GetRole(), UnmarshalTime(v.GetTime())); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
 
-	for {
-		// Wait for next tick or timeout.
-		select {
-		case <-ticker.C:
-		case <-timer.C:
-			return errors.New("timeout")
-		}
+func (fsm *storeFSM) applyRemoveMembershipCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RemoveMembershipCommand_Command)
+	v := ext.(*internal.RemoveMembershipCommand)
 
-		// Compare index against current metadata.
-		s.mu.Lock()
-		ok := (s.data.Index >= index)
-		s.mu.Unlock()
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.RemoveMembership(v.GetOrganizationID(), v.GetUserID()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
 
-		// Exit if we are at least at the given index.
-		if ok {
-			return nil
-		}
+func (fsm *storeFSM) applyEditMyMembershipCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_EditMyMembershipCommand_Command)
+	v := ext.(*internal.EditMyMembershipCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.EditMyMembership(v.GetOrganizationID(), v.GetUserID(), v.GetState(), UnmarshalTime(v.GetTime())); err != nil {
+		return err
 	}
+	fsm.data = other
+	return nil
 }
 
-// BcryptCost is the cost associated with generating password with Bcrypt.
-// This setting is lowered during testing to improve test suite performance.
-var BcryptCost = 10
-
-// HashPasswordFn represnets a password hashing function.
-type HashPasswordFn func(password string) ([]byte, error)
+func (fsm *storeFSM) applyPublicizeMembershipCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_PublicizeMembershipCommand_Command)
+	v := ext.(*internal.PublicizeMembershipCommand)
 
-// GetHashPasswordFn returns the current password hashing function.
-func (s *Store) GetHashPasswordFn() HashPasswordFn {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.hashPassword
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetMembershipPublished(v.GetOrganizationID(), v.GetUserID(), true, UnmarshalTime(v.GetTime())); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
 }
 
-// SetHashPasswordFn sets the password hashing function.
-func (s *Store) SetHashPasswordFn(fn HashPasswordFn) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.hashPassword = fn
+func (fsm *storeFSM) applyConcealMembershipCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_ConcealMembershipCommand_Command)
+	v := ext.(*internal.ConcealMembershipCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.SetMembershipPublished(v.GetOrganizationID(), v.GetUserID(), false, UnmarshalTime(v.GetTime())); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
 }
 
-// storeFSM represents the finite state machine used by Store to interact with Raft.
-type storeFSM Store
+func (fsm *storeFSM) applyCreateInvitationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateInvitationCommand_Command)
+	v := ext.(*internal.CreateInvitationCommand)
 
-func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
-	var cmd internal.Command
-	if err := proto.Unmarshal(l.Data, &cmd); err != nil {
-		panic(fmt.Errorf("cannot marshal command: %x", l.Data))
+	var ii InvitationInfo
+	ii.unmarshal(v.GetInvitation())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateInvitation(ii); err != nil {
+		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// Lock the store.
-	s := (*Store)(fsm)
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (fsm *storeFSM) applyResendInvitationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_ResendInvitationCommand_Command)
+	v := ext.(*internal.ResendInvitationCommand)
 
-	err := func() interface{} {
-		switch cmd.GetType() {
-		case internal.Command_CreateNodeCommand:
-			return fsm.applyCreateNodeCommand(&cmd)
-		case internal.Command_DeleteNodeCommand:
-			return fsm.applyDeleteNodeCommand(&cmd)
-		case internal.Command_CreateDatabaseCommand:
-			return fsm.applyCreateDatabaseCommand(&cmd)
-		case internal.Command_DropDatabaseCommand:
-			return fsm.applyDropDatabaseCommand(&cmd)
-		case internal.Command_CreateRetentionPolicyCommand:
-			return fsm.applyCreateRetentionPolicyCommand(&cmd)
-		case internal.Command_DropRetentionPolicyCommand:
-			return fsm.applyDropRetentionPolicyCommand(&cmd)
-		case internal.Command_SetDefaultRetentionPolicyCommand:
-			return fsm.applySetDefaultRetentionPolicyCommand(&cmd)
-		case internal.Command_UpdateRetentionPolicyCommand:
-			return fsm.applyUpdateRetentionPolicyCommand(&cmd)
-		case internal.Command_CreateShardGroupCommand:
-			return fsm.applyCreateShardGroupCommand(&cmd)
-		case internal.Command_DeleteShardGroupCommand:
-			return fsm.applyDeleteShardGroupCommand(&cmd)
-		// case internal.Command_CreateContinuousQueryCommand:
-		// 	return fsm.applyCreateContinuousQueryCommand(&cmd)
-		// case internal.Command_DropContinuousQueryCommand:
-		// 	return fsm.applyDropContinuousQueryCommand(&cmd)
-		case internal.Command_CreateUserCommand:
-			return fsm.applyCreateUserCommand(&cmd)
-		case internal.Command_DropUserCommand:
-			return fsm.applyDropUserCommand(&cmd)
-		case internal.Command_UpdateUserCommand:
-			return fsm.applyUpdateUserCommand(&cmd)
-		case internal.Command_SetPrivilegeCommand:
-			return fsm.applySetPrivilegeCommand(&cmd)
-		case internal.Command_SetAdminPrivilegeCommand:
-			return fsm.applySetAdminPrivilegeCommand(&cmd)
-		case internal.Command_SetDataCommand:
-			return fsm.applySetDataCommand(&cmd)
-		default:
-			panic(fmt.Errorf("cannot apply command: %x", l.Data))
-		}
-	}()
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.ResendInvitation(v.GetID(), v.GetTokenHash(), UnmarshalTime(v.GetSentAt()), UnmarshalTime(v.GetExpiresAt())); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
 
-	// Copy term and index to new metadata.
-	fsm.data.Term = l.Term
-	fsm.data.Index = l.Index
+func (fsm *storeFSM) applyAcceptInvitationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_AcceptInvitationCommand_Command)
+	v := ext.(*internal.AcceptInvitationCommand)
 
-	return err
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.AcceptInvitation(v.GetID(), v.GetUserID(), UnmarshalTime(v.GetTime())); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
 }
 
-func (fsm *storeFSM) applyCreateNodeCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_CreateNodeCommand_Command)
-	v := ext.(*internal.CreateNodeCommand)
+func (fsm *storeFSM) applyDeleteInvitationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DeleteInvitationCommand_Command)
+	v := ext.(*internal.DeleteInvitationCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.CreateNode(v.GetHost()); err != nil {
+	if err := other.DeleteInvitation(v.GetID()); err != nil {
 		return err
 	}
+	fsm.data = other
+	return nil
+}
 
-	// If the cluster ID hasn't been set then use the command's random number.
-	if other.ClusterID == 0 {
-		other.ClusterID = uint64(v.GetRand())
-	}
+func (fsm *storeFSM) applyCreateTeamCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateTeamCommand_Command)
+	v := ext.(*internal.CreateTeamCommand)
 
+	var ti TeamInfo
+	ti.unmarshal(v.GetTeam())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.CreateTeam(ti); err != nil {
+		return err
+	}
 	fsm.data = other
 	return nil
 }
 
-func (fsm *storeFSM) applyDeleteNodeCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_DeleteNodeCommand_Command)
-	v := ext.(*internal.DeleteNodeCommand)
+func (fsm *storeFSM) applyUpdateTeamCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateTeamCommand_Command)
+	v := ext.(*internal.UpdateTeamCommand)
+
+	var ti TeamInfo
+	ti.unmarshal(v.GetTeam())
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.DeleteNode(v.GetID()); err != nil {
+	if err := other.UpdateTeam(ti); err != nil {
 		return err
 	}
 	fsm.data = other
-
 	return nil
 }
 
-func (fsm *storeFSM) applyCreateDatabaseCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_CreateDatabaseCommand_Command)
-	v := ext.(*internal.CreateDatabaseCommand)
+func (fsm *storeFSM) applyDropTeamCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropTeamCommand_Command)
+	v := ext.(*internal.DropTeamCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.CreateDatabase(v.GetName()); err != nil {
+	if err := other.DropTeam(v.GetID()); err != nil {
 		return err
 	}
 	fsm.data = other
-
 	return nil
 }
 
-func (fsm *storeFSM) applyDropDatabaseCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_DropDatabaseCommand_Command)
-	v := ext.(*internal.DropDatabaseCommand)
+func (fsm *storeFSM) applyAddTeamMemberCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_AddTeamMemberCommand_Command)
+	v := ext.(*internal.AddTeamMemberCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.DropDatabase(v.GetName()); err != nil {
+	if err := other.AddTeamMember(v.GetTeamID(), v.GetUserID(), UnmarshalTime(v.GetTime())); err != nil {
 		return err
 	}
 	fsm.data = other
-
 	return nil
 }
 
-func (fsm *storeFSM) applyCreateRetentionPolicyCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_CreateRetentionPolicyCommand_Command)
-	v := ext.(*internal.CreateRetentionPolicyCommand)
-	pb := v.GetRetentionPolicy()
+func (fsm *storeFSM) applyRemoveTeamMemberCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RemoveTeamMemberCommand_Command)
+	v := ext.(*internal.RemoveTeamMemberCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.CreateRetentionPolicy(v.GetDatabase(),
-		&RetentionPolicyInfo{
-			Name:               pb.GetName(),
-			ReplicaN:           int(pb.GetReplicaN()),
-			Duration:           time.Duration(pb.GetDuration()),
-			ShardGroupDuration: time.Duration(pb.GetShardGroupDuration()),
-		}); err != nil {
+	if err := other.RemoveTeamMember(v.GetTeamID(), v.GetUserID()); err != nil {
 		return err
 	}
 	fsm.data = other
-
 	return nil
 }
 
-func (fsm *storeFSM) applyDropRetentionPolicyCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_DropRetentionPolicyCommand_Command)
-	v := ext.(*internal.DropRetentionPolicyCommand)
+func (fsm *storeFSM) applyGrantTeamConversationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_GrantTeamConversationCommand_Command)
+	v := ext.(*internal.GrantTeamConversationCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.DropRetentionPolicy(v.GetDatabase(), v.GetName()); err != nil {
+	if err := other.GrantTeamConversation(v.GetTeamID(), v.GetConversationID(), UnmarshalTime(v.GetTime())); err != nil {
 		return err
 	}
 	fsm.data = other
-
 	return nil
 }
 
-func (fsm *storeFSM) applySetDefaultRetentionPolicyCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_SetDefaultRetentionPolicyCommand_Command)
-	v := ext.(*internal.SetDefaultRetentionPolicyCommand)
+func (fsm *storeFSM) applyRevokeTeamConversationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RevokeTeamConversationCommand_Command)
+	v := ext.(*internal.RevokeTeamConversationCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.SetDefaultRetentionPolicy(v.GetDatabase(), v.GetName()); err != nil {
+	if err := other.RevokeTeamConversation(v.GetTeamID(), v.GetConversationID()); err != nil {
 		return err
 	}
 	fsm.data = other
-
 	return nil
 }
 
-func (fsm *storeFSM) applyUpdateRetentionPolicyCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_UpdateRetentionPolicyCommand_Command)
-	v := ext.(*internal.UpdateRetentionPolicyCommand)
+func (fsm *storeFSM) applyRevokeTokenCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RevokeTokenCommand_Command)
+	v := ext.(*internal.RevokeTokenCommand)
 
-	// Create update object.
-	rpu := RetentionPolicyUpdate{Name: v.NewName}
-	if v.Duration != nil {
-		value := time.Duration(v.GetDuration())
-		rpu.Duration = &value
-	}
-	if v.ReplicaN != nil {
-		value := int(v.GetReplicaN())
-		rpu.ReplicaN = &value
-	}
+	var ri RevokedTokenInfo
+	ri.unmarshal(v.GetToken())
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.UpdateRetentionPolicy(v.GetDatabase(), v.GetName(), &rpu); err != nil {
+	if err := other.RevokeToken(ri, UnmarshalTime(v.GetTime())); err != nil {
 		return err
 	}
 	fsm.data = other
-
 	return nil
 }
 
-func (fsm *storeFSM) applyCreateShardGroupCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_CreateShardGroupCommand_Command)
-	v := ext.(*internal.CreateShardGroupCommand)
+func (fsm *storeFSM) applyCreateApiTokenCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateApiTokenCommand_Command)
+	v := ext.(*internal.CreateApiTokenCommand)
+
+	var ti ApiTokenInfo
+	ti.unmarshal(v.GetToken())
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.CreateShardGroup(v.GetDatabase(), v.GetPolicy(), time.Unix(0, v.GetTimestamp())); err != nil {
+	if err := other.CreateApiToken(ti); err != nil {
 		return err
 	}
 	fsm.data = other
-
 	return nil
 }
 
-func (fsm *storeFSM) applyDeleteShardGroupCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_DeleteShardGroupCommand_Command)
-	v := ext.(*internal.DeleteShardGroupCommand)
+func (fsm *storeFSM) applyDeleteApiTokenCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DeleteApiTokenCommand_Command)
+	v := ext.(*internal.DeleteApiTokenCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.DeleteShardGroup(v.GetDatabase(), v.GetPolicy(), v.GetShardGroupID()); err != nil {
+	if err := other.DeleteApiToken(v.GetID()); err != nil {
 		return err
 	}
 	fsm.data = other
+	return nil
+}
 
+func (fsm *storeFSM) applyTouchApiTokenCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_TouchApiTokenCommand_Command)
+	v := ext.(*internal.TouchApiTokenCommand)
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.TouchApiToken(v.GetID(), UnmarshalTime(v.GetTime())); err != nil {
+		return err
+	}
+	fsm.data = other
 	return nil
 }
 
-// func (fsm *storeFSM) applyCreateContinuousQueryCommand(cmd *internal.Command) interface{} {
-// 	ext, _ := proto.GetExtension(cmd, internal.E_CreateContinuousQueryCommand_Command)
-// 	v := ext.(*internal.CreateContinuousQueryCommand)
-//
-// 	// Copy data and update.
-// 	other := fsm.data.Clone()
-// 	if err := other.CreateContinuousQuery(v.GetDatabase(), v.GetName(), v.GetQuery()); err != nil {
-// 		return err
-// 	}
-// 	fsm.data = other
-//
-// 	return nil
-// }
-//
-// func (fsm *storeFSM) applyDropContinuousQueryCommand(cmd *internal.Command) interface{} {
-// 	ext, _ := proto.GetExtension(cmd, internal.E_DropContinuousQueryCommand_Command)
-// 	v := ext.(*internal.DropContinuousQueryCommand)
-//
-// 	// Copy data and update.
-// 	other := fsm.data.Clone()
-// 	if err := other.DropContinuousQuery(v.GetDatabase(), v.GetName()); err != nil {
-// 		return err
-// 	}
-// 	fsm.data = other
-//
-// 	return nil
-// }
+func (fsm *storeFSM) applyCreateConversationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_CreateConversationCommand_Command)
+	v := ext.(*internal.CreateConversationCommand)
 
-func (fsm *storeFSM) applyCreateUserCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_CreateUserCommand_Command)
-	v := ext.(*internal.CreateUserCommand)
+	var ci ConversationInfo
+	ci.unmarshal(v.GetConversation())
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.CreateUser(v.GetName(), v.GetHash(), v.GetAdmin()); err != nil {
+	if err := other.CreateConversation(ci); err != nil {
 		return err
 	}
 	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyUpdateConversationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateConversationCommand_Command)
+	v := ext.(*internal.UpdateConversationCommand)
 
+	var ci ConversationInfo
+	ci.unmarshal(v.GetConversation())
+
+	// Copy data and update.
+	other := fsm.data.Clone()
+	if err := other.UpdateConversation(ci); err != nil {
+		return err
+	}
+	fsm.data = other
 	return nil
 }
 
-func (fsm *storeFSM) applyDropUserCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_DropUserCommand_Command)
-	v := ext.(*internal.DropUserCommand)
+func (fsm *storeFSM) applyDropConversationCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DropConversationCommand_Command)
+	v := ext.(*internal.DropConversationCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.DropUser(v.GetName()); err != nil {
+	if err := other.DropConversation(v.GetID()); err != nil {
 		return err
 	}
 	fsm.data = other
-	delete(fsm.authCache, v.GetName())
 	return nil
 }
 
-func (fsm *storeFSM) applyUpdateUserCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_UpdateUserCommand_Command)
-	v := ext.(*internal.UpdateUserCommand)
+func (fsm *storeFSM) applyAddDeviceCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_AddDeviceCommand_Command)
+	v := ext.(*internal.AddDeviceCommand)
+
+	var di DeviceInfo
+	di.unmarshal(v.GetDevice())
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.UpdateUser(v.GetName(), v.GetHash()); err != nil {
+	if err := other.AddDevice(di); err != nil {
 		return err
 	}
 	fsm.data = other
-	delete(fsm.authCache, v.GetName())
 	return nil
 }
 
-func (fsm *storeFSM) applySetPrivilegeCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_SetPrivilegeCommand_Command)
-	v := ext.(*internal.SetPrivilegeCommand)
+func (fsm *storeFSM) applyUpdateDeviceCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateDeviceCommand_Command)
+	v := ext.(*internal.UpdateDeviceCommand)
+
+	var di DeviceInfo
+	di.unmarshal(v.GetDevice())
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.SetPrivilege(v.GetUsername(), v.GetDatabase(), sql.Privilege(v.GetPrivilege())); err != nil {
+	if err := other.UpdateDevice(di); err != nil {
 		return err
 	}
 	fsm.data = other
 	return nil
 }
 
-func (fsm *storeFSM) applySetAdminPrivilegeCommand(cmd *internal.Command) interface{} {
-	ext, _ := proto.GetExtension(cmd, internal.E_SetAdminPrivilegeCommand_Command)
-	v := ext.(*internal.SetAdminPrivilegeCommand)
+func (fsm *storeFSM) applyDeleteDeviceCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_DeleteDeviceCommand_Command)
+	v := ext.(*internal.DeleteDeviceCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
-	if err := other.SetAdminPrivilege(v.GetUsername(), v.GetAdmin()); err != nil {
+	if err := other.DeleteDevice(v.GetID()); err != nil {
 		return err
 	}
 	fsm.data = other
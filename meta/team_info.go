@@ -0,0 +1,102 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/messagedb/messagedb/meta/internal"
+)
+
+// Team types.
+const (
+	TeamTypeOwners = "owners"
+	TeamTypeAdmins = "admins"
+	TeamTypeTeams  = "teams"
+)
+
+// TeamInfo represents a team of members within an organization. The members of a team participate
+// in all the conversations granted to the team.
+type TeamInfo struct {
+	ID             string
+	OrganizationID string
+	Name           string
+	Description    string
+	Type           string
+	Members        []string // user IDs
+	Conversations  []string // IDs of the conversations granted to the team
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// HasMember returns true if the user is a member of the team.
+func (ti *TeamInfo) HasMember(userID string) bool {
+	return contains(ti.Members, userID)
+}
+
+// HasConversation returns true if the conversation is granted to the team.
+func (ti *TeamInfo) HasConversation(conversationID string) bool {
+	return contains(ti.Conversations, conversationID)
+}
+
+// clone returns a deep copy of ti.
+func (ti TeamInfo) clone() TeamInfo {
+	other := ti
+	if ti.Members != nil {
+		other.Members = make([]string, len(ti.Members))
+		copy(other.Members, ti.Members)
+	}
+	if ti.Conversations != nil {
+		other.Conversations = make([]string, len(ti.Conversations))
+		copy(other.Conversations, ti.Conversations)
+	}
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (ti TeamInfo) marshal() *internal.TeamInfo {
+	return &internal.TeamInfo{
+		ID:             proto.String(ti.ID),
+		OrganizationID: proto.String(ti.OrganizationID),
+		Name:           proto.String(ti.Name),
+		Description:    proto.String(ti.Description),
+		Type:           proto.String(ti.Type),
+		Members:        ti.Members,
+		Conversations:  ti.Conversations,
+		CreatedAt:      proto.Int64(MarshalTime(ti.CreatedAt)),
+		UpdatedAt:      proto.Int64(MarshalTime(ti.UpdatedAt)),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ti *TeamInfo) unmarshal(pb *internal.TeamInfo) {
+	ti.ID = pb.GetID()
+	ti.OrganizationID = pb.GetOrganizationID()
+	ti.Name = pb.GetName()
+	ti.Description = pb.GetDescription()
+	ti.Type = pb.GetType()
+	ti.Members = pb.GetMembers()
+	ti.Conversations = pb.GetConversations()
+	ti.CreatedAt = UnmarshalTime(pb.GetCreatedAt())
+	ti.UpdatedAt = UnmarshalTime(pb.GetUpdatedAt())
+}
+
+// contains returns true if a is in the list.
+func contains(list []string, a string) bool {
+	for _, b := range list {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns a copy of the list without a.
+func without(list []string, a string) []string {
+	var other []string
+	for _, b := range list {
+		if b != a {
+			other = append(other, b)
+		}
+	}
+	return other
+}
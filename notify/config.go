@@ -0,0 +1,36 @@
+package notify
+
+const (
+	// SinkSMTP delivers notifications by email through an SMTP server.
+	SinkSMTP = "smtp"
+
+	// SinkFile appends notifications to a local file, for development and tests.
+	SinkFile = "file"
+
+	// SinkLog writes notifications to the log, for local testing.
+	SinkLog = "log"
+
+	// DefaultSMTPAddress is the default address of the SMTP server.
+	DefaultSMTPAddress = "localhost:25"
+
+	// DefaultFrom is the default sender of the notifications.
+	DefaultFrom = "messagedb@localhost"
+)
+
+type Config struct {
+	Enabled      bool   `toml:"enabled"`
+	Sink         string `toml:"sink"`
+	From         string `toml:"from"`
+	SMTPAddress  string `toml:"smtp-address"`
+	SMTPUsername string `toml:"smtp-username"`
+	SMTPPassword string `toml:"smtp-password"`
+	File         string `toml:"file"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Sink:        SinkSMTP,
+		From:        DefaultFrom,
+		SMTPAddress: DefaultSMTPAddress,
+	}
+}
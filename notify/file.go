@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// FileNotifier appends notifications to a local file instead of delivering them. Each message
+// is written as a plain text email followed by an empty line.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+// NewFileNotifier returns a notifier appending messages sent from an address to the file at path.
+func NewFileNotifier(path, from string) *FileNotifier {
+	return &FileNotifier{path: path, from: from}
+}
+
+// Path returns the path of the file messages are appended to.
+func (n *FileNotifier) Path() string { return n.path }
+
+// Notify appends m to the file, creating the file if it doesn't exist.
+func (n *FileNotifier) Notify(m *Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(format(m, n.from, time.Now().UTC()), '\r', '\n')); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,84 @@
+package notify_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/messagedb/messagedb/notify"
+)
+
+// Ensure messages are appended to the file as plain text emails.
+func TestFileNotifier_Notify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notify-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n := notify.NewFileNotifier(filepath.Join(dir, "mail"), "noreply@example.com")
+	if err := n.Notify(&notify.Message{To: "susy@example.com", Subject: "Welcome", Body: "hello\nsusy"}); err != nil {
+		t.Fatal(err)
+	} else if err := n.Notify(&notify.Message{To: "bob@example.com\r\nBcc: eve@example.com", Subject: "Welcome", Body: "hello bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadFile(n.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(buf)
+	if !strings.Contains(s, "From: noreply@example.com\r\nTo: susy@example.com\r\nSubject: Welcome\r\n") {
+		t.Fatalf("unexpected headers: %q", s)
+	} else if !strings.Contains(s, "\r\n\r\nhello\r\nsusy\r\n") {
+		t.Fatalf("unexpected body: %q", s)
+	} else if strings.Contains(s, "\r\nBcc:") {
+		t.Fatalf("unexpected header injection: %q", s)
+	} else if !strings.Contains(s, "hello bob") {
+		t.Fatalf("expected second message: %q", s)
+	}
+}
+
+// Ensure messages are written to the log as plain text emails.
+func TestLogNotifier_Notify(t *testing.T) {
+	var buf bytes.Buffer
+	n := notify.NewLogNotifier("noreply@example.com")
+	n.Logger = log.New(&buf, "", 0)
+
+	if err := n.Notify(&notify.Message{To: "susy@example.com", Subject: "Reset your password", Body: "hello susy"}); err != nil {
+		t.Fatal(err)
+	} else if s := buf.String(); !strings.Contains(s, "To: susy@example.com\r\nSubject: Reset your password\r\n") || !strings.Contains(s, "hello susy") {
+		t.Fatalf("unexpected log: %q", s)
+	}
+}
+
+// Ensure notifications are only enabled with a known sink.
+func TestNew(t *testing.T) {
+	c := notify.NewConfig()
+	if n, err := notify.New(c); err != nil || n != nil {
+		t.Fatalf("unexpected notifier: %#v, %v", n, err)
+	}
+
+	c.Enabled = true
+	if n, err := notify.New(c); err != nil {
+		t.Fatal(err)
+	} else if _, ok := n.(*notify.SMTPNotifier); !ok {
+		t.Fatalf("unexpected notifier: %#v", n)
+	}
+
+	c.Sink = notify.SinkLog
+	if n, err := notify.New(c); err != nil {
+		t.Fatal(err)
+	} else if _, ok := n.(*notify.LogNotifier); !ok {
+		t.Fatalf("unexpected notifier: %#v", n)
+	}
+
+	c.Sink = "pigeon"
+	if _, err := notify.New(c); err != notify.ErrUnknownSink {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
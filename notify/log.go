@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// LogNotifier writes notifications to a logger instead of delivering them, for local testing.
+type LogNotifier struct {
+	Logger *log.Logger
+	from   string
+}
+
+// NewLogNotifier returns a notifier logging messages sent from an address to stderr.
+func NewLogNotifier(from string) *LogNotifier {
+	return &LogNotifier{
+		Logger: log.New(os.Stderr, "[notify] ", log.LstdFlags),
+		from:   from,
+	}
+}
+
+// Notify logs m as a plain text email.
+func (n *LogNotifier) Notify(m *Message) error {
+	n.Logger.Printf("%s", format(m, n.from, time.Now().UTC()))
+	return nil
+}
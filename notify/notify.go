@@ -0,0 +1,62 @@
+// Package notify delivers the notifications sent to users outside of the API, such as the
+// invitations to join an organization or the links resetting a password.
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnknownSink is returned when configuring notifications with a sink other than smtp, file or log.
+var ErrUnknownSink = errors.New("unknown notification sink")
+
+// Message is a notification sent to an email address.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier is the interface of a notification delivery.
+type Notifier interface {
+	// Notify delivers m. It returns once the message is handed over to the sink.
+	Notify(m *Message) error
+}
+
+// New returns the notifier of the sink of c. Returns nil if notifications are disabled.
+func New(c Config) (Notifier, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	switch c.Sink {
+	case SinkSMTP:
+		return NewSMTPNotifier(c.SMTPAddress, c.From, c.SMTPUsername, c.SMTPPassword), nil
+	case SinkFile:
+		return NewFileNotifier(c.File, c.From), nil
+	case SinkLog:
+		return NewLogNotifier(c.From), nil
+	}
+	return nil, ErrUnknownSink
+}
+
+// format returns m as a plain text email sent from an address at t. Line breaks are removed
+// from the headers so that a recipient or a subject cannot add headers of its own.
+func format(m *Message, from string, t time.Time) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&buf, "To: %s\r\n", header.Replace(m.To))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", header.Replace(m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", t.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.Replace(strings.Replace(m.Body, "\r\n", "\n", -1), "\n", "\r\n", -1))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPNotifier delivers notifications by email through an SMTP server.
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPNotifier returns a notifier sending emails from an address through the SMTP server at
+// addr. The server is only authenticated against when a username is given.
+func NewSMTPNotifier(addr, from, username, password string) *SMTPNotifier {
+	n := &SMTPNotifier{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n
+}
+
+// Notify sends m by email.
+func (n *SMTPNotifier) Notify(m *Message) error {
+	return smtp.SendMail(n.addr, n.auth, n.from, []string{m.To}, format(m, n.from, time.Now().UTC()))
+}
@@ -0,0 +1,389 @@
+// Package oidc authenticates users with OpenID Connect identity providers, following the authorization code flow.
+package oidc
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the default time allowed to each request to an identity provider.
+	DefaultTimeout = 10 * time.Second
+
+	// ClockSkew is the difference tolerated between the clocks of the server and of an identity provider.
+	ClockSkew = time.Minute
+
+	// maxResponseSize is the number of bytes read from the responses of an identity provider.
+	maxResponseSize = 1 << 20
+)
+
+var (
+	// ErrIssuerMismatch is returned when the discovery document of a provider is not the one of its issuer.
+	ErrIssuerMismatch = errors.New("issuer mismatch")
+
+	// ErrInvalidIDToken is returned when the ID token of a provider cannot be verified.
+	ErrInvalidIDToken = errors.New("invalid id token")
+
+	// ErrUnknownKey is returned when an ID token is signed with a key the provider does not publish.
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// Config is the registration of the server as a client of an identity provider.
+type Config struct {
+	Name         string // name of the provider in the login URLs
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string // scopes requested in addition to openid, email and profile by default
+}
+
+// Identity is the user an identity provider authenticated, as asserted by its ID token.
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	GivenName         string
+	FamilyName        string
+	PreferredUsername string
+}
+
+// Provider is an OpenID Connect identity provider. Its endpoints and keys are discovered from its issuer on first use.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     map[string]*rsa.PublicKey
+
+	now func() time.Time
+}
+
+// metadata is the part of the discovery document of a provider the authorization code flow relies on.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvider returns a provider for the client registration c. Requests to the provider are made with client, or
+// with a client timing out after DefaultTimeout when nil.
+func NewProvider(c Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"email", "profile"}
+	}
+	c.Issuer = strings.TrimSuffix(c.Issuer, "/")
+	return &Provider{config: c, client: client, now: time.Now}
+}
+
+// Name returns the name of the provider.
+func (p *Provider) Name() string { return p.config.Name }
+
+// Issuer returns the issuer of the provider, which identifies it in the identities it authenticates.
+func (p *Provider) Issuer() string { return p.config.Issuer }
+
+// AuthCodeURL returns the URL of the provider the user is redirected to in order to log in. The provider redirects
+// the user back to the redirect URL with the state and an authorization code, exchanged by Exchange. The nonce is
+// asserted by the ID token issued for the code.
+func (p *Provider) AuthCodeURL(state, nonce string) (string, error) {
+	m, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	scopes := append([]string{"openid"}, p.config.Scopes...)
+	v := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.config.ClientID},
+		"redirect_uri":  {p.config.RedirectURL},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+
+	sep := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return m.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint of the provider, and returns the identity asserted
+// by the ID token issued for it. The ID token must be signed by the provider, issued to this client and carry nonce.
+func (p *Provider) Exchange(code, nonce string) (*Identity, error) {
+	m, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", m.TokenEndpoint, strings.NewReader(url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.config.RedirectURL},
+	}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var resp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.do(req, &resp); err != nil {
+		if resp.Error != "" {
+			return nil, fmt.Errorf("token endpoint: %s %s", resp.Error, resp.ErrorDescription)
+		}
+		return nil, err
+	} else if resp.IDToken == "" {
+		return nil, errors.New("token endpoint: no id token")
+	}
+
+	return p.verify(resp.IDToken, nonce)
+}
+
+// claims are the claims of an ID token.
+type claims struct {
+	Issuer            string      `json:"iss"`
+	Subject           string      `json:"sub"`
+	Audience          audience    `json:"aud"`
+	AuthorizedParty   string      `json:"azp"`
+	Expiry            int64       `json:"exp"`
+	IssuedAt          int64       `json:"iat"`
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"`
+	Name              string      `json:"name"`
+	GivenName         string      `json:"given_name"`
+	FamilyName        string      `json:"family_name"`
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+// audience is the aud claim, either a single client ID or a list of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*a = audience(l)
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, s := range a {
+		if s == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// verify verifies the signature and the claims of an ID token.
+func (p *Provider) verify(token, nonce string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidIDToken
+	} else if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported id token algorithm: %s", header.Alg)
+	}
+
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, h[:], sig); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	now := p.now()
+	switch {
+	case c.Issuer != p.config.Issuer:
+		return nil, fmt.Errorf("id token issued by %s", c.Issuer)
+	case !c.Audience.contains(p.config.ClientID):
+		return nil, errors.New("id token issued to another client")
+	case len(c.Audience) > 1 && c.AuthorizedParty != p.config.ClientID:
+		return nil, errors.New("id token authorized to another client")
+	case c.Subject == "":
+		return nil, errors.New("id token has no subject")
+	case now.Add(-ClockSkew).After(time.Unix(c.Expiry, 0)):
+		return nil, errors.New("id token expired")
+	case c.Nonce != nonce:
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	return &Identity{
+		Issuer:            c.Issuer,
+		Subject:           c.Subject,
+		Email:             c.Email,
+		EmailVerified:     c.EmailVerified == true || c.EmailVerified == "true",
+		Name:              c.Name,
+		GivenName:         c.GivenName,
+		FamilyName:        c.FamilyName,
+		PreferredUsername: c.PreferredUsername,
+	}, nil
+}
+
+// discover returns the metadata of the provider, fetched from its issuer the first time.
+func (p *Provider) discover() (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequest("GET", p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var m metadata
+	if err := p.do(req, &m); err != nil {
+		return nil, fmt.Errorf("discovery: %s", err)
+	} else if strings.TrimSuffix(m.Issuer, "/") != p.config.Issuer {
+		return nil, ErrIssuerMismatch
+	}
+	p.metadata = &m
+	return p.metadata, nil
+}
+
+// key returns the public key the provider signs ID tokens with. The keys of the provider are fetched again when the
+// key is not known, since providers rotate their keys.
+func (p *Provider) key(kid string) (*rsa.PublicKey, error) {
+	m, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+
+	req, err := http.NewRequest("GET", m.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.do(req, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %s", err)
+	}
+
+	p.keys = make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		p.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// lookupKey returns a known key by ID. Tokens without a kid header are verified with the only key of the provider.
+func (p *Provider) lookupKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+// do sends a request to the provider and decodes its JSON response into v. The response is decoded even when the
+// request failed, so that the error returned by the provider can be reported.
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token into v.
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
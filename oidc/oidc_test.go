@@ -0,0 +1,89 @@
+package oidc_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/messagedb/messagedb/oidc"
+	"github.com/messagedb/messagedb/oidc/oidctest"
+)
+
+// Ensure a user can log in with the authorization code flow.
+func TestProvider_Exchange(t *testing.T) {
+	s := oidctest.NewServer("client0", "secret0")
+	defer s.Close()
+
+	s.SetIdentity(oidc.Identity{Subject: "u0", Email: "susy@example.com", EmailVerified: true, PreferredUsername: "susy"})
+	p := oidc.NewProvider(s.Config("corp", "http://localhost:8075/authorize/corp/callback"), nil)
+
+	authURL, err := p.AuthCodeURL("state0", "nonce0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	if q := u.Query(); q.Get("client_id") != "client0" || q.Get("scope") != "openid email profile" || q.Get("nonce") != "nonce0" {
+		t.Fatalf("unexpected authorization url: %s", authURL)
+	}
+
+	code, state, err := s.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	} else if state != "state0" {
+		t.Fatalf("unexpected state: %s", state)
+	}
+
+	identity, err := p.Exchange(code, "nonce0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := oidc.Identity{Issuer: s.Issuer(), Subject: "u0", Email: "susy@example.com", EmailVerified: true, PreferredUsername: "susy"}
+	if *identity != exp {
+		t.Fatalf("unexpected identity: %#v", identity)
+	}
+
+	// Authorization codes can only be exchanged once.
+	if _, err := p.Exchange(code, "nonce0"); err == nil {
+		t.Fatal("expected error exchanging a code twice")
+	}
+}
+
+// Ensure ID tokens issued for another login are rejected.
+func TestProvider_Exchange_NonceMismatch(t *testing.T) {
+	s := oidctest.NewServer("client0", "secret0")
+	defer s.Close()
+
+	p := oidc.NewProvider(s.Config("corp", "http://localhost:8075/authorize/corp/callback"), nil)
+	authURL, err := p.AuthCodeURL("state0", "nonce0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := s.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Exchange(code, "nonce1"); err == nil {
+		t.Fatal("expected nonce mismatch error")
+	}
+}
+
+// Ensure the client credentials are checked by the identity provider.
+func TestProvider_Exchange_InvalidClient(t *testing.T) {
+	s := oidctest.NewServer("client0", "secret0")
+	defer s.Close()
+
+	c := s.Config("corp", "http://localhost:8075/authorize/corp/callback")
+	authURL, err := oidc.NewProvider(c, nil).AuthCodeURL("state0", "nonce0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := s.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.ClientSecret = "wrong"
+	if _, err := oidc.NewProvider(c, nil).Exchange(code, "nonce0"); err == nil {
+		t.Fatal("expected invalid client error")
+	}
+}
@@ -0,0 +1,35 @@
+package attachments
+
+import (
+	"time"
+
+	"github.com/messagedb/messagedb/toml"
+)
+
+const (
+	// DefaultMaxSize is the default maximum size of an uploaded file.
+	DefaultMaxSize = 25 * 1 << 20
+
+	// DefaultCheckInterval is the default time between two collections of unreferenced files.
+	DefaultCheckInterval = 10 * time.Minute
+
+	// DefaultUploadExpiry is the default time an uploaded file is kept before it is sent with a message.
+	DefaultUploadExpiry = 24 * time.Hour
+)
+
+type Config struct {
+	Enabled       bool          `toml:"enabled"`
+	Dir           string        `toml:"dir"`
+	MaxSize       toml.Size     `toml:"max-size"`
+	CheckInterval toml.Duration `toml:"check-interval"`
+	UploadExpiry  toml.Duration `toml:"upload-expiry"`
+}
+
+func NewConfig() Config {
+	return Config{
+		Enabled:       true,
+		MaxSize:       DefaultMaxSize,
+		CheckInterval: toml.Duration(DefaultCheckInterval),
+		UploadExpiry:  toml.Duration(DefaultUploadExpiry),
+	}
+}
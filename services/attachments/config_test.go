@@ -0,0 +1,38 @@
+package attachments_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/services/attachments"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := attachments.NewConfig()
+
+	if _, err := toml.Decode(`
+enabled = true
+dir = "/var/lib/messagedb/attachments"
+max-size = "10m"
+check-interval = "1s"
+upload-expiry = "1h"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if c.Enabled != true {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if c.Dir != "/var/lib/messagedb/attachments" {
+		t.Fatalf("unexpected dir: %s", c.Dir)
+	} else if c.MaxSize != 10*1<<20 {
+		t.Fatalf("unexpected max size: %d", c.MaxSize)
+	} else if time.Duration(c.CheckInterval) != time.Second {
+		t.Fatalf("unexpected check interval: %v", c.CheckInterval)
+	} else if time.Duration(c.UploadExpiry) != time.Hour {
+		t.Fatalf("unexpected upload expiry: %v", c.UploadExpiry)
+	}
+}
@@ -0,0 +1,160 @@
+package attachments
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/messagedb/messagedb/blob"
+	"github.com/messagedb/messagedb/meta"
+)
+
+// Service stores the files attached to messages and removes them once no message
+// references them.
+type Service struct {
+	MetaStore interface {
+		IsLeader() bool
+		DefaultRetentionPolicy(database string) (*meta.RetentionPolicyInfo, error)
+		Attachments(conversationID string) ([]meta.AttachmentInfo, error)
+		DeleteAttachment(id string) error
+	}
+
+	// Database the messages the files are attached to are stored in.
+	Database string
+
+	BlobStore *blob.LocalStore
+
+	maxSize       int64
+	checkInterval time.Duration
+	uploadExpiry  time.Duration
+	wg            sync.WaitGroup
+	done          chan struct{}
+
+	logger *log.Logger
+}
+
+// NewService returns a configured attachments service.
+func NewService(c Config) *Service {
+	return &Service{
+		BlobStore:     blob.NewLocalStore(c.Dir),
+		maxSize:       int64(c.MaxSize),
+		checkInterval: time.Duration(c.CheckInterval),
+		uploadExpiry:  time.Duration(c.UploadExpiry),
+		done:          make(chan struct{}),
+		logger:        log.New(os.Stderr, "[attachments] ", log.LstdFlags),
+	}
+}
+
+// Open opens the blob store and starts the collection of unreferenced files.
+func (s *Service) Open() error {
+	if err := s.BlobStore.Open(); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.collect()
+	return nil
+}
+
+// Close stops the collection of unreferenced files and closes the blob store.
+func (s *Service) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	return s.BlobStore.Close()
+}
+
+// SetLogger sets the internal logger to the logger passed in.
+func (s *Service) SetLogger(l *log.Logger) {
+	s.logger = l
+}
+
+// Logger returns the logger that this service is using
+func (s *Service) Logger() *log.Logger {
+	return s.logger
+}
+
+// MaxSize returns the maximum size of an uploaded file.
+func (s *Service) MaxSize() int64 { return s.maxSize }
+
+func (s *Service) collect() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			s.logger.Println("attachments collection terminating")
+			return
+
+		case <-ticker.C:
+			// Only expire metadata on the leader, but every node removes its own files.
+			if s.MetaStore.IsLeader() {
+				if err := s.DeleteExpiredAttachments(time.Now().UTC()); err != nil {
+					s.logger.Printf("failed to delete expired attachments: %s", err)
+				}
+			}
+			if err := s.DeleteUnreferencedBlobs(time.Now().UTC()); err != nil {
+				s.logger.Printf("failed to delete unreferenced files: %s", err)
+			}
+		}
+	}
+}
+
+// DeleteExpiredAttachments deletes the attachments that were uploaded but never sent with a
+// message, and the attachments of the messages evicted by the retention policy of the database.
+func (s *Service) DeleteExpiredAttachments(now time.Time) error {
+	a, err := s.MetaStore.Attachments("")
+	if err != nil {
+		return err
+	}
+
+	// Messages are evicted with their shard group, which can end up to a shard group
+	// duration after the messages expire.
+	var retention time.Duration
+	if rpi, err := s.MetaStore.DefaultRetentionPolicy(s.Database); err != nil {
+		return err
+	} else if rpi != nil && rpi.Duration > 0 {
+		retention = rpi.Duration + rpi.ShardGroupDuration
+	}
+
+	for _, ai := range a {
+		if ai.MessageID == "" && now.Sub(ai.CreatedAt) < s.uploadExpiry {
+			continue
+		} else if ai.MessageID != "" && (retention == 0 || now.Sub(ai.CreatedAt) < retention) {
+			continue
+		}
+
+		if err := s.MetaStore.DeleteAttachment(ai.ID); err != nil && err != meta.ErrAttachmentNotFound {
+			return err
+		}
+		s.logger.Printf("deleted attachment %s of conversation %s", ai.ID, ai.ConversationID)
+	}
+	return nil
+}
+
+// DeleteUnreferencedBlobs removes the files that no attachment references. Files modified
+// within the upload expiry are kept, as their attachment may not be created yet.
+func (s *Service) DeleteUnreferencedBlobs(now time.Time) error {
+	a, err := s.MetaStore.Attachments("")
+	if err != nil {
+		return err
+	}
+
+	referenced := make(map[string]struct{}, len(a))
+	for _, ai := range a {
+		referenced[ai.SHA256] = struct{}{}
+	}
+
+	return s.BlobStore.Walk(func(digest string, modTime time.Time) error {
+		if _, ok := referenced[digest]; ok || now.Sub(modTime) < s.uploadExpiry {
+			return nil
+		}
+		if err := s.BlobStore.Delete(digest); err != nil {
+			return err
+		}
+		s.logger.Printf("deleted unreferenced file %s", digest)
+		return nil
+	})
+}
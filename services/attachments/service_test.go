@@ -0,0 +1,124 @@
+package attachments_test
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/blob"
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/services/attachments"
+)
+
+// Ensure unsent uploads and attachments of evicted messages are deleted.
+func TestService_DeleteExpiredAttachments(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	now := time.Date(2000, time.January, 2, 0, 0, 0, 0, time.UTC)
+	ms := s.MetaStore.(*MetaStore)
+	ms.attachments = []meta.AttachmentInfo{
+		{ID: "unsent-old", CreatedAt: now.Add(-25 * time.Hour)},
+		{ID: "unsent-new", CreatedAt: now.Add(-time.Hour)},
+		{ID: "sent-old", MessageID: "m0", CreatedAt: now.Add(-9 * 24 * time.Hour)},
+		{ID: "sent-new", MessageID: "m1", CreatedAt: now.Add(-25 * time.Hour)},
+	}
+	ms.rpi = &meta.RetentionPolicyInfo{Duration: 7 * 24 * time.Hour, ShardGroupDuration: 24 * time.Hour}
+
+	if err := s.DeleteExpiredAttachments(now); err != nil {
+		t.Fatal(err)
+	} else if len(ms.attachments) != 2 || ms.attachments[0].ID != "unsent-new" || ms.attachments[1].ID != "sent-new" {
+		t.Fatalf("unexpected attachments: %#v", ms.attachments)
+	}
+}
+
+// Ensure files are removed once no attachment references them.
+func TestService_DeleteUnreferencedBlobs(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	a, err := s.BlobStore.Put(strings.NewReader("a"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.BlobStore.Put(strings.NewReader("b"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms := s.MetaStore.(*MetaStore)
+	ms.attachments = []meta.AttachmentInfo{{ID: "a0", SHA256: a.SHA256}}
+
+	// Recent files are kept as their attachment may not be created yet.
+	if err := s.DeleteUnreferencedBlobs(time.Now()); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.BlobStore.Get(b.SHA256); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.DeleteUnreferencedBlobs(time.Now().Add(48 * time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.BlobStore.Get(a.SHA256); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := s.BlobStore.Get(b.SHA256); err != blob.ErrBlobNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Service is a test wrapper for attachments.Service.
+type Service struct {
+	*attachments.Service
+}
+
+// MustOpenService returns an open service storing files in a temporary directory.
+func MustOpenService() *Service {
+	path, err := ioutil.TempDir("", "messagedb-attachments-")
+	if err != nil {
+		panic(err)
+	}
+
+	c := attachments.NewConfig()
+	c.Dir = path
+	s := &Service{Service: attachments.NewService(c)}
+	s.MetaStore = &MetaStore{}
+	s.SetLogger(log.New(ioutil.Discard, "", 0))
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Close closes the service and removes its files.
+func (s *Service) Close() error {
+	defer os.RemoveAll(s.BlobStore.Path())
+	return s.Service.Close()
+}
+
+// MetaStore is a mockable implementation of attachments.Service.MetaStore.
+type MetaStore struct {
+	attachments []meta.AttachmentInfo
+	rpi         *meta.RetentionPolicyInfo
+}
+
+func (s *MetaStore) IsLeader() bool { return true }
+
+func (s *MetaStore) DefaultRetentionPolicy(database string) (*meta.RetentionPolicyInfo, error) {
+	return s.rpi, nil
+}
+
+func (s *MetaStore) Attachments(conversationID string) ([]meta.AttachmentInfo, error) {
+	return append([]meta.AttachmentInfo(nil), s.attachments...), nil
+}
+
+func (s *MetaStore) DeleteAttachment(id string) error {
+	for i := range s.attachments {
+		if s.attachments[i].ID == id {
+			s.attachments = append(s.attachments[:i], s.attachments[i+1:]...)
+			return nil
+		}
+	}
+	return meta.ErrAttachmentNotFound
+}
@@ -1,5 +1,58 @@
 package httpd
 
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/messagedb/messagedb/toml"
+)
+
+const (
+	// DefaultDatabase is the database the HTTP API stores messages in.
+	DefaultDatabase = "messagedb"
+
+	// DefaultUnfurlCacheSize is the number of link previews kept in memory.
+	DefaultUnfurlCacheSize = 10000
+
+	// DefaultUnfurlCacheTTL is the time a link preview is kept in memory.
+	DefaultUnfurlCacheTTL = time.Hour
+
+	// DefaultInvitationExpiry is the default time an organization invitation can be accepted after it is sent.
+	DefaultInvitationExpiry = 7 * 24 * time.Hour
+
+	// DefaultEmailVerificationExpiry is the default time an email verification link is valid after it is sent.
+	DefaultEmailVerificationExpiry = 24 * time.Hour
+
+	// DefaultPasswordResetExpiry is the default time a password reset link is valid after it is sent.
+	DefaultPasswordResetExpiry = time.Hour
+
+	// DefaultAccessTokenExpiry is the default time an access token is valid after it is issued.
+	DefaultAccessTokenExpiry = 2 * time.Hour
+
+	// DefaultRefreshTokenExpiry is the default time a refresh token is valid after it is issued.
+	DefaultRefreshTokenExpiry = 14 * 24 * time.Hour
+
+	// DefaultApiTokenRateLimit is the default number of requests per minute allowed to an API token.
+	DefaultApiTokenRateLimit = 600
+)
+
+// JWTKey is a secret the authentication tokens are signed with, identified by the kid header of the tokens.
+type JWTKey struct {
+	ID     string `toml:"id"`
+	Secret string `toml:"secret"`
+}
+
+// OIDCProvider is an OpenID Connect identity provider users can log in with, at /authorize/<name>.
+type OIDCProvider struct {
+	Name         string   `toml:"name"`
+	Issuer       string   `toml:"issuer"`
+	ClientID     string   `toml:"client-id"`
+	ClientSecret string   `toml:"client-secret"`
+	RedirectURL  string   `toml:"redirect-url"`
+	Scopes       []string `toml:"scopes"`
+}
+
 type Config struct {
 	Enabled        bool   `toml:"enabled"`
 	BindAddress    string `toml:"bind-address"`
@@ -9,6 +62,29 @@ type Config struct {
 	LogEnabled     bool   `toml:"log-enabled"`
 	WriteTracing   bool   `toml:"write-tracing"`
 	PprofEnabled   bool   `toml:"pprof-enabled"`
+	Database       string `toml:"database"`
+	UnfurlEnabled  bool   `toml:"unfurl-enabled"`
+
+	InvitationExpiry toml.Duration `toml:"invitation-expiry"`
+	InvitationURL    string        `toml:"invitation-url"`
+
+	EmailVerificationExpiry toml.Duration `toml:"email-verification-expiry"`
+	EmailVerificationURL    string        `toml:"email-verification-url"`
+	PasswordResetExpiry     toml.Duration `toml:"password-reset-expiry"`
+	PasswordResetURL        string        `toml:"password-reset-url"`
+
+	// JWTSigningKey is the ID of the key new tokens are signed with, the tokens signed with any of the
+	// JWTKeys remain valid so that keys can be rotated. It can be omitted when a single key is configured.
+	JWTSigningKey      string        `toml:"jwt-signing-key"`
+	JWTKeys            []JWTKey      `toml:"jwt-keys"`
+	AccessTokenExpiry  toml.Duration `toml:"access-token-expiry"`
+	RefreshTokenExpiry toml.Duration `toml:"refresh-token-expiry"`
+
+	// ApiTokenRateLimit is the number of requests per minute allowed to the API tokens created without a
+	// limit of their own. Zero disables the limit.
+	ApiTokenRateLimit int64 `toml:"api-token-rate-limit"`
+
+	OIDCProviders []OIDCProvider `toml:"oidc-providers"`
 }
 
 func NewConfig() Config {
@@ -17,5 +93,60 @@ func NewConfig() Config {
 		BindAddress:    ":8075",
 		LogEnabled:     true,
 		MaxConnections: 5000,
+		Database:       DefaultDatabase,
+
+		InvitationExpiry: toml.Duration(DefaultInvitationExpiry),
+
+		EmailVerificationExpiry: toml.Duration(DefaultEmailVerificationExpiry),
+		PasswordResetExpiry:     toml.Duration(DefaultPasswordResetExpiry),
+
+		AccessTokenExpiry:  toml.Duration(DefaultAccessTokenExpiry),
+		RefreshTokenExpiry: toml.Duration(DefaultRefreshTokenExpiry),
+
+		ApiTokenRateLimit: DefaultApiTokenRateLimit,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	ids := make(map[string]bool)
+	for _, k := range c.JWTKeys {
+		if k.ID == "" {
+			return errors.New("jwt-keys: id must be specified")
+		} else if k.Secret == "" {
+			return fmt.Errorf("jwt-keys: secret of key %s must be specified", k.ID)
+		} else if ids[k.ID] {
+			return fmt.Errorf("jwt-keys: duplicate key %s", k.ID)
+		}
+		ids[k.ID] = true
+	}
+
+	if len(c.JWTKeys) > 0 && !ids[c.signingKeyID()] {
+		return fmt.Errorf("jwt-signing-key: key %q not found in jwt-keys", c.JWTSigningKey)
+	}
+
+	names := make(map[string]bool)
+	for _, p := range c.OIDCProviders {
+		if p.Name == "" {
+			return errors.New("oidc-providers: name must be specified")
+		} else if p.Issuer == "" {
+			return fmt.Errorf("oidc-providers: issuer of provider %s must be specified", p.Name)
+		} else if p.ClientID == "" {
+			return fmt.Errorf("oidc-providers: client-id of provider %s must be specified", p.Name)
+		} else if p.RedirectURL == "" {
+			return fmt.Errorf("oidc-providers: redirect-url of provider %s must be specified", p.Name)
+		} else if names[p.Name] {
+			return fmt.Errorf("oidc-providers: duplicate provider %s", p.Name)
+		}
+		names[p.Name] = true
+	}
+	return nil
+}
+
+// signingKeyID returns the ID of the key new tokens are signed with.
+func (c Config) signingKeyID() string {
+	if c.JWTSigningKey == "" && len(c.JWTKeys) == 1 {
+		return c.JWTKeys[0].ID
 	}
+	return c.JWTSigningKey
 }
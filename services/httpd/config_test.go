@@ -2,6 +2,7 @@ package httpd_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/messagedb/messagedb/services/httpd"
 
@@ -41,6 +42,117 @@ max-connections = 5000
 	}
 }
 
+func TestConfig_JWTKeys(t *testing.T) {
+	var c httpd.Config
+	if _, err := toml.Decode(`
+jwt-signing-key = "k2"
+access-token-expiry = "1h"
+refresh-token-expiry = "48h"
+
+[[jwt-keys]]
+id = "k1"
+secret = "s1"
+
+[[jwt-keys]]
+id = "k2"
+secret = "s2"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.JWTSigningKey != "k2" {
+		t.Fatalf("unexpected signing key: %s", c.JWTSigningKey)
+	} else if len(c.JWTKeys) != 2 || c.JWTKeys[1] != (httpd.JWTKey{ID: "k2", Secret: "s2"}) {
+		t.Fatalf("unexpected keys: %#v", c.JWTKeys)
+	} else if time.Duration(c.AccessTokenExpiry) != time.Hour {
+		t.Fatalf("unexpected access token expiry: %v", c.AccessTokenExpiry)
+	} else if time.Duration(c.RefreshTokenExpiry) != 48*time.Hour {
+		t.Fatalf("unexpected refresh token expiry: %v", c.RefreshTokenExpiry)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signing key must be one of the keys.
+	c.JWTSigningKey = "k3"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected unknown signing key to be invalid")
+	}
+
+	// A single key signs the tokens without naming it.
+	c.JWTSigningKey, c.JWTKeys = "", c.JWTKeys[:1]
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.JWTKeys = append(c.JWTKeys, httpd.JWTKey{ID: "k1", Secret: "s3"})
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected duplicate keys to be invalid")
+	}
+}
+
+func TestConfig_EmailLinks(t *testing.T) {
+	c := httpd.NewConfig()
+	if time.Duration(c.EmailVerificationExpiry) != httpd.DefaultEmailVerificationExpiry {
+		t.Fatalf("unexpected default email verification expiry: %v", c.EmailVerificationExpiry)
+	} else if time.Duration(c.PasswordResetExpiry) != httpd.DefaultPasswordResetExpiry {
+		t.Fatalf("unexpected default password reset expiry: %v", c.PasswordResetExpiry)
+	}
+
+	if _, err := toml.Decode(`
+email-verification-expiry = "48h"
+email-verification-url = "https://messagedb.example.com/verify"
+password-reset-expiry = "30m"
+password-reset-url = "https://messagedb.example.com/reset"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if time.Duration(c.EmailVerificationExpiry) != 48*time.Hour {
+		t.Fatalf("unexpected email verification expiry: %v", c.EmailVerificationExpiry)
+	} else if c.EmailVerificationURL != "https://messagedb.example.com/verify" {
+		t.Fatalf("unexpected email verification url: %s", c.EmailVerificationURL)
+	} else if time.Duration(c.PasswordResetExpiry) != 30*time.Minute {
+		t.Fatalf("unexpected password reset expiry: %v", c.PasswordResetExpiry)
+	} else if c.PasswordResetURL != "https://messagedb.example.com/reset" {
+		t.Fatalf("unexpected password reset url: %s", c.PasswordResetURL)
+	}
+}
+
+func TestConfig_OIDCProviders(t *testing.T) {
+	var c httpd.Config
+	if _, err := toml.Decode(`
+[[oidc-providers]]
+name = "corp"
+issuer = "https://accounts.example.com"
+client-id = "c0"
+client-secret = "s0"
+redirect-url = "https://messagedb.example.com/authorize/corp/callback"
+scopes = ["email"]
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.OIDCProviders) != 1 {
+		t.Fatalf("unexpected providers: %#v", c.OIDCProviders)
+	} else if p := c.OIDCProviders[0]; p.Name != "corp" || p.Issuer != "https://accounts.example.com" || p.ClientID != "c0" || p.ClientSecret != "s0" {
+		t.Fatalf("unexpected provider: %#v", p)
+	} else if p.RedirectURL != "https://messagedb.example.com/authorize/corp/callback" || len(p.Scopes) != 1 || p.Scopes[0] != "email" {
+		t.Fatalf("unexpected provider: %#v", p)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.OIDCProviders = append(c.OIDCProviders, c.OIDCProviders[0])
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected duplicate providers to be invalid")
+	}
+
+	c.OIDCProviders = []httpd.OIDCProvider{{Name: "corp", Issuer: "https://accounts.example.com", ClientID: "c0"}}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected provider without redirect url to be invalid")
+	}
+}
+
 func TestConfig_WriteTracing(t *testing.T) {
 	c := httpd.Config{WriteTracing: true}
 	s := httpd.NewService(c)
@@ -3,6 +3,7 @@ package controllers
 import (
 	"errors"
 
+	"github.com/messagedb/messagedb/db"
 	"github.com/messagedb/messagedb/meta/schema"
 
 	"github.com/gin-gonic/gin"
@@ -43,3 +44,45 @@ func getConversationFromContext(ctx *gin.Context) *schema.Conversation {
 	}
 	return conv
 }
+
+func getDeviceFromContext(ctx *gin.Context) *schema.Device {
+	device, ok := ctx.MustGet("device").(*schema.Device)
+	if !ok {
+		panic("Device has wrong type of object")
+	}
+	return device
+}
+
+func getMessageFromContext(ctx *gin.Context) db.Message {
+	message, ok := ctx.MustGet("message").(db.Message)
+	if !ok {
+		panic("Message has wrong type of object")
+	}
+	return message
+}
+
+func getTeamFromContext(ctx *gin.Context) *schema.Team {
+	team, ok := ctx.MustGet("team").(*schema.Team)
+	if !ok {
+		panic("Team has wrong type of object")
+	}
+	return team
+}
+
+func getApiTokenFromContext(ctx *gin.Context) *schema.ApiToken {
+	token, ok := ctx.MustGet("token").(*schema.ApiToken)
+	if !ok {
+		panic("Token has wrong type of object")
+	}
+	return token
+}
+
+// getApiToken returns the API token the request was authenticated with, or nil when it was authenticated with an
+// access token.
+func getApiToken(ctx *gin.Context) *schema.ApiToken {
+	token, ok := ctx.Get("apiToken")
+	if !ok {
+		return nil
+	}
+	return token.(*schema.ApiToken)
+}
@@ -1,14 +1,42 @@
 package controllers
 
 import (
+	"fmt"
+	"io"
 	"log"
-
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/messagedb/messagedb/blob"
+	"github.com/messagedb/messagedb/cluster"
+	"github.com/messagedb/messagedb/db"
 	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/policy"
+	"github.com/messagedb/messagedb/meta/schema"
 	"github.com/messagedb/messagedb/meta/services"
 	"github.com/messagedb/messagedb/services/httpd/helpers"
 	"github.com/messagedb/messagedb/services/httpd/presenters"
+	"github.com/messagedb/messagedb/sql"
+	"github.com/messagedb/messagedb/unfurl"
 
 	"github.com/gin-gonic/gin"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// attachmentFormField is the name of the multipart form field holding the uploaded file
+	attachmentFormField = "file"
+
+	// maxAttachmentFormOverhead is the size allowed for the multipart headers of an upload on top of the file
+	maxAttachmentFormOverhead = 1 << 20
+
+	// maxConcurrentUnfurls is the number of links of a page unfurled at the same time
+	maxConcurrentUnfurls = 8
 )
 
 // ConversationsController handles RESTful API requests for Conversation resources
@@ -17,11 +45,46 @@ type ConversationsController struct {
 
 	MetaStore interface {
 		Database(name string) (*meta.DatabaseInfo, error)
+		CreateDatabaseIfNotExists(name string) (*meta.DatabaseInfo, error)
 		Authenticate(username, password string) (ui *meta.UserInfo, err error)
 		Users() ([]meta.UserInfo, error)
 		// Conversations() ([]meta.ConversationInfo, error)
+		Attachment(id string) (*meta.AttachmentInfo, error)
+		Attachments(conversationID string) ([]meta.AttachmentInfo, error)
+		CreateAttachment(ai meta.AttachmentInfo) error
+		Participant(conversationID, userID string) (*meta.ParticipantInfo, error)
+		Participants(conversationID string) ([]meta.ParticipantInfo, error)
+		AddParticipant(conversationID, userID string, t time.Time) error
+		RemoveParticipant(conversationID, userID string) error
+		SetParticipantActivity(conversationID, userID string, t time.Time) error
+	}
+
+	// MessagesWriter writes the system messages recording participants joining and leaving
+	MessagesWriter interface {
+		WriteMessages(p *cluster.WriteMessagesRequest) error
 	}
 
+	// BlobStore stores the content of the files attached to messages
+	BlobStore blob.Store
+
+	// MaxAttachmentSize is the maximum size of an uploaded file, unlimited when zero
+	MaxAttachmentSize int64
+
+	// Participants reports whether a user participates in a conversation
+	Participants interface {
+		IsParticipant(conversationID, userID string) (bool, error)
+	}
+
+	QueryExecutor interface {
+		ExecuteQuery(q *sql.Query, db string, chunkSize int) (<-chan *sql.Result, error)
+	}
+
+	// LinkFetcher fetches the previews of links, links are not unfurled when nil
+	LinkFetcher unfurl.Fetcher
+
+	// Database is the database messages are queried from
+	Database string
+
 	Logger         *log.Logger
 	loggingEnabled bool // Log every HTTP access
 	WriteTrace     bool // Detail logging of controller handler
@@ -44,35 +107,40 @@ func (c *ConversationsController) registerRoutes() error {
 		router.GET("/conversations", c.ListPublicConversations)
 
 		convRouter := router.Group("/")
-		convRouter.Use(ConversationFilter())
-
-		convRouter.GET("/conversations/:conversation_id", c.GetConversation)
-		convRouter.PATCH("/conversations/:conversation_id", c.EditConversation)
-		convRouter.DELETE("/conversations/:conversation_id", c.DeleteConversation)
-
-		convRouter.GET("/conversations/:conversation_id/attachments", c.ListAttachments)
-		convRouter.POST("/conversations/:conversation_id/attachments", c.AddAttachment)
-
-		convRouter.GET("/conversations/:conversation_id/links", c.ListLinks)
-		convRouter.GET("/conversations/:conversation_id/snippets", c.ListSnippets)
+		convRouter.Use(AuthenticatedFilter(), ConversationFilter())
 
-		convRouter.GET("/conversations/:conversation_id/participants", c.ListParticipants)
-		convRouter.GET("/conversations/:conversation_id/participants/:username", UsernameFilter(), c.CheckParticipant)
-		convRouter.PUT("/conversations/:conversation_id/participants/:username", UsernameFilter(), c.AddParticipant)
-		convRouter.DELETE("/conversations/:conversation_id/participants/:username", UsernameFilter(), c.RemoveParticipant)
-
-		convRouter.POST("/conversations/:conversation_id/pulses", c.AddPulse)
-
-		convRouter.GET("/conversations/:conversation_id/integrations", c.ListIntegrations)
+		convRouter.GET("/conversations/:conversation_id/integrations", ConversationAccessFilter(c.isParticipant, false), c.ListIntegrations)
 
 		intRouter := convRouter.Group("/")
-		intRouter.Use(IntegrationFilter())
+		intRouter.Use(PolicyFilter(policy.ManageIntegrations, c.isParticipant), IntegrationFilter())
 		{
 			intRouter.GET("/conversations/:conversation_id/integrations/:name", c.AddIntegration)
 			intRouter.PATCH("/conversations/:conversation_id/integrations/:name", c.EditIntegration)
 			intRouter.DELETE("/conversations/:conversation_id/integrations/:name", c.RemoveIntegration)
 		}
 
+		authRouter := router.Group("/conversations/:conversation_id")
+		authRouter.Use(AuthenticatedFilter(), ConversationFilter())
+		{
+			authRouter.GET("", ConversationAccessFilter(c.isParticipant, false), c.GetConversation)
+			authRouter.PATCH("", c.EditConversation)
+			authRouter.DELETE("", c.DeleteConversation)
+
+			authRouter.GET("/attachments", ConversationAccessFilter(c.isParticipant, false), c.ListAttachments)
+			authRouter.POST("/attachments", ConversationAccessFilter(c.isParticipant, true), c.AddAttachment)
+			authRouter.GET("/attachments/:attachment_id", ConversationAccessFilter(c.isParticipant, false), c.GetAttachment)
+			authRouter.GET("/attachments/:attachment_id/download", ConversationAccessFilter(c.isParticipant, false), c.DownloadAttachment)
+
+			authRouter.GET("/links", ConversationAccessFilter(c.isParticipant, false), c.ListLinks)
+
+			authRouter.GET("/participants", ConversationAccessFilter(c.isParticipant, false), c.ListParticipants)
+			authRouter.GET("/participants/:username", ConversationAccessFilter(c.isParticipant, false), UsernameFilter(), c.CheckParticipant)
+			authRouter.PUT("/participants/:username", UsernameFilter(), c.AddParticipant)
+			authRouter.DELETE("/participants/:username", UsernameFilter(), c.RemoveParticipant)
+
+			authRouter.POST("/pulses", ConversationAccessFilter(c.isParticipant, true), c.AddPulse)
+		}
+
 	}
 
 	return nil
@@ -135,76 +203,456 @@ func (c *ConversationsController) DeleteConversation(ctx *gin.Context) {
 	helpers.JSONResponseNotImplemented(ctx)
 }
 
-// ListAttachments lists all conversation attachments
+// ListAttachments lists the attachments of the messages of a conversation, oldest first. Files uploaded but not yet
+// sent with a message are not listed.
 //
 // GET /conversations/:id/attachments
 //
 func (c *ConversationsController) ListAttachments(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	conversation := getConversationFromContext(ctx)
+
+	attachments, err := c.MetaStore.Attachments(conversation.ID.Hex())
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	sent := []meta.AttachmentInfo{}
+	for _, ai := range attachments {
+		if ai.MessageID != "" {
+			sent = append(sent, ai)
+		}
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.AttachmentCollectionPresenter(sent))
 }
 
-// AddAttachment adds attachment to conversation
+// AddAttachment uploads a file to a conversation. The file is sent as the "file" field of a multipart form, and is
+// attached to a message by listing the ID of the attachment in the attachments of the message when it is sent.
+// Files that are not sent with a message are removed after a while.
 //
 // POST /conversations/:id/attachments
 //
 func (c *ConversationsController) AddAttachment(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	if c.BlobStore == nil {
+		helpers.JSONResponseNotImplemented(ctx)
+		return
+	}
+
+	conversation := getConversationFromContext(ctx)
+	user := getCurrentUser(ctx)
+
+	if c.MaxAttachmentSize > 0 {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.MaxAttachmentSize+maxAttachmentFormOverhead)
+	}
+
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "Expected a multipart form: %s", err)
+		return
+	}
+
+	// skip the fields preceding the file
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Missing form field: %s", attachmentFormField)
+			return
+		} else if err != nil {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid multipart form: %s", err)
+			return
+		}
+		if part.FormName() != attachmentFormField {
+			continue
+		}
+
+		filename := attachmentFilename(part.FileName())
+		if filename == "" {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Missing filename")
+			return
+		}
+
+		info, err := c.BlobStore.Put(part, c.MaxAttachmentSize)
+		if err == blob.ErrTooLarge {
+			helpers.JSONErrorf(ctx, http.StatusRequestEntityTooLarge, "File exceeds the maximum size of %d bytes", c.MaxAttachmentSize)
+			return
+		} else if err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		}
+
+		ai := meta.AttachmentInfo{
+			ID:             uuid.NewV4().String(),
+			ConversationID: conversation.ID.Hex(),
+			UploaderID:     user.ID.Hex(),
+			Filename:       filename,
+			ContentType:    attachmentContentType(part.Header.Get("Content-Type"), filename),
+			Size:           info.Size,
+			MD5:            info.MD5,
+			SHA256:         info.SHA256,
+			CreatedAt:      time.Now().UTC(),
+		}
+		if err := c.MetaStore.CreateAttachment(ai); err != nil {
+			if c.WriteTrace {
+				c.Logger.Printf("Failed to create attachment in conversation %s: %v", ai.ConversationID, err)
+			}
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		}
+
+		presenter := presenters.AttachmentPresenter(&ai)
+		if uri := presenter.GetLocation(); uri != nil {
+			ctx.Header(helpers.LocationHeaderKey, uri.String())
+		}
+		helpers.JSONResponse(ctx, http.StatusCreated, presenter)
+		return
+	}
 }
 
-// ListLinks lists all conversation links
+// GetAttachment returns the metadata of an attachment
 //
-// GET /conversations/:id/links
+// GET /conversations/:id/attachments/:attachment_id
 //
-func (c *ConversationsController) ListLinks(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+func (c *ConversationsController) GetAttachment(ctx *gin.Context) {
+	ai, ok := c.findAttachment(ctx)
+	if !ok {
+		return
+	}
+	helpers.JSONResponseObject(ctx, presenters.AttachmentPresenter(ai))
 }
 
-// ListPosts lists all conversation posts
+// DownloadAttachment returns the content of an attachment. Range requests are supported so that large files can be
+// downloaded in parts, and the SHA256 of the content is used as the entity tag.
 //
-// GET /conversations/:id/posts
+// GET /conversations/:id/attachments/:attachment_id/download
 //
-func (c *ConversationsController) ListPosts(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+func (c *ConversationsController) DownloadAttachment(ctx *gin.Context) {
+	if c.BlobStore == nil {
+		helpers.JSONResponseNotImplemented(ctx)
+		return
+	}
+
+	ai, ok := c.findAttachment(ctx)
+	if !ok {
+		return
+	}
+
+	f, err := c.BlobStore.Get(ai.SHA256)
+	if err == blob.ErrBlobNotFound {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "Attachment content not found: %s", ai.ID)
+		return
+	} else if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+	defer f.Close()
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", ai.ContentType)
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": ai.Filename}))
+	header.Set("ETag", fmt.Sprintf("%q", ai.SHA256))
+	header.Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(ctx.Writer, ctx.Request, "", ai.CreatedAt, f)
+}
+
+// findAttachment returns the attachment of the conversation with the ID of the URL, or responds with an error
+func (c *ConversationsController) findAttachment(ctx *gin.Context) (*meta.AttachmentInfo, bool) {
+	conversation := getConversationFromContext(ctx)
+
+	ai, err := c.MetaStore.Attachment(ctx.Param("attachment_id"))
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return nil, false
+	}
+
+	// unsent files are only visible to their uploader
+	if ai == nil || ai.ConversationID != conversation.ID.Hex() || (ai.MessageID == "" && ai.UploaderID != getCurrentUser(ctx).ID.Hex()) {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "Attachment not found: %s", ctx.Param("attachment_id"))
+		return nil, false
+	}
+	return ai, true
+}
+
+// queryLinks executes a SHOW LINKS statement and returns the links of the result
+func queryLinks(executor queryExecutor, database string, stmt *sql.ShowLinksStatement) (db.Links, error) {
+	results, err := executor.ExecuteQuery(&sql.Query{Statements: sql.Statements{stmt}}, database, db.IgnoredChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// the results channel is always drained so the executor can finish
+	var links db.Links
+	for result := range results {
+		if result.Err != nil {
+			if err == nil {
+				err = result.Err
+			}
+			continue
+		}
+		for _, row := range result.Rows {
+			for _, values := range row.Values {
+				link := &db.Link{}
+				for i, column := range row.Columns {
+					switch column {
+					case "time":
+						t, _ := values[i].(time.Time)
+						link.Timestamp = t.UnixNano()
+					case "url":
+						link.URL, _ = values[i].(string)
+					case "message_id":
+						link.MessageID, _ = values[i].(string)
+					case "from":
+						link.SenderID, _ = values[i].(string)
+					case "from_name":
+						link.SenderName, _ = values[i].(string)
+					}
+				}
+				links = append(links, link)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// unfurlLinks sets the title and description of the links whose target could be fetched
+func unfurlLinks(fetcher unfurl.Fetcher, links []*presenters.Link) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentUnfurls)
+	for _, link := range links {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(link *presenters.Link) {
+			defer func() { <-sem; wg.Done() }()
+			if m, err := fetcher.Fetch(link.URL); err == nil && m != nil {
+				link.Title, link.Description = m.Title, m.Description
+			}
+		}(link)
+	}
+	wg.Wait()
+}
+
+// isParticipant returns true if the user participates in the conversation
+func (c *ConversationsController) isParticipant(conversation *schema.Conversation, user *schema.User) (bool, error) {
+	if c.Participants == nil {
+		return false, nil
+	}
+	return c.Participants.IsParticipant(conversation.ID.Hex(), user.ID.Hex())
+}
+
+// attachmentFilename returns the base name of the filename of an upload, or an empty string if it has none
+func attachmentFilename(filename string) string {
+	// browsers on Windows may send the full path of the file
+	filename = path.Base(path.Clean("/" + strings.Replace(filename, `\`, "/", -1)))
+	if filename == "/" {
+		return ""
+	}
+	return filename
+}
+
+// attachmentContentType returns the content type of an upload, guessed from the extension of its filename when the
+// client does not provide it
+func attachmentContentType(contentType, filename string) string {
+	if _, _, err := mime.ParseMediaType(contentType); err == nil && contentType != "" {
+		return contentType
+	}
+	if t := mime.TypeByExtension(filepath.Ext(filename)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// ListLinks returns a page of the links shared in the messages of a conversation, newest first. The links of a
+// message are never split across pages, so a page may hold a few more links than requested. Older pages are selected
+// with the before cursor returned by the previous page. With unfurl=true, the links include the title and description
+// of their target when the server is configured to fetch them.
+//
+// GET /conversations/:id/links?before=<cursor>&per_page=50&unfurl=true
+//
+func (c *ConversationsController) ListLinks(ctx *gin.Context) {
+	conversation := getConversationFromContext(ctx)
+
+	perPage, err := queryInt(ctx, "per_page", DefaultMessagesPerPage)
+	if err != nil || perPage < 1 || perPage > MaxMessagesPerPage {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid per_page: %s", ctx.Query("per_page"))
+		return
+	}
+
+	stmt := &sql.ShowLinksStatement{Name: conversation.ID.Hex(), Limit: perPage}
+	if s := ctx.Query("before"); s != "" {
+		if stmt.Before, err = sql.ParseCursor(s); err != nil {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid before: %s", s)
+			return
+		}
+	}
+
+	if _, err := c.MetaStore.CreateDatabaseIfNotExists(c.Database); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+	links, err := queryLinks(c.QueryExecutor, c.Database, stmt)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	collection := presenters.LinkCollectionPresenter(stmt.Name, links)
+	if c.LinkFetcher != nil && ctx.Query("unfurl") == "true" {
+		unfurlLinks(c.LinkFetcher, collection)
+	}
+
+	cursors := &presenters.Cursors{}
+	if len(links) >= perPage {
+		cursors.Prev = sql.NewCursor(time.Unix(0, links[len(links)-1].Timestamp)).String()
+	}
+
+	helpers.JSONResponsePage(ctx, collection, cursors)
 }
 
-// ListSnippets lists all conversation snippets
+// ListPosts lists all conversation posts
 //
-// GET /conversations/:id/snippets
+// GET /conversations/:id/posts
 //
-func (c *ConversationsController) ListSnippets(ctx *gin.Context) {
+func (c *ConversationsController) ListPosts(ctx *gin.Context) {
 	helpers.JSONResponseNotImplemented(ctx)
 }
 
-// ListParticipants lists all conversation snippets
+// ListParticipants lists the participants of a conversation, in the order they joined
 //
 // GET /conversations/:id/participants
 //
 func (c *ConversationsController) ListParticipants(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	conversation := getConversationFromContext(ctx)
+
+	participants, err := c.MetaStore.Participants(conversation.ID.Hex())
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.ParticipantCollectionPresenter(participants))
 }
 
-// CheckParticipant checks if user is a participant of a conversation
+// CheckParticipant checks if user is a participant of a conversation, responding with the participant or with a
+// 404 Not Found status when the user does not participate
 //
 // GET /conversations/:id/participants/:username
 //
 func (c *ConversationsController) CheckParticipant(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	conversation := getConversationFromContext(ctx)
+	user := getUserFromContext(ctx)
+
+	pi, err := c.MetaStore.Participant(conversation.ID.Hex(), user.ID.Hex())
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	} else if pi == nil {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "Participant not found: %s", user.Username)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.ParticipantPresenter(pi))
 }
 
-// AddParticipant adds user as a participant in this conversation
+// AddParticipant adds user as a participant in this conversation, or joins the conversation when user is the current
+// user. Who can add participants depends on the privacy of the conversation, see canAddParticipant. The change is
+// recorded as a system message in the conversation. Adding a user that already participates has no effect.
 //
 // PUT /conversations/:id/participants/:username
 //
 func (c *ConversationsController) AddParticipant(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	conversation := getConversationFromContext(ctx)
+	currentUser := getCurrentUser(ctx)
+	user := getUserFromContext(ctx)
+	conversationID, userID := conversation.ID.Hex(), user.ID.Hex()
+
+	pi, err := c.MetaStore.Participant(conversationID, userID)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	} else if pi != nil {
+		helpers.JSONResponseObject(ctx, presenters.ParticipantPresenter(pi))
+		return
+	}
+
+	ok, err := c.isParticipant(conversation, currentUser)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+	// guests are restricted to the conversations shared with them, they cannot join on their own
+	if currentUser.ID == user.ID && !authorize(ctx, policy.JoinConversation, policy.Resource{Conversation: conversation}, nil) {
+		return
+	}
+	if !canAddParticipant(conversation, currentUser.ID.Hex(), userID, ok) {
+		// secret conversations are not revealed to the users outside of them
+		if !ok && conversation.Privacy == schema.PrivacySecret {
+			helpers.JSONErrorf(ctx, http.StatusNotFound, "Conversation not found")
+			return
+		}
+		helpers.JSONForbidden(ctx, "Participants cannot be added to this conversation by the authenticated user")
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := c.MetaStore.AddParticipant(conversationID, userID, now); err != nil && err != meta.ErrParticipantExists {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+	if err := c.writeParticipantEvent(conversation, currentUser, user, db.EventParticipantJoined, now); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	if pi, err = c.MetaStore.Participant(conversationID, userID); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	} else if pi == nil {
+		helpers.JSONResponseInternalServerError(ctx, meta.ErrParticipantNotFound)
+		return
+	}
+
+	presenter := presenters.ParticipantPresenter(pi)
+	if uri := presenter.GetLocation(); uri != nil {
+		ctx.Header(helpers.LocationHeaderKey, uri.String())
+	}
+	helpers.JSONResponse(ctx, http.StatusCreated, presenter)
 }
 
-// RemoveParticipant removes user as a participant from this conversation
+// RemoveParticipant removes user as a participant from this conversation, or leaves the conversation when user is
+// the current user. Only the creator of a conversation can remove other participants. The change is recorded as a
+// system message in the conversation.
 //
 // DELETE /conversations/:id/participants/:username
 //
 func (c *ConversationsController) RemoveParticipant(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	conversation := getConversationFromContext(ctx)
+	currentUser := getCurrentUser(ctx)
+	user := getUserFromContext(ctx)
+	conversationID, userID := conversation.ID.Hex(), user.ID.Hex()
+
+	if !canRemoveParticipant(conversation, currentUser.ID.Hex(), userID) {
+		helpers.JSONForbidden(ctx, "Participants cannot be removed from this conversation by the authenticated user")
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := c.MetaStore.RemoveParticipant(conversationID, userID); err == meta.ErrParticipantNotFound {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "Participant not found: %s", user.Username)
+		return
+	} else if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+	if err := c.writeParticipantEvent(conversation, currentUser, user, db.EventParticipantLeft, now); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	ctx.Writer.WriteHeader(http.StatusNoContent)
 }
 
 // AddPulse pulses the current user in the conversation so we can track activity
@@ -212,7 +660,91 @@ func (c *ConversationsController) RemoveParticipant(ctx *gin.Context) {
 // POST /conversations/:id/pulses
 //
 func (c *ConversationsController) AddPulse(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	conversation := getConversationFromContext(ctx)
+	user := getCurrentUser(ctx)
+
+	if err := c.MetaStore.SetParticipantActivity(conversation.ID.Hex(), user.ID.Hex(), time.Now().UTC()); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// writeParticipantEvent writes the system message recording a user joining or leaving a conversation
+func (c *ConversationsController) writeParticipantEvent(conversation *schema.Conversation, currentUser, user *schema.User, event string, t time.Time) error {
+	if _, err := c.MetaStore.CreateDatabaseIfNotExists(c.Database); err != nil {
+		return err
+	}
+
+	return c.MessagesWriter.WriteMessages(&cluster.WriteMessagesRequest{
+		Database:         c.Database,
+		ConsistencyLevel: cluster.ConsistencyLevelOne,
+		Messages:         []db.Message{participantEventMessage(conversation.ID.Hex(), currentUser, user, event, t)},
+	})
+}
+
+// participantEventMessage returns the system message recording a user joining or leaving a conversation. The sender
+// is the current user, who mentions the user when adding or removing someone else.
+func participantEventMessage(conversationID string, currentUser, user *schema.User, event string, t time.Time) db.Message {
+	var text string
+	var mentions []db.Mention
+	switch {
+	case currentUser.ID == user.ID && event == db.EventParticipantJoined:
+		text = fmt.Sprintf("%s joined the conversation", user.Username)
+	case currentUser.ID == user.ID:
+		text = fmt.Sprintf("%s left the conversation", user.Username)
+	case event == db.EventParticipantJoined:
+		text = fmt.Sprintf("%s added %s to the conversation", currentUser.Username, user.Username)
+	default:
+		text = fmt.Sprintf("%s removed %s from the conversation", currentUser.Username, user.Username)
+	}
+	if currentUser.ID != user.ID {
+		mentions = []db.Mention{{RecipientID: user.ID.Hex(), RecipientUsername: user.Username}}
+	}
+
+	message := db.NewMessage(
+		conversationID,
+		db.Sender{UserID: currentUser.ID.Hex(), Name: currentUser.Username},
+		db.Content{PlainText: text},
+		mentions,
+		t,
+	)
+	message.SetID(uuid.NewV4().String())
+	message.SetEvent(event)
+	return message
+}
+
+// canAddParticipant returns true if a user can add a participant to a conversation, which is joining it when both
+// are the same user. Anyone can join a public conversation and its participants can add other users. Users cannot
+// join a protected conversation by themselves, they are added by its participants. Only the creator of a private or
+// secret conversation can add participants. Personal conversations keep the participants they were created with, and
+// participants cannot be added to archived conversations.
+func canAddParticipant(conversation *schema.Conversation, currentUserID, userID string, isParticipant bool) bool {
+	if conversation.IsArchived() {
+		return false
+	}
+
+	isCreator := currentUserID == conversation.CreatorID.Hex()
+	switch conversation.Privacy {
+	case schema.PrivacyPublic:
+		return isParticipant || isCreator || currentUserID == userID
+	case schema.PrivacyProtected:
+		return isParticipant || isCreator
+	case schema.PrivacyPrivate, schema.PrivacySecret:
+		return isCreator
+	}
+	return false
+}
+
+// canRemoveParticipant returns true if a user can remove a participant from a conversation. Participants can always
+// leave a conversation, while only its creator can remove the other participants of a conversation that is not
+// archived.
+func canRemoveParticipant(conversation *schema.Conversation, currentUserID, userID string) bool {
+	if currentUserID == userID {
+		return true
+	}
+	return currentUserID == conversation.CreatorID.Hex() && !conversation.IsArchived()
 }
 
 // ListIntegrations lists all conversation integrations
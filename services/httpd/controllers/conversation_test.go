@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/db"
+	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/services/httpd/presenters"
+	"github.com/messagedb/messagedb/sql"
+	"github.com/messagedb/messagedb/unfurl"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestAttachmentFilename(t *testing.T) {
+	for _, tt := range []struct {
+		filename string
+		exp      string
+	}{
+		{filename: "report.pdf", exp: "report.pdf"},
+		{filename: "../../etc/passwd", exp: "passwd"},
+		{filename: `C:\Users\me\photo.png`, exp: "photo.png"},
+		{filename: "", exp: ""},
+		{filename: "..", exp: ""},
+	} {
+		if s := attachmentFilename(tt.filename); s != tt.exp {
+			t.Errorf("%q: unexpected filename: %q", tt.filename, s)
+		}
+	}
+}
+
+func TestAttachmentContentType(t *testing.T) {
+	for _, tt := range []struct {
+		contentType string
+		filename    string
+		exp         string
+	}{
+		{contentType: "image/png", filename: "photo", exp: "image/png"},
+		{contentType: "", filename: "page.html", exp: "text/html; charset=utf-8"},
+		{contentType: "not a type", filename: "data", exp: "application/octet-stream"},
+	} {
+		if s := attachmentContentType(tt.contentType, tt.filename); s != tt.exp {
+			t.Errorf("%q: unexpected content type: %q", tt.filename, s)
+		}
+	}
+}
+
+func TestQueryLinks(t *testing.T) {
+	executor := &fakeQueryExecutor{row: &sql.Row{
+		Name:    "links",
+		Columns: []string{"time", "url", "message_id", "from", "from_name"},
+		Values: [][]interface{}{
+			{time.Unix(2, 0), "https://example.com/b", "m2", "u1", "susy"},
+			{time.Unix(1, 0), "https://example.com/a", "m1", "u2", ""},
+		},
+	}}
+
+	stmt := &sql.ShowLinksStatement{Name: "c1", Limit: 2}
+	links, err := queryLinks(executor, "db0", stmt)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(links) != 2 {
+		t.Fatalf("unexpected links: %v", links)
+	} else if l := links[0]; l.URL != "https://example.com/b" || l.MessageID != "m2" || l.SenderID != "u1" || l.SenderName != "susy" || l.Timestamp != time.Unix(2, 0).UnixNano() {
+		t.Fatalf("unexpected link: %#v", l)
+	} else if executor.stmt != stmt {
+		t.Fatalf("unexpected statement: %s", executor.stmt)
+	}
+}
+
+func TestUnfurlLinks(t *testing.T) {
+	links := []*presenters.Link{{URL: "https://example.com/a"}, {URL: "https://example.com/down"}}
+	unfurlLinks(unfurl.FetcherFunc(func(url string) (*unfurl.Metadata, error) {
+		if url == "https://example.com/down" {
+			return nil, errors.New("unreachable")
+		}
+		return &unfurl.Metadata{Title: "A", Description: "The A page"}, nil
+	}), links)
+
+	if links[0].Title != "A" || links[0].Description != "The A page" {
+		t.Fatalf("unexpected link: %#v", links[0])
+	} else if links[1].Title != "" || links[1].Description != "" {
+		t.Fatalf("unexpected link: %#v", links[1])
+	}
+}
+
+func TestCanAddParticipant(t *testing.T) {
+	creator, member, other := bson.NewObjectId(), bson.NewObjectId(), bson.NewObjectId()
+	conversation := func(privacy schema.Privacy, archived bool) *schema.Conversation {
+		return &schema.Conversation{CreatorID: creator, Privacy: privacy, Archived: archived}
+	}
+
+	var tests = []struct {
+		conversation  *schema.Conversation
+		currentUser   bson.ObjectId
+		user          bson.ObjectId
+		isParticipant bool
+		exp           bool
+	}{
+		{conversation(schema.PrivacyPublic, false), other, other, false, true},
+		{conversation(schema.PrivacyPublic, false), other, member, false, false},
+		{conversation(schema.PrivacyPublic, false), member, other, true, true},
+		{conversation(schema.PrivacyPublic, true), other, other, false, false},
+		{conversation(schema.PrivacyProtected, false), other, other, false, false},
+		{conversation(schema.PrivacyProtected, false), member, other, true, true},
+		{conversation(schema.PrivacyPrivate, false), member, other, true, false},
+		{conversation(schema.PrivacyPrivate, false), creator, other, false, true},
+		{conversation(schema.PrivacySecret, false), other, other, false, false},
+		{conversation(schema.PrivacySecret, false), creator, other, true, true},
+		{conversation(schema.PrivacyPersonal, false), creator, other, true, false},
+	}
+
+	for i, tt := range tests {
+		if got := canAddParticipant(tt.conversation, tt.currentUser.Hex(), tt.user.Hex(), tt.isParticipant); got != tt.exp {
+			t.Errorf("%d. privacy=%s participant=%v: got %v, exp %v", i, tt.conversation.Privacy, tt.isParticipant, got, tt.exp)
+		}
+	}
+}
+
+func TestCanRemoveParticipant(t *testing.T) {
+	creator, member := bson.NewObjectId(), bson.NewObjectId()
+	conversation := &schema.Conversation{CreatorID: creator, Privacy: schema.PrivacyPrivate}
+	archived := &schema.Conversation{CreatorID: creator, Privacy: schema.PrivacyPrivate, Archived: true}
+
+	if !canRemoveParticipant(conversation, member.Hex(), member.Hex()) {
+		t.Error("expected a participant to be able to leave")
+	} else if !canRemoveParticipant(archived, member.Hex(), member.Hex()) {
+		t.Error("expected a participant to be able to leave an archived conversation")
+	} else if !canRemoveParticipant(conversation, creator.Hex(), member.Hex()) {
+		t.Error("expected the creator to be able to remove a participant")
+	} else if canRemoveParticipant(archived, creator.Hex(), member.Hex()) {
+		t.Error("expected participants of an archived conversation not to be removed")
+	} else if canRemoveParticipant(conversation, member.Hex(), creator.Hex()) {
+		t.Error("expected a participant not to be able to remove another one")
+	}
+}
+
+func TestParticipantEventMessage(t *testing.T) {
+	jdoe := &schema.User{ID: bson.NewObjectId(), Username: "jdoe"}
+	jane := &schema.User{ID: bson.NewObjectId(), Username: "jane"}
+	now := time.Unix(1, 0).UTC()
+
+	m := participantEventMessage("c1", jdoe, jdoe, db.EventParticipantJoined, now)
+	if m.Kind() != db.MessageKindSystem || m.Event() != db.EventParticipantJoined {
+		t.Fatalf("unexpected event: %s %s", m.Kind(), m.Event())
+	} else if m.Content().PlainText != "jdoe joined the conversation" || len(m.Mentions()) != 0 {
+		t.Fatalf("unexpected message: %s", m)
+	} else if m.ID() == "" || string(m.Key()) != "c1" || !m.Time().Equal(now) {
+		t.Fatalf("unexpected message: %s", m)
+	}
+
+	m = participantEventMessage("c1", jdoe, jane, db.EventParticipantLeft, now)
+	exp := []db.Mention{{RecipientID: jane.ID.Hex(), RecipientUsername: "jane"}}
+	if m.Content().PlainText != "jdoe removed jane from the conversation" || !reflect.DeepEqual(m.Mentions(), exp) {
+		t.Fatalf("unexpected message: %s", m)
+	} else if m.From().UserID != jdoe.ID.Hex() {
+		t.Fatalf("unexpected sender: %#v", m.From())
+	}
+}
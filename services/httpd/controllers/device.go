@@ -2,9 +2,13 @@ package controllers
 
 import (
 	"log"
+	"net/http"
 
 	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/services"
 	"github.com/messagedb/messagedb/services/httpd/helpers"
+	"github.com/messagedb/messagedb/services/httpd/presenters"
 
 	"github.com/gin-gonic/gin"
 )
@@ -39,7 +43,7 @@ func NewDevicesController(engine *gin.Engine, logginEnabled, writeTrace bool) *D
 
 func (c *DevicesController) registerRoutes() error {
 
-	router := c.Engine
+	router := c.Engine.Group("", AuthenticatedFilter())
 	router.GET("/devices", c.ListDevices)
 	router.POST("/devices", c.AddDevice)
 
@@ -59,7 +63,13 @@ func (c *DevicesController) registerRoutes() error {
 // GET /devices
 //
 func (c *DevicesController) ListDevices(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	devices, err := services.ListDevices(getCurrentUser(ctx))
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.DeviceCollectionPresenter(devices))
 }
 
 // AddDevice registers a new client device to the authenticated user
@@ -67,7 +77,19 @@ func (c *DevicesController) ListDevices(ctx *gin.Context) {
 // POST /devices
 //
 func (c *DevicesController) AddDevice(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+	var json bindings.AddUpdateDevice
+	if err := ctx.Bind(&json); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	device, err := services.AddDevice(getCurrentUser(ctx), json)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.DevicePresenter(device))
 }
 
 // GetDevice returns a client device
@@ -75,8 +97,8 @@ func (c *DevicesController) AddDevice(ctx *gin.Context) {
 // GET /devices/:id
 //
 func (c *DevicesController) GetDevice(ctx *gin.Context) {
-	_ = ctx.MustGet("device")
-	helpers.JSONResponseNotImplemented(ctx)
+	device := getDeviceFromContext(ctx)
+	helpers.JSONResponseObject(ctx, presenters.DevicePresenter(device))
 }
 
 // EditDevice modifies the client device
@@ -84,8 +106,19 @@ func (c *DevicesController) GetDevice(ctx *gin.Context) {
 // PATCH /devices/:id
 //
 func (c *DevicesController) EditDevice(ctx *gin.Context) {
-	_ = ctx.MustGet("device")
-	helpers.JSONResponseNotImplemented(ctx)
+	var json bindings.AddUpdateDevice
+	if err := ctx.Bind(&json); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	device, err := services.UpdateDevice(getDeviceFromContext(ctx), json)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.DevicePresenter(device))
 }
 
 // DeleteDevice removes a client device from the authenticated users' account
@@ -93,6 +126,10 @@ func (c *DevicesController) EditDevice(ctx *gin.Context) {
 // DELETE /devices/:id
 //
 func (c *DevicesController) DeleteDevice(ctx *gin.Context) {
-	_ = ctx.MustGet("device")
-	helpers.JSONResponseNotImplemented(ctx)
+	if err := services.DeleteDevice(getDeviceFromContext(ctx)); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
 }
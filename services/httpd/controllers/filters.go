@@ -4,21 +4,29 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/messagedb/messagedb/db"
+	"github.com/messagedb/messagedb/meta/policy"
+	"github.com/messagedb/messagedb/meta/schema"
 	"github.com/messagedb/messagedb/meta/services"
+	"github.com/messagedb/messagedb/services/httpd/helpers"
 
 	"github.com/gin-gonic/gin"
+	"gopkg.in/mgo.v2/bson"
 )
 
 // AuthenticatedFilter is a middleware that ensure there is an authentication token in the HTTP headers, only allowing
-// the request to proceeed if the token is present and valid
+// the request to proceeed if the token is present and valid. Requests already authenticated by the ApiTokenMiddleware
+// proceed as the user of their API token.
 func AuthenticatedFilter() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var token string
-		auth := ctx.Request.Header.Get("Authorization")
-		if strings.HasPrefix(strings.ToLower(auth), "bearer ") {
-			token = auth[7:]
-		} else {
+		if getApiToken(ctx) != nil {
+			ctx.Next()
+			return
+		}
+
+		token := bearerToken(ctx.Request)
+		if token == "" {
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
@@ -34,6 +42,19 @@ func AuthenticatedFilter() gin.HandlerFunc {
 	}
 }
 
+// bearerToken returns the token of the Authorization header. Browsers cannot set headers on WebSocket handshakes, so
+// upgrade requests may provide the token in the access_token query parameter instead.
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+		return auth[7:]
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
 // UsernameFilter is a middleware that retrieves the username from the URL and attempts to load a user model
 func UsernameFilter() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -41,75 +62,67 @@ func UsernameFilter() gin.HandlerFunc {
 
 		if len(paramUname) == 0 {
 			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
 		}
 
-		// var user *schema.User
-		// var err error
-		// if utils.IsObjectId(paramUname) {
-		// 	user, err = models.User.FindById(paramUname)
-		// } else {
-		// 	user, err = models.User.FindByUsername(paramUname)
-		// }
-		//
-		// if err != nil {
-		// 	if err == utils.ErrInvalidObjectId || err == ErrNotFound {
-		// 		ctx.AbortWithStatus(http.StatusNotFound)
-		// 	} else {
-		// 		ctx.String(http.StatusBadRequest, err.Error())
-		// 	}
-		// 	return
-		// }
-		//
-		// if user == nil {
-		// 	ctx.AbortWithStatus(http.StatusNotFound)
-		// 	return
-		// }
-		//
-		// //TODO: verify if current user has ability to access this team
-		//
-		// // add to the context so we can reuse in the handlers
-		// ctx.Set("user", user)
+		// previous usernames of renamed users resolve to them through their namespace
+		var user *schema.User
+		var err error
+		if bson.IsObjectIdHex(paramUname) {
+			user, err = services.FindUser(paramUname)
+		} else {
+			user, _, err = services.FindNamespaceOwner(paramUname)
+		}
+
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		if user == nil {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		// add to the context so we can reuse in the handlers
+		ctx.Set("user", user)
 		ctx.Next()
 	}
 }
 
-// func TeamFilter() gin.HandlerFunc {
-// 	return func(ctx *gin.Context) {
-// 		paramTeam := ctx.Param("team_id")
-
-// 		if len(paramTeam) == 0 {
-// 			ctx.AbortWithStatus(http.StatusBadRequest)
-// 		}
+// TeamFilter is a middleware that attempts to load a Team and its organization based on the provided URL parameters
+func TeamFilter() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		paramTeam := ctx.Param("team_id")
 
-// 		team, err := models.Team.FindById(paramTeam)
-// 		if err != nil {
-// 			if err == utils.ErrInvalidObjectId || err == storage.ErrNotFound {
-// 				ctx.AbortWithStatus(http.StatusNotFound)
-// 			} else {
-// 				ctx.AbortWithStatus(http.StatusInternalServerError)
-// 			}
-// 			return
-// 		}
+		if len(paramTeam) == 0 {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
 
-// 		if team == nil {
-// 			ctx.AbortWithStatus(http.StatusNotFound)
-// 			return
-// 		}
+		team, err := services.FindTeam(paramTeam)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
 
-// 		organization, err := models.Organization.FindById(team.OrganizationId)
-// 		if err != nil || organization == nil {
-// 			ctx.AbortWithStatus(http.StatusInternalServerError)
-// 			return
-// 		}
+		if team == nil || !apiTokenAllowsOrganization(ctx, team.OrganizationID) {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
 
-// 		//TODO: verify if current user has ability to access this team
+		organization, err := services.FindOrganization(team.OrganizationID.Hex())
+		if err != nil || organization == nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
 
-// 		// add to the context so we can reuse in the handlers
-// 		ctx.Set("team", team)
-// 		ctx.Set("organization", organization)
-// 		ctx.Next()
-// 	}
-// }
+		// add to the context so we can reuse in the handlers
+		ctx.Set("team", team)
+		ctx.Set("organization", organization)
+		ctx.Next()
+	}
+}
 
 // OrganizationFilter is middleware that attempts to load the organization based on the URL parameters
 func OrganizationFilter() gin.HandlerFunc {
@@ -118,27 +131,30 @@ func OrganizationFilter() gin.HandlerFunc {
 
 		if len(paramOrg) == 0 {
 			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
 		}
 
-		// organization, err := models.Organization.FindByID(paramOrg)
-		// if err != nil {
-		// 	if err == utils.ErrInvalidObjectId || err == storage.ErrNotFound {
-		// 		ctx.AbortWithStatus(http.StatusNotFound)
-		// 	} else {
-		// 		ctx.AbortWithStatus(http.StatusInternalServerError)
-		// 	}
-		// 	return
-		// }
-		//
-		// if organization == nil {
-		// 	ctx.AbortWithStatus(http.StatusNotFound)
-		// 	return
-		// }
-		//
-		// //TODO: verify if current user has ability to access this organization
-		//
-		// // add to the context so we can reuse in the handlers
-		// ctx.Set("organization", organization)
+		// previous paths of renamed organizations resolve to them through their namespace
+		var organization *schema.Organization
+		var err error
+		if bson.IsObjectIdHex(paramOrg) {
+			organization, err = services.FindOrganization(paramOrg)
+		} else {
+			_, organization, err = services.FindNamespaceOwner(paramOrg)
+		}
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		// integration API tokens do not disclose other organizations
+		if organization == nil || !apiTokenAllowsOrganization(ctx, organization.ID) {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		// add to the context so we can reuse in the handlers
+		ctx.Set("organization", organization)
 		ctx.Next()
 	}
 }
@@ -224,38 +240,147 @@ func OrganizationFilter() gin.HandlerFunc {
 // 	}
 // }
 
-// MessageFilter is a middleware that attemps to load a Message based on the provided URL parameters
-func MessageFilter() gin.HandlerFunc {
+// MessageFilter is a middleware that attemps to load a Message based on the provided URL parameters. It must be
+// preceded by the ConversationFilter since messages are looked up within the conversation
+func MessageFilter(find func(conversation *schema.Conversation, id string) (db.Message, error)) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		paramMsgID := ctx.Param("message_id")
 
 		if len(paramMsgID) == 0 {
 			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
 		}
 
-		// message, err := models.Message.FindById(paramMsgID)
-		// if err != nil {
-		// 	if err == utils.ErrInvalidObjectId || err == storage.ErrNotFound {
-		// 		ctx.AbortWithStatus(http.StatusNotFound)
-		// 	} else {
-		// 		ctx.AbortWithStatus(http.StatusInternalServerError)
-		// 	}
-		// 	return
-		// }
-		//
-		// if message == nil {
-		// 	ctx.AbortWithStatus(http.StatusNotFound)
-		// 	return
-		// }
-		//
-		// //TODO: verify if current user has ability to access this conversation
-		//
-		// // add to the context so we can reuse in the handlers
-		// ctx.Set("message", message)
+		message, err := find(getConversationFromContext(ctx), paramMsgID)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		// deleted messages only remain as tombstones in the conversation history
+		if message == nil || message.Deleted() {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		// add to the context so we can reuse in the handlers
+		ctx.Set("message", message)
 		ctx.Next()
 	}
 }
 
+// ConversationAccessFilter is a middleware that only allows the request to proceed if the policy allows the current
+// user to read the conversation loaded by the ConversationFilter, or post to it when write is set
+func ConversationAccessFilter(isParticipant func(conversation *schema.Conversation, user *schema.User) (bool, error), write bool) gin.HandlerFunc {
+	if write {
+		return PolicyFilter(policy.PostMessage, isParticipant)
+	}
+	return PolicyFilter(policy.ReadConversation, isParticipant)
+}
+
+// PolicyFilter is a middleware that only allows the request to proceed if the policy allows the current user to
+// perform the action on the conversation loaded by the ConversationFilter or, without one, on the organization loaded
+// by the OrganizationFilter. isParticipant may be nil for actions on organizations.
+func PolicyFilter(action policy.Action, isParticipant func(conversation *schema.Conversation, user *schema.User) (bool, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var r policy.Resource
+		if conversation, ok := ctx.Get("conversation"); ok {
+			r.Conversation = conversation.(*schema.Conversation)
+		}
+
+		if !authorize(ctx, action, r, isParticipant) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// MessageDeletionFilter is a middleware that only allows the request to proceed if the policy allows the current user
+// to delete messages in the conversation loaded by the ConversationFilter: their own messages as a participant, or the
+// messages of others as a moderator. It must precede the MessageFilter, so that the users who cannot delete any message
+// do not learn which messages exist.
+func MessageDeletionFilter(isParticipant func(conversation *schema.Conversation, user *schema.User) (bool, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		conversation := getConversationFromContext(ctx)
+		s, err := subjectOf(ctx, conversation, isParticipant)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		// the sender of the message is not known yet, the user may either send it or moderate it
+		err = policy.Authorize(s, policy.DeleteMessage, policy.Resource{Conversation: conversation, OwnerID: s.UserID})
+		if err != nil {
+			err = policy.Authorize(s, policy.DeleteMessage, policy.Resource{Conversation: conversation})
+		}
+		if err != nil {
+			if d, ok := err.(*policy.Denial); ok {
+				helpers.JSONDenied(ctx, d)
+			} else {
+				ctx.AbortWithStatus(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// authorize responds with the reason of the denial and returns false if the policy does not allow the current user to
+// perform the action on the resource.
+func authorize(ctx *gin.Context, action policy.Action, r policy.Resource, isParticipant func(conversation *schema.Conversation, user *schema.User) (bool, error)) bool {
+	s, err := subjectOf(ctx, r.Conversation, isParticipant)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return false
+	}
+
+	if err := policy.Authorize(s, action, r); err != nil {
+		if d, ok := err.(*policy.Denial); ok {
+			helpers.JSONDenied(ctx, d)
+		} else {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+// subjectOf returns the current user as the policy sees it: with the role of the user in the organization owning the
+// conversation, or in the organization loaded by the OrganizationFilter, and whether the user participates in the
+// conversation.
+func subjectOf(ctx *gin.Context, conversation *schema.Conversation, isParticipant func(conversation *schema.Conversation, user *schema.User) (bool, error)) (policy.Subject, error) {
+	user := getCurrentUser(ctx)
+	s := policy.Subject{UserID: user.ID.Hex()}
+
+	var orgID string
+	if conversation != nil {
+		if conversation.Namespace.OwnerType == schema.OwnerTypeOrganization {
+			orgID = conversation.Namespace.OwnerID.Hex()
+		}
+	} else if org, ok := ctx.Get("organization"); ok {
+		orgID = org.(*schema.Organization).ID.Hex()
+	}
+
+	if orgID != "" {
+		member, err := services.FindMember(orgID, s.UserID)
+		if err != nil {
+			return s, err
+		}
+		s.Role = policy.RoleOf(member)
+	}
+
+	if conversation != nil && isParticipant != nil {
+		ok, err := isParticipant(conversation, user)
+		if err != nil {
+			return s, err
+		}
+		s.Participant = ok
+	}
+	return s, nil
+}
+
 // DeviceFilter is a middleware that attemps to load a Device based on the provided URL parameters
 func DeviceFilter() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -263,27 +388,50 @@ func DeviceFilter() gin.HandlerFunc {
 
 		if len(paramID) == 0 {
 			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
 		}
 
-		// device, err := models.Device.FindByID(paramID)
-		// if err != nil {
-		// 	if err == utils.ErrInvalidObjectId || err == storage.ErrNotFound {
-		// 		ctx.AbortWithStatus(http.StatusNotFound)
-		// 	} else {
-		// 		ctx.AbortWithStatus(http.StatusInternalServerError)
-		// 	}
-		// 	return
-		// }
-		//
-		// if device == nil {
-		// 	ctx.AbortWithStatus(http.StatusNotFound)
-		// 	return
-		// }
-		//
-		// //TODO: verify if current user has ability to access this team
-		//
-		// // add to the context so we can reuse in the handlers
-		// ctx.Set("device", device)
+		device, err := services.FindDevice(paramID)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		// devices of other users are not disclosed
+		if device == nil || device.UserId != getCurrentUser(ctx).ID {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		// add to the context so we can reuse in the handlers
+		ctx.Set("device", device)
+		ctx.Next()
+	}
+}
+
+// ApiTokenFilter is a middleware that attemps to load an API token based on the provided URL parameters
+func ApiTokenFilter() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		paramID := ctx.Param("token_id")
+
+		if len(paramID) == 0 {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+
+		token, err := services.FindApiToken(paramID)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		if token == nil {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		// add to the context so we can reuse in the handlers
+		ctx.Set("token", token)
 		ctx.Next()
 	}
 }
@@ -295,27 +443,23 @@ func ConversationFilter() gin.HandlerFunc {
 
 		if len(paramID) == 0 {
 			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
 		}
 
-		// conversation, err := models.Conversation.FindByID(paramID)
-		// if err != nil {
-		// 	if err == utils.ErrInvalidObjectId || err == storage.ErrNotFound {
-		// 		ctx.AbortWithStatus(http.StatusNotFound)
-		// 	} else {
-		// 		ctx.AbortWithStatus(http.StatusInternalServerError)
-		// 	}
-		// 	return
-		// }
-		//
-		// if conversation == nil {
-		// 	ctx.AbortWithStatus(http.StatusNotFound)
-		// 	return
-		// }
-		//
-		// //TODO: verify if current user has ability to access this conversation
-		//
-		// // add to the context so we can reuse in the handlers
-		// ctx.Set("conversation", conversation)
+		conversation, err := services.FindConversation(paramID)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		// integration API tokens only reach the conversations of their organization
+		if conversation == nil || !apiTokenAllowsConversation(ctx, conversation) {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		// add to the context so we can reuse in the handlers
+		ctx.Set("conversation", conversation)
 		ctx.Next()
 	}
 }
@@ -327,6 +471,7 @@ func IntegrationFilter() gin.HandlerFunc {
 
 		if len(paramName) == 0 {
 			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
 		}
 
 		// integration, err := models.Integration.FindByName(paramName)
@@ -351,3 +496,20 @@ func IntegrationFilter() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// apiTokenAllowsOrganization returns false if the request is made with the integration API token of another
+// organization.
+func apiTokenAllowsOrganization(ctx *gin.Context, orgID bson.ObjectId) bool {
+	token := getApiToken(ctx)
+	return token == nil || !token.IsIntegration() || token.OrganizationID == orgID
+}
+
+// apiTokenAllowsConversation returns false if the request is made with an integration API token and the conversation
+// is not owned by its organization.
+func apiTokenAllowsConversation(ctx *gin.Context, conversation *schema.Conversation) bool {
+	token := getApiToken(ctx)
+	if token == nil || !token.IsIntegration() {
+		return true
+	}
+	return conversation.Namespace.OwnerType == schema.OwnerTypeOrganization && conversation.Namespace.OwnerID == token.OrganizationID
+}
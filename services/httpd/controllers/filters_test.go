@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/messagedb/messagedb/db"
+	"github.com/messagedb/messagedb/meta/schema"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestBearerToken(t *testing.T) {
+	var tests = []struct {
+		url     string
+		auth    string
+		upgrade string
+		exp     string
+	}{
+		{"/stream", "Bearer abc", "", "abc"},
+		{"/stream", "bearer abc", "websocket", "abc"},
+		{"/stream", "Basic abc", "", ""},
+		{"/stream?access_token=abc", "", "", ""},
+		{"/stream?access_token=abc", "", "websocket", "abc"},
+		{"/stream?access_token=abc", "Bearer def", "websocket", "def"},
+	}
+
+	for i, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.url, nil)
+		if tt.auth != "" {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		if tt.upgrade != "" {
+			r.Header.Set("Upgrade", tt.upgrade)
+		}
+		if got := bearerToken(r); got != tt.exp {
+			t.Errorf("%d. url=%s auth=%q upgrade=%q: got %q, exp %q", i, tt.url, tt.auth, tt.upgrade, got, tt.exp)
+		}
+	}
+}
+
+// Ensure the users who cannot delete any message of a conversation are denied before the message is looked up.
+func TestMessageDeletionFilter(t *testing.T) {
+	user, other := bson.NewObjectId(), bson.NewObjectId()
+
+	var tests = []struct {
+		creator     bson.ObjectId
+		participant bool
+		archived    bool
+		code        int
+	}{
+		{creator: other, participant: true, code: http.StatusNotFound},
+		{creator: user, participant: false, code: http.StatusNotFound},
+		{creator: other, participant: false, code: http.StatusForbidden},
+		{creator: other, participant: true, archived: true, code: http.StatusForbidden},
+	}
+
+	for i, tt := range tests {
+		conversation := &schema.Conversation{CreatorID: tt.creator, Archived: tt.archived}
+		isParticipant := func(*schema.Conversation, *schema.User) (bool, error) { return tt.participant, nil }
+
+		var found bool
+		find := func(*schema.Conversation, string) (db.Message, error) {
+			found = true
+			return nil, nil
+		}
+
+		engine := gin.New()
+		engine.DELETE("/messages/:message_id", func(ctx *gin.Context) {
+			ctx.Set("currentUser", &schema.User{ID: user})
+			ctx.Set("conversation", conversation)
+		}, MessageDeletionFilter(isParticipant), MessageFilter(find))
+
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/messages/m0", nil)
+		engine.ServeHTTP(res, req)
+		if res.Code != tt.code {
+			t.Errorf("%d. unexpected status: %d, exp %d", i, res.Code, tt.code)
+		} else if found != (tt.code == http.StatusNotFound) {
+			t.Errorf("%d. unexpected message lookup: %v", i, found)
+		}
+	}
+}
@@ -1,25 +1,58 @@
 package controllers
 
 import (
+	"bytes"
 	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/messagedb/messagedb/cluster"
 	"github.com/messagedb/messagedb/db"
-	"github.com/messagedb/messagedb/sql"
 	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/policy"
+	"github.com/messagedb/messagedb/meta/schema"
 	"github.com/messagedb/messagedb/services/httpd/helpers"
+	"github.com/messagedb/messagedb/services/httpd/presenters"
+	"github.com/messagedb/messagedb/sql"
 
 	"github.com/gin-gonic/gin"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// DefaultMessagesPerPage is the number of messages returned per page when listing messages
+	DefaultMessagesPerPage = 50
+
+	// MaxMessagesPerPage is the maximum number of messages that can be requested per page
+	MaxMessagesPerPage = 100
+
+	// MaxUnreadCount is the maximum number of unread messages counted in a conversation
+	MaxUnreadCount = 100
 )
 
+// queryExecutor executes queries against the data store
+type queryExecutor interface {
+	ExecuteQuery(q *sql.Query, db string, chunkSize int) (<-chan *sql.Result, error)
+}
+
 // MessagesController handles RESTful API requests for an Message resources
 type MessagesController struct {
 	Engine *gin.Engine
 
 	MetaStore interface {
 		Database(name string) (*meta.DatabaseInfo, error)
+		CreateDatabaseIfNotExists(name string) (*meta.DatabaseInfo, error)
 		Authenticate(username, password string) (ui *meta.UserInfo, err error)
 		Users() ([]meta.UserInfo, error)
+		SetReadMarker(conversationID, userID, messageID string, t time.Time) error
+		Attachment(id string) (*meta.AttachmentInfo, error)
+		Attachments(conversationID string) ([]meta.AttachmentInfo, error)
+		SetAttachmentMessage(id, messageID string) error
+		DeleteAttachment(id string) error
 	}
 
 	QueryExecutor interface {
@@ -32,8 +65,24 @@ type MessagesController struct {
 
 	MessagesWriter interface {
 		WriteMessages(p *cluster.WriteMessagesRequest) error
+		WriteReactions(database string, consistency cluster.ConsistencyLevel, reactions []db.Reaction) error
 	}
 
+	// Publisher delivers read receipts to the other participants
+	Publisher interface {
+		Publish(events ...*cluster.Event)
+	}
+
+	// Participants reports whether a user participates in a conversation, and lists the conversations a user
+	// participates in
+	Participants interface {
+		IsParticipant(conversationID, userID string) (bool, error)
+		Conversations(userID string) ([]string, error)
+	}
+
+	// Database is the database messages are written to and queried from
+	Database string
+
 	Logger        *log.Logger
 	logginEnabled bool // Log every HTTP access
 	WriteTrace    bool // Detail logging of controller handler
@@ -53,41 +102,1097 @@ func (c *MessagesController) registerRoutes() error {
 
 	router := c.Engine
 	{
+		router.GET("/search/messages", AuthenticatedFilter(), c.SearchMessages)
+		router.GET("/user/mentions", AuthenticatedFilter(), c.ListMentions)
+
 		convRouter := router.Group("/conversations/:conversation_id")
-		convRouter.Use(ConversationFilter(), MessageFilter())
+		convRouter.Use(AuthenticatedFilter(), ConversationFilter())
 		{
-			convRouter.GET("/messages/:message_id", c.GetMessage)
-			convRouter.PATCH("/messages/:message_id", c.EditMessage)
-			convRouter.DELETE("/messages/:message_id", c.DeleteMessage)
+			convRouter.GET("/messages", ConversationAccessFilter(c.isParticipant, false), c.ListMessages)
+			convRouter.POST("/messages", ConversationAccessFilter(c.isParticipant, true), c.CreateMessage)
+			convRouter.PUT("/read", ConversationAccessFilter(c.isParticipant, false), c.MarkRead)
+			convRouter.GET("/snippets", ConversationAccessFilter(c.isParticipant, false), c.ListSnippets)
+
+			readRouter := convRouter.Group("/")
+			readRouter.Use(ConversationAccessFilter(c.isParticipant, false), MessageFilter(c.findMessage))
+			{
+				readRouter.GET("/messages/:message_id", c.GetMessage)
+				readRouter.GET("/messages/:message_id/replies", c.ListReplies)
+				readRouter.GET("/snippets/:message_id/raw", c.DownloadSnippet)
+			}
+
+			writeRouter := convRouter.Group("/")
+			writeRouter.Use(ConversationAccessFilter(c.isParticipant, true), MessageFilter(c.findMessage))
+			{
+				writeRouter.PATCH("/messages/:message_id", c.EditMessage)
+				writeRouter.PUT("/messages/:message_id/reactions/:emoji", c.AddReaction)
+				writeRouter.DELETE("/messages/:message_id/reactions/:emoji", c.RemoveReaction)
+			}
+
+			// owners moderate the conversations they do not participate in, the policy decides on the deletion
+			// of a message again once its sender is known
+			convRouter.DELETE("/messages/:message_id", MessageDeletionFilter(c.isParticipant), MessageFilter(c.findMessage), c.DeleteMessage)
 		}
 	}
 
 	return nil
 }
 
+// ListMessages returns a page of the top-level messages in a Conversation, oldest first. Replies are listed in the
+// thread of their parent message, which carries the number of replies. Deleted messages are listed as tombstones
+// without their content.
+//
+// Pages are selected with the before and after cursors, which accept a cursor returned by a previous
+// page, a message ID or a RFC3339 timestamp. The response includes the cursor of the previous page
+// when older messages exist, and the cursor of the next page to poll for newer messages.
+//
+// GET /conversations/:conversation_id/messages?before=<cursor>&after=<cursor>&per_page=50
+// GET /conversations/:conversation_id/messages?page=1&per_page=50
+//
+func (c *MessagesController) ListMessages(ctx *gin.Context) {
+	stmt := selectMessagesStatement(getConversationFromContext(ctx))
+	stmt.TopLevel = true
+	c.listMessages(ctx, stmt)
+}
+
+// ListReplies returns a page of the replies in the thread started by a message, oldest first. Pages are selected
+// the same way as the messages of the Conversation.
+//
+// GET /conversations/:conversation_id/messages/:message_id/replies?before=<cursor>&after=<cursor>&per_page=50
+//
+func (c *MessagesController) ListReplies(ctx *gin.Context) {
+	stmt := selectMessagesStatement(getConversationFromContext(ctx))
+	stmt.Thread = getMessageFromContext(ctx).ID()
+	c.listMessages(ctx, stmt)
+}
+
+// ListSnippets returns a page of the snippets shared in a Conversation, oldest first, optionally restricted to the
+// snippets written in a language. Pages are selected the same way as the messages of the Conversation.
+//
+// GET /conversations/:conversation_id/snippets?language=go&before=<cursor>&after=<cursor>&per_page=50
+//
+func (c *MessagesController) ListSnippets(ctx *gin.Context) {
+	stmt := selectMessagesStatement(getConversationFromContext(ctx))
+	stmt.Condition = snippetsCondition(ctx.Query("language"))
+	c.listMessages(ctx, stmt)
+}
+
+// DownloadSnippet responds with the body of a snippet as plain text, named after the filename of the snippet
+//
+// GET /conversations/:conversation_id/snippets/:message_id/raw
+//
+func (c *MessagesController) DownloadSnippet(ctx *gin.Context) {
+	message := getMessageFromContext(ctx)
+	snippet := message.Snippet()
+	if snippet == nil || message.Deleted() {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "Snippet not found")
+		return
+	}
+
+	filename := snippet.Filename
+	if filename == "" {
+		filename = message.ID() + ".txt"
+	}
+	modTime := message.Time()
+	if t := message.EditedAt(); !t.IsZero() {
+		modTime = t
+	}
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(ctx.Writer, ctx.Request, "", modTime, bytes.NewReader([]byte(snippet.Body)))
+}
+
+// listMessages responds with the page of the messages selected by the statement that is requested by the page,
+// per_page, before and after query parameters
+func (c *MessagesController) listMessages(ctx *gin.Context, stmt *sql.SelectStatement) {
+	conversation := getConversationFromContext(ctx)
+
+	page, err := queryInt(ctx, "page", 1)
+	if err != nil || page < 1 {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid page: %s", ctx.Query("page"))
+		return
+	}
+
+	perPage, err := queryInt(ctx, "per_page", DefaultMessagesPerPage)
+	if err != nil || perPage < 1 || perPage > MaxMessagesPerPage {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid per_page: %s", ctx.Query("per_page"))
+		return
+	}
+
+	if s := ctx.Query("before"); s != "" {
+		if stmt.Before, err = c.parseMessageCursor(conversation, s); err != nil {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid before: %s", s)
+			return
+		}
+	}
+	if s := ctx.Query("after"); s != "" {
+		if stmt.After, err = c.parseMessageCursor(conversation, s); err != nil {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid after: %s", s)
+			return
+		}
+	}
+	if page > 1 && (stmt.Before != nil || stmt.After != nil) {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "page cannot be combined with before or after")
+		return
+	}
+
+	// one more message than requested tells whether there are older messages before the page
+	var older bool
+	switch {
+	case stmt.Before != nil:
+		stmt.Limit = perPage + 1
+	case stmt.After != nil:
+		stmt.Limit = perPage
+		older = true
+	default:
+		stmt.Limit = perPage
+		stmt.Offset = (page - 1) * perPage
+		older = page > 1
+	}
+
+	messages, err := c.queryMessages(stmt)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	// messages before a cursor are returned newest first
+	if stmt.Descending() {
+		if len(messages) > perPage {
+			messages, older = messages[:perPage], true
+		}
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	cursors := &presenters.Cursors{}
+	if len(messages) > 0 {
+		cursors.Next = sql.NewCursor(messages[len(messages)-1].Time()).String()
+		if older {
+			cursors.Prev = sql.NewCursor(messages[0].Time()).String()
+		}
+	} else if stmt.After != nil {
+		cursors.Next = stmt.After.String()
+	}
+
+	collection, err := c.messageCollection(ctx, messages)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponsePage(ctx, collection, cursors)
+}
+
+// SearchMessages returns a page of the messages matching a full-text search query in the conversations the current
+// user participates in, newest first. Words are matched in any order, quoted words as a phrase and words ending with
+// * as a prefix. The response includes the cursor of the previous page when older messages may match.
+//
+// GET /search/messages?q=<query>&before=<cursor>&per_page=50
+//
+func (c *MessagesController) SearchMessages(ctx *gin.Context) {
+	user := getCurrentUser(ctx)
+
+	q := ctx.Query("q")
+	if err := db.ValidateSearchQuery(q); err != nil {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid q: %s", q)
+		return
+	}
+
+	perPage, err := queryInt(ctx, "per_page", DefaultMessagesPerPage)
+	if err != nil || perPage < 1 || perPage > MaxMessagesPerPage {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid per_page: %s", ctx.Query("per_page"))
+		return
+	}
+
+	// the latest messages are searched first, across all the conversations
+	before := sql.NewCursor(time.Now().UTC())
+	if s := ctx.Query("before"); s != "" {
+		if before, err = sql.ParseCursor(s); err != nil {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid before: %s", s)
+			return
+		}
+	}
+
+	// only the conversations the user participates in are searched
+	var sources sql.Sources
+	if c.Participants != nil {
+		ids, err := c.Participants.Conversations(user.ID.Hex())
+		if err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		}
+		for _, id := range ids {
+			sources = append(sources, &sql.Conversation{Name: id})
+		}
+	}
+	if len(sources) == 0 {
+		helpers.JSONResponsePage(ctx, presenters.MessageCollectionPresenter(nil), &presenters.Cursors{})
+		return
+	}
+
+	stmt := &sql.SelectStatement{
+		Fields:     sql.Fields{{Expr: &sql.Wildcard{}}},
+		Sources:    sources,
+		IsRawQuery: true,
+		Condition: &sql.BinaryExpr{
+			Op:  sql.MATCHES,
+			LHS: &sql.VarRef{Val: db.ContentField},
+			RHS: &sql.StringLiteral{Val: q},
+		},
+		Before: before,
+		Limit:  perPage,
+	}
+
+	results, err := c.queryMessages(stmt)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	// the limit applies to each conversation, the newest matches across all of them make the page
+	sort.Stable(sort.Reverse(messagesByTime(results)))
+	cursors := &presenters.Cursors{}
+	if len(results) > perPage {
+		results = results[:perPage]
+	}
+	if len(results) == perPage {
+		cursors.Prev = sql.NewCursor(results[len(results)-1].Time()).String()
+	}
+
+	helpers.JSONResponsePage(ctx, presenters.MessageCollectionPresenter(results), cursors)
+}
+
+// ListMentions returns a page of the messages mentioning the current user in the Conversations the user participates
+// in, newest first. The response includes the cursor of the previous page when older mentions may exist.
+//
+// GET /user/mentions?before=<cursor>&per_page=50
+//
+func (c *MessagesController) ListMentions(ctx *gin.Context) {
+	user := getCurrentUser(ctx)
+
+	perPage, err := queryInt(ctx, "per_page", DefaultMessagesPerPage)
+	if err != nil || perPage < 1 || perPage > MaxMessagesPerPage {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid per_page: %s", ctx.Query("per_page"))
+		return
+	}
+
+	stmt := &sql.ShowMentionsStatement{Name: user.ID.Hex(), Limit: perPage}
+	if s := ctx.Query("before"); s != "" {
+		if stmt.Before, err = sql.ParseCursor(s); err != nil {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid before: %s", s)
+			return
+		}
+	}
+
+	messages, err := c.queryMentions(stmt)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	// the user may have left some of the conversations since being mentioned
+	visible := make(map[string]bool)
+	results := []db.Message{}
+	for _, m := range messages {
+		key := string(m.Key())
+		ok, seen := visible[key]
+		if !seen {
+			if ok, err = c.participates(key, user.ID.Hex()); err != nil {
+				helpers.JSONResponseInternalServerError(ctx, err)
+				return
+			}
+			visible[key] = ok
+		}
+		if ok {
+			results = append(results, m)
+		}
+	}
+
+	cursors := &presenters.Cursors{}
+	if len(messages) == perPage {
+		cursors.Prev = sql.NewCursor(messages[len(messages)-1].Time()).String()
+	}
+
+	helpers.JSONResponsePage(ctx, presenters.MessageCollectionPresenter(results), cursors)
+}
+
+// CreateMessage sends a new message to a Conversation on behalf of the current user. A message with a parent_id is a
+// reply in the thread started by the parent message, and replies to a reply belong to the same thread.
+//
+// POST /conversations/:conversation_id/messages
+//
+func (c *MessagesController) CreateMessage(ctx *gin.Context) {
+	var json bindings.CreateMessage
+	err := ctx.Bind(&json)
+	if err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	var snippet *db.Snippet
+	if json.Snippet != nil {
+		snippet = &db.Snippet{Language: json.Snippet.Language, Filename: json.Snippet.Filename, Body: json.Snippet.Body}
+		if err := snippet.Validate(); err != nil {
+			helpers.JSONResponseValidationFailed(ctx, err)
+			return
+		}
+	} else if json.Text == "" {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "text or snippet required")
+		return
+	}
+	if err := (db.Content{PlainText: json.Text, HTML: json.HTML}).Validate(); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	conversation := getConversationFromContext(ctx)
+	user := getCurrentUser(ctx)
+
+	parentID := json.ParentID
+	if parentID != "" {
+		parent, err := c.findMessage(conversation, parentID)
+		if err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		} else if parent == nil || parent.Deleted() {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid parent_id: %s", parentID)
+			return
+		}
+
+		// threads are a single level deep
+		if id := parent.ParentID(); id != "" {
+			parentID = id
+		}
+	}
+
+	for _, id := range json.Attachments {
+		ai, err := c.MetaStore.Attachment(id)
+		if err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		} else if !canAttach(ai, conversation.ID.Hex(), user.ID.Hex()) {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid attachment: %s", id)
+			return
+		}
+	}
+
+	message := db.NewMessage(
+		conversation.ID.Hex(),
+		db.Sender{UserID: user.ID.Hex(), Name: user.Username},
+		db.Content{PlainText: json.Text, HTML: json.HTML},
+		mentionsFromBindings(json.Mentions),
+		time.Now().UTC(),
+	)
+	message.SetID(uuid.NewV4().String())
+	message.SetParentID(parentID)
+	message.SetSnippet(snippet)
+	for name, value := range json.Fields {
+		message.AddField(name, value)
+	}
+	if err := message.Fields().Validate(); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	if err := c.writeMessage(message); err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to write message to conversation %s: %v", conversation.ID.Hex(), err)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	var attachments []meta.AttachmentInfo
+	for _, id := range json.Attachments {
+		if err := c.MetaStore.SetAttachmentMessage(id, message.ID()); err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		}
+		ai, err := c.MetaStore.Attachment(id)
+		if err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		} else if ai != nil {
+			attachments = append(attachments, *ai)
+		}
+	}
+
+	presenter := presenters.MessagePresenter(message)
+	if len(attachments) > 0 {
+		presenter.Attachments = presenters.AttachmentCollectionPresenter(attachments)
+	}
+	if uri := presenter.GetLocation(); uri != nil {
+		ctx.Header(helpers.LocationHeaderKey, uri.String())
+	}
+	helpers.JSONResponse(ctx, http.StatusCreated, presenter)
+}
+
 // GetMessage returns a message in a Conversation
 //
 // GET /conversations/:conversation_id/messages/:message_id
 //
 func (c *MessagesController) GetMessage(ctx *gin.Context) {
-	_ = ctx.MustGet("conversation")
-	helpers.JSONResponseNotImplemented(ctx)
+	c.respondWithMessage(ctx, getMessageFromContext(ctx))
 }
 
-// EditMessage edits a message in a Conversation
+// EditMessage edits a message in a Conversation. Only the sender of a message can edit it.
 //
 // PATCH /conversations/:conversation_id/messages/:message_id
 //
 func (c *MessagesController) EditMessage(ctx *gin.Context) {
-	_ = ctx.MustGet("conversation")
-	helpers.JSONResponseNotImplemented(ctx)
+	var json bindings.UpdateMessage
+	err := ctx.Bind(&json)
+	if err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	if err := (db.Content{PlainText: json.Text, HTML: json.HTML}).Validate(); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	message := getMessageFromContext(ctx)
+	user := getCurrentUser(ctx)
+	if message.From().UserID != user.ID.Hex() {
+		helpers.JSONForbidden(ctx, "Only the sender can edit a message")
+		return
+	}
+
+	// the edited message becomes the latest revision at the same point of the conversation
+	edited := db.NewMessage(
+		string(message.Key()),
+		message.From(),
+		db.Content{PlainText: json.Text, HTML: json.HTML},
+		mentionsFromBindings(json.Mentions),
+		message.Time(),
+	)
+	edited.SetID(message.ID())
+	edited.SetParentID(message.ParentID())
+	edited.SetSnippet(message.Snippet())
+	edited.SetEditedAt(time.Now().UTC())
+	edited.SetEditedBy(user.ID.Hex())
+	for name, value := range message.Fields() {
+		edited.AddField(name, value)
+	}
+
+	if err := c.writeMessage(edited); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	c.respondWithMessage(ctx, edited)
 }
 
-// DeleteMessage deletes a message from a Conversation
+// DeleteMessage deletes a message from a Conversation. Senders delete their own messages, while the creator of the
+// conversation and the owners of its organization delete the messages of others.
 //
 // DELETE /conversations/:conversation_id/messages/:message_id
 //
 func (c *MessagesController) DeleteMessage(ctx *gin.Context) {
-	_ = ctx.MustGet("conversation")
-	helpers.JSONResponseNotImplemented(ctx)
+	message := getMessageFromContext(ctx)
+	user := getCurrentUser(ctx)
+	r := policy.Resource{Conversation: getConversationFromContext(ctx), OwnerID: message.From().UserID}
+	if !authorize(ctx, policy.DeleteMessage, r, c.isParticipant) {
+		return
+	}
+
+	// a tombstone without content becomes the latest revision of the message
+	tombstone := db.NewMessage(string(message.Key()), message.From(), db.Content{}, nil, message.Time())
+	tombstone.SetID(message.ID())
+	tombstone.SetParentID(message.ParentID())
+	tombstone.SetDeleted(true)
+	tombstone.SetEditedAt(time.Now().UTC())
+	tombstone.SetEditedBy(user.ID.Hex())
+
+	if err := c.writeMessage(tombstone); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	// the files of the message are removed once no other message references their content
+	attachments, err := c.MetaStore.Attachments(string(message.Key()))
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+	for _, ai := range attachments {
+		if ai.MessageID != message.ID() {
+			continue
+		}
+		if err := c.MetaStore.DeleteAttachment(ai.ID); err != nil && err != meta.ErrAttachmentNotFound {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		}
+	}
+
+	ctx.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// AddReaction adds a reaction of the current user with an emoji to a message in a Conversation. Reacting twice with
+// the same emoji has no effect. The response is the message with its reactions.
+//
+// PUT /conversations/:conversation_id/messages/:message_id/reactions/:emoji
+//
+func (c *MessagesController) AddReaction(ctx *gin.Context) {
+	message := getMessageFromContext(ctx)
+	user := getCurrentUser(ctx)
+
+	emoji := ctx.Param("emoji")
+	if err := db.ValidateEmoji(emoji); err != nil {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid emoji: %s", emoji)
+		return
+	}
+
+	r := reactionTo(message, emoji, user.ID.Hex())
+	if err := c.writeReaction(r); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	c.respondWithMessage(ctx, message)
+}
+
+// RemoveReaction removes the reaction of the current user with an emoji from a message in a Conversation. The
+// response is the message with its remaining reactions.
+//
+// DELETE /conversations/:conversation_id/messages/:message_id/reactions/:emoji
+//
+func (c *MessagesController) RemoveReaction(ctx *gin.Context) {
+	message := getMessageFromContext(ctx)
+	user := getCurrentUser(ctx)
+
+	r := reactionTo(message, ctx.Param("emoji"), user.ID.Hex())
+	r.Removed = true
+	if err := c.writeReaction(r); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	c.respondWithMessage(ctx, message)
+}
+
+// MarkRead moves the read marker of the current user in a Conversation to a message, or to the latest message when
+// no message is given. Markers only move forward, and the other participants receive a read receipt. The response is
+// the read state of the Conversation for the current user.
+//
+// PUT /conversations/:conversation_id/read
+//
+func (c *MessagesController) MarkRead(ctx *gin.Context) {
+	var json bindings.MarkRead
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.Bind(&json); err != nil {
+			helpers.JSONResponseValidationFailed(ctx, err)
+			return
+		}
+	}
+
+	conversation := getConversationFromContext(ctx)
+	user := getCurrentUser(ctx)
+
+	var message db.Message
+	var err error
+	if json.MessageID != "" {
+		message, err = c.findMessage(conversation, json.MessageID)
+	} else {
+		message, err = c.latestMessage(conversation)
+	}
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	} else if message == nil {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "Message not found: %s", json.MessageID)
+		return
+	}
+
+	marker := &meta.ReadMarkerInfo{
+		ConversationID: conversation.ID.Hex(),
+		UserID:         user.ID.Hex(),
+		MessageID:      message.ID(),
+		Time:           message.Time(),
+	}
+	if err := c.MetaStore.SetReadMarker(marker.ConversationID, marker.UserID, marker.MessageID, marker.Time); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	if c.Publisher != nil {
+		c.Publisher.Publish(&cluster.Event{
+			Type:         cluster.EventMessageRead,
+			Database:     c.Database,
+			Conversation: marker.ConversationID,
+			UserID:       marker.UserID,
+			Time:         time.Now().UTC(),
+			MessageID:    marker.MessageID,
+		})
+	}
+
+	id := marker.ConversationID
+	unread, mentions, err := countUnread(c.QueryExecutor, c.Database, marker.UserID, []string{id}, map[string]*meta.ReadMarkerInfo{id: marker})
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.ConversationReadStatePresenter(id, marker, unread[id], mentions[id]))
+}
+
+// isParticipant returns true if the user participates in the conversation
+func (c *MessagesController) isParticipant(conversation *schema.Conversation, user *schema.User) (bool, error) {
+	return c.participates(conversation.ID.Hex(), user.ID.Hex())
+}
+
+// participates returns true if the user with the given ID participates in the conversation with the given ID
+func (c *MessagesController) participates(conversationID, userID string) (bool, error) {
+	if c.Participants == nil {
+		return false, nil
+	}
+	return c.Participants.IsParticipant(conversationID, userID)
+}
+
+// findMessage returns the latest version of the message with the given ID in the conversation, or nil if the
+// message does not exist. The mappers of the shards look the ID up in their ID index instead of scanning.
+func (c *MessagesController) findMessage(conversation *schema.Conversation, id string) (db.Message, error) {
+	stmt := selectMessagesStatement(conversation)
+	stmt.Condition = &sql.BinaryExpr{
+		Op:  sql.EQ,
+		LHS: &sql.VarRef{Val: "id"},
+		RHS: &sql.StringLiteral{Val: id},
+	}
+	stmt.Limit = 1
+
+	messages, err := c.queryMessages(stmt)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// latestMessage returns the latest message in the conversation, or nil if the conversation has no messages
+func (c *MessagesController) latestMessage(conversation *schema.Conversation) (db.Message, error) {
+	stmt := selectMessagesStatement(conversation)
+	stmt.Before = sql.NewCursor(time.Now().UTC())
+	stmt.Limit = 1
+
+	messages, err := c.queryMessages(stmt)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// parseMessageCursor returns the cursor for a position in the conversation, given as an encoded cursor,
+// a RFC3339 timestamp or the ID of a message
+func (c *MessagesController) parseMessageCursor(conversation *schema.Conversation, s string) (*sql.Cursor, error) {
+	if cursor, err := sql.ParseCursor(s); err == nil {
+		return cursor, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return sql.NewCursor(t), nil
+	}
+
+	message, err := c.findMessage(conversation, s)
+	if err != nil {
+		return nil, err
+	} else if message == nil {
+		return nil, sql.ErrInvalidCursor
+	}
+	return sql.NewCursor(message.Time()), nil
+}
+
+// writeMessage writes a message to the database through the messages writer
+func (c *MessagesController) writeMessage(message db.Message) error {
+	if _, err := c.MetaStore.CreateDatabaseIfNotExists(c.Database); err != nil {
+		return err
+	}
+
+	return c.MessagesWriter.WriteMessages(&cluster.WriteMessagesRequest{
+		Database:         c.Database,
+		ConsistencyLevel: cluster.ConsistencyLevelOne,
+		Messages:         []db.Message{message},
+	})
+}
+
+// writeReaction writes a reaction to the owners of the shard of its message through the messages writer
+func (c *MessagesController) writeReaction(r db.Reaction) error {
+	return c.MessagesWriter.WriteReactions(c.Database, cluster.ConsistencyLevelOne, []db.Reaction{r})
+}
+
+// reactionTo returns the reaction of a user with an emoji to a message, at the current time
+func reactionTo(message db.Message, emoji, userID string) db.Reaction {
+	return db.Reaction{
+		Conversation: string(message.Key()),
+		Timestamp:    message.UnixNano(),
+		MessageID:    message.ID(),
+		Emoji:        emoji,
+		UserID:       userID,
+		Time:         time.Now().UTC(),
+	}
+}
+
+// queryMessages executes the select statement and returns the messages in the resulting rows
+func (c *MessagesController) queryMessages(stmt *sql.SelectStatement) ([]db.Message, error) {
+	if _, err := c.MetaStore.CreateDatabaseIfNotExists(c.Database); err != nil {
+		return nil, err
+	}
+
+	return executeMessagesQuery(c.QueryExecutor, c.Database, stmt)
+}
+
+// executeMessagesQuery executes the select statement against a database and returns the messages in the resulting rows
+func executeMessagesQuery(executor queryExecutor, database string, stmt *sql.SelectStatement) ([]db.Message, error) {
+	results, err := executor.ExecuteQuery(&sql.Query{Statements: sql.Statements{stmt}}, database, db.IgnoredChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// the results channel is always drained so the executor can finish
+	messages := []db.Message{}
+	for result := range results {
+		if result.Err != nil {
+			if err == nil {
+				err = result.Err
+			}
+			continue
+		}
+		for _, row := range result.Rows {
+			for _, values := range row.Values {
+				m, e := messageFromRow(row.Name, row.Columns, values)
+				if e != nil && err == nil {
+					err = e
+				} else if e == nil {
+					messages = append(messages, m)
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// queryMentions executes the show mentions statement and returns the mentioned messages, newest first
+func (c *MessagesController) queryMentions(stmt *sql.ShowMentionsStatement) ([]db.Message, error) {
+	if _, err := c.MetaStore.CreateDatabaseIfNotExists(c.Database); err != nil {
+		return nil, err
+	}
+
+	results, err := c.QueryExecutor.ExecuteQuery(&sql.Query{Statements: sql.Statements{stmt}}, c.Database, db.IgnoredChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// the results channel is always drained so the executor can finish
+	messages := []db.Message{}
+	for result := range results {
+		if result.Err != nil {
+			if err == nil {
+				err = result.Err
+			}
+			continue
+		}
+		for _, row := range result.Rows {
+			// the conversation of each message is the second column
+			columns := append([]string{row.Columns[0]}, row.Columns[2:]...)
+			for _, values := range row.Values {
+				conversation, _ := values[1].(string)
+				m, e := messageFromRow(conversation, columns, append([]interface{}{values[0]}, values[2:]...))
+				if e != nil && err == nil {
+					err = e
+				} else if e == nil {
+					messages = append(messages, m)
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// messageCollection returns the presenters of messages of a conversation, with the number of replies of the messages
+// starting a thread and the reactions and attachments of the messages that are not deleted
+func (c *MessagesController) messageCollection(ctx *gin.Context, messages []db.Message) ([]*presenters.Message, error) {
+	collection := presenters.MessageCollectionPresenter(messages)
+	if len(messages) == 0 {
+		return collection, nil
+	}
+	key := string(messages[0].Key())
+
+	counts, err := countReplies(c.QueryExecutor, c.Database, key, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, m := range messages {
+		if !m.Deleted() && m.ID() != "" {
+			ids = append(ids, m.ID())
+		}
+	}
+	reactions, err := listReactions(c.QueryExecutor, c.Database, key, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := c.MetaStore.Attachments(key)
+	if err != nil {
+		return nil, err
+	}
+	attachmentsByMessage := make(map[string][]meta.AttachmentInfo)
+	for _, ai := range attachments {
+		if ai.MessageID != "" {
+			attachmentsByMessage[ai.MessageID] = append(attachmentsByMessage[ai.MessageID], ai)
+		}
+	}
+
+	userID := getCurrentUser(ctx).ID.Hex()
+	for i, m := range collection {
+		m.ReplyCount = counts[m.ID]
+		if messages[i].Deleted() {
+			continue
+		}
+		if a := reactions[m.ID]; len(a) > 0 {
+			m.Reactions = presenters.ReactionCollectionPresenter(a, userID)
+		}
+		if a := attachmentsByMessage[m.ID]; len(a) > 0 {
+			m.Attachments = presenters.AttachmentCollectionPresenter(a)
+		}
+	}
+	return collection, nil
+}
+
+// respondWithMessage responds with the presenter of a message, including its replies and reactions
+func (c *MessagesController) respondWithMessage(ctx *gin.Context, message db.Message) {
+	collection, err := c.messageCollection(ctx, []db.Message{message})
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+	helpers.JSONResponseObject(ctx, collection[0])
+}
+
+// canAttach returns true if an attachment can be sent with a new message of a conversation by a user. Only the
+// uploader of a file can send it, once, in the conversation it was uploaded to.
+func canAttach(ai *meta.AttachmentInfo, conversationID, userID string) bool {
+	return ai != nil && ai.ConversationID == conversationID && ai.UploaderID == userID && ai.MessageID == ""
+}
+
+// countReplies returns the number of replies to each of the top-level messages of a conversation. Deleted replies
+// are not counted, and messages without replies are omitted.
+func countReplies(executor queryExecutor, database, conversationID string, messages []db.Message) (map[string]int, error) {
+	stmt := &sql.ShowRepliesStatement{Name: conversationID}
+	for _, m := range messages {
+		if m.ParentID() == "" && !m.Deleted() && m.ID() != "" {
+			stmt.ParentIDs = append(stmt.ParentIDs, m.ID())
+		}
+	}
+	if len(stmt.ParentIDs) == 0 {
+		return nil, nil
+	}
+
+	results, err := executor.ExecuteQuery(&sql.Query{Statements: sql.Statements{stmt}}, database, db.IgnoredChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// the results channel is always drained so the executor can finish
+	counts := make(map[string]int)
+	for result := range results {
+		if result.Err != nil {
+			if err == nil {
+				err = result.Err
+			}
+			continue
+		}
+		for _, row := range result.Rows {
+			for _, values := range row.Values {
+				id, _ := values[0].(string)
+				if n, _ := values[1].(int64); n > 0 {
+					counts[id] = int(n)
+				}
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// listReactions returns the reaction summaries of each of the messages of a conversation, ordered by the time of
+// their first reaction. Messages without reactions are omitted.
+func listReactions(executor queryExecutor, database, conversationID string, messageIDs []string) (map[string]db.ReactionSummaries, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+	stmt := &sql.ShowReactionsStatement{Name: conversationID, MessageIDs: messageIDs}
+
+	results, err := executor.ExecuteQuery(&sql.Query{Statements: sql.Statements{stmt}}, database, db.IgnoredChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// the results channel is always drained so the executor can finish
+	reactions := make(map[string]db.ReactionSummaries)
+	for result := range results {
+		if result.Err != nil {
+			if err == nil {
+				err = result.Err
+			}
+			continue
+		}
+		for _, row := range result.Rows {
+			for _, values := range row.Values {
+				id, _ := values[1].(string)
+				emoji, _ := values[2].(string)
+				count, _ := values[3].(int64)
+				userIDs, _ := values[4].([]string)
+				reactions[id] = append(reactions[id], &db.ReactionSummary{Emoji: emoji, Count: int(count), UserIDs: userIDs})
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return reactions, nil
+}
+
+// countUnread returns the number of messages of each conversation written after the read marker of a user by the
+// other participants, and how many of them mention the user, by conversation ID. The conversations are counted with
+// a single statement reading the latest MaxUnreadCount messages of each of them, so counts stop at MaxUnreadCount.
+// Deleted messages are not counted. All the messages of a conversation without a marker are unread.
+func countUnread(executor queryExecutor, database, userID string, conversationIDs []string, markers map[string]*meta.ReadMarkerInfo) (unread, mentions map[string]int, err error) {
+	unread, mentions = make(map[string]int), make(map[string]int)
+	if len(conversationIDs) == 0 {
+		return unread, mentions, nil
+	}
+
+	stmt := &sql.SelectStatement{
+		Fields:     sql.Fields{{Expr: &sql.Wildcard{}}},
+		IsRawQuery: true,
+		Before:     sql.NewCursor(time.Now().UTC()),
+		Limit:      MaxUnreadCount,
+	}
+
+	// the oldest read marker bounds the scan, unless a conversation has no marker
+	var oldest time.Time
+	bounded := true
+	for i, id := range conversationIDs {
+		stmt.Sources = append(stmt.Sources, &sql.Conversation{Name: id})
+		if marker := markers[id]; marker == nil {
+			bounded = false
+		} else if i == 0 || marker.Time.Before(oldest) {
+			oldest = marker.Time
+		}
+	}
+	if bounded {
+		stmt.After = sql.NewCursor(oldest)
+	}
+
+	messages, err := executeMessagesQuery(executor, database, stmt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, m := range messages {
+		id := string(m.Key())
+		if marker := markers[id]; marker != nil && !m.Time().After(marker.Time) {
+			continue
+		} else if m.Deleted() || m.From().UserID == userID {
+			continue
+		}
+		unread[id]++
+		for _, mention := range m.Mentions() {
+			if mention.RecipientID == userID {
+				mentions[id]++
+				break
+			}
+		}
+	}
+	return unread, mentions, nil
+}
+
+// snippetsCondition returns the condition that selects the snippets of a conversation, in any language if language
+// is empty
+func snippetsCondition(language string) sql.Expr {
+	var expr sql.Expr = &sql.BinaryExpr{
+		Op:  sql.EQ,
+		LHS: &sql.VarRef{Val: "kind"},
+		RHS: &sql.StringLiteral{Val: db.MessageKindSnippet},
+	}
+	if language = db.NormalizeSnippetLanguage(language); language != "" {
+		expr = &sql.BinaryExpr{
+			Op:  sql.AND,
+			LHS: expr,
+			RHS: &sql.BinaryExpr{
+				Op:  sql.EQ,
+				LHS: &sql.VarRef{Val: "snippet_language"},
+				RHS: &sql.StringLiteral{Val: language},
+			},
+		}
+	}
+	return expr
+}
+
+// selectMessagesStatement returns a statement that selects all the messages of a conversation
+func selectMessagesStatement(conversation *schema.Conversation) *sql.SelectStatement {
+	return selectConversationStatement(conversation.ID.Hex())
+}
+
+// selectConversationStatement returns a statement that selects all the messages of the conversation with the given ID
+func selectConversationStatement(conversationID string) *sql.SelectStatement {
+	return &sql.SelectStatement{
+		Fields:     sql.Fields{{Expr: &sql.Wildcard{}}},
+		Sources:    sql.Sources{&sql.Conversation{Name: conversationID}},
+		IsRawQuery: true,
+	}
+}
+
+// messageFromRow returns the message in a row of a raw query. Time is always the first column.
+func messageFromRow(name string, columns []string, values []interface{}) (db.Message, error) {
+	t, _ := values[0].(time.Time)
+
+	fields := make(map[string]interface{}, len(columns)-1)
+	for i := 1; i < len(columns) && i < len(values); i++ {
+		fields[columns[i]] = values[i]
+	}
+
+	return db.UnmarshalMessage(name, t.UnixNano(), fields)
+}
+
+// messagesByTime sorts messages by time, oldest first
+type messagesByTime []db.Message
+
+func (a messagesByTime) Len() int           { return len(a) }
+func (a messagesByTime) Less(i, j int) bool { return a[i].Time().Before(a[j].Time()) }
+func (a messagesByTime) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// mentionsFromBindings converts the mentions of an API payload
+func mentionsFromBindings(a []bindings.Mention) []db.Mention {
+	var mentions []db.Mention
+	for _, m := range a {
+		mentions = append(mentions, db.Mention{RecipientID: m.UserID, RecipientUsername: m.Username})
+	}
+	return mentions
+}
+
+// queryInt returns the integer value of a query parameter, or def if it was not provided
+func queryInt(ctx *gin.Context, key string, def int) (int, error) {
+	s := ctx.Query(key)
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
 }
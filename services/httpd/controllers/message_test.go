@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/db"
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/sql"
+)
+
+// fakeQueryExecutor returns a single row of messages and records the executed statement
+type fakeQueryExecutor struct {
+	stmt sql.Statement
+	row  *sql.Row
+}
+
+func (e *fakeQueryExecutor) ExecuteQuery(q *sql.Query, db string, chunkSize int) (<-chan *sql.Result, error) {
+	e.stmt = q.Statements[0]
+	results := make(chan *sql.Result, 1)
+	results <- &sql.Result{Rows: []*sql.Row{e.row}}
+	close(results)
+	return results, nil
+}
+
+func TestCountUnread(t *testing.T) {
+	executor := &fakeQueryExecutor{row: &sql.Row{
+		Name:    "c1",
+		Columns: []string{"time", "deleted", "from", "mentions", "text"},
+		Values: [][]interface{}{
+			{time.Unix(5, 0), nil, "u3", nil, "hello"},
+			{time.Unix(4, 0), true, "u2", nil, ""},
+			{time.Unix(3, 0), nil, "u2", `[{"RecipientID":"u1"}]`, "hi @u1"},
+			{time.Unix(2, 0), nil, "u1", nil, "mine"},
+			{time.Unix(1, 0), nil, "u2", nil, "read"},
+		},
+	}}
+
+	markers := map[string]*meta.ReadMarkerInfo{
+		"c1": {ConversationID: "c1", UserID: "u1", MessageID: "m1", Time: time.Unix(1, 0)},
+		"c2": {ConversationID: "c2", UserID: "u1", MessageID: "m0", Time: time.Unix(0, 0)},
+	}
+	unread, mentions, err := countUnread(executor, "db0", "u1", []string{"c1", "c2"}, markers)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(unread, map[string]int{"c1": 2}) || !reflect.DeepEqual(mentions, map[string]int{"c1": 1}) {
+		t.Fatalf("unexpected counts: unread=%v mentions=%v", unread, mentions)
+	}
+
+	// a single statement reads the latest messages of all the conversations, back to the oldest marker
+	stmt := executor.stmt.(*sql.SelectStatement)
+	if len(stmt.Sources) != 2 || stmt.Before == nil || stmt.After == nil || stmt.After.Time != markers["c2"].Time.UnixNano() || stmt.Limit != MaxUnreadCount {
+		t.Fatalf("unexpected statement: %s", stmt)
+	}
+
+	// conversations without a marker are read from their first message
+	delete(markers, "c2")
+	if _, _, err := countUnread(executor, "db0", "u1", []string{"c1", "c2"}, markers); err != nil {
+		t.Fatal(err)
+	} else if stmt := executor.stmt.(*sql.SelectStatement); stmt.After != nil {
+		t.Fatalf("unexpected statement: %s", stmt)
+	}
+}
+
+func TestCountReplies(t *testing.T) {
+	executor := &fakeQueryExecutor{row: &sql.Row{
+		Name:    "replies",
+		Columns: []string{"parent_id", "count"},
+		Values: [][]interface{}{
+			{"m1", int64(2)},
+			{"m3", int64(0)},
+		},
+	}}
+
+	reply := db.NewMessage("c1", db.Sender{UserID: "u1"}, db.Content{PlainText: "answer"}, nil, time.Unix(2, 0))
+	reply.SetID("m2")
+	reply.SetParentID("m1")
+	messages := []db.Message{
+		db.NewMessage("c1", db.Sender{UserID: "u1"}, db.Content{PlainText: "question"}, nil, time.Unix(1, 0)),
+		reply,
+		db.NewMessage("c1", db.Sender{UserID: "u1"}, db.Content{PlainText: "unrelated"}, nil, time.Unix(3, 0)),
+	}
+	messages[0].SetID("m1")
+	messages[2].SetID("m3")
+
+	counts, err := countReplies(executor, "db0", "c1", messages)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(counts, map[string]int{"m1": 2}) {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+
+	stmt := executor.stmt.(*sql.ShowRepliesStatement)
+	if stmt.Name != "c1" || !reflect.DeepEqual(stmt.ParentIDs, []string{"m1", "m3"}) {
+		t.Fatalf("unexpected statement: %s", stmt)
+	}
+}
+
+func TestListReactions(t *testing.T) {
+	executor := &fakeQueryExecutor{row: &sql.Row{
+		Name:    "reactions",
+		Columns: []string{"time", "message_id", "emoji", "count", "user_ids"},
+		Values: [][]interface{}{
+			{time.Unix(2, 0), "m1", "tada", int64(1), []string{"u1"}},
+			{time.Unix(3, 0), "m1", "+1", int64(2), []string{"u1", "u2"}},
+		},
+	}}
+
+	reactions, err := listReactions(executor, "db0", "c1", []string{"m1", "m2"})
+	if err != nil {
+		t.Fatal(err)
+	} else if a := reactions["m1"]; len(reactions) != 1 || len(a) != 2 || a[0].Emoji != "tada" || a[1].Count != 2 || !a[1].Reacted("u2") {
+		t.Fatalf("unexpected reactions: %v", reactions)
+	}
+
+	stmt := executor.stmt.(*sql.ShowReactionsStatement)
+	if stmt.Name != "c1" || !reflect.DeepEqual(stmt.MessageIDs, []string{"m1", "m2"}) {
+		t.Fatalf("unexpected statement: %s", stmt)
+	}
+}
+
+func TestCanAttach(t *testing.T) {
+	for i, tt := range []struct {
+		ai  *meta.AttachmentInfo
+		exp bool
+	}{
+		{ai: &meta.AttachmentInfo{ConversationID: "c1", UploaderID: "u1"}, exp: true},
+		{ai: nil, exp: false},
+		{ai: &meta.AttachmentInfo{ConversationID: "c2", UploaderID: "u1"}, exp: false},
+		{ai: &meta.AttachmentInfo{ConversationID: "c1", UploaderID: "u2"}, exp: false},
+		{ai: &meta.AttachmentInfo{ConversationID: "c1", UploaderID: "u1", MessageID: "m1"}, exp: false},
+	} {
+		if ok := canAttach(tt.ai, "c1", "u1"); ok != tt.exp {
+			t.Errorf("%d. unexpected result: %v", i, ok)
+		}
+	}
+}
+
+func TestSnippetsCondition(t *testing.T) {
+	for _, tt := range []struct {
+		language string
+		exp      string
+	}{
+		{language: "", exp: `kind = 'snippet'`},
+		{language: " Go ", exp: `kind = 'snippet' AND snippet_language = 'go'`},
+	} {
+		if got := snippetsCondition(tt.language).String(); got != tt.exp {
+			t.Errorf("%q: condition mismatch: got %s, exp %s", tt.language, got, tt.exp)
+		}
+	}
+}
+
+func TestExecuteMessagesQuery_Snippet(t *testing.T) {
+	executor := &fakeQueryExecutor{row: &sql.Row{
+		Name:    "c1",
+		Columns: []string{"time", "id", "kind", "snippet_body", "snippet_filename", "snippet_language", "text"},
+		Values: [][]interface{}{
+			{time.Unix(1, 0), "m1", "snippet", "package main", "main.go", "go", ""},
+			{time.Unix(2, 0), "m2", nil, nil, nil, nil, "hello"},
+		},
+	}}
+
+	messages, err := executeMessagesQuery(executor, "db0", selectConversationStatement("c1"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(messages) != 2 {
+		t.Fatalf("unexpected message count: %d", len(messages))
+	}
+
+	exp := &db.Snippet{Language: "go", Filename: "main.go", Body: "package main"}
+	if !reflect.DeepEqual(messages[0].Snippet(), exp) || messages[0].Kind() != db.MessageKindSnippet {
+		t.Fatalf("unexpected snippet: %#v", messages[0].Snippet())
+	} else if messages[1].Snippet() != nil || messages[1].Kind() != db.MessageKindText {
+		t.Fatalf("unexpected snippet: %#v", messages[1].Snippet())
+	}
+}
@@ -6,7 +6,7 @@ import (
 
 	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
-	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/meta/policy"
 	"github.com/messagedb/messagedb/meta/services"
 	"github.com/messagedb/messagedb/services/httpd/helpers"
 	"github.com/messagedb/messagedb/services/httpd/presenters"
@@ -57,12 +57,24 @@ func (c *OrganizationsController) registerRoutes() error {
 
 			orgRouter.GET("/orgs/:org/members/:username", UsernameFilter(), c.CheckMembership)
 
+			orgRouter.POST("/orgs/:org/memberships", PolicyFilter(policy.InviteMember, nil), c.InviteMember)
 			orgRouter.GET("/orgs/:org/memberships/:username", UsernameFilter(), c.GetMembership)
 			orgRouter.PUT("/orgs/:org/memberships/:username", UsernameFilter(), c.AddOrUpdateMembership)
 			orgRouter.DELETE("/orgs/:org/memberships/:username", UsernameFilter(), c.RemoveMembership)
 
-			orgRouter.GET("/orgs/:org/conversations", c.ListConversations)
-			orgRouter.POST("/orgs/:org/conversations", c.CreateConversation)
+			orgRouter.GET("/orgs/:org/invitations", c.ListInvitations)
+			orgRouter.POST("/orgs/:org/invitations/:invitation/resend", c.ResendInvitation)
+			orgRouter.DELETE("/orgs/:org/invitations/:invitation", c.CancelInvitation)
+
+			orgRouter.GET("/orgs/:org/teams", c.ListTeams)
+			orgRouter.POST("/orgs/:org/teams", c.CreateTeam)
+
+			orgRouter.GET("/orgs/:org/tokens", PolicyFilter(policy.ManageApiTokens, nil), c.ListApiTokens)
+			orgRouter.POST("/orgs/:org/tokens", PolicyFilter(policy.ManageApiTokens, nil), c.CreateApiToken)
+			orgRouter.DELETE("/orgs/:org/tokens/:token_id", PolicyFilter(policy.ManageApiTokens, nil), ApiTokenFilter(), c.RevokeApiToken)
+
+			orgRouter.GET("/orgs/:org/conversations", PolicyFilter(policy.ListConversations, nil), c.ListConversations)
+			orgRouter.POST("/orgs/:org/conversations", PolicyFilter(policy.CreateConversation, nil), c.CreateConversation)
 
 			orgRouter.GET("/orgs/:org/public_conversations", c.ListPublicConversations)
 
@@ -71,8 +83,13 @@ func (c *OrganizationsController) registerRoutes() error {
 			orgRouter.PUT("/orgs/:org/public_members/:username", UsernameFilter(), c.PublicizeMembership)
 			orgRouter.DELETE("/orgs/:org/public_members/:username", UsernameFilter(), c.ConcealMembership)
 		}
+
+		authRouter.POST("/invitations/:token/accept", c.AcceptInvitation)
 	}
 
+	// declining only requires the token, so that invitations sent to people without an account can be declined
+	c.Engine.POST("/invitations/:token/decline", c.DeclineInvitation)
+
 	return nil
 }
 
@@ -81,15 +98,13 @@ func (c *OrganizationsController) registerRoutes() error {
 // GET /orgs
 //
 func (c *OrganizationsController) ListOrganizations(ctx *gin.Context) {
-	orgs := []*schema.Organization{}
-
 	//TODO: add pagination support via Skip and Limit query methods
 
-	// err := models.Organization.Find(nil).All(&orgs)
-	// if err != nil {
-	// 	helpers.JSONResponseInternalServerError(ctx, err)
-	// 	return
-	// }
+	orgs, err := services.ListAllOrganizations()
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
 
 	helpers.JSONResponseCollection(ctx, presenters.OrganizationCollectionPresenter(orgs))
 }
@@ -111,6 +126,8 @@ func (c *OrganizationsController) CreateOrganization(ctx *gin.Context) {
 	if err != nil {
 		if err == services.ErrNamespaceAlreadyExists {
 			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Organization name already exists")
+		} else if err == services.ErrInvalidNamespacePath {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Organization name is not valid")
 		} else {
 			helpers.JSONResponseInternalServerError(ctx, err)
 		}
@@ -154,7 +171,15 @@ func (c *OrganizationsController) EditOrganization(ctx *gin.Context) {
 
 	org, err = orgService.UpdateOrganization(json)
 	if err != nil {
-		helpers.JSONResponseInternalServerError(ctx, err)
+		if err == services.ErrNotAnOrganizationOwner {
+			helpers.JSONForbidden(ctx, err.Error())
+		} else if err == services.ErrNamespaceAlreadyExists {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Organization name already exists")
+		} else if err == services.ErrInvalidNamespacePath {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Organization name is not valid")
+		} else {
+			helpers.JSONResponseInternalServerError(ctx, err)
+		}
 		return
 	}
 
@@ -298,7 +323,13 @@ func (c *OrganizationsController) PublicizeMembership(ctx *gin.Context) {
 
 	err = orgService.PublicizeMembership(user)
 	if err != nil {
-		helpers.JSONResponseInternalServerError(ctx, err)
+		if err == services.ErrCannotChangeMembershipVisibility {
+			helpers.JSONForbidden(ctx, err.Error())
+		} else if err == services.ErrMembershipNotFound {
+			ctx.AbortWithStatus(http.StatusNotFound)
+		} else {
+			helpers.JSONResponseInternalServerError(ctx, err)
+		}
 		return
 	}
 
@@ -327,7 +358,13 @@ func (c *OrganizationsController) ConcealMembership(ctx *gin.Context) {
 
 	err = orgService.ConcealMembership(user)
 	if err != nil {
-		helpers.JSONResponseInternalServerError(ctx, err)
+		if err == services.ErrCannotChangeMembershipVisibility {
+			helpers.JSONForbidden(ctx, err.Error())
+		} else if err == services.ErrMembershipNotFound {
+			ctx.AbortWithStatus(http.StatusNotFound)
+		} else {
+			helpers.JSONResponseInternalServerError(ctx, err)
+		}
 		return
 	}
 
@@ -358,6 +395,8 @@ func (c *OrganizationsController) GetMembership(ctx *gin.Context) {
 	if err != nil {
 		if err == services.ErrNotAnOrganizationOwner {
 			helpers.JSONForbidden(ctx, err.Error())
+		} else if err == services.ErrMembershipNotFound {
+			ctx.AbortWithStatus(http.StatusNotFound)
 		} else {
 			helpers.JSONResponseInternalServerError(ctx, err)
 		}
@@ -399,6 +438,8 @@ func (c *OrganizationsController) AddOrUpdateMembership(ctx *gin.Context) {
 	if err != nil {
 		if err == services.ErrNotAnOrganizationOwner {
 			helpers.JSONForbidden(ctx, err.Error())
+		} else if err == services.ErrInvalidMembershipRole || err == services.ErrLastOrganizationOwner {
+			helpers.JSONError(ctx, http.StatusBadRequest, err)
 		} else {
 			helpers.JSONResponseInternalServerError(ctx, err)
 		}
@@ -431,6 +472,10 @@ func (c *OrganizationsController) RemoveMembership(ctx *gin.Context) {
 	if err != nil {
 		if err == services.ErrNotAnOrganizationOwner {
 			helpers.JSONForbidden(ctx, err.Error())
+		} else if err == services.ErrMembershipNotFound {
+			ctx.AbortWithStatus(http.StatusNotFound)
+		} else if err == services.ErrLastOrganizationOwner {
+			helpers.JSONError(ctx, http.StatusBadRequest, err)
 		} else {
 			helpers.JSONResponseInternalServerError(ctx, err)
 		}
@@ -440,20 +485,349 @@ func (c *OrganizationsController) RemoveMembership(ctx *gin.Context) {
 	ctx.JSON(http.StatusNoContent, nil)
 }
 
-// ListConversations returns all conversations that are part of the Organization
+// InviteMember invites an email address to join the organization. The authenticated user must be an organization owner.
+// The invitation is delivered with a token that the invited user accepts or declines, until the invitation expires.
+//
+// POST /orgs/:org/memberships
+//
+func (c *OrganizationsController) InviteMember(ctx *gin.Context) {
+
+	var json bindings.InviteMember
+	err := ctx.Bind(&json)
+	if err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	invitation, err := orgService.InviteMember(json)
+	if err != nil {
+		c.invitationError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.MemberPresenter(invitation))
+}
+
+// ListInvitations returns the pending invitations of the organization. The authenticated user must be an organization owner.
+//
+// GET /orgs/:org/invitations
+//
+func (c *OrganizationsController) ListInvitations(ctx *gin.Context) {
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	invitations, err := orgService.ListInvitations()
+	if err != nil {
+		c.invitationError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.MemberCollectionPresenter(invitations))
+}
+
+// ResendInvitation delivers an invitation again with a new token and extends its expiry. The authenticated user must be
+// an organization owner.
+//
+// POST /orgs/:org/invitations/:invitation/resend
+//
+func (c *OrganizationsController) ResendInvitation(ctx *gin.Context) {
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	invitation, err := orgService.ResendInvitation(ctx.Params.ByName("invitation"))
+	if err != nil {
+		c.invitationError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.MemberPresenter(invitation))
+}
+
+// CancelInvitation removes a pending invitation of the organization. The authenticated user must be an organization owner.
+//
+// DELETE /orgs/:org/invitations/:invitation
+//
+func (c *OrganizationsController) CancelInvitation(ctx *gin.Context) {
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	if err := orgService.CancelInvitation(ctx.Params.ByName("invitation")); err != nil {
+		c.invitationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// AcceptInvitation makes the authenticated user an active member of the organization the invitation token was sent for
+//
+// POST /invitations/:token/accept
+//
+func (c *OrganizationsController) AcceptInvitation(ctx *gin.Context) {
+
+	accountService, err := services.NewAccountService(getCurrentUser(ctx))
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	member, err := accountService.AcceptInvitation(ctx.Params.ByName("token"))
+	if err != nil {
+		c.invitationError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.MemberPresenter(member))
+}
+
+// DeclineInvitation removes the invitation the token was sent for
+//
+// POST /invitations/:token/decline
+//
+func (c *OrganizationsController) DeclineInvitation(ctx *gin.Context) {
+
+	if err := services.DeclineInvitation(ctx.Params.ByName("token")); err != nil {
+		c.invitationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// invitationError writes the response of an error raised while managing invitations.
+func (c *OrganizationsController) invitationError(ctx *gin.Context, err error) {
+	switch err {
+	case services.ErrNotAnOrganizationOwner:
+		helpers.JSONForbidden(ctx, err.Error())
+	case services.ErrInvitationNotFound:
+		ctx.AbortWithStatus(http.StatusNotFound)
+	case services.ErrInvitationExpired:
+		helpers.JSONError(ctx, http.StatusGone, err)
+	case services.ErrInvalidMembershipRole, services.ErrAlreadyInvited, services.ErrAlreadyAMember:
+		helpers.JSONError(ctx, http.StatusBadRequest, err)
+	case services.ErrInvitationNotDelivered:
+		helpers.JSONError(ctx, http.StatusBadGateway, err)
+	default:
+		helpers.JSONResponseInternalServerError(ctx, err)
+	}
+}
+
+// ListTeams returns the teams of the organization. The authenticated user must be an organization member.
+//
+// GET /orgs/:org/teams
+//
+func (c *OrganizationsController) ListTeams(ctx *gin.Context) {
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	teams, err := orgService.ListTeams()
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.TeamCollectionPresenter(teams))
+}
+
+// CreateTeam creates a new team in the organization. The authenticated user must be an organization owner.
+//
+// POST /orgs/:org/teams
+//
+func (c *OrganizationsController) CreateTeam(ctx *gin.Context) {
+
+	var json bindings.CreateUpdateTeam
+	err := ctx.Bind(&json)
+	if err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	team, err := orgService.CreateTeam(json)
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.TeamPresenter(team))
+}
+
+// ListApiTokens returns the API tokens of the integrations of the organization. The authenticated user must be an
+// organization owner.
+//
+// GET /orgs/:org/tokens
+//
+func (c *OrganizationsController) ListApiTokens(ctx *gin.Context) {
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	tokens, err := orgService.ListApiTokens()
+	if err != nil {
+		apiTokenError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.ApiTokenCollectionPresenter(tokens))
+}
+
+// CreateApiToken creates an API token for an integration of the organization, such as a bot posting to its
+// conversations. The token is restricted to the organization and is only returned in this response. The
+// authenticated user must be an organization owner.
+//
+// POST /orgs/:org/tokens
+//
+func (c *OrganizationsController) CreateApiToken(ctx *gin.Context) {
+
+	var json bindings.CreateApiToken
+	err := ctx.Bind(&json)
+	if err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	token, raw, err := orgService.CreateApiToken(json)
+	if err != nil {
+		apiTokenError(ctx, err)
+		return
+	}
+
+	p := presenters.ApiTokenPresenter(token)
+	p.Token = raw
+	helpers.JSONResponseObject(ctx, p)
+}
+
+// RevokeApiToken deletes an API token of the organization. The authenticated user must be an organization owner.
+//
+// DELETE /orgs/:org/tokens/:token_id
+//
+func (c *OrganizationsController) RevokeApiToken(ctx *gin.Context) {
+
+	org := getOrganizationFromContext(ctx)
+	orgService, err := services.NewOrganizationService(org, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create OrganizationService for org: %v", org)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	if err := orgService.RevokeApiToken(getApiTokenFromContext(ctx)); err != nil {
+		apiTokenError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// ListConversations returns all conversations that are part of the Organization. The authenticated user must be a
+// member of the organization, guests only list the conversations shared with them.
 //
 // GET /orgs/:org/conversations
 //
 func (c *OrganizationsController) ListConversations(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+
+	org := getOrganizationFromContext(ctx)
+	conversations, err := services.ListNamespaceConversations(org.Namespace.ID.Hex())
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.ConversationCollectionPresenter(conversations))
 }
 
-// CreateConversation creates a new conversation in the Organization
+// CreateConversation creates a new conversation in the Organization. The authenticated user must be a member of the
+// organization, guests cannot create conversations.
 //
 // POST /orgs/:org/conversations
 //
 func (c *OrganizationsController) CreateConversation(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+
+	var json bindings.CreateConversation
+	err := ctx.Bind(&json)
+	if err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	org := getOrganizationFromContext(ctx)
+	conversation, err := services.CreateConversation(org.Namespace.ID.Hex(), json, getCurrentUser(ctx))
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.ConversationPresenter(conversation))
 }
 
 // ListPublicConversations returns all conversations that are marked as public in the Organization.
@@ -461,5 +835,13 @@ func (c *OrganizationsController) CreateConversation(ctx *gin.Context) {
 // GET /orgs/:org/public_conversations
 //
 func (c *OrganizationsController) ListPublicConversations(ctx *gin.Context) {
-	helpers.JSONResponseNotImplemented(ctx)
+
+	org := getOrganizationFromContext(ctx)
+	conversations, err := services.ListNamespacePublicConversations(org.Namespace.ID.Hex())
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.ConversationCollectionPresenter(conversations))
 }
@@ -1,11 +1,16 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"log"
+	"net/http"
 
 	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
 	"github.com/messagedb/messagedb/meta/services"
+	"github.com/messagedb/messagedb/oidc"
 	"github.com/messagedb/messagedb/services/httpd/helpers"
 	"github.com/messagedb/messagedb/services/httpd/presenters"
 	"github.com/messagedb/messagedb/sql"
@@ -27,11 +32,17 @@ type SessionController struct {
 		Users() ([]meta.UserInfo, error)
 	}
 
+	// Providers are the OpenID Connect identity providers users can log in with, by name.
+	Providers map[string]*oidc.Provider
+
 	Logger        *log.Logger
 	logginEnabled bool // Log every HTTP access
 	WriteTrace    bool // Detail logging of controller handler
 }
 
+// stateCookieName is the cookie binding a login with an identity provider to the browser that started it
+const stateCookieName = "oidc_state"
+
 func NewSessionController(engine *gin.Engine, logginEnabled, writeTrace bool) *SessionController {
 	c := &SessionController{
 		Engine:        engine,
@@ -46,7 +57,12 @@ func (c *SessionController) registerRoutes() error {
 
 	router := c.Engine
 	router.POST("/authorize", c.AuthorizeUser)
+	router.GET("/authorize/:provider", c.AuthorizeProvider)
+	router.GET("/authorize/:provider/callback", c.AuthorizeProviderCallback)
 	router.POST("/token/refresh", c.RefreshToken)
+	router.POST("/password/reset/request", c.RequestPasswordReset)
+	router.POST("/password/reset", c.ResetPassword)
+	router.POST("/logout", AuthenticatedFilter(), c.Logout)
 
 	return nil
 }
@@ -83,7 +99,113 @@ func (c *SessionController) AuthorizeUser(ctx *gin.Context) {
 
 }
 
-// RefreshToken generates a new set of authentication tokens for the user to consume the API
+// AuthorizeProvider starts a login with an OpenID Connect identity provider, the user is redirected to the provider
+// which redirects back to the callback once the user is authenticated.
+//
+// GET /authorize/:provider
+//
+func (c *SessionController) AuthorizeProvider(ctx *gin.Context) {
+	provider := c.Providers[ctx.Param("provider")]
+	if provider == nil {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "Identity provider not found")
+		return
+	}
+
+	state, nonce, err := services.Auth.NewAuthorizationState(provider.Name())
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce)
+	if err != nil {
+		c.Logger.Printf("oidc provider %s: %s", provider.Name(), err)
+		helpers.JSONErrorf(ctx, http.StatusBadGateway, "Identity provider unavailable")
+		return
+	}
+
+	// the callback only completes the login in the browser that started it
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    stateDigest(state),
+		Path:     stateCookiePath(ctx),
+		MaxAge:   int(services.AuthorizationStateExpiry.Seconds()),
+		Secure:   ctx.Request.TLS != nil,
+		HttpOnly: true,
+	})
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// AuthorizeProviderCallback completes a login with an OpenID Connect identity provider, exchanging the authorization
+// code for the identity of the user. The user is created on its first login, unless the identity can be linked to an
+// existing user by a confirmed email address.
+//
+// GET /authorize/:provider/callback
+//
+func (c *SessionController) AuthorizeProviderCallback(ctx *gin.Context) {
+	provider := c.Providers[ctx.Param("provider")]
+	if provider == nil {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "Identity provider not found")
+		return
+	}
+
+	if e := ctx.Query("error"); e != "" {
+		helpers.JSONForbidden(ctx, "Identity provider denied the login: %s", e)
+		return
+	}
+
+	// a state sent by another browser, such as the state of a login of an attacker, is refused
+	cookie, err := ctx.Request.Cookie(stateCookieName)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(stateDigest(ctx.Query("state")))) != 1 {
+		helpers.JSONForbidden(ctx, "Invalid or expired login state")
+		return
+	}
+	http.SetCookie(ctx.Writer, &http.Cookie{Name: stateCookieName, Path: stateCookiePath(ctx), MaxAge: -1})
+
+	nonce, err := services.Auth.ValidateAuthorizationState(ctx.Query("state"), provider.Name())
+	if err == services.ErrInvalidAuthorizationState {
+		helpers.JSONForbidden(ctx, "Invalid or expired login state")
+		return
+	} else if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	identity, err := provider.Exchange(ctx.Query("code"), nonce)
+	if err != nil {
+		c.Logger.Printf("oidc provider %s: %s", provider.Name(), err)
+		helpers.JSONForbidden(ctx, "Unable to authenticate with the identity provider")
+		return
+	}
+
+	user, err := services.Auth.AuthorizeIdentity(identity)
+	switch err {
+	case nil:
+	case services.ErrIdentityEmailUnconfirmed, services.ErrEmailAlreadyExists:
+		helpers.JSONForbidden(ctx, "Email address belongs to another user, confirm it to link the identity")
+		return
+	case services.ErrIdentityAlreadyLinked, services.ErrNamespaceAlreadyExists:
+		helpers.JSONErrorf(ctx, http.StatusConflict, "%s", err)
+		return
+	default:
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	tokenFields, err := services.Auth.GenerateToken(user)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseOK(ctx, gin.H{
+		"user":   presenters.UserPresenter(user),
+		"tokens": tokenFields,
+	})
+}
+
+// RefreshToken generates a new set of authentication tokens for the user to consume the API. The refresh token is
+// exchanged only once, the new refresh token must be used for the next refresh.
 //
 // GET /token/refresh
 //
@@ -96,21 +218,116 @@ func (c *SessionController) RefreshToken(ctx *gin.Context) {
 		return
 	}
 
-	//TODO: fix this
-	// user, err := services.Auth.ValidateRefreshToken(json.Token)
-	// if err != nil {
-	// 	helpers.JSONForbidden(ctx, "Unable to validate refresh token")
-	// 	return
-	// }
-	//
-	// tokenFields, err := services.Auth.GenerateToken(user)
-	// if err != nil {
-	// 	helpers.JSONResponseInternalServerError(ctx, err)
-	// 	return
-	// }
-	//
-	// helpers.JSONResponseOK(ctx, gin.H{
-	// 	"user":   presenters.UserPresenter(user),
-	// 	"tokens": tokenFields,
-	// })
+	user, tokenFields, err := services.Auth.RefreshToken(json.Token)
+	if err == services.ErrInvalidRefreshToken {
+		helpers.JSONForbidden(ctx, "Unable to validate refresh token")
+		return
+	} else if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseOK(ctx, gin.H{
+		"user":   presenters.UserPresenter(user),
+		"tokens": tokenFields,
+	})
+}
+
+// RequestPasswordReset sends a link resetting the password of the user a confirmed email address belongs to. The
+// request is accepted whether or not the address belongs to a user, so that the addresses of users cannot be found out.
+//
+// POST /password/reset/request
+//
+func (c *SessionController) RequestPasswordReset(ctx *gin.Context) {
+	var json bindings.RequestPasswordReset
+	if err := ctx.Bind(&json); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	if err := services.Auth.SendPasswordReset(json.Email); err != nil {
+		c.Logger.Printf("unable to send password reset to %s: %s", json.Email, err)
+	}
+
+	ctx.JSON(http.StatusAccepted, nil)
+}
+
+// ResetPassword sets a new password for the user a password reset token was sent to, and authorizes the user
+//
+// POST /password/reset
+//
+func (c *SessionController) ResetPassword(ctx *gin.Context) {
+	var json bindings.ResetPassword
+	if err := ctx.Bind(&json); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	user, err := services.Auth.ResetPassword(json.Token, json.Password)
+	if err == services.ErrInvalidPasswordResetToken {
+		helpers.JSONForbidden(ctx, "Invalid or expired password reset token")
+		return
+	} else if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	tokenFields, err := services.Auth.GenerateToken(user)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseOK(ctx, gin.H{
+		"user":   presenters.UserPresenter(user),
+		"tokens": tokenFields,
+	})
+}
+
+// Logout revokes the access token of the request, along with the refresh token issued with it when provided. The
+// tokens are revoked on every node of the cluster.
+//
+// POST /logout
+//
+func (c *SessionController) Logout(ctx *gin.Context) {
+	var json bindings.Logout
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.Bind(&json); err != nil {
+			helpers.JSONResponseValidationFailed(ctx, err)
+			return
+		}
+	}
+
+	if json.RefreshToken != "" {
+		err := services.Auth.RevokeRefreshToken(json.RefreshToken, getCurrentUser(ctx))
+		if err == services.ErrInvalidRefreshToken {
+			helpers.JSONError(ctx, http.StatusBadRequest, err)
+			return
+		} else if err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		}
+	}
+
+	// requests authenticated with an API token have no access token to revoke
+	if token := bearerToken(ctx.Request); token != "" {
+		if err := services.Auth.RevokeAccessToken(token); err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// stateCookiePath returns the path of the state cookie, covering the login with the identity provider and its callback.
+func stateCookiePath(ctx *gin.Context) string {
+	return "/authorize/" + ctx.Param("provider")
+}
+
+// stateDigest returns the digest of the state of a login with an identity provider, kept in the browser that started
+// the login.
+func stateDigest(state string) string {
+	h := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(h[:])
 }
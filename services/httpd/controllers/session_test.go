@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/messagedb/messagedb/meta/services"
+	"github.com/messagedb/messagedb/oidc"
+	"github.com/messagedb/messagedb/oidc/oidctest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ensure a login with an identity provider only completes in the browser that started it.
+func TestSessionController_AuthorizeProviderCallback_StateCookie(t *testing.T) {
+	idp := oidctest.NewServer("client0", "secret0")
+	defer idp.Close()
+
+	c := NewSessionController(gin.New(), false, false)
+	c.Providers = map[string]*oidc.Provider{
+		"corp": oidc.NewProvider(idp.Config("corp", "http://localhost:8075/authorize/corp/callback"), nil),
+	}
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost:8075/authorize/corp", nil)
+	c.Engine.ServeHTTP(res, req)
+	if res.Code != http.StatusFound {
+		t.Fatalf("unexpected status: %d %s", res.Code, res.Body.String())
+	}
+
+	loc, err := url.Parse(res.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := loc.Query().Get("state")
+	cookies := (&http.Response{Header: res.Header()}).Cookies()
+	if len(cookies) != 1 || cookies[0].Name != stateCookieName || cookies[0].Value != stateDigest(state) || !cookies[0].HttpOnly {
+		t.Fatalf("unexpected cookies: %v", cookies)
+	} else if cookies[0].Path != "/authorize/corp" {
+		t.Fatalf("unexpected cookie path: %s", cookies[0].Path)
+	}
+
+	// the state of another login, or a state without the cookie, is refused before it is redeemed
+	other, _, err := services.Auth.NewAuthorizationState("corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, cookie := range []*http.Cookie{nil, cookies[0]} {
+		res := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://localhost:8075/authorize/corp/callback?code=c0&state="+url.QueryEscape(other), nil)
+		if cookie != nil {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+		c.Engine.ServeHTTP(res, req)
+		if res.Code != http.StatusForbidden {
+			t.Fatalf("%d. unexpected status: %d %s", i, res.Code, res.Body.String())
+		}
+	}
+}
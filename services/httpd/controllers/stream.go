@@ -0,0 +1,484 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/messagedb/messagedb/cluster"
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/services/httpd/helpers"
+	"github.com/messagedb/messagedb/services/httpd/presenters"
+	"github.com/messagedb/messagedb/sql"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// DefaultStreamPulseInterval is the interval at which the server sends a pulse to keep a stream alive
+	DefaultStreamPulseInterval = 30 * time.Second
+
+	// StreamWriteTimeout is the maximum time allowed to write an event to a stream
+	StreamWriteTimeout = 10 * time.Second
+
+	// DefaultStreamParticipationTTL is how long a stream trusts that the user participates in a conversation before
+	// checking it again, so that removed participants stop receiving its events
+	DefaultStreamParticipationTTL = 5 * time.Second
+)
+
+// lastEventIDHeaderKey is the header sent by Server-Sent Events clients to resume a stream after reconnecting
+const lastEventIDHeaderKey = "Last-Event-ID"
+
+// Types of the commands sent by clients over the stream, and of the replies sent back
+const (
+	streamSubscribe    = "subscribe"
+	streamUnsubscribe  = "unsubscribe"
+	streamSubscribed   = "subscribed"
+	streamUnsubscribed = "unsubscribed"
+	streamError        = "error"
+)
+
+// StreamController handles the real-time streams of events over WebSockets and Server-Sent Events
+type StreamController struct {
+	Engine *gin.Engine
+
+	MetaStore interface {
+		CreateDatabaseIfNotExists(name string) (*meta.DatabaseInfo, error)
+	}
+
+	QueryExecutor interface {
+		ExecuteQuery(q *sql.Query, db string, chunkSize int) (<-chan *sql.Result, error)
+	}
+
+	Hub interface {
+		Subscribe(database string) *cluster.Subscription
+	}
+
+	Publisher interface {
+		Publish(events ...*cluster.Event)
+	}
+
+	// Participants reports whether a user participates in a conversation
+	Participants interface {
+		IsParticipant(conversationID, userID string) (bool, error)
+	}
+
+	// Database is the database events are streamed from
+	Database string
+
+	// PulseInterval is the interval at which the server sends a pulse to the client
+	PulseInterval time.Duration
+
+	// ParticipationTTL is how long the participation of the user in a conversation is cached by a stream
+	ParticipationTTL time.Duration
+
+	Logger        *log.Logger
+	logginEnabled bool // Log every HTTP access
+	WriteTrace    bool // Detail logging of controller handler
+}
+
+// NewStreamController returns an instance of the StreamController
+func NewStreamController(engine *gin.Engine, logginEnabled, writeTrace bool) *StreamController {
+	c := &StreamController{
+		Engine:           engine,
+		PulseInterval:    DefaultStreamPulseInterval,
+		ParticipationTTL: DefaultStreamParticipationTTL,
+		logginEnabled:    logginEnabled,
+		WriteTrace:       writeTrace,
+	}
+	c.registerRoutes()
+	return c
+}
+
+func (c *StreamController) registerRoutes() error {
+	c.Engine.GET("/stream", AuthenticatedFilter(), c.Stream)
+	c.Engine.GET("/conversations/:conversation_id/events", AuthenticatedFilter(), ConversationFilter(),
+		ConversationAccessFilter(c.isConversationParticipant, false), c.Events)
+	return nil
+}
+
+// Stream upgrades the request to a WebSocket that streams the events of the conversations the client subscribes
+// to. The client sends JSON commands to subscribe and unsubscribe from conversations it participates in, and to
+// notify the other participants that the user is typing or active with typing and pulse commands. New, edited and
+// deleted messages, read receipts, typing notifications and pulses are sent to the client as they happen.
+//
+// GET /stream
+//
+func (c *StreamController) Stream(ctx *gin.Context) {
+	user := getCurrentUser(ctx)
+
+	server := websocket.Server{
+		// clients authenticate with a bearer token so connections are accepted from any origin
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   func(ws *websocket.Conn) { c.serveStream(ws, user) },
+	}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// Events streams the events of a conversation as Server-Sent Events, for clients that cannot use WebSockets. The
+// events are the same as the ones sent over the WebSocket stream, and the server sends a pulse at regular intervals.
+//
+// New messages carry their cursor as the event ID. When a client reconnects with the Last-Event-ID header, the
+// messages written since that cursor are replayed from the conversation history before streaming new events.
+//
+// GET /conversations/:conversation_id/events
+//
+func (c *StreamController) Events(ctx *gin.Context) {
+	conversation := getConversationFromContext(ctx)
+	conversationID := conversation.ID.Hex()
+	userID := getCurrentUser(ctx).ID.Hex()
+
+	var last *sql.Cursor
+	if s := ctx.Request.Header.Get(lastEventIDHeaderKey); s != "" {
+		cursor, err := sql.ParseCursor(s)
+		if err != nil {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Invalid %s: %s", lastEventIDHeaderKey, s)
+			return
+		}
+		last = cursor
+	}
+
+	// subscribe before replaying the history so no message is missed in between
+	sub := c.Hub.Subscribe(c.Database)
+	defer sub.Close()
+	sub.Add(conversationID)
+
+	w := ctx.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	if last != nil {
+		var err error
+		if last, err = c.replayEvents(w, conversation, last); err != nil {
+			if c.WriteTrace {
+				c.Logger.Printf("Failed to replay events of conversation %s: %v", conversationID, err)
+			}
+			return
+		}
+		w.Flush()
+	}
+
+	// the stream ends with an unsubscribed event once the user no longer participates in the conversation
+	participation := newParticipationCache(userID, c.ParticipationTTL, c.isParticipant)
+	participates := func() bool {
+		ok, err := participation.check(conversationID)
+		if err != nil {
+			if c.WriteTrace {
+				c.Logger.Printf("Failed to check participant of conversation %s: %v", conversationID, err)
+			}
+			return false
+		} else if !ok {
+			writeServerSentEvent(w, "", &presenters.Event{Type: streamUnsubscribed, ConversationID: conversationID,
+				Time: time.Now().UTC(), Error: "No longer a participant of the conversation"})
+			w.Flush()
+		}
+		return ok
+	}
+
+	closed := w.CloseNotify()
+	ticker := time.NewTicker(c.PulseInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-closed:
+			return
+		case e, ok := <-sub.C:
+			// the subscription is closed when the client is too slow, it reconnects from its last event
+			if !ok {
+				return
+			}
+			if !participates() {
+				return
+			}
+
+			switch {
+			case e.Type == cluster.EventMessageCreated:
+				// messages already replayed from the history are skipped
+				if last != nil && e.Message.Time().UnixNano() <= last.Time {
+					continue
+				}
+				last = sql.NewCursor(e.Message.Time())
+				err = writeServerSentEvent(w, last.String(), presenters.EventPresenter(e))
+			case (e.Type == cluster.EventTyping || e.Type == cluster.EventPulse) && e.UserID == userID:
+				continue
+			default:
+				err = writeServerSentEvent(w, "", presenters.EventPresenter(e))
+			}
+		case <-ticker.C:
+			if !participates() {
+				return
+			}
+			err = writeServerSentEvent(w, "", &presenters.Event{Type: string(cluster.EventPulse), Time: time.Now().UTC()})
+		}
+		if err != nil {
+			return
+		}
+		w.Flush()
+	}
+}
+
+// replayEvents writes the events of the messages written to a conversation after a cursor, and returns the cursor
+// of the last message written
+func (c *StreamController) replayEvents(w io.Writer, conversation *schema.Conversation, after *sql.Cursor) (*sql.Cursor, error) {
+	if _, err := c.MetaStore.CreateDatabaseIfNotExists(c.Database); err != nil {
+		return after, err
+	}
+
+	for {
+		stmt := selectMessagesStatement(conversation)
+		stmt.After = after
+		stmt.Limit = MaxMessagesPerPage
+
+		messages, err := executeMessagesQuery(c.QueryExecutor, c.Database, stmt)
+		if err != nil {
+			return after, err
+		}
+
+		for _, m := range messages {
+			after = sql.NewCursor(m.Time())
+			if err := writeServerSentEvent(w, after.String(), presenters.EventPresenter(cluster.NewMessageEvent(c.Database, m))); err != nil {
+				return after, err
+			}
+		}
+
+		if len(messages) < MaxMessagesPerPage {
+			return after, nil
+		}
+	}
+}
+
+// serveStream sends the events of the subscribed conversations to the client until it disconnects
+func (c *StreamController) serveStream(ws *websocket.Conn, user *schema.User) {
+	defer ws.Close()
+
+	userID := user.ID.Hex()
+	sub := c.Hub.Subscribe(c.Database)
+	defer sub.Close()
+
+	// the participation is checked again for the events sent, participants can be removed while subscribed
+	participation := newParticipationCache(userID, c.ParticipationTTL, c.isParticipant)
+
+	// replies to commands and events are written from different goroutines
+	var mu sync.Mutex
+	send := func(e *presenters.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ws.SetWriteDeadline(time.Now().Add(StreamWriteTimeout))
+		return websocket.JSON.Send(ws, e)
+	}
+
+	// commands are read in a separate goroutine until the client disconnects
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var data []byte
+			if err := websocket.Message.Receive(ws, &data); err != nil {
+				return
+			}
+
+			var reply *presenters.Event
+			var cmd bindings.StreamCommand
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				reply = streamErrorReply(&cmd, "Invalid command")
+			} else {
+				reply = c.handleCommand(sub, participation, &cmd)
+			}
+
+			if reply != nil {
+				if err := send(reply); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(c.PulseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-sub.C:
+			// the subscription is closed when the client is too slow, it reconnects and pages from its cursors
+			if !ok {
+				return
+			}
+
+			// clients already know their own typing notifications and pulses
+			if (e.Type == cluster.EventTyping || e.Type == cluster.EventPulse) && e.UserID == userID {
+				continue
+			}
+
+			// the conversation is unsubscribed once the user no longer participates in it
+			if ok, err := participation.check(e.Conversation); err != nil {
+				if c.WriteTrace {
+					c.Logger.Printf("Failed to check participant of conversation %s: %v", e.Conversation, err)
+				}
+				continue
+			} else if !ok {
+				sub.Remove(e.Conversation)
+				if err := send(&presenters.Event{Type: streamUnsubscribed, ConversationID: e.Conversation, Time: time.Now().UTC(),
+					Error: "No longer a participant of the conversation"}); err != nil {
+					return
+				}
+				continue
+			}
+
+			if err := send(presenters.EventPresenter(e)); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := send(&presenters.Event{Type: string(cluster.EventPulse), Time: time.Now().UTC()}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleCommand executes a command sent by the client and returns the reply, if any
+func (c *StreamController) handleCommand(sub *cluster.Subscription, participation *participationCache, cmd *bindings.StreamCommand) *presenters.Event {
+	if cmd.ConversationID == "" {
+		return streamErrorReply(cmd, "conversation_id is required")
+	}
+
+	switch cmd.Type {
+	case streamSubscribe:
+		participation.forget(cmd.ConversationID)
+		ok, err := participation.check(cmd.ConversationID)
+		if err != nil {
+			if c.WriteTrace {
+				c.Logger.Printf("Failed to check participant of conversation %s: %v", cmd.ConversationID, err)
+			}
+			return streamErrorReply(cmd, "Failed to subscribe to conversation")
+		} else if !ok {
+			return streamErrorReply(cmd, "Action not authorized for authenticated user")
+		}
+		sub.Add(cmd.ConversationID)
+		return &presenters.Event{Type: streamSubscribed, ConversationID: cmd.ConversationID, Time: time.Now().UTC()}
+
+	case streamUnsubscribe:
+		sub.Remove(cmd.ConversationID)
+		return &presenters.Event{Type: streamUnsubscribed, ConversationID: cmd.ConversationID, Time: time.Now().UTC()}
+
+	case string(cluster.EventTyping), string(cluster.EventPulse):
+		// only participants that subscribed to the conversation can notify it
+		if !sub.Has(cmd.ConversationID) {
+			return streamErrorReply(cmd, "Not subscribed to conversation")
+		}
+		if ok, err := participation.check(cmd.ConversationID); err != nil || !ok {
+			sub.Remove(cmd.ConversationID)
+			return streamErrorReply(cmd, "Action not authorized for authenticated user")
+		}
+		c.Publisher.Publish(&cluster.Event{
+			Type:         cluster.EventType(cmd.Type),
+			Database:     c.Database,
+			Conversation: cmd.ConversationID,
+			UserID:       participation.userID,
+			Time:         time.Now().UTC(),
+		})
+		return nil
+	}
+
+	return streamErrorReply(cmd, "Unknown command type")
+}
+
+// isConversationParticipant returns true if the user participates in the conversation
+func (c *StreamController) isConversationParticipant(conversation *schema.Conversation, user *schema.User) (bool, error) {
+	return c.isParticipant(conversation.ID.Hex(), user.ID.Hex())
+}
+
+// isParticipant returns true if the user participates in the conversation
+func (c *StreamController) isParticipant(conversationID, userID string) (bool, error) {
+	if c.Participants == nil {
+		return false, nil
+	}
+	return c.Participants.IsParticipant(conversationID, userID)
+}
+
+// participationCache remembers for a short time whether a user participates in conversations, so that a stream
+// checks the participation for every event it sends without asking the meta store each time
+type participationCache struct {
+	mu      sync.Mutex
+	userID  string
+	ttl     time.Duration
+	entries map[string]participationEntry
+
+	isParticipant func(conversationID, userID string) (bool, error)
+}
+
+// participationEntry is the cached participation of the user in a conversation
+type participationEntry struct {
+	ok      bool
+	expires time.Time
+}
+
+// newParticipationCache returns a cache of the participation of a user, checked with isParticipant
+func newParticipationCache(userID string, ttl time.Duration, isParticipant func(conversationID, userID string) (bool, error)) *participationCache {
+	return &participationCache{
+		userID:        userID,
+		ttl:           ttl,
+		entries:       make(map[string]participationEntry),
+		isParticipant: isParticipant,
+	}
+}
+
+// check returns true if the user participates in the conversation. Failed checks are not cached.
+func (pc *participationCache) check(conversationID string) (bool, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	now := time.Now()
+	if e, ok := pc.entries[conversationID]; ok && now.Before(e.expires) {
+		return e.ok, nil
+	}
+
+	ok, err := pc.isParticipant(conversationID, pc.userID)
+	if err != nil {
+		return false, err
+	}
+	pc.entries[conversationID] = participationEntry{ok: ok, expires: now.Add(pc.ttl)}
+	return ok, nil
+}
+
+// forget drops the cached participation in a conversation, so the next check asks the meta store
+func (pc *participationCache) forget(conversationID string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	delete(pc.entries, conversationID)
+}
+
+// streamErrorReply returns the reply sent when a command fails
+func streamErrorReply(cmd *bindings.StreamCommand, message string) *presenters.Event {
+	return &presenters.Event{Type: streamError, ConversationID: cmd.ConversationID, Time: time.Now().UTC(), Error: message}
+}
+
+// writeServerSentEvent writes an event in the Server-Sent Events format. The event ID is omitted when empty so the
+// client keeps the ID of the last message it received.
+func writeServerSentEvent(w io.Writer, id string, e *presenters.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+	return err
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/cluster"
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/services/httpd/presenters"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestWriteServerSentEvent(t *testing.T) {
+	var tests = []struct {
+		id  string
+		e   *presenters.Event
+		exp string
+	}{
+		{
+			id:  "dDox",
+			e:   &presenters.Event{Type: "message.created", ConversationID: "c1", Time: time.Unix(1, 0).UTC()},
+			exp: "id: dDox\nevent: message.created\ndata: {\"type\":\"message.created\",\"conversation_id\":\"c1\",\"time\":\"1970-01-01T00:00:01Z\"}\n\n",
+		},
+		{
+			e:   &presenters.Event{Type: "pulse", Time: time.Unix(2, 0).UTC()},
+			exp: "event: pulse\ndata: {\"type\":\"pulse\",\"time\":\"1970-01-01T00:00:02Z\"}\n\n",
+		},
+	}
+
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeServerSentEvent(&buf, tt.id, tt.e); err != nil {
+			t.Fatalf("%d. unexpected error: %s", i, err)
+		} else if buf.String() != tt.exp {
+			t.Errorf("%d. got %q, exp %q", i, buf.String(), tt.exp)
+		}
+	}
+}
+
+// participantSet is a set of the participants of conversations that can change while streams are open
+type participantSet struct {
+	mu           sync.Mutex
+	participants map[string]bool
+	checks       int
+}
+
+func (p *participantSet) IsParticipant(conversationID, userID string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checks++
+	return p.participants[conversationID+"/"+userID], nil
+}
+
+func (p *participantSet) set(conversationID, userID string, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.participants[conversationID+"/"+userID] = ok
+}
+
+func TestParticipationCache(t *testing.T) {
+	participants := &participantSet{participants: map[string]bool{"c1/u1": true}}
+	pc := newParticipationCache("u1", time.Hour, participants.IsParticipant)
+
+	for i := 0; i < 3; i++ {
+		if ok, err := pc.check("c1"); err != nil || !ok {
+			t.Fatalf("%d. unexpected participation: %v %v", i, ok, err)
+		}
+	}
+	if participants.checks != 1 {
+		t.Fatalf("unexpected check count: %d", participants.checks)
+	}
+
+	// the cached participation is kept until it expires or is forgotten
+	participants.set("c1", "u1", false)
+	if ok, _ := pc.check("c1"); !ok {
+		t.Fatal("expected cached participation")
+	}
+	pc.forget("c1")
+	if ok, _ := pc.check("c1"); ok {
+		t.Fatal("expected participation to be checked again")
+	}
+}
+
+func TestStreamController_Stream_RemovedParticipant(t *testing.T) {
+	user := &schema.User{ID: bson.NewObjectId()}
+	userID := user.ID.Hex()
+
+	hub := cluster.NewHub()
+	participants := &participantSet{participants: map[string]bool{"c1/" + userID: true}}
+
+	c := NewStreamController(gin.New(), false, false)
+	c.Database = "db0"
+	c.Hub = hub
+	c.Publisher = hub
+	c.Participants = participants
+	c.ParticipationTTL = 0
+
+	s := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) { c.serveStream(ws, user) }))
+	defer s.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(s.URL, "http"), "", s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	receive := func() *presenters.Event {
+		var e presenters.Event
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := websocket.JSON.Receive(ws, &e); err != nil {
+			t.Fatal(err)
+		}
+		return &e
+	}
+	typing := &cluster.Event{Type: cluster.EventTyping, Database: "db0", Conversation: "c1", UserID: "u2"}
+
+	websocket.JSON.Send(ws, &bindings.StreamCommand{Type: streamSubscribe, ConversationID: "c1"})
+	if e := receive(); e.Type != streamSubscribed {
+		t.Fatalf("unexpected reply: %+v", e)
+	}
+
+	hub.Publish(typing)
+	if e := receive(); e.Type != string(cluster.EventTyping) || e.UserID != "u2" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	// the next event of the conversation unsubscribes the removed participant instead of being sent
+	participants.set("c1", userID, false)
+	hub.Publish(typing)
+	if e := receive(); e.Type != streamUnsubscribed || e.ConversationID != "c1" || e.Error == "" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	websocket.JSON.Send(ws, &bindings.StreamCommand{Type: string(cluster.EventTyping), ConversationID: "c1"})
+	if e := receive(); e.Type != streamError {
+		t.Fatalf("unexpected reply: %+v", e)
+	}
+}
+
+func TestStreamController_Events_RemovedParticipant(t *testing.T) {
+	user := &schema.User{ID: bson.NewObjectId()}
+	conversation := &schema.Conversation{ID: bson.NewObjectId()}
+	conversationID := conversation.ID.Hex()
+
+	hub := cluster.NewHub()
+	participants := &participantSet{participants: map[string]bool{conversationID + "/" + user.ID.Hex(): true}}
+
+	c := NewStreamController(gin.New(), false, false)
+	c.Database = "db0"
+	c.Hub = hub
+	c.Participants = participants
+	c.ParticipationTTL = 0
+
+	engine := gin.New()
+	engine.GET("/events", func(ctx *gin.Context) {
+		ctx.Set("currentUser", user)
+		ctx.Set("conversation", conversation)
+	}, c.Events)
+	s := httptest.NewServer(engine)
+	defer s.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	res, err := client.Get(s.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	r := bufio.NewReader(res.Body)
+
+	// the event type is the first line of every event
+	next := func() string {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			} else if strings.HasPrefix(line, "event: ") {
+				return strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+			}
+		}
+	}
+	typing := &cluster.Event{Type: cluster.EventTyping, Database: "db0", Conversation: conversationID, UserID: "u2"}
+
+	hub.Publish(typing)
+	if typ := next(); typ != string(cluster.EventTyping) {
+		t.Fatalf("unexpected event: %s", typ)
+	}
+
+	participants.set(conversationID, user.ID.Hex(), false)
+	hub.Publish(typing)
+	if typ := next(); typ != streamUnsubscribed {
+		t.Fatalf("unexpected event: %s", typ)
+	}
+	if rest, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(rest), "event: ") {
+		t.Fatalf("unexpected events after unsubscribing: %s", rest)
+	}
+}
@@ -1,168 +1,310 @@
 package controllers
 
-// import (
-// 	"net/http"
-
-// 	"github.com/messagedb/messagedb/services/httpd/presenters"
-// 	"github.com/messagedb/messagedb/meta/bindings"
-// 	"github.com/messagedb/messagedb/meta/models"
-
-// 	"github.com/gin-gonic/gin"
-// )
-
-// type TeamController struct {
-// 	Engine *gin.Engine
-// }
-
-// func newTeamController(engine *gin.Engine) Controller {
-// 	return &TeamController{Engine: engine}
-// }
-
-// func (c *TeamController) registerRoutes() error {
-
-// 	authRouter := c.Engine.Group("")
-// 	authRouter.Use(filters.AuthenticatedFilter())
-// 	authRouter.Use(filters.TeamFilter())
-// 	{
-// 		memberRouter := authRouter.Group("", filters.CheckOrgMembershipFilter())
-// 		ownerRouter := authRouter.Group("", filters.CheckOrgOwnershipFilter())
-// 		teamMemberRouter := memberRouter.Group("", filters.CheckTeamMembershipFilter())
-
-// 		memberRouter.GET("/teams/:team_id", c.GetTeam)
-
-// 		ownerRouter.PATCH("/teams/:team_id", c.EditTeam)
-// 		ownerRouter.DELETE("/teams/:team_id", c.DeleteTeam)
-
-// 		memberRouter.GET("/teams/:team_id/members", c.ListMembers)
-
-// 		ownerRouter.GET("/teams/:team_id/membership/:username", c.GetTeamMembership)
-// 		ownerRouter.PUT("/teams/:team_id/membership/:username", c.AddTeamMembership)
-// 		ownerRouter.DELETE("/teams/:team_id/membership/:username", c.RemoveTeamMembership)
-
-// 		memberRouter.GET("/teams/:team_id/conversations", c.ListTeamConversations)
-
-// 		memberRouter.GET("/teams/:team_id/conversations/:conversation_id", c.CheckIfTeamManagesConversation)
-// 		teamMemberRouter.PUT("/teams/:team_id/conversations/:conversation_id", c.AddTeamConversation)
-// 		teamMemberRouter.DELETE("/teams/:team_id/conversations/:conversation_id", c.RemoveTeamConversation)
-// 	}
-
-// 	return nil
-// }
-
-// // GET /teams/:id
-// // Get a Team
-// func (c *TeamController) GetTeam(ctx *gin.Context) {
-// 	team := GetTeamFromContext(ctx)
-// 	helpers.JSONResponseObject(ctx, presenters.TeamPresenter(team))
-// }
-
-// // PATCH /teams/:id
-// // Edit a team
-// func (c *TeamController) EditTeam(ctx *gin.Context) {
-// 	org := getOrganizationFromContext(ctx)
-// 	team := GetTeamFromContext(ctx)
-
-// 	var json bindings.CreateUpdateTeam
-// 	err := ctx.Bind(&json)
-// 	if err != nil {
-// 		helpers.JSONResponseValidationFailed(ctx, err)
-// 		return
-// 	}
-
-// 	team, err = services.Team.UpdateTeam(org, team, json)
-// 	if err != nil {
-// 		if err == services.ErrTeamNameAlreadyExists {
-// 			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Team name already exists in this organization")
-// 		} else if err == services.ErrForbiddenNotOrganizationOwner {
-// 			helpers.JSONErrorf(ctx, http.StatusForbidden, "User cannot manage this organization")
-// 		} else {
-// 			helpers.JSONResponseInternalServerError(ctx, err)
-// 		}
-// 		return
-// 	}
-
-// 	helpers.JSONResponseObject(ctx, presenters.TeamPresenter(team))
-// }
-
-// // DELETE /teams/:id
-// // DELETE a team
-// func (c *TeamController) DeleteTeam(ctx *gin.Context) {
-// 	org := getOrganizationFromContext(ctx)
-// 	team := GetTeamFromContext(ctx)
-
-// 	err := services.Team.DeleteTeam(org, team)
-// 	if err != nil {
-// 		if err == services.ErrTeamHasMembers {
-// 			helpers.JSONErrorf(ctx, http.StatusForbidden, "Unable to delete Team because it has members or pending invitations")
-// 		} else if err == services.ErrForbiddenToDeleteOwnersTeam {
-// 			helpers.JSONErrorf(ctx, http.StatusForbidden, "Organization Owner's team cannot be deleted")
-// 		} else {
-// 			helpers.JSONResponseInternalServerError(ctx, err)
-// 		}
-// 		return
-// 	}
-// 	helpers.JSONResponseOK(ctx)
-// }
-
-// // GET /teams/:id/members
-// // List team members
-// func (c *TeamController) ListMembers(ctx *gin.Context) {
-// 	team := GetTeamFromContext(ctx)
-
-// 	members, err := models.Member.FindByTeam(team)
-// 	if err != nil {
-// 		helpers.JSONResponseInternalServerError(ctx, err)
-// 		return
-// 	}
-
-// 	helpers.JSONResponseCollection(ctx, presenters.MemberCollectionPresenter(members))
-// }
-
-// // GET /teams/:id/membership/:username
-// // Get team membership
-// func (c *TeamController) GetTeamMembership(ctx *gin.Context) {
-// 	_ = GetTeamFromContext(ctx)
-// 	helpers.JSONResponseNotImplemented(ctx)
-// }
-
-// // PUT /teams/:id/membership/:username
-// // Add team membership
-// func (c *TeamController) AddTeamMembership(ctx *gin.Context) {
-// 	_ = GetTeamFromContext(ctx)
-// 	helpers.JSONResponseNotImplemented(ctx)
-// }
-
-// // DELETE /teams/:id/membership/:username
-// // Remove team membership
-// func (c *TeamController) RemoveTeamMembership(ctx *gin.Context) {
-// 	_ = GetTeamFromContext(ctx)
-// 	helpers.JSONResponseNotImplemented(ctx)
-// }
-
-// // GET /teams/:id/conversations
-// // List team conversations
-// func (c *TeamController) ListTeamConversations(ctx *gin.Context) {
-// 	_ = GetTeamFromContext(ctx)
-// 	helpers.JSONResponseNotImplemented(ctx)
-// }
-
-// // GET /teams/:id/conversations/:owner/:conversation
-// // Check if a team manages a conversation
-// func (c *TeamController) CheckIfTeamManagesConversation(ctx *gin.Context) {
-// 	_ = GetTeamFromContext(ctx)
-// 	helpers.JSONResponseNotImplemented(ctx)
-// }
-
-// // PUT /teams/:id/conversations/:owner/:conversation
-// // Add team conversations
-// func (c *TeamController) AddTeamConversation(ctx *gin.Context) {
-// 	_ = GetTeamFromContext(ctx)
-// 	helpers.JSONResponseNotImplemented(ctx)
-// }
-
-// // DELETE /teams/:id/conversations/:owner/:conversation
-// // Remove team conversations
-// func (c *TeamController) RemoveTeamConversation(ctx *gin.Context) {
-// 	_ = GetTeamFromContext(ctx)
-// 	helpers.JSONResponseNotImplemented(ctx)
-// }
+import (
+	"log"
+	"net/http"
+
+	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/services"
+	"github.com/messagedb/messagedb/services/httpd/helpers"
+	"github.com/messagedb/messagedb/services/httpd/presenters"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TeamsController handles RESTful API requests for the Team resources of an organization
+type TeamsController struct {
+	Engine *gin.Engine
+
+	Logger         *log.Logger
+	loggingEnabled bool // Log every HTTP access
+	WriteTrace     bool // Detail logging of controller handler
+}
+
+func NewTeamsController(engine *gin.Engine, loggingEnabled, writeTrace bool) *TeamsController {
+	c := &TeamsController{
+		Engine:         engine,
+		loggingEnabled: loggingEnabled,
+		WriteTrace:     writeTrace,
+	}
+	c.registerRoutes()
+	return c
+}
+
+func (c *TeamsController) registerRoutes() error {
+
+	teamRouter := c.Engine.Group("", AuthenticatedFilter(), TeamFilter())
+	{
+		teamRouter.GET("/teams/:team_id", c.GetTeam)
+		teamRouter.PATCH("/teams/:team_id", c.EditTeam)
+		teamRouter.DELETE("/teams/:team_id", c.DeleteTeam)
+
+		teamRouter.GET("/teams/:team_id/members", c.ListMembers)
+
+		teamRouter.GET("/teams/:team_id/membership/:username", UsernameFilter(), c.GetTeamMembership)
+		teamRouter.PUT("/teams/:team_id/membership/:username", UsernameFilter(), c.AddTeamMembership)
+		teamRouter.DELETE("/teams/:team_id/membership/:username", UsernameFilter(), c.RemoveTeamMembership)
+
+		teamRouter.GET("/teams/:team_id/conversations", c.ListTeamConversations)
+
+		teamRouter.GET("/teams/:team_id/conversations/:conversation_id", ConversationFilter(), c.CheckTeamConversation)
+		teamRouter.PUT("/teams/:team_id/conversations/:conversation_id", ConversationFilter(), c.AddTeamConversation)
+		teamRouter.DELETE("/teams/:team_id/conversations/:conversation_id", ConversationFilter(), c.RemoveTeamConversation)
+	}
+
+	return nil
+}
+
+// GetTeam returns a team record. The authenticated user must be an organization member.
+//
+// GET /teams/:team_id
+//
+func (c *TeamsController) GetTeam(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	team, err := teamService.GetTeam()
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.TeamPresenter(team))
+}
+
+// EditTeam changes the name and the description of a team. The authenticated user must be an organization owner.
+//
+// PATCH /teams/:team_id
+//
+func (c *TeamsController) EditTeam(ctx *gin.Context) {
+
+	var json bindings.CreateUpdateTeam
+	err := ctx.Bind(&json)
+	if err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	team, err := teamService.UpdateTeam(json)
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.TeamPresenter(team))
+}
+
+// DeleteTeam deletes a team that has no members. The Owners team of an organization cannot be deleted. The
+// authenticated user must be an organization owner.
+//
+// DELETE /teams/:team_id
+//
+func (c *TeamsController) DeleteTeam(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	if err := teamService.DeleteTeam(); err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// ListMembers returns the memberships of the members of a team. The authenticated user must be an organization member.
+//
+// GET /teams/:team_id/members
+//
+func (c *TeamsController) ListMembers(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	members, err := teamService.ListMembers()
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.MemberCollectionPresenter(members))
+}
+
+// GetTeamMembership returns the membership of a member of the team. The authenticated user must be an organization owner.
+//
+// GET /teams/:team_id/membership/:username
+//
+func (c *TeamsController) GetTeamMembership(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	member, err := teamService.GetTeamMembership(getUserFromContext(ctx))
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.MemberPresenter(member))
+}
+
+// AddTeamMembership adds a member of the organization to the team, granting the user all the conversations of the
+// team. The authenticated user must be an organization owner.
+//
+// PUT /teams/:team_id/membership/:username
+//
+func (c *TeamsController) AddTeamMembership(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	member, err := teamService.AddTeamMembership(getUserFromContext(ctx))
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.MemberPresenter(member))
+}
+
+// RemoveTeamMembership removes a user from the team, revoking the conversations the team granted to the user. The
+// authenticated user must be an organization owner.
+//
+// DELETE /teams/:team_id/membership/:username
+//
+func (c *TeamsController) RemoveTeamMembership(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	if err := teamService.RemoveTeamMembership(getUserFromContext(ctx)); err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// ListTeamConversations returns the conversations granted to the team. The authenticated user must be an organization
+// member.
+//
+// GET /teams/:team_id/conversations
+//
+func (c *TeamsController) ListTeamConversations(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	conversations, err := teamService.ListConversations()
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.ConversationCollectionPresenter(conversations))
+}
+
+// CheckTeamConversation responds with no content if the conversation is granted to the team, or not found otherwise.
+// The authenticated user must be an organization member.
+//
+// GET /teams/:team_id/conversations/:conversation_id
+//
+func (c *TeamsController) CheckTeamConversation(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	granted, err := teamService.CheckConversation(getConversationFromContext(ctx))
+	if err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	if !granted {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// AddTeamConversation grants a conversation of the organization to the team, making every member of the team a
+// participant. The authenticated user must be a member of the team or an organization owner.
+//
+// PUT /teams/:team_id/conversations/:conversation_id
+//
+func (c *TeamsController) AddTeamConversation(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	if err := teamService.AddConversation(getConversationFromContext(ctx)); err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// RemoveTeamConversation revokes a conversation from the team. The authenticated user must be a member of the team or
+// an organization owner.
+//
+// DELETE /teams/:team_id/conversations/:conversation_id
+//
+func (c *TeamsController) RemoveTeamConversation(ctx *gin.Context) {
+	teamService, ok := c.teamService(ctx)
+	if !ok {
+		return
+	}
+
+	if err := teamService.RemoveConversation(getConversationFromContext(ctx)); err != nil {
+		teamError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// teamService creates the service for the team loaded by the TeamFilter. It writes the error response and returns
+// false if the service cannot be created.
+func (c *TeamsController) teamService(ctx *gin.Context) (*services.TeamService, bool) {
+	team := getTeamFromContext(ctx)
+	teamService, err := services.NewTeamService(team, getCurrentUser(ctx))
+	if err != nil {
+		if c.WriteTrace {
+			c.Logger.Printf("Failed to create TeamService for team: %v", team)
+		}
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return nil, false
+	}
+	return teamService, true
+}
+
+// teamError writes the response of an error raised while managing teams.
+func teamError(ctx *gin.Context, err error) {
+	switch err {
+	case services.ErrNotAnOrganizationOwner, services.ErrNotAnOrganizationMember, services.ErrNotATeamMember, services.ErrForbiddenToDeleteOwnersTeam:
+		helpers.JSONForbidden(ctx, err.Error())
+	case services.ErrTeamNotFound, services.ErrTeamMembershipNotFound, services.ErrTeamConversationNotFound:
+		ctx.AbortWithStatus(http.StatusNotFound)
+	case services.ErrTeamNameAlreadyExists, services.ErrTeamHasMembers, services.ErrMembershipNotFound, services.ErrConversationNotFound:
+		helpers.JSONError(ctx, http.StatusBadRequest, err)
+	default:
+		helpers.JSONResponseInternalServerError(ctx, err)
+	}
+}
@@ -6,9 +6,11 @@ import (
 
 	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/bindings"
+	"github.com/messagedb/messagedb/meta/schema"
 	"github.com/messagedb/messagedb/meta/services"
 	"github.com/messagedb/messagedb/services/httpd/helpers"
 	"github.com/messagedb/messagedb/services/httpd/presenters"
+	"github.com/messagedb/messagedb/sql"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,10 +21,24 @@ type UsersController struct {
 
 	MetaStore interface {
 		Database(name string) (*meta.DatabaseInfo, error)
+		CreateDatabaseIfNotExists(name string) (*meta.DatabaseInfo, error)
 		Authenticate(username, password string) (ui *meta.UserInfo, err error)
 		Users() ([]meta.UserInfo, error)
+		ReadMarkers(userID string) ([]meta.ReadMarkerInfo, error)
 	}
 
+	QueryExecutor interface {
+		ExecuteQuery(q *sql.Query, db string, chunkSize int) (<-chan *sql.Result, error)
+	}
+
+	// Participants lists the conversations a user participates in
+	Participants interface {
+		Conversations(userID string) ([]string, error)
+	}
+
+	// Database is the database messages are queried from
+	Database string
+
 	Logger         *log.Logger
 	loggingEnabled bool // Log every HTTP access
 	WriteTrace     bool // Detail logging of controller handler
@@ -43,6 +59,7 @@ func (c *UsersController) registerRoutes() error {
 	router := c.Engine
 	{
 		router.POST("/users", c.RegisterNewUser)
+		router.POST("/emails/verify", c.ConfirmEmail)
 
 		authRouter := router.Group("", AuthenticatedFilter())
 		{
@@ -51,18 +68,24 @@ func (c *UsersController) registerRoutes() error {
 				meRouter.GET("", c.GetMe)
 				meRouter.PATCH("", c.UpdateMe)
 
+				meRouter.POST("/change/username", c.ChangeUsername)
 				meRouter.POST("/change/password", c.ChangePassword)
 
 				meRouter.GET("/emails", c.ListMyEmails)
 				meRouter.POST("/emails", c.AddEmail)
 				meRouter.DELETE("/emails", c.DeleteEmail)
+				meRouter.POST("/emails/verify", c.SendEmailVerification)
 				meRouter.GET("/orgs", c.ListMyOrganizations)
 				meRouter.GET("/memberships/orgs", c.ListMyOrganizationMemberships)
-				meRouter.GET("/memberships/orgs/:org_id", c.GetMyOrganizationMembership)
-				meRouter.PATCH("/memberships/orgs/:org_id", c.EditMyOrganizationMembership)
+				meRouter.GET("/memberships/orgs/:org", OrganizationFilter(), c.GetMyOrganizationMembership)
+				meRouter.PATCH("/memberships/orgs/:org", OrganizationFilter(), c.EditMyOrganizationMembership)
 				meRouter.GET("/conversations", c.ListMyConversations)
 			}
 
+			authRouter.GET("/user/tokens", c.ListApiTokens)
+			authRouter.POST("/user/tokens", c.CreateApiToken)
+			authRouter.DELETE("/user/tokens/:token_id", ApiTokenFilter(), c.RevokeApiToken)
+
 			authRouter.GET("/users", c.ListAllUsers)
 
 			unameRouter := authRouter.Group("/", UsernameFilter())
@@ -119,7 +142,13 @@ func (c *UsersController) ChangeUsername(ctx *gin.Context) {
 
 	ok, err := accountService.ChangeUsername(json)
 	if err != nil {
-		helpers.JSONResponseInternalServerError(ctx, err)
+		if err == services.ErrNamespaceAlreadyExists {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Username already exists")
+		} else if err == services.ErrInvalidNamespacePath {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Username is not valid")
+		} else {
+			helpers.JSONResponseInternalServerError(ctx, err)
+		}
 		return
 	}
 
@@ -193,6 +222,7 @@ func (c *UsersController) AddEmail(ctx *gin.Context) {
 	err := ctx.Bind(&json)
 	if err != nil {
 		helpers.JSONResponseValidationFailed(ctx, err)
+	return
 	}
 
 	user := getCurrentUser(ctx)
@@ -207,13 +237,80 @@ func (c *UsersController) AddEmail(ctx *gin.Context) {
 
 	err = accountService.AddEmailAddress(json)
 	if err != nil {
-		helpers.JSONResponseInternalServerError(ctx, err)
+		if err == services.ErrEmailAlreadyExists {
+			helpers.JSONError(ctx, http.StatusBadRequest, err)
+		} else {
+			helpers.JSONResponseInternalServerError(ctx, err)
+		}
 		return
 	}
+	c.sendEmailVerification(user, json.Email)
 
 	helpers.JSONResponseOK(ctx, user.ListOfEmails())
 }
 
+// SendEmailVerification sends a link confirming an email address of the current user to that address
+//
+// POST /me/emails/verify
+//
+func (c *UsersController) SendEmailVerification(ctx *gin.Context) {
+	var json bindings.UpdateEmail
+	if err := ctx.Bind(&json); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	err := services.Auth.SendEmailVerification(getCurrentUser(ctx), json.Email)
+	switch err {
+	case nil:
+	case services.ErrEmailNotFound:
+		helpers.JSONError(ctx, http.StatusNotFound, err)
+		return
+	case services.ErrEmailAlreadyConfirmed:
+		helpers.JSONError(ctx, http.StatusBadRequest, err)
+		return
+	case services.ErrNotificationNotDelivered:
+		helpers.JSONError(ctx, http.StatusBadGateway, err)
+		return
+	default:
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, nil)
+}
+
+// ConfirmEmail confirms the email address a verification token was sent to
+//
+// POST /emails/verify
+//
+func (c *UsersController) ConfirmEmail(ctx *gin.Context) {
+	var json bindings.ConfirmEmail
+	if err := ctx.Bind(&json); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	user, err := services.Auth.ConfirmEmail(json.Token)
+	if err == services.ErrInvalidVerificationToken {
+		helpers.JSONForbidden(ctx, "Invalid or expired verification token")
+		return
+	} else if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseObject(ctx, presenters.UserPresenter(user))
+}
+
+// sendEmailVerification sends a link confirming a newly registered email address. Failures are logged, the address
+// can be verified again later.
+func (c *UsersController) sendEmailVerification(user *schema.User, email string) {
+	if err := services.Auth.SendEmailVerification(user, email); err != nil && err != services.ErrEmailAlreadyConfirmed {
+		c.Logger.Printf("unable to send email verification to %s: %s", email, err)
+	}
+}
+
 // DeleteEmail deletes email address for current user
 //
 // DELETE /user/emails
@@ -223,6 +320,7 @@ func (c *UsersController) DeleteEmail(ctx *gin.Context) {
 	err := ctx.Bind(&json)
 	if err != nil {
 		helpers.JSONResponseValidationFailed(ctx, err)
+	return
 	}
 
 	user := getCurrentUser(ctx)
@@ -237,7 +335,11 @@ func (c *UsersController) DeleteEmail(ctx *gin.Context) {
 
 	err = accountService.RemoveEmailAddress(json)
 	if err != nil {
-		helpers.JSONResponseInternalServerError(ctx, err)
+		if err == services.ErrCannotRemovePrimaryEmail {
+			helpers.JSONError(ctx, http.StatusBadRequest, err)
+		} else {
+			helpers.JSONResponseInternalServerError(ctx, err)
+		}
 		return
 	}
 
@@ -316,7 +418,7 @@ func (c *UsersController) GetMyOrganizationMembership(ctx *gin.Context) {
 
 	member, err := accountService.GetMyMembership(org.ID)
 	if err != nil {
-		if err == ErrNotFound {
+		if err == services.ErrMembershipNotFound {
 			helpers.JSONErrorf(ctx, http.StatusNotFound, "No membership found for this organization")
 			return
 		}
@@ -336,6 +438,7 @@ func (c *UsersController) EditMyOrganizationMembership(ctx *gin.Context) {
 	err := ctx.Bind(&json)
 	if err != nil {
 		helpers.JSONResponseValidationFailed(ctx, err)
+	return
 	}
 
 	user := getCurrentUser(ctx)
@@ -353,9 +456,12 @@ func (c *UsersController) EditMyOrganizationMembership(ctx *gin.Context) {
 
 	member, err := accountService.EditMyMembership(org.ID, json)
 	if err != nil {
-		if err == ErrNotFound {
+		if err == services.ErrMembershipNotFound {
 			helpers.JSONErrorf(ctx, http.StatusNotFound, "No membership found for this organization")
 			return
+		} else if err == services.ErrInvalidMembershipState {
+			helpers.JSONError(ctx, http.StatusBadRequest, err)
+			return
 		}
 		helpers.JSONResponseInternalServerError(ctx, err)
 		return
@@ -364,13 +470,51 @@ func (c *UsersController) EditMyOrganizationMembership(ctx *gin.Context) {
 	helpers.JSONResponseObject(ctx, presenters.MemberPresenter(member))
 }
 
-// ListMyConversations lists the conversations that the authenticated user participates in
+// ListMyConversations lists the conversations that the authenticated user participates in, with the number of
+// unread messages and unread mentions of the user in each of them. Counts stop at MaxUnreadCount.
 //
 // GET /user/conversations
 //
 func (c *UsersController) ListMyConversations(ctx *gin.Context) {
-	// TODO: implement this
-	helpers.JSONResponseNotImplemented(ctx)
+	user := getCurrentUser(ctx)
+	userID := user.ID.Hex()
+
+	var ids []string
+	if c.Participants != nil {
+		var err error
+		if ids, err = c.Participants.Conversations(userID); err != nil {
+			helpers.JSONResponseInternalServerError(ctx, err)
+			return
+		}
+	}
+
+	markers, err := c.MetaStore.ReadMarkers(userID)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+	markersByConversation := make(map[string]*meta.ReadMarkerInfo, len(markers))
+	for i := range markers {
+		markersByConversation[markers[i].ConversationID] = &markers[i]
+	}
+
+	if _, err := c.MetaStore.CreateDatabaseIfNotExists(c.Database); err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	unread, mentions, err := countUnread(c.QueryExecutor, c.Database, userID, ids, markersByConversation)
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	conversations := []*presenters.ConversationReadState{}
+	for _, id := range ids {
+		conversations = append(conversations, presenters.ConversationReadStatePresenter(id, markersByConversation[id], unread[id], mentions[id]))
+	}
+
+	helpers.JSONResponseCollection(ctx, conversations)
 }
 
 ///// USERS Resource /////
@@ -404,11 +548,16 @@ func (c *UsersController) RegisterNewUser(ctx *gin.Context) {
 	if err != nil {
 		if err == services.ErrNamespaceAlreadyExists {
 			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Username already exists")
+		} else if err == services.ErrInvalidNamespacePath {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Username is not valid")
+		} else if err == services.ErrEmailAlreadyExists {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, "Email address already exists")
 		} else {
 			helpers.JSONResponseInternalServerError(ctx, err)
 		}
 		return
 	}
+	c.sendEmailVerification(user, json.EmailAddress)
 
 	helpers.JSONResponseObject(ctx, presenters.UserPresenter(user))
 }
@@ -472,3 +621,67 @@ func (c *UsersController) ListUserConversations(ctx *gin.Context) {
 
 	helpers.JSONResponseObject(ctx, presenters.MemberCollectionPresenter(members))
 }
+
+// ListApiTokens lists the personal API tokens of the authenticated user
+//
+// GET /user/tokens
+//
+func (c *UsersController) ListApiTokens(ctx *gin.Context) {
+	tokens, err := services.ListApiTokens(getCurrentUser(ctx))
+	if err != nil {
+		helpers.JSONResponseInternalServerError(ctx, err)
+		return
+	}
+
+	helpers.JSONResponseCollection(ctx, presenters.ApiTokenCollectionPresenter(tokens))
+}
+
+// CreateApiToken creates a personal API token for the authenticated user. The token is only returned in this
+// response, it cannot be retrieved afterwards.
+//
+// POST /user/tokens
+//
+func (c *UsersController) CreateApiToken(ctx *gin.Context) {
+	var json bindings.CreateApiToken
+	if err := ctx.Bind(&json); err != nil {
+		helpers.JSONResponseValidationFailed(ctx, err)
+		return
+	}
+
+	token, raw, err := services.CreateApiToken(getCurrentUser(ctx), json)
+	if err != nil {
+		apiTokenError(ctx, err)
+		return
+	}
+
+	p := presenters.ApiTokenPresenter(token)
+	p.Token = raw
+	helpers.JSONResponseObject(ctx, p)
+}
+
+// RevokeApiToken deletes a personal API token of the authenticated user
+//
+// DELETE /user/tokens/:token_id
+//
+func (c *UsersController) RevokeApiToken(ctx *gin.Context) {
+	if err := services.RevokeApiToken(getCurrentUser(ctx), getApiTokenFromContext(ctx)); err != nil {
+		apiTokenError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// apiTokenError responds with the error raised while managing API tokens.
+func apiTokenError(ctx *gin.Context, err error) {
+	switch err {
+	case services.ErrNotAnOrganizationOwner, services.ErrNotAnOrganizationMember:
+		helpers.JSONForbidden(ctx, err.Error())
+	case services.ErrApiTokenNotFound:
+		ctx.AbortWithStatus(http.StatusNotFound)
+	case services.ErrInvalidApiTokenScope:
+		helpers.JSONError(ctx, http.StatusBadRequest, err)
+	default:
+		helpers.JSONResponseInternalServerError(ctx, err)
+	}
+}
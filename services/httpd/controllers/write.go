@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/messagedb/messagedb/cluster"
+	"github.com/messagedb/messagedb/db"
+	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/services/httpd/helpers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WritePath is the path of the line protocol write endpoint, its body is not JSON
+const WritePath = "/write"
+
+// WriteController handles the writes of messages in the line protocol
+type WriteController struct {
+	Engine *gin.Engine
+
+	MetaStore interface {
+		Database(name string) (*meta.DatabaseInfo, error)
+		Authenticate(username, password string) (ui *meta.UserInfo, err error)
+	}
+
+	MessagesWriter interface {
+		WriteMessages(p *cluster.WriteMessagesRequest) error
+	}
+
+	// Database is the database written to when the request does not name one
+	Database string
+
+	Logger         *log.Logger
+	loggingEnabled bool // Log every HTTP access
+	WriteTrace     bool // Detail logging of controller handler
+}
+
+// NewWriteController returns an instance of the WriteController
+func NewWriteController(engine *gin.Engine, loggingEnabled, writeTrace bool) *WriteController {
+	c := &WriteController{
+		Engine:         engine,
+		loggingEnabled: loggingEnabled,
+		WriteTrace:     writeTrace,
+	}
+
+	c.Engine.POST(WritePath, c.Write)
+
+	return c
+}
+
+// parseErrorLine is the detail of a line of the request that could not be parsed
+type parseErrorLine struct {
+	Line  int    `json:"line"`
+	Text  string `json:"text"`
+	Error string `json:"error"`
+}
+
+// Write writes the messages of the body, one per line, to the database. The messages bypass the conversation
+// policies, so only admin users are allowed to write. Nothing is written when a line cannot be parsed, the
+// response lists every such line instead.
+//
+// POST /write?db=<database>&rp=<retention policy>&precision=<precision>&consistency=<level>
+//
+func (c *WriteController) Write(ctx *gin.Context) {
+	username, password, ok := ctx.Request.BasicAuth()
+	if !ok {
+		username, password = ctx.Query("u"), ctx.Query("p")
+	}
+	if username == "" {
+		ctx.Header("WWW-Authenticate", `Basic realm="messagedb"`)
+		helpers.JSONErrorf(ctx, http.StatusUnauthorized, "username and password are required")
+		return
+	}
+	ui, err := c.MetaStore.Authenticate(username, password)
+	if err != nil {
+		helpers.JSONErrorf(ctx, http.StatusUnauthorized, err.Error())
+		return
+	} else if !ui.Admin {
+		helpers.JSONForbidden(ctx, "user %q is not allowed to write messages", username)
+		return
+	}
+
+	database := ctx.Query("db")
+	if database == "" {
+		database = c.Database
+	}
+	if database == "" {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, "database is required")
+		return
+	}
+	if di, err := c.MetaStore.Database(database); err != nil {
+		helpers.JSONErrorf(ctx, http.StatusInternalServerError, err.Error())
+		return
+	} else if di == nil {
+		helpers.JSONErrorf(ctx, http.StatusNotFound, "database not found: %q", database)
+		return
+	}
+
+	consistency := cluster.ConsistencyLevelOne
+	if level := ctx.Query("consistency"); level != "" {
+		if consistency, err = cluster.ParseConsistencyLevel(level); err != nil {
+			helpers.JSONErrorf(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	body, err := ioutil.ReadAll(ctx.Request.Body)
+	if err != nil {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	messages, err := db.ParseMessagesWithPrecision(body, time.Now().UTC(), ctx.Query("precision"))
+	if errs, ok := err.(db.ParseErrors); ok {
+		lines := make([]parseErrorLine, len(errs))
+		for i, e := range errs {
+			lines[i] = parseErrorLine{Line: e.Line, Text: e.Text, Error: e.Err.Error()}
+		}
+		helpers.JSONResponseBadRequest(ctx, gin.H{"error": "unable to parse messages", "lines": lines})
+		return
+	} else if err != nil {
+		helpers.JSONErrorf(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(messages) == 0 {
+		ctx.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	if err := c.MessagesWriter.WriteMessages(&cluster.WriteMessagesRequest{
+		Database:         database,
+		RetentionPolicy:  ctx.Query("rp"),
+		ConsistencyLevel: consistency,
+		Messages:         messages,
+	}); err != nil {
+		helpers.JSONErrorf(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.AbortWithStatus(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/messagedb/messagedb/cluster"
+	"github.com/messagedb/messagedb/meta"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeMetaStore authenticates the users of a fixed set and knows a single database
+type writeMetaStore struct {
+	users map[string]meta.UserInfo
+}
+
+func (s *writeMetaStore) Database(name string) (*meta.DatabaseInfo, error) {
+	if name != "db0" {
+		return nil, nil
+	}
+	return &meta.DatabaseInfo{Name: name}, nil
+}
+
+func (s *writeMetaStore) Authenticate(username, password string) (*meta.UserInfo, error) {
+	ui, ok := s.users[username]
+	if !ok || password != "secret" {
+		return nil, meta.ErrAuthenticate
+	}
+	return &ui, nil
+}
+
+// recordingWriter records the write requests
+type recordingWriter struct {
+	requests []*cluster.WriteMessagesRequest
+}
+
+func (w *recordingWriter) WriteMessages(p *cluster.WriteMessagesRequest) error {
+	w.requests = append(w.requests, p)
+	return nil
+}
+
+func newTestWriteController() (*WriteController, *recordingWriter) {
+	w := &recordingWriter{}
+	c := NewWriteController(gin.New(), false, false)
+	c.MetaStore = &writeMetaStore{users: map[string]meta.UserInfo{
+		"admin": {Name: "admin", Admin: true},
+		"user":  {Name: "user"},
+	}}
+	c.MessagesWriter = w
+	return c, w
+}
+
+func serveWrite(c *WriteController, query, username, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "http://example.com/write?"+query, strings.NewReader(body))
+	if username != "" {
+		req.SetBasicAuth(username, "secret")
+	}
+	res := httptest.NewRecorder()
+	c.Engine.ServeHTTP(res, req)
+	return res
+}
+
+func TestWriteController_Write(t *testing.T) {
+	c, w := newTestWriteController()
+
+	res := serveWrite(c, "db=db0&rp=default&consistency=all&precision=s", "admin",
+		"conv0,id=m1,from=u1 text=\"hello\" 10\nconv0,id=m2,from=u2 text=\"world\" 11\n")
+	if res.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d %s", res.Code, res.Body.String())
+	} else if len(w.requests) != 1 {
+		t.Fatalf("unexpected write count: %d", len(w.requests))
+	}
+
+	req := w.requests[0]
+	if req.Database != "db0" || req.RetentionPolicy != "default" || req.ConsistencyLevel != cluster.ConsistencyLevelAll {
+		t.Fatalf("unexpected request: %+v", req)
+	} else if len(req.Messages) != 2 {
+		t.Fatalf("unexpected message count: %d", len(req.Messages))
+	} else if ts := req.Messages[1].Time().Unix(); ts != 11 {
+		t.Fatalf("unexpected time: %d", ts)
+	}
+}
+
+func TestWriteController_Write_ParseErrors(t *testing.T) {
+	c, w := newTestWriteController()
+
+	res := serveWrite(c, "db=db0", "admin", "conv0,id=m1 text=\"hello\"\nconv0,id=m2\n\nconv0 text=\n")
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d %s", res.Code, res.Body.String())
+	} else if len(w.requests) != 0 {
+		t.Fatalf("unexpected write: %+v", w.requests[0])
+	}
+
+	var body struct {
+		Lines []parseErrorLine `json:"lines"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	lines := make([]int, len(body.Lines))
+	for i, l := range body.Lines {
+		lines[i] = l.Line
+		if l.Text == "" || l.Error == "" {
+			t.Fatalf("missing detail: %+v", l)
+		}
+	}
+	if !reflect.DeepEqual(lines, []int{2, 4}) {
+		t.Fatalf("unexpected lines: %v", lines)
+	}
+}
+
+func TestWriteController_Write_Errors(t *testing.T) {
+	var tests = []struct {
+		query    string
+		username string
+		code     int
+	}{
+		{query: "db=db0", code: http.StatusUnauthorized},
+		{query: "db=db0", username: "nobody", code: http.StatusUnauthorized},
+		{query: "db=db0", username: "user", code: http.StatusForbidden},
+		{query: "", username: "admin", code: http.StatusBadRequest},
+		{query: "db=db1", username: "admin", code: http.StatusNotFound},
+		{query: "db=db0&consistency=most", username: "admin", code: http.StatusBadRequest},
+	}
+
+	for i, tt := range tests {
+		c, w := newTestWriteController()
+		res := serveWrite(c, tt.query, tt.username, "conv0,id=m1 text=\"hello\"\n")
+		if res.Code != tt.code {
+			t.Errorf("%d. unexpected status: %d, exp %d", i, res.Code, tt.code)
+		} else if len(w.requests) != 0 {
+			t.Errorf("%d. unexpected write", i)
+		}
+	}
+}
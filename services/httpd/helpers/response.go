@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/messagedb/messagedb/meta/policy"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -48,6 +50,10 @@ func JSONResponseCollection(ctx *gin.Context, obj interface{}) {
 	JSONResponse(ctx, http.StatusOK, map[string]interface{}{"results": obj})
 }
 
+func JSONResponsePage(ctx *gin.Context, obj interface{}, cursors interface{}) {
+	JSONResponse(ctx, http.StatusOK, map[string]interface{}{"results": obj, "cursors": cursors})
+}
+
 func JSONResponseOK(ctx *gin.Context, obj ...interface{}) {
 	JSONResponse(ctx, http.StatusOK, obj...)
 }
@@ -93,3 +99,16 @@ func JSONErrorCodef(ctx *gin.Context, statusCode int, code int, format string, a
 	msg := fmt.Sprintf(format, a...)
 	JSONResponse(ctx, statusCode, &ResponseError{Code: code, Message: msg})
 }
+
+// DeniedError is the body of the response to a request the authorization policy denied
+type DeniedError struct {
+	Message string `json:"error"`
+	Action  string `json:"action"`
+	Role    string `json:"role"`
+}
+
+// JSONDenied responds with a forbidden error describing why the policy denied the request, and aborts it
+func JSONDenied(ctx *gin.Context, d *policy.Denial) {
+	JSONResponse(ctx, http.StatusForbidden, &DeniedError{Message: d.Reason, Action: string(d.Action), Role: d.Role.String()})
+	ctx.Abort()
+}
@@ -2,37 +2,166 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/messagedb/messagedb/meta/schema"
+	"github.com/messagedb/messagedb/meta/services"
+	"github.com/messagedb/messagedb/services/httpd/helpers"
 
 	"github.com/gin-gonic/gin"
 )
 
 const ApiTokenHeaderKey = "X-MessageDB-Api-Token"
 
-func ApiTokenMiddleware() gin.HandlerFunc {
+// ApiTokenMiddleware authenticates the requests carrying an API token in the X-MessageDB-Api-Token header. The user
+// the token was created by is set as the current user of the request, as long as the scopes of the token allow the
+// request and its rate limit is not exceeded. Requests without the header proceed untouched, the AuthenticatedFilter
+// then requires an access token.
+func ApiTokenMiddleware(limiter *RateLimiter) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		token := ctx.Request.Header.Get(ApiTokenHeaderKey)
-		if len(token) == 0 {
-			ctx.AbortWithStatus(http.StatusForbidden)
+		raw := ctx.Request.Header.Get(ApiTokenHeaderKey)
+		if len(raw) == 0 {
+			ctx.Next()
 			return
 		}
 
-		valid, err := isValidApiToken(token)
-		if err != nil {
+		token, user, err := services.ValidateApiToken(raw)
+		if err == services.ErrInvalidApiToken {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		} else if err != nil {
 			ctx.AbortWithStatus(http.StatusInternalServerError)
 			return
 		}
 
-		if !valid {
-			ctx.AbortWithStatus(http.StatusForbidden)
+		if !apiTokenAllows(token, ctx.Request.Method, ctx.Request.URL.Path) {
+			helpers.JSONForbidden(ctx, "API token scopes do not allow this request")
+			ctx.Abort()
+			return
+		}
+
+		if ok, retryAfter := limiter.Allow(token.ID.Hex(), token.RateLimit); !ok {
+			ctx.Header("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+			helpers.JSONErrorf(ctx, http.StatusTooManyRequests, "API token rate limit exceeded")
+			ctx.Abort()
 			return
 		}
 
+		ctx.Set("currentUser", user)
+		ctx.Set("apiToken", token)
 		ctx.Next()
 	}
 }
 
-func isValidApiToken(token string) (bool, error) {
-	//TODO: check if Api Token is a valid one here...
-	return true, nil
+// apiTokenAllows returns true if the scopes of the token allow the request. The admin scope allows any request, while
+// the messages scopes only allow reading and writing the messages of conversations. Integration tokens are restricted
+// to the conversations, teams and organization endpoints, the filters loading those resources reject the ones of
+// other organizations.
+func apiTokenAllows(token *schema.ApiToken, method, path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if token.IsIntegration() {
+		if len(parts) < 2 || (parts[0] != "conversations" && parts[0] != "orgs" && parts[0] != "teams") {
+			return false
+		}
+	}
+	if token.HasScope(schema.ApiTokenScopeAdmin) {
+		return true
+	}
+
+	read := method == "GET" || method == "HEAD"
+	if read && token.HasScope(schema.ApiTokenScopeReadMessages) {
+		switch {
+		case parts[0] == "conversations" && len(parts) > 1:
+			return true
+		case parts[0] == "orgs" && len(parts) == 3 && parts[2] == "conversations":
+			return true
+		case !token.IsIntegration():
+			p := strings.Join(parts, "/")
+			return p == "search/messages" || p == "user/mentions" || p == "stream" || p == "me/conversations"
+		}
+		return false
+	}
+
+	if !read && token.HasScope(schema.ApiTokenScopeWriteMessages) {
+		if parts[0] == "conversations" && len(parts) > 2 {
+			switch parts[2] {
+			case "messages", "attachments", "read", "pulses":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RateLimiter limits the number of requests made with each API token within windows of a minute.
+type RateLimiter struct {
+	mu      sync.Mutex
+	limit   int64 // requests per window when a token has no limit of its own, zero for unlimited
+	window  time.Duration
+	counts  map[string]*rateCount
+	sweepAt time.Time
+
+	now func() time.Time
+}
+
+type rateCount struct {
+	start time.Time
+	n     int64
+}
+
+// NewRateLimiter returns a rate limiter allowing limit requests per minute to the tokens without a limit of their own.
+func NewRateLimiter(limit int64) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: time.Minute,
+		counts: make(map[string]*rateCount),
+		now:    time.Now,
+	}
+}
+
+// Allow records a request made with the token key and returns true if the limit of the current window is not
+// exceeded. Otherwise it returns the time left until the window ends. The default limit applies when limit is zero.
+func (l *RateLimiter) Allow(key string, limit int64) (bool, time.Duration) {
+	if limit <= 0 {
+		limit = l.limit
+	}
+	if limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	c, ok := l.counts[key]
+	if !ok || now.Sub(c.start) >= l.window {
+		c = &rateCount{start: now}
+		l.counts[key] = c
+	}
+
+	if c.n >= limit {
+		return false, c.start.Add(l.window).Sub(now)
+	}
+	c.n++
+	return true, 0
+}
+
+// sweep forgets the windows that ended, once per window.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Before(l.sweepAt) {
+		return
+	}
+	for key, c := range l.counts {
+		if now.Sub(c.start) >= l.window {
+			delete(l.counts, key)
+		}
+	}
+	l.sweepAt = now.Add(l.window)
 }
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/meta/schema"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Ensure the scopes of API tokens decide which requests they can make.
+func TestApiTokenAllows(t *testing.T) {
+	read := &schema.ApiToken{Scopes: []string{schema.ApiTokenScopeReadMessages}}
+	write := &schema.ApiToken{Scopes: []string{schema.ApiTokenScopeWriteMessages}}
+	admin := &schema.ApiToken{Scopes: []string{schema.ApiTokenScopeAdmin}}
+	bot := &schema.ApiToken{OrganizationID: bson.NewObjectId(), Scopes: []string{schema.ApiTokenScopeAdmin}}
+
+	var tests = []struct {
+		token  *schema.ApiToken
+		method string
+		path   string
+		exp    bool
+	}{
+		{read, "GET", "/conversations/c0/messages", true},
+		{read, "POST", "/conversations/c0/messages", false},
+		{read, "GET", "/search/messages", true},
+		{read, "GET", "/me", false},
+		{write, "POST", "/conversations/c0/messages", true},
+		{write, "PATCH", "/conversations/c0/messages/m0", true},
+		{write, "PATCH", "/conversations/c0", false},
+		{write, "GET", "/conversations/c0/messages", false},
+		{admin, "POST", "/orgs", true},
+		{admin, "PATCH", "/me", true},
+
+		// integration tokens stay within the endpoints of organizations
+		{bot, "POST", "/conversations/c0/messages", true},
+		{bot, "GET", "/orgs/acme/members", true},
+		{bot, "POST", "/orgs", false},
+		{bot, "GET", "/me", false},
+		{bot, "GET", "/search/messages", false},
+	}
+
+	for i, tt := range tests {
+		if got := apiTokenAllows(tt.token, tt.method, tt.path); got != tt.exp {
+			t.Errorf("%d. %v %s %s: got %v, exp %v", i, tt.token.Scopes, tt.method, tt.path, got, tt.exp)
+		}
+	}
+}
+
+// Ensure the rate limiter allows a number of requests per token and window.
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(2)
+	l.now = func() time.Time { return now }
+
+	if ok, _ := l.Allow("k0", 0); !ok {
+		t.Fatal("expected first request to be allowed")
+	} else if ok, _ := l.Allow("k0", 0); !ok {
+		t.Fatal("expected second request to be allowed")
+	}
+
+	now = now.Add(20 * time.Second)
+	if ok, retryAfter := l.Allow("k0", 0); ok {
+		t.Fatal("expected third request to be limited")
+	} else if retryAfter != 40*time.Second {
+		t.Fatalf("unexpected retry after: %v", retryAfter)
+	}
+
+	// Tokens with a limit of their own are counted separately.
+	if ok, _ := l.Allow("k1", 3); !ok {
+		t.Fatal("expected request of another token to be allowed")
+	}
+
+	now = now.Add(40 * time.Second)
+	if ok, _ := l.Allow("k0", 0); !ok {
+		t.Fatal("expected request of a new window to be allowed")
+	}
+}
@@ -10,8 +10,16 @@ import (
 
 const ContentTypeHeaderKey = "Content-Type"
 
-func ContentTypeCheckerMiddleware() gin.HandlerFunc {
+// ContentTypeCheckerMiddleware rejects the requests whose body is not JSON, except the requests to the paths
+// that read their own body format
+func ContentTypeCheckerMiddleware(rawPaths ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		for _, p := range rawPaths {
+			if ctx.Request.URL.Path == p {
+				ctx.Next()
+				return
+			}
+		}
 
 		header := ctx.Request.Header.Get(ContentTypeHeaderKey)
 		mediatype, params, _ := mime.ParseMediaType(header)
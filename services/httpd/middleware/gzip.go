@@ -4,6 +4,9 @@ import (
 	"compress/gzip"
 	"net/http"
 	"strings"
+
+	gingzip "github.com/gin-gonic/contrib/gzip"
+	"github.com/gin-gonic/gin"
 )
 
 // These compression constants are copied from the compress/gzip package.
@@ -16,6 +19,9 @@ const (
 	headerContentType     = "Content-Type"
 	headerVary            = "Vary"
 	headerSecWebSocketKey = "Sec-WebSocket-Key"
+	headerAccept          = "Accept"
+
+	mimeEventStream = "text/event-stream"
 
 	BestCompression    = gzip.BestCompression
 	BestSpeed          = gzip.BestSpeed
@@ -69,6 +75,20 @@ func GzipHandler(compressionLevel int) func(http.Handler) http.Handler {
 	}
 }
 
+// GzipMiddleware compresses the response with Gzip if supported by the client. WebSocket connections and
+// Server-Sent Events streams are not compressed since their events must reach the client as they are written.
+func GzipMiddleware(compressionLevel int) gin.HandlerFunc {
+	compress := gingzip.Gzip(compressionLevel)
+	return func(ctx *gin.Context) {
+		req := ctx.Request
+		if len(req.Header.Get(headerSecWebSocketKey)) > 0 || strings.Contains(req.Header.Get(headerAccept), mimeEventStream) {
+			ctx.Next()
+			return
+		}
+		compress(ctx)
+	}
+}
+
 // gzipResponseWriter is the ResponseWriter that negroni.ResponseWriter is
 // wrapped in.
 type gzipResponseWriter struct {
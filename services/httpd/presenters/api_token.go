@@ -0,0 +1,53 @@
+package presenters
+
+import (
+	"time"
+
+	"github.com/messagedb/messagedb/meta/schema"
+)
+
+// ApiToken presents the schema.ApiToken record that is return to the API responses. The token itself is only
+// presented once, when it is created
+type ApiToken struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Token          string     `json:"token,omitempty"`
+	UserID         string     `json:"user_id"`
+	OrganizationID string     `json:"org_id,omitempty"`
+	Scopes         []string   `json:"scopes"`
+	RateLimit      int64      `json:"rate_limit,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ApiTokenPresenter creates a new instance of the ApiToken presenter
+func ApiTokenPresenter(t *schema.ApiToken) *ApiToken {
+	token := &ApiToken{}
+	token.ID = t.ID.Hex()
+	token.Name = t.Name
+	token.UserID = t.UserID.Hex()
+	if t.IsIntegration() {
+		token.OrganizationID = t.OrganizationID.Hex()
+	}
+	token.Scopes = t.Scopes
+	token.RateLimit = t.RateLimit
+	if !t.ExpiresAt.IsZero() {
+		token.ExpiresAt = &t.ExpiresAt
+	}
+	if !t.LastUsedAt.IsZero() {
+		token.LastUsedAt = &t.LastUsedAt
+	}
+	token.CreatedAt = t.CreatedAt
+
+	return token
+}
+
+// ApiTokenCollectionPresenter creates a collection of presenters for ApiToken
+func ApiTokenCollectionPresenter(items []*schema.ApiToken) []*ApiToken {
+	var collection []*ApiToken
+	for _, item := range items {
+		collection = append(collection, ApiTokenPresenter(item))
+	}
+	return collection
+}
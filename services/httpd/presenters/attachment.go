@@ -0,0 +1,59 @@
+package presenters
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+)
+
+// Attachment is a presenter for the meta.AttachmentInfo model
+type Attachment struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversation_id"`
+	MessageID      string    `json:"message_id,omitempty"`
+	UploaderID     string    `json:"uploader_id"`
+	Filename       string    `json:"filename"`
+	ContentType    string    `json:"content_type"`
+	FileSize       int64     `json:"file_size"`
+	Md5            string    `json:"md5"`
+	Sha256         string    `json:"sha256"`
+	DownloadURL    string    `json:"download_url"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// GetLocation returns the API location for the attachment resource
+func (a *Attachment) GetLocation() *url.URL {
+	uri, err := url.Parse(fmt.Sprintf("/conversations/%s/attachments/%s", a.ConversationID, a.ID))
+	if err != nil {
+		return nil
+	}
+	return uri
+}
+
+// AttachmentPresenter creates a new instance of the presenter for the AttachmentInfo model
+func AttachmentPresenter(ai *meta.AttachmentInfo) *Attachment {
+	return &Attachment{
+		ID:             ai.ID,
+		ConversationID: ai.ConversationID,
+		MessageID:      ai.MessageID,
+		UploaderID:     ai.UploaderID,
+		Filename:       ai.Filename,
+		ContentType:    ai.ContentType,
+		FileSize:       ai.Size,
+		Md5:            ai.MD5,
+		Sha256:         ai.SHA256,
+		DownloadURL:    fmt.Sprintf("/conversations/%s/attachments/%s/download", ai.ConversationID, ai.ID),
+		CreatedAt:      ai.CreatedAt,
+	}
+}
+
+// AttachmentCollectionPresenter creates an array of presenters for the AttachmentInfo model
+func AttachmentCollectionPresenter(items []meta.AttachmentInfo) []*Attachment {
+	collection := []*Attachment{}
+	for i := range items {
+		collection = append(collection, AttachmentPresenter(&items[i]))
+	}
+	return collection
+}
@@ -3,15 +3,18 @@ package presenters
 import (
 	"fmt"
 	"net/url"
+	"time"
 
+	"github.com/messagedb/messagedb/meta"
 	"github.com/messagedb/messagedb/meta/schema"
 )
 
 // Conversation is a presenter for the schema.Conversation model
 type Conversation struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
-	Purpose string `json:"purpose"`
+	ID                string `json:"id"`
+	Title             string `json:"title"`
+	Purpose           string `json:"purpose"`
+	ParticipantsCount int    `json:"participants_count"`
 
 	Namespace struct {
 		ID        string `json:"id,omitempty"`
@@ -35,6 +38,7 @@ func ConversationPresenter(c *schema.Conversation) *Conversation {
 	conversation.ID = c.ID.Hex()
 	conversation.Title = c.Title
 	conversation.Purpose = c.Purpose
+	conversation.ParticipantsCount = c.ParticipantsCount
 
 	conversation.Namespace.ID = c.Namespace.ID.Hex()
 	conversation.Namespace.Path = c.Namespace.Path
@@ -50,3 +54,29 @@ func ConversationCollectionPresenter(items []*schema.Conversation) []*Conversati
 	}
 	return collection
 }
+
+// ConversationReadState is a presenter for the unread messages of a conversation for the current user. Only the
+// latest messages of the conversation are counted, so the unread and mention counts are capped, see
+// MaxUnreadCount in the controllers.
+type ConversationReadState struct {
+	ID                string     `json:"id"`
+	UnreadCount       int        `json:"unread_count"`
+	MentionCount      int        `json:"mention_count"`
+	LastReadMessageID string     `json:"last_read_message_id,omitempty"`
+	LastReadAt        *time.Time `json:"last_read_at,omitempty"`
+}
+
+// ConversationReadStatePresenter creates a new instance of the presenter for the read marker of a conversation
+func ConversationReadStatePresenter(conversationID string, marker *meta.ReadMarkerInfo, unread, mentions int) *ConversationReadState {
+	state := &ConversationReadState{}
+	state.ID = conversationID
+	state.UnreadCount = unread
+	state.MentionCount = mentions
+
+	if marker != nil {
+		state.LastReadMessageID = marker.MessageID
+		state.LastReadAt = &marker.Time
+	}
+
+	return state
+}
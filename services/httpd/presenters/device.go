@@ -0,0 +1,39 @@
+package presenters
+
+import (
+	"time"
+
+	"github.com/messagedb/messagedb/meta/schema"
+)
+
+// Device presents the schema.Device record that is return to the API responses
+type Device struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Platform  string    `json:"platform,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DevicePresenter creates a new instance of the Device presenter
+func DevicePresenter(d *schema.Device) *Device {
+	device := &Device{}
+	device.ID = d.Id.Hex()
+	device.UserID = d.UserId.Hex()
+	device.Name = d.Name
+	device.Platform = d.Platform
+	device.CreatedAt = d.CreatedAt
+	device.UpdatedAt = d.UpdatedAt
+
+	return device
+}
+
+// DeviceCollectionPresenter creates a collection of presenters for Device
+func DeviceCollectionPresenter(items []*schema.Device) []*Device {
+	var collection []*Device
+	for _, item := range items {
+		collection = append(collection, DevicePresenter(item))
+	}
+	return collection
+}
@@ -0,0 +1,34 @@
+package presenters
+
+import (
+	"time"
+
+	"github.com/messagedb/messagedb/cluster"
+)
+
+// Event is a presenter for a real-time event sent over the stream
+type Event struct {
+	Type           string    `json:"type"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	UserID         string    `json:"user_id,omitempty"`
+	Time           time.Time `json:"time"`
+	Message        *Message  `json:"message,omitempty"`
+	MessageID      string    `json:"message_id,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// EventPresenter creates a new instance of the presenter for the cluster.Event model
+func EventPresenter(e *cluster.Event) *Event {
+	event := &Event{}
+	event.Type = string(e.Type)
+	event.ConversationID = e.Conversation
+	event.UserID = e.UserID
+	event.Time = e.Time
+	event.MessageID = e.MessageID
+
+	if e.Message != nil {
+		event.Message = MessagePresenter(e.Message)
+	}
+
+	return event
+}
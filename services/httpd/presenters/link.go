@@ -0,0 +1,43 @@
+package presenters
+
+import (
+	"time"
+
+	"github.com/messagedb/messagedb/db"
+)
+
+// Link is a presenter for a link shared in a message
+type Link struct {
+	URL            string `json:"url"`
+	ConversationID string `json:"conversation_id"`
+	MessageID      string `json:"message_id"`
+
+	From struct {
+		UserID string `json:"user_id"`
+		Name   string `json:"name,omitempty"`
+	} `json:"from"`
+
+	CreatedAt time.Time `json:"created_at"`
+
+	// Preview of the target of the link, when unfurled
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// LinkPresenter creates a new instance of the presenter for the db.Link model
+func LinkPresenter(conversationID string, l *db.Link) *Link {
+	link := &Link{URL: l.URL, ConversationID: conversationID, MessageID: l.MessageID}
+	link.From.UserID = l.SenderID
+	link.From.Name = l.SenderName
+	link.CreatedAt = time.Unix(0, l.Timestamp).UTC()
+	return link
+}
+
+// LinkCollectionPresenter creates an array of presenters for the db.Link model
+func LinkCollectionPresenter(conversationID string, items db.Links) []*Link {
+	collection := []*Link{}
+	for _, item := range items {
+		collection = append(collection, LinkPresenter(conversationID, item))
+	}
+	return collection
+}
@@ -13,9 +13,11 @@ type Member struct {
 	OrganizationID   string    `json:"org_id"`
 	State            string    `json:"status"`
 	Published        bool      `json:"published"`
+	Role             string    `json:"role"`
 	InvitedBy        string    `json:"invited_by,omitempty"`
 	InviteEmail      string    `json:"invite_email,omitempty"`
 	InviteSentAt     time.Time `json:"invite_sent_at,omitempty"`
+	InviteExpiresAt  time.Time `json:"invite_expires_at,omitempty"`
 	InviteAcceptedAt time.Time `json:"invite_accepted_at,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
@@ -29,9 +31,11 @@ func MemberPresenter(m *schema.Member) *Member {
 	member.OrganizationID = m.OrganizationID.Hex()
 	member.State = m.State
 	member.Published = m.Published
+	member.Role = m.Role.String()
 	member.InvitedBy = m.InvitedBy.Hex()
 	member.InviteEmail = m.InviteEmail
 	member.InviteSentAt = m.InviteSentAt
+	member.InviteExpiresAt = m.InviteExpiresAt
 	member.InviteAcceptedAt = m.InviteAcceptedAt
 	member.CreatedAt = m.CreatedAt
 	member.UpdatedAt = m.UpdatedAt
@@ -0,0 +1,143 @@
+package presenters
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/messagedb/messagedb/db"
+)
+
+// Message is a presenter for the db.Message model
+type Message struct {
+	ID             string `json:"id"`
+	ConversationID string `json:"conversation_id"`
+	ParentID       string `json:"parent_id,omitempty"`
+	Kind           string `json:"kind"`
+
+	From struct {
+		UserID string `json:"user_id"`
+		Name   string `json:"name,omitempty"`
+	} `json:"from"`
+
+	Content struct {
+		PlainText string `json:"plain_text"`
+		HTML      string `json:"html,omitempty"`
+	} `json:"content"`
+
+	Snippet *Snippet `json:"snippet,omitempty"`
+
+	// Event is the event of the conversation recorded by a system message
+	Event string `json:"event,omitempty"`
+
+	Mentions []*Mention             `json:"mentions,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	EditedBy  string     `json:"edited_by,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+
+	// ReplyCount is the number of replies in the thread started by the message
+	ReplyCount int `json:"reply_count,omitempty"`
+
+	Reactions   []*Reaction   `json:"reactions,omitempty"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+}
+
+// Mention is a presenter for a user mentioned in a message
+type Mention struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username,omitempty"`
+}
+
+// Snippet is a presenter for the code snippet of a message
+type Snippet struct {
+	Language string `json:"language,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Body     string `json:"body"`
+	Size     int    `json:"size"`
+	RawURL   string `json:"raw_url"`
+}
+
+// Reaction is a presenter for the reactions to a message with the same emoji
+type Reaction struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// ReactionCollectionPresenter creates an array of presenters for the reactions to a message, where reacted tells
+// whether the user with the given ID is one of the users that reacted
+func ReactionCollectionPresenter(items db.ReactionSummaries, userID string) []*Reaction {
+	collection := []*Reaction{}
+	for _, item := range items {
+		collection = append(collection, &Reaction{Emoji: item.Emoji, Count: item.Count, Reacted: item.Reacted(userID)})
+	}
+	return collection
+}
+
+// Cursors is a presenter for the cursors used to page through the messages of a conversation
+type Cursors struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// GetLocation returns the API location for the message resource
+func (m *Message) GetLocation() *url.URL {
+	uri, err := url.Parse(fmt.Sprintf("/conversations/%s/messages/%s", m.ConversationID, m.ID))
+	if err != nil {
+		return nil
+	}
+	return uri
+}
+
+// MessagePresenter creates a new instance of the presenter for the Message model
+func MessagePresenter(m db.Message) *Message {
+	message := &Message{}
+	message.ID = m.ID()
+	message.ConversationID = string(m.Key())
+	message.ParentID = m.ParentID()
+	message.Kind = m.Kind()
+	message.Event = m.Event()
+	message.From.UserID = m.From().UserID
+	message.From.Name = m.From().Name
+	message.Content.PlainText = m.Content().PlainText
+	message.Content.HTML = m.Content().HTML
+	message.CreatedAt = m.Time()
+	message.EditedBy = m.EditedBy()
+	message.Deleted = m.Deleted()
+
+	for _, mention := range m.Mentions() {
+		message.Mentions = append(message.Mentions, &Mention{UserID: mention.RecipientID, Username: mention.RecipientUsername})
+	}
+
+	if s := m.Snippet(); s != nil {
+		message.Snippet = &Snippet{
+			Language: s.Language,
+			Filename: s.Filename,
+			Body:     s.Body,
+			Size:     len(s.Body),
+			RawURL:   fmt.Sprintf("/conversations/%s/snippets/%s/raw", message.ConversationID, message.ID),
+		}
+	}
+
+	if fields := m.Fields(); len(fields) > 0 {
+		message.Fields = fields
+	}
+
+	if editedAt := m.EditedAt(); !editedAt.IsZero() {
+		message.EditedAt = &editedAt
+	}
+
+	return message
+}
+
+// MessageCollectionPresenter creates an array of presenters for the Message model
+func MessageCollectionPresenter(items []db.Message) []*Message {
+	collection := []*Message{}
+	for _, item := range items {
+		collection = append(collection, MessagePresenter(item))
+	}
+	return collection
+}
@@ -0,0 +1,45 @@
+package presenters
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/messagedb/messagedb/meta"
+)
+
+// Participant is a presenter for the membership of a user in a conversation
+type Participant struct {
+	UserID         string    `json:"user_id"`
+	ConversationID string    `json:"conversation_id"`
+	JoinedAt       time.Time `json:"joined_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// GetLocation returns the API location for the participant resource
+func (p *Participant) GetLocation() *url.URL {
+	uri, err := url.Parse(fmt.Sprintf("/conversations/%s/participants/%s", p.ConversationID, p.UserID))
+	if err != nil {
+		return nil
+	}
+	return uri
+}
+
+// ParticipantPresenter creates a new instance of the presenter for the meta.ParticipantInfo model
+func ParticipantPresenter(pi *meta.ParticipantInfo) *Participant {
+	return &Participant{
+		UserID:         pi.UserID,
+		ConversationID: pi.ConversationID,
+		JoinedAt:       pi.JoinedAt,
+		LastActivityAt: pi.LastActivityAt,
+	}
+}
+
+// ParticipantCollectionPresenter creates an array of presenters for the meta.ParticipantInfo model
+func ParticipantCollectionPresenter(items []meta.ParticipantInfo) []*Participant {
+	collection := []*Participant{}
+	for i := range items {
+		collection = append(collection, ParticipantPresenter(&items[i]))
+	}
+	return collection
+}
@@ -1,48 +1,56 @@
 package presenters
 
-// import (
-// 	"fmt"
-// 	"net/url"
-// 	"time"
+import (
+	"fmt"
+	"net/url"
+	"time"
 
-// 	"github.com/messagedb/messagedb/meta/schema"
-// )
+	"github.com/messagedb/messagedb/meta/schema"
+)
 
-// type Team struct {
-// 	Id             string          `json:"id"`
-// 	OrganizationId string          `json:"org_id"`
-// 	Name           string          `json:"name"`
-// 	Description    string          `json:"description"`
-// 	TeamType       schema.TeamType `json:"team_type"`
-// 	CreatedAt      time.Time       `json:"created_at"`
-// 	UpdatedAt      time.Time       `json:"updated_at"`
-// }
+// Team presents the schema.Team record that is return to the API responses
+type Team struct {
+	ID                 string    `json:"id"`
+	OrganizationID     string    `json:"org_id"`
+	Name               string    `json:"name"`
+	Description        string    `json:"description"`
+	TeamType           string    `json:"team_type"`
+	MembersCount       int       `json:"members_count"`
+	ConversationsCount int       `json:"conversations_count"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
 
-// func (t *Team) GetLocation() *url.URL {
-// 	uri, err := url.Parse(fmt.Sprintf("/teams/%s", t.Id))
-// 	if err != nil {
-// 		return nil
-// 	}
-// 	return uri
-// }
+// GetLocation returns the URI of the team
+func (t *Team) GetLocation() *url.URL {
+	uri, err := url.Parse(fmt.Sprintf("/teams/%s", t.ID))
+	if err != nil {
+		return nil
+	}
+	return uri
+}
 
-// func TeamPresenter(t *schema.Team) *Team {
-// 	team := &Team{}
-// 	team.Id = t.Id.Hex()
-// 	team.OrganizationId = t.OrganizationId.Hex()
-// 	team.Name = t.Name
-// 	team.Description = t.Description
-// 	team.TeamType = t.TeamType
-// 	team.CreatedAt = t.CreatedAt
-// 	team.UpdatedAt = t.UpdatedAt
+// TeamPresenter creates a new instance of the Team presenter
+func TeamPresenter(t *schema.Team) *Team {
+	team := &Team{}
+	team.ID = t.ID.Hex()
+	team.OrganizationID = t.OrganizationID.Hex()
+	team.Name = t.Name
+	team.Description = t.Description
+	team.TeamType = t.TeamType.String()
+	team.MembersCount = len(t.MemberIDs)
+	team.ConversationsCount = len(t.Conversations)
+	team.CreatedAt = t.CreatedAt
+	team.UpdatedAt = t.UpdatedAt
 
-// 	return team
-// }
+	return team
+}
 
-// func TeamCollectionPresenter(items []*schema.Team) []*Team {
-// 	collection := make([]*Team, 0)
-// 	for _, item := range items {
-// 		collection = append(collection, TeamPresenter(item))
-// 	}
-// 	return collection
-// }
+// TeamCollectionPresenter creates a collection of presenters for Team
+func TeamCollectionPresenter(items []*schema.Team) []*Team {
+	collection := make([]*Team, 0)
+	for _, item := range items {
+		collection = append(collection, TeamPresenter(item))
+	}
+	return collection
+}
@@ -7,14 +7,19 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/messagedb/messagedb/blob"
 	"github.com/messagedb/messagedb/cluster"
 	"github.com/messagedb/messagedb/db"
 	"github.com/messagedb/messagedb/meta"
+	"github.com/messagedb/messagedb/meta/services"
+	"github.com/messagedb/messagedb/notify"
+	"github.com/messagedb/messagedb/oidc"
 	"github.com/messagedb/messagedb/services/httpd/controllers"
 	"github.com/messagedb/messagedb/services/httpd/middleware"
+	"github.com/messagedb/messagedb/unfurl"
 
-	"github.com/gin-gonic/contrib/gzip"
 	"github.com/gin-gonic/gin"
 	"github.com/itsjamie/gin-cors"
 	"golang.org/x/net/netutil"
@@ -30,12 +35,15 @@ type Service struct {
 	Version  string
 
 	PingController          *controllers.PingController
+	WriteController         *controllers.WriteController
 	SessionController       *controllers.SessionController
 	UsersController         *controllers.UsersController
 	DevicesController       *controllers.DevicesController
 	OrganizationsController *controllers.OrganizationsController
+	TeamsController         *controllers.TeamsController
 	ConversationsController *controllers.ConversationsController
 	MessagesController      *controllers.MessagesController
+	StreamController        *controllers.StreamController
 
 	Logger *log.Logger
 }
@@ -50,13 +58,48 @@ func NewService(c Config) *Service {
 		Logger:  log.New(os.Stderr, "[httpd] ", log.LstdFlags),
 	}
 
+	// API tokens authenticate the requests before the routes registered by the controllers
+	s.router.Use(middleware.ApiTokenMiddleware(middleware.NewRateLimiter(c.ApiTokenRateLimit)))
+
 	s.PingController = s.setupPingController(c)
+	s.WriteController = s.setupWriteController(c)
 	s.SessionController = s.setupSessionController(c)
 	s.UsersController = s.setupUsersController(c)
 	s.DevicesController = s.setupDevicesController(c)
 	s.OrganizationsController = s.setupOrganizationsController(c)
+	s.TeamsController = s.setupTeamsController(c)
 	s.ConversationsController = s.setupConversationsController(c)
 	s.MessagesController = s.setupMessagesController(c)
+	s.StreamController = s.setupStreamController(c)
+
+	services.InvitationExpiry = time.Duration(c.InvitationExpiry)
+	services.InvitationURL = c.InvitationURL
+	services.EmailVerificationURL = c.EmailVerificationURL
+	services.PasswordResetURL = c.PasswordResetURL
+	if c.EmailVerificationExpiry > 0 {
+		services.EmailVerificationExpiry = time.Duration(c.EmailVerificationExpiry)
+	}
+	if c.PasswordResetExpiry > 0 {
+		services.PasswordResetExpiry = time.Duration(c.PasswordResetExpiry)
+	}
+
+	if c.AccessTokenExpiry > 0 {
+		services.Auth.AccessTokenExpiry = time.Duration(c.AccessTokenExpiry)
+	}
+	if c.RefreshTokenExpiry > 0 {
+		services.Auth.RefreshTokenExpiry = time.Duration(c.RefreshTokenExpiry)
+	}
+	if len(c.JWTKeys) > 0 {
+		keys := make(map[string][]byte, len(c.JWTKeys))
+		for _, k := range c.JWTKeys {
+			keys[k.ID] = []byte(k.Secret)
+		}
+		if err := services.Auth.SetKeys(c.signingKeyID(), keys); err != nil {
+			s.Logger.Printf("jwt-signing-key: %s", err)
+		}
+	} else {
+		s.Logger.Println("no jwt-keys configured, tokens are signed with a random key and are only valid on this node until it restarts")
+	}
 
 	return s
 }
@@ -68,6 +111,15 @@ func (s *Service) SetMetaStore(metaStore *meta.Store) {
 	s.OrganizationsController.MetaStore = metaStore
 	s.ConversationsController.MetaStore = metaStore
 	s.MessagesController.MetaStore = metaStore
+	s.StreamController.MetaStore = metaStore
+	s.WriteController.MetaStore = metaStore
+
+	s.UsersController.Participants = metaStore
+	s.ConversationsController.Participants = metaStore
+	s.MessagesController.Participants = metaStore
+	s.StreamController.Participants = metaStore
+
+	services.SetMetaStore(metaStore)
 }
 
 func (s *Service) SetDataStore(dataStore *db.Store) {
@@ -75,11 +127,35 @@ func (s *Service) SetDataStore(dataStore *db.Store) {
 }
 
 func (s *Service) SetQueryExecutor(executor *db.QueryExecutor) {
+	s.ConversationsController.QueryExecutor = executor
+	s.UsersController.QueryExecutor = executor
 	s.MessagesController.QueryExecutor = executor
+	s.StreamController.QueryExecutor = executor
 }
 
 func (s *Service) SetMessagesWriter(writer *cluster.MessagesWriter) {
 	s.MessagesController.MessagesWriter = writer
+	s.ConversationsController.MessagesWriter = writer
+	s.WriteController.MessagesWriter = writer
+}
+
+// SetPublisher sets the hub streams subscribe to and the publisher of the events sent by clients.
+func (s *Service) SetPublisher(hub *cluster.Hub, publisher *cluster.Publisher) {
+	s.StreamController.Hub = hub
+	s.StreamController.Publisher = publisher
+	s.MessagesController.Publisher = publisher
+}
+
+// SetBlobStore sets the store of the files attached to messages, and the maximum size of an uploaded file.
+func (s *Service) SetBlobStore(store blob.Store, maxSize int64) {
+	s.ConversationsController.BlobStore = store
+	s.ConversationsController.MaxAttachmentSize = maxSize
+}
+
+// SetNotifier sets the notifier that delivers the invitations to join an organization, the email verifications and the
+// password resets.
+func (s *Service) SetNotifier(n notify.Notifier) {
+	services.SetNotifier(n)
 }
 
 func (s *Service) setupPingController(config Config) *controllers.PingController {
@@ -88,14 +164,33 @@ func (s *Service) setupPingController(config Config) *controllers.PingController
 	return c
 }
 
+func (s *Service) setupWriteController(config Config) *controllers.WriteController {
+	c := controllers.NewWriteController(s.router, config.LogEnabled, config.WriteTracing)
+	c.Database = config.Database
+	c.Logger = s.Logger
+	return c
+}
+
 func (s *Service) setupSessionController(config Config) *controllers.SessionController {
 	c := controllers.NewSessionController(s.router, config.LogEnabled, config.WriteTracing)
 	c.Logger = s.Logger
+	c.Providers = make(map[string]*oidc.Provider, len(config.OIDCProviders))
+	for _, p := range config.OIDCProviders {
+		c.Providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
+	}
 	return c
 }
 
 func (s *Service) setupUsersController(config Config) *controllers.UsersController {
 	c := controllers.NewUsersController(s.router, config.LogEnabled, config.WriteTracing)
+	c.Database = config.Database
 	c.Logger = s.Logger
 	return c
 }
@@ -112,14 +207,32 @@ func (s *Service) setupOrganizationsController(config Config) *controllers.Organ
 	return c
 }
 
+func (s *Service) setupTeamsController(config Config) *controllers.TeamsController {
+	c := controllers.NewTeamsController(s.router, config.LogEnabled, config.WriteTracing)
+	c.Logger = s.Logger
+	return c
+}
+
 func (s *Service) setupConversationsController(config Config) *controllers.ConversationsController {
 	c := controllers.NewConversationsController(s.router, config.LogEnabled, config.WriteTracing)
+	c.Database = config.Database
+	if config.UnfurlEnabled {
+		c.LinkFetcher = unfurl.NewCache(unfurl.NewHTTPFetcher(unfurl.DefaultTimeout), DefaultUnfurlCacheSize, DefaultUnfurlCacheTTL)
+	}
 	c.Logger = s.Logger
 	return c
 }
 
 func (s *Service) setupMessagesController(config Config) *controllers.MessagesController {
 	c := controllers.NewMessagesController(s.router, config.LogEnabled, config.WriteTracing)
+	c.Database = config.Database
+	c.Logger = s.Logger
+	return c
+}
+
+func (s *Service) setupStreamController(config Config) *controllers.StreamController {
+	c := controllers.NewStreamController(s.router, config.LogEnabled, config.WriteTracing)
+	c.Database = config.Database
 	c.Logger = s.Logger
 	return c
 }
@@ -183,15 +296,15 @@ func NewRouter() *gin.Engine {
 	router.RedirectTrailingSlash = true
 	router.RedirectFixedPath = true
 
-	router.Use(gzip.Gzip(gzip.DefaultCompression))
-	router.Use(middleware.ContentTypeCheckerMiddleware())
+	router.Use(middleware.GzipMiddleware(middleware.DefaultCompression))
+	router.Use(middleware.ContentTypeCheckerMiddleware(controllers.WritePath))
 	router.Use(middleware.RequestIdMiddleware())
 	router.Use(middleware.RevisionMiddleware())
 
 	router.Use(cors.Middleware(cors.Config{
 		Origins:         "*",
 		Methods:         "GET, PUT, POST, DELETE, PATCH, OPTIONS",
-		RequestHeaders:  "Origin, Authorization, Content-Type",
+		RequestHeaders:  "Origin, Authorization, Content-Type, " + middleware.ApiTokenHeaderKey,
 		ExposedHeaders:  "",
 		MaxAge:          1728000,
 		Credentials:     true,
@@ -99,6 +99,10 @@ func (*SetPasswordUserStatement) node()  {}
 
 func (*ShowGrantsForUserStatement) node()       {}
 func (*ShowDevicesForUserStatement) node()      {}
+func (*ShowMentionsStatement) node()            {}
+func (*ShowLinksStatement) node()               {}
+func (*ShowRepliesStatement) node()             {}
+func (*ShowReactionsStatement) node()           {}
 func (*ShowServersStatement) node()             {}
 func (*ShowDatabasesStatement) node()           {}
 func (*ShowRetentionPoliciesStatement) node()   {}
@@ -712,10 +716,25 @@ func Eval(expr Expr, m map[string]interface{}) interface{} {
 	}
 }
 
+// TextMatcher is implemented by values that can be searched with the MATCHES operator.
+type TextMatcher interface {
+	MatchText(query string) bool
+}
+
 func evalBinaryExpr(expr *BinaryExpr, m map[string]interface{}) interface{} {
 	lhs := Eval(expr.LHS, m)
 	rhs := Eval(expr.RHS, m)
 
+	// Full-text matches are resolved by the searched value.
+	if expr.Op == MATCHES {
+		lhs, ok := lhs.(TextMatcher)
+		if !ok {
+			return false
+		}
+		rhs, _ := rhs.(string)
+		return lhs.MatchText(rhs)
+	}
+
 	// Evaluate if both sides are simple types.
 	switch lhs := lhs.(type) {
 	case bool:
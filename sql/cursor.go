@@ -0,0 +1,45 @@
+package sql
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorPrefix identifies the version of the encoded cursor.
+const cursorPrefix = "t:"
+
+// Cursor represents a position in the history of a conversation. It is used to
+// page through messages before or after the position without an offset.
+type Cursor struct {
+	Time int64 // timestamp of the message, in nanoseconds
+}
+
+// NewCursor returns a cursor positioned at the given time.
+func NewCursor(t time.Time) *Cursor {
+	return &Cursor{Time: t.UnixNano()}
+}
+
+// String returns the opaque representation of the cursor.
+func (c *Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(c.Time, 10)))
+}
+
+// ParseCursor decodes the opaque representation of a cursor.
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || !strings.HasPrefix(string(b), cursorPrefix) {
+		return nil, ErrInvalidCursor
+	}
+
+	t, err := strconv.ParseInt(strings.TrimPrefix(string(b), cursorPrefix), 10, 64)
+	if err != nil || t < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Time: t}, nil
+}
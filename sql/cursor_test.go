@@ -0,0 +1,73 @@
+package sql_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/sql"
+)
+
+// Ensure cursors round trip through their encoding and select statements.
+func TestCursor_RoundTrip(t *testing.T) {
+	c := sql.NewCursor(time.Unix(1, 500))
+	if other, err := sql.ParseCursor(c.String()); err != nil {
+		t.Fatal(err)
+	} else if other.Time != 1000000500 {
+		t.Fatalf("unexpected cursor time: %d", other.Time)
+	}
+
+	s := `SELECT text FROM general AFTER '` + c.String() + `' BEFORE '` + c.String() + `' LIMIT 10`
+	stmt, err := sql.NewParser(strings.NewReader(s)).ParseStatement()
+	if err != nil {
+		t.Fatal(err)
+	} else if stmt.String() != s {
+		t.Fatalf("unexpected statement:\n got %s\n exp %s", stmt.String(), s)
+	}
+}
+
+// Ensure the show mentions statement accepts a before cursor only.
+func TestParser_ShowMentions(t *testing.T) {
+	c := sql.NewCursor(time.Unix(1, 500))
+	s := `SHOW MENTIONS FOR u1 BEFORE '` + c.String() + `' LIMIT 10`
+	stmt, err := sql.NewParser(strings.NewReader(s)).ParseStatement()
+	if err != nil {
+		t.Fatal(err)
+	} else if stmt.String() != s {
+		t.Fatalf("unexpected statement:\n got %s\n exp %s", stmt.String(), s)
+	}
+
+	s = `SHOW MENTIONS FOR u1 AFTER '` + c.String() + `'`
+	if _, err := sql.NewParser(strings.NewReader(s)).ParseStatement(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure the show links statement accepts a before cursor only.
+func TestParser_ShowLinks(t *testing.T) {
+	c := sql.NewCursor(time.Unix(1, 500))
+	s := `SHOW LINKS FROM general BEFORE '` + c.String() + `' LIMIT 10`
+	stmt, err := sql.NewParser(strings.NewReader(s)).ParseStatement()
+	if err != nil {
+		t.Fatal(err)
+	} else if stmt.String() != s {
+		t.Fatalf("unexpected statement:\n got %s\n exp %s", stmt.String(), s)
+	}
+
+	s = `SHOW LINKS FROM general AFTER '` + c.String() + `'`
+	if _, err := sql.NewParser(strings.NewReader(s)).ParseStatement(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure invalid cursors are rejected.
+func TestParseCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"", "not a cursor", "dDp4"} {
+		if _, err := sql.ParseCursor(s); err != sql.ErrInvalidCursor {
+			t.Errorf("%q: unexpected error: %v", s, err)
+		}
+	}
+	if _, err := sql.NewParser(strings.NewReader(`SELECT text FROM general BEFORE 'x'`)).ParseStatement(); err == nil {
+		t.Fatal("expected error")
+	}
+}
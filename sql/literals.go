@@ -8,8 +8,16 @@ import (
 	"time"
 )
 
-// String returns a string representation of the variable reference.
-func (r *VarRef) String() string { return r.Val }
+// String returns a string representation of the variable reference. The
+// reference is quoted when it could not be parsed back as a bare identifier.
+func (r *VarRef) String() string {
+	for _, segment := range strings.Split(r.Val, ".") {
+		if IdentNeedsQuotes(segment) {
+			return QuoteIdent(r.Val)
+		}
+	}
+	return r.Val
+}
 
 // NumberLiteral represents a numeric literal.
 type NumberLiteral struct {
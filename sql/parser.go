@@ -117,6 +117,14 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		return p.parseShowConversationsStatement()
 	case DEVICES:
 		return p.parseShowDevicesForUserStatement()
+	case MENTIONS:
+		return p.parseShowMentionsStatement()
+	case LINKS:
+		return p.parseShowLinksStatement()
+	case REPLIES:
+		return p.parseShowRepliesStatement()
+	case REACTIONS:
+		return p.parseShowReactionsStatement()
 	case GRANTS:
 		return p.parseGrantsForUserStatement()
 	case DATABASES:
@@ -137,7 +145,7 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		return p.parseShowUsersStatement()
 	}
 
-	return nil, newParseError(tokstr(tok, lit), []string{"CONVERSATIONS", "ORGANIZATION", "ORGANIZATIONS", "DATABASES", "FIELD", "GRANTS", "RETENTION", "SERVERS", "TAG", "USERS"}, pos)
+	return nil, newParseError(tokstr(tok, lit), []string{"CONVERSATIONS", "ORGANIZATION", "ORGANIZATIONS", "DATABASES", "FIELD", "GRANTS", "LINKS", "MENTIONS", "REACTIONS", "REPLIES", "RETENTION", "SERVERS", "TAG", "USERS"}, pos)
 }
 
 // parseCreateStatement parses a string and returns a create statement.
@@ -498,6 +506,34 @@ func (p *Parser) parseSegmentedIdents() ([]string, error) {
 	return idents, nil
 }
 
+// parseCursors parses the optional "AFTER" and "BEFORE" cursors of a select statement.
+func (p *Parser) parseCursors() (after, before *Cursor, err error) {
+	for {
+		tok, _, _ := p.scanIgnoreWhitespace()
+		if tok != AFTER && tok != BEFORE {
+			p.unscan()
+			return after, before, nil
+		}
+
+		tok2, pos, lit := p.scanIgnoreWhitespace()
+		if tok2 != STRING {
+			return nil, nil, newParseError(tokstr(tok2, lit), []string{"string"}, pos)
+		}
+		c, err := ParseCursor(lit)
+		if err != nil {
+			return nil, nil, &ParseError{Message: fmt.Sprintf("%s: %s", err, lit), Pos: pos}
+		}
+
+		if tok == AFTER && after == nil {
+			after = c
+		} else if tok == BEFORE && before == nil {
+			before = c
+		} else {
+			return nil, nil, &ParseError{Message: fmt.Sprintf("duplicate %s cursor", tok), Pos: pos}
+		}
+	}
+}
+
 // parserString parses a string.
 func (p *Parser) parseString() (string, error) {
 	tok, pos, lit := p.scanIgnoreWhitespace()
@@ -708,6 +744,35 @@ func (p *Parser) parseSelectStatement(tr targetRequirement) (*SelectStatement, e
 		return nil, err
 	}
 
+	// Parse history: "WITH HISTORY".
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == WITH {
+		if err := p.parseTokens([]Token{HISTORY}); err != nil {
+			return nil, err
+		}
+		stmt.WithHistory = true
+	} else {
+		p.unscan()
+	}
+
+	// Parse thread: "THREAD 'id'" or "WITHOUT REPLIES".
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == THREAD {
+		if stmt.Thread, err = p.parseString(); err != nil {
+			return nil, err
+		}
+	} else if tok == WITHOUT {
+		if err := p.parseTokens([]Token{REPLIES}); err != nil {
+			return nil, err
+		}
+		stmt.TopLevel = true
+	} else {
+		p.unscan()
+	}
+
+	// Parse cursors: "AFTER 'cursor'" and "BEFORE 'cursor'".
+	if stmt.After, stmt.Before, err = p.parseCursors(); err != nil {
+		return nil, err
+	}
+
 	// Parse sort: "ORDER BY FIELD+".
 	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
 		return nil, err
@@ -935,6 +1000,137 @@ func (p *Parser) parseShowDevicesForUserStatement() (*ShowDevicesForUserStatemen
 	return stmt, nil
 }
 
+// parseShowMentionsStatement parses a string and returns a ShowMentionsStatement.
+// This function assumes the "SHOW MENTIONS" tokens have already been consumed.
+func (p *Parser) parseShowMentionsStatement() (*ShowMentionsStatement, error) {
+	stmt := &ShowMentionsStatement{}
+
+	// Expect a "FOR" token.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FOR {
+		return nil, newParseError(tokstr(tok, lit), []string{"FOR"}, pos)
+	}
+
+	// Parse the ID of the mentioned user.
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	// Parse the optional cursor: "BEFORE '<cursor>'".
+	after, before, err := p.parseCursors()
+	if err != nil {
+		return nil, err
+	} else if after != nil {
+		return nil, &ParseError{Message: "AFTER is not supported for mentions"}
+	}
+	stmt.Before = before
+
+	// Parse limit: "LIMIT <n>".
+	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// parseShowLinksStatement parses a string and returns a ShowLinksStatement.
+// This function assumes the "SHOW LINKS" tokens have already been consumed.
+func (p *Parser) parseShowLinksStatement() (*ShowLinksStatement, error) {
+	stmt := &ShowLinksStatement{}
+
+	// Parse the conversation: "FROM <name>".
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FROM {
+		return nil, newParseError(tokstr(tok, lit), []string{"FROM"}, pos)
+	}
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	// Parse the optional cursor: "BEFORE '<cursor>'".
+	after, before, err := p.parseCursors()
+	if err != nil {
+		return nil, err
+	} else if after != nil {
+		return nil, &ParseError{Message: "AFTER is not supported for links"}
+	}
+	stmt.Before = before
+
+	// Parse limit: "LIMIT <n>".
+	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// parseShowRepliesStatement parses a string and returns a ShowRepliesStatement.
+// This function assumes the "SHOW REPLIES" tokens have already been consumed.
+func (p *Parser) parseShowRepliesStatement() (*ShowRepliesStatement, error) {
+	stmt := &ShowRepliesStatement{}
+
+	// Parse the conversation: "FROM <name>".
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FROM {
+		return nil, newParseError(tokstr(tok, lit), []string{"FROM"}, pos)
+	}
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	// Parse the IDs of the parent messages: "FOR 'id'[, 'id']".
+	if stmt.ParentIDs, err = p.parseMessageIDs(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// parseShowReactionsStatement parses a string and returns a ShowReactionsStatement.
+// This function assumes the "SHOW REACTIONS" tokens have already been consumed.
+func (p *Parser) parseShowReactionsStatement() (*ShowReactionsStatement, error) {
+	stmt := &ShowReactionsStatement{}
+
+	// Parse the conversation: "FROM <name>".
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FROM {
+		return nil, newParseError(tokstr(tok, lit), []string{"FROM"}, pos)
+	}
+	lit, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = lit
+
+	// Parse the IDs of the messages: "FOR 'id'[, 'id']".
+	if stmt.MessageIDs, err = p.parseMessageIDs(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// parseMessageIDs parses a list of message IDs: "FOR 'id'[, 'id']".
+func (p *Parser) parseMessageIDs() ([]string, error) {
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FOR {
+		return nil, newParseError(tokstr(tok, lit), []string{"FOR"}, pos)
+	}
+
+	var ids []string
+	for {
+		id, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != COMMA {
+			p.unscan()
+			return ids, nil
+		}
+	}
+}
+
 // parseShowDatabasesStatement parses a string and returns a ShowDatabasesStatement.
 // This function assumes the "SHOW DATABASE" tokens have already been consumed.
 func (p *Parser) parseShowDatabasesStatement() (*ShowDatabasesStatement, error) {
@@ -1444,6 +1640,13 @@ func (p *Parser) ParseExpr() (Expr, error) {
 				return nil, newParseError(tokstr(tok, lit), []string{"regex"}, pos)
 			}
 
+		} else if op == MATCHES {
+			// RHS of a full-text match must be the search query string.
+			tok, pos, lit := p.scanIgnoreWhitespace()
+			if tok != STRING {
+				return nil, newParseError(tokstr(tok, lit), []string{"string"}, pos)
+			}
+			rhs = &StringLiteral{Val: lit}
 		} else {
 			if rhs, err = p.parseUnaryExpr(); err != nil {
 				return nil, err
@@ -1755,6 +1958,10 @@ func QuoteIdent(segments ...string) string {
 
 // IdentNeedsQuotes returns true if the ident string given would require quotes.
 func IdentNeedsQuotes(ident string) bool {
+	// Keywords must be quoted to be read back as identifiers.
+	if Lookup(ident) != IDENT {
+		return true
+	}
 	for i, r := range ident {
 		if i == 0 && !isIdentFirstChar(r) {
 			return true
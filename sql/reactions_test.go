@@ -0,0 +1,25 @@
+package sql_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/messagedb/messagedb/sql"
+)
+
+// Ensure the show reactions statement parses a list of messages.
+func TestParser_ShowReactions(t *testing.T) {
+	s := `SHOW REACTIONS FROM general FOR 'm1', 'm2'`
+	stmt, err := sql.NewParser(strings.NewReader(s)).ParseStatement()
+	if err != nil {
+		t.Fatal(err)
+	} else if stmt.String() != s {
+		t.Fatalf("unexpected statement:\n got %s\n exp %s", stmt.String(), s)
+	} else if ids := stmt.(*sql.ShowReactionsStatement).MessageIDs; len(ids) != 2 {
+		t.Fatalf("unexpected message ids: %v", ids)
+	}
+
+	if _, err := sql.NewParser(strings.NewReader(`SHOW REACTIONS FROM general`)).ParseStatement(); err == nil {
+		t.Fatal("expected error")
+	}
+}
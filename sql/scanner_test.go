@@ -41,6 +41,10 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `OR`, tok: sql.OR},
 		{s: `or`, tok: sql.OR},
 
+		// Full-text search operator
+		{s: `MATCHES`, tok: sql.MATCHES},
+		{s: `matches`, tok: sql.MATCHES},
+
 		{s: `=`, tok: sql.EQ},
 		{s: `<>`, tok: sql.NEQ},
 		{s: `! `, tok: sql.ILLEGAL, lit: "!"},
@@ -137,6 +141,8 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `SHOW`, tok: sql.SHOW},
 		{s: `MEMBER`, tok: sql.MEMBER},
 		{s: `MEMBERS`, tok: sql.MEMBERS},
+		{s: `LINKS`, tok: sql.LINKS},
+		{s: `MENTIONS`, tok: sql.MENTIONS},
 		{s: `OFFSET`, tok: sql.OFFSET},
 		{s: `ON`, tok: sql.ON},
 		{s: `ORDER`, tok: sql.ORDER},
@@ -149,16 +155,19 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `QUERIES`, tok: sql.QUERIES},
 		{s: `QUERY`, tok: sql.QUERY},
 		{s: `READ`, tok: sql.READ},
+		{s: `REPLIES`, tok: sql.REPLIES},
 		{s: `RETENTION`, tok: sql.RETENTION},
 		{s: `REVOKE`, tok: sql.REVOKE},
 		{s: `SELECT`, tok: sql.SELECT},
 		{s: `TAG`, tok: sql.TAG},
+		{s: `THREAD`, tok: sql.THREAD},
 		{s: `TO`, tok: sql.TO},
 		{s: `USER`, tok: sql.USER},
 		{s: `USERS`, tok: sql.USERS},
 		{s: `VALUES`, tok: sql.VALUES},
 		{s: `WHERE`, tok: sql.WHERE},
 		{s: `WITH`, tok: sql.WITH},
+		{s: `WITHOUT`, tok: sql.WITHOUT},
 		{s: `WRITE`, tok: sql.WRITE},
 		{s: `explain`, tok: sql.EXPLAIN}, // case insensitive
 		{s: `seLECT`, tok: sql.SELECT},   // case insensitive
@@ -78,6 +78,10 @@ func (*ShowDatabasesStatement) stmt()           {}
 func (*ShowDiagnosticsStatement) stmt()         {}
 func (*ShowDevicesForUserStatement) stmt()      {}
 func (*ShowGrantsForUserStatement) stmt()       {}
+func (*ShowMentionsStatement) stmt()            {}
+func (*ShowLinksStatement) stmt()               {}
+func (*ShowRepliesStatement) stmt()             {}
+func (*ShowReactionsStatement) stmt()           {}
 func (*ShowOrganizationsStatement) stmt()       {}
 func (*ShowOrganizationMembersStatement) stmt() {}
 func (*ShowRetentionPoliciesStatement) stmt()   {}
@@ -472,19 +476,57 @@ type SelectStatement struct {
 
 	// if it's a query for raw data values (i.e. not an aggregate)
 	IsRawQuery bool
+
+	// Returns every revision of edited and deleted messages instead of the latest one.
+	WithHistory bool
+
+	// Only returns the replies to the message with this ID, when set.
+	Thread string
+
+	// Only returns the messages that are not replies to another message.
+	TopLevel bool
+
+	// Returns messages after the position of the cursor, oldest first.
+	After *Cursor
+
+	// Returns messages before the position of the cursor, newest first.
+	Before *Cursor
+}
+
+// Descending returns true if the messages are returned newest first, which is
+// the case when paging backwards from a cursor.
+func (s *SelectStatement) Descending() bool {
+	return s.Before != nil
+}
+
+// CursorTimeRange narrows a time range, in nanoseconds, to the messages between
+// the cursors of the statement. The cursor positions themselves are excluded.
+func (s *SelectStatement) CursorTimeRange(min, max int64) (int64, int64) {
+	if s.After != nil && s.After.Time >= min {
+		min = s.After.Time + 1
+	}
+	if s.Before != nil && s.Before.Time <= max {
+		max = s.Before.Time - 1
+	}
+	return min, max
 }
 
 // Clone returns a deep copy of the statement.
 func (s *SelectStatement) Clone() *SelectStatement {
 	clone := &SelectStatement{
-		Fields:     make(Fields, 0, len(s.Fields)),
-		Dimensions: make(Dimensions, 0, len(s.Dimensions)),
-		Sources:    cloneSources(s.Sources),
-		SortFields: make(SortFields, 0, len(s.SortFields)),
-		Condition:  CloneExpr(s.Condition),
-		Limit:      s.Limit,
-		Offset:     s.Offset,
-		IsRawQuery: s.IsRawQuery,
+		Fields:      make(Fields, 0, len(s.Fields)),
+		Dimensions:  make(Dimensions, 0, len(s.Dimensions)),
+		Sources:     cloneSources(s.Sources),
+		SortFields:  make(SortFields, 0, len(s.SortFields)),
+		Condition:   CloneExpr(s.Condition),
+		Limit:       s.Limit,
+		Offset:      s.Offset,
+		IsRawQuery:  s.IsRawQuery,
+		WithHistory: s.WithHistory,
+		Thread:      s.Thread,
+		TopLevel:    s.TopLevel,
+		After:       cloneCursor(s.After),
+		Before:      cloneCursor(s.Before),
 	}
 	for _, f := range s.Fields {
 		clone.Fields = append(clone.Fields, &Field{Expr: CloneExpr(f.Expr), Alias: f.Alias})
@@ -512,6 +554,24 @@ func (s *SelectStatement) String() string {
 		_, _ = buf.WriteString(" WHERE ")
 		_, _ = buf.WriteString(s.Condition.String())
 	}
+	if s.WithHistory {
+		_, _ = buf.WriteString(" WITH HISTORY")
+	}
+	if s.Thread != "" {
+		_, _ = buf.WriteString(" THREAD ")
+		_, _ = buf.WriteString(QuoteString(s.Thread))
+	}
+	if s.TopLevel {
+		_, _ = buf.WriteString(" WITHOUT REPLIES")
+	}
+	if s.After != nil {
+		_, _ = buf.WriteString(" AFTER ")
+		_, _ = buf.WriteString(QuoteString(s.After.String()))
+	}
+	if s.Before != nil {
+		_, _ = buf.WriteString(" BEFORE ")
+		_, _ = buf.WriteString(QuoteString(s.Before.String()))
+	}
 	if len(s.SortFields) > 0 {
 		_, _ = buf.WriteString(" ORDER BY ")
 		_, _ = buf.WriteString(s.SortFields.String())
@@ -858,6 +918,134 @@ func (s *ShowDevicesForUserStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+// ShowMentionsStatement represents a command for listing the messages mentioning a user, newest first.
+type ShowMentionsStatement struct {
+	// ID of the mentioned user.
+	Name string
+
+	// Only lists the mentions before the cursor, when set.
+	Before *Cursor
+
+	// Maximum number of rows to be returned.
+	// Unlimited if zero.
+	Limit int
+}
+
+// String returns a string representation of the show mentions statement.
+func (s *ShowMentionsStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SHOW MENTIONS FOR ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+
+	if s.Before != nil {
+		_, _ = buf.WriteString(" BEFORE ")
+		_, _ = buf.WriteString(QuoteString(s.Before.String()))
+	}
+	if s.Limit > 0 {
+		_, _ = buf.WriteString(" LIMIT ")
+		_, _ = buf.WriteString(strconv.Itoa(s.Limit))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowMentionsStatement.
+func (s *ShowMentionsStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: ReadPrivilege}}
+}
+
+// ShowLinksStatement represents a command for listing the links found in the messages of a conversation, newest first.
+type ShowLinksStatement struct {
+	// Name of the conversation.
+	Name string
+
+	// Only lists the links before the cursor, when set.
+	Before *Cursor
+
+	// Maximum number of rows to be returned, more when the last message has several links.
+	// Unlimited if zero.
+	Limit int
+}
+
+// String returns a string representation of the show links statement.
+func (s *ShowLinksStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SHOW LINKS FROM ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+
+	if s.Before != nil {
+		_, _ = buf.WriteString(" BEFORE ")
+		_, _ = buf.WriteString(QuoteString(s.Before.String()))
+	}
+	if s.Limit > 0 {
+		_, _ = buf.WriteString(" LIMIT ")
+		_, _ = buf.WriteString(strconv.Itoa(s.Limit))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowLinksStatement.
+func (s *ShowLinksStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: ReadPrivilege}}
+}
+
+// ShowRepliesStatement represents a command for counting the replies to messages of a conversation.
+type ShowRepliesStatement struct {
+	// Name of the conversation.
+	Name string
+
+	// IDs of the messages the replies belong to.
+	ParentIDs []string
+}
+
+// String returns a string representation of the show replies statement.
+func (s *ShowRepliesStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SHOW REPLIES FROM ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	_, _ = buf.WriteString(" FOR ")
+	for i, id := range s.ParentIDs {
+		if i > 0 {
+			_, _ = buf.WriteString(", ")
+		}
+		_, _ = buf.WriteString(QuoteString(id))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowRepliesStatement.
+func (s *ShowRepliesStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: ReadPrivilege}}
+}
+
+// ShowReactionsStatement represents a command for listing the reactions to messages of a conversation.
+type ShowReactionsStatement struct {
+	// Name of the conversation.
+	Name string
+
+	// IDs of the messages the reactions belong to.
+	MessageIDs []string
+}
+
+// String returns a string representation of the show reactions statement.
+func (s *ShowReactionsStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SHOW REACTIONS FROM ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+	_, _ = buf.WriteString(" FOR ")
+	for i, id := range s.MessageIDs {
+		if i > 0 {
+			_, _ = buf.WriteString(", ")
+		}
+		_, _ = buf.WriteString(QuoteString(id))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute a ShowReactionsStatement.
+func (s *ShowReactionsStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Admin: false, Name: "", Privilege: ReadPrivilege}}
+}
+
 // ShowServersStatement represents a command for listing all servers.
 type ShowServersStatement struct{}
 
@@ -944,6 +1132,14 @@ func (s *ShowUsersStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}
 }
 
+func cloneCursor(c *Cursor) *Cursor {
+	if c == nil {
+		return nil
+	}
+	other := *c
+	return &other
+}
+
 func cloneSources(sources Sources) Sources {
 	clone := make(Sources, 0, len(sources))
 	for _, s := range sources {
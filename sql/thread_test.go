@@ -0,0 +1,54 @@
+package sql_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/messagedb/messagedb/sql"
+)
+
+// Ensure the thread clauses of select statements round trip.
+func TestParser_SelectThread(t *testing.T) {
+	for _, tt := range []struct {
+		s        string
+		thread   string
+		topLevel bool
+	}{
+		{s: `SELECT text FROM general THREAD 'm1' LIMIT 10`, thread: "m1"},
+		{s: `SELECT text FROM general WITH HISTORY WITHOUT REPLIES`, topLevel: true},
+	} {
+		stmt, err := sql.NewParser(strings.NewReader(tt.s)).ParseStatement()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.s, err)
+		}
+		sel := stmt.(*sql.SelectStatement)
+		if sel.Thread != tt.thread || sel.TopLevel != tt.topLevel {
+			t.Fatalf("%s: unexpected thread: %q %v", tt.s, sel.Thread, sel.TopLevel)
+		} else if sel.String() != tt.s {
+			t.Fatalf("unexpected statement:\n got %s\n exp %s", sel.String(), tt.s)
+		} else if clone := sel.Clone(); clone.String() != tt.s {
+			t.Fatalf("unexpected clone:\n got %s\n exp %s", clone.String(), tt.s)
+		}
+	}
+
+	if _, err := sql.NewParser(strings.NewReader(`SELECT text FROM general THREAD m1`)).ParseStatement(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure the show replies statement parses a list of parent messages.
+func TestParser_ShowReplies(t *testing.T) {
+	s := `SHOW REPLIES FROM general FOR 'm1', 'm2'`
+	stmt, err := sql.NewParser(strings.NewReader(s)).ParseStatement()
+	if err != nil {
+		t.Fatal(err)
+	} else if stmt.String() != s {
+		t.Fatalf("unexpected statement:\n got %s\n exp %s", stmt.String(), s)
+	} else if ids := stmt.(*sql.ShowRepliesStatement).ParentIDs; len(ids) != 2 {
+		t.Fatalf("unexpected parent ids: %v", ids)
+	}
+
+	if _, err := sql.NewParser(strings.NewReader(`SHOW REPLIES FROM general`)).ParseStatement(); err == nil {
+		t.Fatal("expected error")
+	}
+}
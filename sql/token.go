@@ -44,6 +44,7 @@ const (
 	NEQ      // !=
 	EQREGEX  // =~
 	NEQREGEX // !~
+	MATCHES  // MATCHES
 	LT       // <
 	LTE      // <=
 	GT       // >
@@ -59,10 +60,12 @@ const (
 	keywordBegining
 	// Keywords
 
+	AFTER
 	ALL
 	ALTER
 	AS
 	ASC
+	BEFORE
 	BEGIN
 	BY
 	CREATE
@@ -87,6 +90,7 @@ const (
 	GRANT
 	GRANTS
 	GROUP
+	HISTORY
 	IF
 	IN
 	INF
@@ -96,8 +100,10 @@ const (
 	KEY
 	KEYS
 	LIMIT
+	LINKS
 	MEMBER
 	MEMBERS
+	MENTIONS
 	OFFSET
 	ON
 	ORDER
@@ -109,8 +115,10 @@ const (
 	PRIVILEGES
 	QUERIES
 	QUERY
+	REACTIONS
 	READ
 	REPLICATION
+	REPLIES
 	RETENTION
 	REVOKE
 	SELECT
@@ -123,12 +131,14 @@ const (
 	DIAGNOSTICS
 	SOFFSET
 	TAG
+	THREAD
 	TO
 	USER
 	USERS
 	VALUES
 	WHERE
 	WITH
+	WITHOUT
 	WRITE
 	keywordEnd
 )
@@ -160,6 +170,7 @@ var tokens = [...]string{
 	NEQ:      "!=",
 	EQREGEX:  "=~",
 	NEQREGEX: "!~",
+	MATCHES:  "MATCHES",
 	LT:       "<",
 	LTE:      "<=",
 	GT:       ">",
@@ -171,10 +182,12 @@ var tokens = [...]string{
 	SEMICOLON: ";",
 	DOT:       ".",
 
+	AFTER:         "AFTER",
 	ALL:           "ALL",
 	ALTER:         "ALTER",
 	AS:            "AS",
 	ASC:           "ASC",
+	BEFORE:        "BEFORE",
 	BEGIN:         "BEGIN",
 	BY:            "BY",
 	CREATE:        "CREATE",
@@ -197,6 +210,7 @@ var tokens = [...]string{
 	GRANT:         "GRANT",
 	GRANTS:        "GRANTS",
 	GROUP:         "GROUP",
+	HISTORY:       "HISTORY",
 	IF:            "IF",
 	IN:            "IN",
 	INF:           "INF",
@@ -205,8 +219,10 @@ var tokens = [...]string{
 	KEY:           "KEY",
 	KEYS:          "KEYS",
 	LIMIT:         "LIMIT",
+	LINKS:         "LINKS",
 	MEMBER:        "MEMBER",
 	MEMBERS:       "MEMBERS",
+	MENTIONS:      "MENTIONS",
 	OFFSET:        "OFFSET",
 	ON:            "ON",
 	ORDER:         "ORDER",
@@ -218,8 +234,10 @@ var tokens = [...]string{
 	PRIVILEGES:    "PRIVILEGES",
 	QUERIES:       "QUERIES",
 	QUERY:         "QUERY",
+	REACTIONS:     "REACTIONS",
 	READ:          "READ",
 	REPLICATION:   "REPLICATION",
+	REPLIES:       "REPLIES",
 	RETENTION:     "RETENTION",
 	REVOKE:        "REVOKE",
 	SELECT:        "SELECT",
@@ -230,12 +248,14 @@ var tokens = [...]string{
 	STATS:         "STATS",
 	DIAGNOSTICS:   "DIAGNOSTICS",
 	TAG:           "TAG",
+	THREAD:        "THREAD",
 	TO:            "TO",
 	USER:          "USER",
 	USERS:         "USERS",
 	VALUES:        "VALUES",
 	WHERE:         "WHERE",
 	WITH:          "WITH",
+	WITHOUT:       "WITHOUT",
 	WRITE:         "WRITE",
 }
 
@@ -246,7 +266,7 @@ func init() {
 	for tok := keywordBegining + 1; tok < keywordEnd; tok++ {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
-	for _, tok := range []Token{AND, OR} {
+	for _, tok := range []Token{AND, OR, MATCHES} {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
 	keywords["true"] = TRUE
@@ -268,7 +288,7 @@ func (tok Token) Precedence() int {
 		return 1
 	case AND:
 		return 2
-	case EQ, NEQ, EQREGEX, NEQREGEX, LT, LTE, GT, GTE:
+	case EQ, NEQ, EQREGEX, NEQREGEX, MATCHES, LT, LTE, GT, GTE:
 		return 3
 	case ADD, SUB:
 		return 4
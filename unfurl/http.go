@@ -0,0 +1,181 @@
+package unfurl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// DefaultTimeout is the default time allowed to fetch a link.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultMaxBodySize is the default number of bytes of a document read to find its metadata.
+	DefaultMaxBodySize = 512 * 1024
+
+	// MaxDescriptionLength is the maximum length in bytes of a description.
+	MaxDescriptionLength = 512
+)
+
+// ErrForbiddenAddress is returned when a link targets a loopback, private or link-local address.
+var ErrForbiddenAddress = errors.New("forbidden address")
+
+// HTTPFetcher fetches the metadata of a link from the title and meta tags of its target.
+type HTTPFetcher struct {
+	Client      *http.Client
+	MaxBodySize int64
+	UserAgent   string
+}
+
+// NewHTTPFetcher returns a fetcher that only connects to public addresses, so that links
+// cannot be used to probe the internal network of the server.
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	dialer := &net.Dialer{Timeout: timeout, Control: denyInternalAddresses}
+	return &HTTPFetcher{
+		Client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DialContext: dialer.DialContext},
+		},
+		MaxBodySize: DefaultMaxBodySize,
+		UserAgent:   "MessageDB-Unfurl/1.0",
+	}
+}
+
+// Fetch requests the target of a link and parses its metadata.
+func (f *HTTPFetcher) Fetch(url string) (*Metadata, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+	req.Header.Set("User-Agent", f.UserAgent)
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	} else if t, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); t != "text/html" && t != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	return Parse(io.LimitReader(resp.Body, f.MaxBodySize))
+}
+
+// Parse returns the metadata of an HTML document. Open Graph properties take precedence over
+// the title and the description of the document.
+func Parse(r io.Reader) (*Metadata, error) {
+	var title, ogTitle, description, ogDescription string
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			return newMetadata(first(ogTitle, title), first(ogDescription, description)), nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "title":
+				if z.Next() == html.TextToken && title == "" {
+					title = string(z.Text())
+				}
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "name", "property":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				switch key {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "description":
+					description = content
+				}
+			case "body":
+				// Metadata lives in the head of the document.
+				return newMetadata(first(ogTitle, title), first(ogDescription, description)), nil
+			}
+		}
+	}
+}
+
+// newMetadata returns metadata with normalized whitespace and a bounded description.
+func newMetadata(title, description string) *Metadata {
+	m := &Metadata{
+		Title:       strings.Join(strings.Fields(title), " "),
+		Description: strings.Join(strings.Fields(description), " "),
+	}
+	if len(m.Description) > MaxDescriptionLength {
+		m.Description = strings.ToValidUTF8(m.Description[:MaxDescriptionLength], "")
+	}
+	return m
+}
+
+// first returns the first non-blank string.
+func first(a ...string) string {
+	for _, s := range a {
+		if strings.TrimSpace(s) != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// internalNetworks are the networks links are not allowed to target.
+var internalNetworks = mustParseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.168.0.0/16", "::/128", "::1/128", "fc00::/7", "fe80::/10",
+)
+
+// denyInternalAddresses refuses connections to internal addresses. It runs after the host of a
+// link is resolved, so names resolving to internal addresses are refused too.
+func denyInternalAddresses(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ErrForbiddenAddress
+	}
+	for _, n := range internalNetworks {
+		if n.Contains(ip) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+func mustParseCIDRs(a ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(a))
+	for i, s := range a {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = n
+	}
+	return networks
+}
@@ -0,0 +1,97 @@
+// Package unfurl fetches the metadata displayed in the previews of the links shared in messages.
+package unfurl
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotHTML is returned when the target of a link is not an HTML document.
+var ErrNotHTML = errors.New("not an html document")
+
+// Metadata is the preview of the target of a link.
+type Metadata struct {
+	Title       string
+	Description string
+}
+
+// Fetcher is the interface to fetch the metadata of the target of a link.
+type Fetcher interface {
+	Fetch(url string) (*Metadata, error)
+}
+
+// FetcherFunc is an adapter to use an ordinary function as a Fetcher, such as a stub
+// returning canned metadata.
+type FetcherFunc func(url string) (*Metadata, error)
+
+// Fetch calls f(url).
+func (f FetcherFunc) Fetch(url string) (*Metadata, error) { return f(url) }
+
+// Cache is a Fetcher remembering the metadata fetched by another Fetcher for a while.
+// Failures are remembered too, so that unreachable links are not fetched repeatedly.
+type Cache struct {
+	mu      sync.Mutex
+	fetcher Fetcher
+	ttl     time.Duration
+	size    int
+	entries map[string]*cacheEntry
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+type cacheEntry struct {
+	metadata  *Metadata
+	err       error
+	expiresAt time.Time
+}
+
+// NewCache returns a cache of up to size entries, each kept for ttl.
+func NewCache(fetcher Fetcher, size int, ttl time.Duration) *Cache {
+	return &Cache{
+		fetcher: fetcher,
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]*cacheEntry),
+		Now:     time.Now,
+	}
+}
+
+// Fetch returns the cached metadata of a link, fetching it when it is missing or expired.
+func (c *Cache) Fetch(url string) (*Metadata, error) {
+	c.mu.Lock()
+	e := c.entries[url]
+	c.mu.Unlock()
+	if e != nil && c.Now().Before(e.expiresAt) {
+		return e.metadata, e.err
+	}
+
+	m, err := c.fetcher.Fetch(url)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict()
+	c.entries[url] = &cacheEntry{metadata: m, err: err, expiresAt: c.Now().Add(c.ttl)}
+	return m, err
+}
+
+// evict removes the expired entries, and arbitrary entries if the cache is still full.
+// This function must be called within the context of a lock.
+func (c *Cache) evict() {
+	if len(c.entries) < c.size {
+		return
+	}
+	now := c.Now()
+	for url, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, url)
+		}
+	}
+	for url := range c.entries {
+		if len(c.entries) < c.size {
+			break
+		}
+		delete(c.entries, url)
+	}
+}
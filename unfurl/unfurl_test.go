@@ -0,0 +1,85 @@
+package unfurl_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/messagedb/messagedb/unfurl"
+)
+
+// Ensure the metadata is parsed from the head of a document.
+func TestParse(t *testing.T) {
+	for i, tt := range []struct {
+		html string
+		exp  unfurl.Metadata
+	}{
+		{
+			html: `<html><head><title> Hello
+				World </title><meta name="description" content="A page"></head><body>x</body></html>`,
+			exp: unfurl.Metadata{Title: "Hello World", Description: "A page"},
+		},
+		{
+			html: `<head><title>Page</title><meta property="og:title" content="OG Page"><meta property="og:description" content="OG description"/>`,
+			exp:  unfurl.Metadata{Title: "OG Page", Description: "OG description"},
+		},
+		{
+			html: `<body><title>ignored</title></body>`,
+			exp:  unfurl.Metadata{},
+		},
+	} {
+		m, err := unfurl.Parse(strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatalf("%d. unexpected error: %s", i, err)
+		} else if *m != tt.exp {
+			t.Errorf("%d. unexpected metadata: %#v", i, m)
+		}
+	}
+}
+
+// Ensure links to internal addresses are refused.
+func TestHTTPFetcher_InternalAddress(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<title>internal</title>`))
+	}))
+	defer s.Close()
+
+	if _, err := unfurl.NewHTTPFetcher(time.Second).Fetch(s.URL); err == nil || !strings.Contains(err.Error(), unfurl.ErrForbiddenAddress.Error()) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure fetched metadata and failures are cached until they expire.
+func TestCache(t *testing.T) {
+	var n int
+	now := time.Unix(0, 0)
+	c := unfurl.NewCache(unfurl.FetcherFunc(func(url string) (*unfurl.Metadata, error) {
+		n++
+		if url == "http://fail" {
+			return nil, errors.New("unreachable")
+		}
+		return &unfurl.Metadata{Title: url}, nil
+	}), 10, time.Minute)
+	c.Now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if m, err := c.Fetch("http://a"); err != nil || m.Title != "http://a" {
+			t.Fatalf("unexpected result: %v %v", m, err)
+		} else if _, err := c.Fetch("http://fail"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if n != 2 {
+		t.Fatalf("unexpected fetch count: %d", n)
+	}
+
+	now = now.Add(time.Minute)
+	c.Fetch("http://a")
+	if n != 3 {
+		t.Fatalf("unexpected fetch count: %d", n)
+	}
+}